@@ -247,9 +247,9 @@ function getTemplatePath(repoRoot, template) {
   if (!template) return null;
   const t = String(template).trim();
   if (!t) return null;
-  if (t === 'node') return path.join(repoRoot, 'ops', 'packaging', 'templates', 'Dockerfile.node');
+  if (t === 'node') return path.join(repoRoot, 'ops', 'packaging', 'templates', 'Dockerfile.node.tmpl');
   if (t === 'python') return path.join(repoRoot, 'ops', 'packaging', 'templates', 'Dockerfile.python');
-  if (t === 'go') return path.join(repoRoot, 'ops', 'packaging', 'templates', 'Dockerfile.go');
+  if (t === 'go') return path.join(repoRoot, 'ops', 'packaging', 'templates', 'Dockerfile.go.tmpl');
   return path.isAbsolute(t) ? t : path.join(repoRoot, t);
 }
 
@@ -0,0 +1,821 @@
+// Command admissions-api serves the student application endpoints.
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/audit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth/oidc"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/deadlines"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/export"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/handlers"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/idempotency"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/importer"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/interviews"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/jobs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/letters"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/migrations"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/programs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/recommendations"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/search"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ws"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/admin"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/buildinfo"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/cache"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	pkgconfig "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/health"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	httpmetrics "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/server"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// Build metadata, stamped by the Dockerfile's -ldflags from the VERSION,
+// COMMIT, and BUILD_TIME build args.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+func main() {
+	buildinfo.Set(version, commit, buildTime)
+	showVersion := flag.Bool("version", false, "print build metadata and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Println("admissions-api", buildinfo.String())
+		return
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return err
+	}
+	// The level is a LevelVar so the admin listener can change it.
+	var logLevel slog.LevelVar
+	logLevel.Set(level)
+	logger := logging.NewLogger(&logLevel, envOr("LOG_FORMAT", logging.FormatFromEnv()))
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Init("admissions-api", os.Getenv("TRACING_EXPORTER_ADDR"))
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing()
+	tracer := tracing.Tracer()
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return errors.New("JWT_SECRET is required")
+	}
+	issuer := auth.NewIssuer(secret)
+	if issuer.AccessTTL, err = envDuration("ACCESS_TOKEN_TTL", auth.DefaultAccessTTL); err != nil {
+		return err
+	}
+	if issuer.RefreshTTL, err = envDuration("REFRESH_TOKEN_TTL", auth.DefaultRefreshTTL); err != nil {
+		return err
+	}
+	if issuer.Sessions, err = newSessionStore(); err != nil {
+		return err
+	}
+
+	policy, err := rbac.LoadPolicy(envOr("RBAC_POLICY_FILE", "config/rbac.yaml"))
+	if err != nil {
+		return err
+	}
+
+	rt := router.New(middleware.Chain(middleware.JWTAuth(secret), middleware.RejectRevoked(issuer.Sessions), middleware.RequireMFA(), middleware.RequireRole(policy)))
+	healthz := health.NewHandler()
+	healthz.Info = map[string]any{"service": "admissions-api"}
+	for k, v := range buildinfo.Fields() {
+		healthz.Info[k] = v
+	}
+	rt.Handle("GET /health", healthz, router.SkipAuth())
+	rt.Handle("GET /healthz", healthz, router.SkipAuth())
+	readiness := &server.Readiness{}
+	rt.Handle("GET /readyz", readiness, router.SkipAuth())
+	// /healthz/live answers while the process runs; /healthz/ready, the
+	// Kubernetes readiness probe, also needs every dependency configured
+	// below and fails once shutdown starts.
+	rt.Handle("GET /healthz/live", health.NewHandler(), router.SkipAuth())
+	ready := health.NewHandler()
+	ready.Deadline = 2 * time.Second
+	ready.Register("shutdown", health.CheckerFunc(func(context.Context) error {
+		if readiness.Draining() {
+			return errors.New("draining")
+		}
+		return nil
+	}))
+	rt.Handle("GET /healthz/ready", ready, router.SkipAuth())
+	rt.HandleFunc("GET /version", versionHandler, router.SkipAuth())
+	rec := metrics.NewRecorder(nil)
+	rec.Registry.MustRegister(httpmetrics.BuildInfo())
+	metricsNets, err := middleware.ParseNetworks(os.Getenv("METRICS_ALLOWED_CIDRS"))
+	if err != nil {
+		return fmt.Errorf("METRICS_ALLOWED_CIDRS: %w", err)
+	}
+	if port := os.Getenv("METRICS_PORT"); port != "" {
+		if err := serveMetrics(":"+port, middleware.AllowNetworks(metricsNets)(rec.Handler()), logger); err != nil {
+			return err
+		}
+	} else {
+		rt.Handle("GET /metrics", rec.Handler(), router.SkipAuth(), router.With(middleware.AllowNetworks(metricsNets)))
+	}
+
+	// Probes above are neither logged nor rate limited; everything
+	// registered below is both.
+	rt.Use(middleware.RequestLogger(logger))
+	limits, err := newRateLimitStore()
+	if err != nil {
+		return err
+	}
+	if c, ok := limits.(health.Checker); ok {
+		ready.Register("redis", c)
+	}
+	limit, err := envInt64("RATE_LIMIT", 120)
+	if err != nil {
+		return err
+	}
+	window, err := envDuration("RATE_LIMIT_WINDOW", time.Minute)
+	if err != nil {
+		return err
+	}
+	rt.Use(middleware.RateLimit(limits, int(limit), window))
+
+	pagination, err := store.ParsePaginationMode(os.Getenv("PAGINATION_MODE"))
+	if err != nil {
+		return err
+	}
+	// Applications are kept in memory without DATABASE_URL, and in the
+	// student_applications table, under row level security, with it.
+	// Search always reads where they are kept.
+	memApps := store.NewMemoryStore()
+	var apps applicationStore = memApps
+	var engine search.Engine = search.MemoryEngine{Applications: memApps}
+	applications := &handlers.ApplicationHandler{
+		Programs:   handlers.NewProgramSet(os.Getenv("PROGRAM_CODES")),
+		Pagination: pagination,
+		Metrics:    rec,
+	}
+	var db *sql.DB
+	// Background workers stop when shutdown starts and finish within the
+	// same SHUTDOWN_GRACE as in-flight requests.
+	serveOpts := []server.Option{server.WithReadiness(readiness)}
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		if db, err = sql.Open("pgx", dsn); err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		if os.Getenv("MIGRATE_ON_START") == "true" {
+			if err := migrateOnStart(db); err != nil {
+				return err
+			}
+		}
+		registry, err := loadDeadlines(db)
+		if err != nil {
+			return err
+		}
+		applications.SubmitGuard = deadlines.Enforcer(registry, clock.System)
+		serveOpts = append(serveOpts, server.WithWorker(server.WorkerFunc(registry.Watch)))
+		apps, engine = store.NewSQLStore(db), search.PostgresEngine{DB: db}
+		healthz.Register("database", health.Ping(db))
+		ready.RegisterWithTimeout("database", 500*time.Millisecond, health.Ping(db))
+	}
+	// Every route below serves one tenant, which the stores scope their
+	// reads and writes to.
+	tenants := newTenants(db)
+	var tenantOpts []middleware.TenantOption
+	if os.Getenv("MULTI_TENANT") != "true" {
+		tenantOpts = append(tenantOpts, middleware.FallbackTenant(tenant.DefaultID))
+	}
+	trusted, err := middleware.ParseNetworks(os.Getenv("TENANT_HEADER_TRUSTED_CIDRS"))
+	if err != nil {
+		return fmt.Errorf("TENANT_HEADER_TRUSTED_CIDRS: %w", err)
+	}
+	tenantOpts = append(tenantOpts, middleware.TrustTenantHeader(trusted))
+	rt.Use(middleware.TenantResolver(tenants, tenantOpts...))
+	// Logins come after the resolver, so their tokens carry the tenant
+	// they were made at.
+	rt.Handle("POST /auth/refresh", auth.RefreshHandler(issuer), router.SkipAuth())
+	totp, err := newTOTPStore()
+	if err != nil {
+		return err
+	}
+	// The second-factor endpoints check tokens themselves, so a login
+	// still marked mfa_pending can reach them; a code is six digits, so
+	// guesses get a budget of their own.
+	mfa := &auth.TOTPHandler{Issuer: issuer, Store: totp}
+	rt.HandleFunc("POST /auth/totp/enroll", mfa.Enroll, router.SkipAuth())
+	rt.HandleFunc("POST /auth/totp/verify", mfa.Verify, router.SkipAuth(), router.Limit(ratelimit.Config{Scope: "totp", Limit: 10, Window: time.Minute}))
+	rt.HandleFunc("DELETE /auth/totp", mfa.Unenroll, router.SkipAuth())
+	sso, err := newSSO(issuer, db, secret, totp)
+	if err != nil {
+		return err
+	}
+	if sso != nil {
+		rt.HandleFunc("GET /auth/login", sso.Login, router.SkipAuth())
+		rt.HandleFunc("GET /auth/callback", sso.Callback, router.SkipAuth())
+		rt.HandleFunc("GET /auth/logout", sso.Logout, router.SkipAuth())
+	}
+	idem, err := newIdempotencyStore()
+	if err != nil {
+		return err
+	}
+	idemTTL, err := envDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return err
+	}
+	rt.Use(middleware.Idempotency(idem, idemTTL))
+	mailer, queue, err := newMailer(rec)
+	if err != nil {
+		return err
+	}
+	if mailer != nil {
+		if db == nil {
+			return errors.New("SMTP_ADDR requires DATABASE_URL for applicant addresses")
+		}
+		applications.Notifier = &notify.StatusMailer{Sender: mailer, Directory: notify.SQLDirectory{DB: db}}
+	}
+	if queue != nil {
+		serveOpts = append(serveOpts, server.WithWorker(queue))
+	}
+	// Every mutation is recorded with its actor in the audit log, in the
+	// same transaction when the log is the audit_log table.
+	var recorder audit.Recorder = audit.NewMemoryRecorder()
+	if db != nil {
+		recorder = audit.SQLRecorder{DB: db}
+	}
+	auditLog := audit.New(recorder)
+	auditedApps := auditLog.Applications(store.Traced(apps, tracer))
+	applications.Store = auditedApps
+	corsCfg, err := config.LoadCORS()
+	if err != nil {
+		return err
+	}
+	origins, err := cors.Compile(corsCfg.Policy())
+	if err != nil {
+		return err
+	}
+	// Status changes are pushed to the applicant's open tabs, which may be
+	// on the origins CORS admits; the hub closes its connections at
+	// shutdown, which Server.Shutdown leaves to their handlers.
+	hub := ws.NewHub(ws.WithLogger(logger), ws.WithAllowedOrigins(origins))
+	applications.Events = hub
+	rt.Handle("GET /v1/ws", hub)
+	serveOpts = append(serveOpts, server.WithWorker(hub))
+	applications.Register(rt)
+	(&handlers.AuditHandler{Recorder: recorder}).Register(rt)
+	(&handlers.SearchHandler{Engine: engine}).Register(rt)
+	(&handlers.ExportHandler{Exporter: export.New(apps), DateFormat: os.Getenv("EXPORT_DATE_FORMAT")}).Register(rt)
+	importMax, err := envInt64("IMPORT_MAX_BYTES", importer.DefaultMaxSize)
+	if err != nil {
+		return err
+	}
+	(&handlers.ImportHandler{Store: auditedApps, Programs: applications.Programs, MaxSize: importMax}).Register(rt)
+	catalog, err := newCatalog(db, logger)
+	if err != nil {
+		return err
+	}
+	(&handlers.ProgramHandler{Programs: catalog}).Register(rt)
+	interviewer := &handlers.InterviewHandler{
+		Applications: auditedApps,
+		Interviews:   interviews.NewMemoryStore(),
+		Programs:     applications.Programs,
+		Mailer:       mailer,
+	}
+	if db != nil {
+		interviewer.Interviews = interviews.SQLStore{DB: db}
+		interviewer.Directory = notify.SQLDirectory{DB: db}
+	}
+	if interviewer.CancelCutoff, err = envDuration("INTERVIEW_CANCEL_CUTOFF", interviews.DefaultCancelCutoff); err != nil {
+		return err
+	}
+	interviewer.Register(rt)
+
+	maxSize, err := envInt64("DOCUMENT_MAX_BYTES", documents.DefaultMaxSize)
+	if err != nil {
+		return err
+	}
+	uploader, err := newUploader(context.Background(), maxSize, rec)
+	if err != nil {
+		return err
+	}
+	if c, ok := uploader.(health.Checker); ok {
+		ready.Register("s3", c)
+	}
+	// Document metadata is kept next to the applications, in the documents
+	// table when there is a database.
+	var metas documents.MetaStore = documents.NewMemoryMetaStore()
+	if db != nil {
+		metas = documents.SQLMetaStore{DB: db}
+	}
+	docs := &handlers.DocumentHandler{
+		Applications: auditedApps,
+		Uploader:     uploader,
+		Metas:        auditLog.Documents(metas),
+		MaxSize:      maxSize,
+		Metrics:      rec,
+	}
+	if docs.UploadLimit, err = uploadLimit(); err != nil {
+		return err
+	}
+	// Stored documents are scanned and thumbnailed in the background, by
+	// a worker on every replica when the queue is in Redis.
+	jobQueue, err := newJobQueue()
+	if err != nil {
+		return err
+	}
+	if c, ok := jobQueue.(health.Checker); ok {
+		ready.Register("jobs", c)
+	}
+	docs.Jobs = jobQueue
+	worker := jobs.NewWorker(jobQueue)
+	worker.Logger = logger
+	if files, ok := uploader.(jobs.DocumentFiles); ok {
+		processor := &jobs.DocumentProcessor{Files: files, Metas: docs.Metas, Logger: logger}
+		if addr := os.Getenv("CLAMD_ADDR"); addr != "" {
+			processor.Scanner = documents.Clamd{Addr: addr}
+		}
+		processor.Register(worker)
+	}
+	serveOpts = append(serveOpts, server.WithWorker(worker))
+	docs.Register(rt)
+	// Referees answer a recommendation request through the emailed link,
+	// so without SMTP_ADDR requests cannot be made.
+	var requests recommendations.Store = recommendations.NewMemoryStore()
+	if db != nil {
+		requests = recommendations.SQLStore{DB: db}
+	}
+	recs := &handlers.RecommendationHandler{
+		Applications: auditedApps,
+		Requests:     requests,
+		Uploader:     uploader,
+		Metas:        docs.Metas,
+		Jobs:         jobQueue,
+		Mailer:       mailer,
+		SubmitURL:    envOr("RECOMMENDATION_SUBMIT_URL", "http://localhost:8080/v1/recommendations/submit"),
+		MaxSize:      maxSize,
+	}
+	if recs.TTL, err = envDuration("RECOMMENDATION_TTL", recommendations.DefaultTTL); err != nil {
+		return err
+	}
+	expiryInterval, err := envDuration("RECOMMENDATION_EXPIRY_INTERVAL", recommendations.DefaultExpiryInterval)
+	if err != nil {
+		return err
+	}
+	recs.Register(rt)
+	serveOpts = append(serveOpts, server.WithWorker(&recommendations.Expirer{Store: recs.Requests, Interval: expiryInterval, Logger: logger}))
+	if archive, ok := uploader.(letters.Archive); ok {
+		gen := letters.NewGenerator(auditedApps, letters.WKHTMLToPDF{Path: os.Getenv("WKHTMLTOPDF_PATH")}, archive)
+		gen.University = envOr("LETTER_UNIVERSITY", gen.University)
+		gen.Signatory = envOr("LETTER_SIGNATORY", gen.Signatory)
+		(&handlers.LetterHandler{Applications: auditedApps, Letters: gen}).Register(rt)
+	}
+
+	tlsOpts, err := server.TLSFromEnv()
+	if err != nil {
+		return err
+	}
+	if tlsOpts != nil {
+		serveOpts = append(serveOpts, server.WithTLS(*tlsOpts))
+	}
+	timeouts, err := server.TimeoutsFromEnv()
+	if err != nil {
+		return err
+	}
+	serveOpts = append(serveOpts, server.WithTimeouts(timeouts))
+	var conns admin.Conns
+	tenantAdmin := &handlers.TenantAdminHandler{Tenants: tenants, Applications: apps}
+	adminSrv, err := newAdmin(&logLevel, &conns, logger, tenantAdmin.Routes())
+	if err != nil {
+		return err
+	}
+	if adminSrv != nil {
+		serveOpts = append(serveOpts, server.WithWorker(adminSrv))
+	}
+	addr := ":" + envOr("PORT", "8080")
+	logger.Info("admissions-api listening", "addr", addr, "tls", tlsOpts != nil)
+	// The request ID is assigned outermost, so probes and auth failures
+	// echo one too; CORS comes next, so preflights never reach auth or
+	// the rate limits.
+	var idOpts []middleware.RequestIDOption
+	if os.Getenv("REQUEST_ID_REJECT_CLIENT") == "true" {
+		idOpts = append(idOpts, middleware.RejectClientRequestIDs())
+	}
+	ids := middleware.RequestID(idOpts...)
+	handler := middleware.Chain(ids, middleware.CORS(*corsCfg), middleware.Trace(tracer), middleware.Instrument(rec))(rt)
+	srv := &http.Server{Addr: addr, Handler: handler, ConnState: conns.ConnState}
+	return server.Run(context.Background(), srv, serveOpts...)
+}
+
+// serveMetrics serves /metrics on a listener of its own, METRICS_PORT, so
+// the public one does not expose it. The listener is opened before
+// returning, so a taken port fails startup, and lives as long as the
+// process.
+func serveMetrics(addr string, h http.Handler, logger *slog.Logger) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("METRICS_PORT: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", h)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Info("metrics listening", "addr", addr)
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			logger.Error("metrics listener stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// newAdmin opens the admin listener (pkg/admin) when ADMIN_ENABLED is
+// true, on ADMIN_ADDR with ADMIN_TOKEN as its bearer token, or returns
+// nil. It shows the environment as the effective config, since that is
+// where this service's settings come from, and serves routes, the
+// service's own operator endpoints, beside the built-in ones.
+func newAdmin(level *slog.LevelVar, conns *admin.Conns, logger *slog.Logger, routes map[string]http.Handler) (*admin.Server, error) {
+	if os.Getenv("ADMIN_ENABLED") != "true" {
+		return nil, nil
+	}
+	opts := []admin.Option{admin.WithConfig(admin.Environ()), admin.WithLevel(level), admin.WithConns(conns), admin.WithLogger(logger)}
+	for pattern, h := range routes {
+		opts = append(opts, admin.WithHandler(pattern, h))
+	}
+	return admin.Listen(admin.Config{
+		Enabled: true,
+		Addr:    envOr("ADMIN_ADDR", admin.DefaultAddr),
+		Token:   pkgconfig.Secret(os.Getenv("ADMIN_TOKEN")),
+	}, opts...)
+}
+
+// applicationStore is what admissions-api needs of the applications
+// store: the handlers' store.ApplicationStore and the cross-applicant
+// queries of exports and the admin API.
+type applicationStore interface {
+	store.ApplicationStore
+	export.Source
+}
+
+// newTenants returns the tenants table's store, or without a database one
+// holding only the default tenant.
+func newTenants(db *sql.DB) tenant.Store {
+	if db != nil {
+		return tenant.SQLStore{DB: db}
+	}
+	return tenant.NewMemoryStore(tenant.Default)
+}
+
+// newCatalog keeps the program catalog in the database when there is
+// one, reading it through Redis when REDIS_URL is set, for
+// PROGRAM_CACHE_TTL.
+func newCatalog(db *sql.DB, logger *slog.Logger) (programs.Store, error) {
+	var st programs.Store = programs.NewMemoryStore()
+	if db != nil {
+		st = programs.SQLStore{DB: db}
+	}
+	ttl, err := envDuration("PROGRAM_CACHE_TTL", programs.DefaultCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	c, err := cache.New(os.Getenv("REDIS_URL"), cache.WithLogger(logger), cache.WithPrefix("cache:admissions:"))
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return programs.Cached(st, c, ttl), nil
+}
+
+// migrateOnStart applies pending migrations before the server reads any
+// table. Replicas starting together serialize on the runner's lock.
+func migrateOnStart(db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	n, err := migrations.Up(ctx, db, nil)
+	if err == nil && n > 0 {
+		slog.Info("migrations applied at startup", "count", n)
+	}
+	return err
+}
+
+// loadDeadlines loads the application_deadlines table. Running the
+// registry's Watch keeps it fresh every DEADLINE_RELOAD_INTERVAL.
+func loadDeadlines(db *sql.DB) (*deadlines.Registry, error) {
+	registry := deadlines.NewRegistry(deadlines.SQLSource{DB: db})
+	var err error
+	if registry.Interval, err = envDuration("DEADLINE_RELOAD_INTERVAL", deadlines.DefaultReloadInterval); err != nil {
+		return nil, err
+	}
+	if err := registry.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// newMailer sends email through SMTP_ADDR when it is set, using the
+// templates in EMAIL_TEMPLATES_DIR. Without SMTP_ADDR it returns a nil
+// sender and no mail is sent. Mail goes out within the request unless
+// NOTIFY_QUEUE_SIZE is set; then it is queued and sent by the returned
+// Queue, which must be run as a server worker. Either way the relay is
+// behind a circuit breaker (see newBreaker).
+func newMailer(rec *metrics.Recorder) (notify.EmailSender, *notify.Queue, error) {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return nil, nil, nil
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		return nil, nil, errors.New("SMTP_FROM is required with SMTP_ADDR")
+	}
+	templates, err := notify.LoadTemplates(envOr("EMAIL_TEMPLATES_DIR", "config/email"))
+	if err != nil {
+		return nil, nil, err
+	}
+	breaker, err := newBreaker("smtp", rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	smtpSender := &notify.SMTPSender{Addr: addr, From: from, Templates: templates, Breaker: breaker}
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("SMTP_ADDR: %w", err)
+		}
+		smtpSender.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	var (
+		sender notify.EmailSender = smtpSender
+		queue  *notify.Queue
+	)
+	if os.Getenv("NOTIFY_QUEUE_SIZE") != "" {
+		size, err := envInt64("NOTIFY_QUEUE_SIZE", 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		queue = notify.NewQueue(smtpSender, int(size))
+		sender = queue
+	}
+	return sender, queue, nil
+}
+
+// newUploader stores documents in DOCUMENTS_BUCKET when it is set, using
+// the standard AWS credential chain and S3_ENDPOINT for S3-compatible
+// services, and under DOCUMENTS_DIR otherwise. S3 is behind a circuit
+// breaker (see newBreaker).
+func newUploader(ctx context.Context, maxSize int64, rec *metrics.Recorder) (documents.Uploader, error) {
+	bucket := os.Getenv("DOCUMENTS_BUCKET")
+	if bucket == "" {
+		u := documents.NewLocalUploader(envOr("DOCUMENTS_DIR", "data/documents"))
+		u.MaxSize = maxSize
+		return u, nil
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	// S3 calls carry the request ID and traceparent of the upload.
+	cfg.HTTPClient = &http.Client{Transport: middleware.Propagate(awshttp.NewBuildableClient().GetTransport())}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+			o.BaseEndpoint = &endpoint
+			o.UsePathStyle = true
+		}
+	})
+	u := documents.NewS3Uploader(client, bucket, os.Getenv("DOCUMENTS_PREFIX"))
+	u.MaxSize = maxSize
+	if u.Breaker, err = newBreaker("s3", rec); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// newBreaker returns the circuit breaker of dependency name, with the
+// thresholds in CIRCUIT_FAILURE_THRESHOLD, CIRCUIT_SUCCESS_THRESHOLD, and
+// CIRCUIT_TIMEOUT, exporting its state to rec.
+func newBreaker(name string, rec *metrics.Recorder) (*circuit.Breaker, error) {
+	failures, err := envInt64("CIRCUIT_FAILURE_THRESHOLD", circuit.DefaultFailureThreshold)
+	if err != nil {
+		return nil, err
+	}
+	successes, err := envInt64("CIRCUIT_SUCCESS_THRESHOLD", circuit.DefaultSuccessThreshold)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := envDuration("CIRCUIT_TIMEOUT", circuit.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rec.CircuitState(name, string(circuit.Closed))
+	return &circuit.Breaker{
+		Name:          name,
+		Policy:        circuit.Policy{FailureThreshold: int(failures), SuccessThreshold: int(successes), Timeout: timeout},
+		OnStateChange: func(_, to circuit.State) { rec.CircuitState(name, string(to)) },
+	}, nil
+}
+
+// newSSO returns the university SSO endpoints configured by the
+// UNIASSIST_OIDC__ variables, or nil when there is no issuer. The
+// provider's discovery document must be reachable at startup. Users are
+// kept in the users table when db is set.
+func newSSO(issuer *auth.Issuer, db *sql.DB, secret string, totp auth.TOTPStore) (*oidc.Handler, error) {
+	cfg, err := config.LoadOIDC()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, oidc.Options{
+		IssuerURL:    cfg.IssuerURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: string(cfg.ClientSecret),
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		Client:       &http.Client{Timeout: 10 * time.Second, Transport: middleware.Propagate(nil)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The state cookie key is derived from JWT_SECRET so that every
+	// replica accepts the others' logins.
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("oidc-state"))
+	var users store.UserStore = store.NewMemoryUserStore()
+	if db != nil {
+		users = store.NewSQLUserStore(db)
+	}
+	return &oidc.Handler{
+		Provider:      provider,
+		Issuer:        issuer,
+		Users:         users,
+		StateKey:      mac.Sum(nil),
+		RoleClaim:     cfg.RoleClaim,
+		DefaultRole:   cfg.DefaultRole,
+		PostLogoutURL: cfg.PostLogoutURL,
+		TOTP:          totp,
+		MFARoles:      strings.FieldsFunc(envOr("MFA_REQUIRED_ROLES", rbac.RoleAdmin+","+rbac.RoleAdvisor), func(r rune) bool { return r == ',' || r == ' ' }),
+	}, nil
+}
+
+// newTOTPStore keeps second factors in Redis when REDIS_URL is set, so
+// every replica refuses a code any of them accepted.
+func newTOTPStore() (auth.TOTPStore, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return auth.NewMemoryTOTPStore(), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return auth.NewRedisTOTPStore(redis.NewClient(opts)), nil
+}
+
+// newSessionStore keeps revoked sessions in Redis when REDIS_URL is set,
+// so a logout at one replica is refused at all of them.
+func newSessionStore() (auth.SessionStore, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return auth.NewMemorySessions(), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return auth.NewRedisSessions(redis.NewClient(opts)), nil
+}
+
+// newJobQueue shares background jobs across replicas through Redis when
+// REDIS_URL is set and keeps them in process otherwise.
+func newJobQueue() (jobs.RetryQueue, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return jobs.NewMemoryQueue(), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return jobs.NewRedisQueue(redis.NewClient(opts)), nil
+}
+
+// newRateLimitStore shares buckets across replicas through Redis when
+// REDIS_URL is set and keeps them in process otherwise.
+func newRateLimitStore() (ratelimit.Store, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return ratelimit.NewMemStore(nil), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return ratelimit.NewRedisStore(redis.NewClient(opts)), nil
+}
+
+func newIdempotencyStore() (idempotency.Store, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return idempotency.NewMemStore(nil), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	return idempotency.NewRedisStore(redis.NewClient(opts)), nil
+}
+
+// uploadLimit is the document upload budget, tighter than the default
+// because each request can carry DOCUMENT_MAX_BYTES.
+func uploadLimit() (ratelimit.Config, error) {
+	limit, err := envInt64("UPLOAD_RATE_LIMIT", 20)
+	if err != nil {
+		return ratelimit.Config{}, err
+	}
+	window, err := envDuration("UPLOAD_RATE_LIMIT_WINDOW", time.Hour)
+	if err != nil {
+		return ratelimit.Config{}, err
+	}
+	return ratelimit.Config{Scope: "uploads", Limit: int(limit), Window: window}, nil
+}
+
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	respond.JSON(w, http.StatusOK, buildinfo.Fields())
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+func envInt64(key string, fallback int64) (int64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: want a positive integer, got %q", key, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/migrations"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// runMigrate is the migrate subcommand:
+//
+//	admissions-api migrate [-database-url URL] [-timeout 5m] [-dry-run] [up | down | status]
+//
+// up (the default) applies every pending migration, or with -dry-run lists
+// the ones it would apply; down rolls back the latest one, and status
+// lists them all.
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dsn := fs.String("database-url", os.Getenv("DATABASE_URL"), "Postgres connection URL (default $DATABASE_URL)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "give up after this long, waiting for another replica's lock included")
+	dryRun := fs.Bool("dry-run", false, "with up, list the pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	action := "up"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		action = fs.Arg(0)
+	default:
+		return errors.New("migrate: want one of up, down, or status")
+	}
+	if *dryRun && action != "up" {
+		return fmt.Errorf("migrate: -dry-run applies to up, not %s", action)
+	}
+	if *dsn == "" {
+		return errors.New("migrate: DATABASE_URL or -database-url is required")
+	}
+	runner, err := migrations.NewRunner(nil)
+	if err != nil {
+		return err
+	}
+	runner.Logger = logging.NewLogger(slog.LevelInfo, logging.FormatFromEnv())
+	runner.DryRun = *dryRun
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	switch action {
+	case "up":
+		n, err := runner.Up(ctx, db)
+		if err == nil && *dryRun {
+			fmt.Fprintf(stdout, "%d pending migration(s) would be applied\n", n)
+		}
+		return err
+	case "down":
+		return runner.Down(ctx, db)
+	case "status":
+		status, err := runner.Status(ctx, db)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("migrate: unknown action %q: want up, down, or status", action)
+}
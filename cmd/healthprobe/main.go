@@ -0,0 +1,47 @@
+// Command healthprobe is the HEALTHCHECK of the rendered Go images: it GETs
+// a URL and exits 0 on a 2xx response and 1 otherwise. Distroless and
+// minimal alpine images have no curl or wget, so the Dockerfile template
+// builds this next to the service binary.
+//
+//	healthprobe [-timeout 3s] http://localhost:8080/healthz
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 3*time.Second, "give up after this long")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: healthprobe [-timeout 3s] <url>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := probe(&http.Client{Timeout: *timeout}, flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, "healthprobe:", err)
+		os.Exit(1)
+	}
+}
+
+// probe returns nil if url answers a GET with a 2xx status.
+func probe(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Package audit records who changed which admissions record, when, and
+// how. A Logger wraps the application and document stores so that every
+// successful create, update, and delete appends an Entry, with the actor
+// taken from the request's claims and a field-level Diff of the record,
+// to a Recorder: the audit_log table (see SQLRecorder) or, without
+// DATABASE_URL, memory.
+//
+// Each mutation and its entry are committed together inside
+// Recorder.Atomic, so a mutation that fails leaves no entry behind.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// Action is what a mutation did to its entity.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Entity types recorded by Logger.
+const (
+	EntityApplication = "application"
+	EntityDocument    = "document"
+)
+
+// SystemActor is recorded for mutations made outside a request, such as
+// by a background job that did not name itself with WithActor.
+const SystemActor = "system"
+
+// Entry is one recorded mutation.
+type Entry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	ActorID    string    `json:"actor_id"`
+	Action     Action    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	Diff       []Change  `json:"diff"`
+}
+
+// Query selects entries. Zero fields do not filter; Start is inclusive
+// and End exclusive.
+type Query struct {
+	EntityID string
+	Start    time.Time
+	End      time.Time
+}
+
+// Match reports whether e is selected by q.
+func (q Query) Match(e Entry) bool {
+	return (q.EntityID == "" || e.EntityID == q.EntityID) &&
+		(q.Start.IsZero() || !e.Time.Before(q.Start)) &&
+		(q.End.IsZero() || e.Time.Before(q.End))
+}
+
+// Recorder stores entries.
+type Recorder interface {
+	// Atomic runs fn, which makes one mutation and records its entry,
+	// so that both take effect or neither does: entries recorded with
+	// the context fn is given are kept only if fn returns nil. A nested
+	// call joins the outer one.
+	Atomic(ctx context.Context, fn func(ctx context.Context) error) error
+	// Record stores e, assigning its ID.
+	Record(ctx context.Context, e *Entry) error
+	// Query calls fn with each entry q selects, oldest first, stopping
+	// at the first error fn returns.
+	Query(ctx context.Context, q Query, fn func(Entry) error) error
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose mutations are recorded as made by id,
+// for work done outside a request on someone's behalf.
+func WithActor(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, actorKey{}, id)
+}
+
+// ActorFromContext is the actor of ctx's mutations: the WithActor ID, the
+// caller's token subject, or SystemActor.
+func ActorFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(actorKey{}).(string); ok && id != "" {
+		return id
+	}
+	if claims, ok := middleware.ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		return claims.Subject
+	}
+	return SystemActor
+}
+
+// Logger records the mutations of the stores it wraps.
+type Logger struct {
+	rec Recorder
+	now func() time.Time
+}
+
+// New returns a Logger recording to rec.
+func New(rec Recorder) *Logger {
+	return &Logger{rec: rec, now: time.Now}
+}
+
+// record appends the entry for one mutation, inside the Atomic call
+// making it.
+func (l *Logger) record(ctx context.Context, action Action, entityType, entityID string, old, new any) error {
+	return l.rec.Record(ctx, &Entry{
+		Time:       l.now().UTC(),
+		ActorID:    ActorFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       Diff(old, new),
+	})
+}
+
+// Applications returns s with its mutations recorded. Reads pass through.
+// A soft delete is recorded as a delete whose diff sets deleted_at, and a
+// hard delete as one clearing every field.
+func (l *Logger) Applications(s store.ApplicationStore) store.ApplicationStore {
+	return applications{ApplicationStore: s, l: l}
+}
+
+type applications struct {
+	store.ApplicationStore
+	l *Logger
+}
+
+func (a applications) Create(ctx context.Context, app *models.StudentApplication) error {
+	return a.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		if err := a.ApplicationStore.Create(ctx, app); err != nil {
+			return err
+		}
+		return a.l.record(ctx, ActionCreate, EntityApplication, app.ID, nil, app)
+	})
+}
+
+func (a applications) Update(ctx context.Context, app *models.StudentApplication) error {
+	return a.UpdateFunc(ctx, app, nil)
+}
+
+// UpdateFunc records the update from its commit hook, so the store only
+// keeps the change once the entry has been recorded.
+func (a applications) UpdateFunc(ctx context.Context, app *models.StudentApplication, fn func(*models.StudentApplication) error) error {
+	return a.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		old, err := a.ApplicationStore.GetByID(ctx, app.ID)
+		if err != nil {
+			return err
+		}
+		return a.ApplicationStore.UpdateFunc(ctx, app, func(next *models.StudentApplication) error {
+			if fn != nil {
+				if err := fn(next); err != nil {
+					return err
+				}
+			}
+			return a.l.record(ctx, ActionUpdate, EntityApplication, app.ID, old, next)
+		})
+	})
+}
+
+func (a applications) Delete(ctx context.Context, id string) error {
+	return a.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		old, err := a.ApplicationStore.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := a.ApplicationStore.Delete(ctx, id); err != nil {
+			return err
+		}
+		deleted, err := a.ApplicationStore.GetByID(ctx, id, store.WithDeleted())
+		if err != nil {
+			return err
+		}
+		return a.l.record(ctx, ActionDelete, EntityApplication, id, old, deleted)
+	})
+}
+
+func (a applications) HardDelete(ctx context.Context, id string) error {
+	return a.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		old, err := a.ApplicationStore.GetByID(ctx, id, store.WithDeleted())
+		if err != nil {
+			return err
+		}
+		if err := a.ApplicationStore.HardDelete(ctx, id); err != nil {
+			return err
+		}
+		return a.l.record(ctx, ActionDelete, EntityApplication, id, old, nil)
+	})
+}
+
+// Documents returns s with its mutations recorded, as Applications does.
+func (l *Logger) Documents(s documents.MetaStore) documents.MetaStore {
+	return metas{MetaStore: s, l: l}
+}
+
+type metas struct {
+	documents.MetaStore
+	l *Logger
+}
+
+func (m metas) Create(ctx context.Context, meta *documents.Meta) error {
+	return m.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		if err := m.MetaStore.Create(ctx, meta); err != nil {
+			return err
+		}
+		return m.l.record(ctx, ActionCreate, EntityDocument, meta.ID, nil, meta)
+	})
+}
+
+func (m metas) Delete(ctx context.Context, id string) error {
+	return m.l.rec.Atomic(ctx, func(ctx context.Context) error {
+		old, err := m.MetaStore.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := m.MetaStore.Delete(ctx, id); err != nil {
+			return err
+		}
+		deleted, err := m.MetaStore.GetByID(ctx, id, store.WithDeleted())
+		if err != nil {
+			return err
+		}
+		return m.l.record(ctx, ActionDelete, EntityDocument, id, old, deleted)
+	})
+}
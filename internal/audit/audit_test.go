@@ -0,0 +1,339 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func TestDiff(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type record struct {
+		Name     string     `json:"name"`
+		Password string     `json:"password" audit:"-"`
+		Internal string     `json:"-"`
+		Address  address    `json:"address"`
+		Tags     []string   `json:"tags,omitempty"`
+		At       time.Time  `json:"at"`
+		Deleted  *time.Time `json:"deleted_at,omitempty"`
+		Count    int
+	}
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	base := record{Name: "a", Password: "p1", Internal: "x", Address: address{City: "Oslo"}, At: at}
+
+	for _, tc := range []struct {
+		name     string
+		old, new any
+		want     []Change
+	}{
+		{"no change", base, base, []Change{}},
+		{"same instant in another zone", base, func() record { r := base; r.At = at.In(time.FixedZone("CET", 3600)); return r }(), []Change{}},
+		{
+			"fields", &base, func() *record {
+				r := base
+				r.Name, r.Internal, r.Address.Zip, r.Tags, r.Deleted, r.Count = "b", "y", "0150", []string{"t"}, &at, 2
+				return &r
+			}(),
+			[]Change{{"name", "a", "b"}, {"address.zip", "", "0150"}, {"tags", []string(nil), []string{"t"}}, {"deleted_at", nil, at}, {"Count", 0, 2}},
+		},
+		{"redacted", base, func() record { r := base; r.Password = "p2"; return r }(), []Change{{"password", Redacted, Redacted}}},
+		{"create", nil, &base, []Change{{"name", nil, "a"}, {"password", nil, Redacted}, {"address.city", nil, "Oslo"}, {"at", nil, at}}},
+		{"delete", base, nil, []Change{{"name", "a", nil}, {"password", Redacted, nil}, {"address.city", "Oslo", nil}, {"at", at, nil}}},
+	} {
+		old, new := tc.old, tc.new
+		if old == nil {
+			old = (*record)(nil)
+		}
+		if new == nil {
+			new = (*record)(nil)
+		}
+		if got := Diff(old, new); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Diff = %#v\nwant %#v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// entries returns everything rec holds.
+func entries(t *testing.T, rec Recorder, q Query) []Entry {
+	t.Helper()
+	var out []Entry
+	if err := rec.Query(context.Background(), q, func(e Entry) error { out = append(out, e); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func paths(changes []Change) []string {
+	out := []string{}
+	for _, c := range changes {
+		out = append(out, c.Path)
+	}
+	return out
+}
+
+func TestApplicationsRecordsMutations(t *testing.T) {
+	ctx := WithActor(context.Background(), "admin-1")
+	rec := NewMemoryRecorder()
+	l := New(rec)
+	apps := l.Applications(store.NewMemoryStore())
+
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS-MSC", Status: status.Pending}
+	if err := apps.Create(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	app.Status = status.UnderReview
+	if err := apps.Update(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	if err := apps.Delete(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := apps.HardDelete(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries(t, rec, Query{EntityID: app.ID})
+	want := []struct {
+		action Action
+		paths  []string
+	}{
+		{ActionCreate, []string{"id", "applicant_id", "program_code", "status", "submitted_at", "updated_at"}},
+		{ActionUpdate, []string{"status", "updated_at"}},
+		{ActionDelete, []string{"deleted_at"}},
+		{ActionDelete, []string{"id", "applicant_id", "program_code", "status", "submitted_at", "updated_at", "deleted_at"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		e := got[i]
+		if e.Action != w.action || e.ActorID != "admin-1" || e.EntityType != EntityApplication || e.ID != int64(i+1) {
+			t.Errorf("entry %d = %+v, want a %s by admin-1", i, e, w.action)
+		}
+		if p := paths(e.Diff); !reflect.DeepEqual(p, w.paths) {
+			t.Errorf("entry %d (%s) diff paths = %v, want %v", i, e.Action, p, w.paths)
+		}
+	}
+	if c := got[1].Diff[0]; c.From != status.Pending || c.To != status.UnderReview {
+		t.Errorf("status change = %+v", c)
+	}
+}
+
+func TestDocumentsRecordsMutations(t *testing.T) {
+	ctx := context.Background()
+	rec := NewMemoryRecorder()
+	metas := New(rec).Documents(documents.NewMemoryMetaStore())
+	if err := metas.Create(ctx, &documents.Meta{ID: "doc-1", ApplicationID: "app-1", Filename: "cv.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := metas.Delete(ctx, "doc-1"); err != nil {
+		t.Fatal(err)
+	}
+	got := entries(t, rec, Query{})
+	if len(got) != 2 || got[0].Action != ActionCreate || got[1].Action != ActionDelete || got[1].EntityType != EntityDocument {
+		t.Fatalf("entries = %+v", got)
+	}
+	if got[0].ActorID != SystemActor {
+		t.Errorf("actor outside a request = %q, want %q", got[0].ActorID, SystemActor)
+	}
+}
+
+// failingRecorder is a MemoryRecorder whose Record fails.
+type failingRecorder struct{ *MemoryRecorder }
+
+func (failingRecorder) Record(context.Context, *Entry) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestFailedMutationRecordsNothing(t *testing.T) {
+	ctx := context.Background()
+	rec := NewMemoryRecorder()
+	s := store.NewMemoryStore()
+	apps := New(rec).Applications(s)
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS-MSC", Status: status.Pending}
+	if err := apps.Create(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	before := len(entries(t, rec, Query{}))
+
+	var invalid *status.ErrInvalidTransition
+	bad := *app
+	bad.Status = status.Enrolled
+	if err := apps.Update(ctx, &bad); !errors.As(err, &invalid) {
+		t.Errorf("invalid transition: err = %v", err)
+	}
+	boom := errors.New("boom")
+	next := *app
+	next.Status = status.UnderReview
+	if err := apps.UpdateFunc(ctx, &next, func(*models.StudentApplication) error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("failing commit hook: err = %v", err)
+	}
+	for name, err := range map[string]error{
+		"update of a missing application": apps.Update(ctx, &models.StudentApplication{ID: "missing", Status: status.Pending}),
+		"delete of a missing application": apps.Delete(ctx, "missing"),
+		"hard delete of a missing one":    apps.HardDelete(ctx, "missing"),
+	} {
+		if !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if n := len(entries(t, rec, Query{})); n != before {
+		t.Errorf("%d entries after failed mutations, want %d", n, before)
+	}
+
+	// An entry that cannot be recorded fails the update it describes.
+	failing := New(failingRecorder{NewMemoryRecorder()}).Applications(s)
+	if err := failing.Update(ctx, &next); err == nil {
+		t.Fatal("update succeeded without its audit entry")
+	}
+	if cur, _ := s.GetByID(ctx, app.ID); cur.Status != status.Pending {
+		t.Errorf("status = %s after an unrecorded update, want it unchanged", cur.Status)
+	}
+}
+
+func TestQueryTimeRange(t *testing.T) {
+	rec := NewMemoryRecorder()
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		rec.Record(context.Background(), &Entry{Time: at.Add(time.Duration(i) * time.Hour), EntityID: "app-1"})
+	}
+	got := entries(t, rec, Query{EntityID: "app-1", Start: at.Add(time.Hour), End: at.Add(2 * time.Hour)})
+	if len(got) != 1 || !got[0].Time.Equal(at.Add(time.Hour)) {
+		t.Errorf("entries = %+v, want only the 10:00 one", got)
+	}
+	if got := entries(t, rec, Query{EntityID: "app-2"}); len(got) != 0 {
+		t.Errorf("other entity: %+v", got)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	stmt, args := buildQuery(Query{EntityID: "app-1", Start: at, End: at.Add(time.Hour)})
+	if !strings.Contains(stmt, "WHERE entity_id = $1 AND occurred_at >= $2 AND occurred_at < $3 ORDER BY occurred_at, id") || len(args) != 3 {
+		t.Errorf("stmt = %s, args = %v", stmt, args)
+	}
+	if stmt, args := buildQuery(Query{}); strings.Contains(stmt, "WHERE") || len(args) != 0 {
+		t.Errorf("unfiltered stmt = %s, args = %v", stmt, args)
+	}
+}
+
+// fakeDB is just enough of Postgres for SQLRecorder: the tenant settings
+// of store.Scoped, audit_log inserts that only land on commit, and a
+// select of everything.
+type fakeDB struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db      *fakeDB
+	pending [][]driver.Value
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { c.pending = nil; return c, nil }
+func (c *fakeConn) Rollback() error                     { c.pending = nil; return nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "set_config") {
+		return nil, errors.New("unexpected statement " + query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rows = append(c.db.rows, c.pending...)
+	c.pending = nil
+	return nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if query == insertEntry {
+		id := int64(len(c.db.rows) + len(c.pending) + 1)
+		row := []driver.Value{id}
+		for _, a := range args {
+			row = append(row, a.Value)
+		}
+		c.pending = append(c.pending, row)
+		return &fakeRows{cols: []string{"id"}, rows: [][]driver.Value{{id}}}, nil
+	}
+	return &fakeRows{cols: []string{"id", "occurred_at", "actor_id", "action", "entity_type", "entity_id", "diff"}, rows: append([][]driver.Value(nil), c.db.rows...)}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLRecorderSharesTheTransaction(t *testing.T) {
+	db := sql.OpenDB(&fakeDB{})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	rec := SQLRecorder{DB: db}
+	ctx := WithActor(context.Background(), "admin-1")
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// A mutation that fails after its entry was written rolls both back.
+	boom := errors.New("constraint violation")
+	err := rec.Atomic(ctx, func(ctx context.Context) error {
+		if _, ok := store.TxFromContext(ctx); !ok {
+			t.Error("no transaction in the context for the store to use")
+		}
+		if err := rec.Record(ctx, &Entry{Time: at, ActorID: "admin-1", Action: ActionUpdate, EntityID: "app-1"}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Atomic = %v, want the mutation's error", err)
+	}
+	if got := entries(t, rec, Query{}); len(got) != 0 {
+		t.Fatalf("failed mutation left %d audit rows", len(got))
+	}
+
+	e := &Entry{Time: at, ActorID: "admin-1", Action: ActionCreate, EntityType: EntityApplication, EntityID: "app-1", Diff: []Change{{"status", nil, "pending"}}}
+	if err := rec.Atomic(ctx, func(ctx context.Context) error { return rec.Record(ctx, e) }); err != nil {
+		t.Fatal(err)
+	}
+	got := entries(t, rec, Query{})
+	if len(got) != 1 || got[0].ID != e.ID || got[0].Action != ActionCreate || got[0].Diff[0].To != "pending" {
+		b, _ := json.Marshal(got)
+		t.Errorf("entries = %s", b)
+	}
+}
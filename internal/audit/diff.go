@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Redacted stands in for the old and new values of a field tagged
+// audit:"-".
+const Redacted = "[redacted]"
+
+// Change is one field that differs between the old and new value of an
+// entity. Path is the field's JSON name, dotted for nested structs. From
+// is null on creation and To on deletion.
+type Change struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Diff lists the fields that differ between old and new, two values of
+// the same struct type or pointers to it, either of which may be nil for
+// a creation or deletion. Fields are named and skipped as encoding/json
+// does; nested structs are compared field by field, and other values,
+// slices and maps included, as a whole. On creation and deletion, fields
+// holding their zero value are left out. A field tagged audit:"-" is
+// reported with both values Redacted, so the log shows that it changed
+// but not to what.
+func Diff(old, new any) []Change {
+	changes := []Change{}
+	diffValue(&changes, "", reflect.ValueOf(old), reflect.ValueOf(new))
+	return changes
+}
+
+func diffValue(changes *[]Change, path string, a, b reflect.Value) {
+	a, b = indirect(a), indirect(b)
+	t := typeOf(a, b)
+	if t == nil {
+		return
+	}
+	if t.Kind() == reflect.Struct && !leaf(t) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			name, ok := jsonName(sf)
+			if !ok {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			fa, fb := field(a, i), field(b, i)
+			if sf.Tag.Get("audit") == "-" {
+				if !absentZero(fa, fb) && !equal(fa, fb) {
+					*changes = append(*changes, Change{Path: name, From: redacted(fa), To: redacted(fb)})
+				}
+				continue
+			}
+			diffValue(changes, name, fa, fb)
+		}
+		return
+	}
+	if !absentZero(a, b) && !equal(a, b) {
+		*changes = append(*changes, Change{Path: path, From: value(a), To: value(b)})
+	}
+}
+
+// indirect follows pointers and interfaces, returning the zero Value for
+// a nil one.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func typeOf(a, b reflect.Value) reflect.Type {
+	if a.IsValid() {
+		return a.Type()
+	}
+	if b.IsValid() {
+		return b.Type()
+	}
+	return nil
+}
+
+// leaf reports whether a struct type is compared whole: time.Time and
+// types that marshal themselves.
+func leaf(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	p := reflect.PointerTo(t)
+	return p.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) ||
+		p.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem())
+}
+
+// jsonName is the key encoding/json gives sf, and false for a field it
+// skips.
+func jsonName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = sf.Name
+	}
+	return name, true
+}
+
+func field(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	return v.Field(i)
+}
+
+// absentZero reports whether one of a and b is absent, as every field is
+// on creation or deletion, and the other is its zero value.
+func absentZero(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	return !a.IsValid() && b.IsValid() && b.IsZero() || !b.IsValid() && a.IsValid() && a.IsZero()
+}
+
+func equal(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func value(v reflect.Value) any {
+	if v = indirect(v); !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func redacted(v reflect.Value) any {
+	if indirect(v).IsValid() {
+		return Redacted
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryRecorder is an in-process Recorder for tests and local
+// development without DATABASE_URL.
+type MemoryRecorder struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewMemoryRecorder returns an empty MemoryRecorder.
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{}
+}
+
+type pendingKey struct{}
+
+// pending holds the entries recorded inside an Atomic call until fn
+// returns.
+type pending struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// Atomic implements Recorder.
+func (r *MemoryRecorder) Atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		return fn(ctx)
+	}
+	p := &pending{}
+	if err := fn(context.WithValue(ctx, pendingKey{}, p)); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range p.entries {
+		r.append(e)
+	}
+	return nil
+}
+
+// Record implements Recorder. Inside Atomic, e gets its ID when the call
+// commits.
+func (r *MemoryRecorder) Record(ctx context.Context, e *Entry) error {
+	if p, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		p.mu.Lock()
+		p.entries = append(p.entries, e)
+		p.mu.Unlock()
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.append(e)
+	return nil
+}
+
+// append stores e. r.mu must be held.
+func (r *MemoryRecorder) append(e *Entry) {
+	e.ID = int64(len(r.entries)) + 1
+	r.entries = append(r.entries, *e)
+}
+
+// Query implements Recorder. Entries are kept in the order recorded,
+// which is time order.
+func (r *MemoryRecorder) Query(ctx context.Context, q Query, fn func(Entry) error) error {
+	r.mu.RLock()
+	selected := []Entry{}
+	for _, e := range r.entries {
+		if q.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+	r.mu.RUnlock()
+	for _, e := range selected {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
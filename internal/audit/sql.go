@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLRecorder stores entries in the audit_log table (see
+// prisma/schema.prisma), each owned by the tenant of the context it was
+// recorded in. Atomic runs its fn in a transaction carried by
+// the context (store.WithTx), which the entries recorded in it use, as
+// does a SQL-backed store making the mutation; a store that keeps its
+// records elsewhere commits them whether or not the transaction does.
+type SQLRecorder struct {
+	DB *sql.DB
+}
+
+// Atomic implements Recorder, in a transaction scoped to the context's
+// tenant (see store.Scoped).
+func (r SQLRecorder) Atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := store.TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	return store.Scoped(ctx, r.DB, func(ctx context.Context, _ *sql.Tx) error { return fn(ctx) })
+}
+
+const insertEntry = `INSERT INTO audit_log (occurred_at, actor_id, action, entity_type, entity_id, diff)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+// Record implements Recorder, on the context's transaction if it has one.
+func (r SQLRecorder) Record(ctx context.Context, e *Entry) error {
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return fmt.Errorf("audit: encode diff: %w", err)
+	}
+	return store.Scoped(ctx, r.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, insertEntry)
+		defer span.End()
+		if err := tx.QueryRowContext(ctx, insertEntry, e.Time, e.ActorID, string(e.Action), e.EntityType, e.EntityID, diff).Scan(&e.ID); err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("audit: insert: %w", err)
+		}
+		return nil
+	})
+}
+
+// Query implements Recorder, reading the context tenant's rows as fn
+// consumes them.
+func (r SQLRecorder) Query(ctx context.Context, q Query, fn func(Entry) error) error {
+	return store.Scoped(ctx, r.DB, func(ctx context.Context, tx *sql.Tx) error {
+		return r.query(ctx, tx, q, fn)
+	})
+}
+
+func (r SQLRecorder) query(ctx context.Context, tx *sql.Tx, q Query, fn func(Entry) error) error {
+	stmt, args := buildQuery(q)
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("audit: query: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e      Entry
+			action string
+			diff   []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Time, &e.ActorID, &action, &e.EntityType, &e.EntityID, &diff); err != nil {
+			return fmt.Errorf("audit: scan: %w", err)
+		}
+		e.Action = Action(action)
+		if err := json.Unmarshal(diff, &e.Diff); err != nil {
+			return fmt.Errorf("audit: entry %d: decode diff: %w", e.ID, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("audit: query: %w", err)
+	}
+	return nil
+}
+
+// buildQuery returns the SELECT for q and its arguments. The conditions
+// use the (entity_id, occurred_at) and (occurred_at) indexes.
+func buildQuery(q Query) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.EntityID != "" {
+		add("entity_id = $%d", q.EntityID)
+	}
+	if !q.Start.IsZero() {
+		add("occurred_at >= $%d", q.Start)
+	}
+	if !q.End.IsZero() {
+		add("occurred_at < $%d", q.End)
+	}
+	stmt := "SELECT id, occurred_at, actor_id, action, entity_type, entity_id, diff FROM audit_log"
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	return stmt + " ORDER BY occurred_at, id", args
+}
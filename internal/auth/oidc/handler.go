@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// StateCookie carries a login's state, nonce, and PKCE verifier from
+// /auth/login to /auth/callback, signed so it cannot be forged.
+const StateCookie = "uniassist_oidc"
+
+// stateTTL is how long a login may take at the provider.
+const stateTTL = 10 * time.Minute
+
+// Handler serves the single sign-on endpoints. A successful login
+// upserts the provider account in Users and answers with a token pair
+// from Issuer, as POST /auth/refresh does; tokens issued without SSO,
+// such as those of service accounts, keep working alongside.
+type Handler struct {
+	Provider *Provider
+	Issuer   *auth.Issuer
+	Users    store.UserStore
+	// StateKey signs the state cookie.
+	StateKey []byte
+	// RoleClaim, DefaultRole, and PostLogoutURL are as in config.OIDC.
+	RoleClaim     string
+	DefaultRole   string
+	PostLogoutURL string
+	// TOTP holds the second factors of accounts that enrolled one, and
+	// MFARoles lists the roles that must. Logins by either are issued
+	// tokens marked mfa_pending, good only for /auth/totp until a code
+	// is verified there; see auth.TOTPHandler.
+	TOTP     auth.TOTPStore
+	MFARoles []string
+
+	now func() time.Time
+}
+
+// loginState is the content of the state cookie.
+type loginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+// Login handles GET /auth/login, redirecting the browser to the
+// provider with a fresh state cookie.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	st := loginState{State: randomToken(), Nonce: randomToken(), Verifier: randomToken(), Expires: h.clock().Add(stateTTL).Unix()}
+	http.SetCookie(w, h.stateCookie(h.sign(st), int(stateTTL/time.Second)))
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.Provider.AuthCodeURL(st.State, st.Nonce, st.Verifier), http.StatusFound)
+}
+
+// Callback handles GET /auth/callback?code=&state=, where the provider
+// sends the browser back. The state must match the cookie Login set,
+// which is used up either way.
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var st loginState
+	c, err := r.Cookie(StateCookie)
+	if err == nil {
+		st, err = h.verify(c.Value)
+	}
+	http.SetCookie(w, h.stateCookie("", -1))
+	w.Header().Set("Cache-Control", "no-store")
+	if msg := q.Get("error"); msg != "" {
+		if d := q.Get("error_description"); d != "" {
+			msg += ": " + d
+		}
+		respond.Error(w, http.StatusUnauthorized, "LOGIN_FAILED", "identity provider refused the login: "+msg)
+		return
+	}
+	if err != nil || !hmac.Equal([]byte(q.Get("state")), []byte(st.State)) {
+		respond.Error(w, http.StatusBadRequest, "INVALID_STATE", "login state is missing, expired, or does not match; start again at /auth/login")
+		return
+	}
+	if q.Get("code") == "" {
+		respond.Error(w, http.StatusBadRequest, "INVALID_REQUEST", "code is required")
+		return
+	}
+	id, err := h.Provider.Exchange(r.Context(), q.Get("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("OIDC login failed", "error", err)
+		respond.Error(w, http.StatusUnauthorized, "LOGIN_FAILED", "login with the identity provider failed")
+		return
+	}
+	user := &models.User{Issuer: h.Provider.Issuer(), Subject: id.Subject, Email: id.Email, Name: id.Name, Role: h.role(id)}
+	if err := h.Users.UpsertExternal(r.Context(), user, h.DefaultRole); err != nil {
+		logging.FromContext(r.Context()).Error("upsert OIDC user", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to record the login")
+		return
+	}
+	pending, err := h.mfaPending(r.Context(), user)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("load TOTP enrollment", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check two-factor enrollment")
+		return
+	}
+	issue := h.Issuer.IssueSession
+	if pending {
+		issue = h.Issuer.IssuePending
+	}
+	pair, err := issue(user.ID, user.Role, tenant.ID(r.Context()), auth.NewSessionID())
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to issue token")
+		return
+	}
+	respond.JSON(w, http.StatusOK, pair)
+}
+
+// Logout handles GET /auth/logout. It takes the session's access token,
+// or its refresh token once that has expired, as a Bearer token, revokes
+// the session so neither can be used again, and returns the provider's
+// logout page as end_session_url when it has one. Tokens issued outside
+// a login session cannot be revoked and run out on their own.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing bearer token")
+		return
+	}
+	claims, err := h.Issuer.Parse(strings.TrimSpace(token), auth.TokenTypeAccess)
+	if err != nil {
+		claims, err = h.Issuer.Parse(strings.TrimSpace(token), auth.TokenTypeRefresh)
+	}
+	if err != nil {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
+		return
+	}
+	if claims.SessionID != "" && h.Issuer.Sessions != nil {
+		// The session's tokens are all expired by the time the last
+		// refresh token it could have been given is.
+		ttl := h.Issuer.RefreshTTL
+		if ttl <= 0 {
+			ttl = auth.DefaultRefreshTTL
+		}
+		if err := h.Issuer.Sessions.Revoke(r.Context(), claims.SessionID, h.clock().Add(ttl)); err != nil {
+			logging.FromContext(r.Context()).Error("revoke session", "error", err)
+			respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke session")
+			return
+		}
+	}
+	body := map[string]any{"logged_out": true}
+	if u := h.Provider.EndSessionURL(h.PostLogoutURL); u != "" {
+		body["end_session_url"] = u
+	}
+	respond.JSON(w, http.StatusOK, body)
+}
+
+// mfaPending reports whether a login by user owes a TOTP code: their
+// role requires one, or they enrolled one of their own accord.
+func (h *Handler) mfaPending(ctx context.Context, user *models.User) (bool, error) {
+	if slices.Contains(h.MFARoles, user.Role) {
+		return true, nil
+	}
+	if h.TOTP == nil {
+		return false, nil
+	}
+	_, confirmed, err := h.TOTP.Secret(ctx, user.ID)
+	if errors.Is(err, auth.ErrTOTPNotEnrolled) {
+		return false, nil
+	}
+	return confirmed, err
+}
+
+// role is the role RoleClaim grants: its value, or the first known role
+// in it when it is a list such as groups. Empty means none.
+func (h *Handler) role(id *IDToken) string {
+	if h.RoleClaim == "" {
+		return ""
+	}
+	var values []any
+	switch v := id.Claims[h.RoleClaim].(type) {
+	case string:
+		values = []any{v}
+	case []any:
+		values = v
+	}
+	for _, v := range values {
+		switch s, _ := v.(string); s {
+		case rbac.RoleStudent, rbac.RoleAdvisor, rbac.RoleAdmin:
+			return s
+		}
+	}
+	return ""
+}
+
+func (h *Handler) stateCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     StateCookie,
+		Value:    value,
+		Path:     "/auth/callback",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		// Served over HTTPS wherever the provider sends the browser back
+		// over HTTPS.
+		Secure: strings.HasPrefix(h.Provider.config.RedirectURL, "https://"),
+		// Lax, so the cookie comes along on the provider's redirect back.
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// sign encodes st as base64(JSON).base64(HMAC-SHA256).
+func (h *Handler) sign(st loginState) string {
+	payload, _ := json.Marshal(st)
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	return enc + "." + base64.RawURLEncoding.EncodeToString(h.mac(enc))
+}
+
+func (h *Handler) verify(value string) (loginState, error) {
+	var st loginState
+	enc, sig, ok := strings.Cut(value, ".")
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if !ok || err != nil || !hmac.Equal(mac, h.mac(enc)) {
+		return st, errors.New("bad state signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil || json.Unmarshal(payload, &st) != nil {
+		return st, errors.New("malformed state")
+	}
+	if h.clock().Unix() > st.Expires {
+		return st, errors.New("state expired")
+	}
+	return st, nil
+}
+
+func (h *Handler) mac(s string) []byte {
+	m := hmac.New(sha256.New, h.StateKey)
+	m.Write([]byte(s))
+	return m.Sum(nil)
+}
+
+func (h *Handler) clock() time.Time {
+	if h.now == nil {
+		return time.Now()
+	}
+	return h.now()
+}
+
+// randomToken returns 32 random bytes, base64url-encoded: enough for a
+// state, a nonce, or a PKCE verifier (RFC 7636 wants 43 to 128 chars).
+func randomToken() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("oidc: crypto/rand failed: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
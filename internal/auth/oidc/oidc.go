@@ -0,0 +1,171 @@
+// Package oidc signs students and staff in through their university's
+// OpenID Connect provider, with the authorization code flow and PKCE, and
+// answers a login with the API's own tokens from auth.Issuer.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	pkgauth "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/auth"
+)
+
+// ErrLogin is wrapped by every failure to complete a login with the
+// identity provider, whether the provider refused it or its answer did
+// not check out.
+var ErrLogin = errors.New("oidc: login failed")
+
+// Options configures NewProvider.
+type Options struct {
+	// IssuerURL is the provider's issuer identifier.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback registered with the provider.
+	RedirectURL string
+	// Scopes are requested on login; "openid" is always added.
+	Scopes []string
+	// Client makes the discovery, token, and key requests; nil means
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Provider runs the OpenID Connect authorization code flow, with
+// PKCE, against an identity provider: it builds the login redirect,
+// exchanges the returned code, and verifies the ID token against the
+// provider's published keys, which go-oidc fetches and caches.
+type Provider struct {
+	config     oauth2.Config
+	provider   *gooidc.Provider
+	verifier   *gooidc.IDTokenVerifier
+	client     *http.Client
+	issuer     string
+	endSession string
+}
+
+// NewProvider reads the provider's discovery document from
+// IssuerURL/.well-known/openid-configuration. It fails if the document
+// is unreachable or names another issuer.
+func NewProvider(ctx context.Context, opts Options) (*Provider, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if !slices.Contains(opts.Scopes, gooidc.ScopeOpenID) {
+		opts.Scopes = append([]string{gooidc.ScopeOpenID}, opts.Scopes...)
+	}
+	// The key set go-oidc builds keeps this context's client for its
+	// fetches, so it must outlive ctx's deadline.
+	provider, err := gooidc.NewProvider(gooidc.ClientContext(context.WithoutCancel(ctx), opts.Client), opts.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery at %s: %w", opts.IssuerURL, err)
+	}
+	var md struct {
+		Issuer             string `json:"issuer"`
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&md); err != nil {
+		return nil, fmt.Errorf("oidc: discovery at %s: %w", opts.IssuerURL, err)
+	}
+	return &Provider{
+		config: oauth2.Config{
+			ClientID:     opts.ClientID,
+			ClientSecret: opts.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  opts.RedirectURL,
+			Scopes:       opts.Scopes,
+		},
+		provider:   provider,
+		verifier:   provider.Verifier(&gooidc.Config{ClientID: opts.ClientID, SupportedSigningAlgs: pkgauth.DefaultAlgorithms}),
+		client:     opts.Client,
+		issuer:     md.Issuer,
+		endSession: md.EndSessionEndpoint,
+	}, nil
+}
+
+// Issuer is the provider's issuer identifier.
+func (p *Provider) Issuer() string { return p.issuer }
+
+// AuthCodeURL is the provider's login page for a flow identified by
+// state, whose ID token must carry nonce, and whose code can only be
+// redeemed with verifier.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.config.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// EndSessionURL is the provider's logout page, which sends the browser on
+// to postLogout; it is empty if the provider has none.
+func (p *Provider) EndSessionURL(postLogout string) string {
+	if p.endSession == "" {
+		return ""
+	}
+	q := url.Values{"client_id": {p.config.ClientID}}
+	if postLogout != "" {
+		q.Set("post_logout_redirect_uri", postLogout)
+	}
+	return p.endSession + "?" + q.Encode()
+}
+
+// IDToken is the verified identity a login returns.
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Claims holds every claim in the token.
+	Claims jwt.MapClaims
+}
+
+// Exchange redeems an authorization code and verifies the ID token that
+// comes with it: its signature, issuer, audience, expiry, and nonce.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	ctx = gooidc.ClientContext(ctx, p.client)
+	tok, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: token exchange: %w", ErrLogin, err)
+	}
+	raw, _ := tok.Extra("id_token").(string)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrLogin)
+	}
+	id, err := p.verify(ctx, raw, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ID token: %w", ErrLogin, err)
+	}
+	return id, nil
+}
+
+func (p *Provider) verify(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	tok, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	if tok.Nonce == "" || tok.Nonce != nonce {
+		return nil, errors.New("nonce does not match the login")
+	}
+	var mc jwt.MapClaims
+	if err := tok.Claims(&mc); err != nil {
+		return nil, err
+	}
+	// With several audiences, azp must name this client (OIDC Core 3.1.3.7).
+	if len(tok.Audience) > 1 {
+		if azp, _ := mc["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("azp %q is not this client", azp)
+		}
+	}
+	if tok.Subject == "" {
+		return nil, errors.New("no subject")
+	}
+	id := &IDToken{Subject: tok.Subject, Claims: mc}
+	id.Email, _ = mc["email"].(string)
+	id.EmailVerified, _ = mc["email_verified"].(bool)
+	id.Name, _ = mc["name"].(string)
+	return id, nil
+}
@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// fakeIdP is an OpenID provider with one client, "uniassist", whose codes
+// the test mints with authorize.
+type fakeIdP struct {
+	*httptest.Server
+	key *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant is what an authorization code was issued for.
+type grant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{key: key, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+			"end_session_endpoint":   idp.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "EC", "kid": "idp-1", "crv": "P-256", "use": "sig",
+			"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		oauthError := func(code string) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+		}
+		if id, secret, _ := r.BasicAuth(); id != "uniassist" || secret != "hunter2" {
+			oauthError("invalid_client")
+			return
+		}
+		idp.mu.Lock()
+		g, ok := idp.codes[r.PostFormValue("code")]
+		delete(idp.codes, r.PostFormValue("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || r.PostFormValue("grant_type") != "authorization_code" || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+			oauthError("invalid_grant")
+			return
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodES256, g.claims)
+		tok.Header["kid"] = "idp-1"
+		signed, err := tok.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": signed})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize plays the user signing in at authURL, returning the code the
+// provider would send back with claims, over defaults, in its ID token.
+func (idp *fakeIdP) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != "uniassist" || q.Get("code_challenge_method") != "S256" || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("authorize request: %s", authURL)
+	}
+	now := time.Now()
+	all := jwt.MapClaims{
+		"iss": idp.URL, "aud": "uniassist", "sub": "u-42", "email": "ada@example.edu", "name": "Ada",
+		"nonce": q.Get("nonce"), "iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+	code := auth.NewSessionID()
+	idp.mu.Lock()
+	idp.codes[code] = grant{challenge: q.Get("code_challenge"), claims: all}
+	idp.mu.Unlock()
+	return code
+}
+
+func newHandler(t *testing.T, idp *fakeIdP) *Handler {
+	t.Helper()
+	p, err := NewProvider(context.Background(), Options{
+		IssuerURL:    idp.URL,
+		ClientID:     "uniassist",
+		ClientSecret: "hunter2",
+		RedirectURL:  "https://apply.example.edu/auth/callback",
+		Scopes:       []string{"email", "profile"},
+		Client:       idp.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := auth.NewIssuer("s3cret")
+	issuer.Sessions = auth.NewMemorySessions()
+	return &Handler{
+		Provider:      p,
+		Issuer:        issuer,
+		Users:         store.NewMemoryUserStore(),
+		StateKey:      []byte("state-key"),
+		RoleClaim:     "groups",
+		DefaultRole:   "student",
+		PostLogoutURL: "https://apply.example.edu/",
+	}
+}
+
+// login starts a login, returning the state cookie and provider URL.
+func login(t *testing.T, h *Handler) (*http.Cookie, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status %d", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != StateCookie || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("login cookies: %+v", cookies)
+	}
+	return cookies[0], rec.Header().Get("Location")
+}
+
+func callback(h *Handler, cookie *http.Cookie, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?"+query.Encode(), nil)
+	// TenantResolver places the callback at the tenant it reached.
+	req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{ID: "north"}))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.Callback(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q: %v", rec.Body, err)
+	}
+	return body.Code
+}
+
+func TestLoginFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	h := newHandler(t, idp)
+
+	cookie, authURL := login(t, h)
+	state := mustQuery(t, authURL).Get("state")
+	code := idp.authorize(t, authURL, jwt.MapClaims{"groups": []any{"staff", "advisor"}})
+	rec := callback(h, cookie, url.Values{"code": {code}, "state": {state}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", rec.Code, rec.Body)
+	}
+	var pair auth.TokenPair
+	if err := json.Unmarshal(rec.Body.Bytes(), &pair); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := h.Issuer.Parse(pair.AccessToken, auth.TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != "advisor" || claims.SessionID == "" || claims.TenantID != "north" {
+		t.Errorf("claims = %+v", claims)
+	}
+	user, err := h.Users.GetByID(context.Background(), claims.Subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Issuer != idp.URL || user.Subject != "u-42" || user.Email != "ada@example.edu" {
+		t.Errorf("user = %+v", user)
+	}
+
+	// Logging out ends the session at the provider and here.
+	req := httptest.NewRequest(http.MethodGet, "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	rec = httptest.NewRecorder()
+	h.Logout(rec, req)
+	var out struct {
+		LoggedOut     bool   `json:"logged_out"`
+		EndSessionURL string `json:"end_session_url"`
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &out) != nil || !out.LoggedOut {
+		t.Fatalf("logout: %d %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(out.EndSessionURL, idp.URL+"/logout?") || mustQuery(t, out.EndSessionURL).Get("post_logout_redirect_uri") != h.PostLogoutURL {
+		t.Errorf("end_session_url = %q", out.EndSessionURL)
+	}
+	rec = httptest.NewRecorder()
+	auth.RefreshHandler(h.Issuer).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"`+pair.RefreshToken+`"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: %d, want 401", rec.Code)
+	}
+
+	// A second login keeps the role the account was given.
+	cookie, authURL = login(t, h)
+	code = idp.authorize(t, authURL, nil)
+	rec = callback(h, cookie, url.Values{"code": {code}, "state": {mustQuery(t, authURL).Get("state")}})
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &pair) != nil {
+		t.Fatalf("second callback: %d %s", rec.Code, rec.Body)
+	}
+	if again, err := h.Issuer.Parse(pair.AccessToken, auth.TokenTypeAccess); err != nil || again.Subject != claims.Subject || again.Role != "advisor" {
+		t.Errorf("second login claims = %+v, %v", again, err)
+	}
+}
+
+func TestCallbackRejects(t *testing.T) {
+	idp := newFakeIdP(t)
+	h := newHandler(t, idp)
+
+	for _, tc := range []struct {
+		name   string
+		claims jwt.MapClaims
+		// tamper alters the callback; nil sends it as the provider would.
+		tamper func(cookie *http.Cookie, q url.Values) *http.Cookie
+		status int
+		code   string
+	}{
+		{"no cookie", nil, func(*http.Cookie, url.Values) *http.Cookie { return nil }, http.StatusBadRequest, "INVALID_STATE"},
+		{"state mismatch", nil, func(c *http.Cookie, q url.Values) *http.Cookie { q.Set("state", "forged"); return c }, http.StatusBadRequest, "INVALID_STATE"},
+		{"forged cookie", nil, func(c *http.Cookie, q url.Values) *http.Cookie {
+			enc, _, _ := strings.Cut(c.Value, ".")
+			return &http.Cookie{Name: c.Name, Value: enc + ".AAAA"}
+		}, http.StatusBadRequest, "INVALID_STATE"},
+		{"provider error", nil, func(c *http.Cookie, q url.Values) *http.Cookie { q.Set("error", "access_denied"); return c }, http.StatusUnauthorized, "LOGIN_FAILED"},
+		{"nonce mismatch", jwt.MapClaims{"nonce": "replayed"}, nil, http.StatusUnauthorized, "LOGIN_FAILED"},
+		{"wrong audience", jwt.MapClaims{"aud": "another-app"}, nil, http.StatusUnauthorized, "LOGIN_FAILED"},
+		{"expired token", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, nil, http.StatusUnauthorized, "LOGIN_FAILED"},
+		{"wrong verifier", nil, func(c *http.Cookie, q url.Values) *http.Cookie {
+			st, err := h.verify(c.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			st.Verifier = randomToken()
+			return &http.Cookie{Name: c.Name, Value: h.sign(st)}
+		}, http.StatusUnauthorized, "LOGIN_FAILED"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cookie, authURL := login(t, h)
+			q := url.Values{"code": {idp.authorize(t, authURL, tc.claims)}, "state": {mustQuery(t, authURL).Get("state")}}
+			if tc.tamper != nil {
+				cookie = tc.tamper(cookie, q)
+			}
+			rec := callback(h, cookie, q)
+			if rec.Code != tc.status || errorCode(t, rec) != tc.code {
+				t.Errorf("got %d %s, want %d %s", rec.Code, rec.Body, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestStateExpires(t *testing.T) {
+	idp := newFakeIdP(t)
+	h := newHandler(t, idp)
+	cookie, authURL := login(t, h)
+	h.now = func() time.Time { return time.Now().Add(stateTTL + time.Minute) }
+	rec := callback(h, cookie, url.Values{"code": {idp.authorize(t, authURL, nil)}, "state": {mustQuery(t, authURL).Get("state")}})
+	if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "INVALID_STATE" {
+		t.Errorf("got %d %s, want 400 INVALID_STATE", rec.Code, rec.Body)
+	}
+}
+
+func TestNewProviderIssuerMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	_, err := NewProvider(context.Background(), Options{IssuerURL: idp.URL + "/", ClientID: "uniassist", Client: idp.Client()})
+	if err == nil || !strings.Contains(err.Error(), "did not match") {
+		t.Errorf("err = %v, want an issuer mismatch", err)
+	}
+	if errors.Is(err, ErrLogin) {
+		t.Errorf("discovery failure wraps ErrOIDC: %v", err)
+	}
+}
+
+func TestLoginRequiresTOTP(t *testing.T) {
+	idp := newFakeIdP(t)
+	h := newHandler(t, idp)
+	h.TOTP = auth.NewMemoryTOTPStore()
+	h.MFARoles = []string{"admin", "advisor"}
+	loginAs := func(groups ...any) *auth.Claims {
+		t.Helper()
+		cookie, authURL := login(t, h)
+		code := idp.authorize(t, authURL, jwt.MapClaims{"sub": "u-" + groups[0].(string), "groups": groups})
+		rec := callback(h, cookie, url.Values{"code": {code}, "state": {mustQuery(t, authURL).Get("state")}})
+		var pair auth.TokenPair
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &pair) != nil {
+			t.Fatalf("callback: %d %s", rec.Code, rec.Body)
+		}
+		claims, err := h.Issuer.Parse(pair.AccessToken, auth.TokenTypeAccess)
+		if err != nil || claims.MFAPending != pair.MFAPending {
+			t.Fatalf("claims = %+v, %v", claims, err)
+		}
+		return claims
+	}
+
+	if c := loginAs("advisor"); !c.MFAPending {
+		t.Error("advisor login not marked mfa_pending")
+	}
+	student := loginAs("student")
+	if student.MFAPending {
+		t.Error("student login marked mfa_pending")
+	}
+	// A student who enrolls is asked for codes too.
+	s, _ := auth.TOTPEnrollment(student.Subject)
+	h.TOTP.SetSecret(context.Background(), student.Subject, s.Secret, true)
+	if c := loginAs("student"); !c.MFAPending {
+		t.Error("enrolled student login not marked mfa_pending")
+	}
+
+	// Refreshing a pending pair keeps the mark.
+	pair, _ := h.Issuer.IssuePending("u-1", "admin", "", "s-1")
+	rec := httptest.NewRecorder()
+	auth.RefreshHandler(h.Issuer).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"`+pair.RefreshToken+`"}`)))
+	json.Unmarshal(rec.Body.Bytes(), &pair)
+	if claims, err := h.Issuer.Parse(pair.AccessToken, auth.TokenTypeAccess); err != nil || !claims.MFAPending {
+		t.Errorf("refreshed pending claims = %+v, %v", claims, err)
+	}
+}
+
+func mustQuery(t *testing.T, raw string) url.Values {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
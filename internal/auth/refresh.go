@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshHandler serves POST /auth/refresh: it exchanges a valid refresh
+// token for a new token pair in the same session and tenant, still marked
+// mfa_pending if it was, unless the session has been revoked. The route must be registered as public since the caller's
+// access token has usually already expired.
+func RefreshHandler(issuer *Issuer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req refreshRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil || req.RefreshToken == "" {
+			respond.Error(w, http.StatusBadRequest, "INVALID_REQUEST", "refresh_token is required")
+			return
+		}
+		claims, err := issuer.Parse(req.RefreshToken, TokenTypeRefresh)
+		if err != nil {
+			respond.Error(w, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "refresh token is invalid or expired")
+			return
+		}
+		if issuer.Sessions != nil && claims.SessionID != "" {
+			revoked, err := issuer.Sessions.Revoked(r.Context(), claims.SessionID)
+			if err != nil {
+				respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check session")
+				return
+			}
+			if revoked {
+				respond.Error(w, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "refresh token is invalid or expired")
+				return
+			}
+		}
+		pair, err := issuer.issue(*claims, claims.Subject)
+		if err != nil {
+			respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to issue token")
+			return
+		}
+		respond.JSON(w, http.StatusOK, pair)
+	})
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	issuer := NewIssuer("s3cret")
+	pair, err := issuer.IssueSession("advisor-7", "advisor", "north", "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := RefreshHandler(issuer)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"`+pair.RefreshToken+`"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got TokenPair
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := issuer.Parse(got.AccessToken, TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "advisor-7" || claims.Role != "advisor" || claims.Tenant() != "north" || claims.SessionID != "s-1" {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"`+pair.AccessToken+`"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("access token accepted as refresh token: %d", rec.Code)
+	}
+}
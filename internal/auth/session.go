@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionStore remembers revoked login sessions for as long as tokens
+// issued to them could still be presented.
+type SessionStore interface {
+	// Revoke ends session id; until is when its last token expires, after
+	// which it may be forgotten.
+	Revoke(ctx context.Context, id string, until time.Time) error
+	Revoked(ctx context.Context, id string) (bool, error)
+}
+
+// NewSessionID returns a random session ID.
+func NewSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("auth: crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// MemorySessions is an in-process SessionStore for a single replica.
+type MemorySessions struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemorySessions returns an empty MemorySessions.
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{revoked: map[string]time.Time{}, now: time.Now}
+}
+
+// Revoke implements SessionStore, dropping revocations that have lapsed.
+func (s *MemorySessions) Revoke(_ context.Context, id string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for sid, t := range s.revoked {
+		if !t.After(now) {
+			delete(s.revoked, sid)
+		}
+	}
+	s.revoked[id] = until
+	return nil
+}
+
+// Revoked implements SessionStore.
+func (s *MemorySessions) Revoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.revoked[id]
+	return ok, nil
+}
+
+// RedisSessions is a SessionStore shared by every replica through Redis,
+// so a logout at one is refused at all. Each revocation is a key,
+// Prefix+id, that expires with the session's last token.
+type RedisSessions struct {
+	Client RedisClient
+	// Prefix namespaces the keys; it defaults to "session:revoked:".
+	Prefix string
+
+	now func() time.Time
+}
+
+// NewRedisSessions returns a RedisSessions using client.
+func NewRedisSessions(client RedisClient) *RedisSessions {
+	return &RedisSessions{Client: client, Prefix: "session:revoked:", now: time.Now}
+}
+
+// Revoke implements SessionStore. A session whose tokens have already
+// expired needs no key.
+func (s *RedisSessions) Revoke(ctx context.Context, id string, until time.Time) error {
+	ttl := until.Sub(s.now())
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.Client.Set(ctx, s.Prefix+id, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("auth: revoke session: %w", err)
+	}
+	return nil
+}
+
+// Revoked implements SessionStore.
+func (s *RedisSessions) Revoked(ctx context.Context, id string) (bool, error) {
+	err := s.Client.Get(ctx, s.Prefix+id).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("auth: check session: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionStores(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]SessionStore{
+		"memory": NewMemorySessions(),
+		"redis":  NewRedisSessions(newFakeRedis()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if revoked, err := store.Revoked(ctx, "s-1"); err != nil || revoked {
+				t.Fatalf("before Revoke: %v, %v", revoked, err)
+			}
+			if err := store.Revoke(ctx, "s-1", time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := store.Revoked(ctx, "s-1"); err != nil || !revoked {
+				t.Errorf("after Revoke: %v, %v", revoked, err)
+			}
+			if revoked, _ := store.Revoked(ctx, "s-2"); revoked {
+				t.Error("another session is revoked")
+			}
+		})
+	}
+}
+
+func TestRedisSessionsExpire(t *testing.T) {
+	f := newFakeRedis()
+	s := NewRedisSessions(f)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := s.Revoke(ctx, "s-1", now.Add(DefaultRefreshTTL)); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := f.ttls["session:revoked:s-1"]; ttl != DefaultRefreshTTL {
+		t.Errorf("TTL = %v, want %v", ttl, DefaultRefreshTTL)
+	}
+	// Tokens that have all expired need no revocation.
+	if err := s.Revoke(ctx, "s-2", now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.data["session:revoked:s-2"]; ok {
+		t.Error("stored the revocation of an expired session")
+	}
+}
@@ -0,0 +1,179 @@
+// Package auth issues and parses the HS256 tokens used by the admissions API.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// Token types carried in the `typ` claim so a refresh token can never be
+// presented as an access token and vice versa.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Default lifetimes used when an Issuer leaves its TTLs unset.
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// ErrInvalidToken is returned for malformed, expired, or mistyped tokens.
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Claims is the payload of every token issued by this service.
+type Claims struct {
+	Role      string `json:"role,omitempty"`
+	TokenType string `json:"typ"`
+	// SessionID ties tokens to the login that issued them, so logging out
+	// can revoke them; see SessionStore.
+	SessionID string `json:"sid,omitempty"`
+	// MFAPending marks the tokens of a login that still owes its second
+	// factor; middleware.RequireMFA keeps them from everything but
+	// /auth/totp until a code is verified.
+	MFAPending bool `json:"mfa_pending,omitempty"`
+	// TenantID is the tenant the login happened at; middleware.
+	// TenantResolver refuses the token for requests to any other.
+	TenantID string `json:"tenant_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Tenant returns the tenant the token was issued for. Tokens without the
+// claim, issued before tenants or by Issue, belong to tenant.DefaultID.
+func (c *Claims) Tenant() string {
+	if c.TenantID == "" {
+		return tenant.DefaultID
+	}
+	return c.TenantID
+}
+
+// TokenPair is the response body of a successful login or refresh.
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// MFAPending tells the client to ask for a TOTP code and send it to
+	// /auth/totp/verify for a pair without the mark.
+	MFAPending bool `json:"mfa_pending,omitempty"`
+}
+
+// Issuer signs access and refresh tokens with a shared secret.
+type Issuer struct {
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Sessions, if set, is checked by RefreshHandler, so the refresh
+	// tokens of a revoked session stop working.
+	Sessions SessionStore
+
+	// Now overrides the clock in tests.
+	Now func() time.Time
+}
+
+// NewIssuer returns an Issuer with the default token lifetimes.
+func NewIssuer(secret string) *Issuer {
+	return &Issuer{
+		Secret:     []byte(secret),
+		AccessTTL:  DefaultAccessTTL,
+		RefreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// Issue mints a fresh access/refresh pair for the subject in the default
+// tenant.
+func (i *Issuer) Issue(subject, role string) (TokenPair, error) {
+	return i.IssueSession(subject, role, "", "")
+}
+
+// IssueSession is Issue for a pair belonging to login session sessionID
+// at tenant tenantID; an empty tenantID is tenant.DefaultID.
+func (i *Issuer) IssueSession(subject, role, tenantID, sessionID string) (TokenPair, error) {
+	return i.issue(Claims{Role: role, TenantID: tenantID, SessionID: sessionID}, subject)
+}
+
+// IssuePending is IssueSession for a login that still owes a TOTP code:
+// both tokens carry mfa_pending, refreshing keeps it, and only a pair
+// issued once the code is verified drops it.
+func (i *Issuer) IssuePending(subject, role, tenantID, sessionID string) (TokenPair, error) {
+	return i.issue(Claims{Role: role, TenantID: tenantID, SessionID: sessionID, MFAPending: true}, subject)
+}
+
+// issue signs a pair carrying base's role, tenant, session, and MFA mark;
+// sign replaces the rest, so a refreshed token's claims can be passed.
+func (i *Issuer) issue(base Claims, subject string) (TokenPair, error) {
+	now := i.now()
+	accessExp := now.Add(orDefault(i.AccessTTL, DefaultAccessTTL))
+	access, err := i.sign(base, subject, TokenTypeAccess, now, accessExp)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := i.sign(base, subject, TokenTypeRefresh, now, now.Add(orDefault(i.RefreshTTL, DefaultRefreshTTL)))
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresAt:    accessExp,
+		MFAPending:   base.MFAPending,
+	}, nil
+}
+
+// Parse validates the signature, expiry, and type of a token.
+func (i *Issuer) Parse(token, wantType string) (*Claims, error) {
+	return Parse(i.Secret, token, wantType, i.now)
+}
+
+// Parse validates an HS256 token signed with secret. A nil now uses the
+// wall clock.
+func Parse(secret []byte, token, wantType string, now func() time.Time) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()}
+	if now != nil {
+		opts = append(opts, jwt.WithTimeFunc(now))
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return secret, nil }, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.TokenType != wantType {
+		return nil, fmt.Errorf("%w: expected %s token, got %q", ErrInvalidToken, wantType, claims.TokenType)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// sign signs claims, given the role, session, and MFA mark, as a token
+// of type typ for subject.
+func (i *Issuer) sign(claims Claims, subject, typ string, issuedAt, expiresAt time.Time) (string, error) {
+	claims.TokenType = typ
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.Secret)
+}
+
+func (i *Issuer) now() time.Time {
+	if i.Now != nil {
+		return i.Now()
+	}
+	return time.Now()
+}
+
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
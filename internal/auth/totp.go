@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TOTPIssuer names the service in authenticator apps.
+const TOTPIssuer = "UniAssist"
+
+// TOTP parameters, those every authenticator app supports: RFC 6238 with
+// HMAC-SHA1, six digits, a 30 second step, and codes of the steps either
+// side of the current one accepted for clock drift.
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+var (
+	// ErrTOTPNotEnrolled is returned for users without a TOTP secret.
+	ErrTOTPNotEnrolled = errors.New("auth: TOTP not enrolled")
+	// ErrTOTPInvalid is returned for a code that is wrong, out of the
+	// window, or already used.
+	ErrTOTPInvalid = errors.New("auth: invalid TOTP code")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPSecret is a new second factor, shown once to be added to an
+// authenticator app, typically by scanning URI as a QR code.
+type TOTPSecret struct {
+	// Secret is the key in base32, for entering by hand.
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TOTPEnrollment generates a secret for userID. It is not stored: the
+// caller saves it unconfirmed in a TOTPStore, and the first code
+// TOTPVerify accepts confirms it.
+func TOTPEnrollment(userID string) (*TOTPSecret, error) {
+	var key [20]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, fmt.Errorf("auth: generate TOTP secret: %w", err)
+	}
+	secret := totpEncoding.EncodeToString(key[:])
+	q := url.Values{
+		"secret":    {secret},
+		"issuer":    {TOTPIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {strconv.Itoa(totpDigits)},
+		"period":    {strconv.Itoa(totpPeriod)},
+	}
+	uri := "otpauth://totp/" + url.PathEscape(TOTPIssuer+":"+userID) + "?" + q.Encode()
+	return &TOTPSecret{Secret: secret, URI: uri}, nil
+}
+
+// TOTPVerify checks code against userID's secret in store. Each code is
+// accepted once: the time step it matched is marked used for as long as
+// the code stays in the window, so a code seen over someone's shoulder
+// or replayed from a log is refused. The first code accepted for an
+// unconfirmed secret confirms it.
+func TOTPVerify(ctx context.Context, userID, code string, store TOTPStore) error {
+	return totpVerify(ctx, userID, code, store, time.Now())
+}
+
+func totpVerify(ctx context.Context, userID, code string, store TOTPStore, now time.Time) error {
+	secret, confirmed, err := store.Secret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return fmt.Errorf("auth: TOTP secret of %s: %w", userID, err)
+	}
+	if len(code) != totpDigits {
+		return ErrTOTPInvalid
+	}
+	cur := uint64(now.Unix()) / totpPeriod
+	var step uint64
+	matched := 0
+	for s := cur - totpSkew; s <= cur+totpSkew; s++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, s)), []byte(code)) == 1 {
+			step, matched = s, 1
+		}
+	}
+	if matched == 0 {
+		return ErrTOTPInvalid
+	}
+	until := time.Unix(int64(step+totpSkew+1)*totpPeriod, 0)
+	first, err := store.MarkUsed(ctx, userID, step, until.Sub(now))
+	if err != nil {
+		return err
+	}
+	if !first {
+		return fmt.Errorf("%w: code already used", ErrTOTPInvalid)
+	}
+	if !confirmed {
+		return store.SetSecret(ctx, userID, secret, true)
+	}
+	return nil
+}
+
+// hotp is the RFC 4226 code of key for counter.
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	m := hmac.New(sha1.New, key)
+	m.Write(msg[:])
+	sum := m.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1_000_000)
+}
+
+// TOTPStore holds users' TOTP secrets and the codes they have used.
+type TOTPStore interface {
+	// Secret returns userID's secret and whether a code has confirmed
+	// it, or ErrTOTPNotEnrolled.
+	Secret(ctx context.Context, userID string) (secret string, confirmed bool, err error)
+	SetSecret(ctx context.Context, userID, secret string, confirmed bool) error
+	// DeleteSecret unenrolls userID; it is not an error if they were not.
+	DeleteSecret(ctx context.Context, userID string) error
+	// MarkUsed records that userID used the code of time step step, for
+	// ttl, reporting false if it already had been.
+	MarkUsed(ctx context.Context, userID string, step uint64, ttl time.Duration) (bool, error)
+}
+
+type totpRecord struct {
+	Secret    string `json:"secret"`
+	Confirmed bool   `json:"confirmed"`
+}
+
+// MemoryTOTPStore is an in-process TOTPStore for a single replica.
+type MemoryTOTPStore struct {
+	mu      sync.Mutex
+	secrets map[string]totpRecord
+	used    map[string]time.Time // userID:step -> expiry
+	now     func() time.Time
+}
+
+// NewMemoryTOTPStore returns an empty MemoryTOTPStore.
+func NewMemoryTOTPStore() *MemoryTOTPStore {
+	return &MemoryTOTPStore{secrets: map[string]totpRecord{}, used: map[string]time.Time{}, now: time.Now}
+}
+
+// Secret implements TOTPStore.
+func (s *MemoryTOTPStore) Secret(_ context.Context, userID string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.secrets[userID]
+	if !ok {
+		return "", false, ErrTOTPNotEnrolled
+	}
+	return rec.Secret, rec.Confirmed, nil
+}
+
+// SetSecret implements TOTPStore.
+func (s *MemoryTOTPStore) SetSecret(_ context.Context, userID, secret string, confirmed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[userID] = totpRecord{Secret: secret, Confirmed: confirmed}
+	return nil
+}
+
+// DeleteSecret implements TOTPStore.
+func (s *MemoryTOTPStore) DeleteSecret(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, userID)
+	return nil
+}
+
+// MarkUsed implements TOTPStore, dropping marks that have lapsed.
+func (s *MemoryTOTPStore) MarkUsed(_ context.Context, userID string, step uint64, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, t := range s.used {
+		if !t.After(now) {
+			delete(s.used, k)
+		}
+	}
+	key := userID + ":" + strconv.FormatUint(step, 10)
+	if _, ok := s.used[key]; ok {
+		return false, nil
+	}
+	s.used[key] = now.Add(ttl)
+	return true, nil
+}
+
+// RedisClient is the part of a go-redis client RedisTOTPStore and
+// RedisSessions use; *redis.Client and *redis.ClusterClient implement it.
+type RedisClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, ttl time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value any, ttl time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// RedisTOTPStore is a TOTPStore shared by every replica through Redis, so
+// a code used at one cannot be used again at another. Secrets are kept
+// under Prefix+"secret:"+userID and used codes, with their TTL, under
+// Prefix+"used:"+userID+":"+step.
+type RedisTOTPStore struct {
+	Client RedisClient
+	// Prefix namespaces the keys; it defaults to "totp:".
+	Prefix string
+}
+
+// NewRedisTOTPStore returns a RedisTOTPStore using client.
+func NewRedisTOTPStore(client RedisClient) *RedisTOTPStore {
+	return &RedisTOTPStore{Client: client, Prefix: "totp:"}
+}
+
+// Secret implements TOTPStore.
+func (s *RedisTOTPStore) Secret(ctx context.Context, userID string) (string, bool, error) {
+	b, err := s.Client.Get(ctx, s.Prefix+"secret:"+userID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return "", false, ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("auth: load TOTP secret: %w", err)
+	}
+	var rec totpRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return "", false, fmt.Errorf("auth: decode TOTP secret: %w", err)
+	}
+	return rec.Secret, rec.Confirmed, nil
+}
+
+// SetSecret implements TOTPStore.
+func (s *RedisTOTPStore) SetSecret(ctx context.Context, userID, secret string, confirmed bool) error {
+	b, _ := json.Marshal(totpRecord{Secret: secret, Confirmed: confirmed})
+	if err := s.Client.Set(ctx, s.Prefix+"secret:"+userID, b, 0).Err(); err != nil {
+		return fmt.Errorf("auth: store TOTP secret: %w", err)
+	}
+	return nil
+}
+
+// DeleteSecret implements TOTPStore.
+func (s *RedisTOTPStore) DeleteSecret(ctx context.Context, userID string) error {
+	if err := s.Client.Del(ctx, s.Prefix+"secret:"+userID).Err(); err != nil {
+		return fmt.Errorf("auth: delete TOTP secret: %w", err)
+	}
+	return nil
+}
+
+// MarkUsed implements TOTPStore with SET NX, so of two replicas
+// accepting the same code at once only one wins.
+func (s *RedisTOTPStore) MarkUsed(ctx context.Context, userID string, step uint64, ttl time.Duration) (bool, error) {
+	key := s.Prefix + "used:" + userID + ":" + strconv.FormatUint(step, 10)
+	first, err := s.Client.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("auth: mark TOTP code used: %w", err)
+	}
+	return first, nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// TOTPHandler serves the second-factor endpoints. They take the caller's
+// access token as a Bearer token themselves, because a login still marked
+// mfa_pending must reach them, so they are registered as public routes
+// outside JWTAuth and RequireMFA:
+//
+//	POST   /auth/totp/enroll  a new secret and otpauth:// URI
+//	POST   /auth/totp/verify  {"code": "123456"}, answered with a token pair
+//	DELETE /auth/totp         unenroll
+type TOTPHandler struct {
+	Issuer *Issuer
+	Store  TOTPStore
+}
+
+type totpRequest struct {
+	Code string `json:"code"`
+}
+
+// Enroll handles POST /auth/totp/enroll. It replaces any secret not yet
+// confirmed, so a user who lost the QR code can start over, but refuses
+// once one is: changing a working second factor takes unenrolling first,
+// with a session that has verified it.
+func (h *TOTPHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	_, confirmed, err := h.Store.Secret(r.Context(), claims.Subject)
+	if err != nil && !errors.Is(err, ErrTOTPNotEnrolled) {
+		h.internalError(w, r, "load TOTP secret", err)
+		return
+	}
+	if confirmed {
+		respond.Error(w, http.StatusConflict, "TOTP_ALREADY_ENROLLED", "a TOTP authenticator is already enrolled; unenroll it first")
+		return
+	}
+	secret, err := TOTPEnrollment(claims.Subject)
+	if err != nil {
+		h.internalError(w, r, "generate TOTP secret", err)
+		return
+	}
+	if err := h.Store.SetSecret(r.Context(), claims.Subject, secret.Secret, false); err != nil {
+		h.internalError(w, r, "store TOTP secret", err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respond.JSON(w, http.StatusCreated, secret)
+}
+
+// Verify handles POST /auth/totp/verify. A good code confirms a new
+// enrollment and is answered with a token pair for the same session
+// without the mfa_pending mark.
+func (h *TOTPHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	var req totpRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<12)).Decode(&req); err != nil || req.Code == "" {
+		respond.Error(w, http.StatusBadRequest, "INVALID_REQUEST", "code is required")
+		return
+	}
+	switch err := TOTPVerify(r.Context(), claims.Subject, strings.TrimSpace(req.Code), h.Store); {
+	case errors.Is(err, ErrTOTPNotEnrolled):
+		respond.Error(w, http.StatusNotFound, "TOTP_NOT_ENROLLED", "no TOTP authenticator is enrolled; enroll one at /auth/totp/enroll")
+		return
+	case errors.Is(err, ErrTOTPInvalid):
+		respond.Error(w, http.StatusUnauthorized, "INVALID_TOTP_CODE", "code is wrong, expired, or already used")
+		return
+	case err != nil:
+		h.internalError(w, r, "verify TOTP code", err)
+		return
+	}
+	pair, err := h.Issuer.IssueSession(claims.Subject, claims.Role, claims.TenantID, claims.SessionID)
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to issue token")
+		return
+	}
+	respond.JSON(w, http.StatusOK, pair)
+}
+
+// Unenroll handles DELETE /auth/totp. It needs a session that has passed
+// its second factor, so a stolen first factor cannot remove it; a role
+// in oidc.Handler.MFARoles has to enroll again at its next login.
+func (h *TOTPHandler) Unenroll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	if claims.MFAPending {
+		respond.Error(w, http.StatusUnauthorized, "MFA_REQUIRED", "verify a TOTP code at /auth/totp/verify first")
+		return
+	}
+	if _, _, err := h.Store.Secret(r.Context(), claims.Subject); errors.Is(err, ErrTOTPNotEnrolled) {
+		respond.Error(w, http.StatusNotFound, "TOTP_NOT_ENROLLED", "no TOTP authenticator is enrolled")
+		return
+	} else if err != nil {
+		h.internalError(w, r, "load TOTP secret", err)
+		return
+	}
+	if err := h.Store.DeleteSecret(r.Context(), claims.Subject); err != nil {
+		h.internalError(w, r, "delete TOTP secret", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// caller checks the request's access token as JWTAuth and RejectRevoked
+// would, without refusing one marked mfa_pending.
+func (h *TOTPHandler) caller(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing bearer token")
+		return nil, false
+	}
+	claims, err := h.Issuer.Parse(strings.TrimSpace(token), TokenTypeAccess)
+	if err != nil {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
+		return nil, false
+	}
+	if claims.SessionID != "" && h.Issuer.Sessions != nil {
+		revoked, err := h.Issuer.Sessions.Revoked(r.Context(), claims.SessionID)
+		if err != nil {
+			respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check session")
+			return nil, false
+		}
+		if revoked {
+			respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "session has been logged out")
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+func (h *TOTPHandler) internalError(w http.ResponseWriter, r *http.Request, what string, err error) {
+	logging.FromContext(r.Context()).Error(what, "error", err)
+	respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to "+what)
+}
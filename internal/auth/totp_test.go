@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, cut to six digits.
+	key := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		if got := hotp(key, uint64(unix)/totpPeriod); got != want {
+			t.Errorf("code at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	s, err := TOTPEnrollment("u-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := totpEncoding.DecodeString(s.Secret); err != nil || len(key) != 20 {
+		t.Fatalf("secret %q: %d bytes, %v", s.Secret, len(key), err)
+	}
+	u, err := url.Parse(s.URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/UniAssist:u-42" ||
+		q.Get("secret") != s.Secret || q.Get("issuer") != TOTPIssuer || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI = %s", s.URI)
+	}
+	if again, _ := TOTPEnrollment("u-42"); again.Secret == s.Secret {
+		t.Error("two enrollments got the same secret")
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]TOTPStore{
+		"memory": NewMemoryTOTPStore(),
+		"redis":  NewRedisTOTPStore(newFakeRedis()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := totpVerify(ctx, "u-1", "123456", store, time.Now()); !errors.Is(err, ErrTOTPNotEnrolled) {
+				t.Fatalf("unenrolled: err = %v", err)
+			}
+			s, _ := TOTPEnrollment("u-1")
+			if err := store.SetSecret(ctx, "u-1", s.Secret, false); err != nil {
+				t.Fatal(err)
+			}
+			key, _ := totpEncoding.DecodeString(s.Secret)
+			now := time.Unix(1_800_000_015, 0)
+			step := uint64(now.Unix()) / totpPeriod
+			code := func(s uint64) string { return hotp(key, s) }
+
+			for _, bad := range []string{"", "12345", "1234567", code(step - 2), code(step + 2)} {
+				if bad == code(step) {
+					continue
+				}
+				if err := totpVerify(ctx, "u-1", bad, store, now); !errors.Is(err, ErrTOTPInvalid) {
+					t.Errorf("code %q: err = %v, want ErrTOTPInvalid", bad, err)
+				}
+			}
+			if err := totpVerify(ctx, "u-1", code(step-1), store, now); err != nil {
+				t.Fatalf("previous step's code: %v", err)
+			}
+			if _, confirmed, _ := store.Secret(ctx, "u-1"); !confirmed {
+				t.Error("first good code did not confirm the enrollment")
+			}
+			// A code is used up, even later in its window.
+			if err := totpVerify(ctx, "u-1", code(step-1), store, now.Add(20*time.Second)); !errors.Is(err, ErrTOTPInvalid) {
+				t.Errorf("reused code: err = %v, want ErrTOTPInvalid", err)
+			}
+			if err := totpVerify(ctx, "u-1", code(step+1), store, now); err != nil {
+				t.Errorf("next step's code: %v", err)
+			}
+		})
+	}
+}
+
+func TestRedisTOTPStoreKeys(t *testing.T) {
+	f := newFakeRedis()
+	s := NewRedisTOTPStore(f)
+	ctx := context.Background()
+	first, err := s.MarkUsed(ctx, "u-1", 42, 75*time.Second)
+	if err != nil || !first {
+		t.Fatalf("MarkUsed = %v, %v", first, err)
+	}
+	if ttl := f.ttls["totp:used:u-1:42"]; ttl != 75*time.Second {
+		t.Errorf("used code TTL = %s, want until it leaves the window", ttl)
+	}
+	if again, _ := s.MarkUsed(ctx, "u-1", 42, time.Minute); again {
+		t.Error("a step was marked used twice")
+	}
+	s.SetSecret(ctx, "u-1", "ABC", true)
+	if _, ok := f.data["totp:secret:u-1"]; !ok || f.ttls["totp:secret:u-1"] != 0 {
+		t.Errorf("secret stored as %v", f.data)
+	}
+	s.DeleteSecret(ctx, "u-1")
+	if _, _, err := s.Secret(ctx, "u-1"); !errors.Is(err, ErrTOTPNotEnrolled) {
+		t.Errorf("after DeleteSecret: err = %v", err)
+	}
+}
+
+func TestTOTPHandler(t *testing.T) {
+	issuer := NewIssuer("s3cret")
+	issuer.Sessions = NewMemorySessions()
+	h := &TOTPHandler{Issuer: issuer, Store: NewMemoryTOTPStore()}
+	serve := func(handler http.HandlerFunc, method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	pending, err := issuer.IssuePending("admin-1", "admin", "", "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := serve(h.Enroll, "POST", "/auth/totp/enroll", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("enroll without a token: %d", rec.Code)
+	}
+	rec := serve(h.Enroll, "POST", "/auth/totp/enroll", pending.AccessToken, "")
+	var secret TOTPSecret
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &secret) != nil || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("enroll: %d %s", rec.Code, rec.Body)
+	}
+	key, _ := totpEncoding.DecodeString(secret.Secret)
+	code := hotp(key, uint64(time.Now().Unix())/totpPeriod)
+
+	if rec := serve(h.Verify, "POST", "/auth/totp/verify", pending.AccessToken, `{"code":"000000x"}`); errorCode(t, rec) != "INVALID_TOTP_CODE" {
+		t.Errorf("wrong code: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(h.Verify, "POST", "/auth/totp/verify", pending.AccessToken, `{"code":"`+code+`"}`)
+	var pair TokenPair
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &pair) != nil {
+		t.Fatalf("verify: %d %s", rec.Code, rec.Body)
+	}
+	claims, err := issuer.Parse(pair.AccessToken, TokenTypeAccess)
+	if err != nil || claims.MFAPending || pair.MFAPending || claims.SessionID != "s-1" || claims.Role != "admin" {
+		t.Fatalf("verified claims = %+v, %v", claims, err)
+	}
+	if rec := serve(h.Verify, "POST", "/auth/totp/verify", pending.AccessToken, `{"code":"`+code+`"}`); errorCode(t, rec) != "INVALID_TOTP_CODE" {
+		t.Errorf("replayed code: %d %s", rec.Code, rec.Body)
+	}
+
+	// A confirmed factor is neither replaced nor removed by a pending
+	// session.
+	if rec := serve(h.Enroll, "POST", "/auth/totp/enroll", pending.AccessToken, ""); errorCode(t, rec) != "TOTP_ALREADY_ENROLLED" {
+		t.Errorf("re-enroll: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(h.Unenroll, "DELETE", "/auth/totp", pending.AccessToken, ""); errorCode(t, rec) != "MFA_REQUIRED" {
+		t.Errorf("unenroll while pending: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(h.Unenroll, "DELETE", "/auth/totp", pair.AccessToken, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("unenroll: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(h.Verify, "POST", "/auth/totp/verify", pending.AccessToken, `{"code":"123456"}`); errorCode(t, rec) != "TOTP_NOT_ENROLLED" {
+		t.Errorf("verify after unenrolling: %d %s", rec.Code, rec.Body)
+	}
+
+	issuer.Sessions.Revoke(context.Background(), "s-1", time.Now().Add(time.Hour))
+	if rec := serve(h.Enroll, "POST", "/auth/totp/enroll", pending.AccessToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("enroll after logout: %d", rec.Code)
+	}
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q: %v", rec.Body, err)
+	}
+	return body.Code
+}
+
+// fakeRedis is an in-memory RedisClient.
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(string(v), nil)
+}
+
+func (f *fakeRedis) Set(_ context.Context, key string, value any, ttl time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key], f.ttls[key] = toBytes(value), ttl
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) SetNX(_ context.Context, key string, value any, ttl time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.data[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.data[key], f.ttls[key] = toBytes(value), ttl
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *fakeRedis) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range keys {
+		delete(f.data, k)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func toBytes(v any) []byte {
+	if b, ok := v.([]byte); ok {
+		return b
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
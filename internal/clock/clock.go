@@ -0,0 +1,24 @@
+// Package clock abstracts the current time so time-dependent rules can be
+// tested at exact instants.
+package clock
+
+import "time"
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function to Clock, e.g. clock.Func(func() time.Time { return t }).
+type Func func() time.Time
+
+// Now implements Clock.
+func (f Func) Now() time.Time { return f() }
+
+// System is the wall clock.
+var System Clock = Func(time.Now)
+
+// Fixed returns a Clock that always reports t.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
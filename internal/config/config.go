@@ -0,0 +1,313 @@
+// Package config is the typed configuration shared by the UniAssist Go
+// services. Load reads it from optional YAML files and UNIASSIST_
+// environment variables through pkg/config, then checks it against the
+// validate tags:
+//
+//	server:
+//	  port: 8080
+//	database:
+//	  host: postgres
+//	  name: uniassist
+//	  user: uniassist
+//
+// with the password from UNIASSIST_DATABASE__PASSWORD. Nested keys are
+// joined with a double underscore in variable names, so database.ssl_mode
+// is UNIASSIST_DATABASE__SSL_MODE. Passwords and keys are Secrets, which
+// print as [redacted], so a loaded Config can be logged whole.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	pkgconfig "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+)
+
+// Environment variable naming.
+const (
+	EnvPrefix    = "UNIASSIST_"
+	EnvSeparator = "__"
+)
+
+// Config is the full service configuration.
+type Config struct {
+	Server   Server     `config:"server"`
+	Database Database   `config:"database"`
+	Redis    Redis      `config:"redis"`
+	S3       S3         `config:"s3"`
+	SMTP     SMTP       `config:"smtp"`
+	JWT      JWT        `config:"jwt"`
+	OIDC     OIDC       `config:"oidc"`
+	CORS     CORSConfig `config:"cors"`
+}
+
+// Server is the HTTP listener.
+type Server struct {
+	Port              int           `config:"port" default:"8080" validate:"min=1,max=65535"`
+	MetricsPort       int           `config:"metrics_port" validate:"omitempty,min=1,max=65535,nefield=Port"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" default:"10s" validate:"gt=0"`
+	ShutdownGrace     time.Duration `config:"shutdown_grace" default:"20s" validate:"gt=0"`
+	LogLevel          string        `config:"log_level" default:"info" validate:"oneof=debug info warn error"`
+	LogFormat         string        `config:"log_format" default:"json" validate:"oneof=json text"`
+}
+
+// Database is the Postgres connection. It is optional: without a host
+// the services fall back to in-memory state.
+type Database struct {
+	Host     string           `config:"host" validate:"omitempty,hostname_rfc1123|ip"`
+	Port     int              `config:"port" default:"5432" validate:"min=1,max=65535"`
+	Name     string           `config:"name" validate:"required_with=Host"`
+	User     string           `config:"user" validate:"required_with=Host"`
+	Password pkgconfig.Secret `config:"password"`
+	SSLMode  string           `config:"ssl_mode" default:"require" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	MaxConns int              `config:"max_conns" default:"10" validate:"min=1"`
+}
+
+// Enabled reports whether a database is configured.
+func (d Database) Enabled() bool { return d.Host != "" }
+
+// URL returns the postgres:// connection string for pgx, password
+// included; do not log it.
+func (d Database) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, string(d.Password)),
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}, "pool_max_conns": {strconv.Itoa(d.MaxConns)}}.Encode(),
+	}
+	return u.String()
+}
+
+// Redis backs shared rate limits and queues. It is optional.
+type Redis struct {
+	Addr     string           `config:"addr" validate:"omitempty,hostname_port"`
+	Password pkgconfig.Secret `config:"password"`
+	DB       int              `config:"db" validate:"min=0,max=15"`
+}
+
+// S3 stores uploaded documents. Without a bucket they are kept on local
+// disk.
+type S3 struct {
+	Bucket string `config:"bucket"`
+	Region string `config:"region" validate:"required_with=Bucket"`
+	Prefix string `config:"prefix"`
+	// Endpoint points at an S3-compatible service instead of AWS.
+	Endpoint        string           `config:"endpoint" validate:"omitempty,url"`
+	AccessKeyID     string           `config:"access_key_id" validate:"required_with=SecretAccessKey"`
+	SecretAccessKey pkgconfig.Secret `config:"secret_access_key" validate:"required_with=AccessKeyID"`
+}
+
+// SMTP delivers applicant email. Without an address no mail is sent.
+type SMTP struct {
+	Addr     string           `config:"addr" validate:"omitempty,hostname_port"`
+	From     string           `config:"from" validate:"required_with=Addr"`
+	Username string           `config:"username"`
+	Password pkgconfig.Secret `config:"password" validate:"required_with=Username"`
+}
+
+// JWT signs and checks access and refresh tokens.
+type JWT struct {
+	Secret     pkgconfig.Secret `config:"secret" validate:"required,min=32"`
+	AccessTTL  time.Duration    `config:"access_ttl" default:"15m" validate:"gt=0"`
+	RefreshTTL time.Duration    `config:"refresh_ttl" default:"168h" validate:"gtfield=AccessTTL"`
+}
+
+// OIDC signs students and staff in through the university identity
+// provider. It is optional: without an issuer there is no SSO login.
+type OIDC struct {
+	// IssuerURL is the provider's issuer; its discovery document is read
+	// from /.well-known/openid-configuration under it.
+	IssuerURL    string           `config:"issuer_url" validate:"omitempty,url"`
+	ClientID     string           `config:"client_id" validate:"required_with=IssuerURL"`
+	ClientSecret pkgconfig.Secret `config:"client_secret" validate:"required_with=IssuerURL"`
+	// RedirectURL is this service's /auth/callback as registered with the
+	// provider.
+	RedirectURL string   `config:"redirect_url" validate:"required_with=IssuerURL,omitempty,url"`
+	Scopes      []string `config:"scopes" default:"openid,email,profile"`
+	// RoleClaim, if set, names an ID token claim holding the user's role,
+	// applied on every login. Otherwise a user keeps the role they were
+	// first given, DefaultRole.
+	RoleClaim   string `config:"role_claim"`
+	DefaultRole string `config:"default_role" default:"student" validate:"oneof=student advisor admin"`
+	// PostLogoutURL is where the provider sends the browser after logout.
+	PostLogoutURL string `config:"post_logout_url" validate:"omitempty,url"`
+}
+
+// Enabled reports whether SSO login is configured.
+func (o OIDC) Enabled() bool { return o.IssuerURL != "" }
+
+// CORSConfig lets browser scripts on other origins, such as the SPA's,
+// call the API. It is optional: without origins no CORS headers are sent.
+// See pkg/middleware/cors.Policy for the fields.
+type CORSConfig struct {
+	AllowedOrigins   []string      `config:"allowed_origins"`
+	AllowedMethods   []string      `config:"allowed_methods" default:"GET,HEAD,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string      `config:"allowed_headers" default:"Authorization,Content-Type,Idempotency-Key,X-Request-ID"`
+	ExposedHeaders   []string      `config:"exposed_headers" default:"Location,Retry-After,X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Idempotent-Replayed"`
+	AllowCredentials bool          `config:"allow_credentials"`
+	MaxAge           time.Duration `config:"max_age" default:"10m" validate:"min=0"`
+}
+
+// Enabled reports whether any origin is allowed.
+func (c CORSConfig) Enabled() bool { return len(c.AllowedOrigins) > 0 }
+
+// Policy returns c as a pkg/middleware/cors Policy, the zero Policy when
+// c is not Enabled.
+func (c CORSConfig) Policy() cors.Policy {
+	if !c.Enabled() {
+		return cors.Policy{}
+	}
+	return cors.Policy{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+// Validate rejects malformed origins, methods, and headers, and the "*"
+// origin with allow_credentials, which browsers refuse.
+func (c CORSConfig) Validate() error {
+	if err := c.Policy().Validate(); err != nil {
+		return fmt.Errorf("config: cors (%sCORS%s*): %w", EnvPrefix, EnvSeparator, err)
+	}
+	return nil
+}
+
+// Load reads paths in order, later files overriding earlier ones, then
+// the UNIASSIST_ environment variables, over the defaults. With no paths
+// only the environment is read. Parse errors are reported together, and
+// so, once everything parses, is every field failing its validate tag.
+func Load(paths ...string) (*Config, error) {
+	return load(paths)
+}
+
+// load is Load with extra pkg/config options, for tests.
+func load(paths []string, opts ...pkgconfig.Option) (*Config, error) {
+	opts = append([]pkgconfig.Option{
+		pkgconfig.WithFiles(paths...),
+		pkgconfig.WithEnvPrefix(EnvPrefix),
+		pkgconfig.WithEnvSeparator(EnvSeparator),
+		pkgconfig.WithArgs(nil),
+	}, opts...)
+	cfg, err := pkgconfig.Load[Config](opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.CORS.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadOIDC reads only the oidc section, from the same files and
+// variables as Load, for services that take the rest of their settings
+// from elsewhere.
+func LoadOIDC(paths ...string) (*OIDC, error) {
+	return loadOIDC(paths)
+}
+
+func loadOIDC(paths []string, opts ...pkgconfig.Option) (*OIDC, error) {
+	cfg, err := loadSection(paths, "OIDC", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.OIDC, nil
+}
+
+// LoadCORS reads only the cors section, like LoadOIDC, and validates it
+// as a whole too (see CORSConfig.Validate).
+func LoadCORS(paths ...string) (*CORSConfig, error) {
+	return loadCORS(paths)
+}
+
+func loadCORS(paths []string, opts ...pkgconfig.Option) (*CORSConfig, error) {
+	cfg, err := loadSection(paths, "CORS", opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.CORS.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg.CORS, nil
+}
+
+// loadSection loads a Config checking only the validate tags of the named
+// top-level field.
+func loadSection(paths []string, field string, opts ...pkgconfig.Option) (*Config, error) {
+	opts = append([]pkgconfig.Option{
+		pkgconfig.WithFiles(paths...),
+		pkgconfig.WithEnvPrefix(EnvPrefix),
+		pkgconfig.WithEnvSeparator(EnvSeparator),
+		pkgconfig.WithArgs(nil),
+		pkgconfig.AllowUnknownKeys(),
+	}, opts...)
+	cfg, err := pkgconfig.Load[Config](opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(&cfg, field); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+var validate = newValidator()
+
+// newValidator returns a function checking a Config's validate tags, or
+// only those of the named top-level fields. Its errors name each field by
+// config key and environment variable rather than Go field name.
+func newValidator() func(cfg *Config, only ...string) error {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("config"), ",")
+		return name
+	})
+	return func(cfg *Config, only ...string) error {
+		var err error
+		if len(only) > 0 {
+			// StructPartial wants the leaf fields, by Go name.
+			var fields []string
+			for _, name := range only {
+				sf, _ := reflect.TypeOf(cfg).Elem().FieldByName(name)
+				for i := range sf.Type.NumField() {
+					fields = append(fields, name+"."+sf.Type.Field(i).Name)
+				}
+			}
+			err = v.StructPartial(cfg, fields...)
+		} else {
+			err = v.Struct(cfg)
+		}
+		var fails validator.ValidationErrors
+		if !errors.As(err, &fails) {
+			return err
+		}
+		errs := make([]error, len(fails))
+		for i, fe := range fails {
+			// The namespace starts with the Config type name.
+			_, key, _ := strings.Cut(fe.Namespace(), ".")
+			env := EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", EnvSeparator))
+			rule := fe.Tag()
+			if fe.Param() != "" {
+				rule += "=" + fe.Param()
+			}
+			errs[i] = fmt.Errorf("config: %s (%s): fails %s", key, env, rule)
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,53 @@
+package deadlines
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+const maxPeekBytes = 1 << 20
+
+// submission is the part of an application body the enforcer reads.
+type submission struct {
+	ProgramCode string `json:"program_code"`
+	Round       string `json:"round"`
+}
+
+// Enforcer rejects submissions whose program round has closed for the
+// request's tenant with 409 DEADLINE_PASSED. It reads program_code and
+// round from the JSON body and restores the body for the next handler;
+// bodies it cannot parse are passed through for the handler to reject.
+func Enforcer(reg *Registry, clk clock.Clock) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBytes+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			var sub submission
+			if err != nil || len(body) > maxPeekBytes || json.Unmarshal(body, &sub) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id := tenant.ID(r.Context())
+			if now := clk.Now(); !reg.IsOpen(id, sub.ProgramCode, sub.Round, now) {
+				closes, _ := reg.Deadline(id, sub.ProgramCode, sub.Round)
+				respond.ErrorWithDetails(w, http.StatusConflict, "DEADLINE_PASSED", "the application deadline has passed", map[string]string{
+					"program_code": sub.ProgramCode,
+					"round":        sub.Round,
+					"deadline":     closes.UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
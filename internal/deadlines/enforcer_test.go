@@ -0,0 +1,56 @@
+package deadlines
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestEnforcer(t *testing.T) {
+	reg := newRegistry(t)
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.WriteHeader(http.StatusCreated)
+	})
+	body := `{"program_code":"CS","round":"regular"}`
+	post := func(now time.Time, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/applications", strings.NewReader(body))
+		req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{ID: "north"}))
+		Enforcer(reg, clock.Fixed(now))(next).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(closes, body); rec.Code != http.StatusCreated || seen != body {
+		t.Fatalf("at the deadline: %d, handler saw %q", rec.Code, seen)
+	}
+
+	seen = ""
+	rec := post(closes.Add(time.Second), body)
+	if rec.Code != http.StatusConflict || seen != "" {
+		t.Fatalf("after the deadline: %d, handler saw %q", rec.Code, seen)
+	}
+	var got struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != "DEADLINE_PASSED" || got.Details["deadline"] != "2026-01-15T23:59:59Z" || got.Details["program_code"] != "CS" {
+		t.Errorf("error body = %s", rec.Body)
+	}
+
+	if rec := post(closes.Add(time.Hour), "not json"); rec.Code != http.StatusCreated || seen != "not json" {
+		t.Errorf("unparseable body: %d, handler saw %q; want it passed through", rec.Code, seen)
+	}
+}
@@ -0,0 +1,114 @@
+// Package deadlines enforces per-program, per-round application
+// deadlines, which each tenant sets for itself.
+package deadlines
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often Watch reloads when Registry.Interval
+// is 0.
+const DefaultReloadInterval = time.Minute
+
+// Deadline closes submissions for one program round of a tenant.
+type Deadline struct {
+	TenantID    string
+	ProgramCode string
+	Round       string
+	ClosesAt    time.Time
+}
+
+// Source loads the full set of deadlines.
+type Source interface {
+	LoadDeadlines(ctx context.Context) ([]Deadline, error)
+}
+
+// StaticSource is a fixed Source for tests and local development.
+type StaticSource []Deadline
+
+// LoadDeadlines implements Source.
+func (s StaticSource) LoadDeadlines(context.Context) ([]Deadline, error) {
+	return s, nil
+}
+
+type key struct{ tenant, program, round string }
+
+// Registry holds the deadlines loaded from a Source. Lookups never block on
+// a reload; a failed reload keeps the previous entries.
+type Registry struct {
+	source Source
+	// Interval between reloads in Watch.
+	Interval time.Duration
+	// Logf reports reload failures; it defaults to log.Printf.
+	Logf func(format string, args ...any)
+
+	mu      sync.RWMutex
+	entries map[key]time.Time
+}
+
+// NewRegistry returns an empty Registry backed by source; call Reload
+// before serving so the first requests see the deadlines.
+func NewRegistry(source Source) *Registry {
+	return &Registry{source: source, Interval: DefaultReloadInterval, entries: map[key]time.Time{}}
+}
+
+// Reload replaces the entries with the source's current deadlines.
+func (r *Registry) Reload(ctx context.Context) error {
+	list, err := r.source.LoadDeadlines(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make(map[key]time.Time, len(list))
+	for _, d := range list {
+		entries[key{d.TenantID, d.ProgramCode, d.Round}] = d.ClosesAt
+	}
+	r.mu.Lock()
+	r.entries = entries
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads every Interval until ctx is done. Run it in its own
+// goroutine.
+func (r *Registry) Watch(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	logf := r.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Reload(ctx); err != nil && ctx.Err() == nil {
+				logf("deadlines: reload failed, keeping previous entries: %v", err)
+			}
+		}
+	}
+}
+
+// Deadline returns the closing time a tenant set for a program round, if
+// it set one.
+func (r *Registry) Deadline(tenantID, programCode, round string) (time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.entries[key{tenantID, programCode, round}]
+	return t, ok
+}
+
+// IsOpen reports whether a submission at now is on time. Rounds without a
+// deadline are open, and deadlines have one-second resolution: anything
+// within the deadline's own second is accepted.
+func (r *Registry) IsOpen(tenantID, programCode, round string, now time.Time) bool {
+	closes, ok := r.Deadline(tenantID, programCode, round)
+	return !ok || now.Unix() <= closes.Unix()
+}
@@ -0,0 +1,89 @@
+package deadlines
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var closes = time.Date(2026, 1, 15, 23, 59, 59, 0, time.UTC)
+
+func newRegistry(t *testing.T) *Registry {
+	t.Helper()
+	reg := NewRegistry(StaticSource{
+		{TenantID: "north", ProgramCode: "CS", Round: "regular", ClosesAt: closes},
+		{TenantID: "south", ProgramCode: "CS", Round: "regular", ClosesAt: closes.Add(-24 * time.Hour)},
+	})
+	if err := reg.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestIsOpen(t *testing.T) {
+	reg := newRegistry(t)
+	tests := []struct {
+		name                   string
+		tenant, program, round string
+		now                    time.Time
+		want                   bool
+	}{
+		{"before", "north", "CS", "regular", closes.Add(-time.Hour), true},
+		{"exactly at the deadline", "north", "CS", "regular", closes, true},
+		{"within the deadline second", "north", "CS", "regular", closes.Add(999 * time.Millisecond), true},
+		{"next second", "north", "CS", "regular", closes.Add(time.Second), false},
+		{"other time zone, same instant", "north", "CS", "regular", closes.In(time.FixedZone("CST", 8*3600)), true},
+		{"round without deadline", "north", "CS", "early", closes.Add(time.Hour), true},
+		{"program without deadline", "north", "EE", "regular", closes.Add(time.Hour), true},
+		{"other tenant's deadline", "south", "CS", "regular", closes.Add(-time.Hour), false},
+		{"tenant without deadlines", "west", "CS", "regular", closes.Add(time.Hour), true},
+	}
+	for _, tc := range tests {
+		if got := reg.IsOpen(tc.tenant, tc.program, tc.round, tc.now); got != tc.want {
+			t.Errorf("%s: IsOpen = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// flakySource fails every other load.
+type flakySource struct {
+	loads atomic.Int32
+}
+
+func (s *flakySource) LoadDeadlines(context.Context) ([]Deadline, error) {
+	n := s.loads.Add(1)
+	if n%2 == 0 {
+		return nil, errors.New("connection reset")
+	}
+	return []Deadline{{TenantID: "north", ProgramCode: "CS", Round: "regular", ClosesAt: closes.Add(time.Duration(n) * time.Hour)}}, nil
+}
+
+func TestWatchReloads(t *testing.T) {
+	src := &flakySource{}
+	reg := NewRegistry(src)
+	reg.Interval = time.Millisecond
+	var failures atomic.Int32
+	reg.Logf = func(string, ...any) { failures.Add(1) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { reg.Watch(ctx); close(done) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for src.loads.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if src.loads.Load() < 4 {
+		t.Fatalf("Watch loaded %d times", src.loads.Load())
+	}
+	if failures.Load() == 0 {
+		t.Error("reload failure was not logged")
+	}
+	if _, ok := reg.Deadline("north", "CS", "regular"); !ok {
+		t.Error("a failed reload dropped the previous entries")
+	}
+}
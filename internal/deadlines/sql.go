@@ -0,0 +1,53 @@
+package deadlines
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLSource loads deadlines from the application_deadlines table (see
+// prisma/schema.prisma). The registry is shared by every tenant, so it
+// loads with an unscoped context to read all of their rows, and keys each
+// by the tenant that owns it.
+type SQLSource struct {
+	DB *sql.DB
+}
+
+// LoadDeadlines implements Source.
+func (s SQLSource) LoadDeadlines(ctx context.Context) ([]Deadline, error) {
+	var out []Deadline
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		out, err = loadDeadlines(ctx, tx)
+		return err
+	})
+	return out, err
+}
+
+func loadDeadlines(ctx context.Context, tx *sql.Tx) ([]Deadline, error) {
+	const stmt = `SELECT tenant_id, program_code, round, closes_at FROM application_deadlines`
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("deadlines: query: %w", err)
+	}
+	defer rows.Close()
+	var out []Deadline
+	for rows.Next() {
+		var d Deadline
+		if err := rows.Scan(&d.TenantID, &d.ProgramCode, &d.Round, &d.ClosesAt); err != nil {
+			return nil, fmt.Errorf("deadlines: scan: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("deadlines: query: %w", err)
+	}
+	return out, nil
+}
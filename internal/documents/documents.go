@@ -0,0 +1,146 @@
+// Package documents stores files attached to student applications, such
+// as transcripts and ID scans.
+package documents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSize is the upload limit used when an uploader's MaxSize is 0.
+const DefaultMaxSize int64 = 10 << 20
+
+// Accepted MIME types, detected from the file content.
+const (
+	MIMEPDF  = "application/pdf"
+	MIMEJPEG = "image/jpeg"
+	MIMEPNG  = "image/png"
+)
+
+var (
+	// ErrUnsupportedType is returned for content that is not a PDF, JPEG,
+	// or PNG, whatever its file name says.
+	ErrUnsupportedType = errors.New("documents: unsupported file type")
+	// ErrTooLarge is returned once an upload exceeds the size limit.
+	ErrTooLarge = errors.New("documents: file too large")
+	// ErrNotFound is returned by Open for a key that holds no document.
+	ErrNotFound = errors.New("documents: not found")
+)
+
+// Meta describes a stored document.
+type Meta struct {
+	ID            string    `json:"id"`
+	ApplicationID string    `json:"application_id"`
+	Key           string    `json:"key"`
+	Filename      string    `json:"filename"`
+	MIMEType      string    `json:"mime_type"`
+	Size          int64     `json:"size"`
+	UploadedAt    time.Time `json:"uploaded_at"`
+	// DeletedAt is when the document was soft-deleted; the stored file is
+	// kept.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Uploader stores a document for an application. size is the length of r
+// if the caller knows it, or -1. Implementations must return
+// ErrUnsupportedType or ErrTooLarge before keeping anything.
+type Uploader interface {
+	Upload(ctx context.Context, appID string, r io.Reader, filename string, size int64) (*Meta, error)
+}
+
+// Opener reads back a stored document by its Meta.Key.
+type Opener interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// Remover deletes a stored document by its Meta.Key, for callers that
+// must take back an upload nothing will refer to. Removing a key that
+// holds nothing is not an error.
+type Remover interface {
+	Remove(ctx context.Context, key string) error
+}
+
+// Putter stores a file derived from a document, such as its thumbnail,
+// under a key of the caller's choosing, replacing what is there.
+type Putter interface {
+	Put(ctx context.Context, key string, r io.Reader, mimeType string) error
+}
+
+// magic maps the leading bytes of each accepted format to its MIME type.
+var magic = []struct {
+	prefix []byte
+	mime   string
+}{
+	{[]byte("%PDF-"), MIMEPDF},
+	{[]byte{0xFF, 0xD8, 0xFF}, MIMEJPEG},
+	{[]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, MIMEPNG},
+}
+
+// inspect checks the declared size and the content type of r. It returns
+// the MIME type and a reader that yields all of r but fails with
+// ErrTooLarge after max bytes.
+func inspect(r io.Reader, size, max int64) (string, io.Reader, error) {
+	if max <= 0 {
+		max = DefaultMaxSize
+	}
+	if size > max {
+		return "", nil, ErrTooLarge
+	}
+	br := bufio.NewReader(r)
+	head, err := br.Peek(8)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, err
+	}
+	for _, m := range magic {
+		if bytes.HasPrefix(head, m.prefix) {
+			return m.mime, &limitReader{r: br, left: max}, nil
+		}
+	}
+	return "", nil, ErrUnsupportedType
+}
+
+// limitReader is io.LimitReader that reports overflow instead of EOF.
+type limitReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// extensions are appended to storage keys so downloaded objects open with
+// the right application.
+var extensions = map[string]string{MIMEPDF: ".pdf", MIMEJPEG: ".jpg", MIMEPNG: ".png"}
+
+// objectKey is where a document is stored, relative to the uploader's
+// root. Keys never contain the client-supplied file name.
+func objectKey(appID, docID, mime string) string {
+	return path.Join("applications", appID, docID+extensions[mime])
+}
+
+// cleanFilename keeps only the base name of a client-supplied file name.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
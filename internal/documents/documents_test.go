@@ -0,0 +1,369 @@
+package documents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	stdpng "image/png"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+)
+
+var (
+	pdf = append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte("x"), 100)...)
+	png = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 13}
+	jpg = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 16}
+)
+
+func TestInspect(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"pdf", pdf, MIMEPDF, nil},
+		{"png", png, MIMEPNG, nil},
+		{"jpeg", jpg, MIMEJPEG, nil},
+		{"text named .pdf", []byte("hello, world"), "", ErrUnsupportedType},
+		{"empty", nil, "", ErrUnsupportedType},
+		{"gif", []byte("GIF89a..."), "", ErrUnsupportedType},
+	} {
+		mime, body, err := inspect(bytes.NewReader(tc.data), -1, 1<<10)
+		if !errors.Is(err, tc.err) || mime != tc.want {
+			t.Errorf("%s: inspect = %q, %v; want %q, %v", tc.name, mime, err, tc.want, tc.err)
+			continue
+		}
+		if err == nil {
+			if got, _ := io.ReadAll(body); !bytes.Equal(got, tc.data) {
+				t.Errorf("%s: body lost the sniffed bytes", tc.name)
+			}
+		}
+	}
+}
+
+func TestInspectSizeLimit(t *testing.T) {
+	if _, _, err := inspect(bytes.NewReader(pdf), 1<<20, 1<<10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("declared size over limit: err = %v, want ErrTooLarge", err)
+	}
+	_, body, err := inspect(bytes.NewReader(pdf), -1, int64(len(pdf))-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(body); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("streamed size over limit: err = %v, want ErrTooLarge", err)
+	}
+	_, body, _ = inspect(bytes.NewReader(pdf), -1, int64(len(pdf)))
+	if _, err := io.ReadAll(body); err != nil {
+		t.Errorf("body exactly at the limit: %v", err)
+	}
+}
+
+func TestLocalUploader(t *testing.T) {
+	dir := t.TempDir()
+	u := NewLocalUploader(dir)
+	meta, err := u.Upload(context.Background(), "app-1", bytes.NewReader(png), `C:\scans\..\id.png`, int64(len(png)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.MIMEType != MIMEPNG || meta.Size != int64(len(png)) || meta.Filename != "id.png" || meta.UploadedAt.IsZero() {
+		t.Errorf("meta = %+v", meta)
+	}
+	if !strings.HasPrefix(meta.Key, "applications/app-1/") || !strings.HasSuffix(meta.Key, ".png") {
+		t.Errorf("key = %q", meta.Key)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(meta.Key)))
+	if err != nil || !bytes.Equal(got, png) {
+		t.Errorf("stored file = %v, %v", got, err)
+	}
+	if rc, err := u.Open(context.Background(), meta.Key); err != nil {
+		t.Errorf("Open: %v", err)
+	} else if got, _ := io.ReadAll(rc); !bytes.Equal(got, png) {
+		t.Errorf("opened file = %v", got)
+	} else {
+		rc.Close()
+	}
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "outside.pdf"), pdf, 0o600)
+	for _, key := range []string{"applications/app-1/missing.png", "../outside.pdf"} {
+		if _, err := u.Open(context.Background(), key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) = %v, want ErrNotFound", key, err)
+		}
+	}
+
+	u.MaxSize = 4
+	if _, err := u.Upload(context.Background(), "app-1", bytes.NewReader(pdf), "big.pdf", -1); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "applications", "app-1"))
+	if len(entries) != 1 {
+		t.Errorf("oversized upload left a partial file: %v", entries)
+	}
+}
+
+// fakeS3 records single-part uploads; the transfer manager only calls
+// PutObject for bodies smaller than one part.
+type fakeS3 struct {
+	manager.UploadAPIClient
+	input *s3.PutObjectInput
+	body  []byte
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.input, f.body = in, body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if *in.Bucket != "docs" {
+		return nil, errors.New("NotFound")
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if f.input == nil || *in.Key != *f.input.Key {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(f.body))}, nil
+}
+
+func TestS3UploaderCheck(t *testing.T) {
+	u := &S3Uploader{Client: &fakeS3{}, Bucket: "docs"}
+	if err := u.Check(context.Background()); err != nil {
+		t.Errorf("reachable bucket: %v", err)
+	}
+	u.Bucket = "gone"
+	if err := u.Check(context.Background()); err == nil {
+		t.Error("missing bucket passed")
+	}
+}
+
+func TestS3Uploader(t *testing.T) {
+	client := &fakeS3{}
+	u := &S3Uploader{Client: client, Bucket: "docs", Prefix: "admissions", MaxSize: DefaultMaxSize}
+	meta, err := u.Upload(context.Background(), "app-1", bytes.NewReader(pdf), "transcript.pdf", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *client.input.Bucket != "docs" || *client.input.Key != meta.Key || *client.input.ContentType != MIMEPDF {
+		t.Errorf("PutObject input = %+v", client.input)
+	}
+	if !strings.HasPrefix(meta.Key, "admissions/applications/app-1/") || !bytes.Equal(client.body, pdf) || meta.Size != int64(len(pdf)) {
+		t.Errorf("meta = %+v, body %d bytes", meta, len(client.body))
+	}
+	if rc, err := u.Open(context.Background(), meta.Key); err != nil {
+		t.Errorf("Open: %v", err)
+	} else if got, _ := io.ReadAll(rc); !bytes.Equal(got, pdf) {
+		t.Errorf("opened object = %q", got)
+	}
+	if _, err := u.Open(context.Background(), "admissions/missing.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open of a missing key = %v, want ErrNotFound", err)
+	}
+
+	client.input = nil
+	if _, err := u.Upload(context.Background(), "app-1", strings.NewReader("#!/bin/sh"), "x.pdf", -1); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("err = %v, want ErrUnsupportedType", err)
+	}
+	if client.input != nil {
+		t.Error("rejected file was sent to S3")
+	}
+}
+
+// downS3 fails every call.
+type downS3 struct{ fakeS3 }
+
+func (downS3) PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func TestS3UploaderBreaker(t *testing.T) {
+	ctx := context.Background()
+	u := &S3Uploader{Client: &fakeS3{}, Bucket: "docs", MaxSize: DefaultMaxSize, Breaker: &circuit.Breaker{Name: "s3", Policy: circuit.Policy{FailureThreshold: 1}}}
+	// Missing objects and rejected files show nothing wrong with S3.
+	if _, err := u.Open(ctx, "missing.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open of a missing key = %v, want ErrNotFound", err)
+	}
+	if _, err := u.Upload(ctx, "app-1", bytes.NewReader(append(pdf, make([]byte, 64)...)), "big.pdf", -1); err != nil {
+		t.Fatal(err)
+	}
+	u.MaxSize = int64(len(pdf))
+	if _, err := u.Upload(ctx, "app-1", bytes.NewReader(append(pdf, make([]byte, 64)...)), "big.pdf", -1); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("oversized upload = %v, want ErrTooLarge", err)
+	}
+	if u.Breaker.State() != circuit.Closed {
+		t.Fatalf("state %s, want closed", u.Breaker.State())
+	}
+
+	u.Client = downS3{}
+	if _, err := u.Upload(ctx, "app-1", bytes.NewReader(pdf), "t.pdf", -1); err == nil || errors.Is(err, circuit.ErrOpen) {
+		t.Fatalf("S3 down: %v, want its error", err)
+	}
+	if _, err := u.Upload(ctx, "app-1", bytes.NewReader(pdf), "t.pdf", -1); !errors.Is(err, circuit.ErrOpen) {
+		t.Errorf("after the breaker opened: %v, want circuit.ErrOpen", err)
+	}
+}
+
+func TestMemoryMetaStoreSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryMetaStore()
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"doc-1", "doc-2"} {
+		if err := s.Create(ctx, &Meta{ID: id, ApplicationID: "app-1", UploadedAt: at.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete(ctx, "doc-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "doc-1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("second Delete = %v, want store.ErrNotFound", err)
+	}
+	if got, _ := s.ListByApplication(ctx, "app-1"); len(got) != 1 || got[0].ID != "doc-2" {
+		t.Errorf("ListByApplication = %+v, want only doc-2", got)
+	}
+	got, _ := s.ListByApplication(ctx, "app-1", store.WithDeleted())
+	if len(got) != 2 || got[0].DeletedAt == nil || got[1].DeletedAt != nil {
+		t.Errorf("ListByApplication WithDeleted = %+v, want both with doc-1 marked deleted", got)
+	}
+	if _, err := s.GetByID(ctx, "doc-1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetByID of a deleted document = %v, want store.ErrNotFound", err)
+	}
+	if m, err := s.GetByID(ctx, "doc-1", store.WithDeleted()); err != nil || m.DeletedAt == nil {
+		t.Errorf("GetByID WithDeleted = %+v, %v", m, err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	// Left half black, right half white.
+	src := image.NewGray(image.Rect(0, 0, 400, 200))
+	for y := range 200 {
+		for x := 200; x < 400; x++ {
+			src.SetGray(x, y, color.Gray{255})
+		}
+	}
+	var in bytes.Buffer
+	stdpng.Encode(&in, src)
+
+	out, err := Thumbnail(&in, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := stdpng.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("thumbnail is %v, want 100x50", b)
+	}
+	if r, _, _, _ := thumb.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("left pixel r = %d, want 0", r)
+	}
+	if r, _, _, _ := thumb.At(90, 10).RGBA(); r != 0xffff {
+		t.Errorf("right pixel r = %d, want 0xffff", r)
+	}
+
+	if _, err := Thumbnail(bytes.NewReader(pdf), 100); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("PDF: err = %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestLocalUploaderPut(t *testing.T) {
+	dir := t.TempDir()
+	u := NewLocalUploader(dir)
+	key := ThumbnailKey("applications/app-1/doc-1.png")
+	for _, body := range []string{"first", "second"} {
+		if err := u.Put(context.Background(), key, strings.NewReader(body), MIMEPNG); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key))); err != nil || string(got) != "second" {
+		t.Errorf("stored file = %q, %v", got, err)
+	}
+}
+
+// fakeClamd answers one INSTREAM scan with reply, recording the document
+// it was sent.
+func fakeClamd(t *testing.T, reply string) (addr string, got <-chan []byte) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+			t.Errorf("command = %q, %v", cmd, err)
+			return
+		}
+		var doc []byte
+		for {
+			var n uint32
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+				t.Error(err)
+				return
+			}
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				t.Error(err)
+				return
+			}
+			doc = append(doc, chunk...)
+		}
+		ch <- doc
+		conn.Write([]byte(reply + "\x00"))
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestClamd(t *testing.T) {
+	doc := bytes.Repeat([]byte("x"), 3*clamdChunk/2)
+	addr, got := fakeClamd(t, "stream: OK")
+	if err := (Clamd{Addr: addr}).Scan(context.Background(), bytes.NewReader(doc)); err != nil {
+		t.Fatalf("clean document: %v", err)
+	}
+	if sent := <-got; !bytes.Equal(sent, doc) {
+		t.Errorf("clamd got %d bytes, want %d", len(sent), len(doc))
+	}
+
+	addr, _ = fakeClamd(t, "stream: Eicar-Test-Signature FOUND")
+	err := (Clamd{Addr: addr}).Scan(context.Background(), bytes.NewReader(pdf))
+	var infected *InfectedError
+	if !errors.Is(err, ErrInfected) || !errors.As(err, &infected) || infected.Signature != "Eicar-Test-Signature" {
+		t.Errorf("infected document: err = %v", err)
+	}
+
+	addr, _ = fakeClamd(t, "INSTREAM size limit exceeded. ERROR")
+	if err := (Clamd{Addr: addr}).Scan(context.Background(), bytes.NewReader(pdf)); err == nil || errors.Is(err, ErrInfected) {
+		t.Errorf("clamd error: err = %v", err)
+	}
+}
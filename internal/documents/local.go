@@ -0,0 +1,102 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// LocalUploader stores documents under Dir on the local file system. It
+// is meant for tests and local development.
+type LocalUploader struct {
+	Dir     string
+	MaxSize int64
+	now     func() time.Time
+}
+
+// NewLocalUploader returns a LocalUploader rooted at dir with the default
+// size limit.
+func NewLocalUploader(dir string) *LocalUploader {
+	return &LocalUploader{Dir: dir, MaxSize: DefaultMaxSize, now: time.Now}
+}
+
+// Upload writes the document to Dir/<key>, removing partial files on
+// failure.
+func (u *LocalUploader) Upload(_ context.Context, appID string, r io.Reader, filename string, size int64) (*Meta, error) {
+	mime, body, err := inspect(r, size, u.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	meta := &Meta{ID: store.NewID(), ApplicationID: appID, Filename: cleanFilename(filename), MIMEType: mime}
+	meta.Key = objectKey(appID, meta.ID, mime)
+
+	path := filepath.Join(u.Dir, filepath.FromSlash(meta.Key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(f, body)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	meta.Size = n
+	meta.UploadedAt = u.clock().UTC()
+	return meta, nil
+}
+
+// Open reads the document stored under key. Keys cannot reach outside
+// Dir.
+func (u *LocalUploader) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(u.Dir, filepath.FromSlash(path.Clean("/"+key))))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Remove deletes the document stored under key.
+func (u *LocalUploader) Remove(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(u.Dir, filepath.FromSlash(path.Clean("/"+key))))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Put writes r to Dir/<key>, replacing any file there. Keys cannot reach
+// outside Dir.
+func (u *LocalUploader) Put(_ context.Context, key string, r io.Reader, _ string) error {
+	path := filepath.Join(u.Dir, filepath.FromSlash(path.Clean("/"+key)))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func (u *LocalUploader) clock() time.Time {
+	if u.now == nil {
+		return time.Now()
+	}
+	return u.now()
+}
@@ -0,0 +1,88 @@
+package documents
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// MetaStore persists document metadata. Delete is a soft delete, as for
+// applications: GetByID and ListByApplication skip the document
+// afterwards unless given store.WithDeleted.
+type MetaStore interface {
+	Create(ctx context.Context, meta *Meta) error
+	GetByID(ctx context.Context, id string, qopts ...store.QueryOption) (*Meta, error)
+	ListByApplication(ctx context.Context, appID string, qopts ...store.QueryOption) ([]Meta, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryMetaStore is an in-process MetaStore for tests and local
+// development without DATABASE_URL.
+type MemoryMetaStore struct {
+	mu    sync.RWMutex
+	metas map[string][]Meta
+	now   func() time.Time
+}
+
+// NewMemoryMetaStore returns an empty MemoryMetaStore.
+func NewMemoryMetaStore() *MemoryMetaStore {
+	return &MemoryMetaStore{metas: map[string][]Meta{}, now: time.Now}
+}
+
+// Create stores meta.
+func (s *MemoryMetaStore) Create(_ context.Context, meta *Meta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metas[meta.ApplicationID] = append(s.metas[meta.ApplicationID], *meta)
+	return nil
+}
+
+// GetByID returns a document's metadata or store.ErrNotFound.
+func (s *MemoryMetaStore) GetByID(_ context.Context, id string, qopts ...store.QueryOption) (*Meta, error) {
+	q := store.ApplyQueryOptions(qopts...)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, metas := range s.metas {
+		for _, m := range metas {
+			if m.ID == id && q.Visible(m.DeletedAt) {
+				return &m, nil
+			}
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// ListByApplication returns an application's documents, oldest first.
+func (s *MemoryMetaStore) ListByApplication(_ context.Context, appID string, qopts ...store.QueryOption) ([]Meta, error) {
+	q := store.ApplyQueryOptions(qopts...)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []Meta{}
+	for _, m := range s.metas[appID] {
+		if q.Visible(m.DeletedAt) {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].UploadedAt.Before(out[j].UploadedAt) })
+	return out, nil
+}
+
+// Delete soft-deletes a document, returning store.ErrNotFound if there is
+// no live one with that ID.
+func (s *MemoryMetaStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, metas := range s.metas {
+		for i := range metas {
+			if metas[i].ID == id && metas[i].DeletedAt == nil {
+				now := s.now().UTC()
+				metas[i].DeletedAt = &now
+				return nil
+			}
+		}
+	}
+	return store.ErrNotFound
+}
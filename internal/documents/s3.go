@@ -0,0 +1,186 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// S3Uploader stores documents in an S3-compatible bucket. Bodies are
+// streamed with the transfer manager, so uploads of unknown length are
+// sent as multipart uploads without buffering the whole file.
+type S3Uploader struct {
+	Client  manager.UploadAPIClient
+	Bucket  string
+	Prefix  string
+	MaxSize int64
+	// Breaker, if set, guards the uploads and reads, so while S3 is down
+	// they fail at once with circuit.ErrOpen. Check always calls S3.
+	Breaker *circuit.Breaker
+	now     func() time.Time
+}
+
+// NewS3Uploader returns an S3Uploader for bucket with the default size
+// limit. Keys are prefixed with prefix, which may be empty.
+func NewS3Uploader(client *s3.Client, bucket, prefix string) *S3Uploader {
+	return &S3Uploader{Client: client, Bucket: bucket, Prefix: prefix, MaxSize: DefaultMaxSize, now: time.Now}
+}
+
+// Upload streams the document to Bucket/Prefix/<key>. A size or type
+// failure detected mid-stream aborts the upload.
+func (u *S3Uploader) Upload(ctx context.Context, appID string, r io.Reader, filename string, size int64) (*Meta, error) {
+	mime, body, err := inspect(r, size, u.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	meta := &Meta{ID: store.NewID(), ApplicationID: appID, Filename: cleanFilename(filename), MIMEType: mime}
+	meta.Key = path.Join(u.Prefix, objectKey(appID, meta.ID, mime))
+
+	ctx, span := tracing.Tracer().Start(ctx, "s3.Upload", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", u.Bucket), attribute.String("s3.key", meta.Key)))
+	defer span.End()
+	counted := &countingReader{r: body}
+	err = u.Breaker.Do(ctx, func() error {
+		_, err := manager.NewUploader(u.Client).Upload(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(u.Bucket),
+			Key:         aws.String(meta.Key),
+			Body:        counted,
+			ContentType: aws.String(mime),
+		})
+		if err != nil && counted.err != nil {
+			// The document, not S3, failed.
+			return circuit.Healthy(counted.err)
+		}
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	meta.Size = counted.n
+	span.SetAttributes(attribute.Int64("s3.size", meta.Size))
+	meta.UploadedAt = u.clock().UTC()
+	return meta, nil
+}
+
+// Check reports whether Bucket is reachable with a HeadBucket call, so an
+// S3Uploader can back a readiness check. A Client without HeadBucket
+// fails it.
+func (u *S3Uploader) Check(ctx context.Context) error {
+	client, ok := u.Client.(s3.HeadBucketAPIClient)
+	if !ok {
+		return errors.New("documents: S3 client cannot check the bucket")
+	}
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(u.Bucket)})
+	return err
+}
+
+// Open streams the object stored under key, which already carries Prefix.
+// A Client without GetObject fails it.
+func (u *S3Uploader) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	client, ok := u.Client.(manager.DownloadAPIClient)
+	if !ok {
+		return nil, errors.New("documents: S3 client cannot read objects")
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "s3.GetObject", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", u.Bucket), attribute.String("s3.key", key)))
+	defer span.End()
+	var out *s3.GetObjectOutput
+	err := u.Breaker.Do(ctx, func() error {
+		var err error
+		out, err = client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(u.Bucket), Key: aws.String(key)})
+		var missing *types.NoSuchKey
+		if errors.As(err, &missing) {
+			return circuit.Healthy(ErrNotFound)
+		}
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// Remove deletes the object stored under key, which already carries
+// Prefix. A Client without DeleteObject fails it.
+func (u *S3Uploader) Remove(ctx context.Context, key string) error {
+	client, ok := u.Client.(interface {
+		DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, opts ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	})
+	if !ok {
+		return errors.New("documents: S3 client cannot delete objects")
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "s3.DeleteObject", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", u.Bucket), attribute.String("s3.key", key)))
+	defer span.End()
+	err := u.Breaker.Do(ctx, func() error {
+		_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(u.Bucket), Key: aws.String(key)})
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return err
+}
+
+// Put uploads r to Bucket/<key>; key already carries Prefix, as the key
+// of the document it derives from does.
+func (u *S3Uploader) Put(ctx context.Context, key string, r io.Reader, mimeType string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "s3.Upload", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", u.Bucket), attribute.String("s3.key", key)))
+	defer span.End()
+	err := u.Breaker.Do(ctx, func() error {
+		_, err := manager.NewUploader(u.Client).Upload(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(u.Bucket),
+			Key:         aws.String(key),
+			Body:        r,
+			ContentType: aws.String(mimeType),
+		})
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return err
+}
+
+func (u *S3Uploader) clock() time.Time {
+	if u.now == nil {
+		return time.Now()
+	}
+	return u.now()
+}
+
+// countingReader records how much was read and the body's own error, which
+// the transfer manager wraps beyond errors.Is.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,115 @@
+package documents
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultScanTimeout bounds a Clamd scan when its Timeout is 0.
+const DefaultScanTimeout = time.Minute
+
+// ErrInfected is matched, with errors.Is, by the *InfectedError a Scanner
+// returns for a document that carries malware.
+var ErrInfected = errors.New("documents: malware found")
+
+// InfectedError is a document a Scanner flagged.
+type InfectedError struct {
+	// Signature names what was found.
+	Signature string
+}
+
+func (e *InfectedError) Error() string { return ErrInfected.Error() + ": " + e.Signature }
+
+// Is makes an *InfectedError match ErrInfected.
+func (e *InfectedError) Is(target error) bool { return target == ErrInfected }
+
+// Scanner checks a document's content for malware, returning an
+// *InfectedError if it finds any.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// clamdChunk is how much of a document each INSTREAM chunk carries.
+const clamdChunk = 32 << 10
+
+// Clamd is a Scanner backed by a ClamAV daemon, to which documents are
+// streamed with the INSTREAM command.
+type Clamd struct {
+	// Addr is clamd's TCP address, host:port.
+	Addr string
+	// Timeout bounds a whole scan; zero means DefaultScanTimeout.
+	Timeout time.Duration
+}
+
+// Scan implements Scanner.
+func (c Clamd) Scan(ctx context.Context, r io.Reader) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultScanTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("documents: clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	reply, err := instream(conn, r)
+	if err != nil {
+		return fmt.Errorf("documents: clamd: %w", err)
+	}
+	// The reply is "stream: OK", "stream: <signature> FOUND", or an
+	// error ending in "ERROR".
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &InfectedError{Signature: strings.TrimSuffix(result, " FOUND")}
+	}
+	return fmt.Errorf("documents: clamd: %s", reply)
+}
+
+// instream sends r to clamd in length-prefixed chunks, ended by an empty
+// one, and reads the NUL-terminated reply.
+func instream(conn net.Conn, r io.Reader) (string, error) {
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	buf := make([]byte, 4+clamdChunk)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return "", werr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	binary.BigEndian.PutUint32(buf, 0)
+	w.Write(buf[:4])
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
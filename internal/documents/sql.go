@@ -0,0 +1,109 @@
+package documents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLMetaStore keeps document metadata in the documents table (see
+// prisma/schema.prisma), in transactions scoped to the context's tenant
+// (see store.Scoped), which the audit log's entries share.
+type SQLMetaStore struct {
+	DB *sql.DB
+}
+
+const metaColumns = `id, application_id, key, filename, mime_type, size, uploaded_at, deleted_at`
+
+// Create implements MetaStore.
+func (s SQLMetaStore) Create(ctx context.Context, meta *Meta) error {
+	const stmt = `INSERT INTO documents (` + metaColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, NULL)`
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		if _, err := tx.ExecContext(ctx, stmt, meta.ID, meta.ApplicationID, meta.Key, meta.Filename, meta.MIMEType, meta.Size, meta.UploadedAt.UTC()); err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("documents: insert: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByID implements MetaStore.
+func (s SQLMetaStore) GetByID(ctx context.Context, id string, qopts ...store.QueryOption) (*Meta, error) {
+	metas, err := s.query(ctx, `SELECT `+metaColumns+` FROM documents WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(metas) == 0 || !store.ApplyQueryOptions(qopts...).Visible(metas[0].DeletedAt) {
+		return nil, store.ErrNotFound
+	}
+	return &metas[0], nil
+}
+
+// ListByApplication implements MetaStore, oldest first.
+func (s SQLMetaStore) ListByApplication(ctx context.Context, appID string, qopts ...store.QueryOption) ([]Meta, error) {
+	stmt := `SELECT ` + metaColumns + ` FROM documents WHERE application_id = $1`
+	if !store.ApplyQueryOptions(qopts...).WithDeleted {
+		stmt += ` AND deleted_at IS NULL`
+	}
+	return s.query(ctx, stmt+` ORDER BY uploaded_at, id`, appID)
+}
+
+// Delete implements MetaStore.
+func (s SQLMetaStore) Delete(ctx context.Context, id string) error {
+	const stmt = `UPDATE documents SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		res, err := tx.ExecContext(ctx, stmt, id, time.Now().UTC().Truncate(time.Millisecond))
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("documents: delete: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return store.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s SQLMetaStore) query(ctx context.Context, stmt string, args ...any) ([]Meta, error) {
+	out := []Meta{}
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		rows, err := tx.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("documents: query: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				m         Meta
+				deletedAt sql.NullTime
+			)
+			if err := rows.Scan(&m.ID, &m.ApplicationID, &m.Key, &m.Filename, &m.MIMEType, &m.Size, &m.UploadedAt, &deletedAt); err != nil {
+				return fmt.Errorf("documents: scan: %w", err)
+			}
+			if deletedAt.Valid {
+				t := deletedAt.Time
+				m.DeletedAt = &t
+			}
+			out = append(out, m)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("documents: query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
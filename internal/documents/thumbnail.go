@@ -0,0 +1,72 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // decodes JPEG uploads
+	stdpng "image/png"
+	"io"
+)
+
+// MaxThumbnailPixels bounds the images Thumbnail decodes, so a small file
+// declaring a huge canvas cannot exhaust memory.
+const MaxThumbnailPixels = 50_000_000
+
+// ThumbnailKey is where the thumbnail of the document stored under key
+// is kept.
+func ThumbnailKey(key string) string { return key + ".thumb.png" }
+
+// Thumbnail scales the JPEG or PNG image in r down to width pixels wide,
+// keeping its aspect ratio, and encodes it as a PNG. An image no wider
+// than width keeps its size. Other content, such as a PDF, is
+// ErrUnsupportedType.
+func Thumbnail(r io.Reader, width int) ([]byte, error) {
+	var head bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		if format == "" {
+			return nil, ErrUnsupportedType
+		}
+		return nil, fmt.Errorf("documents: decode %s: %w", format, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxThumbnailPixels {
+		return nil, fmt.Errorf("documents: %dx%d image is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return nil, fmt.Errorf("documents: decode %s: %w", format, err)
+	}
+	var out bytes.Buffer
+	if err := stdpng.Encode(&out, scale(src, width)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// scale shrinks img to width pixels wide, each pixel the average of the
+// source pixels it covers.
+func scale(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if width <= 0 || b.Dx() <= width {
+		return img
+	}
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		for x := range width {
+			x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
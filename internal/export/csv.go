@@ -0,0 +1,38 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// ExportCSV implements Exporter. Cells that a spreadsheet would read as a
+// formula (starting with =, +, -, @, tab, or carriage return) are
+// prefixed with a single quote, since applicants choose some of the
+// values.
+func (e *StoreExporter) ExportCSV(ctx context.Context, w io.Writer, opts Options) error {
+	return e.export(ctx, &csvSheet{w: csv.NewWriter(w)}, opts)
+}
+
+type csvSheet struct {
+	w *csv.Writer
+}
+
+func (s *csvSheet) header(names []string) error { return s.w.Write(names) }
+
+func (s *csvSheet) row(cells []string) error {
+	for i, c := range cells {
+		if c != "" && strings.ContainsRune("=+-@\t\r", rune(c[0])) {
+			cells[i] = "'" + c
+		}
+	}
+	return s.w.Write(cells)
+}
+
+func (s *csvSheet) flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *csvSheet) close() error { return s.flush() }
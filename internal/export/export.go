@@ -0,0 +1,184 @@
+// Package export writes applications out as CSV or Excel (XLSX) files for
+// the /v1/applications/export endpoint.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// DefaultPageSize is how many records StoreExporter reads per query when
+// PageSize is unset.
+const DefaultPageSize = 500
+
+// Exporter writes the applications opts selects to w, one header row
+// followed by one row per application in submission order. Rows are
+// written as they are read, so an export's memory use does not grow with
+// its length; an error after the first write leaves w truncated.
+type Exporter interface {
+	ExportCSV(ctx context.Context, w io.Writer, opts Options) error
+	ExportXLSX(ctx context.Context, w io.Writer, opts Options) error
+}
+
+// Options selects what an export contains.
+type Options struct {
+	// Columns lists the columns to write, in order; empty means every
+	// column in Columns order.
+	Columns []string
+	// Filter selects the applications, as for store.MemoryStore.Query.
+	Filter store.Filter
+	// DateFormat is the time.Format layout for timestamps, which are
+	// written in UTC; empty means time.RFC3339.
+	DateFormat string
+}
+
+// column renders one field of an application.
+type column struct {
+	name  string
+	value func(app *models.StudentApplication, dateFormat string) string
+}
+
+var columns = []column{
+	{"id", func(a *models.StudentApplication, _ string) string { return a.ID }},
+	{"applicant_id", func(a *models.StudentApplication, _ string) string { return a.ApplicantID }},
+	{"program_code", func(a *models.StudentApplication, _ string) string { return a.ProgramCode }},
+	{"round", func(a *models.StudentApplication, _ string) string { return a.Round }},
+	{"status", func(a *models.StudentApplication, _ string) string { return string(a.Status) }},
+	{"submitted_at", func(a *models.StudentApplication, f string) string { return formatTime(a.SubmittedAt, f) }},
+	{"updated_at", func(a *models.StudentApplication, f string) string { return formatTime(a.UpdatedAt, f) }},
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// Columns returns the names of every exportable column, in default order.
+func Columns() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Validate reports every unknown or repeated column in o at once.
+func (o Options) Validate() error {
+	_, err := o.columns()
+	return err
+}
+
+func (o Options) columns() ([]column, error) {
+	if len(o.Columns) == 0 {
+		return columns, nil
+	}
+	var errs []error
+	out := make([]column, 0, len(o.Columns))
+	seen := make(map[string]bool, len(o.Columns))
+	for _, name := range o.Columns {
+		i := slices.IndexFunc(columns, func(c column) bool { return c.name == name })
+		switch {
+		case i < 0:
+			errs = append(errs, fmt.Errorf("export: unknown column %q (have %s)", name, strings.Join(Columns(), ", ")))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("export: column %q is listed twice", name))
+		default:
+			seen[name] = true
+			out = append(out, columns[i])
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+func (o Options) dateFormat() string {
+	if o.DateFormat == "" {
+		return time.RFC3339
+	}
+	return o.DateFormat
+}
+
+// Source pages through applications across applicants;
+// *store.MemoryStore and *store.SQLStore implement it.
+type Source interface {
+	Query(ctx context.Context, f store.Filter, opts store.ListOptions) (*store.ListResult, error)
+}
+
+// StoreExporter is the Exporter over a Source. It reads PageSize records
+// at a time by keyset cursor, so records created during an export never
+// shift or repeat the ones already written.
+type StoreExporter struct {
+	Source Source
+	// PageSize is the number of records per query; 0 means
+	// DefaultPageSize.
+	PageSize int
+}
+
+// New returns a StoreExporter reading from src.
+func New(src Source) *StoreExporter {
+	return &StoreExporter{Source: src}
+}
+
+// sheet is an output format: a header row, then records, then the end.
+type sheet interface {
+	header(names []string) error
+	row(cells []string) error
+	// flush pushes buffered rows to the underlying writer after each page.
+	flush() error
+	close() error
+}
+
+// export validates opts and writes every selected record to s.
+func (e *StoreExporter) export(ctx context.Context, s sheet, opts Options) error {
+	cols, err := opts.columns()
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	if err := s.header(names); err != nil {
+		return err
+	}
+	limit := e.PageSize
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	layout := opts.dateFormat()
+	cells := make([]string, len(cols))
+	page := store.ListOptions{Limit: limit}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := e.Source.Query(ctx, opts.Filter, page)
+		if err != nil {
+			return fmt.Errorf("export: query: %w", err)
+		}
+		for i := range res.Items {
+			for j, c := range cols {
+				cells[j] = c.value(&res.Items[i], layout)
+			}
+			if err := s.row(cells); err != nil {
+				return err
+			}
+		}
+		if err := s.flush(); err != nil {
+			return err
+		}
+		if !res.HasMore {
+			return s.close()
+		}
+		page.Cursor = res.NextCursor
+	}
+}
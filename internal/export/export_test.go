@@ -0,0 +1,225 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func seed(t *testing.T, apps ...models.StudentApplication) *store.MemoryStore {
+	t.Helper()
+	s := store.NewMemoryStore()
+	for _, app := range apps {
+		if err := s.Create(context.Background(), &app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func readCSV(t *testing.T, b []byte) [][]string {
+	t.Helper()
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv %q: %v", b, err)
+	}
+	return rows
+}
+
+func TestExportCSV(t *testing.T) {
+	s := seed(t,
+		models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending},
+		models.StudentApplication{ApplicantID: "stu-2", ProgramCode: "EE", Status: status.Pending},
+		models.StudentApplication{ApplicantID: "stu-3", ProgramCode: "CS", Round: "=HYPERLINK(\"x\")", Status: status.Accepted},
+	)
+	var buf bytes.Buffer
+	err := New(s).ExportCSV(context.Background(), &buf, Options{
+		Columns:    []string{"applicant_id", "round", "status", "submitted_at"},
+		Filter:     store.Filter{ProgramCode: "CS"},
+		DateFormat: "2006-01-02",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readCSV(t, buf.Bytes())
+	today := time.Now().UTC().Format("2006-01-02")
+	want := [][]string{
+		{"applicant_id", "round", "status", "submitted_at"},
+		{"stu-1", "", "pending", today},
+		{"stu-3", "'=HYPERLINK(\"x\")", "accepted", today},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %q, want %q", rows, want)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+
+	buf.Reset()
+	if err := New(s).ExportCSV(context.Background(), &buf, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readCSV(t, buf.Bytes()); len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(Columns(), ",") {
+		t.Errorf("default export = %q", rows)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	if err := (Options{Columns: []string{"id", "status"}}).Validate(); err != nil {
+		t.Errorf("valid columns: %v", err)
+	}
+	err := Options{Columns: []string{"id", "email", "id", "score"}}.Validate()
+	for _, want := range []string{`"email"`, `"score"`, `"id" is listed twice`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := New(seed(t)).ExportCSV(context.Background(), &buf, Options{Columns: []string{"email"}}); err == nil || buf.Len() != 0 {
+		t.Errorf("export with a bad column: err %v, wrote %q", err, buf.Bytes())
+	}
+}
+
+// pagedSource records how much had been written to out each time a page
+// was read.
+type pagedSource struct {
+	Source
+	out     *bytes.Buffer
+	written []int
+}
+
+func (p *pagedSource) Query(ctx context.Context, f store.Filter, opts store.ListOptions) (*store.ListResult, error) {
+	p.written = append(p.written, p.out.Len())
+	return p.Source.Query(ctx, f, opts)
+}
+
+func TestExportStreamsByPage(t *testing.T) {
+	apps := make([]models.StudentApplication, 25)
+	for i := range apps {
+		apps[i] = models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS"}
+	}
+	var buf bytes.Buffer
+	src := &pagedSource{Source: seed(t, apps...), out: &buf}
+	e := &StoreExporter{Source: src, PageSize: 10}
+	if err := e.ExportCSV(context.Background(), &buf, Options{Columns: []string{"id"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(src.written) != 3 {
+		t.Fatalf("read %d pages, want 3", len(src.written))
+	}
+	for i := 1; i < len(src.written); i++ {
+		if src.written[i] <= src.written[i-1] {
+			t.Errorf("nothing was written between pages %d and %d: %v", i, i+1, src.written)
+		}
+	}
+	rows := readCSV(t, buf.Bytes())
+	seen := map[string]bool{}
+	for _, r := range rows[1:] {
+		seen[r[0]] = true
+	}
+	if len(rows) != 26 || len(seen) != 25 {
+		t.Errorf("exported %d rows, %d distinct, want 26 and 25", len(rows), len(seen))
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) Query(context.Context, store.Filter, store.ListOptions) (*store.ListResult, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestExportSourceError(t *testing.T) {
+	err := New(failingSource{}).ExportXLSX(context.Background(), io.Discard, Options{})
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// worksheet is the part of sheet1.xml the tests read back.
+type worksheet struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R    string `xml:"r,attr"`
+			Type string `xml:"t,attr"`
+			Text string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestExportXLSX(t *testing.T) {
+	s := seed(t,
+		models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending},
+		models.StudentApplication{ApplicantID: "stu-2", ProgramCode: "EE", Round: "<early> & \"late\"", Status: status.Pending},
+	)
+	var buf bytes.Buffer
+	err := New(s).ExportXLSX(context.Background(), &buf, Options{
+		Columns: []string{"applicant_id", "program_code", "round"},
+		Filter:  store.Filter{Status: status.Pending},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		f, ok := parts[name]
+		if !ok {
+			t.Fatalf("workbook has no %s", name)
+		}
+		rc, _ := f.Open()
+		var v any
+		if err := xml.NewDecoder(rc).Decode(&v); err != nil {
+			t.Errorf("%s is not XML: %v", name, err)
+		}
+		rc.Close()
+	}
+
+	rc, _ := parts["xl/worksheets/sheet1.xml"].Open()
+	defer rc.Close()
+	var ws worksheet
+	if err := xml.NewDecoder(rc).Decode(&ws); err != nil {
+		t.Fatal(err)
+	}
+	if len(ws.Rows) != 3 {
+		t.Fatalf("sheet has %d rows, want 3", len(ws.Rows))
+	}
+	last := ws.Rows[2]
+	if last.R != "3" || len(last.Cells) != 3 || last.Cells[2].R != "C3" || last.Cells[2].Type != "inlineStr" {
+		t.Errorf("row 3 = %+v", last)
+	}
+	if got := ws.Rows[0].Cells[1].Text; got != "program_code" {
+		t.Errorf("header B1 = %q", got)
+	}
+	if got := last.Cells[2].Text; got != "<early> & \"late\"" {
+		t.Errorf("C3 = %q, want the round unescaped", got)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
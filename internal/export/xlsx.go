@@ -0,0 +1,112 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// MaxXLSXRows is the most rows, header included, an Excel worksheet holds.
+const MaxXLSXRows = 1 << 20
+
+// ErrTooManyRows is returned by ExportXLSX when the selection does not
+// fit in one worksheet; export it as CSV instead.
+var ErrTooManyRows = errors.New("export: too many rows for an XLSX worksheet")
+
+// ExportXLSX implements Exporter. It writes a single-sheet workbook with
+// every cell an inline string, streaming the worksheet into the zip
+// rather than building it in memory.
+func (e *StoreExporter) ExportXLSX(ctx context.Context, w io.Writer, opts Options) error {
+	return e.export(ctx, &xlsxSheet{zw: zip.NewWriter(w)}, opts)
+}
+
+// xlsxParts are the fixed parts of the workbook around its one worksheet.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Applications" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+type xlsxSheet struct {
+	zw   *zip.Writer
+	w    *bufio.Writer // the worksheet entry
+	rows int
+}
+
+func (s *xlsxSheet) header(names []string) error {
+	for _, p := range xlsxParts {
+		f, err := s.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return err
+		}
+	}
+	f, err := s.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	s.w = bufio.NewWriter(f)
+	s.w.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return s.row(names)
+}
+
+func (s *xlsxSheet) row(cells []string) error {
+	if s.rows == MaxXLSXRows {
+		return ErrTooManyRows
+	}
+	s.rows++
+	n := strconv.Itoa(s.rows)
+	s.w.WriteString(`<row r="` + n + `">`)
+	for i, c := range cells {
+		s.w.WriteString(`<c r="` + columnName(i) + n + `" t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(s.w, []byte(c)); err != nil {
+			return err
+		}
+		s.w.WriteString(`</t></is></c>`)
+	}
+	_, err := s.w.WriteString(`</row>`)
+	return err
+}
+
+func (s *xlsxSheet) flush() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.zw.Flush()
+}
+
+func (s *xlsxSheet) close() error {
+	s.w.WriteString(`</sheetData></worksheet>`)
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.zw.Close()
+}
+
+// columnName returns the spreadsheet letters of zero-based column i: A,
+// ..., Z, AA, AB, ...
+func columnName(i int) string {
+	var b []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		b = append([]byte{byte('A' + (i-1)%26)}, b...)
+	}
+	return string(b)
+}
@@ -0,0 +1,408 @@
+// Package handlers implements the admissions API HTTP endpoints.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ws"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// ApplicationHandler serves the /v1/applications endpoints. Students only
+// ever see their own applications; other roles see all of them.
+type ApplicationHandler struct {
+	Store    store.ApplicationStore
+	Programs ProgramChecker
+	// SubmitGuard, if set, wraps POST /v1/applications, e.g. with
+	// deadlines.Enforcer.
+	SubmitGuard router.Middleware
+	// Pagination selects ?after= (keyset, the default) or ?offset= paging
+	// for List.
+	Pagination store.PaginationMode
+	// Notifier, if set, is told about new applications and status changes.
+	// If it fails, the create or update is rolled back.
+	Notifier StatusNotifier
+	// Metrics, if set, counts status transitions.
+	Metrics *metrics.Recorder
+	// Events, if set, is sent an EventStatusChanged for the applicant and
+	// for their tenant's staff once a status change is stored. Unlike
+	// Notifier it cannot roll the change back; a failure is only logged.
+	Events ws.Publisher
+}
+
+// EventStatusChanged is the type of the event published on a status
+// change, with a StatusChangedPayload.
+const EventStatusChanged = "application.status_changed"
+
+// StatusChangedPayload is the payload of an EventStatusChanged event.
+type StatusChangedPayload struct {
+	ApplicationID string       `json:"application_id"`
+	ProgramCode   string       `json:"program_code"`
+	From          status.State `json:"from"`
+	To            status.State `json:"to"`
+}
+
+// StatusNotifier tells an applicant that their application's status
+// changed; notify.StatusMailer implements it.
+type StatusNotifier interface {
+	StatusChanged(ctx context.Context, app *models.StudentApplication) error
+}
+
+// Page sizes for List.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// listResponse is the List envelope. NextCursor is only set in keyset
+// mode.
+type listResponse struct {
+	Data       []models.StudentApplication `json:"data"`
+	Total      int64                       `json:"total"`
+	NextCursor string                      `json:"next_cursor"`
+	HasMore    bool                        `json:"has_more"`
+}
+
+// Register wires the handler's routes.
+func (h *ApplicationHandler) Register(rt *router.Router) {
+	rt.HandleFunc("POST /v1/applications", h.Create, router.With(h.SubmitGuard))
+	rt.HandleFunc("GET /v1/applications", h.List)
+	rt.HandleFunc("GET /v1/applications/{id}", h.Get)
+	rt.HandleFunc("PUT /v1/applications/{id}", h.Update)
+	rt.HandleFunc("DELETE /v1/applications/{id}", h.Delete)
+}
+
+type createApplicationRequest struct {
+	ApplicantID string `json:"applicant_id"`
+	ProgramCode string `json:"program_code"`
+	Round       string `json:"round"`
+}
+
+type updateApplicationRequest struct {
+	ProgramCode *string       `json:"program_code"`
+	Status      *status.State `json:"status"`
+}
+
+// Create handles POST /v1/applications.
+func (h *ApplicationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	var req createApplicationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if claims.Role == rbac.RoleStudent {
+		if req.ApplicantID == "" {
+			req.ApplicantID = claims.Subject
+		}
+		if req.ApplicantID != claims.Subject {
+			respond.Error(w, http.StatusForbidden, "FORBIDDEN", "students can only apply for themselves")
+			return
+		}
+	}
+
+	var errs []FieldError
+	if req.ApplicantID == "" {
+		errs = append(errs, FieldError{"applicant_id", "is required"})
+	}
+	errs = append(errs, h.validateProgram(req.ProgramCode)...)
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+
+	app := &models.StudentApplication{
+		ApplicantID: req.ApplicantID,
+		ProgramCode: req.ProgramCode,
+		Round:       req.Round,
+		Status:      status.Pending,
+	}
+	if err := h.Store.Create(r.Context(), app); err != nil {
+		storeError(w, r, err)
+		return
+	}
+	if h.Notifier != nil {
+		if err := h.Notifier.StatusChanged(r.Context(), app); err != nil {
+			// Create has no commit hook; the record has been visible only
+			// for the length of the send.
+			if delErr := h.Store.HardDelete(r.Context(), app.ID); delErr != nil {
+				err = errors.Join(err, delErr)
+			}
+			notificationFailed(w, r, err)
+			return
+		}
+	}
+	respond.JSON(w, http.StatusCreated, app)
+}
+
+// Get handles GET /v1/applications/{id}.
+func (h *ApplicationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	respond.JSON(w, http.StatusOK, app)
+}
+
+// List handles GET /v1/applications?applicant_id=&limit=&after= (or
+// &offset= in offset mode). Students are always scoped to themselves.
+func (h *ApplicationHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	applicantID := q.Get("applicant_id")
+	if claims.Role == rbac.RoleStudent {
+		applicantID = claims.Subject
+	}
+	var errs []FieldError
+	if applicantID == "" {
+		errs = append(errs, FieldError{"applicant_id", "is required"})
+	}
+	opts, pageErrs := h.listOptions(q)
+	if errs = append(errs, pageErrs...); len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	res, err := h.Store.List(r.Context(), applicantID, opts)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		validationFailed(w, []FieldError{{"after", "is not a cursor returned by this endpoint"}})
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	resp := listResponse{Data: res.Items, Total: res.Total, HasMore: res.HasMore}
+	if h.Pagination != store.PaginationOffset {
+		resp.NextCursor = res.NextCursor
+	}
+	respond.JSON(w, http.StatusOK, resp)
+}
+
+// listOptions parses the paging query parameters for h's pagination mode.
+func (h *ApplicationHandler) listOptions(q url.Values) (store.ListOptions, []FieldError) {
+	opts := store.ListOptions{Limit: DefaultPageSize}
+	var errs []FieldError
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			errs = append(errs, FieldError{"limit", fmt.Sprintf("must be an integer between 1 and %d", MaxPageSize)})
+		}
+		opts.Limit = n
+	}
+	if h.Pagination == store.PaginationOffset {
+		if q.Has("after") {
+			errs = append(errs, FieldError{"after", "is not supported with offset pagination; use offset"})
+		}
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				errs = append(errs, FieldError{"offset", "must be a non-negative integer"})
+			}
+			opts.Offset = n
+		}
+		return opts, errs
+	}
+	if q.Has("offset") {
+		errs = append(errs, FieldError{"offset", "is not supported with keyset pagination; use after"})
+	}
+	opts.Cursor = q.Get("after")
+	return opts, errs
+}
+
+// Update handles PUT /v1/applications/{id}. Students may change the
+// program of their own application; only staff may change its status.
+func (h *ApplicationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	claims, _ := middleware.ClaimsFromContext(r.Context())
+	var req updateApplicationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var errs []FieldError
+	if req.ProgramCode == nil && req.Status == nil {
+		errs = append(errs, FieldError{"body", "at least one of program_code or status is required"})
+	}
+	if req.ProgramCode != nil {
+		errs = append(errs, h.validateProgram(*req.ProgramCode)...)
+	}
+	if req.Status != nil && !status.Default().Known(*req.Status) {
+		errs = append(errs, FieldError{"status", fmt.Sprintf("must be one of %v", status.Default().States())})
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	if req.Status != nil && claims.Role == rbac.RoleStudent {
+		respond.Error(w, http.StatusForbidden, "FORBIDDEN", "students cannot change application status")
+		return
+	}
+
+	if req.ProgramCode != nil {
+		app.ProgramCode = *req.ProgramCode
+	}
+	var notify func(*models.StudentApplication) error
+	var notifyErr error
+	if req.Status != nil && *req.Status != app.Status && h.Notifier != nil {
+		notify = func(next *models.StudentApplication) error {
+			notifyErr = h.Notifier.StatusChanged(r.Context(), next)
+			return notifyErr
+		}
+	}
+	from := app.Status
+	if req.Status != nil {
+		app.Status = *req.Status
+	}
+	if err := h.Store.UpdateFunc(r.Context(), app, notify); err != nil {
+		if notifyErr != nil {
+			notificationFailed(w, r, err)
+			return
+		}
+		storeError(w, r, err)
+		return
+	}
+	if app.Status != from {
+		h.Metrics.StatusTransition(string(from), string(app.Status))
+		h.publishStatusChange(r, app, from)
+	}
+	respond.JSON(w, http.StatusOK, app)
+}
+
+func (h *ApplicationHandler) publishStatusChange(r *http.Request, app *models.StudentApplication, from status.State) {
+	if h.Events == nil {
+		return
+	}
+	event := ws.Event{
+		Type:    EventStatusChanged,
+		Payload: StatusChangedPayload{ApplicationID: app.ID, ProgramCode: app.ProgramCode, From: from, To: app.Status},
+		At:      app.UpdatedAt,
+	}
+	// Advisors and admins see every application of their tenant, so they
+	// all hear of it; an application made outside any tenant belongs to
+	// the default one, as do tokens without a tenant.
+	tenantID := app.TenantID
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	err := errors.Join(h.Events.Publish(app.ApplicantID, event), h.Events.PublishStaff(tenantID, event))
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("publish status change", "application_id", app.ID, "error", err)
+	}
+}
+
+// Delete handles DELETE /v1/applications/{id}, a soft delete. With
+// X-Hard-Delete: true an admin removes the record for good, including one
+// already soft-deleted.
+func (h *ApplicationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	del, opts := h.Store.Delete, []store.QueryOption(nil)
+	if r.Header.Get("X-Hard-Delete") == "true" {
+		if claims.Role != rbac.RoleAdmin {
+			respond.Error(w, http.StatusForbidden, "FORBIDDEN", "only admins can hard-delete applications")
+			return
+		}
+		del, opts = h.Store.HardDelete, []store.QueryOption{store.WithDeleted()}
+	}
+	app, ok := loadApplication(w, r, h.Store, opts...)
+	if !ok {
+		return
+	}
+	if err := del(r.Context(), app.ID); err != nil {
+		storeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ApplicationHandler) load(w http.ResponseWriter, r *http.Request) (*models.StudentApplication, bool) {
+	return loadApplication(w, r, h.Store)
+}
+
+// loadApplication fetches the {id} application, answering 404 both for
+// missing records and for other students' records so IDs cannot be probed.
+// Soft-deleted records are missing unless qopts say otherwise.
+func loadApplication(w http.ResponseWriter, r *http.Request, st store.ApplicationStore, qopts ...store.QueryOption) (*models.StudentApplication, bool) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return nil, false
+	}
+	app, err := st.GetByID(r.Context(), r.PathValue("id"), qopts...)
+	if err == nil && claims.Role == rbac.RoleStudent && app.ApplicantID != claims.Subject {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return nil, false
+	}
+	return app, true
+}
+
+func (h *ApplicationHandler) validateProgram(code string) []FieldError {
+	return validateProgram(h.Programs, code)
+}
+
+func validateProgram(programs ProgramChecker, code string) []FieldError {
+	switch {
+	case code == "":
+		return []FieldError{{"program_code", "is required"}}
+	case programs == nil || !programs.KnownProgram(code):
+		return []FieldError{{"program_code", "unknown program code " + code}}
+	}
+	return nil
+}
+
+func callerClaims(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing credentials")
+	}
+	return claims, ok
+}
+
+// notificationFailed answers a write that was rolled back because the
+// applicant could not be notified.
+func notificationFailed(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error("notification failed, change rolled back", "error", err)
+	respond.Error(w, http.StatusInternalServerError, "NOTIFICATION_FAILED", "could not notify the applicant; the change was not saved")
+}
+
+func storeError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *status.ErrInvalidTransition
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "application not found")
+		return
+	case errors.As(err, &invalid):
+		respond.Error(w, http.StatusUnprocessableEntity, "INVALID_TRANSITION",
+			fmt.Sprintf("cannot change status from %s to %s", invalid.From, invalid.To))
+		return
+	}
+	logging.FromContext(r.Context()).Error("store failed", "error", err)
+	respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+}
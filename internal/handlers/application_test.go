@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ws"
+)
+
+func newApplicationAPI(t *testing.T) *testAPI {
+	api := newTestAPI(t)
+	h := &ApplicationHandler{Store: store.NewMemoryStore(), Programs: NewProgramSet("CS,EE")}
+	h.Register(api.router)
+	return api
+}
+
+func TestApplicationCRUD(t *testing.T) {
+	api := newApplicationAPI(t)
+
+	var created models.StudentApplication
+	rec := api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &created)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	if created.ID == "" || created.ApplicantID != "stu-1" || created.Status != status.Pending || created.SubmittedAt.IsZero() {
+		t.Fatalf("created = %+v", created)
+	}
+
+	var got models.StudentApplication
+	if rec := api.do("GET", "/v1/applications/"+created.ID, "stu-1", "student", nil, &got); rec.Code != http.StatusOK || got.ID != created.ID {
+		t.Fatalf("get: %d %+v", rec.Code, got)
+	}
+
+	var list struct {
+		Data []models.StudentApplication `json:"data"`
+	}
+	if rec := api.do("GET", "/v1/applications", "stu-1", "student", nil, &list); rec.Code != http.StatusOK || len(list.Data) != 1 {
+		t.Fatalf("list: %d %+v", rec.Code, list)
+	}
+
+	var updated models.StudentApplication
+	rec = api.do("PUT", "/v1/applications/"+created.ID, "adv-1", "advisor", map[string]string{"status": "under_review"}, &updated)
+	if rec.Code != http.StatusOK || updated.Status != status.UnderReview {
+		t.Fatalf("update: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := api.do("DELETE", "/v1/applications/"+created.ID, "admin-1", "admin", nil, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	if rec := api.do("GET", "/v1/applications/"+created.ID, "admin-1", "admin", nil, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("get after delete: %d", rec.Code)
+	}
+}
+
+func TestApplicationValidation(t *testing.T) {
+	api := newApplicationAPI(t)
+	tests := []struct {
+		name  string
+		role  string
+		body  string
+		code  string
+		field string
+	}{
+		{"missing program", "student", `{}`, "VALIDATION_FAILED", "program_code"},
+		{"unknown program", "student", `{"program_code":"ASTRO"}`, "VALIDATION_FAILED", "program_code"},
+		{"staff missing applicant", "advisor", `{"program_code":"CS"}`, "VALIDATION_FAILED", "applicant_id"},
+		{"unknown field", "student", `{"program_code":"CS","status":"accepted"}`, "INVALID_JSON", ""},
+		{"not json", "student", `program=CS`, "INVALID_JSON", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do("POST", "/v1/applications", "u-1", tt.role, tt.body, nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+			if got := errorCode(t, rec); got != tt.code {
+				t.Fatalf("code = %s, want %s", got, tt.code)
+			}
+			if tt.field != "" && !strings.Contains(rec.Body.String(), `"field":"`+tt.field+`"`) {
+				t.Fatalf("body does not name field %s: %s", tt.field, rec.Body)
+			}
+		})
+	}
+}
+
+func TestApplicationStudentIsolation(t *testing.T) {
+	api := newApplicationAPI(t)
+	var app models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "EE"}, &app)
+
+	if rec := api.do("GET", "/v1/applications/"+app.ID, "stu-2", "student", nil, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("other student read: %d", rec.Code)
+	}
+	if rec := api.do("POST", "/v1/applications", "stu-2", "student", map[string]string{"applicant_id": "stu-1", "program_code": "EE"}, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("apply on behalf of another student: %d", rec.Code)
+	}
+	if rec := api.do("PUT", "/v1/applications/"+app.ID, "stu-1", "student", map[string]string{"status": "accepted"}, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("student self-accept: %d", rec.Code)
+	}
+	if rec := api.do("GET", "/v1/applications/"+app.ID, "", "", nil, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous read: %d", rec.Code)
+	}
+}
+
+func TestApplicationInvalidTransition(t *testing.T) {
+	api := newApplicationAPI(t)
+	var app models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &app)
+
+	rec := api.do("PUT", "/v1/applications/"+app.ID, "adv-1", "advisor", map[string]string{"status": "enrolled"}, nil)
+	if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != "INVALID_TRANSITION" {
+		t.Fatalf("pending -> enrolled: %d %s", rec.Code, rec.Body)
+	}
+	var got models.StudentApplication
+	api.do("GET", "/v1/applications/"+app.ID, "adv-1", "advisor", nil, &got)
+	if got.Status != status.Pending {
+		t.Fatalf("rejected transition was persisted: %s", got.Status)
+	}
+	if rec := api.do("PUT", "/v1/applications/"+app.ID, "adv-1", "advisor", map[string]string{"status": "reviewed"}, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status: %d", rec.Code)
+	}
+}
+
+func TestApplicationStatusMetrics(t *testing.T) {
+	api := newTestAPI(t)
+	rec := metrics.NewRecorder(nil)
+	h := &ApplicationHandler{Store: store.NewMemoryStore(), Programs: NewProgramSet("CS"), Metrics: rec}
+	h.Register(api.router)
+
+	var app models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &app)
+	path := "/v1/applications/" + app.ID
+	api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "under_review"}, nil)
+	api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "under_review"}, nil)
+	api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "enrolled"}, nil)
+
+	if got := testutil.ToFloat64(rec.StatusTransitions.WithLabelValues("pending", "under_review")); got != 1 {
+		t.Errorf("pending -> under_review = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(rec.StatusTransitions); got != 1 {
+		t.Errorf("transition series = %d, want only the applied change", got)
+	}
+}
+
+// publisher records the events published to it.
+type publisher struct {
+	users  []string
+	staff  []string
+	events []ws.Event
+	err    error
+}
+
+func (p *publisher) Publish(userID string, e ws.Event) error {
+	p.users, p.events = append(p.users, userID), append(p.events, e)
+	return p.err
+}
+
+func (p *publisher) PublishStaff(tenantID string, e ws.Event) error {
+	p.staff = append(p.staff, tenantID)
+	return p.err
+}
+
+func TestApplicationStatusEvents(t *testing.T) {
+	api := newTestAPI(t)
+	events := &publisher{}
+	h := &ApplicationHandler{Store: store.NewMemoryStore(), Programs: NewProgramSet("CS,EE"), Events: events}
+	h.Register(api.router)
+
+	var app models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &app)
+	path := "/v1/applications/" + app.ID
+	api.do("PUT", path, "stu-1", "student", map[string]string{"program_code": "EE"}, nil)
+	api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "pending"}, nil)
+	if len(events.events) != 0 {
+		t.Fatalf("events without a status change: %+v", events.events)
+	}
+
+	if rec := api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "under_review"}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", rec.Code, rec.Body)
+	}
+	want := StatusChangedPayload{ApplicationID: app.ID, ProgramCode: "EE", From: status.Pending, To: "under_review"}
+	if len(events.events) != 1 || events.users[0] != "stu-1" || events.events[0].Type != EventStatusChanged || events.events[0].Payload != want || events.events[0].At.IsZero() {
+		t.Fatalf("published %v %+v, want %+v to stu-1", events.users, events.events, want)
+	}
+	if len(events.staff) != 1 || events.staff[0] != tenant.DefaultID {
+		t.Errorf("published to the staff of %v, want %s", events.staff, tenant.DefaultID)
+	}
+
+	// The change is stored by then, so a failed publish does not fail it.
+	events.err = errors.New("hub gone")
+	if rec := api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "accepted"}, nil); rec.Code != http.StatusOK {
+		t.Errorf("update with a failing publisher: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestApplicationStatusEventsReachStaff(t *testing.T) {
+	api := newTestAPI(t)
+	hub := ws.NewHub()
+	h := &ApplicationHandler{Store: store.NewMemoryStore(), Programs: NewProgramSet("CS"), Events: hub}
+	h.Register(api.router)
+	api.router.Handle("GET /v1/ws", hub)
+	srv := httptest.NewServer(api.router)
+	defer srv.Close()
+	dial := func(subject, role string) *websocket.Conn {
+		pair, err := api.issuer.Issue(subject, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := websocket.Dialer{Subprotocols: []string{middleware.WebSocketTokenProtocol, pair.AccessToken}}
+		conn, _, err := d.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", nil)
+		if err != nil {
+			t.Fatalf("dial as %s: %v", subject, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	advisor, other := dial("adv-2", "advisor"), dial("stu-2", "student")
+	for deadline := time.Now().Add(2 * time.Second); hub.StaffConnections(tenant.DefaultID) != 1 || hub.Connections("stu-2") != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the connections")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var app models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &app)
+	if rec := api.do("PUT", "/v1/applications/"+app.ID, "adv-1", "advisor", map[string]string{"status": "under_review"}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", rec.Code, rec.Body)
+	}
+	advisor.SetReadDeadline(time.Now().Add(time.Second))
+	var e struct {
+		Type    string
+		Payload StatusChangedPayload
+	}
+	if err := advisor.ReadJSON(&e); err != nil {
+		t.Fatalf("advisor: %v", err)
+	}
+	if e.Type != EventStatusChanged || e.Payload.ApplicationID != app.ID || e.Payload.To != status.UnderReview {
+		t.Errorf("advisor got %+v", e)
+	}
+	other.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, msg, err := other.ReadMessage(); err == nil {
+		t.Errorf("another student got %s", msg)
+	}
+}
+
+func TestApplicationListPagination(t *testing.T) {
+	for _, mode := range []store.PaginationMode{store.PaginationKeyset, store.PaginationOffset} {
+		api := newTestAPI(t)
+		h := &ApplicationHandler{Store: store.NewMemoryStore(), Programs: NewProgramSet("CS"), Pagination: mode}
+		h.Register(api.router)
+		for i := 0; i < 5; i++ {
+			if rec := api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, nil); rec.Code != http.StatusCreated {
+				t.Fatalf("create: %d %s", rec.Code, rec.Body)
+			}
+		}
+
+		var page listResponse
+		seen := map[string]bool{}
+		next := "/v1/applications?limit=2"
+		for i := 0; next != ""; i++ {
+			if i > 3 {
+				t.Fatalf("%s: paging did not terminate", mode)
+			}
+			if rec := api.do("GET", next, "stu-1", "student", nil, &page); rec.Code != http.StatusOK {
+				t.Fatalf("%s: list: %d %s", mode, rec.Code, rec.Body)
+			}
+			if page.Total != 5 || len(page.Data) > 2 {
+				t.Fatalf("%s: page = %+v", mode, page)
+			}
+			for _, app := range page.Data {
+				seen[app.ID] = true
+			}
+			next = ""
+			if page.HasMore && mode == store.PaginationKeyset {
+				next = "/v1/applications?limit=2&after=" + page.NextCursor
+			} else if page.HasMore {
+				if page.NextCursor != "" {
+					t.Errorf("offset mode returned a cursor")
+				}
+				next = fmt.Sprintf("/v1/applications?limit=2&offset=%d", 2*(i+1))
+			}
+		}
+		if len(seen) != 5 {
+			t.Errorf("%s: paged through %d distinct applications, want 5", mode, len(seen))
+		}
+
+		wrongParam := "/v1/applications?offset=2"
+		if mode == store.PaginationOffset {
+			wrongParam = "/v1/applications?after=abc"
+		}
+		for _, path := range []string{"/v1/applications?limit=0", "/v1/applications?limit=101", "/v1/applications?limit=x", wrongParam} {
+			if rec := api.do("GET", path, "stu-1", "student", nil, nil); rec.Code != http.StatusBadRequest || errorCode(t, rec) != "VALIDATION_FAILED" {
+				t.Errorf("%s: GET %s = %d %s, want 400", mode, path, rec.Code, rec.Body)
+			}
+		}
+	}
+}
+
+func TestApplicationHardDelete(t *testing.T) {
+	api := newTestAPI(t)
+	st := store.NewMemoryStore()
+	(&ApplicationHandler{Store: st, Programs: NewProgramSet("CS")}).Register(api.router)
+	hardDelete := func(id, role string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("DELETE", "/v1/applications/"+id, nil)
+		req.Header.Set("X-Hard-Delete", "true")
+		pair, err := api.issuer.Issue(role+"-1", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		rec := httptest.NewRecorder()
+		api.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var created models.StudentApplication
+	api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &created)
+	if rec := api.do("DELETE", "/v1/applications/"+created.ID, "admin-1", "admin", nil, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("soft delete: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := st.GetByID(context.Background(), created.ID, store.WithDeleted()); err != nil {
+		t.Fatalf("soft-deleted record gone from the store: %v", err)
+	}
+
+	if rec := hardDelete(created.ID, "advisor"); rec.Code != http.StatusForbidden || errorCode(t, rec) != "FORBIDDEN" {
+		t.Errorf("advisor hard delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := hardDelete(created.ID, "admin"); rec.Code != http.StatusNoContent {
+		t.Fatalf("admin hard delete of a soft-deleted record: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := st.GetByID(context.Background(), created.ID, store.WithDeleted()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("after hard delete GetByID = %v, want ErrNotFound", err)
+	}
+	if rec := hardDelete(created.ID, "admin"); rec.Code != http.StatusNotFound {
+		t.Errorf("second hard delete: %d", rec.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/audit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// AuditHandler serves GET /v1/audit to admins.
+type AuditHandler struct {
+	Recorder audit.Recorder
+}
+
+// Register wires the handler's routes.
+func (h *AuditHandler) Register(rt *router.Router) {
+	rt.HandleFunc("GET /v1/audit", h.List)
+}
+
+// List handles GET /v1/audit?entity_id=&start=&end=, with start and end
+// as RFC 3339 times, start inclusive and end exclusive. Entries are
+// streamed oldest first as newline-delimited JSON, one audit.Entry per
+// line, however many there are.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	if claims.Role != rbac.RoleAdmin {
+		respond.Error(w, http.StatusForbidden, "FORBIDDEN", "only admins can read the audit log")
+		return
+	}
+	q := r.URL.Query()
+	query := audit.Query{EntityID: q.Get("entity_id")}
+	var errs []FieldError
+	for _, p := range []struct {
+		name string
+		into *time.Time
+	}{{"start", &query.Start}, {"end", &query.End}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, FieldError{p.name, "must be an RFC 3339 time such as 2026-03-01T09:00:00Z"})
+			}
+			*p.into = t
+		}
+	}
+	if !query.Start.IsZero() && !query.End.IsZero() && !query.End.After(query.Start) {
+		errs = append(errs, FieldError{"end", "must be after start"})
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	sent := 0
+	err := h.Recorder.Query(r.Context(), query, func(e audit.Entry) error {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		if sent++; flusher != nil && sent%100 == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	logging.FromContext(r.Context()).Error("audit query failed", "entries_sent", sent, "error", err)
+	if sent == 0 {
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "audit query failed")
+		return
+	}
+	// The status line has gone out; cut the connection so the client sees
+	// a broken stream rather than one that looks complete.
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/audit"
+)
+
+func TestAuditList(t *testing.T) {
+	rec := audit.NewMemoryRecorder()
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"app-1", "app-2", "app-1", "app-1"} {
+		rec.Record(context.Background(), &audit.Entry{
+			Time: at.Add(time.Duration(i) * time.Hour), ActorID: "adm-1", Action: audit.ActionUpdate,
+			EntityType: audit.EntityApplication, EntityID: id,
+			Diff: []audit.Change{{Path: "status", From: "pending", To: "under_review"}},
+		})
+	}
+	api := newTestAPI(t)
+	(&AuditHandler{Recorder: rec}).Register(api.router)
+
+	res := api.do("GET", "/v1/audit?entity_id=app-1&start=2026-03-01T10:00:00Z&end=2026-03-01T12:00:00%2B00:00", "adm-1", "admin", nil, nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("admin list: %d %s", res.Code, res.Body)
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got []audit.Entry
+	for sc := bufio.NewScanner(res.Body); sc.Scan(); {
+		var e audit.Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 1 || got[0].ID != 3 || got[0].ActorID != "adm-1" || got[0].Diff[0].To != "under_review" {
+		t.Errorf("entries = %+v, want only entry 3", got)
+	}
+
+	for _, tc := range []struct {
+		name, path, role string
+		status           int
+		code             string
+	}{
+		{"advisor", "/v1/audit", "advisor", http.StatusForbidden, "FORBIDDEN"},
+		{"bad start", "/v1/audit?start=yesterday", "admin", http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"end before start", "/v1/audit?start=2026-03-02T00:00:00Z&end=2026-03-01T00:00:00Z", "admin", http.StatusBadRequest, "VALIDATION_FAILED"},
+	} {
+		if res := api.do("GET", tc.path, "u-1", tc.role, nil, nil); res.Code != tc.status || errorCode(t, res) != tc.code {
+			t.Errorf("%s: %d %s, want %d %s", tc.name, res.Code, res.Body, tc.status, tc.code)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/jobs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// multipartOverhead is the slack allowed on top of the file size for
+// multipart boundaries and part headers.
+const multipartOverhead = 64 << 10
+
+// DocumentHandler serves the /v1/applications/{id}/documents endpoints
+// with the same visibility rules as ApplicationHandler.
+type DocumentHandler struct {
+	Applications store.ApplicationStore
+	Uploader     documents.Uploader
+	Metas        documents.MetaStore
+	// MaxSize caps the request body; the uploader enforces the exact file
+	// limit. Zero means documents.DefaultMaxSize.
+	MaxSize int64
+	// UploadLimit, when its Limit is set, gives uploads their own
+	// rate-limit budget instead of the router's default.
+	UploadLimit ratelimit.Config
+	// Metrics, if set, counts stored bytes.
+	Metrics *metrics.Recorder
+	// Jobs, if set, receives a ScanDocument and a GenerateThumbnail job
+	// for every stored document.
+	Jobs jobs.Queue
+}
+
+// Register wires the handler's routes.
+func (h *DocumentHandler) Register(rt *router.Router) {
+	var opts []router.Option
+	if h.UploadLimit.Limit > 0 {
+		opts = append(opts, router.Limit(h.UploadLimit))
+	}
+	rt.HandleFunc("POST /v1/applications/{id}/documents", h.Upload, opts...)
+	rt.HandleFunc("GET /v1/applications/{id}/documents", h.List)
+}
+
+// Upload handles POST /v1/applications/{id}/documents. The body is a
+// multipart form whose "file" part is streamed to the uploader without
+// being buffered.
+func (h *DocumentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	max := h.MaxSize
+	if max <= 0 {
+		max = documents.DefaultMaxSize
+	}
+	part, ok := filePart(w, r, max)
+	if !ok {
+		return
+	}
+	meta, err := h.Uploader.Upload(r.Context(), app.ID, part, part.FileName(), -1)
+	part.Close()
+	if err != nil {
+		uploadError(w, r, err, max)
+		return
+	}
+	if err := h.Metas.Create(r.Context(), meta); err != nil {
+		logging.FromContext(r.Context()).Error("save document metadata", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+		return
+	}
+	h.Metrics.UploadedBytes(meta.Size)
+	enqueueProcessing(r, h.Jobs, meta)
+	respond.JSON(w, http.StatusCreated, meta)
+}
+
+// filePart caps r's body for a file of up to max bytes and returns its
+// multipart "file" part, unread, or writes the error response. The caller
+// closes the part.
+func filePart(w http.ResponseWriter, r *http.Request, max int64) (*multipart.Part, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, max+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		respond.Error(w, http.StatusBadRequest, "INVALID_MULTIPART", "request body must be multipart/form-data")
+		return nil, false
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			validationFailed(w, []FieldError{{"file", "is required"}})
+			return nil, false
+		}
+		if err != nil {
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				uploadError(w, r, err, max)
+				return nil, false
+			}
+			respond.Error(w, http.StatusBadRequest, "INVALID_MULTIPART", "malformed multipart body: "+err.Error())
+			return nil, false
+		}
+		if part.FormName() == "file" {
+			return part, true
+		}
+		part.Close()
+	}
+}
+
+// enqueueProcessing queues the background work for a stored document on
+// q, if set. The upload has succeeded either way, so a queue failure is
+// logged rather than returned.
+func enqueueProcessing(r *http.Request, q jobs.Queue, meta *documents.Meta) {
+	if q == nil {
+		return
+	}
+	for _, job := range []jobs.Job{
+		jobs.ScanDocument{DocumentID: meta.ID, ApplicationID: meta.ApplicationID, Key: meta.Key, MIMEType: meta.MIMEType},
+		jobs.GenerateThumbnail{DocumentID: meta.ID, Key: meta.Key, MIMEType: meta.MIMEType},
+	} {
+		if err := q.Enqueue(r.Context(), job); err != nil {
+			logging.FromContext(r.Context()).Error("enqueue document job", "job_type", job.Type(), "document_id", meta.ID, "error", err)
+		}
+	}
+}
+
+// List handles GET /v1/applications/{id}/documents.
+func (h *DocumentHandler) List(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	metas, err := h.Metas.ListByApplication(r.Context(), app.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("list document metadata", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"data": metas})
+}
+
+func uploadError(w http.ResponseWriter, r *http.Request, err error, max int64) {
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.Is(err, documents.ErrUnsupportedType):
+		respond.Error(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "file must be a PDF, JPEG, or PNG")
+	case errors.Is(err, documents.ErrTooLarge), errors.As(err, &tooBig):
+		respond.Error(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", fmt.Sprintf("file exceeds %d bytes", max))
+	case errors.Is(err, circuit.ErrOpen):
+		respond.Error(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "document storage is unavailable; try again shortly")
+	default:
+		logging.FromContext(r.Context()).Error("upload document", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "upload failed")
+	}
+}
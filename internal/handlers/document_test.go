@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/jobs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+)
+
+func newDocumentAPI(t *testing.T, configure ...func(*DocumentHandler)) (*testAPI, *models.StudentApplication) {
+	api := newTestAPI(t)
+	apps := store.NewMemoryStore()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}
+	if err := apps.Create(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	uploader := documents.NewLocalUploader(t.TempDir())
+	uploader.MaxSize = 1 << 10
+	h := &DocumentHandler{Applications: apps, Uploader: uploader, Metas: documents.NewMemoryMetaStore(), MaxSize: 1 << 10}
+	for _, c := range configure {
+		c(h)
+	}
+	h.Register(api.router)
+	return api, app
+}
+
+// upload posts data as the "file" part of a multipart form.
+func (a *testAPI) upload(path, subject, role, filename string, data []byte) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("note", "ignored")
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	pair, err := a.issuer.Issue(subject, role)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDocumentUpload(t *testing.T) {
+	api, app := newDocumentAPI(t)
+	path := "/v1/applications/" + app.ID + "/documents"
+
+	rec := api.upload(path, "stu-1", "student", "transcript.pdf", []byte("%PDF-1.7\n..."))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+	}
+	var meta documents.Meta
+	if err := json.Unmarshal(rec.Body.Bytes(), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.ApplicationID != app.ID || meta.MIMEType != documents.MIMEPDF || meta.Filename != "transcript.pdf" {
+		t.Errorf("meta = %+v", meta)
+	}
+
+	var list struct {
+		Data []documents.Meta `json:"data"`
+	}
+	if rec := api.do("GET", path, "adv-1", "advisor", nil, &list); rec.Code != http.StatusOK || len(list.Data) != 1 || list.Data[0].ID != meta.ID {
+		t.Fatalf("list: %d %+v", rec.Code, list)
+	}
+}
+
+func TestDocumentUploadQueuesProcessing(t *testing.T) {
+	q := jobs.NewMemoryQueue()
+	api, app := newDocumentAPI(t, func(h *DocumentHandler) { h.Jobs = q })
+	rec := api.upload("/v1/applications/"+app.ID+"/documents", "stu-1", "student", "scan.png", []byte("\x89PNG\r\n\x1a\n..."))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+	}
+	var meta documents.Meta
+	json.Unmarshal(rec.Body.Bytes(), &meta)
+
+	if q.Len() != 2 {
+		t.Fatalf("%d jobs queued, want 2", q.Len())
+	}
+	types := map[string]bool{}
+	for range 2 {
+		job, err := q.Dequeue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload struct {
+			DocumentID string `json:"document_id"`
+			Key        string `json:"key"`
+		}
+		if err := jobs.Decode(job, &payload); err != nil || payload.DocumentID != meta.ID || payload.Key != meta.Key {
+			t.Errorf("%s payload = %+v (%v), want document %s", job.Type(), payload, err, meta.ID)
+		}
+		types[job.Type()] = true
+	}
+	if !types[jobs.TypeScanDocument] || !types[jobs.TypeGenerateThumbnail] {
+		t.Errorf("queued %v", types)
+	}
+}
+
+func TestDocumentUploadRejections(t *testing.T) {
+	api, app := newDocumentAPI(t)
+	path := "/v1/applications/" + app.ID + "/documents"
+	tests := []struct {
+		name, subject, filename string
+		data                    []byte
+		status                  int
+		code                    string
+	}{
+		{"text renamed to pdf", "stu-1", "transcript.pdf", []byte("just text"), http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"over the size limit", "stu-1", "scan.png", append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, make([]byte, 2<<10)...), http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE"},
+		{"another student's application", "stu-2", "id.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0}, http.StatusNotFound, "NOT_FOUND"},
+	}
+	for _, tc := range tests {
+		rec := api.upload(path, tc.subject, "student", tc.filename, tc.data)
+		if rec.Code != tc.status || errorCode(t, rec) != tc.code {
+			t.Errorf("%s: %d %s, want %d %s", tc.name, rec.Code, rec.Body, tc.status, tc.code)
+		}
+	}
+
+	if rec := api.do("POST", path, "stu-1", "student", map[string]string{"file": "x"}, nil); rec.Code != http.StatusBadRequest || errorCode(t, rec) != "INVALID_MULTIPART" {
+		t.Errorf("JSON body: %d %s", rec.Code, rec.Body)
+	}
+}
+
+// openUploader is storage behind an open circuit breaker.
+type openUploader struct{}
+
+func (openUploader) Upload(context.Context, string, io.Reader, string, int64) (*documents.Meta, error) {
+	return nil, fmt.Errorf("s3: %w", circuit.ErrOpen)
+}
+
+func TestDocumentUploadStorageUnavailable(t *testing.T) {
+	api, app := newDocumentAPI(t, func(h *DocumentHandler) { h.Uploader = openUploader{} })
+	rec := api.upload("/v1/applications/"+app.ID+"/documents", "stu-1", "student", "id.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0})
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != "STORAGE_UNAVAILABLE" {
+		t.Errorf("%d %s, want 503 STORAGE_UNAVAILABLE", rec.Code, rec.Body)
+	}
+}
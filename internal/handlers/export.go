@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/export"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// ExportHandler serves GET /v1/applications/export. Students only ever
+// export their own applications; other roles export all of them.
+type ExportHandler struct {
+	Exporter export.Exporter
+	// DateFormat is the time.Format layout for timestamps; empty means
+	// RFC 3339.
+	DateFormat string
+}
+
+// exportFormats maps the format parameter to the Content-Type served.
+var exportFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Register wires the handler's routes.
+func (h *ExportHandler) Register(rt *router.Router) {
+	rt.HandleFunc("GET /v1/applications/export", h.Export)
+}
+
+// Export handles GET /v1/applications/export?format=csv|xlsx&columns=
+// &program=&status=&applicant_id=, streaming the file as an attachment.
+// The format defaults to csv and the columns to all of them.
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	opts := export.Options{
+		Filter: store.Filter{
+			ApplicantID: q.Get("applicant_id"),
+			ProgramCode: q.Get("program"),
+			Status:      status.State(q.Get("status")),
+		},
+		DateFormat: h.DateFormat,
+	}
+	if claims.Role == rbac.RoleStudent {
+		opts.Filter.ApplicantID = claims.Subject
+	}
+	var errs []FieldError
+	if _, ok := exportFormats[format]; !ok {
+		errs = append(errs, FieldError{"format", "must be csv or xlsx"})
+	}
+	if v := q.Get("columns"); v != "" {
+		known := export.Columns()
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			switch {
+			case !slices.Contains(known, name):
+				errs = append(errs, FieldError{"columns", fmt.Sprintf("unknown column %q; must be among %v", name, known)})
+			case slices.Contains(opts.Columns, name):
+				errs = append(errs, FieldError{"columns", fmt.Sprintf("column %q is listed twice", name)})
+			default:
+				opts.Columns = append(opts.Columns, name)
+			}
+		}
+	}
+	if opts.Filter.Status != "" && !status.Default().Known(opts.Filter.Status) {
+		errs = append(errs, FieldError{"status", fmt.Sprintf("must be one of %v", status.Default().States())})
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportFormats[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="applications-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+	out := &countingWriter{w: w}
+	var err error
+	if format == "xlsx" {
+		err = h.Exporter.ExportXLSX(r.Context(), out, opts)
+	} else {
+		err = h.Exporter.ExportCSV(r.Context(), out, opts)
+	}
+	if err == nil {
+		return
+	}
+	logging.FromContext(r.Context()).Error("export failed", "format", format, "bytes_written", out.n, "error", err)
+	if out.n == 0 {
+		w.Header().Del("Content-Disposition")
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "export failed")
+		return
+	}
+	// The status line has gone out; cut the connection so the client sees
+	// a broken download rather than a file that looks complete.
+	panic(http.ErrAbortHandler)
+}
+
+// countingWriter counts the bytes written through it. Empty writes are
+// dropped so they do not commit the response status.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/export"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func newExportAPI(t *testing.T, exp export.Exporter) *testAPI {
+	t.Helper()
+	api := newTestAPI(t)
+	(&ExportHandler{Exporter: exp}).Register(api.router)
+	return api
+}
+
+func TestExportApplications(t *testing.T) {
+	apps := store.NewMemoryStore()
+	for _, app := range []models.StudentApplication{
+		{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"},
+		{ApplicantID: "stu-2", ProgramCode: "CS", Status: "accepted"},
+		{ApplicantID: "stu-2", ProgramCode: "EE", Status: "pending"},
+	} {
+		if err := apps.Create(context.Background(), &app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := newExportAPI(t, export.New(apps))
+
+	rec := api.do("GET", "/v1/applications/export?format=csv&columns=applicant_id,status&program=CS", "adv-1", "advisor", nil, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("advisor export: %d %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="applications-`) || !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows; len(got) != 3 || strings.Join(got[0], ",") != "applicant_id,status" || got[1][0] != "stu-1" || got[2][1] != "accepted" {
+		t.Errorf("advisor export = %q", got)
+	}
+
+	rec = api.do("GET", "/v1/applications/export?columns=applicant_id&applicant_id=stu-2", "stu-1", "student", nil, nil)
+	rows, _ = csv.NewReader(rec.Body).ReadAll()
+	if rec.Code != http.StatusOK || len(rows) != 2 || rows[1][0] != "stu-1" {
+		t.Errorf("student export: %d %q", rec.Code, rows)
+	}
+
+	rec = api.do("GET", "/v1/applications/export?format=xlsx", "adv-1", "advisor", nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("xlsx export: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("PK")) {
+		t.Errorf("xlsx export is not a zip: %q", rec.Body.Bytes()[:min(rec.Body.Len(), 16)])
+	}
+
+	for _, path := range []string{
+		"/v1/applications/export?format=pdf",
+		"/v1/applications/export?columns=id,email",
+		"/v1/applications/export?columns=id,id",
+		"/v1/applications/export?status=lost",
+	} {
+		rec := api.do("GET", path, "adv-1", "advisor", nil, nil)
+		if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "VALIDATION_FAILED" {
+			t.Errorf("%s: got %d %s, want 400", path, rec.Code, rec.Body)
+		}
+	}
+	if rec := api.do("GET", "/v1/applications/export", "", "", nil, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous export: got %d, want 401", rec.Code)
+	}
+}
+
+// brokenExporter fails after writing written bytes.
+type brokenExporter struct {
+	written string
+}
+
+func (b brokenExporter) ExportCSV(_ context.Context, w io.Writer, _ export.Options) error {
+	io.WriteString(w, b.written)
+	return errors.New("store went away")
+}
+
+func (b brokenExporter) ExportXLSX(ctx context.Context, w io.Writer, opts export.Options) error {
+	return b.ExportCSV(ctx, w, opts)
+}
+
+func TestExportFailure(t *testing.T) {
+	api := newExportAPI(t, brokenExporter{})
+	rec := api.do("GET", "/v1/applications/export", "adv-1", "advisor", nil, nil)
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("failure before any output: %d, Content-Disposition %q", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+
+	api = newExportAPI(t, brokenExporter{written: "id\n"})
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("failure mid-stream: recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	api.do("GET", "/v1/applications/export", "adv-1", "advisor", nil, nil)
+}
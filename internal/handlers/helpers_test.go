@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+const testSecret = "test-secret"
+
+// testAPI is a router with JWT auth plus a helper to call it as a user.
+type testAPI struct {
+	t      *testing.T
+	router *router.Router
+	issuer *auth.Issuer
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	return &testAPI{
+		t:      t,
+		router: router.New(middleware.JWTAuth(testSecret)),
+		issuer: auth.NewIssuer(testSecret),
+	}
+}
+
+// do sends a request as subject/role (no auth header when role is empty)
+// and decodes a JSON response into out when out is non-nil.
+func (a *testAPI) do(method, path, subject, role string, body any, out any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if s, ok := body.(string); ok {
+			buf.WriteString(s)
+		} else if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if role != "" {
+		pair, err := a.issuer.Issue(subject, role)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("decode %s %s response %q: %v", method, path, rec.Body, err)
+		}
+	}
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q: %v", rec.Body, err)
+	}
+	return body.Code
+}
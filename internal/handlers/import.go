@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/importer"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// ImportHandler serves POST /v1/applications/import, where admissions
+// staff create applications in bulk from a CSV or XLSX sheet. Imported
+// applications are pending, like created ones, but applicants are not
+// notified of them.
+type ImportHandler struct {
+	Store    store.ApplicationStore
+	Programs ProgramChecker
+	// MaxSize caps the uploaded file; zero means importer.DefaultMaxSize.
+	MaxSize int64
+	// TempDir is where uploads are spooled while they are read; empty
+	// means os.TempDir.
+	TempDir string
+}
+
+// importColumns are the header names a sheet may use, in report order.
+var importColumns = []string{"applicant_id", "program_code", "round"}
+
+// importReport is the Import response. A sheet that cannot be read past
+// some row ends the import there, with Error set; the rows before it
+// stay imported.
+type importReport struct {
+	Accepted int         `json:"accepted"`
+	Rejected int         `json:"rejected"`
+	Rows     []importRow `json:"rows"`
+	Error    string      `json:"error,omitempty"`
+}
+
+type importRow struct {
+	Row    int          `json:"row"`
+	Status string       `json:"status"`
+	ID     string       `json:"id,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Register wires the handler's routes.
+func (h *ImportHandler) Register(rt *router.Router) {
+	rt.HandleFunc("POST /v1/applications/import", h.Import)
+}
+
+// Import handles POST /v1/applications/import. The body is a multipart
+// form whose "file" part is a .csv or .xlsx sheet with a header row
+// naming applicant_id, program_code, and optionally round. Each row is
+// validated and stored on its own, and the response reports every row as
+// accepted, with the application's ID, or rejected, with its errors. The
+// upload is spooled to disk, up to MaxSize, and then read a row at a
+// time, so an oversized file is refused before any row is imported.
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	if claims.Role == rbac.RoleStudent {
+		respond.Error(w, http.StatusForbidden, "FORBIDDEN", "students cannot import applications")
+		return
+	}
+	max := h.MaxSize
+	if max <= 0 {
+		max = importer.DefaultMaxSize
+	}
+	part, ok := filePart(w, r, max)
+	if !ok {
+		return
+	}
+	defer part.Close()
+	format, err := importer.FormatOf(part.FileName(), part.Header.Get("Content-Type"))
+	if err != nil {
+		respond.Error(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "file must be a .csv or .xlsx sheet")
+		return
+	}
+	f, err := os.CreateTemp(h.TempDir, "import-*."+format)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("spool import", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "import failed")
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	n, err := io.Copy(f, io.LimitReader(part, max+1))
+	var tooBig *http.MaxBytesError
+	switch {
+	case n > max || errors.As(err, &tooBig):
+		respond.Error(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", fmt.Sprintf("file exceeds %d bytes", max))
+		return
+	case err != nil:
+		respond.Error(w, http.StatusBadRequest, "INVALID_MULTIPART", "malformed multipart body: "+err.Error())
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		logging.FromContext(r.Context()).Error("spool import", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "import failed")
+		return
+	}
+
+	var sheet importer.Reader = importer.NewCSV(f)
+	if format == importer.XLSX {
+		if sheet, err = importer.OpenXLSX(f, n); err != nil {
+			validationFailed(w, []FieldError{{"file", "is not a readable XLSX workbook: " + err.Error()}})
+			return
+		}
+	}
+	defer sheet.Close()
+	cols, errs := importHeader(sheet)
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	respond.JSON(w, http.StatusOK, h.importRows(r, sheet, cols))
+}
+
+// importHeader reads the header row, returning the column of each name
+// in importColumns, or -1 for an optional one the sheet leaves out.
+func importHeader(sheet importer.Reader) (map[string]int, []FieldError) {
+	var header importer.Row
+	for header.Blank() {
+		var err error
+		if header, err = sheet.Next(); errors.Is(err, io.EOF) {
+			return nil, []FieldError{{"file", "has no header row"}}
+		} else if err != nil {
+			return nil, []FieldError{{"file", "header row: " + err.Error()}}
+		}
+	}
+	cols := map[string]int{"round": -1}
+	seen := map[string]bool{}
+	var errs []FieldError
+	for i, name := range header.Cells {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case !slices.Contains(importColumns, name):
+			errs = append(errs, FieldError{"file", fmt.Sprintf("unknown column %q; must be among %v", name, importColumns)})
+		case seen[name]:
+			errs = append(errs, FieldError{"file", fmt.Sprintf("column %q is listed twice", name)})
+		default:
+			seen[name], cols[name] = true, i
+		}
+	}
+	for _, name := range importColumns[:2] {
+		if !seen[name] {
+			errs = append(errs, FieldError{"file", "missing column " + name})
+		}
+	}
+	return cols, errs
+}
+
+// importRows imports the rows after the header, skipping blank ones.
+func (h *ImportHandler) importRows(r *http.Request, sheet importer.Reader, cols map[string]int) importReport {
+	report := importReport{Rows: []importRow{}}
+	reject := func(row int, errs ...FieldError) {
+		report.Rejected++
+		report.Rows = append(report.Rows, importRow{Row: row, Status: "rejected", Errors: errs})
+	}
+	firstRow := map[string]int{} // applicant, program, and round to the row importing them
+	for {
+		row, err := sheet.Next()
+		var rowErr *importer.RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			return report
+		case errors.As(err, &rowErr):
+			reject(rowErr.Row, FieldError{"row", rowErr.Err.Error()})
+			continue
+		case err != nil:
+			report.Error = err.Error()
+			return report
+		case row.Blank():
+			continue
+		}
+		cell := func(name string) string {
+			if i := cols[name]; i >= 0 && i < len(row.Cells) {
+				return strings.TrimSpace(row.Cells[i])
+			}
+			return ""
+		}
+		app := &models.StudentApplication{
+			ApplicantID: cell("applicant_id"),
+			ProgramCode: cell("program_code"),
+			Round:       cell("round"),
+			Status:      status.Pending,
+		}
+		var errs []FieldError
+		if app.ApplicantID == "" {
+			errs = append(errs, FieldError{"applicant_id", "is required"})
+		}
+		errs = append(errs, validateProgram(h.Programs, app.ProgramCode)...)
+		key := app.ApplicantID + "\x00" + app.ProgramCode + "\x00" + app.Round
+		if first, dup := firstRow[key]; dup {
+			errs = append(errs, FieldError{"row", fmt.Sprintf("duplicates row %d", first)})
+		}
+		if len(errs) > 0 {
+			reject(row.Num, errs...)
+			continue
+		}
+		if err := h.Store.Create(r.Context(), app); err != nil {
+			if r.Context().Err() != nil {
+				// The client is gone; nobody will read the report.
+				return report
+			}
+			logging.FromContext(r.Context()).Error("import application", "row", row.Num, "error", err)
+			reject(row.Num, FieldError{"row", "could not be saved; import it again"})
+			continue
+		}
+		firstRow[key] = row.Num
+		report.Accepted++
+		report.Rows = append(report.Rows, importRow{Row: row.Num, Status: "accepted", ID: app.ID})
+	}
+}
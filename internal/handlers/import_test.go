@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func newImportAPI(t *testing.T) (*testAPI, *store.MemoryStore) {
+	api := newTestAPI(t)
+	st := store.NewMemoryStore()
+	(&ImportHandler{Store: st, Programs: NewProgramSet("CS,EE"), MaxSize: 4 << 10, TempDir: t.TempDir()}).Register(api.router)
+	return api, st
+}
+
+func decodeReport(t *testing.T, body *bytes.Buffer) importReport {
+	t.Helper()
+	var report importReport
+	if err := json.Unmarshal(body.Bytes(), &report); err != nil {
+		t.Fatalf("report %q: %v", body, err)
+	}
+	return report
+}
+
+func TestImportCSV(t *testing.T) {
+	api, st := newImportAPI(t)
+	sheet := "Program_Code,applicant_id,round\nCS,stu-1,early\nEE,stu-2,\n\nCS,stu-3,regular\n"
+	rec := api.upload("/v1/applications/import", "admin-1", "admin", "applicants.csv", []byte(sheet))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", rec.Code, rec.Body)
+	}
+	report := decodeReport(t, rec.Body)
+	if report.Accepted != 3 || report.Rejected != 0 || len(report.Rows) != 3 || report.Rows[2].Row != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+	res, err := st.List(context.Background(), "stu-1", store.ListOptions{Limit: 10})
+	if err != nil || len(res.Items) != 1 {
+		t.Fatalf("stu-1 applications: %v %v", res.Items, err)
+	}
+	if app := res.Items[0]; app.ID != report.Rows[0].ID || app.ProgramCode != "CS" || app.Round != "early" || app.Status != "pending" {
+		t.Errorf("imported %+v", app)
+	}
+}
+
+func TestImportXLSX(t *testing.T) {
+	api, _ := newImportAPI(t)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Applicants" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>applicant_id</t></si><si><t>program_code</t></si><si><t>CS</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>stu-1</t></is></c><c r="B2" t="s"><v>2</v></c></row>` +
+			`<row r="3"><c r="A3"><v>20231</v></c><c r="B3" t="inlineStr"><is><t>LAW</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	} {
+		f, _ := zw.Create(name)
+		f.Write([]byte(body))
+	}
+	zw.Close()
+	rec := api.upload("/v1/applications/import", "admin-1", "admin", "applicants.xlsx", buf.Bytes())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", rec.Code, rec.Body)
+	}
+	report := decodeReport(t, rec.Body)
+	if report.Accepted != 1 || report.Rejected != 1 || report.Rows[1].Row != 3 || report.Rows[1].Errors[0].Field != "program_code" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestImportRejectsInvalidRows(t *testing.T) {
+	api, _ := newImportAPI(t)
+	sheet := strings.Join([]string{
+		"applicant_id,program_code",
+		"stu-1,CS",
+		",CS",       // no applicant
+		"stu-2,LAW", // unknown program
+		`stu-3,"C"S"`,
+		"stu-1,CS", // the same application again
+		"stu-4,EE",
+	}, "\n")
+	rec := api.upload("/v1/applications/import", "admin-1", "admin", "applicants.csv", []byte(sheet))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", rec.Code, rec.Body)
+	}
+	report := decodeReport(t, rec.Body)
+	if report.Accepted != 2 || report.Rejected != 4 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[int]string{3: "applicant_id", 4: "program_code", 5: "row", 6: "row"}
+	for _, row := range report.Rows {
+		field, rejected := want[row.Row]
+		if rejected != (row.Status == "rejected") || rejected && row.Errors[0].Field != field {
+			t.Errorf("row %d: %+v", row.Row, row)
+		}
+	}
+	if msg := report.Rows[4].Errors[0].Message; msg != "duplicates row 2" {
+		t.Errorf("duplicate row: %q", msg)
+	}
+}
+
+func TestImportRejectsFile(t *testing.T) {
+	api, st := newImportAPI(t)
+	oversized := "applicant_id,program_code\n" + strings.Repeat("stu-1,CS\n", 1000)
+	for _, tc := range []struct {
+		name, filename, sheet, role string
+		status                      int
+	}{
+		{"oversized", "applicants.csv", oversized, "admin", http.StatusRequestEntityTooLarge},
+		{"student", "applicants.csv", "applicant_id,program_code\nstu-1,CS\n", "student", http.StatusForbidden},
+		{"xls", "applicants.xls", "applicant_id,program_code\n", "admin", http.StatusUnsupportedMediaType},
+		{"missing column", "applicants.csv", "applicant_id,programme\nstu-1,CS\n", "admin", http.StatusBadRequest},
+		{"empty", "applicants.csv", "\n\n", "admin", http.StatusBadRequest},
+		{"corrupt workbook", "applicants.xlsx", "applicant_id,program_code\n", "admin", http.StatusBadRequest},
+	} {
+		if rec := api.upload("/v1/applications/import", "admin-1", tc.role, tc.filename, []byte(tc.sheet)); rec.Code != tc.status {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.status)
+		}
+	}
+	if res, _ := st.List(context.Background(), "stu-1", store.ListOptions{Limit: 10}); len(res.Items) != 0 {
+		t.Errorf("rejected files imported %d applications", len(res.Items))
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/interviews"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// InterviewHandler serves interview scheduling. Admins open slots under
+// /v1/slots; applicants book and cancel a place for an application under
+// /v1/applications/{id}/interview, with the same visibility rules as
+// ApplicationHandler.
+type InterviewHandler struct {
+	Applications store.ApplicationStore
+	Interviews   interviews.Store
+	// Programs, if set, limits slots to the codes it knows.
+	Programs ProgramChecker
+	// Mailer and Directory email the applicant a confirmation of each
+	// booking; without a Mailer none is sent.
+	Mailer    notify.EmailSender
+	Directory notify.Directory
+	// CancelCutoff is how long before its slot a booking can last be
+	// cancelled; zero means interviews.DefaultCancelCutoff.
+	CancelCutoff time.Duration
+	// Clock defaults to clock.System.
+	Clock clock.Clock
+}
+
+// Register wires the handler's routes.
+func (h *InterviewHandler) Register(rt *router.Router) {
+	rt.HandleFunc("POST /v1/slots", h.CreateSlot)
+	rt.HandleFunc("GET /v1/slots", h.ListSlots)
+	rt.HandleFunc("POST /v1/applications/{id}/interview", h.Book)
+	rt.HandleFunc("DELETE /v1/applications/{id}/interview", h.Cancel)
+}
+
+type createSlotRequest struct {
+	ProgramCode   string    `json:"program_code"`
+	StartAt       time.Time `json:"start_at"`
+	EndAt         time.Time `json:"end_at"`
+	AdvisorID     string    `json:"advisor_id"`
+	MaxCandidates int       `json:"max_candidates"`
+}
+
+// CreateSlot handles POST /v1/slots, opening a slot for admins.
+func (h *InterviewHandler) CreateSlot(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "only admins can open interview slots") {
+		return
+	}
+	var body createSlotRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	var errs []FieldError
+	switch {
+	case body.ProgramCode == "":
+		errs = append(errs, FieldError{"program_code", "is required"})
+	case h.Programs != nil && !h.Programs.KnownProgram(body.ProgramCode):
+		errs = append(errs, FieldError{"program_code", "unknown program code " + body.ProgramCode})
+	}
+	if body.StartAt.IsZero() {
+		errs = append(errs, FieldError{"start_at", "is required"})
+	} else if !body.StartAt.After(h.now()) {
+		errs = append(errs, FieldError{"start_at", "must be in the future"})
+	}
+	if !body.EndAt.After(body.StartAt) {
+		errs = append(errs, FieldError{"end_at", "must be after start_at"})
+	}
+	if strings.TrimSpace(body.AdvisorID) == "" {
+		errs = append(errs, FieldError{"advisor_id", "is required"})
+	}
+	if body.MaxCandidates < 1 {
+		errs = append(errs, FieldError{"max_candidates", "must be at least 1"})
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	slot := &models.InterviewSlot{
+		ProgramCode:   body.ProgramCode,
+		StartAt:       body.StartAt.UTC(),
+		EndAt:         body.EndAt.UTC(),
+		AdvisorID:     body.AdvisorID,
+		MaxCandidates: body.MaxCandidates,
+	}
+	if err := h.Interviews.CreateSlot(r.Context(), slot); err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, slot)
+}
+
+// ListSlots handles GET /v1/slots?program_code=, a program's slots with
+// how many places each has booked, earliest first.
+func (h *InterviewHandler) ListSlots(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("program_code")
+	if code == "" {
+		validationFailed(w, []FieldError{{"program_code", "is required"}})
+		return
+	}
+	slots, err := h.Interviews.ListSlots(r.Context(), code)
+	if err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"data": slots})
+}
+
+type bookInterviewRequest struct {
+	SlotID string `json:"slot_id"`
+}
+
+// Book handles POST /v1/applications/{id}/interview, booking the
+// application a place in a future slot of its program. A full slot, or
+// an application already booked for its program, answers 409.
+func (h *InterviewHandler) Book(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	var body bookInterviewRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.SlotID == "" {
+		validationFailed(w, []FieldError{{"slot_id", "is required"}})
+		return
+	}
+	slot, err := h.Interviews.GetSlot(r.Context(), body.SlotID)
+	if err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	now := h.now()
+	switch {
+	case slot.ProgramCode != app.ProgramCode:
+		validationFailed(w, []FieldError{{"slot_id", "is a slot of another program"}})
+		return
+	case !slot.StartAt.After(now):
+		validationFailed(w, []FieldError{{"slot_id", "has already started"}})
+		return
+	}
+	booking, err := h.Interviews.Book(r.Context(), slot.ID, app.ID, now)
+	if err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	h.confirm(r, app, slot)
+	respond.JSON(w, http.StatusCreated, booking)
+}
+
+// Cancel handles DELETE /v1/applications/{id}/interview, freeing the
+// application's place up to CancelCutoff before its slot starts; later,
+// it answers 409.
+func (h *InterviewHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	booking, err := h.Interviews.BookingFor(r.Context(), app.ID, app.ProgramCode)
+	if err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	slot, err := h.Interviews.GetSlot(r.Context(), booking.SlotID)
+	if err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	if h.now().After(slot.StartAt.Add(-h.cancelCutoff())) {
+		respond.Error(w, http.StatusConflict, "CANCEL_WINDOW_CLOSED", "the interview is too close to cancel; contact the admissions office")
+		return
+	}
+	if err := h.Interviews.Cancel(r.Context(), booking.ID); err != nil {
+		interviewError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// confirm emails the applicant their booking. The booking stands even if
+// the email fails, so a failure is only logged.
+func (h *InterviewHandler) confirm(r *http.Request, app *models.StudentApplication, slot *models.InterviewSlot) {
+	if h.Mailer == nil {
+		return
+	}
+	log := logging.FromContext(r.Context())
+	to, err := h.Directory.Email(r.Context(), app.ApplicantID)
+	if err != nil {
+		log.Error("interview confirmation address", "application_id", app.ID, "error", err)
+		return
+	}
+	msg := notify.InterviewMessage
+	data := notify.InterviewData{ApplicationID: app.ID, ProgramCode: slot.ProgramCode, StartAt: slot.StartAt, EndAt: slot.EndAt}
+	if err := h.Mailer.Send(r.Context(), to, msg.Subject, msg.Template, data); err != nil {
+		log.Error("send interview confirmation", "application_id", app.ID, "error", err)
+	}
+}
+
+func (h *InterviewHandler) now() time.Time {
+	if h.Clock == nil {
+		return clock.System.Now().UTC()
+	}
+	return h.Clock.Now().UTC()
+}
+
+func (h *InterviewHandler) cancelCutoff() time.Duration {
+	if h.CancelCutoff <= 0 {
+		return interviews.DefaultCancelCutoff
+	}
+	return h.CancelCutoff
+}
+
+func interviewError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "interview slot or booking not found")
+	case errors.Is(err, interviews.ErrFull):
+		respond.Error(w, http.StatusConflict, "SLOT_FULL", "this interview slot has no places left")
+	case errors.Is(err, interviews.ErrAlreadyBooked):
+		respond.Error(w, http.StatusConflict, "ALREADY_BOOKED", "this application already has an interview booked for its program; cancel it first")
+	default:
+		logging.FromContext(r.Context()).Error("interview store failed", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+	}
+}
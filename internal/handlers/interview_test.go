@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/interviews"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+type interviewAPI struct {
+	*testAPI
+	app    *models.StudentApplication
+	sender *notify.MockSender
+	now    time.Time
+}
+
+func newInterviewAPI(t *testing.T) *interviewAPI {
+	templates, err := notify.LoadTemplates("../../config/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := store.NewMemoryStore()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}
+	if err := apps.Create(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	a := &interviewAPI{
+		testAPI: newTestAPI(t),
+		app:     app,
+		sender:  &notify.MockSender{Templates: templates},
+		now:     time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	h := &InterviewHandler{
+		Applications: apps,
+		Interviews:   interviews.NewMemoryStore(),
+		Programs:     NewProgramSet("CS,EE"),
+		Mailer:       a.sender,
+		Directory:    notify.StaticDirectory{"stu-1": "stu-1@example.edu"},
+		Clock:        clock.Func(func() time.Time { return a.now }),
+	}
+	h.Register(a.router)
+	return a
+}
+
+// slot opens a slot as an admin.
+func (a *interviewAPI) slot(program string, start time.Time, max int) models.InterviewSlot {
+	a.t.Helper()
+	var slot models.InterviewSlot
+	body := map[string]any{"program_code": program, "start_at": start, "end_at": start.Add(30 * time.Minute), "advisor_id": "adv-1", "max_candidates": max}
+	if rec := a.do("POST", "/v1/slots", "admin-1", "admin", body, &slot); rec.Code != http.StatusCreated {
+		a.t.Fatalf("create slot: %d %s", rec.Code, rec.Body)
+	}
+	return slot
+}
+
+func (a *interviewAPI) book(appID, subject, slotID string) *http.Response {
+	a.t.Helper()
+	return a.do("POST", "/v1/applications/"+appID+"/interview", subject, "student", map[string]string{"slot_id": slotID}, nil).Result()
+}
+
+func TestCreateSlot(t *testing.T) {
+	a := newInterviewAPI(t)
+	start := a.now.Add(48 * time.Hour)
+	slot := a.slot("CS", start, 2)
+	if slot.ID == "" || !slot.StartAt.Equal(start) || slot.MaxCandidates != 2 {
+		t.Fatalf("created %+v", slot)
+	}
+	body := map[string]any{"program_code": "CS", "start_at": start, "end_at": start.Add(time.Hour), "advisor_id": "adv-1", "max_candidates": 1}
+	if rec := a.do("POST", "/v1/slots", "stu-1", "student", body, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("student creating a slot: %d", rec.Code)
+	}
+	bad := map[string]any{"program_code": "LAW", "start_at": a.now.Add(-time.Hour), "end_at": a.now.Add(-2 * time.Hour), "max_candidates": 0}
+	rec := a.do("POST", "/v1/slots", "admin-1", "admin", bad, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid slot: %d %s", rec.Code, rec.Body)
+	}
+	for _, field := range []string{"program_code", "start_at", "end_at", "advisor_id", "max_candidates"} {
+		if !strings.Contains(rec.Body.String(), `"`+field+`"`) {
+			t.Errorf("no error for %s: %s", field, rec.Body)
+		}
+	}
+
+	var list struct {
+		Data []models.InterviewSlot `json:"data"`
+	}
+	if rec := a.do("GET", "/v1/slots?program_code=CS", "stu-1", "student", nil, &list); rec.Code != http.StatusOK || len(list.Data) != 1 || list.Data[0].ID != slot.ID {
+		t.Errorf("list: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBookInterview(t *testing.T) {
+	a := newInterviewAPI(t)
+	slot := a.slot("CS", a.now.Add(72*time.Hour), 1)
+	other := a.slot("CS", a.now.Add(96*time.Hour), 5)
+
+	var booking models.InterviewBooking
+	rec := a.do("POST", "/v1/applications/"+a.app.ID+"/interview", "stu-1", "student", map[string]string{"slot_id": slot.ID}, &booking)
+	if rec.Code != http.StatusCreated || booking.SlotID != slot.ID || booking.ApplicationID != a.app.ID || !booking.BookedAt.Equal(a.now) {
+		t.Fatalf("book: %d %s", rec.Code, rec.Body)
+	}
+	sent := a.sender.Sent()
+	if len(sent) != 1 || sent[0].To != "stu-1@example.edu" || sent[0].Template != notify.InterviewMessage.Template || !strings.Contains(sent[0].Body, "Wednesday 4 March 2026, 09:00") {
+		t.Fatalf("confirmation = %+v", sent)
+	}
+
+	// Booking again, even another slot of the program, conflicts.
+	if res := a.book(a.app.ID, "stu-1", other.ID); res.StatusCode != http.StatusConflict {
+		t.Errorf("second booking: %d", res.StatusCode)
+	}
+
+	if res := a.book(a.app.ID, "stu-2", other.ID); res.StatusCode != http.StatusNotFound {
+		t.Errorf("booking another applicant's application: %d", res.StatusCode)
+	}
+	if res := a.book(a.app.ID, "stu-1", "no-such-slot"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown slot: %d", res.StatusCode)
+	}
+	ee := a.slot("EE", a.now.Add(72*time.Hour), 1)
+	if res := a.book(a.app.ID, "stu-1", ee.ID); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("slot of another program: %d", res.StatusCode)
+	}
+}
+
+func TestBookInterviewFullSlot(t *testing.T) {
+	a := newInterviewAPI(t)
+	h := &InterviewHandler{Applications: store.NewMemoryStore(), Interviews: interviews.NewMemoryStore(), Clock: clock.Func(func() time.Time { return a.now })}
+	api := newTestAPI(t)
+	h.Register(api.router)
+	start := a.now.Add(72 * time.Hour)
+	slot := &models.InterviewSlot{ProgramCode: "CS", StartAt: start, EndAt: start.Add(time.Hour), AdvisorID: "adv-1", MaxCandidates: 1}
+	if err := h.Interviews.CreateSlot(context.Background(), slot); err != nil {
+		t.Fatal(err)
+	}
+	for i, subject := range []string{"stu-1", "stu-2"} {
+		app := &models.StudentApplication{ApplicantID: subject, ProgramCode: "CS", Status: "pending"}
+		if err := h.Applications.Create(context.Background(), app); err != nil {
+			t.Fatal(err)
+		}
+		rec := api.do("POST", "/v1/applications/"+app.ID+"/interview", subject, "student", map[string]string{"slot_id": slot.ID}, nil)
+		want := []int{http.StatusCreated, http.StatusConflict}[i]
+		if rec.Code != want {
+			t.Fatalf("%s: %d %s, want %d", subject, rec.Code, rec.Body, want)
+		}
+		if i == 1 && errorCode(t, rec) != "SLOT_FULL" {
+			t.Errorf("full slot code %s", errorCode(t, rec))
+		}
+	}
+}
+
+func TestCancelInterview(t *testing.T) {
+	a := newInterviewAPI(t)
+	slot := a.slot("CS", a.now.Add(72*time.Hour), 1)
+	path := "/v1/applications/" + a.app.ID + "/interview"
+	if rec := a.do("DELETE", path, "stu-1", "student", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel without a booking: %d", rec.Code)
+	}
+	if res := a.book(a.app.ID, "stu-1", slot.ID); res.StatusCode != http.StatusCreated {
+		t.Fatalf("book: %d", res.StatusCode)
+	}
+
+	a.now = slot.StartAt.Add(-23 * time.Hour)
+	rec := a.do("DELETE", path, "stu-1", "student", nil, nil)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "CANCEL_WINDOW_CLOSED" {
+		t.Fatalf("cancel within a day: %d %s", rec.Code, rec.Body)
+	}
+
+	a.now = slot.StartAt.Add(-25 * time.Hour)
+	if rec := a.do("DELETE", path, "stu-1", "student", nil, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("cancel: %d %s", rec.Code, rec.Body)
+	}
+	if res := a.book(a.app.ID, "stu-1", slot.ID); res.StatusCode != http.StatusCreated {
+		t.Errorf("rebooking the freed place: %d", res.StatusCode)
+	}
+}
+
+func TestInterviewConfirmationFailureKeepsBooking(t *testing.T) {
+	a := newInterviewAPI(t)
+	a.sender.Err = errors.New("relay down")
+	slot := a.slot("CS", a.now.Add(72*time.Hour), 1)
+	if res := a.book(a.app.ID, "stu-1", slot.ID); res.StatusCode != http.StatusCreated {
+		t.Fatalf("book with a failing mailer: %d", res.StatusCode)
+	}
+	var list struct {
+		Data []models.InterviewSlot `json:"data"`
+	}
+	if a.do("GET", "/v1/slots?program_code=CS", "stu-1", "student", nil, &list); len(list.Data) != 1 || list.Data[0].Booked != 1 {
+		t.Errorf("slots after booking: %+v", list.Data)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/letters"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// LetterGenerator produces the decision letter of an application; see
+// letters.Generator.
+type LetterGenerator interface {
+	Generate(ctx context.Context, appID string) (io.Reader, error)
+}
+
+// LetterHandler serves GET /v1/applications/{id}/letter with the same
+// visibility rules as ApplicationHandler.
+type LetterHandler struct {
+	Applications store.ApplicationStore
+	Letters      LetterGenerator
+}
+
+// Register wires the handler's routes.
+func (h *LetterHandler) Register(rt *router.Router) {
+	rt.HandleFunc("GET /v1/applications/{id}/letter", h.Letter)
+}
+
+// Letter handles GET /v1/applications/{id}/letter, answering with the
+// PDF of the acceptance or rejection letter, or 404 before a decision.
+func (h *LetterHandler) Letter(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	pdf, err := h.Letters.Generate(r.Context(), app.ID)
+	switch {
+	case errors.Is(err, letters.ErrNoDecision):
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "no decision has been made on this application")
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("generate decision letter", "application_id", app.ID, "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "letter generation failed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="decision-letter-%s.pdf"`, app.ID))
+	io.Copy(w, pdf)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/letters"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// pdfRenderer stands in for wkhtmltopdf.
+type pdfRenderer struct{}
+
+func (pdfRenderer) Render(_ context.Context, html []byte) (io.Reader, error) {
+	return io.MultiReader(strings.NewReader("%PDF-1.4\n"), bytes.NewReader(html)), nil
+}
+
+// newLetterAPI serves letters for an application under review and an
+// accepted one, both stu-1's.
+func newLetterAPI(t *testing.T, gen func(store.ApplicationStore) LetterGenerator) (api *testAPI, pending, accepted string) {
+	t.Helper()
+	api = newTestAPI(t)
+	apps := store.NewMemoryStore()
+	var ids []string
+	for _, path := range [][]status.State{{status.UnderReview}, {status.UnderReview, status.Accepted}} {
+		app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending}
+		if err := apps.Create(context.Background(), app); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range path {
+			app.Status = s
+			if err := apps.Update(context.Background(), app); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids = append(ids, app.ID)
+	}
+	(&LetterHandler{Applications: apps, Letters: gen(apps)}).Register(api.router)
+	return api, ids[0], ids[1]
+}
+
+func TestDecisionLetter(t *testing.T) {
+	api, pending, accepted := newLetterAPI(t, func(apps store.ApplicationStore) LetterGenerator {
+		return letters.NewGenerator(apps, pdfRenderer{}, documents.NewLocalUploader(t.TempDir()))
+	})
+
+	rec := api.do("GET", "/v1/applications/"+accepted+"/letter", "stu-1", "student", nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("own letter: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) {
+		t.Errorf("letter is not a PDF: %q", rec.Body.Bytes()[:min(rec.Body.Len(), 16)])
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="decision-letter-`+accepted+`.pdf"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if rec := api.do("GET", "/v1/applications/"+accepted+"/letter", "adv-1", "advisor", nil, nil); rec.Code != http.StatusOK {
+		t.Errorf("advisor: got %d, want 200", rec.Code)
+	}
+
+	for _, tc := range []struct {
+		name, id, subject string
+	}{
+		{"no decision", pending, "stu-1"},
+		{"another student's", accepted, "stu-2"},
+		{"missing application", "missing", "stu-1"},
+	} {
+		rec := api.do("GET", "/v1/applications/"+tc.id+"/letter", tc.subject, "student", nil, nil)
+		if rec.Code != http.StatusNotFound || errorCode(t, rec) != "NOT_FOUND" {
+			t.Errorf("%s: got %d %s, want 404", tc.name, rec.Code, rec.Body)
+		}
+	}
+}
+
+// brokenLetters fails every letter.
+type brokenLetters struct{}
+
+func (brokenLetters) Generate(context.Context, string) (io.Reader, error) {
+	return nil, errors.New("renderer crashed")
+}
+
+func TestDecisionLetterFailure(t *testing.T) {
+	api, _, accepted := newLetterAPI(t, func(store.ApplicationStore) LetterGenerator { return brokenLetters{} })
+	rec := api.do("GET", "/v1/applications/"+accepted+"/letter", "adv-1", "advisor", nil, nil)
+	if rec.Code != http.StatusInternalServerError || errorCode(t, rec) != "INTERNAL_ERROR" {
+		t.Errorf("got %d %s, want 500", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func TestApplicationNotifications(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"application_received.html": `received {{.Application.ID}}`,
+		"under_review.html":         `reviewing {{.Application.ID}}`,
+		// decision.html is missing, so decisions fail to render.
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := notify.LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &notify.MockSender{Templates: templates}
+	apps := store.NewMemoryStore()
+	api := newTestAPI(t)
+	h := &ApplicationHandler{Store: apps, Programs: NewProgramSet("CS"), Notifier: &notify.StatusMailer{
+		Sender:    sender,
+		Directory: notify.StaticDirectory{"stu-1": "ada@example.edu"},
+	}}
+	h.Register(api.router)
+
+	var app models.StudentApplication
+	if rec := api.do("POST", "/v1/applications", "stu-1", "student", map[string]string{"program_code": "CS"}, &app); rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	path := "/v1/applications/" + app.ID
+	if rec := api.do("PUT", path, "stu-1", "student", map[string]string{"program_code": "CS"}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("program change: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "under_review"}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("review: %d %s", rec.Code, rec.Body)
+	}
+	sent := sender.Sent()
+	if len(sent) != 2 || sent[0].Body != "received "+app.ID || sent[1].Body != "reviewing "+app.ID || sent[1].To != "ada@example.edu" {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	rec := api.do("PUT", path, "adv-1", "advisor", map[string]string{"status": "accepted"}, nil)
+	if rec.Code != http.StatusInternalServerError || errorCode(t, rec) != "NOTIFICATION_FAILED" {
+		t.Fatalf("failed render: %d %s", rec.Code, rec.Body)
+	}
+	got, err := apps.GetByID(context.Background(), app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != status.UnderReview {
+		t.Errorf("status = %s after a failed notification, want the update rolled back", got.Status)
+	}
+
+	// stu-2 has no address, so their application is not kept.
+	rec = api.do("POST", "/v1/applications", "stu-2", "student", map[string]string{"program_code": "CS"}, nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("create without address: %d %s", rec.Code, rec.Body)
+	}
+	res, err := apps.List(context.Background(), "stu-2", store.ListOptions{})
+	if err != nil || res.Total != 0 {
+		t.Errorf("stu-2 applications = %+v, %v; want the create rolled back", res, err)
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/listing"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/programs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// ProgramChecker reports whether a program code can be applied to.
+type ProgramChecker interface {
+	KnownProgram(code string) bool
+}
+
+// ProgramSet is a fixed set of program codes.
+type ProgramSet map[string]bool
+
+// NewProgramSet builds a set from codes such as "CS,EE".
+func NewProgramSet(codes ...string) ProgramSet {
+	set := ProgramSet{}
+	for _, c := range codes {
+		for _, code := range strings.Split(c, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				set[code] = true
+			}
+		}
+	}
+	return set
+}
+
+// KnownProgram implements ProgramChecker.
+func (s ProgramSet) KnownProgram(code string) bool { return s[code] }
+
+// ProgramHandler serves the program catalog. Anyone may browse it, since
+// prospective applicants do so before they have an account; only admins
+// change it.
+type ProgramHandler struct {
+	Programs programs.Store
+	// Clock decides which programs ?open=true lists; it defaults to
+	// clock.System.
+	Clock clock.Clock
+}
+
+// Register wires the handler's routes.
+func (h *ProgramHandler) Register(rt *router.Router) {
+	rt.HandleFunc("GET /v1/programs", h.List, router.SkipAuth())
+	rt.HandleFunc("GET /v1/programs/{code}", h.Get, router.SkipAuth())
+	rt.HandleFunc("POST /v1/programs", h.Create)
+	rt.HandleFunc("PUT /v1/programs/{code}", h.Update)
+	rt.HandleFunc("DELETE /v1/programs/{code}", h.Delete)
+}
+
+// programBody is a program as admins write it; the store sets the rest.
+type programBody struct {
+	Name              string               `json:"name"`
+	Faculty           string               `json:"faculty"`
+	RequiredDocuments []string             `json:"required_documents"`
+	Capacity          int                  `json:"capacity"`
+	DeadlinesByRound  map[string]time.Time `json:"deadlines_by_round"`
+}
+
+type createProgramRequest struct {
+	Code string `json:"code"`
+	programBody
+}
+
+func (b programBody) validate() []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(b.Name) == "" {
+		errs = append(errs, FieldError{"name", "is required"})
+	}
+	if strings.TrimSpace(b.Faculty) == "" {
+		errs = append(errs, FieldError{"faculty", "is required"})
+	}
+	if b.Capacity < 0 {
+		errs = append(errs, FieldError{"capacity", "must not be negative"})
+	}
+	for _, doc := range b.RequiredDocuments {
+		if strings.TrimSpace(doc) == "" {
+			errs = append(errs, FieldError{"required_documents", "must not contain empty names"})
+			break
+		}
+	}
+	for round := range b.DeadlinesByRound {
+		if strings.TrimSpace(round) == "" {
+			errs = append(errs, FieldError{"deadlines_by_round", "must not contain an empty round"})
+			break
+		}
+	}
+	return errs
+}
+
+func (b programBody) program(code string) *models.Program {
+	return &models.Program{
+		Code:              code,
+		Name:              b.Name,
+		Faculty:           b.Faculty,
+		RequiredDocuments: b.RequiredDocuments,
+		Capacity:          b.Capacity,
+		DeadlinesByRound:  b.DeadlinesByRound,
+	}
+}
+
+// programListing is the listing of GET /v1/programs.
+var programListing = listing.Options{Sorts: []string{"code", "name", "faculty"}, Filters: []string{"faculty", "open"}}
+
+// List handles GET /v1/programs?filter=faculty:&filter=open:&sort=&limit=&cursor=,
+// a page of the current version of every program, ordered by code unless
+// sort says otherwise. faculty matches case-insensitively; open:true keeps
+// the programs with a round still accepting submissions and open:false
+// the others. ?faculty= and ?open= still work in place of the filters.
+func (h *ProgramHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params, perrs := listing.Parse(q, programListing)
+	errs := paramErrors(perrs)
+	faculty := cmp.Or(params.Filter["faculty"], q.Get("faculty"))
+	var (
+		open     bool
+		openArg  = cmp.Or(params.Filter["open"], q.Get("open"))
+		filtered = openArg != ""
+	)
+	if filtered {
+		var err error
+		if open, err = strconv.ParseBool(openArg); err != nil {
+			errs = append(errs, FieldError{"open", "must be true or false"})
+		}
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	all, err := h.Programs.List(r.Context())
+	if err != nil {
+		programError(w, r, err)
+		return
+	}
+	now := h.now()
+	out := []models.Program{}
+	for _, p := range all {
+		if faculty != "" && !strings.EqualFold(p.Faculty, faculty) {
+			continue
+		}
+		if filtered && p.IsOpen(now) != open {
+			continue
+		}
+		out = append(out, p)
+	}
+	pos := func(p models.Program) listing.Position {
+		switch params.Sort {
+		case "name":
+			return listing.Position{Key: p.Name, ID: p.Code}
+		case "faculty":
+			return listing.Position{Key: p.Faculty, ID: p.Code}
+		}
+		return listing.Position{ID: p.Code}
+	}
+	slices.SortStableFunc(out, func(a, b models.Program) int {
+		pa, pb := pos(a), pos(b)
+		return cmp.Or(cmp.Compare(pa.Key, pb.Key), cmp.Compare(pa.ID, pb.ID))
+	})
+	if params.Desc {
+		slices.Reverse(out)
+	}
+	page, next := listing.Page(out, params, pos)
+	respond.JSON(w, http.StatusOK, map[string]any{"data": page, "next_cursor": next, "has_more": next != ""})
+}
+
+// Get handles GET /v1/programs/{code}?at=, the program's current version
+// or, with at, the one in effect then. at is an RFC 3339 time or a date
+// such as 2024-01-01, which means the version in effect at the end of
+// that day in UTC.
+func (h *ProgramHandler) Get(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	var (
+		p   *models.Program
+		err error
+	)
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, ok := parseAt(at)
+		if !ok {
+			validationFailed(w, []FieldError{{"at", "must be a date such as 2024-01-01 or an RFC 3339 time"}})
+			return
+		}
+		p, err = h.Programs.At(r.Context(), code, t)
+	} else {
+		p, err = h.Programs.Get(r.Context(), code)
+	}
+	if err != nil {
+		programError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, p)
+}
+
+func parseAt(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d.AddDate(0, 0, 1).Add(-time.Nanosecond), true
+}
+
+// Create handles POST /v1/programs, storing version 1 of a new program.
+func (h *ProgramHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "only admins can change the program catalog") {
+		return
+	}
+	var body createProgramRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	errs := body.validate()
+	if code := strings.TrimSpace(body.Code); code == "" || code != body.Code || strings.Contains(code, "/") {
+		errs = append([]FieldError{{"code", "is required and must not contain spaces or slashes"}}, errs...)
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	p := body.program(body.Code)
+	if err := h.Programs.Create(r.Context(), p); err != nil {
+		programError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, p)
+}
+
+// Update handles PUT /v1/programs/{code}, storing the body as the
+// program's next version; the earlier ones stay readable through ?at=.
+func (h *ProgramHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "only admins can change the program catalog") {
+		return
+	}
+	var body programBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if errs := body.validate(); len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	p := body.program(r.PathValue("code"))
+	if err := h.Programs.Update(r.Context(), p); err != nil {
+		programError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, p)
+}
+
+// Delete handles DELETE /v1/programs/{code}, removing the program with
+// its history.
+func (h *ProgramHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "only admins can change the program catalog") {
+		return
+	}
+	if err := h.Programs.Delete(r.Context(), r.PathValue("code")); err != nil {
+		programError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProgramHandler) now() time.Time {
+	if h.Clock == nil {
+		return clock.System.Now()
+	}
+	return h.Clock.Now()
+}
+
+// requireAdmin answers 403 with msg unless the caller is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request, msg string) bool {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return false
+	}
+	if claims.Role != rbac.RoleAdmin {
+		respond.Error(w, http.StatusForbidden, "FORBIDDEN", msg)
+		return false
+	}
+	return true
+}
+
+func programError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "program not found")
+	case errors.Is(err, programs.ErrExists):
+		respond.Error(w, http.StatusConflict, "PROGRAM_EXISTS", "a program with this code already exists")
+	default:
+		logging.FromContext(r.Context()).Error("program store failed", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+	}
+}
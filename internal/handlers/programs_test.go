@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/programs"
+)
+
+func TestProgramCatalog(t *testing.T) {
+	api := newTestAPI(t)
+	catalog := programs.NewMemoryStore()
+	now := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	(&ProgramHandler{Programs: catalog, Clock: clock.Fixed(now)}).Register(api.router)
+
+	cs := map[string]any{
+		"code": "CS", "name": "Computer Science", "faculty": "Engineering", "capacity": 40,
+		"required_documents": []string{"transcript"},
+		"deadlines_by_round": map[string]time.Time{"regular": now.AddDate(0, 1, 0)},
+	}
+	if rec := api.do("POST", "/v1/programs", "stu-1", "student", cs, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("student create: %d", rec.Code)
+	}
+	var created models.Program
+	if rec := api.do("POST", "/v1/programs", "adm-1", "admin", cs, &created); rec.Code != http.StatusCreated || created.Version != 1 {
+		t.Fatalf("create: %d %+v", rec.Code, created)
+	}
+	if rec := api.do("POST", "/v1/programs", "adm-1", "admin", cs, nil); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate create: %d", rec.Code)
+	}
+	if rec := api.do("POST", "/v1/programs", "adm-1", "admin", map[string]any{"code": "EE"}, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("create without a name: %d", rec.Code)
+	}
+	if err := catalog.Create(context.Background(), &models.Program{Code: "HIST", Name: "History", Faculty: "Arts",
+		DeadlinesByRound: map[string]time.Time{"regular": now.AddDate(0, -1, 0)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var list struct {
+		Data []models.Program `json:"data"`
+	}
+	for query, want := range map[string]string{
+		"":                     "CS,HIST",
+		"?faculty=engineering": "CS",
+		"?open=true":           "CS",
+		"?open=false":          "HIST",
+		"?filter=faculty:arts": "HIST",
+		"?filter=open:true":    "CS",
+		"?sort=-code":          "HIST,CS",
+		"?sort=faculty":        "HIST,CS",
+	} {
+		if rec := api.do("GET", "/v1/programs"+query, "", "", nil, &list); rec.Code != http.StatusOK {
+			t.Fatalf("list %s: %d", query, rec.Code)
+		}
+		var codes string
+		for i, p := range list.Data {
+			if i > 0 {
+				codes += ","
+			}
+			codes += p.Code
+		}
+		if codes != want {
+			t.Errorf("list %s = %s, want %s", query, codes, want)
+		}
+	}
+	for _, bad := range []string{"open=maybe", "sort=capacity", "filter=capacity:40", "cursor=nope"} {
+		if rec := api.do("GET", "/v1/programs?"+bad, "", "", nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", bad, rec.Code)
+		}
+	}
+	var page struct {
+		Data       []models.Program `json:"data"`
+		NextCursor string           `json:"next_cursor"`
+		HasMore    bool             `json:"has_more"`
+	}
+	if rec := api.do("GET", "/v1/programs?limit=1&sort=-name", "", "", nil, &page); rec.Code != http.StatusOK || len(page.Data) != 1 || page.Data[0].Code != "HIST" || !page.HasMore {
+		t.Fatalf("first page: %d %+v", rec.Code, page)
+	}
+	if rec := api.do("GET", "/v1/programs?limit=1&sort=-name&cursor="+page.NextCursor, "", "", nil, &page); rec.Code != http.StatusOK || len(page.Data) != 1 || page.Data[0].Code != "CS" || page.HasMore || page.NextCursor != "" {
+		t.Errorf("last page: %d %+v", rec.Code, page)
+	}
+
+	update := map[string]any{"name": "Computer Science", "faculty": "Engineering", "capacity": 60}
+	var updated models.Program
+	if rec := api.do("PUT", "/v1/programs/CS", "adm-1", "admin", update, &updated); rec.Code != http.StatusOK || updated.Version != 2 {
+		t.Fatalf("update: %d %+v", rec.Code, updated)
+	}
+	var got models.Program
+	if rec := api.do("GET", "/v1/programs/CS", "", "", nil, &got); rec.Code != http.StatusOK || got.Capacity != 60 {
+		t.Errorf("current: %d %+v", rec.Code, got)
+	}
+	at := created.ValidFrom.Format(time.RFC3339Nano)
+	if rec := api.do("GET", "/v1/programs/CS?at="+at, "", "", nil, &got); rec.Code != http.StatusOK || got.Version != 1 || got.Capacity != 40 {
+		t.Errorf("at creation: %d %+v", rec.Code, got)
+	}
+	if rec := api.do("GET", "/v1/programs/CS?at=2020-01-01", "", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("before creation: %d", rec.Code)
+	}
+	if rec := api.do("GET", "/v1/programs/CS?at=yesterday", "", "", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad at: %d", rec.Code)
+	}
+	if rec := api.do("PUT", "/v1/programs/EE", "adm-1", "admin", update, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("update unknown: %d", rec.Code)
+	}
+	if rec := api.do("DELETE", "/v1/programs/CS", "adm-1", "admin", nil, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: %d", rec.Code)
+	}
+	if rec := api.do("GET", "/v1/programs/CS", "", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("after delete: %d", rec.Code)
+	}
+}
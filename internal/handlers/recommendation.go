@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/jobs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/recommendations"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// RecommendationHandler serves the recommendation-letter workflow.
+// Applicants request letters and re-send invites under
+// /v1/applications/{id}/recommendations, with the same visibility rules
+// as ApplicationHandler; referees, who have no account, answer through
+// the public /v1/recommendations/submit with the token from their link.
+type RecommendationHandler struct {
+	Applications store.ApplicationStore
+	Requests     recommendations.Store
+	// Uploader and Metas store submitted letters as documents of the
+	// application.
+	Uploader documents.Uploader
+	Metas    documents.MetaStore
+	// Jobs, if set, receives the processing jobs of each submitted
+	// letter, as DocumentHandler.Jobs does for uploads.
+	Jobs jobs.Queue
+	// Mailer emails the invites. Without one, requesting a letter and
+	// re-sending an invite answer 503.
+	Mailer notify.EmailSender
+	// SubmitURL is the page the invite links to; the token is added as
+	// its token query parameter.
+	SubmitURL string
+	// TTL is how long an invite stays valid; zero means
+	// recommendations.DefaultTTL.
+	TTL time.Duration
+	// MaxSize caps a letter; zero means documents.DefaultMaxSize.
+	MaxSize int64
+	// Clock defaults to clock.System.
+	Clock clock.Clock
+}
+
+// Register wires the handler's routes.
+func (h *RecommendationHandler) Register(rt *router.Router) {
+	rt.HandleFunc("POST /v1/applications/{id}/recommendations", h.Create)
+	rt.HandleFunc("GET /v1/applications/{id}/recommendations", h.List)
+	rt.HandleFunc("POST /v1/applications/{id}/recommendations/{rid}/resend", h.Resend)
+	rt.HandleFunc("GET /v1/recommendations/submit", h.Lookup, router.SkipAuth())
+	rt.HandleFunc("POST /v1/recommendations/submit", h.Submit, router.SkipAuth())
+}
+
+type createRecommendationRequest struct {
+	RefereeEmail string `json:"referee_email"`
+}
+
+// Create handles POST /v1/applications/{id}/recommendations, emailing the
+// referee a link before storing the request, so a failed send leaves
+// nothing behind.
+func (h *RecommendationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	var body createRecommendationRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if addr, err := mail.ParseAddress(body.RefereeEmail); err != nil || addr.Address != body.RefereeEmail {
+		validationFailed(w, []FieldError{{"referee_email", "must be an email address"}})
+		return
+	}
+	if !h.mailerConfigured(w) {
+		return
+	}
+	now := h.now()
+	req := &models.RecommendationRequest{
+		ApplicationID: app.ID,
+		RefereeEmail:  body.RefereeEmail,
+		Token:         recommendations.NewToken(),
+		Status:        models.RecommendationPending,
+		ExpiresAt:     now.Add(h.ttl()),
+		SentAt:        now,
+	}
+	if !h.invite(w, r, app, req) {
+		return
+	}
+	if err := h.Requests.Create(r.Context(), req); err != nil {
+		recommendationError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, req)
+}
+
+// List handles GET /v1/applications/{id}/recommendations.
+func (h *RecommendationHandler) List(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	reqs, err := h.Requests.ListByApplication(r.Context(), app.ID)
+	if err != nil {
+		recommendationError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"data": reqs})
+}
+
+// Resend handles POST /v1/applications/{id}/recommendations/{rid}/resend.
+// The referee gets a new link with a fresh expiry, and the old link stops
+// working; a request whose letter is in answers 409.
+func (h *RecommendationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	app, ok := loadApplication(w, r, h.Applications)
+	if !ok {
+		return
+	}
+	req, err := h.Requests.Get(r.Context(), r.PathValue("rid"))
+	if err == nil && req.ApplicationID != app.ID {
+		err = store.ErrNotFound
+	}
+	if err == nil && req.Status == models.RecommendationSubmitted {
+		err = recommendations.ErrSubmitted
+	}
+	if err != nil {
+		recommendationError(w, r, err)
+		return
+	}
+	if !h.mailerConfigured(w) {
+		return
+	}
+	now := h.now()
+	req.Token, req.ExpiresAt, req.SentAt = recommendations.NewToken(), now.Add(h.ttl()), now
+	if !h.invite(w, r, app, req) {
+		return
+	}
+	if req, err = h.Requests.Reissue(r.Context(), req.ID, req.Token, req.ExpiresAt, req.SentAt); err != nil {
+		recommendationError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, req)
+}
+
+// submitPage is what a referee's link shows before they upload.
+type submitPage struct {
+	ProgramCode string    `json:"program_code"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	MaxBytes    int64     `json:"max_bytes"`
+}
+
+// Lookup handles GET /v1/recommendations/submit?token=, telling the
+// referee's page whether the link can still be used: 404 for an unknown
+// or replaced token, 409 once the letter is in, and 410 after expiry.
+func (h *RecommendationHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.openRequest(w, r)
+	if !ok {
+		return
+	}
+	app, err := h.Applications.GetByID(r.Context(), req.ApplicationID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, submitPage{ProgramCode: app.ProgramCode, ExpiresAt: req.ExpiresAt, MaxBytes: h.maxSize()})
+}
+
+// Submit handles POST /v1/recommendations/submit?token=, whose multipart
+// "file" part is the letter as a PDF. The token admits one letter; the
+// answers for an unusable one are Lookup's.
+func (h *RecommendationHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.openRequest(w, r)
+	if !ok {
+		return
+	}
+	max := h.maxSize()
+	part, ok := filePart(w, r, max)
+	if !ok {
+		return
+	}
+	defer part.Close()
+	// Check the content before the uploader keeps it, since it would
+	// also take an image.
+	letter := bufio.NewReader(part)
+	if head, _ := letter.Peek(5); !bytes.Equal(head, []byte("%PDF-")) {
+		respond.Error(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "letter must be a PDF")
+		return
+	}
+	meta, err := h.Uploader.Upload(r.Context(), req.ApplicationID, letter, part.FileName(), -1)
+	if err != nil {
+		uploadError(w, r, err, max)
+		return
+	}
+	if err := h.Metas.Create(r.Context(), meta); err != nil {
+		logging.FromContext(r.Context()).Error("save document metadata", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+		return
+	}
+	// A concurrent submission with the same token may have won since
+	// openRequest; only one of them is recorded, and the others' letters
+	// are taken back.
+	req, err = h.Requests.Submit(r.Context(), r.URL.Query().Get("token"), meta.ID, h.now())
+	if err != nil {
+		h.discard(r, meta)
+		recommendationError(w, r, err)
+		return
+	}
+	enqueueProcessing(r, h.Jobs, meta)
+	respond.JSON(w, http.StatusCreated, req)
+}
+
+// discard removes a letter no request refers to, logging what it cannot,
+// so the application does not gain an orphaned document.
+func (h *RecommendationHandler) discard(r *http.Request, meta *documents.Meta) {
+	logger := logging.FromContext(r.Context())
+	if err := h.Metas.Delete(r.Context(), meta.ID); err != nil {
+		logger.Error("delete unrecorded letter metadata", "document_id", meta.ID, "error", err)
+	}
+	rm, ok := h.Uploader.(documents.Remover)
+	if !ok {
+		return
+	}
+	if err := rm.Remove(r.Context(), meta.Key); err != nil {
+		logger.Error("delete unrecorded letter", "key", meta.Key, "error", err)
+	}
+}
+
+// openRequest returns the request holding the token query parameter if a
+// letter can still be submitted to it, or writes the error response.
+func (h *RecommendationHandler) openRequest(w http.ResponseWriter, r *http.Request) (*models.RecommendationRequest, bool) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		validationFailed(w, []FieldError{{"token", "is required"}})
+		return nil, false
+	}
+	req, err := h.Requests.ByToken(r.Context(), token)
+	switch {
+	case err != nil:
+	case req.Status == models.RecommendationSubmitted:
+		err = recommendations.ErrSubmitted
+	case req.Status == models.RecommendationExpired || !h.now().Before(req.ExpiresAt):
+		err = recommendations.ErrExpired
+	}
+	if err != nil {
+		recommendationError(w, r, err)
+		return nil, false
+	}
+	return req, true
+}
+
+// invite emails req's referee their link, or writes the error response.
+func (h *RecommendationHandler) invite(w http.ResponseWriter, r *http.Request, app *models.StudentApplication, req *models.RecommendationRequest) bool {
+	link, err := url.Parse(h.SubmitURL)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("recommendation submit URL", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "invalid submit URL")
+		return false
+	}
+	q := link.Query()
+	q.Set("token", req.Token)
+	link.RawQuery = q.Encode()
+	msg := notify.RecommendationMessage
+	data := notify.RecommendationData{Link: link.String(), ApplicationID: app.ID, ProgramCode: app.ProgramCode, ExpiresAt: req.ExpiresAt}
+	if err := h.Mailer.Send(r.Context(), req.RefereeEmail, msg.Subject, msg.Template, data); err != nil {
+		logging.FromContext(r.Context()).Error("send recommendation invite", "application_id", app.ID, "error", err)
+		if errors.Is(err, circuit.ErrOpen) {
+			respond.Error(w, http.StatusServiceUnavailable, "EMAIL_UNAVAILABLE", "email is unavailable, so the referee cannot be invited; try again shortly")
+			return false
+		}
+		respond.Error(w, http.StatusInternalServerError, "NOTIFICATION_FAILED", "could not email the referee; nothing was saved")
+		return false
+	}
+	return true
+}
+
+func (h *RecommendationHandler) mailerConfigured(w http.ResponseWriter) bool {
+	if h.Mailer == nil {
+		respond.Error(w, http.StatusServiceUnavailable, "EMAIL_UNAVAILABLE", "email is not configured, so referees cannot be invited")
+		return false
+	}
+	return true
+}
+
+func (h *RecommendationHandler) now() time.Time {
+	if h.Clock == nil {
+		return clock.System.Now().UTC()
+	}
+	return h.Clock.Now().UTC()
+}
+
+func (h *RecommendationHandler) ttl() time.Duration {
+	if h.TTL <= 0 {
+		return recommendations.DefaultTTL
+	}
+	return h.TTL
+}
+
+func (h *RecommendationHandler) maxSize() int64 {
+	if h.MaxSize <= 0 {
+		return documents.DefaultMaxSize
+	}
+	return h.MaxSize
+}
+
+func recommendationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "recommendation request not found")
+	case errors.Is(err, recommendations.ErrSubmitted):
+		respond.Error(w, http.StatusConflict, "ALREADY_SUBMITTED", "a letter has already been submitted for this request")
+	case errors.Is(err, recommendations.ErrExpired):
+		respond.Error(w, http.StatusGone, "EXPIRED", "this request has expired; ask the applicant to send a new link")
+	default:
+		logging.FromContext(r.Context()).Error("recommendation store failed", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+	}
+}
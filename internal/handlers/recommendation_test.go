@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/jobs"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/notify"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/recommendations"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+const submitURL = "https://apply.example.edu/recommend"
+
+type recommendationAPI struct {
+	*testAPI
+	app    *models.StudentApplication
+	sender *notify.MockSender
+	metas  *documents.MemoryMetaStore
+	dir    string // the uploader's
+	h      *RecommendationHandler
+	now    time.Time
+}
+
+func newRecommendationAPI(t *testing.T) *recommendationAPI {
+	templates, err := notify.LoadTemplates("../../config/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := store.NewMemoryStore()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}
+	if err := apps.Create(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	a := &recommendationAPI{
+		testAPI: newTestAPI(t),
+		app:     app,
+		sender:  &notify.MockSender{Templates: templates},
+		metas:   documents.NewMemoryMetaStore(),
+		dir:     t.TempDir(),
+		now:     time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	h := &RecommendationHandler{
+		Applications: apps,
+		Requests:     recommendations.NewMemoryStore(),
+		Uploader:     documents.NewLocalUploader(a.dir),
+		Metas:        a.metas,
+		Mailer:       a.sender,
+		SubmitURL:    submitURL,
+		TTL:          24 * time.Hour,
+		Clock:        clock.Func(func() time.Time { return a.now }),
+	}
+	h.Register(a.router)
+	a.h = h
+	return a
+}
+
+// request asks for a letter from referee and returns the request and the
+// token from the emailed link.
+func (a *recommendationAPI) request(referee string) (models.RecommendationRequest, string) {
+	a.t.Helper()
+	var req models.RecommendationRequest
+	rec := a.do("POST", "/v1/applications/"+a.app.ID+"/recommendations", "stu-1", "student", map[string]string{"referee_email": referee}, &req)
+	if rec.Code != http.StatusCreated {
+		a.t.Fatalf("request: %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), `"token"`) {
+		a.t.Errorf("token in the applicant's response: %s", rec.Body)
+	}
+	return req, a.lastToken()
+}
+
+func (a *recommendationAPI) lastToken() string {
+	a.t.Helper()
+	sent := a.sender.Sent()
+	if len(sent) == 0 {
+		a.t.Fatal("no invite sent")
+	}
+	link, err := url.Parse(sent[len(sent)-1].Data.(notify.RecommendationData).Link)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return link.Query().Get("token")
+}
+
+// submit posts a letter to the public endpoint, without credentials.
+func (a *recommendationAPI) submit(token string, letter []byte) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "letter.pdf")
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	fw.Write(letter)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/v1/recommendations/submit?token="+url.QueryEscape(token), &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+var pdfLetter = []byte("%PDF-1.7\nTo whom it may concern...")
+
+func TestRecommendationRequest(t *testing.T) {
+	api := newRecommendationAPI(t)
+	req, token := api.request("prof@example.edu")
+	if req.Status != models.RecommendationPending || req.RefereeEmail != "prof@example.edu" || !req.ExpiresAt.Equal(api.now.Add(24*time.Hour)) {
+		t.Errorf("request = %+v", req)
+	}
+
+	sent := api.sender.Sent()
+	if len(sent) != 1 || sent[0].To != "prof@example.edu" || sent[0].Template != notify.RecommendationMessage.Template {
+		t.Fatalf("sent = %+v", sent)
+	}
+	if !strings.HasPrefix(sent[0].Data.(notify.RecommendationData).Link, submitURL+"?token=") || token == "" || !strings.Contains(sent[0].Body, token) {
+		t.Errorf("invite = %+v", sent[0])
+	}
+
+	// The referee's page learns the program without credentials.
+	var page submitPage
+	if rec := api.do("GET", "/v1/recommendations/submit?token="+token, "", "", nil, &page); rec.Code != http.StatusOK || page.ProgramCode != "CS" {
+		t.Errorf("lookup: %d %s", rec.Code, rec.Body)
+	}
+
+	for _, tc := range []struct {
+		name, subject, body string
+		want                int
+	}{
+		{"other student", "stu-2", `{"referee_email":"prof@example.edu"}`, http.StatusNotFound},
+		{"bad email", "stu-1", `{"referee_email":"Prof <prof@example.edu>"}`, http.StatusBadRequest},
+	} {
+		if rec := api.do("POST", "/v1/applications/"+api.app.ID+"/recommendations", tc.subject, "student", tc.body, nil); rec.Code != tc.want {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.want)
+		}
+	}
+	if got := len(api.sender.Sent()); got != 1 {
+		t.Errorf("%d invites sent, want 1", got)
+	}
+}
+
+func TestRecommendationTokenIsSingleUse(t *testing.T) {
+	api := newRecommendationAPI(t)
+	q := jobs.NewMemoryQueue()
+	api.h.Jobs = q
+	req, token := api.request("prof@example.edu")
+
+	if rec := api.submit(token, []byte("\x89PNG\r\n\x1a\n")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("image letter: %d %s", rec.Code, rec.Body)
+	}
+	rec := api.submit(token, pdfLetter)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("submit: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.submit(token, pdfLetter); rec.Code != http.StatusConflict || errorCode(t, rec) != "ALREADY_SUBMITTED" {
+		t.Errorf("second submit: %d %s", rec.Code, rec.Body)
+	}
+	// Only the recorded letter is processed.
+	if q.Len() != 2 {
+		t.Errorf("%d jobs queued, want 2", q.Len())
+	}
+	if rec := api.do("GET", "/v1/recommendations/submit?token="+token, "", "", nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("lookup after submit: %d", rec.Code)
+	}
+
+	var list struct {
+		Data []models.RecommendationRequest `json:"data"`
+	}
+	api.do("GET", "/v1/applications/"+api.app.ID+"/recommendations", "adv-1", "advisor", nil, &list)
+	if len(list.Data) != 1 || list.Data[0].Status != models.RecommendationSubmitted || list.Data[0].DocumentID == "" {
+		t.Fatalf("list = %+v", list.Data)
+	}
+	metas, _ := api.metas.ListByApplication(context.Background(), api.app.ID)
+	if len(metas) != 1 || metas[0].ID != list.Data[0].DocumentID || metas[0].MIMEType != documents.MIMEPDF {
+		t.Errorf("documents = %+v", metas)
+	}
+	if rec := api.do("POST", "/v1/applications/"+api.app.ID+"/recommendations/"+req.ID+"/resend", "stu-1", "student", nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("resend after submit: %d %s", rec.Code, rec.Body)
+	}
+}
+
+// racedStore lets a competing letter in just before each Submit, as a
+// concurrent submission with the same token would.
+type racedStore struct {
+	recommendations.Store
+	now time.Time
+}
+
+func (s racedStore) Submit(ctx context.Context, token, documentID string, at time.Time) (*models.RecommendationRequest, error) {
+	s.Store.Submit(ctx, token, "doc-winner", s.now)
+	return s.Store.Submit(ctx, token, documentID, at)
+}
+
+func TestRecommendationLosingSubmitLeavesNoDocument(t *testing.T) {
+	api := newRecommendationAPI(t)
+	_, token := api.request("prof@example.edu")
+	api.h.Requests = racedStore{api.h.Requests, api.now}
+
+	if rec := api.submit(token, pdfLetter); rec.Code != http.StatusConflict {
+		t.Fatalf("losing submit: %d %s", rec.Code, rec.Body)
+	}
+	if metas, _ := api.metas.ListByApplication(context.Background(), api.app.ID); len(metas) != 0 {
+		t.Errorf("documents left behind: %+v", metas)
+	}
+	var files []string
+	filepath.WalkDir(api.dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if len(files) != 0 {
+		t.Errorf("files left behind: %v", files)
+	}
+}
+
+func TestRecommendationResendInvalidatesOldLink(t *testing.T) {
+	api := newRecommendationAPI(t)
+	req, old := api.request("prof@example.edu")
+
+	// Past the expiry, the link answers 410 until the invite is re-sent.
+	api.now = req.ExpiresAt
+	if rec := api.submit(old, pdfLetter); rec.Code != http.StatusGone || errorCode(t, rec) != "EXPIRED" {
+		t.Errorf("expired submit: %d %s", rec.Code, rec.Body)
+	}
+
+	var resent models.RecommendationRequest
+	if rec := api.do("POST", "/v1/applications/"+api.app.ID+"/recommendations/"+req.ID+"/resend", "stu-1", "student", nil, &resent); rec.Code != http.StatusOK {
+		t.Fatalf("resend: %d %s", rec.Code, rec.Body)
+	}
+	if !resent.ExpiresAt.Equal(api.now.Add(24*time.Hour)) || !resent.SentAt.Equal(api.now) {
+		t.Errorf("resent = %+v", resent)
+	}
+	fresh := api.lastToken()
+	if fresh == old {
+		t.Fatal("resend reused the token")
+	}
+	if rec := api.submit(old, pdfLetter); rec.Code != http.StatusNotFound {
+		t.Errorf("old link: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.submit(fresh, pdfLetter); rec.Code != http.StatusCreated {
+		t.Errorf("new link: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := api.do("POST", "/v1/applications/"+api.app.ID+"/recommendations/"+req.ID+"/resend", "stu-2", "student", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("other student's resend: %d", rec.Code)
+	}
+}
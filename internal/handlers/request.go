@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/listing"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+)
+
+const maxBodyBytes = 1 << 20
+
+// FieldError describes one invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// decodeJSON decodes a single JSON object, rejecting unknown fields. On
+// failure it writes a 400 response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		msg := "request body must be a JSON object"
+		if !errors.Is(err, io.EOF) {
+			msg += ": " + err.Error()
+		}
+		respond.Error(w, http.StatusBadRequest, "INVALID_JSON", msg)
+		return false
+	}
+	return true
+}
+
+// paramErrors converts the query parameters listing.Parse rejected.
+func paramErrors(errs []listing.ParamError) []FieldError {
+	var out []FieldError
+	for _, e := range errs {
+		out = append(out, FieldError{e.Param, e.Message})
+	}
+	return out
+}
+
+func validationFailed(w http.ResponseWriter, errs []FieldError) {
+	respond.ErrorWithDetails(w, http.StatusBadRequest, "VALIDATION_FAILED", "request validation failed", errs)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/search"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// SearchHandler serves GET /v1/search. Students only ever find their own
+// applications; other roles search all of them.
+type SearchHandler struct {
+	Engine search.Engine
+}
+
+type searchResponse struct {
+	Data []search.Result `json:"data"`
+}
+
+// Register wires the handler's routes.
+func (h *SearchHandler) Register(rt *router.Router) {
+	rt.HandleFunc("GET /v1/search", h.Search)
+}
+
+// Search handles GET /v1/search?q=&program=&status=&limit=. Results are
+// ordered by descending score.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	claims, ok := callerClaims(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filters := search.Filters{
+		ProgramCode: q.Get("program"),
+		Status:      status.State(q.Get("status")),
+		Limit:       DefaultPageSize,
+	}
+	if claims.Role == rbac.RoleStudent {
+		filters.ApplicantID = claims.Subject
+	}
+	var errs []FieldError
+	if filters.Status != "" && !status.Default().Known(filters.Status) {
+		errs = append(errs, FieldError{"status", fmt.Sprintf("must be one of %v", status.Default().States())})
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			errs = append(errs, FieldError{"limit", fmt.Sprintf("must be an integer between 1 and %d", MaxPageSize)})
+		}
+		filters.Limit = n
+	}
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	results, err := h.Engine.Search(r.Context(), q.Get("q"), filters)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("search failed", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "search failed")
+		return
+	}
+	respond.JSON(w, http.StatusOK, searchResponse{Data: results})
+}
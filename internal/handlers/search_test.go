@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/search"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func TestSearch(t *testing.T) {
+	api := newTestAPI(t)
+	apps := store.NewMemoryStore()
+	for _, app := range []models.StudentApplication{
+		{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"},
+		{ApplicantID: "stu-2", ProgramCode: "CS", Status: "accepted"},
+		{ApplicantID: "stu-2", ProgramCode: "EE", Status: "pending"},
+	} {
+		if err := apps.Create(context.Background(), &app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &SearchHandler{Engine: search.MemoryEngine{Applications: apps, Profiles: map[string]models.ApplicantProfile{
+		"stu-1": {ID: "stu-1", FullName: "Ada Lovelace"},
+		"stu-2": {ID: "stu-2", FullName: "Grace Hopper"},
+	}}}
+	h.Register(api.router)
+
+	var got searchResponse
+	if rec := api.do("GET", "/v1/search?q=hopper", "adv-1", "advisor", nil, &got); rec.Code != http.StatusOK {
+		t.Fatalf("advisor search: %d %s", rec.Code, rec.Body)
+	}
+	if len(got.Data) != 2 || got.Data[0].Applicant.FullName != "Grace Hopper" {
+		t.Errorf("advisor search = %+v", got.Data)
+	}
+
+	got = searchResponse{}
+	api.do("GET", "/v1/search?q=hopper&program=EE&status=pending", "adv-1", "advisor", nil, &got)
+	if len(got.Data) != 1 || got.Data[0].Application.ProgramCode != "EE" {
+		t.Errorf("filtered search = %+v", got.Data)
+	}
+
+	got = searchResponse{}
+	api.do("GET", "/v1/search?q=hopper", "stu-1", "student", nil, &got)
+	if len(got.Data) != 0 {
+		t.Errorf("student found another applicant's records: %+v", got.Data)
+	}
+	got = searchResponse{}
+	api.do("GET", "/v1/search?q=%20%20", "stu-1", "student", nil, &got)
+	if len(got.Data) != 1 || got.Data[0].Application.ApplicantID != "stu-1" {
+		t.Errorf("student whitespace search = %+v", got.Data)
+	}
+
+	if rec := api.do("GET", "/v1/search?status=lost", "adv-1", "advisor", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: got %d, want 400", rec.Code)
+	}
+	if rec := api.do("GET", "/v1/search?limit=0", "adv-1", "advisor", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad limit: got %d, want 400", rec.Code)
+	}
+}
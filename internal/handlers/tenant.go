@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/export"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// TenantAdminHandler serves the cross-tenant queries of the platform's
+// operators. It belongs on the admin listener (pkg/admin), whose token
+// guards it, never the public API, where every request is scoped to one
+// tenant:
+//
+//	GET /admin/tenants
+//	GET /admin/applications?tenant_id=&program_code=&status=&limit=&after=
+type TenantAdminHandler struct {
+	Tenants      tenant.Store
+	Applications export.Source
+}
+
+// Routes returns the handler's routes, keyed by ServeMux pattern, for
+// admin.WithHandler.
+func (h *TenantAdminHandler) Routes() map[string]http.Handler {
+	return map[string]http.Handler{
+		"GET /admin/tenants":      http.HandlerFunc(h.ListTenants),
+		"GET /admin/applications": http.HandlerFunc(h.ListApplications),
+	}
+}
+
+// ListTenants handles GET /admin/tenants.
+func (h *TenantAdminHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	ts, err := h.Tenants.List(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("list tenants", "error", err)
+		respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "storage error")
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"data": ts})
+}
+
+// ListApplications handles GET /admin/applications, a page of the live
+// applications of every tenant, or of the one tenant_id names, oldest
+// first.
+func (h *TenantAdminHandler) ListApplications(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ctx := r.Context()
+	if id := q.Get("tenant_id"); id != "" {
+		t, err := h.Tenants.Get(ctx, id)
+		if errors.Is(err, tenant.ErrUnknown) {
+			validationFailed(w, []FieldError{{"tenant_id", "is not a tenant"}})
+			return
+		}
+		if err != nil {
+			storeError(w, r, err)
+			return
+		}
+		ctx = tenant.NewContext(ctx, t)
+	}
+	opts, errs := (&ApplicationHandler{}).listOptions(q)
+	if len(errs) > 0 {
+		validationFailed(w, errs)
+		return
+	}
+	f := store.Filter{ProgramCode: q.Get("program_code"), Status: status.State(q.Get("status"))}
+	res, err := h.Applications.Query(ctx, f, opts)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		validationFailed(w, []FieldError{{"after", "is not a cursor returned by this endpoint"}})
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, listResponse{Data: res.Items, Total: res.Total, HasMore: res.HasMore, NextCursor: res.NextCursor})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestTenantAdminListsAcrossTenants(t *testing.T) {
+	tenants := tenant.NewMemoryStore(tenant.Tenant{ID: "north", Name: "North"}, tenant.Tenant{ID: "south", Name: "South"})
+	apps := store.NewMemoryStore()
+	for _, id := range []string{"north", "south", "south"} {
+		ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: id})
+		if err := apps.Create(ctx, &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	for pattern, h := range (&TenantAdminHandler{Tenants: tenants, Applications: apps}).Routes() {
+		mux.Handle(pattern, h)
+	}
+	get := func(path string, out any) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if out != nil && rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code
+	}
+
+	var list struct {
+		Data  []models.StudentApplication `json:"data"`
+		Total int64                       `json:"total"`
+	}
+	if code := get("/admin/applications", &list); code != http.StatusOK || list.Total != 3 {
+		t.Errorf("all tenants: %d, total %d", code, list.Total)
+	}
+	if code := get("/admin/applications?tenant_id=south", &list); code != http.StatusOK || list.Total != 2 || list.Data[0].TenantID != "south" {
+		t.Errorf("south: %d, %+v", code, list)
+	}
+	if code := get("/admin/applications?tenant_id=west", nil); code != http.StatusBadRequest {
+		t.Errorf("unknown tenant: %d", code)
+	}
+	var ts struct {
+		Data []tenant.Tenant `json:"data"`
+	}
+	if code := get("/admin/tenants", &ts); code != http.StatusOK || len(ts.Data) != 2 || ts.Data[0].ID != "north" {
+		t.Errorf("tenants: %d, %+v", code, ts.Data)
+	}
+}
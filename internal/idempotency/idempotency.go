@@ -0,0 +1,48 @@
+// Package idempotency stores the responses to requests sent with an
+// Idempotency-Key, so a client retrying a mutation after a lost response
+// gets the first outcome back instead of applying the change twice.
+//
+// A key is claimed with a pending Record before the handler runs, so of
+// two concurrent requests with the same key only one runs it; the Record
+// is then replaced by the response, or released if the handler failed in
+// a way worth retrying.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNotFound is returned by Get for keys with no Record, or whose Record
+// has expired.
+var ErrNotFound = errors.New("idempotency: key not found")
+
+// Store keeps Records by key until their TTL runs out.
+type Store interface {
+	// Claim stores rec under key if there is no Record there, reporting
+	// whether it did. It is atomic: of concurrent Claims of one key, one
+	// wins.
+	Claim(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, error)
+	// Get returns the Record under key, or ErrNotFound.
+	Get(ctx context.Context, key string) (Record, error)
+	// Save replaces the Record under key.
+	Save(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Release deletes the Record under key, so the key can be claimed
+	// again.
+	Release(ctx context.Context, key string) error
+}
+
+// Record is the state of one key: pending while the first request runs,
+// then its response.
+type Record struct {
+	// Fingerprint identifies the request that claimed the key, so the key
+	// cannot be replayed against another endpoint.
+	Fingerprint string `json:"fingerprint"`
+	// Done is set once the response is stored.
+	Done   bool        `json:"done"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+)
+
+// sweepEvery is how many Claims pass between sweeps of expired Records.
+const sweepEvery = 1024
+
+// MemStore is a Store for a single instance; a retry that reaches another
+// replica of a scaled-out service runs again.
+type MemStore struct {
+	clock   clock.Clock
+	mu      sync.Mutex
+	records map[string]memRecord
+	claims  int
+}
+
+type memRecord struct {
+	Record
+	expires time.Time
+}
+
+// NewMemStore returns an empty MemStore reading the time from clk, or the
+// system clock if clk is nil.
+func NewMemStore(clk clock.Clock) *MemStore {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &MemStore{clock: clk, records: map[string]memRecord{}}
+}
+
+// Claim implements Store.
+func (s *MemStore) Claim(_ context.Context, key string, rec Record, ttl time.Duration) (bool, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claims++; s.claims%sweepEvery == 0 {
+		s.sweep(now)
+	}
+	if r, ok := s.records[key]; ok && now.Before(r.expires) {
+		return false, nil
+	}
+	s.records[key] = memRecord{Record: rec, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Get implements Store.
+func (s *MemStore) Get(_ context.Context, key string) (Record, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[key]
+	if !ok || !now.Before(r.expires) {
+		return Record{}, ErrNotFound
+	}
+	return r.Record, nil
+}
+
+// Save implements Store.
+func (s *MemStore) Save(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memRecord{Record: rec, expires: now.Add(ttl)}
+	return nil
+}
+
+// Release implements Store.
+func (s *MemStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemStore) sweep(now time.Time) {
+	for key, r := range s.records {
+		if !now.Before(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
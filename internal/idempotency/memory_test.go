@@ -0,0 +1,52 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+)
+
+func TestMemStoreClaimsOnceUntilExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	s := NewMemStore(clock.Func(func() time.Time { return now }))
+	ctx := context.Background()
+	pending := Record{Fingerprint: "POST /v1/applications"}
+
+	if ok, err := s.Claim(ctx, "user:stu-1:k", pending, time.Minute); err != nil || !ok {
+		t.Fatalf("first claim: %v, %v", ok, err)
+	}
+	if ok, _ := s.Claim(ctx, "user:stu-1:k", pending, time.Minute); ok {
+		t.Fatal("a claimed key was claimed again")
+	}
+	done := Record{Fingerprint: pending.Fingerprint, Done: true, Status: 201, Body: []byte(`{"id":"a1"}`)}
+	if err := s.Save(ctx, "user:stu-1:k", done, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(ctx, "user:stu-1:k")
+	if err != nil || !got.Done || got.Status != 201 || string(got.Body) != `{"id":"a1"}` {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+
+	// Save's TTL replaces the claim's.
+	now = now.Add(59 * time.Minute)
+	if _, err := s.Get(ctx, "user:stu-1:k"); err != nil {
+		t.Fatalf("before expiry: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := s.Get(ctx, "user:stu-1:k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("after expiry: %v, want ErrNotFound", err)
+	}
+	if ok, _ := s.Claim(ctx, "user:stu-1:k", pending, time.Minute); !ok {
+		t.Fatal("an expired key could not be claimed")
+	}
+
+	if err := s.Release(ctx, "user:stu-1:k"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Claim(ctx, "user:stu-1:k", pending, time.Minute); !ok {
+		t.Fatal("a released key could not be claimed")
+	}
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient is the part of a go-redis client a RedisStore uses;
+// *redis.Client and *redis.ClusterClient implement it.
+type RedisClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, ttl time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value any, ttl time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// RedisStore is a Store shared by every replica through Redis, so a retry
+// is answered from the first response wherever it lands. Claims are SET
+// NX, and Records are JSON under keys that expire with them.
+type RedisStore struct {
+	Client RedisClient
+	// Prefix namespaces the keys; it defaults to "idempotency:".
+	Prefix string
+}
+
+// NewRedisStore returns a RedisStore using client.
+func NewRedisStore(client RedisClient) *RedisStore {
+	return &RedisStore{Client: client, Prefix: "idempotency:"}
+}
+
+// Claim implements Store.
+func (s *RedisStore) Claim(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.Client.SetNX(ctx, s.Prefix+key, b, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("idempotency: redis: %w", err)
+	}
+	return ok, nil
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(ctx context.Context, key string) (Record, error) {
+	b, err := s.Client.Get(ctx, s.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("idempotency: redis: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return Record{}, fmt.Errorf("idempotency: record %s: %w", key, err)
+	}
+	return rec, nil
+}
+
+// Save implements Store.
+func (s *RedisStore) Save(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := s.Client.Set(ctx, s.Prefix+key, b, ttl).Err(); err != nil {
+		return fmt.Errorf("idempotency: redis: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store.
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := s.Client.Del(ctx, s.Prefix+key).Err(); err != nil {
+		return fmt.Errorf("idempotency: redis: %w", err)
+	}
+	return nil
+}
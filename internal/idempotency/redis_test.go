@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedisStore runs a RedisStore on the Redis at REDIS_TEST_URL, such
+// as redis://localhost:6379/15, and is skipped without one.
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		t.Skip("REDIS_TEST_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+	s := NewRedisStore(client)
+	s.Prefix = "idempotency:test:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const key = "user:stu-1:k"
+	t.Cleanup(func() { client.Del(context.Background(), s.Prefix+key) })
+	pending := Record{Fingerprint: "POST /v1/applications"}
+
+	// Of concurrent claims of one key, exactly one wins.
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		won  int
+		errs []error
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.Claim(ctx, key, pending, time.Minute)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			} else if ok {
+				won++
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 || len(errs) != 0 {
+		t.Fatalf("%d of 20 concurrent claims won (errors %v), want 1", won, errs)
+	}
+	if got, err := s.Get(ctx, key); err != nil || got.Done || got.Fingerprint != pending.Fingerprint {
+		t.Fatalf("claimed record = %+v, %v", got, err)
+	}
+	if ttl, _ := client.TTL(ctx, s.Prefix+key).Result(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("claim TTL = %v, want up to a minute", ttl)
+	}
+
+	// The saved response comes back whole for a retry to replay, under
+	// Save's TTL.
+	done := Record{
+		Fingerprint: pending.Fingerprint,
+		Done:        true,
+		Status:      http.StatusCreated,
+		Header:      http.Header{"Content-Type": {"application/json"}, "Location": {"/v1/applications/a1"}},
+		Body:        []byte(`{"id":"a1"}`),
+	}
+	if err := s.Save(ctx, key, done, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(ctx, key)
+	if err != nil || !reflect.DeepEqual(got, done) {
+		t.Fatalf("Get = %+v, %v, want %+v", got, err, done)
+	}
+	if ttl, _ := client.TTL(ctx, s.Prefix+key).Result(); ttl <= time.Minute {
+		t.Errorf("saved TTL = %v, want Save's hour", ttl)
+	}
+	if ok, _ := s.Claim(ctx, key, pending, time.Minute); ok {
+		t.Error("a key with a saved response was claimed again")
+	}
+
+	if err := s.Release(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Release: %v, want ErrNotFound", err)
+	}
+	if ok, err := s.Claim(ctx, key, pending, time.Minute); err != nil || !ok {
+		t.Errorf("claim after Release: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// NewCSV returns a Reader of the CSV in r. A leading UTF-8 byte order
+// mark, which Excel writes, is skipped, and a row that fails to parse is
+// a RowError.
+func NewCSV(r io.Reader) Reader {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); string(bom) == "\uFEFF" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	return &csvReader{r: cr}
+}
+
+type csvReader struct {
+	r *csv.Reader
+}
+
+func (c *csvReader) Close() error { return nil }
+
+func (c *csvReader) Next() (Row, error) {
+	cells, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return Row{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Row{}, &RowError{Row: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return Row{}, err
+	}
+	// Rows are numbered by the line they start on, as a spreadsheet
+	// opening the file would, blank lines included.
+	line, _ := c.r.FieldPos(0)
+	return Row{Num: line, Cells: cells}, nil
+}
@@ -0,0 +1,82 @@
+// Package importer reads spreadsheets uploaded to the
+// /v1/applications/import endpoint, CSV or Excel (XLSX), one row at a
+// time, so a sheet's memory use does not grow with its length.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultMaxSize is the upload limit used when the handler sets none.
+const DefaultMaxSize int64 = 10 << 20
+
+// ErrUnsupportedFormat is returned by FormatOf for files that are neither
+// CSV nor XLSX.
+var ErrUnsupportedFormat = errors.New("importer: file must be .csv or .xlsx")
+
+// ErrMalformed is wrapped by the errors of a file that cannot be read at
+// all, as opposed to a RowError.
+var ErrMalformed = errors.New("importer: malformed file")
+
+// Formats.
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// FormatOf picks the format of an upload by its file name, falling back
+// to its Content-Type.
+func FormatOf(filename, contentType string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return CSV, nil
+	case ".xlsx":
+		return XLSX, nil
+	}
+	switch ct, _, _ := strings.Cut(contentType, ";"); strings.TrimSpace(ct) {
+	case "text/csv":
+		return CSV, nil
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return XLSX, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Row is one row of a sheet. Num counts from 1, the header row, as a
+// spreadsheet numbers rows.
+type Row struct {
+	Num   int
+	Cells []string
+}
+
+// Blank reports whether every cell of r is empty.
+func (r Row) Blank() bool {
+	for _, c := range r.Cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// RowError is a row that could not be read; the rows after it still can.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string { return fmt.Sprintf("row %d: %v", e.Row, e.Err) }
+
+func (e *RowError) Unwrap() error { return e.Err }
+
+// Reader reads a sheet's rows in order.
+type Reader interface {
+	// Next returns the next row, a *RowError for one that could not be
+	// read, or io.EOF after the last. Any other error ends the sheet.
+	Next() (Row, error)
+	// Close releases the file; a CSV Reader has nothing to release.
+	Close() error
+}
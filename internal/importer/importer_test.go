@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll returns the rows of r and the row numbers of its RowErrors.
+func readAll(t *testing.T, r Reader) ([]Row, []int) {
+	t.Helper()
+	var rows []Row
+	var bad []int
+	for {
+		row, err := r.Next()
+		var rowErr *RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			return rows, bad
+		case errors.As(err, &rowErr):
+			bad = append(bad, rowErr.Row)
+		case err != nil:
+			t.Fatal(err)
+		default:
+			rows = append(rows, row)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	in := "\uFEFFapplicant_id,program_code\nstu-1,CS\nstu-2,\"E\"E\"\n\nstu-3,\"EE, evening\",extra\n"
+	rows, bad := readAll(t, NewCSV(strings.NewReader(in)))
+	want := []Row{
+		{Num: 1, Cells: []string{"applicant_id", "program_code"}},
+		{Num: 2, Cells: []string{"stu-1", "CS"}},
+		{Num: 5, Cells: []string{"stu-3", "EE, evening", "extra"}},
+	}
+	if !reflect.DeepEqual(rows, want) || !reflect.DeepEqual(bad, []int{3}) {
+		t.Errorf("rows %v, bad %v", rows, bad)
+	}
+}
+
+// buildXLSX zips a workbook around one worksheet's sheetData.
+func buildXLSX(t *testing.T, sheetData string, shared ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Applicants" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId7" Target="worksheets/applicants.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml":     `<worksheet><sheetData><row r="1"><c t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/applicants.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetData + `</sheetData></worksheet>`,
+	}
+	var sst strings.Builder
+	sst.WriteString(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	for _, s := range shared {
+		sst.WriteString("<si>" + s + "</si>")
+	}
+	parts["xl/sharedStrings.xml"] = sst.String() + "</sst>"
+	for name, body := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestXLSX(t *testing.T) {
+	data := buildXLSX(t, `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>`+
+		`<row r="2"><c r="A2" t="inlineStr"><is><t>stu-1</t></is></c><c r="B2" t="b"><v>1</v></c><c r="C2"><v>2027</v></c></row>`+
+		`<row r="4"><c r="B4" t="s"><v>9</v></c></row>`+
+		`<row r="5"><c r="A5" t="s"><v>2</v></c></row>`,
+		"<t>applicant_id</t>", "<r><t>ro</t></r><r><t>und</t></r>", "<t>stu-2</t>")
+	r, err := OpenXLSX(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rows, bad := readAll(t, r)
+	want := []Row{
+		{Num: 1, Cells: []string{"applicant_id", "", "round"}},
+		{Num: 2, Cells: []string{"stu-1", "TRUE", "2027"}},
+		{Num: 5, Cells: []string{"stu-2"}},
+	}
+	if !reflect.DeepEqual(rows, want) || !reflect.DeepEqual(bad, []int{4}) {
+		t.Errorf("rows %v, bad %v", rows, bad)
+	}
+}
+
+func TestXLSXMalformed(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"not a zip": []byte("applicant_id,program_code\n"),
+		"no sheets": func() []byte {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			zw.Create("word/document.xml")
+			zw.Close()
+			return buf.Bytes()
+		}(),
+	} {
+		if _, err := OpenXLSX(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for _, tc := range []struct{ name, contentType, want string }{
+		{"applicants.CSV", "application/octet-stream", CSV},
+		{"applicants.xlsx", "", XLSX},
+		{"export", "text/csv; charset=utf-8", CSV},
+	} {
+		if got, err := FormatOf(tc.name, tc.contentType); err != nil || got != tc.want {
+			t.Errorf("FormatOf(%q, %q) = %q, %v", tc.name, tc.contentType, got, err)
+		}
+	}
+	if _, err := FormatOf("applicants.xls", "application/vnd.ms-excel"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("xls: %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Limits on what OpenXLSX reads. The shared string table is the one part
+// held in memory; the worksheet is decoded as it is read.
+const (
+	MaxSharedStrings = 32 << 20
+	// MaxXLSXColumns is the widest an Excel worksheet gets (column XFD).
+	MaxXLSXColumns = 16384
+)
+
+// OpenXLSX returns a Reader of the first worksheet of the workbook in r.
+// Cells are read as the text Excel shows for strings and as the stored
+// value otherwise, so dates arrive as serial numbers.
+func OpenXLSX(r io.ReaderAt, size int64) (Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	sheet, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxReader{}
+	if f := files["xl/sharedStrings.xml"]; f != nil {
+		if x.shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	rc, err := sheet.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	x.body, x.dec = rc, xml.NewDecoder(rc)
+	return x, nil
+}
+
+// firstSheet resolves the first sheet the workbook lists to its part.
+func firstSheet(files map[string]*zip.File) (*zip.File, error) {
+	var wb struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files["xl/workbook.xml"], &wb); err != nil {
+		return nil, err
+	}
+	if err := decodePart(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("%w: the workbook has no sheets", ErrMalformed)
+	}
+	for _, rel := range rels.Rels {
+		if rel.ID != wb.Sheets[0].ID {
+			continue
+		}
+		name := path.Join("xl", rel.Target)
+		if strings.HasPrefix(rel.Target, "/") {
+			name = strings.TrimPrefix(rel.Target, "/")
+		}
+		if f := files[name]; f != nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: the first sheet is missing", ErrMalformed)
+}
+
+func decodePart(f *zip.File, v any) error {
+	if f == nil {
+		return fmt.Errorf("%w: not an XLSX workbook", ErrMalformed)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, MaxSharedStrings)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrMalformed, f.Name, err)
+	}
+	return nil
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	if f.UncompressedSize64 > MaxSharedStrings {
+		return nil, fmt.Errorf("%w: shared strings exceed %d bytes", ErrMalformed, MaxSharedStrings)
+	}
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodePart(f, &sst); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.String()
+	}
+	return out, nil
+}
+
+// richText is a string item: plain, <t>, or runs of <r><t>.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.T
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxReader struct {
+	body   io.Closer
+	dec    *xml.Decoder
+	shared []string
+	last   int // number of the row read last
+}
+
+// xlsxCell is a <c> element of a worksheet row.
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+func (x *xlsxReader) Close() error { return x.body.Close() }
+
+func (x *xlsxReader) Next() (Row, error) {
+	for {
+		tok, err := x.dec.Token()
+		if errors.Is(err, io.EOF) {
+			return Row{}, io.EOF
+		}
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: worksheet: %v", ErrMalformed, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "row" {
+			return x.row(start)
+		}
+	}
+}
+
+// row reads the cells of the <row> that start opens.
+func (x *xlsxReader) row(start xml.StartElement) (Row, error) {
+	row := Row{Num: x.last + 1}
+	for _, a := range start.Attr {
+		if a.Name.Local == "r" {
+			n, err := strconv.Atoi(a.Value)
+			if err != nil || n <= x.last {
+				return Row{}, fmt.Errorf("%w: worksheet: bad row number %q", ErrMalformed, a.Value)
+			}
+			row.Num = n
+		}
+	}
+	x.last = row.Num
+	var rowErr error
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: worksheet: %v", ErrMalformed, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				if rowErr != nil {
+					return Row{}, &RowError{Row: row.Num, Err: rowErr}
+				}
+				return row, nil
+			}
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			var c xlsxCell
+			if err := x.dec.DecodeElement(&c, &t); err != nil {
+				return Row{}, fmt.Errorf("%w: worksheet: %v", ErrMalformed, err)
+			}
+			col := len(row.Cells)
+			if c.Ref != "" {
+				if col, err = column(c.Ref); err == nil && col < len(row.Cells) {
+					err = fmt.Errorf("cell %s is out of order", c.Ref)
+				}
+			}
+			v, vErr := x.value(c)
+			if err = cmp.Or(err, vErr); err != nil {
+				// The first bad cell is reported; the row is read to
+				// its end so the next one can be.
+				if rowErr == nil {
+					rowErr = err
+				}
+				continue
+			}
+			for len(row.Cells) < col {
+				row.Cells = append(row.Cells, "")
+			}
+			row.Cells = append(row.Cells, v)
+		}
+	}
+}
+
+func (x *xlsxReader) value(c xlsxCell) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(x.shared) {
+			return "", fmt.Errorf("cell %s names no shared string %q", c.Ref, c.Value)
+		}
+		return x.shared[i], nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return c.Value, nil
+}
+
+// column returns the zero-based column of a cell reference such as "C12".
+func column(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > MaxXLSXColumns {
+			return 0, fmt.Errorf("column of %q beyond %d", ref, MaxXLSXColumns)
+		}
+	}
+	if i == 0 || i == len(ref) {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,48 @@
+// Package interviews schedules the interviews some programs hold before
+// a decision. Admins open slots, each with an advisor and a capacity, and
+// applicants book a place in one; a slot never takes more bookings than
+// its capacity, and an application holds at most one booking per
+// program.
+package interviews
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+)
+
+// DefaultCancelCutoff is how long before its slot starts a booking can
+// last be cancelled.
+const DefaultCancelCutoff = 24 * time.Hour
+
+var (
+	// ErrFull is returned when booking a slot holding MaxCandidates
+	// bookings.
+	ErrFull = errors.New("interviews: slot is full")
+	// ErrAlreadyBooked is returned when booking for an application that
+	// already holds a booking in a slot of the same program.
+	ErrAlreadyBooked = errors.New("interviews: application already booked for this program")
+)
+
+// Store persists slots and bookings. Lookups of an unknown slot or a
+// missing booking return store.ErrNotFound. Both belong to the tenant of
+// the context they are created in, and only that tenant's reads see them.
+type Store interface {
+	// CreateSlot assigns slot an ID and stores it.
+	CreateSlot(ctx context.Context, slot *models.InterviewSlot) error
+	GetSlot(ctx context.Context, id string) (*models.InterviewSlot, error)
+	// ListSlots returns a program's slots, earliest first.
+	ListSlots(ctx context.Context, programCode string) ([]models.InterviewSlot, error)
+	// Book gives appID a place in the slot, booked at at. Concurrent
+	// bookings of one slot are serialized, so the capacity check and the
+	// insert see the same count: it returns ErrFull once the slot holds
+	// MaxCandidates bookings and ErrAlreadyBooked when appID holds one
+	// in any slot of the program.
+	Book(ctx context.Context, slotID, appID string, at time.Time) (*models.InterviewBooking, error)
+	// BookingFor returns appID's booking for a program.
+	BookingFor(ctx context.Context, appID, programCode string) (*models.InterviewBooking, error)
+	// Cancel deletes a booking, freeing its place.
+	Cancel(ctx context.Context, bookingID string) error
+}
@@ -0,0 +1,108 @@
+package interviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestMemoryStoreBooking(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	now := start.AddDate(0, 0, -7)
+	slot := func(program string, max int, at time.Time) *models.InterviewSlot {
+		t.Helper()
+		sl := &models.InterviewSlot{ProgramCode: program, StartAt: at, EndAt: at.Add(30 * time.Minute), AdvisorID: "adv-1", MaxCandidates: max}
+		if err := s.CreateSlot(ctx, sl); err != nil {
+			t.Fatal(err)
+		}
+		return sl
+	}
+	first, second, other := slot("CS", 2, start.Add(time.Hour)), slot("CS", 1, start), slot("EE", 1, start)
+	if first.ID == "" || first.TenantID != "north" {
+		t.Fatalf("created %+v", first)
+	}
+
+	b, err := s.Book(ctx, first.ID, "app-1", now)
+	if err != nil || b.SlotID != first.ID || b.ApplicationID != "app-1" {
+		t.Fatalf("book: %+v, %v", b, err)
+	}
+	if _, err := s.Book(ctx, second.ID, "app-1", now); !errors.Is(err, ErrAlreadyBooked) {
+		t.Errorf("second slot of the same program: %v, want ErrAlreadyBooked", err)
+	}
+	if _, err := s.Book(ctx, other.ID, "app-1", now); err != nil {
+		t.Errorf("slot of another program: %v", err)
+	}
+	if _, err := s.Book(ctx, first.ID, "app-2", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Book(ctx, first.ID, "app-3", now); !errors.Is(err, ErrFull) {
+		t.Errorf("booking past capacity: %v, want ErrFull", err)
+	}
+
+	list, err := s.ListSlots(ctx, "CS")
+	if err != nil || len(list) != 2 || list[0].ID != second.ID || list[1].Booked != 2 {
+		t.Fatalf("ListSlots = %+v, %v", list, err)
+	}
+	got, err := s.BookingFor(ctx, "app-1", "CS")
+	if err != nil || got.ID != b.ID {
+		t.Fatalf("BookingFor = %+v, %v", got, err)
+	}
+	if err := s.Cancel(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.BookingFor(ctx, "app-1", "CS"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("cancelled booking: %v", err)
+	}
+	if _, err := s.Book(ctx, first.ID, "app-3", now); err != nil {
+		t.Errorf("booking the freed place: %v", err)
+	}
+
+	south := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "south"})
+	if _, err := s.GetSlot(south, first.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("another tenant's slot: %v", err)
+	}
+	if _, err := s.Book(south, second.ID, "app-9", now); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("booking another tenant's slot: %v", err)
+	}
+}
+
+func TestMemoryStoreConcurrentBookingsStayWithinCapacity(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	sl := &models.InterviewSlot{ProgramCode: "CS", StartAt: time.Now().Add(time.Hour), MaxCandidates: 3}
+	if err := s.CreateSlot(ctx, sl); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		booked int
+	)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Book(ctx, sl.ID, fmt.Sprintf("app-%d", i), time.Now())
+			if err == nil {
+				mu.Lock()
+				booked++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrFull) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, _ := s.GetSlot(ctx, sl.ID); booked != 3 || got.Booked != 3 {
+		t.Errorf("booked %d, slot holds %d; want 3", booked, got.Booked)
+	}
+}
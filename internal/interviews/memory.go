@@ -0,0 +1,142 @@
+package interviews
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// MemoryStore is an in-process Store for tests and local development.
+type MemoryStore struct {
+	mu       sync.Mutex
+	slots    map[string]models.InterviewSlot
+	bookings map[string]models.InterviewBooking
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{slots: map[string]models.InterviewSlot{}, bookings: map[string]models.InterviewBooking{}}
+}
+
+// CreateSlot implements Store.
+func (s *MemoryStore) CreateSlot(ctx context.Context, slot *models.InterviewSlot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot.ID, slot.TenantID, slot.Booked = store.NewID(), tenant.ID(ctx), 0
+	s.slots[slot.ID] = *slot
+	return nil
+}
+
+// GetSlot implements Store.
+func (s *MemoryStore) GetSlot(ctx context.Context, id string) (*models.InterviewSlot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.slot(ctx, id)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &slot, nil
+}
+
+// ListSlots implements Store.
+func (s *MemoryStore) ListSlots(ctx context.Context, programCode string) ([]models.InterviewSlot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.InterviewSlot{}
+	for id, slot := range s.slots {
+		if slot.ProgramCode == programCode && tenant.Allows(ctx, slot.TenantID) {
+			slot.Booked = s.booked(id)
+			out = append(out, slot)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartAt.Equal(out[j].StartAt) {
+			return out[i].StartAt.Before(out[j].StartAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// Book implements Store.
+func (s *MemoryStore) Book(ctx context.Context, slotID, appID string, at time.Time) (*models.InterviewBooking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.slot(ctx, slotID)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if _, ok := s.bookingFor(appID, slot.ProgramCode, slot.TenantID); ok {
+		return nil, ErrAlreadyBooked
+	}
+	if slot.Booked >= slot.MaxCandidates {
+		return nil, ErrFull
+	}
+	b := models.InterviewBooking{ID: store.NewID(), SlotID: slotID, ApplicationID: appID, BookedAt: at.UTC()}
+	s.bookings[b.ID] = b
+	return &b, nil
+}
+
+// BookingFor implements Store.
+func (s *MemoryStore) BookingFor(ctx context.Context, appID, programCode string) (*models.InterviewBooking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.bookingFor(appID, programCode, tenant.ID(ctx))
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &b, nil
+}
+
+// Cancel implements Store.
+func (s *MemoryStore) Cancel(ctx context.Context, bookingID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.bookings[bookingID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := s.slot(ctx, b.SlotID); !ok {
+		return store.ErrNotFound
+	}
+	delete(s.bookings, bookingID)
+	return nil
+}
+
+// slot returns the slot with id, with its Booked count, if ctx's tenant
+// may see it.
+func (s *MemoryStore) slot(ctx context.Context, id string) (models.InterviewSlot, bool) {
+	slot, ok := s.slots[id]
+	if !ok || !tenant.Allows(ctx, slot.TenantID) {
+		return models.InterviewSlot{}, false
+	}
+	slot.Booked = s.booked(id)
+	return slot, true
+}
+
+func (s *MemoryStore) booked(slotID string) int {
+	n := 0
+	for _, b := range s.bookings {
+		if b.SlotID == slotID {
+			n++
+		}
+	}
+	return n
+}
+
+// bookingFor finds appID's booking in a slot of programCode. An empty
+// tenantID, an unscoped context's, matches slots of every tenant.
+func (s *MemoryStore) bookingFor(appID, programCode, tenantID string) (models.InterviewBooking, bool) {
+	for _, b := range s.bookings {
+		slot := s.slots[b.SlotID]
+		if b.ApplicationID == appID && slot.ProgramCode == programCode && (tenantID == "" || slot.TenantID == tenantID) {
+			return b, true
+		}
+	}
+	return models.InterviewBooking{}, false
+}
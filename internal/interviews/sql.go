@@ -0,0 +1,200 @@
+package interviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLStore keeps slots and bookings in the interview_slots and
+// interview_bookings tables (see prisma/schema.prisma), in transactions
+// scoped to the context's tenant (see store.Scoped).
+type SQLStore struct {
+	DB *sql.DB
+}
+
+const slotColumns = `s.id, s.program_code, s.start_at, s.end_at, s.advisor_id, s.max_candidates, s.tenant_id,
+    (SELECT count(*) FROM interview_bookings b WHERE b.slot_id = s.id)`
+
+// CreateSlot implements Store.
+func (s SQLStore) CreateSlot(ctx context.Context, slot *models.InterviewSlot) error {
+	const stmt = `INSERT INTO interview_slots (id, program_code, start_at, end_at, advisor_id, max_candidates)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING tenant_id`
+	slot.ID, slot.Booked = store.NewID(), 0
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		return query(ctx, tx, stmt, []any{slot.ID, slot.ProgramCode, slot.StartAt.UTC(), slot.EndAt.UTC(), slot.AdvisorID, slot.MaxCandidates}, &slot.TenantID)
+	})
+}
+
+// GetSlot implements Store.
+func (s SQLStore) GetSlot(ctx context.Context, id string) (*models.InterviewSlot, error) {
+	stmt := `SELECT ` + slotColumns + ` FROM interview_slots s WHERE s.id = $1`
+	var slot *models.InterviewSlot
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		var err error
+		slot, err = scanSlot(tx.QueryRowContext(ctx, stmt, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("interviews: query: %w", err)
+		}
+		return nil
+	})
+	return slot, err
+}
+
+// ListSlots implements Store.
+func (s SQLStore) ListSlots(ctx context.Context, programCode string) ([]models.InterviewSlot, error) {
+	stmt := `SELECT ` + slotColumns + ` FROM interview_slots s WHERE s.program_code = $1 ORDER BY s.start_at, s.id`
+	out := []models.InterviewSlot{}
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		rows, err := tx.QueryContext(ctx, stmt, programCode)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("interviews: query: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			slot, err := scanSlot(rows)
+			if err != nil {
+				return fmt.Errorf("interviews: scan: %w", err)
+			}
+			out = append(out, *slot)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("interviews: query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Book implements Store. The slot's row is locked with SELECT ... FOR
+// UPDATE for the rest of the transaction, so bookings of one slot count
+// and insert one at a time; the unique key on application and program
+// catches a concurrent booking of the same application in another slot.
+func (s SQLStore) Book(ctx context.Context, slotID, appID string, at time.Time) (*models.InterviewBooking, error) {
+	const (
+		lockSlot = `SELECT program_code, max_candidates FROM interview_slots WHERE id = $1 FOR UPDATE`
+		booked   = `SELECT EXISTS (SELECT 1 FROM interview_bookings WHERE application_id = $1 AND program_code = $2)`
+		count    = `SELECT count(*) FROM interview_bookings WHERE slot_id = $1`
+		insert   = `INSERT INTO interview_bookings (id, slot_id, application_id, program_code, booked_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id, application_id, program_code) DO NOTHING
+RETURNING id`
+	)
+	b := &models.InterviewBooking{ID: store.NewID(), SlotID: slotID, ApplicationID: appID, BookedAt: at.UTC()}
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		var (
+			program string
+			max, n  int
+			exists  bool
+		)
+		err := query(ctx, tx, lockSlot, []any{slotID}, &program, &max)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := query(ctx, tx, booked, []any{appID, program}, &exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyBooked
+		}
+		if err := query(ctx, tx, count, []any{slotID}, &n); err != nil {
+			return err
+		}
+		if n >= max {
+			return ErrFull
+		}
+		err = query(ctx, tx, insert, []any{b.ID, slotID, appID, program, b.BookedAt}, &b.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAlreadyBooked
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// BookingFor implements Store.
+func (s SQLStore) BookingFor(ctx context.Context, appID, programCode string) (*models.InterviewBooking, error) {
+	const stmt = `SELECT id, slot_id, application_id, booked_at FROM interview_bookings WHERE application_id = $1 AND program_code = $2`
+	var b models.InterviewBooking
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		err := query(ctx, tx, stmt, []any{appID, programCode}, &b.ID, &b.SlotID, &b.ApplicationID, &b.BookedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.BookedAt = b.BookedAt.UTC()
+	return &b, nil
+}
+
+// Cancel implements Store.
+func (s SQLStore) Cancel(ctx context.Context, bookingID string) error {
+	const stmt = `DELETE FROM interview_bookings WHERE id = $1`
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		res, err := tx.ExecContext(ctx, stmt, bookingID)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("interviews: delete: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return store.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// query scans the one row stmt returns into dest, returning
+// sql.ErrNoRows unwrapped for the caller to map.
+func query(ctx context.Context, tx *sql.Tx, stmt string, args []any, dest ...any) error {
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	err := tx.QueryRowContext(ctx, stmt, args...).Scan(dest...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("interviews: query: %w", err)
+	}
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSlot(row scanner) (*models.InterviewSlot, error) {
+	var slot models.InterviewSlot
+	if err := row.Scan(&slot.ID, &slot.ProgramCode, &slot.StartAt, &slot.EndAt, &slot.AdvisorID, &slot.MaxCandidates, &slot.TenantID, &slot.Booked); err != nil {
+		return nil, err
+	}
+	slot.StartAt, slot.EndAt = slot.StartAt.UTC(), slot.EndAt.UTC()
+	return &slot, nil
+}
@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// Job types for uploaded documents.
+const (
+	TypeScanDocument      = "document.scan"
+	TypeGenerateThumbnail = "document.thumbnail"
+)
+
+// DefaultThumbnailWidth is the thumbnail width, in pixels, when a
+// GenerateThumbnail leaves Width unset.
+const DefaultThumbnailWidth = 256
+
+// ScanDocument asks for a stored document to be virus-scanned and its
+// text extracted.
+type ScanDocument struct {
+	DocumentID    string `json:"document_id"`
+	ApplicationID string `json:"application_id"`
+	// Key locates the file in the document store.
+	Key      string `json:"key"`
+	MIMEType string `json:"mime_type"`
+}
+
+// Type implements Job.
+func (ScanDocument) Type() string { return TypeScanDocument }
+
+// Payload implements Job.
+func (j ScanDocument) Payload() json.RawMessage { return mustMarshal(j) }
+
+// GenerateThumbnail asks for a preview image of a stored document. Only
+// images get one for now; DocumentProcessor skips PDFs.
+type GenerateThumbnail struct {
+	DocumentID string `json:"document_id"`
+	Key        string `json:"key"`
+	MIMEType   string `json:"mime_type"`
+	Width      int    `json:"width,omitempty"`
+}
+
+// Type implements Job.
+func (GenerateThumbnail) Type() string { return TypeGenerateThumbnail }
+
+// Payload implements Job.
+func (j GenerateThumbnail) Payload() json.RawMessage { return mustMarshal(j) }
+
+// DocumentFiles reads stored documents back and stores the files derived
+// from them; documents.LocalUploader and documents.S3Uploader are both
+// one.
+type DocumentFiles interface {
+	documents.Opener
+	documents.Putter
+}
+
+// DocumentProcessor runs the jobs queued for an uploaded document. A
+// document deleted before its job runs is skipped.
+type DocumentProcessor struct {
+	Files DocumentFiles
+	// Scanner, if set, scans each document, and a document it flags is
+	// soft-deleted from Metas, keeping the file for inspection. Without
+	// one, ScanDocument jobs do nothing.
+	Scanner documents.Scanner
+	Metas   documents.MetaStore
+	// Logger receives quarantines and skipped jobs; nil means
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// Register makes p the handler of the document job types on w.
+func (p *DocumentProcessor) Register(w *Worker) {
+	w.Handle(TypeScanDocument, p.Scan)
+	w.Handle(TypeGenerateThumbnail, p.Thumbnail)
+}
+
+// Scan handles a ScanDocument job.
+func (p *DocumentProcessor) Scan(ctx context.Context, job Job) error {
+	var j ScanDocument
+	if err := Decode(job, &j); err != nil {
+		return err
+	}
+	if p.Scanner == nil {
+		return nil
+	}
+	f, err := p.Files.Open(ctx, j.Key)
+	if errors.Is(err, documents.ErrNotFound) {
+		p.logger().Info("document gone before its scan", "document_id", j.DocumentID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = p.Scanner.Scan(ctx, f)
+	var infected *documents.InfectedError
+	if !errors.As(err, &infected) {
+		return err
+	}
+	p.logger().Warn("quarantining infected document", "document_id", j.DocumentID, "application_id", j.ApplicationID, "signature", infected.Signature)
+	if err := p.Metas.Delete(ctx, j.DocumentID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("jobs: quarantine document %s: %w", j.DocumentID, err)
+	}
+	return nil
+}
+
+// Thumbnail handles a GenerateThumbnail job, storing the thumbnail under
+// documents.ThumbnailKey.
+func (p *DocumentProcessor) Thumbnail(ctx context.Context, job Job) error {
+	var j GenerateThumbnail
+	if err := Decode(job, &j); err != nil {
+		return err
+	}
+	if j.MIMEType != documents.MIMEJPEG && j.MIMEType != documents.MIMEPNG {
+		return nil
+	}
+	width := j.Width
+	if width <= 0 {
+		width = DefaultThumbnailWidth
+	}
+	f, err := p.Files.Open(ctx, j.Key)
+	if errors.Is(err, documents.ErrNotFound) {
+		p.logger().Info("document gone before its thumbnail", "document_id", j.DocumentID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Reading it first tells a failed read, worth a retry, from a file
+	// that will not decode any better on one.
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	thumb, err := documents.Thumbnail(bytes.NewReader(data), width)
+	if err != nil {
+		p.logger().Warn("no thumbnail for document", "document_id", j.DocumentID, "error", err)
+		return nil
+	}
+	return p.Files.Put(ctx, documents.ThumbnailKey(j.Key), bytes.NewReader(thumb), documents.MIMEPNG)
+}
+
+func (p *DocumentProcessor) logger() *slog.Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return slog.Default()
+}
+
+// mustMarshal encodes a job struct, which holds only strings and numbers
+// and so cannot fail.
+func mustMarshal(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
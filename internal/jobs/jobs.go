@@ -0,0 +1,87 @@
+// Package jobs runs work that should not hold up a request, such as
+// scanning and thumbnailing an uploaded document, on a queue drained by a
+// Worker. RedisQueue shares the queue between replicas; MemoryQueue keeps
+// it in process.
+//
+//	q := jobs.NewRedisQueue(client)
+//	w := jobs.NewWorker(q)
+//	w.Handle(jobs.TypeScanDocument, scan)
+//	go w.Run(ctx)
+//	q.Enqueue(ctx, jobs.ScanDocument{DocumentID: meta.ID, Key: meta.Key})
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Job is a unit of work: a type naming its handler and a JSON payload.
+type Job interface {
+	Type() string
+	Payload() json.RawMessage
+}
+
+// Queue holds jobs until a worker takes them. Dequeue blocks until a job
+// is ready or ctx is done, and returns the job as an *Envelope.
+type Queue interface {
+	Enqueue(ctx context.Context, job Job) error
+	Dequeue(ctx context.Context) (Job, error)
+}
+
+// RetryQueue is a Queue that can deliver a failed job again later and
+// park one that keeps failing. Worker needs one.
+type RetryQueue interface {
+	Queue
+	// Retry delivers env again once delay has passed.
+	Retry(ctx context.Context, env *Envelope, delay time.Duration) error
+	// DeadLetter parks env where it is no longer delivered, for an
+	// operator to inspect.
+	DeadLetter(ctx context.Context, env *Envelope) error
+}
+
+// Envelope is a Job as it sits on a queue: the job plus its delivery
+// history. Enqueue wraps other Jobs in one.
+type Envelope struct {
+	ID      string          `json:"id"`
+	JobType string          `json:"type"`
+	Data    json.RawMessage `json:"payload"`
+	// Attempts counts the failed deliveries so far.
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Type implements Job.
+func (e *Envelope) Type() string { return e.JobType }
+
+// Payload implements Job.
+func (e *Envelope) Payload() json.RawMessage { return e.Data }
+
+// envelope wraps job for queueing. An *Envelope is queued as it is, so a
+// dequeued job can be enqueued again without losing its history.
+func envelope(job Job) (*Envelope, error) {
+	if e, ok := job.(*Envelope); ok {
+		return e, nil
+	}
+	if job.Type() == "" {
+		return nil, fmt.Errorf("jobs: %T has no type", job)
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	return &Envelope{ID: hex.EncodeToString(id[:]), JobType: job.Type(), Data: job.Payload(), EnqueuedAt: time.Now().UTC()}, nil
+}
+
+// Decode unmarshals job's payload into v, typically the concrete job
+// struct its handler expects.
+func Decode(job Job, v any) error {
+	if err := json.Unmarshal(job.Payload(), v); err != nil {
+		return fmt.Errorf("jobs: decode %s payload: %w", job.Type(), err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// startWorker runs w until the test ends.
+func startWorker(t *testing.T, w *Worker) {
+	t.Helper()
+	w.Logger = quiet
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerProcessesJobs(t *testing.T) {
+	q := NewMemoryQueue()
+	w := NewWorker(q)
+	w.Concurrency = 3
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	w.Handle(TypeScanDocument, func(_ context.Context, job Job) error {
+		var scan ScanDocument
+		if err := Decode(job, &scan); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seen[scan.DocumentID] = true
+		return nil
+	})
+	startWorker(t, w)
+
+	for _, id := range []string{"doc-1", "doc-2", "doc-3", "doc-4", "doc-5"} {
+		if err := q.Enqueue(context.Background(), ScanDocument{DocumentID: id, Key: "applications/a1/" + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eventually(t, "all jobs processed", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == 5
+	})
+}
+
+func TestWorkerRetriesThenDeadLetters(t *testing.T) {
+	q := NewMemoryQueue()
+	w := NewWorker(q)
+	w.MaxAttempts = 3
+	w.Backoff = 10 * time.Millisecond
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	w.Handle(TypeGenerateThumbnail, func(context.Context, Job) error {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		n := len(calls)
+		mu.Unlock()
+		if n == 2 {
+			panic("decoder crashed")
+		}
+		return errors.New("image is corrupt")
+	})
+	startWorker(t, w)
+
+	q.Enqueue(context.Background(), GenerateThumbnail{DocumentID: "doc-1"})
+	eventually(t, "the job to be dead-lettered", func() bool { return len(q.Dead()) == 1 })
+
+	dead := q.Dead()[0]
+	if dead.JobType != TypeGenerateThumbnail || dead.Attempts != 3 || dead.LastError != "image is corrupt" {
+		t.Errorf("dead letter = %+v", dead)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 3 {
+		t.Fatalf("%d deliveries, want 3", len(calls))
+	}
+	// The backoff doubles: 10ms, then 20ms.
+	if gap := calls[1].Sub(calls[0]); gap < 10*time.Millisecond {
+		t.Errorf("first retry after %s, want at least 10ms", gap)
+	}
+	if gap := calls[2].Sub(calls[1]); gap < 20*time.Millisecond {
+		t.Errorf("second retry after %s, want at least 20ms", gap)
+	}
+	if q.Len() != 0 {
+		t.Errorf("%d jobs left on the queue", q.Len())
+	}
+}
+
+func TestWorkerDeadLettersUnknownType(t *testing.T) {
+	q := NewMemoryQueue()
+	startWorker(t, NewWorker(q))
+	q.Enqueue(context.Background(), ScanDocument{DocumentID: "doc-1"})
+	eventually(t, "the job to be dead-lettered", func() bool { return len(q.Dead()) == 1 })
+	if d := q.Dead()[0]; d.Attempts != 0 || !strings.Contains(d.LastError, "no handler") {
+		t.Errorf("dead letter = %+v", d)
+	}
+}
+
+func TestWorkerRequeuesOnShutdown(t *testing.T) {
+	q := NewMemoryQueue()
+	w := NewWorker(q)
+	w.Logger = quiet
+	started := make(chan struct{})
+	w.Handle(TypeScanDocument, func(ctx context.Context, _ Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	q.Enqueue(context.Background(), ScanDocument{DocumentID: "doc-1"})
+	<-started
+	cancel()
+	<-done
+
+	job, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env := job.(*Envelope); env.Attempts != 0 || env.Type() != TypeScanDocument {
+		t.Errorf("requeued job = %+v, want it back with no attempt counted", env)
+	}
+	if len(q.Dead()) != 0 {
+		t.Error("interrupted job was dead-lettered")
+	}
+}
+
+func TestWorkerBackoff(t *testing.T) {
+	w := &Worker{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 60: 10 * time.Second} {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestMemoryQueueDelaysRetries(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+	env, _ := envelope(ScanDocument{DocumentID: "doc-1"})
+	q.Retry(ctx, env, 30*time.Millisecond)
+	q.Enqueue(ctx, ScanDocument{DocumentID: "doc-2"})
+
+	start := time.Now()
+	first, _ := q.Dequeue(ctx)
+	second, _ := q.Dequeue(ctx)
+	var a, b ScanDocument
+	Decode(first, &a)
+	Decode(second, &b)
+	if a.DocumentID != "doc-2" || b.DocumentID != "doc-1" {
+		t.Errorf("dequeued %s then %s, want the ready job before the retry", a.DocumentID, b.DocumentID)
+	}
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("retry delivered after %s, want at least 30ms", waited)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("empty queue: %v", err)
+	}
+}
+
+func TestMemoryQueueConcurrentDequeue(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := q.Dequeue(ctx); err == nil {
+				got.Add(1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	for range 8 {
+		q.Enqueue(ctx, GenerateThumbnail{DocumentID: "doc"})
+	}
+	wg.Wait()
+	if got.Load() != 8 {
+		t.Errorf("%d of 8 blocked Dequeues got a job", got.Load())
+	}
+}
+
+// flagScanner reports every document containing "EICAR" as infected.
+type flagScanner struct{}
+
+func (flagScanner) Scan(_ context.Context, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("EICAR")) {
+		return &documents.InfectedError{Signature: "Eicar-Test-Signature"}
+	}
+	return nil
+}
+
+func TestDocumentProcessor(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := documents.NewLocalUploader(dir)
+	metas := documents.NewMemoryMetaStore()
+	p := &DocumentProcessor{Files: files, Scanner: flagScanner{}, Metas: metas, Logger: quiet}
+	upload := func(body []byte) *documents.Meta {
+		t.Helper()
+		meta, err := files.Upload(ctx, "app-1", bytes.NewReader(body), "scan", -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := metas.Create(ctx, meta); err != nil {
+			t.Fatal(err)
+		}
+		return meta
+	}
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 600, 300)))
+	clean := upload(img.Bytes())
+	infected := upload([]byte("%PDF-1.7 EICAR"))
+
+	for _, m := range []*documents.Meta{clean, infected} {
+		if err := p.Scan(ctx, ScanDocument{DocumentID: m.ID, ApplicationID: m.ApplicationID, Key: m.Key, MIMEType: m.MIMEType}); err != nil {
+			t.Errorf("Scan %s: %v", m.ID, err)
+		}
+	}
+	if _, err := metas.GetByID(ctx, clean.ID); err != nil {
+		t.Errorf("clean document: %v", err)
+	}
+	if _, err := metas.GetByID(ctx, infected.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("infected document: err = %v, want it quarantined", err)
+	}
+
+	if err := p.Thumbnail(ctx, GenerateThumbnail{DocumentID: clean.ID, Key: clean.Key, MIMEType: clean.MIMEType}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(documents.ThumbnailKey(clean.Key))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if cfg, err := png.DecodeConfig(f); err != nil || cfg.Width != DefaultThumbnailWidth || cfg.Height != DefaultThumbnailWidth/2 {
+		t.Errorf("thumbnail = %+v, %v", cfg, err)
+	}
+	// PDFs get none, and a document gone from the store is skipped.
+	if err := p.Thumbnail(ctx, GenerateThumbnail{DocumentID: infected.ID, Key: infected.Key, MIMEType: infected.MIMEType}); err != nil {
+		t.Errorf("PDF thumbnail: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(documents.ThumbnailKey(infected.Key)))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PDF got a thumbnail: %v", err)
+	}
+	if err := p.Scan(ctx, ScanDocument{DocumentID: "gone", Key: "applications/app-1/gone.png"}); err != nil {
+		t.Errorf("missing document: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryQueue is a RetryQueue for a single instance, such as local
+// development or tests. Its jobs are lost when the process exits.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	ready   []*Envelope
+	delayed []delayed
+	dead    []*Envelope
+	wake    chan struct{}
+}
+
+type delayed struct {
+	env *Envelope
+	due time.Time
+}
+
+// NewMemoryQueue returns an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{wake: make(chan struct{}, 1)}
+}
+
+// Enqueue implements Queue.
+func (q *MemoryQueue) Enqueue(_ context.Context, job Job) error {
+	env, err := envelope(job)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.ready = append(q.ready, env)
+	q.mu.Unlock()
+	q.signal()
+	return nil
+}
+
+// Dequeue implements Queue.
+func (q *MemoryQueue) Dequeue(ctx context.Context) (Job, error) {
+	for {
+		env, wait := q.take(time.Now())
+		if env != nil {
+			return env, nil
+		}
+		var (
+			timer *time.Timer
+			due   <-chan time.Time
+		)
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-q.wake:
+		case <-due:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// take pops the next ready job, first moving retries due by now onto the
+// ready list. With none ready it returns how long until the next retry is
+// due, or 0 if there is none.
+func (q *MemoryQueue) take(now time.Time) (*Envelope, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var (
+		pending = q.delayed[:0]
+		next    time.Duration
+	)
+	for _, d := range q.delayed {
+		if !d.due.After(now) {
+			q.ready = append(q.ready, d.env)
+			continue
+		}
+		if wait := d.due.Sub(now); next == 0 || wait < next {
+			next = wait
+		}
+		pending = append(pending, d)
+	}
+	q.delayed = pending
+	if len(q.ready) == 0 {
+		return nil, next
+	}
+	env := q.ready[0]
+	q.ready = q.ready[1:]
+	if len(q.ready) > 0 {
+		// One wake-up may stand for several jobs; pass it on.
+		q.signal()
+	}
+	return env, 0
+}
+
+// Retry implements RetryQueue.
+func (q *MemoryQueue) Retry(_ context.Context, env *Envelope, delay time.Duration) error {
+	q.mu.Lock()
+	q.delayed = append(q.delayed, delayed{env, time.Now().Add(delay)})
+	q.mu.Unlock()
+	q.signal()
+	return nil
+}
+
+// DeadLetter implements RetryQueue.
+func (q *MemoryQueue) DeadLetter(_ context.Context, env *Envelope) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, env)
+	return nil
+}
+
+// Dead returns the dead-lettered jobs, oldest first.
+func (q *MemoryQueue) Dead() []*Envelope {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Envelope(nil), q.dead...)
+}
+
+// Len returns how many jobs are ready or waiting to be retried.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + len(q.delayed)
+}
+
+// signal wakes one blocked Dequeue to look again.
+func (q *MemoryQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
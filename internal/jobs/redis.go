@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPollInterval is how long a RedisQueue Dequeue blocks in BLPOP
+// before looking for due retries again.
+const DefaultPollInterval = time.Second
+
+// promoteBatch caps how many due retries one Dequeue moves to the queue.
+const promoteBatch = 100
+
+// promoteScript moves retries that are due from the delayed set (KEYS[1])
+// to the tail of the queue (KEYS[2]), up to ARGV[1] of them. Like retry,
+// it reads the server's clock, so replicas with skewed clocks agree on
+// when a retry is due.
+var promoteScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, tonumber(ARGV[1]))
+for _, job in ipairs(due) do
+  redis.call('ZREM', KEYS[1], job)
+  redis.call('RPUSH', KEYS[2], job)
+end
+return #due
+`)
+
+// retryScript adds ARGV[2] to the delayed set (KEYS[1]), due ARGV[1]
+// milliseconds from now.
+var retryScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+return redis.call('ZADD', KEYS[1], now + tonumber(ARGV[1]), ARGV[2])
+`)
+
+// RedisQueue is a RetryQueue shared by every replica through Redis. Jobs
+// are JSON Envelopes pushed with RPUSH onto a list and taken with BLPOP,
+// so each is delivered to one worker; retries wait in a sorted set
+// scored by due time, and dead letters go to a list of their own. A job
+// is off the queue once dequeued: one whose worker dies mid-job is lost,
+// so handlers that must not lose work record their progress elsewhere.
+type RedisQueue struct {
+	Client redis.Cmdable
+	// Prefix namespaces the keys: <prefix>queue, <prefix>delayed, and
+	// <prefix>dead. It defaults to "jobs:".
+	Prefix string
+	// PollInterval bounds how late a due retry is delivered; zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// NewRedisQueue returns a RedisQueue using client.
+func NewRedisQueue(client redis.Cmdable) *RedisQueue {
+	return &RedisQueue{Client: client, Prefix: "jobs:"}
+}
+
+func (q *RedisQueue) queueKey() string   { return q.Prefix + "queue" }
+func (q *RedisQueue) delayedKey() string { return q.Prefix + "delayed" }
+func (q *RedisQueue) deadKey() string    { return q.Prefix + "dead" }
+
+// Check pings Redis, so a RedisQueue can back a readiness check.
+func (q *RedisQueue) Check(ctx context.Context) error {
+	return q.Client.Ping(ctx).Err()
+}
+
+// Enqueue implements Queue.
+func (q *RedisQueue) Enqueue(ctx context.Context, job Job) error {
+	raw, err := encode(job)
+	if err != nil {
+		return err
+	}
+	if err := q.Client.RPush(ctx, q.queueKey(), raw).Err(); err != nil {
+		return fmt.Errorf("jobs: redis: %w", err)
+	}
+	return nil
+}
+
+// Dequeue implements Queue. A job that is not a valid Envelope is moved
+// to the dead-letter list and reported as an error.
+func (q *RedisQueue) Dequeue(ctx context.Context) (Job, error) {
+	poll := q.PollInterval
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+	for {
+		if err := promoteScript.Run(ctx, q.Client, []string{q.delayedKey(), q.queueKey()}, promoteBatch).Err(); err != nil {
+			return nil, q.redisError(ctx, err)
+		}
+		res, err := q.Client.BLPop(ctx, poll, q.queueKey()).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, q.redisError(ctx, err)
+		}
+		var env Envelope
+		if err := json.Unmarshal([]byte(res[1]), &env); err != nil || env.JobType == "" {
+			if err == nil {
+				err = errors.New("no type")
+			}
+			if derr := q.Client.RPush(ctx, q.deadKey(), res[1]).Err(); derr != nil {
+				return nil, fmt.Errorf("jobs: malformed job %q (%v) could not be dead-lettered: %w", res[1], err, derr)
+			}
+			return nil, fmt.Errorf("jobs: malformed job moved to %s: %w", q.deadKey(), err)
+		}
+		return &env, nil
+	}
+}
+
+// Retry implements RetryQueue.
+func (q *RedisQueue) Retry(ctx context.Context, env *Envelope, delay time.Duration) error {
+	raw, err := encode(env)
+	if err != nil {
+		return err
+	}
+	if err := retryScript.Run(ctx, q.Client, []string{q.delayedKey()}, delay.Milliseconds(), raw).Err(); err != nil {
+		return fmt.Errorf("jobs: redis: %w", err)
+	}
+	return nil
+}
+
+// DeadLetter implements RetryQueue.
+func (q *RedisQueue) DeadLetter(ctx context.Context, env *Envelope) error {
+	raw, err := encode(env)
+	if err != nil {
+		return err
+	}
+	if err := q.Client.RPush(ctx, q.deadKey(), raw).Err(); err != nil {
+		return fmt.Errorf("jobs: redis: %w", err)
+	}
+	return nil
+}
+
+// redisError reports ctx's error when ctx ended the call, so callers can
+// tell shutdown from a Redis failure.
+func (q *RedisQueue) redisError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("jobs: redis: %w", err)
+}
+
+func encode(job Job) ([]byte, error) {
+	env, err := envelope(job)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedisQueue runs a RedisQueue, with promoteScript and retryScript,
+// on the Redis at REDIS_TEST_URL, such as redis://localhost:6379/15, and
+// is skipped without one.
+func TestRedisQueue(t *testing.T) {
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		t.Skip("REDIS_TEST_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+	q := NewRedisQueue(client)
+	q.Prefix = "jobs:test:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	t.Cleanup(func() { client.Del(context.Background(), q.queueKey(), q.delayedKey(), q.deadKey()) })
+
+	if err := q.Check(ctx); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	// Jobs come off in the order they went on.
+	for _, id := range []string{"doc-1", "doc-2"} {
+		if err := q.Enqueue(ctx, ScanDocument{DocumentID: id, Key: "applications/app-1/" + id + ".pdf"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var first *Envelope
+	for _, want := range []string{"doc-1", "doc-2"} {
+		job, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scan ScanDocument
+		if err := Decode(job, &scan); err != nil || job.Type() != TypeScanDocument || scan.DocumentID != want {
+			t.Fatalf("dequeued %s %+v (%v), want %s", job.Type(), scan, err, want)
+		}
+		if first == nil {
+			first = job.(*Envelope)
+		}
+	}
+
+	// A retry waits in the delayed set until it is due, then is promoted
+	// behind the jobs already queued, keeping its history.
+	first.Attempts, first.LastError = 1, "clamd: connection refused"
+	start := time.Now()
+	if err := q.Retry(ctx, first, 300*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := client.ZCard(ctx, q.delayedKey()).Result(); err != nil || n != 1 {
+		t.Fatalf("delayed set holds %d (%v), want 1", n, err)
+	}
+	if err := q.Enqueue(ctx, GenerateThumbnail{DocumentID: "doc-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if job, err := q.Dequeue(ctx); err != nil || job.Type() != TypeGenerateThumbnail {
+		t.Fatalf("dequeued %v (%v), want the ready job before the retry", job, err)
+	}
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 250*time.Millisecond {
+		t.Errorf("retry delivered after %s, want about 300ms", waited)
+	}
+	env := job.(*Envelope)
+	if env.ID != first.ID || env.Attempts != 1 || env.LastError != first.LastError {
+		t.Errorf("retried job = %+v, want %+v", env, first)
+	}
+	if n, _ := client.ZCard(ctx, q.delayedKey()).Result(); n != 0 {
+		t.Errorf("delayed set still holds %d after promotion", n)
+	}
+
+	// Malformed jobs and jobs that keep failing end up in the dead-letter
+	// list.
+	for _, raw := range []string{"not json", `{"id":"j-1","payload":{}}`} {
+		if err := client.RPush(ctx, q.queueKey(), raw).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.Dequeue(ctx); err == nil || !strings.Contains(err.Error(), q.deadKey()) {
+			t.Errorf("malformed job %q: err = %v, want it dead-lettered", raw, err)
+		}
+	}
+	if err := q.DeadLetter(ctx, env); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := client.LRange(ctx, q.deadKey(), 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 3 || dead[0] != "not json" || dead[1] != `{"id":"j-1","payload":{}}` {
+		t.Fatalf("dead letters = %q", dead)
+	}
+	var parked Envelope
+	if err := json.Unmarshal([]byte(dead[2]), &parked); err != nil || parked.ID != first.ID || parked.JobType != TypeScanDocument {
+		t.Errorf("dead-lettered job = %+v (%v)", parked, err)
+	}
+	if n, _ := client.LLen(ctx, q.queueKey()).Result(); n != 0 {
+		t.Errorf("queue still holds %d jobs", n)
+	}
+}
@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Worker defaults.
+const (
+	DefaultConcurrency = 4
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultMaxBackoff  = 5 * time.Minute
+)
+
+// dequeueBackoff is how long a worker goroutine waits after the queue
+// fails before asking it again.
+const dequeueBackoff = time.Second
+
+// HandlerFunc processes one job. A returned error, or a panic, fails the
+// delivery; ctx is cancelled when the Worker stops.
+type HandlerFunc func(ctx context.Context, job Job) error
+
+// Worker processes jobs from Queue on Concurrency goroutines, passing
+// each to the handler registered for its type. A failed job is retried
+// after a backoff that doubles from Backoff up to MaxBackoff, and
+// dead-lettered once it has failed MaxAttempts times; a job with no
+// handler is dead-lettered at once. Worker implements server.Worker.
+type Worker struct {
+	Queue RetryQueue
+	// Concurrency is how many jobs run at once; zero means
+	// DefaultConcurrency.
+	Concurrency int
+	// MaxAttempts is how many deliveries a job gets; zero means
+	// DefaultMaxAttempts.
+	MaxAttempts int
+	// Backoff is the delay before the first retry and MaxBackoff the
+	// longest; zero means DefaultBackoff and DefaultMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Logger receives failures and panics; nil means slog.Default().
+	Logger *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+// NewWorker returns a Worker for q with no handlers.
+func NewWorker(q RetryQueue) *Worker {
+	return &Worker{Queue: q, handlers: map[string]HandlerFunc{}}
+}
+
+// Handle registers h for jobs of jobType, replacing any earlier handler.
+func (w *Worker) Handle(jobType string, h HandlerFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.handlers == nil {
+		w.handlers = map[string]HandlerFunc{}
+	}
+	w.handlers[jobType] = h
+}
+
+// Run processes jobs until ctx is done, then returns once the jobs in
+// progress have finished. A job cut off by ctx goes back on the queue
+// without counting as an attempt.
+func (w *Worker) Run(ctx context.Context) {
+	n := w.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		job, err := w.Queue.Dequeue(ctx)
+		if ctx.Err() != nil {
+			if err == nil {
+				w.requeue(ctx, job)
+			}
+			return
+		}
+		if err != nil {
+			w.logger().Error("dequeue job", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(dequeueBackoff):
+			}
+			continue
+		}
+		w.process(ctx, job)
+	}
+}
+
+// process runs job and settles the outcome: done, retried, or dead.
+func (w *Worker) process(ctx context.Context, job Job) {
+	env, err := envelope(job)
+	if err != nil {
+		w.logger().Error("dequeued job is unusable", "type", job.Type(), "error", err)
+		return
+	}
+	log := w.logger().With("job_id", env.ID, "job_type", env.JobType)
+	w.mu.RLock()
+	h, ok := w.handlers[env.JobType]
+	w.mu.RUnlock()
+	if !ok {
+		env.LastError = "no handler for job type"
+		w.deadLetter(ctx, log, env)
+		return
+	}
+
+	err = w.call(ctx, log, h, env)
+	switch {
+	case err == nil:
+		return
+	case ctx.Err() != nil:
+		// Cut off by shutdown, not the job's fault.
+		w.requeue(ctx, env)
+		return
+	}
+	env.Attempts++
+	env.LastError = err.Error()
+	if env.Attempts >= w.maxAttempts() {
+		w.deadLetter(ctx, log, env)
+		return
+	}
+	delay := w.backoff(env.Attempts)
+	log.Warn("job failed; will retry", "error", err, "attempts", env.Attempts, "retry_in", delay)
+	if err := w.Queue.Retry(ctx, env, delay); err != nil {
+		log.Error("job lost: retry failed", "error", err)
+	}
+}
+
+// call runs h, turning a panic into an error.
+func (w *Worker) call(ctx context.Context, log *slog.Logger, h HandlerFunc, env *Envelope) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("job panicked", "panic", p, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, env)
+}
+
+func (w *Worker) deadLetter(ctx context.Context, log *slog.Logger, env *Envelope) {
+	log.Error("job dead-lettered", "error", env.LastError, "attempts", env.Attempts)
+	if err := w.Queue.DeadLetter(ctx, env); err != nil {
+		log.Error("job lost: dead-letter failed", "error", err)
+	}
+}
+
+// requeue puts a job taken or interrupted during shutdown back on the
+// queue, outliving ctx.
+func (w *Worker) requeue(ctx context.Context, job Job) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dequeueBackoff)
+	defer cancel()
+	if err := w.Queue.Enqueue(ctx, job); err != nil {
+		w.logger().Error("job lost: requeue at shutdown failed", "job_type", job.Type(), "error", err)
+	}
+}
+
+// backoff is the delay before the retry following the attempts-th
+// failure.
+func (w *Worker) backoff(attempts int) time.Duration {
+	base, ceiling := w.Backoff, w.MaxBackoff
+	if base <= 0 {
+		base = DefaultBackoff
+	}
+	if ceiling <= 0 {
+		ceiling = DefaultMaxBackoff
+	}
+	d := base
+	for i := 1; i < attempts && d < ceiling; i++ {
+		d *= 2
+	}
+	return min(d, ceiling)
+}
+
+func (w *Worker) maxAttempts() int {
+	if w.MaxAttempts > 0 {
+		return w.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+func (w *Worker) logger() *slog.Logger {
+	if w.Logger != nil {
+		return w.Logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,186 @@
+// Package letters produces the official PDF letters that tell applicants
+// of an admission decision. A letter is rendered once per decision,
+// archived next to the application's documents, and served from the
+// archive afterwards.
+package letters
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// ErrNoDecision is returned for an application whose status is not a
+// decision, so there is no letter to send.
+var ErrNoDecision = errors.New("letters: no decision has been made")
+
+//go:embed templates/letter.html templates/logo.png
+var files embed.FS
+
+var (
+	letterTemplate = template.Must(template.New("letter.html").Option("missingkey=error").ParseFS(files, "templates/letter.html"))
+	logo           = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(mustRead("templates/logo.png")))
+)
+
+func mustRead(name string) []byte {
+	b, err := files.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Renderer turns an HTML document into a PDF.
+type Renderer interface {
+	Render(ctx context.Context, html []byte) (io.Reader, error)
+}
+
+// Archive stores rendered letters and reads them back;
+// documents.LocalUploader and documents.S3Uploader are both one.
+type Archive interface {
+	documents.Uploader
+	documents.Opener
+}
+
+// Generator produces decision letters for the applications in
+// Applications: an acceptance letter once one is accepted or enrolled and
+// a rejection letter once it is rejected.
+type Generator struct {
+	Applications store.ApplicationStore
+	Renderer     Renderer
+	Archive      Archive
+	// University heads the letter and Signatory signs it.
+	University string
+	Signatory  string
+	now        func() time.Time
+}
+
+// NewGenerator returns a Generator with a generic letterhead.
+func NewGenerator(apps store.ApplicationStore, r Renderer, archive Archive) *Generator {
+	return &Generator{
+		Applications: apps,
+		Renderer:     r,
+		Archive:      archive,
+		University:   "Office of Admissions",
+		Signatory:    "Director of Admissions",
+		now:          time.Now,
+	}
+}
+
+// letterData is what the letter template sees.
+type letterData struct {
+	Application models.StudentApplication
+	Accepted    bool
+	University  string
+	Signatory   string
+	Date        string
+	Logo        template.URL
+}
+
+// Generate returns the PDF letter for application appID, or ErrNoDecision
+// if it has none yet. The first call for a decision renders the letter
+// and records its archive key as the application's LetterKey; later ones
+// return the archived copy, so the letter an applicant downloads does not
+// change until the decision does.
+func (g *Generator) Generate(ctx context.Context, appID string) (io.Reader, error) {
+	app, err := g.Applications.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	var accepted bool
+	switch app.Status {
+	case status.Accepted, status.Enrolled:
+		accepted = true
+	case status.Rejected:
+	default:
+		return nil, ErrNoDecision
+	}
+	if app.LetterKey != "" {
+		pdf, err := g.archived(ctx, app.LetterKey)
+		if !errors.Is(err, documents.ErrNotFound) {
+			return pdf, err
+		}
+	}
+	pdf, err := g.render(ctx, letterData{
+		Application: *app,
+		Accepted:    accepted,
+		University:  g.University,
+		Signatory:   g.Signatory,
+		Date:        g.clock().UTC().Format("January 2, 2006"),
+		Logo:        logo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	meta, err := g.Archive.Upload(ctx, app.ID, bytes.NewReader(pdf), "letter.pdf", int64(len(pdf)))
+	if err != nil {
+		return nil, fmt.Errorf("letters: archive: %w", err)
+	}
+	// Record the key on a fresh copy, and only while the decision is the
+	// one the letter states, so a status change made meanwhile is neither
+	// overwritten nor given this letter.
+	cur, err := g.Applications.GetByID(ctx, app.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Status == app.Status {
+		cur.LetterKey = meta.Key
+		if err := g.Applications.Update(ctx, cur); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.NewReader(pdf), nil
+}
+
+// archived reads the whole of a stored letter, so a failure surfaces here
+// rather than halfway through a response.
+func (g *Generator) archived(ctx context.Context, key string) (io.Reader, error) {
+	rc, err := g.Archive.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	pdf, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("letters: read %s: %w", key, err)
+	}
+	return bytes.NewReader(pdf), nil
+}
+
+// render executes the letter template and converts it to a PDF.
+func (g *Generator) render(ctx context.Context, data letterData) ([]byte, error) {
+	var html bytes.Buffer
+	if err := letterTemplate.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("letters: template: %w", err)
+	}
+	out, err := g.Renderer.Render(ctx, html.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := io.ReadAll(out)
+	if err != nil {
+		return nil, fmt.Errorf("letters: render: %w", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		return nil, errors.New("letters: renderer did not produce a PDF")
+	}
+	return pdf, nil
+}
+
+func (g *Generator) clock() time.Time {
+	if g.now == nil {
+		return time.Now()
+	}
+	return g.now()
+}
@@ -0,0 +1,179 @@
+package letters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/documents"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// fakeRenderer wraps the HTML it is given in a PDF header.
+type fakeRenderer struct {
+	calls int
+	html  string
+	out   string
+}
+
+func (f *fakeRenderer) Render(_ context.Context, html []byte) (io.Reader, error) {
+	f.calls++
+	f.html = string(html)
+	if f.out != "" {
+		return strings.NewReader(f.out), nil
+	}
+	return strings.NewReader("%PDF-1.4\n" + f.html), nil
+}
+
+// decide creates an application and moves it through path.
+func decide(t *testing.T, apps *store.MemoryStore, path ...status.State) *models.StudentApplication {
+	t.Helper()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Round: "early", Status: status.Pending}
+	if err := apps.Create(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range path {
+		app.Status = s
+		if err := apps.Update(context.Background(), app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app
+}
+
+// generate returns the letter for appID, failing the test unless it is a
+// PDF.
+func generate(t *testing.T, g *Generator, appID string) string {
+	t.Helper()
+	r, err := g.Generate(context.Background(), appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readPDF(t, r)
+}
+
+func readPDF(t *testing.T, r io.Reader) string {
+	t.Helper()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte("%PDF-")) {
+		t.Fatalf("letter is not a PDF: %q", b[:min(len(b), 16)])
+	}
+	return string(b)
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	apps := store.NewMemoryStore()
+	r := &fakeRenderer{}
+	g := NewGenerator(apps, r, documents.NewLocalUploader(t.TempDir()))
+	g.University = "Northfield University"
+
+	if _, err := g.Generate(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("missing application: %v, want store.ErrNotFound", err)
+	}
+	pending := decide(t, apps, status.UnderReview)
+	if _, err := g.Generate(ctx, pending.ID); !errors.Is(err, ErrNoDecision) {
+		t.Errorf("application under review: %v, want ErrNoDecision", err)
+	}
+
+	app := decide(t, apps, status.UnderReview, status.Accepted)
+	letter := generate(t, g, app.ID)
+	for _, want := range []string{"offer you a place", "<strong>CS</strong> program, early round", "Northfield University", `src="data:image/png;base64,`} {
+		if !strings.Contains(letter, want) {
+			t.Errorf("acceptance letter lacks %q", want)
+		}
+	}
+	stored, _ := apps.GetByID(ctx, app.ID)
+	if !strings.HasPrefix(stored.LetterKey, "applications/"+app.ID+"/") {
+		t.Fatalf("letter key = %q", stored.LetterKey)
+	}
+	if again := generate(t, g, app.ID); again != letter || r.calls != 1 {
+		t.Errorf("second call rendered again (%d renders) or changed the letter", r.calls)
+	}
+
+	stored.Status = status.Enrolled
+	if err := apps.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	generate(t, g, app.ID)
+	if r.calls != 2 {
+		t.Errorf("status change: %d renders, want a new letter", r.calls)
+	}
+
+	rejected := decide(t, apps, status.UnderReview, status.Rejected)
+	if letter := generate(t, g, rejected.ID); !strings.Contains(letter, "unable to offer you a place") {
+		t.Errorf("rejection letter = %q", letter)
+	}
+}
+
+func TestGenerateRerendersLostLetter(t *testing.T) {
+	ctx := context.Background()
+	apps := store.NewMemoryStore()
+	dir := t.TempDir()
+	r := &fakeRenderer{}
+	g := NewGenerator(apps, r, documents.NewLocalUploader(dir))
+	app := decide(t, apps, status.UnderReview, status.Rejected)
+	generate(t, g, app.ID)
+	stored, _ := apps.GetByID(ctx, app.ID)
+	if err := os.Remove(filepath.Join(dir, filepath.FromSlash(stored.LetterKey))); err != nil {
+		t.Fatal(err)
+	}
+	generate(t, g, app.ID)
+	if again, _ := apps.GetByID(ctx, app.ID); r.calls != 2 || again.LetterKey == stored.LetterKey {
+		t.Errorf("lost letter: %d renders, key %q", r.calls, again.LetterKey)
+	}
+}
+
+func TestGenerateRejectsNonPDF(t *testing.T) {
+	apps := store.NewMemoryStore()
+	g := NewGenerator(apps, &fakeRenderer{out: "<html>"}, documents.NewLocalUploader(t.TempDir()))
+	app := decide(t, apps, status.UnderReview, status.Accepted)
+	if _, err := g.Generate(context.Background(), app.ID); err == nil {
+		t.Fatal("renderer output without a PDF header was accepted")
+	}
+	if stored, _ := apps.GetByID(context.Background(), app.ID); stored.LetterKey != "" {
+		t.Errorf("letter key = %q after a failed render", stored.LetterKey)
+	}
+}
+
+func TestWKHTMLToPDF(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ok := WKHTMLToPDF{Path: script("ok", "cat >/dev/null\nprintf '%%PDF-1.4 fake'\n")}
+	out, err := ok.Render(context.Background(), []byte("<p>hi</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readPDF(t, out); got != "%PDF-1.4 fake" {
+		t.Errorf("output = %q", got)
+	}
+	broken := WKHTMLToPDF{Path: script("broken", "echo 'Exit with code 1 due to network error' >&2\nexit 1\n")}
+	if _, err := broken.Render(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "network error") {
+		t.Errorf("failing binary: %v, want its stderr", err)
+	}
+
+	if _, err := exec.LookPath("wkhtmltopdf"); err != nil {
+		t.Skip("wkhtmltopdf not installed")
+	}
+	apps := store.NewMemoryStore()
+	g := NewGenerator(apps, WKHTMLToPDF{}, documents.NewLocalUploader(t.TempDir()))
+	app := decide(t, apps, status.UnderReview, status.Accepted)
+	generate(t, g, app.ID)
+}
@@ -0,0 +1,37 @@
+package letters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// WKHTMLToPDF renders with the wkhtmltopdf command-line tool, which must
+// be installed on the host. Pages are A4 and may not load local files;
+// the logo is inlined, so letters need nothing outside the HTML.
+type WKHTMLToPDF struct {
+	// Path is the binary to run; empty means wkhtmltopdf from PATH.
+	Path string
+}
+
+// Render pipes html through wkhtmltopdf and returns the PDF it writes.
+func (w WKHTMLToPDF) Render(ctx context.Context, html []byte) (io.Reader, error) {
+	path := w.Path
+	if path == "" {
+		path = "wkhtmltopdf"
+	}
+	cmd := exec.CommandContext(ctx, path, "--quiet", "--encoding", "utf-8", "--page-size", "A4", "--disable-local-file-access", "-", "-")
+	cmd.Stdin = bytes.NewReader(html)
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("letters: wkhtmltopdf: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("letters: wkhtmltopdf: %w", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,186 @@
+// Package listing parses the paging, sorting, and filtering query
+// parameters of list endpoints:
+//
+//	GET /v1/programs?limit=20&sort=-name&filter=faculty:Engineering&cursor=...
+//
+// Paging is keyset-based: a cursor is an opaque token naming the last item
+// of the previous page under the same sort, so pages stay stable while
+// items are added or removed before them.
+//
+//	params, errs := listing.Parse(r.URL.Query(), listing.Options{Sorts: []string{"code", "name"}})
+//	// sort items by params.Sort, then:
+//	page, next := listing.Page(items, params, func(p Program) listing.Position {
+//		return listing.Position{Key: p.Name, ID: p.Code}
+//	})
+package listing
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Page sizes for Options that set none.
+const (
+	DefaultLimit    = 20
+	DefaultMaxLimit = 100
+)
+
+// Options describe one endpoint's listing.
+type Options struct {
+	// DefaultLimit is the page size without ?limit=; zero means
+	// DefaultLimit.
+	DefaultLimit int
+	// MaxLimit caps ?limit=; zero means DefaultMaxLimit.
+	MaxLimit int
+	// Sorts are the fields ?sort= may name; the first is the default.
+	Sorts []string
+	// Filters are the fields ?filter= may name.
+	Filters []string
+}
+
+// ListParams are a parsed listing request.
+type ListParams struct {
+	Limit int
+	// Sort is the field to order by, descending if Desc.
+	Sort string
+	Desc bool
+	// Filter maps each filtered field to its value.
+	Filter map[string]string
+	// After is where the page starts; zero for the first page.
+	After Position
+}
+
+// Position is an item's place in a sorted listing: its value of the sort
+// field, then an ID unique within the listing breaking ties.
+type Position struct {
+	Key string `json:"k"`
+	ID  string `json:"i"`
+}
+
+// IsZero reports whether p is the start of the listing.
+func (p Position) IsZero() bool { return p == Position{} }
+
+func (p Position) compare(q Position) int {
+	if c := cmp.Compare(p.Key, q.Key); c != 0 {
+		return c
+	}
+	return cmp.Compare(p.ID, q.ID)
+}
+
+// ParamError is a query parameter Parse rejected.
+type ParamError struct {
+	Param   string
+	Message string
+}
+
+func (e ParamError) Error() string { return e.Param + " " + e.Message }
+
+// Parse reads ?limit=, ?cursor=, ?sort=, and ?filter= from q:
+//
+//   - limit is a positive integer, capped at o.MaxLimit;
+//   - sort names one of o.Sorts, prefixed with - to sort descending;
+//   - filter is field:value, naming one of o.Filters, and may be repeated
+//     for different fields;
+//   - cursor is a value a previous page's Cursor returned under the same
+//     sort.
+//
+// Every rejected parameter is reported.
+func Parse(q url.Values, o Options) (ListParams, []ParamError) {
+	p := ListParams{Limit: cmp.Or(o.DefaultLimit, DefaultLimit), Filter: map[string]string{}}
+	if len(o.Sorts) > 0 {
+		p.Sort = o.Sorts[0]
+	}
+	var errs []ParamError
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errs = append(errs, ParamError{"limit", "must be a positive integer"})
+		}
+		p.Limit = min(n, cmp.Or(o.MaxLimit, DefaultMaxLimit))
+	}
+	if v := q.Get("sort"); v != "" {
+		field, desc := strings.CutPrefix(v, "-")
+		if !slices.Contains(o.Sorts, field) {
+			errs = append(errs, ParamError{"sort", fmt.Sprintf("must be one of %s, prefixed with - to sort descending", strings.Join(o.Sorts, ", "))})
+		}
+		p.Sort, p.Desc = field, desc
+	}
+	for _, v := range q["filter"] {
+		field, value, ok := strings.Cut(v, ":")
+		switch {
+		case !ok:
+			errs = append(errs, ParamError{"filter", "must be field:value"})
+		case !slices.Contains(o.Filters, field):
+			errs = append(errs, ParamError{"filter", fmt.Sprintf("cannot filter on %q; filterable fields are %s", field, strings.Join(o.Filters, ", "))})
+		case p.Filter[field] != "":
+			errs = append(errs, ParamError{"filter", fmt.Sprintf("filters on %s twice", field)})
+		default:
+			p.Filter[field] = value
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		after, err := p.decode(v)
+		if err != nil {
+			errs = append(errs, *err)
+		}
+		p.After = after
+	}
+	return p, errs
+}
+
+// cursor is what a cursor token encodes.
+type cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Position
+}
+
+// Cursor returns the token of the page after one ending at last.
+func (p ListParams) Cursor(last Position) string {
+	b, _ := json.Marshal(cursor{Sort: p.Sort, Desc: p.Desc, Position: last})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p ListParams) decode(token string) (Position, *ParamError) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.Position.IsZero() {
+		return Position{}, &ParamError{"cursor", "is not a cursor returned by this endpoint"}
+	}
+	if c.Sort != p.Sort || c.Desc != p.Desc {
+		return Position{}, &ParamError{"cursor", "was returned for another sort; keep sort the same while paging"}
+	}
+	return c.Position, nil
+}
+
+// Page returns the page of items p selects and the cursor of the next
+// page, or "" if this is the last. items must be in p's order, ascending
+// or descending by the Position pos returns.
+func Page[T any](items []T, p ListParams, pos func(T) Position) ([]T, string) {
+	start := 0
+	if !p.After.IsZero() {
+		start = len(items)
+		for i, item := range items {
+			c := pos(item).compare(p.After)
+			if p.Desc {
+				c = -c
+			}
+			if c > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	items = items[start:]
+	if len(items) <= p.Limit {
+		return items, ""
+	}
+	page := items[:p.Limit]
+	return page, p.Cursor(pos(page[len(page)-1]))
+}
@@ -0,0 +1,111 @@
+package listing
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var programs = Options{Sorts: []string{"code", "name"}, Filters: []string{"faculty"}, MaxLimit: 50}
+
+func parse(t *testing.T, query string) (ListParams, []ParamError) {
+	t.Helper()
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Parse(q, programs)
+}
+
+func TestParseDefaults(t *testing.T) {
+	p, errs := parse(t, "")
+	if len(errs) > 0 || p.Limit != DefaultLimit || p.Sort != "code" || p.Desc || !p.After.IsZero() || len(p.Filter) != 0 {
+		t.Errorf("defaults: %+v %v", p, errs)
+	}
+	p, errs = parse(t, "sort=-name&filter=faculty:Engineering&limit=5")
+	if len(errs) > 0 || p.Sort != "name" || !p.Desc || p.Filter["faculty"] != "Engineering" || p.Limit != 5 {
+		t.Errorf("parsed: %+v %v", p, errs)
+	}
+}
+
+func TestLimitIsCapped(t *testing.T) {
+	if p, errs := parse(t, "limit=500"); len(errs) > 0 || p.Limit != 50 {
+		t.Errorf("limit=500: %d %v, want capped at 50", p.Limit, errs)
+	}
+	if p, errs := Parse(url.Values{"limit": {"500"}}, Options{}); len(errs) > 0 || p.Limit != DefaultMaxLimit {
+		t.Errorf("without MaxLimit: %d %v", p.Limit, errs)
+	}
+	for _, bad := range []string{"0", "-1", "ten"} {
+		if _, errs := parse(t, "limit="+bad); len(errs) != 1 || errs[0].Param != "limit" {
+			t.Errorf("limit=%s: %v", bad, errs)
+		}
+	}
+}
+
+func TestRejectsUnknownSortAndFilter(t *testing.T) {
+	_, errs := parse(t, "sort=capacity&filter=status:open&filter=faculty&filter=faculty:Arts&filter=faculty:Law")
+	var params []string
+	for _, e := range errs {
+		params = append(params, e.Param)
+	}
+	if strings.Join(params, ",") != "sort,filter,filter,filter" {
+		t.Fatalf("errors %v", errs)
+	}
+	if !strings.Contains(errs[0].Message, "code, name") {
+		t.Errorf("sort error %q does not list the fields", errs[0].Message)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	p, _ := parse(t, "sort=-name")
+	token := p.Cursor(Position{Key: "History", ID: "HIST"})
+	next, errs := parse(t, "sort=-name&cursor="+token)
+	if len(errs) > 0 || next.After != (Position{Key: "History", ID: "HIST"}) {
+		t.Fatalf("decoded %+v %v", next.After, errs)
+	}
+	if _, errs := parse(t, "sort=name&cursor="+token); len(errs) != 1 || errs[0].Param != "cursor" {
+		t.Errorf("cursor under another sort: %v", errs)
+	}
+	for _, bad := range []string{"not base64!", "e30"} { // e30 is {}
+		if _, errs := parse(t, "cursor="+url.QueryEscape(bad)); len(errs) != 1 || errs[0].Param != "cursor" {
+			t.Errorf("cursor %q: %v", bad, errs)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	codes := []string{"AA", "BB", "CC", "DD", "EE"}
+	pos := func(c string) Position { return Position{Key: c, ID: c} }
+	for _, desc := range []bool{false, true} {
+		items := slices.Clone(codes)
+		query := "limit=2"
+		if desc {
+			slices.Reverse(items)
+			query += "&sort=-code"
+		}
+		var got []string
+		cursor := ""
+		for range 5 {
+			p, errs := parse(t, query+"&cursor="+cursor)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			page, next := Page(items, p, pos)
+			got = append(got, page...)
+			if cursor = next; cursor == "" {
+				break
+			}
+		}
+		if !slices.Equal(got, items) {
+			t.Errorf("desc=%v: paged %v, want %v", desc, got, items)
+		}
+	}
+
+	// A cursor whose item was deleted resumes at the next one.
+	p, _ := parse(t, "limit=2")
+	p.After = Position{Key: "BC", ID: "BC"}
+	if page, next := Page(codes, p, pos); !slices.Equal(page, []string{"CC", "DD"}) || next == "" {
+		t.Errorf("after a deleted item: %v %q", page, next)
+	}
+}
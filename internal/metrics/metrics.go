@@ -0,0 +1,112 @@
+// Package metrics records the admissions API's Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Recorder owns the API's collectors and the registry they are
+// registered with. A nil *Recorder records nothing, so handlers can take
+// one optionally. Tests build one on a fresh registry and read the values
+// back with prometheus/testutil.
+type Recorder struct {
+	Registry *prometheus.Registry
+
+	RequestDuration   *prometheus.HistogramVec
+	Requests          *prometheus.CounterVec
+	StatusTransitions *prometheus.CounterVec
+	UploadBytes       prometheus.Counter
+	CircuitStates     *prometheus.GaugeVec
+	CircuitTrips      *prometheus.CounterVec
+}
+
+// circuitValues are the circuit_breaker_state values.
+var circuitValues = map[string]float64{"closed": 0, "half_open": 1, "open": 2}
+
+// NewRecorder registers the collectors with reg, or with a new registry
+// if reg is nil.
+func NewRecorder(reg *prometheus.Registry) *Recorder {
+	if reg == nil {
+		reg = prometheus.NewRegistry()
+	}
+	r := &Recorder{
+		Registry: reg,
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route, and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path", "status"}),
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route, and status.",
+		}, []string{"method", "path", "status"}),
+		StatusTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "application_status_transitions_total",
+			Help: "Application status changes by previous and new status.",
+		}, []string{"from", "to"}),
+		UploadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "document_upload_bytes_total",
+			Help: "Bytes of documents stored.",
+		}),
+		CircuitStates: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state by dependency: 0 closed, 1 half-open, 2 open.",
+		}, []string{"dependency"}),
+		CircuitTrips: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "circuit_breaker_trips_total",
+			Help: "Times a dependency's circuit breaker opened.",
+		}, []string{"dependency"}),
+	}
+	reg.MustRegister(r.RequestDuration, r.Requests, r.StatusTransitions, r.UploadBytes, r.CircuitStates, r.CircuitTrips)
+	return r
+}
+
+// ObserveRequest records one served request. path is the route pattern,
+// not the raw URL path, to keep the label set bounded.
+func (r *Recorder) ObserveRequest(method, path string, status int, d time.Duration) {
+	if r == nil {
+		return
+	}
+	code := strconv.Itoa(status)
+	r.RequestDuration.WithLabelValues(method, path, code).Observe(d.Seconds())
+	r.Requests.WithLabelValues(method, path, code).Inc()
+}
+
+// StatusTransition records an application moving from one status to
+// another.
+func (r *Recorder) StatusTransition(from, to string) {
+	if r == nil {
+		return
+	}
+	r.StatusTransitions.WithLabelValues(from, to).Inc()
+}
+
+// UploadedBytes adds n stored document bytes.
+func (r *Recorder) UploadedBytes(n int64) {
+	if r == nil || n <= 0 {
+		return
+	}
+	r.UploadBytes.Add(float64(n))
+}
+
+// CircuitState records that dependency's circuit breaker is now in
+// state (closed, half_open, or open), counting a trip when it opens.
+func (r *Recorder) CircuitState(dependency, state string) {
+	if r == nil {
+		return
+	}
+	r.CircuitStates.WithLabelValues(dependency).Set(circuitValues[state])
+	if state == "open" {
+		r.CircuitTrips.WithLabelValues(dependency).Inc()
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.Registry, promhttp.HandlerOpts{Registry: r.Registry})
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(nil)
+	rec.ObserveRequest("GET", "/v1/applications/{id}", 200, 30*time.Millisecond)
+	rec.ObserveRequest("GET", "/v1/applications/{id}", 200, 10*time.Millisecond)
+	rec.ObserveRequest("GET", "/v1/applications/{id}", 404, time.Millisecond)
+	rec.StatusTransition("submitted", "under_review")
+	rec.UploadedBytes(512)
+	rec.UploadedBytes(0)
+	rec.CircuitState("smtp", "closed")
+	rec.CircuitState("smtp", "open")
+	rec.CircuitState("smtp", "half_open")
+	rec.CircuitState("s3", "closed")
+
+	if got := testutil.ToFloat64(rec.Requests.WithLabelValues("GET", "/v1/applications/{id}", "200")); got != 2 {
+		t.Errorf("200 requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(rec.Requests.WithLabelValues("GET", "/v1/applications/{id}", "404")); got != 1 {
+		t.Errorf("404 requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(rec.RequestDuration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(rec.StatusTransitions.WithLabelValues("submitted", "under_review")); got != 1 {
+		t.Errorf("transitions = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(rec.UploadBytes); got != 512 {
+		t.Errorf("upload bytes = %v, want 512", got)
+	}
+	if got := testutil.ToFloat64(rec.CircuitStates.WithLabelValues("smtp")); got != 1 {
+		t.Errorf("smtp circuit state = %v, want 1 (half-open)", got)
+	}
+	if got := testutil.ToFloat64(rec.CircuitTrips.WithLabelValues("smtp")); got != 1 {
+		t.Errorf("smtp trips = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(rec.CircuitTrips.WithLabelValues("s3")); got != 0 {
+		t.Errorf("s3 trips = %v, want 0", got)
+	}
+
+	w := httptest.NewRecorder()
+	rec.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `document_upload_bytes_total 512`) {
+		t.Errorf("exposition: %d\n%s", w.Code, w.Body)
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var rec *Recorder
+	rec.ObserveRequest("GET", "/", 200, time.Second)
+	rec.StatusTransition("a", "b")
+	rec.UploadedBytes(1)
+	rec.CircuitState("smtp", "open")
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+)
+
+// CORS lets browser scripts on cfg.AllowedOrigins call the API, with the
+// pkg/middleware/cors rules: preflights are answered here, before auth,
+// and responses to listed origins carry Access-Control-Allow-Origin. An
+// origin that is not listed gets no CORS headers at all, preflights
+// included, which are answered 204 rather than 403, so the browser
+// rejects the call as it does any other. Without origins it does
+// nothing. It wraps the router, inside only RequestID, so preflights
+// skip auth and rate limiting. It panics if cfg fails
+// config.CORSConfig.Validate, which config.LoadCORS checks.
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	rules, err := cors.Compile(cfg.Policy())
+	if err != nil {
+		panic(fmt.Sprintf("middleware.CORS: %v", err))
+	}
+	apply := cors.New(func(*http.Request) *cors.Rules { return rules })
+	return func(next http.Handler) http.Handler {
+		if rules == nil {
+			return next
+		}
+		h := apply(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			o := r.Header.Get("Origin")
+			if r.Method == http.MethodOptions && o != "" && r.Header.Get("Access-Control-Request-Method") != "" && !rules.Allows(o) {
+				w.Header().Add("Vary", "Origin")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.uniassist.app"},
+		AllowedMethods:   []string{"GET", "POST", "PATCH"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	reached := 0
+	h := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		reached++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	call := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/applications", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+			req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call("OPTIONS", "https://app.uniassist.app", "PATCH")
+	if rec.Code != http.StatusNoContent || reached != 0 {
+		t.Fatalf("preflight: %d, reached handler %d times", rec.Code, reached)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.uniassist.app",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, PATCH",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("preflight %s = %q, want %q", name, got, want)
+		}
+	}
+
+	rec = call("GET", "https://app.uniassist.app", "")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.uniassist.app" || rec.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+		t.Errorf("allowed origin: %d %v", rec.Code, rec.Header())
+	}
+
+	// Unknown origins get no CORS headers, and preflights no 403.
+	for _, method := range []string{"OPTIONS", "GET"} {
+		requestMethod := ""
+		if method == "OPTIONS" {
+			requestMethod = "POST"
+		}
+		rec := call(method, "https://evil.example", requestMethod)
+		for name := range rec.Header() {
+			if strings.HasPrefix(name, "Access-Control") {
+				t.Errorf("%s from an unknown origin got %s", method, name)
+			}
+		}
+		if method == "OPTIONS" && rec.Code != http.StatusNoContent {
+			t.Errorf("unknown origin preflight: %d, want 204", rec.Code)
+		}
+	}
+
+	// An allowed origin asking for a method not listed is refused.
+	if rec := call("OPTIONS", "https://app.uniassist.app", "DELETE"); rec.Code != http.StatusForbidden {
+		t.Errorf("disallowed method: %d, want 403", rec.Code)
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusMethodNotAllowed) })
+	req := httptest.NewRequest("OPTIONS", "/v1/applications", nil)
+	req.Header.Set("Origin", "https://app.uniassist.app")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	CORS(config.CORSConfig{AllowedMethods: []string{"GET"}})(next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed || len(rec.Header()) != 0 {
+		t.Errorf("without origins: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORSWildcardWithCredentialsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("* origin with credentials accepted")
+		}
+	}()
+	CORS(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/idempotency"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// IdempotencyKeyHeader carries the client's key for a mutation.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// maxIdempotentBody bounds the responses stored for replay; a larger
+	// one is not stored, and a retry runs again.
+	maxIdempotentBody = 1 << 20
+	// idempotencyPendingTTL bounds how long a claimed key blocks retries
+	// if the instance running the first request dies mid-way.
+	idempotencyPendingTTL = 5 * time.Minute
+)
+
+// replayedHeaders are the response headers stored with the body.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency makes mutations sent with an Idempotency-Key (a UUID) safe
+// to retry: the first response to a caller's key is stored for ttl and
+// replayed for every retry, status and body verbatim, with
+// Idempotent-Replayed: true. A retry that arrives while the first request
+// is still running answers 409 IDEMPOTENCY_CONFLICT, and a key reused for
+// another method or path 422 IDEMPOTENCY_KEY_REUSED. Keys are scoped to
+// the authenticated subject; GET and HEAD requests, requests without the
+// header, and unauthenticated requests pass through. 5xx responses are not
+// stored, so the retry runs again. If the store fails the request runs
+// without the guarantee, as with RateLimit.
+func Idempotency(store idempotency.Store, ttl time.Duration) func(http.Handler) http.Handler {
+	pendingTTL := min(ttl, idempotencyPendingTTL)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := r.Header.Get(IdempotencyKeyHeader)
+			if raw == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				respond.Error(w, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY", "Idempotency-Key must be a UUID")
+				return
+			}
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || claims.Subject == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := "user:" + claims.Subject + ":" + id.String()
+			fingerprint := r.Method + " " + r.URL.Path
+			log := logging.FromContext(r.Context())
+
+			prev, claimed, err := claimKey(r.Context(), store, key, idempotency.Record{Fingerprint: fingerprint}, pendingTTL)
+			if err != nil {
+				log.Warn("idempotency store failed; running request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !claimed {
+				replay(w, prev, fingerprint)
+				return
+			}
+
+			// The outcome is stored even if the client has gone, since
+			// its retry is what the record is for.
+			ctx := context.WithoutCancel(r.Context())
+			settled := false
+			defer func() {
+				if !settled {
+					if err := store.Release(ctx, key); err != nil {
+						log.Warn("idempotency key release failed", "error", err)
+					}
+				}
+			}()
+			c := &responseCapture{ResponseWriter: w}
+			next.ServeHTTP(c, r)
+			status := c.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= 500 || c.overflow {
+				return
+			}
+			rec := idempotency.Record{Fingerprint: fingerprint, Done: true, Status: status, Header: http.Header{}, Body: c.body.Bytes()}
+			for _, h := range replayedHeaders {
+				if v := c.Header().Values(h); len(v) > 0 {
+					rec.Header[h] = v
+				}
+			}
+			if err := store.Save(ctx, key, rec, ttl); err != nil {
+				log.Warn("idempotency store failed; response not saved", "error", err)
+				return
+			}
+			settled = true
+		})
+	}
+}
+
+// claimKey claims key with rec, or returns the Record already there. A
+// Record that expires between the failed claim and the read is claimed
+// once more.
+func claimKey(ctx context.Context, store idempotency.Store, key string, rec idempotency.Record, ttl time.Duration) (idempotency.Record, bool, error) {
+	for attempt := 0; ; attempt++ {
+		claimed, err := store.Claim(ctx, key, rec, ttl)
+		if err != nil || claimed {
+			return idempotency.Record{}, claimed, err
+		}
+		prev, err := store.Get(ctx, key)
+		if errors.Is(err, idempotency.ErrNotFound) && attempt == 0 {
+			continue
+		}
+		return prev, false, err
+	}
+}
+
+// replay answers a request whose key prev already holds.
+func replay(w http.ResponseWriter, prev idempotency.Record, fingerprint string) {
+	switch {
+	case prev.Fingerprint != fingerprint:
+		respond.Error(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used for another request")
+	case !prev.Done:
+		w.Header().Set("Retry-After", "1")
+		respond.Error(w, http.StatusConflict, "IDEMPOTENCY_CONFLICT", "a request with this Idempotency-Key is in progress")
+	default:
+		h := w.Header()
+		for name, v := range prev.Header {
+			h[name] = v
+		}
+		h.Set("Idempotent-Replayed", "true")
+		w.WriteHeader(prev.Status)
+		w.Write(prev.Body)
+	}
+}
+
+// responseCapture copies the status and body written through it, up to
+// maxIdempotentBody.
+type responseCapture struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.overflow {
+		if c.body.Len()+len(b) > maxIdempotentBody {
+			c.overflow = true
+			c.body = bytes.Buffer{}
+		} else {
+			c.body.Write(b)
+		}
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/idempotency"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+const idemKey = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+func TestIdempotency(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	store := idempotency.NewMemStore(clock.Func(func() time.Time { return now }))
+	var created atomic.Int32
+	status := http.StatusCreated
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(Idempotency(store, time.Hour))
+	rt.HandleFunc("POST /v1/applications", func(w http.ResponseWriter, _ *http.Request) {
+		n := created.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", fmt.Sprintf("/v1/applications/a%d", n))
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"id":"a%d"}`, n)
+	})
+	rt.HandleFunc("POST /v1/programs", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	rt.HandleFunc("GET /v1/applications", func(w http.ResponseWriter, _ *http.Request) { created.Add(1) })
+
+	issuer := auth.NewIssuer("s3cret")
+	call := func(method, path, subject, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		pair, err := issuer.Issue(subject, "student")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := call("POST", "/v1/applications", "stu-1", idemKey)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: %d %v", first.Code, first.Header())
+	}
+	retry := call("POST", "/v1/applications", "stu-1", strings.ToUpper(idemKey))
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"id":"a1"}` {
+		t.Fatalf("retry: %d %s, want the first response", retry.Code, retry.Body)
+	}
+	if retry.Header().Get("Location") != "/v1/applications/a1" || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("retry headers: %v", retry.Header())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay is not marked Idempotent-Replayed")
+	}
+	if n := created.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+
+	// Keys are per caller, and mutations without one always run.
+	if rec := call("POST", "/v1/applications", "stu-2", idemKey); rec.Body.String() != `{"id":"a2"}` {
+		t.Errorf("another caller's key: %s", rec.Body)
+	}
+	call("POST", "/v1/applications", "stu-1", "")
+	if n := created.Load(); n != 3 {
+		t.Fatalf("handler ran %d times, want 3", n)
+	}
+
+	if rec := call("POST", "/v1/programs", "stu-1", idemKey); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "IDEMPOTENCY_KEY_REUSED") {
+		t.Errorf("key reused on another path: %d %s", rec.Code, rec.Body)
+	}
+	if rec := call("POST", "/v1/applications", "stu-1", "not-a-uuid"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_IDEMPOTENCY_KEY") {
+		t.Errorf("malformed key: %d %s", rec.Code, rec.Body)
+	}
+	call("GET", "/v1/applications", "stu-1", idemKey)
+	call("GET", "/v1/applications", "stu-1", idemKey)
+	if n := created.Load(); n != 5 {
+		t.Errorf("GETs with a key: handler ran %d times in all, want 5", n)
+	}
+
+	// After ttl the key is fresh.
+	now = now.Add(time.Hour)
+	if rec := call("POST", "/v1/applications", "stu-1", idemKey); rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expired key was replayed: %s", rec.Body)
+	}
+
+	// Server errors are not stored, so the retry runs again.
+	const other = "9b2c1f9e-8d3a-4c5e-a1f0-0e6d2b7c4a11"
+	status = http.StatusServiceUnavailable
+	call("POST", "/v1/applications", "stu-1", other)
+	status = http.StatusCreated
+	if rec := call("POST", "/v1/applications", "stu-1", other); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a 503: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotencyConcurrentRetry(t *testing.T) {
+	store := idempotency.NewMemStore(nil)
+	started, release := make(chan struct{}), make(chan struct{})
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(Idempotency(store, time.Hour))
+	rt.HandleFunc("POST /v1/applications", func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	pair, err := auth.NewIssuer("s3cret").Issue("stu-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/applications", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		req.Header.Set(IdempotencyKeyHeader, idemKey)
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+		return rec
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- call() }()
+	<-started
+	if rec := call(); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "IDEMPOTENCY_CONFLICT") {
+		t.Errorf("retry during the first request: %d %s", rec.Code, rec.Body)
+	}
+	close(release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Fatalf("first request: %d", rec.Code)
+	}
+	if rec := call(); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after it: %d %v", rec.Code, rec.Header())
+	}
+}
+
+// downStore is an idempotency.Store whose backend is unreachable.
+type downStore struct{}
+
+var errStoreDown = errors.New("connection refused")
+
+func (downStore) Claim(context.Context, string, idempotency.Record, time.Duration) (bool, error) {
+	return false, errStoreDown
+}
+
+func (downStore) Get(context.Context, string) (idempotency.Record, error) {
+	return idempotency.Record{}, errStoreDown
+}
+
+func (downStore) Save(context.Context, string, idempotency.Record, time.Duration) error {
+	return errStoreDown
+}
+
+func (downStore) Release(context.Context, string) error { return errStoreDown }
+
+func TestIdempotencyFailsOpen(t *testing.T) {
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(Idempotency(downStore{}, time.Hour))
+	rt.HandleFunc("POST /v1/applications", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusCreated) })
+	pair, err := auth.NewIssuer("s3cret").Issue("stu-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/v1/applications", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	req.Header.Set(IdempotencyKeyHeader, idemKey)
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("store down: %d, want the request to run", rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+// Package middleware holds the HTTP middleware used by the admissions API.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+)
+
+type contextKey string
+
+// ContextKeyClaims is the request context key holding the caller's
+// *auth.Claims once JWTAuth has accepted the request.
+const ContextKeyClaims contextKey = "claims"
+
+// JWTAuth rejects requests that do not carry a valid HS256 access token in
+// the Authorization header, or for a WebSocket handshake, which browsers
+// cannot add headers to, as the subprotocols "bearer" and the token.
+// Accepted claims are stored under ContextKeyClaims.
+func JWTAuth(secret string) func(http.Handler) http.Handler {
+	key := []byte(secret)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing bearer token")
+				return
+			}
+			claims, err := auth.Parse(key, token, auth.TokenTypeAccess, nil)
+			if err != nil {
+				respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyClaims, claims)))
+		})
+	}
+}
+
+// RejectRevoked rejects access tokens whose login session has been ended
+// through /auth/logout. It goes after JWTAuth; tokens without a session
+// pass through.
+func RejectRevoked(sessions auth.SessionStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claims.SessionID != "" {
+				revoked, err := sessions.Revoked(r.Context(), claims.SessionID)
+				if err != nil {
+					respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check session")
+					return
+				}
+				if revoked {
+					respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "session has been logged out")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireMFA rejects access tokens marked mfa_pending, those of a login
+// that still owes a TOTP code, so they are good only for the /auth/totp
+// endpoints, which check tokens themselves. It goes after JWTAuth.
+func RequireMFA() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claims.MFAPending {
+				respond.Error(w, http.StatusUnauthorized, "MFA_REQUIRED", "verify a TOTP code at /auth/totp/verify first")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims stored by JWTAuth.
+func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(ContextKeyClaims).(*auth.Claims)
+	return claims, ok
+}
+
+// WebSocketTokenProtocol is the subprotocol a WebSocket handshake offers
+// ahead of its access token, as in new WebSocket(url, ["bearer", token]).
+// The server selects it in its answer.
+const WebSocketTokenProtocol = "bearer"
+
+func bearerToken(r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") == "" {
+		return webSocketToken(r)
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func webSocketToken(r *http.Request) (string, bool) {
+	var protocols []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	if len(protocols) < 2 || protocols[0] != WebSocketTokenProtocol || protocols[1] == "" {
+		return "", false
+	}
+	return protocols[1], true
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+)
+
+func TestJWTAuth(t *testing.T) {
+	issuer := auth.NewIssuer("s3cret")
+	pair, err := issuer.Issue("student-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := &auth.Issuer{Secret: []byte("s3cret"), AccessTTL: time.Minute, Now: func() time.Time { return time.Now().Add(-time.Hour) }}
+	old, err := expired.Issue("student-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := auth.NewIssuer("other").Issue("student-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := JWTAuth("s3cret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			t.Error("claims missing from context")
+			return
+		}
+		_, _ = w.Write([]byte(claims.Subject))
+	}))
+
+	tests := []struct {
+		name      string
+		header    string
+		protocols string
+		want      int
+	}{
+		{"valid", "Bearer " + pair.AccessToken, "", http.StatusOK},
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + pair.AccessToken, "", http.StatusUnauthorized},
+		{"refresh token", "Bearer " + pair.RefreshToken, "", http.StatusUnauthorized},
+		{"expired", "Bearer " + old.AccessToken, "", http.StatusUnauthorized},
+		{"wrong secret", "Bearer " + other.AccessToken, "", http.StatusUnauthorized},
+		{"websocket protocol", "", "bearer, " + pair.AccessToken, http.StatusOK},
+		{"websocket protocol out of order", "", pair.AccessToken + ", bearer", http.StatusUnauthorized},
+		{"websocket protocol without token", "", "bearer", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/applications", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.protocols != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", tt.protocols)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized {
+				var body map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["code"] != "UNAUTHORIZED" {
+					t.Fatalf("unexpected body %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRejectRevoked(t *testing.T) {
+	issuer := auth.NewIssuer("s3cret")
+	sessions := auth.NewMemorySessions()
+	live, err := issuer.IssueSession("student-1", "student", "", "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ended, err := issuer.IssueSession("student-1", "student", "", "ended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := issuer.Issue("service-1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Revoke(context.Background(), "ended", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := Chain(JWTAuth("s3cret"), RejectRevoked(sessions))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		name, token string
+		want        int
+	}{
+		{"live session", live.AccessToken, http.StatusOK},
+		{"no session", plain.AccessToken, http.StatusOK},
+		{"logged out", ended.AccessToken, http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/applications", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestRequireMFA(t *testing.T) {
+	issuer := auth.NewIssuer("s3cret")
+	pending, err := issuer.IssuePending("admin-1", "admin", "", "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := issuer.IssueSession("admin-1", "admin", "", "s-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := Chain(JWTAuth("s3cret"), RequireMFA())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		name, token string
+		want        int
+		code        string
+	}{
+		{"pending", pending.AccessToken, http.StatusUnauthorized, "MFA_REQUIRED"},
+		{"verified", verified.AccessToken, http.StatusOK, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/applications", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want || (tc.code != "" && !strings.Contains(rec.Body.String(), tc.code)) {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// RequestIDHeader is requestid.Header.
+const RequestIDHeader = requestid.Header
+
+// RequestLogger stores a child of log with request_id, method, and path,
+// plus user_id once JWTAuth has run and trace_id once Trace or RequestID
+// has, in each request's context for logging.FromContext. The ID is the
+// one requestid.New assigned further out, or one assigned here with
+// requestid's defaults. Each request is logged once with its status and
+// duration when it completes.
+func RequestLogger(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return requestid.New(requestid.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := log.With("request_id", requestid.FromContext(r.Context()), "method", r.Method, "path", r.URL.Path)
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+				l = l.With("user_id", claims.Subject)
+			}
+			if tp, ok := TraceParentFromContext(r.Context()); ok {
+				l = l.With("trace_id", tp.TraceID.String())
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r.WithContext(logging.NewContext(r.Context(), l)))
+			l.Info("request", "status", rec.status, "duration_ms", time.Since(start).Milliseconds())
+		}))
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = code, true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Hijack passes through to the underlying writer, for WebSocket
+// upgrades, which are recorded as 101 Switching Protocols.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status, r.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(RequestLogger(logger))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusAccepted)
+	}
+	rt.HandleFunc("GET /v1/applications/{id}", handler)
+	rt.HandleFunc("GET /version", handler, router.SkipAuth())
+
+	pair, err := auth.NewIssuer("s3cret").Issue("stu-1", "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/v1/applications/app-1", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got != "req-42" {
+		t.Errorf("response request ID = %q, want the client's", got)
+	}
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want handler and completion:\n%v", len(lines), lines)
+	}
+	for _, line := range lines {
+		for k, want := range map[string]any{"request_id": "req-42", "user_id": "stu-1", "method": "GET", "path": "/v1/applications/app-1"} {
+			if line[k] != want {
+				t.Errorf("%s = %v, want %v in %v", k, line[k], want, line)
+			}
+		}
+	}
+	if lines[1]["status"] != float64(http.StatusAccepted) {
+		t.Errorf("completion line status = %v", lines[1]["status"])
+	}
+
+	req = httptest.NewRequest("GET", "/version", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nforged=1")
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	id := rec.Header().Get(RequestIDHeader)
+	if len(id) != 32 || strings.Contains(id, "forged") {
+		t.Errorf("malformed client ID kept or not replaced: %q", id)
+	}
+	lines = decodeLines(t, &buf)
+	if lines[0]["request_id"] != id {
+		t.Errorf("logged request_id %v, want %s", lines[0]["request_id"], id)
+	}
+	if _, ok := lines[0]["user_id"]; ok {
+		t.Errorf("public route logged a user_id: %v", lines[0])
+	}
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	defer buf.Reset()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("decode %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
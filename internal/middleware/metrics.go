@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+// unmatchedPath labels requests no route matched, so stray URLs cannot
+// grow the label set.
+const unmatchedPath = "unmatched"
+
+// Instrument records each request's latency and outcome in rec. It wraps
+// the whole Router, outside authentication, so rejected requests are
+// counted too, and labels them with the matched route pattern's path.
+func Instrument(rec *metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, pattern := router.CapturePattern(r.Context())
+			sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			rec.ObserveRequest(r.Method, patternPath(pattern()), sw.status, time.Since(start))
+		})
+	}
+}
+
+// patternPath drops the method from a pattern such as
+// "GET /v1/applications/{id}".
+func patternPath(pattern string) string {
+	if pattern == "" {
+		return unmatchedPath
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+// AllowNetworks answers 403 unless the client address is loopback or
+// inside one of allowed. Like RateLimit it trusts only RemoteAddr, not
+// proxy headers.
+func AllowNetworks(allowed []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allowedAddr(r.RemoteAddr, allowed) {
+				respond.Error(w, http.StatusForbidden, "FORBIDDEN", "not available from this address")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowedAddr(remote string, allowed []netip.Prefix) bool {
+	addr, ok := remoteAddr(remote)
+	return ok && (addr.IsLoopback() || inNetworks(addr, allowed))
+}
+
+// remoteAddr parses the IP of a RemoteAddr.
+func remoteAddr(remote string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		host = remote
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr.Unmap(), err == nil
+}
+
+func inNetworks(addr netip.Addr, nets []netip.Prefix) bool {
+	for _, p := range nets {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks reads a comma-separated CIDR list such as
+// "10.0.0.0/8, 192.168.1.10/32" for AllowNetworks.
+func ParseNetworks(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", item, err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+func TestInstrument(t *testing.T) {
+	rec := metrics.NewRecorder(nil)
+	rt := router.New(JWTAuth("s3cret"))
+	rt.HandleFunc("GET /v1/applications/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	rt.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}, router.SkipAuth())
+	h := Instrument(rec)(rt)
+
+	for _, path := range []string{"/version", "/version", "/v1/applications/app-1", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	for _, tt := range []struct {
+		path, status string
+		want         float64
+	}{
+		{"/version", "418", 2},
+		{"/v1/applications/{id}", "401", 1},
+		{unmatchedPath, "404", 1},
+	} {
+		if got := testutil.ToFloat64(rec.Requests.WithLabelValues("GET", tt.path, tt.status)); got != tt.want {
+			t.Errorf("requests{path=%q,status=%s} = %v, want %v", tt.path, tt.status, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(rec.RequestDuration); got != 3 {
+		t.Errorf("duration series = %d, want 3", got)
+	}
+}
+
+func TestAllowNetworks(t *testing.T) {
+	nets, err := ParseNetworks(" 10.0.0.0/8, 192.168.1.10/32 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nets) != 2 || nets[0] != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Fatalf("networks = %v", nets)
+	}
+	h := AllowNetworks(nets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for remote, want := range map[string]int{
+		"127.0.0.1:5000":       http.StatusOK,
+		"[::1]:5000":           http.StatusOK,
+		"10.2.3.4:5000":        http.StatusOK,
+		"[::ffff:10.2.3.4]:80": http.StatusOK,
+		"192.168.1.10:5000":    http.StatusOK,
+		"192.168.1.11:5000":    http.StatusForbidden,
+		"203.0.113.7:5000":     http.StatusForbidden,
+		"not-an-address":       http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d", remote, w.Code, want)
+		}
+	}
+
+	if _, err := ParseNetworks("10.0.0.0/8,10.0.0.1"); err == nil {
+		t.Error("bare address accepted as a CIDR")
+	}
+	if nets, err := ParseNetworks(""); err != nil || len(nets) != 0 {
+		t.Errorf("empty list: %v %v", nets, err)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// DefaultRateLimitScope names the bucket family of routes without their
+// own ratelimit.Config.
+const DefaultRateLimitScope = "default"
+
+// RateLimit allows each caller limit requests per window, keyed on the
+// authenticated subject or, on public routes, the client IP. A route can
+// carry its own budget in a ratelimit.Config (see router.Limit). Over the
+// limit it answers 429 with Retry-After; every response carries the
+// X-RateLimit-Limit, -Remaining, and -Reset (Unix seconds) headers. If
+// the store fails the request is let through, so a Redis outage does not
+// take the API down with it.
+func RateLimit(store ratelimit.Store, limit int, window time.Duration) func(http.Handler) http.Handler {
+	def := ratelimit.Config{Scope: DefaultRateLimitScope, Limit: limit, Window: window}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg, ok := ratelimit.ConfigFrom(r.Context())
+			if !ok {
+				cfg = def
+			}
+			res, err := store.Take(r.Context(), cfg.Scope+":"+clientKey(r), cfg.Limit, cfg.Window)
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("rate limit store failed; allowing request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				respond.Error(w, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests; retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the caller: the token subject when JWTAuth has run,
+// the remote IP otherwise. Proxy headers are not trusted.
+func clientKey(r *http.Request) string {
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return "user:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemStore(clock.Func(func() time.Time { return now }))
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(RateLimit(store, 2, time.Minute))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	rt.Handle("GET /v1/applications", ok)
+	rt.Handle("POST /v1/applications/{id}/documents", ok, router.Limit(ratelimit.Config{Scope: "uploads", Limit: 1, Window: time.Hour}))
+	rt.Handle("GET /health", ok, router.SkipAuth())
+
+	issuer := auth.NewIssuer("s3cret")
+	call := func(method, path, subject, ip string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":51234"
+		if subject != "" {
+			pair, err := issuer.Issue(subject, "student")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		}
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Authenticated callers are keyed by subject, whatever their IP.
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		rec := call("GET", "/v1/applications", "stu-1", ip)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: %d", i, rec.Code)
+		}
+		if got, want := rec.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(1-i); got != want {
+			t.Errorf("request %d: remaining = %s, want %s", i, got, want)
+		}
+	}
+	rec := call("GET", "/v1/applications", "stu-1", "10.0.0.3")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("X-RateLimit-Limit = %q", got)
+	}
+	if got, want := rec.Header().Get("X-RateLimit-Reset"), strconv.FormatInt(now.Add(time.Minute).Unix(), 10); got != want {
+		t.Errorf("X-RateLimit-Reset = %s, want %s", got, want)
+	}
+	if call("GET", "/v1/applications", "stu-2", "10.0.0.3").Code != http.StatusNoContent {
+		t.Error("another user shares stu-1's budget")
+	}
+
+	// The upload route has its own, tighter budget.
+	if rec := call("POST", "/v1/applications/a1/documents", "stu-1", "10.0.0.1"); rec.Code != http.StatusNoContent || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("first upload: %d limit=%s", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := call("POST", "/v1/applications/a1/documents", "stu-1", "10.0.0.1"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "3600" {
+		t.Errorf("second upload: %d Retry-After=%s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Public routes are keyed by IP.
+	call("GET", "/health", "", "10.0.0.9")
+	call("GET", "/health", "", "10.0.0.9")
+	if call("GET", "/health", "", "10.0.0.9").Code != http.StatusTooManyRequests {
+		t.Error("public route not limited by IP")
+	}
+	if call("GET", "/health", "", "10.0.0.8").Code != http.StatusNoContent {
+		t.Error("another IP shares the budget")
+	}
+}
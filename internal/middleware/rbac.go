@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+)
+
+// RequireRole rejects requests whose caller role is not among roles (when
+// any are given) or is not allowed by policy for the request's method and
+// path. It must run after JWTAuth.
+func RequireRole(policy *rbac.Policy, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing credentials")
+				return
+			}
+			if len(roles) > 0 && !slices.Contains(roles, claims.Role) {
+				respond.Error(w, http.StatusForbidden, "FORBIDDEN", "role is not permitted to access this resource")
+				return
+			}
+			// Clean the path so "/v1/students/../admin" is checked as the
+			// route the mux will actually dispatch to.
+			if !policy.Allowed(claims.Role, r.Method, path.Clean("/"+r.URL.Path)) {
+				respond.Error(w, http.StatusForbidden, "FORBIDDEN", "role is not permitted to access this resource")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
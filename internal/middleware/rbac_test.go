@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+)
+
+func TestRequireRolePrivilegeEscalation(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte(`
+roles:
+  student:
+    - GET /v1/applications/{id}
+  admin:
+    - "* /v1/*"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := auth.NewIssuer("s3cret")
+	token := func(role string) string {
+		pair, err := issuer.Issue("user-1", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pair.AccessToken
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	withPolicy := Chain(JWTAuth("s3cret"), RequireRole(policy))(ok)
+	adminOnly := Chain(JWTAuth("s3cret"), RequireRole(policy, rbac.RoleAdmin))(ok)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		role    string
+		method  string
+		path    string
+		want    int
+	}{
+		{"student reads own application", withPolicy, rbac.RoleStudent, "GET", "/v1/applications/7", http.StatusNoContent},
+		{"student hits admin endpoint", withPolicy, rbac.RoleStudent, "GET", "/v1/admin/users", http.StatusForbidden},
+		{"student deletes application", withPolicy, rbac.RoleStudent, "DELETE", "/v1/applications/7", http.StatusForbidden},
+		{"student traverses into admin path", withPolicy, rbac.RoleStudent, "GET", "/v1/applications/7/../../admin/users", http.StatusForbidden},
+		{"student forges admin-looking role", withPolicy, "Admin", "GET", "/v1/admin/users", http.StatusForbidden},
+		{"student on admin-only route", adminOnly, rbac.RoleStudent, "GET", "/v1/applications/7", http.StatusForbidden},
+		{"admin on admin-only route", adminOnly, rbac.RoleAdmin, "GET", "/v1/admin/users", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.URL.Path = tt.path
+			req.Header.Set("Authorization", "Bearer "+token(tt.role))
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireRoleWithoutClaims(t *testing.T) {
+	h := RequireRole(nil)(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/applications", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// TraceParentHeader is the W3C Trace Context header,
+// 00-{trace-id}-{parent-id}-{flags}.
+const TraceParentHeader = "traceparent"
+
+// RequestIDOption configures RequestID.
+type RequestIDOption func(*requestid.Options)
+
+// RejectClientRequestIDs ignores X-Request-ID on incoming requests, for
+// deployments whose clients are not trusted to name their own requests.
+func RejectClientRequestIDs() RequestIDOption {
+	return func(o *requestid.Options) { o.RejectClientIDs = true }
+}
+
+// RequestID gives each request an ID and a place in a trace, so its log
+// lines can be matched with those of the services it calls and that call
+// it. The ID is the client's X-Request-ID if well formed, otherwise a new
+// UUID; it is set in the response, stored for requestid.FromContext, and
+// added as request_id to the logger logging.FromContext returns. The trace
+// continues the caller's traceparent, or starts one, with a span ID of its
+// own for TraceParentFromContext. Wrap outgoing transports in Propagate to
+// pass both on.
+func RequestID(opts ...RequestIDOption) func(http.Handler) http.Handler {
+	o := requestid.Options{Generate: uuid.NewString}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ids := requestid.New(o)
+	return func(next http.Handler) http.Handler {
+		return ids(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if _, ok := TraceParentFromContext(ctx); !ok {
+				tp, err := ParseTraceParent(r.Header.Get(TraceParentHeader))
+				if err != nil {
+					tp = TraceParent{TraceID: newTraceID()}
+				}
+				tp.SpanID = newSpanID()
+				ctx = context.WithValue(ctx, traceParentKey{}, tp)
+			}
+			l := logging.FromContext(ctx).With("request_id", requestid.FromContext(ctx))
+			next.ServeHTTP(w, r.WithContext(logging.NewContext(ctx, l)))
+		}))
+	}
+}
+
+// TraceParent is a request's position in a W3C trace.
+type TraceParent struct {
+	TraceID trace.TraceID
+	// SpanID identifies this service's part of the request: the
+	// parent-id of the calls it makes. Parsed from a header, it is the
+	// caller's.
+	SpanID trace.SpanID
+	Flags  trace.TraceFlags
+}
+
+// String formats tp as a version 00 traceparent header.
+func (tp TraceParent) String() string {
+	return "00-" + tp.TraceID.String() + "-" + tp.SpanID.String() + "-" + tp.Flags.String()
+}
+
+var errTraceParent = errors.New("middleware: malformed traceparent")
+
+// ParseTraceParent reads a traceparent header. Versions after 00 are read
+// as 00, ignoring any fields they add; trace and parent IDs must be
+// lowercase hex and not all zeros.
+func ParseTraceParent(s string) (TraceParent, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !lowerHex(parts[0]) ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return TraceParent{}, errTraceParent
+	}
+	if !lowerHex(parts[1]) || !lowerHex(parts[2]) || len(parts[3]) != 2 || !lowerHex(parts[3]) {
+		return TraceParent{}, errTraceParent
+	}
+	tid, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return TraceParent{}, errTraceParent
+	}
+	sid, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return TraceParent{}, errTraceParent
+	}
+	flags := trace.TraceFlags(fromHex(parts[3][0])<<4 | fromHex(parts[3][1]))
+	return TraceParent{TraceID: tid, SpanID: sid, Flags: flags & trace.FlagsSampled}, nil
+}
+
+func lowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func fromHex(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
+
+type traceParentKey struct{}
+
+// TraceParentFromContext returns the request's trace position, set by
+// RequestID. Inside an OpenTelemetry span started here (see Trace), that
+// span is the request's part of the trace instead.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && !sc.IsRemote() {
+		return TraceParent{TraceID: sc.TraceID(), SpanID: sc.SpanID(), Flags: sc.TraceFlags()}, true
+	}
+	tp, ok := ctx.Value(traceParentKey{}).(TraceParent)
+	return tp, ok
+}
+
+func newTraceID() trace.TraceID {
+	var id trace.TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() trace.SpanID {
+	var id trace.SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// Propagate returns a transport that sends the X-Request-ID and
+// traceparent of each request's context on to the service it calls,
+// such as S3 or an identity provider, unless the request sets them
+// itself. A nil base means http.DefaultTransport.
+func Propagate(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return propagator{base}
+}
+
+type propagator struct {
+	base http.RoundTripper
+}
+
+func (p propagator) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	id := requestid.FromContext(ctx)
+	tp, traced := TraceParentFromContext(ctx)
+	setID := id != "" && req.Header.Get(RequestIDHeader) == ""
+	setTrace := traced && req.Header.Get(TraceParentHeader) == ""
+	if !setID && !setTrace {
+		return p.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(ctx)
+	if setID {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	if setTrace {
+		req.Header.Set(TraceParentHeader, tp.String())
+	}
+	return p.base.RoundTrip(req)
+}
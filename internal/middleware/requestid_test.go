@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var got TraceParent
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = TraceParentFromContext(r.Context())
+		logging.FromContext(r.Context()).Info("handled")
+	}))
+
+	// A caller's ID and trace are continued.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	req.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if id := rec.Header().Get(RequestIDHeader); id != "req-42" {
+		t.Errorf("response request ID = %q, want the client's", id)
+	}
+	if got.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !got.Flags.IsSampled() {
+		t.Errorf("trace = %s, want the caller's", got)
+	}
+	if !got.SpanID.IsValid() || got.SpanID.String() == "00f067aa0ba902b7" {
+		t.Errorf("span ID = %s, want a new one", got.SpanID)
+	}
+	if line := decodeLines(t, &buf)[0]; line["request_id"] != "req-42" {
+		t.Errorf("log line = %v, want request_id", line)
+	}
+
+	// Without them, both are generated.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	if u, err := uuid.Parse(id); err != nil || u.Version() != 4 {
+		t.Errorf("generated request ID %q is not a UUID v4", id)
+	}
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-00$`).MatchString(got.String()) || !got.TraceID.IsValid() {
+		t.Errorf("generated traceparent = %s", got)
+	}
+	if line := decodeLines(t, &buf)[0]; line["request_id"] != id {
+		t.Errorf("log line = %v, want request_id %s", line, id)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	RequestID(RejectClientRequestIDs())(h).ServeHTTP(rec, req)
+	if id := rec.Header().Get(RequestIDHeader); id == "req-42" {
+		t.Error("client request ID kept despite RejectClientRequestIDs")
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	for _, s := range []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+	} {
+		tp, err := ParseTraceParent(s)
+		if err != nil || tp.String() != "00"+s[2:55] {
+			t.Errorf("ParseTraceParent(%q) = %s, %v", s, tp, err)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	} {
+		if tp, err := ParseTraceParent(s); err == nil {
+			t.Errorf("ParseTraceParent(%q) = %s, want an error", s, tp)
+		}
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	var seen http.Header
+	var ids []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Clone()
+		ids = append(ids, r.Header.Get(RequestIDHeader))
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: Propagate(nil)}
+
+	var tp TraceParent
+	var requestID string
+	RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tp, _ = TraceParentFromContext(r.Context())
+		requestID = w.Header().Get(RequestIDHeader)
+		out, _ := http.NewRequestWithContext(r.Context(), "GET", upstream.URL, nil)
+		resp, err := client.Do(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if out.Header.Get(RequestIDHeader) != "" {
+			t.Error("Propagate modified the caller's request")
+		}
+
+		out, _ = http.NewRequestWithContext(r.Context(), "GET", upstream.URL, nil)
+		out.Header.Set(RequestIDHeader, "own-id")
+		resp, err = client.Do(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(ids) != 2 || ids[0] != requestID || ids[1] != "own-id" {
+		t.Errorf("upstream request IDs = %q, want the request's (%s) then the call's own", ids, requestID)
+	}
+	if seen.Get(TraceParentHeader) != tp.String() {
+		t.Errorf("upstream traceparent = %q, want %s", seen.Get(TraceParentHeader), tp)
+	}
+
+	// Outside a request there is nothing to send.
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if seen.Get(RequestIDHeader) != "" || seen.Get(TraceParentHeader) != "" {
+		t.Errorf("upstream headers outside a request = %v", seen)
+	}
+}
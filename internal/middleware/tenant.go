@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// TenantHeader names the tenant of a request reaching the API at a host
+// shared by several, such as the gateway's or a cluster-internal one. It
+// is only honored from TrustTenantHeader networks.
+const TenantHeader = "X-Tenant-ID"
+
+// TenantOption configures TenantResolver.
+type TenantOption func(*tenantResolver)
+
+// FallbackTenant serves requests to a host no tenant claims, and without
+// TenantHeader, for the tenant with id; a single-tenant deployment uses
+// it with tenant.DefaultID so that every host reaches it.
+func FallbackTenant(id string) TenantOption {
+	return func(r *tenantResolver) { r.fallback = id }
+}
+
+// TrustTenantHeader honors TenantHeader on requests whose RemoteAddr is
+// inside one of trusted, such as the gateway's; from anywhere else it is
+// ignored and the tenant comes from the Host. Like AllowNetworks it
+// trusts only RemoteAddr.
+func TrustTenantHeader(trusted []netip.Prefix) TenantOption {
+	return func(r *tenantResolver) { r.trusted = trusted }
+}
+
+type tenantResolver struct {
+	tenants  tenant.Store
+	fallback string
+	trusted  []netip.Prefix
+}
+
+// TenantResolver scopes each request to a tenant: the one TenantHeader
+// names, from a trusted network, or else the one whose domain is the
+// request's Host, port aside. The tenant is stored for
+// tenant.FromContext, where the stores read it, and added as tenant_id to
+// the logger logging.FromContext returns. A request it cannot place
+// answers 400 UNKNOWN_TENANT, and one whose token was issued for another
+// tenant (auth.Claims.Tenant) 403 TENANT_MISMATCH, so a user of one
+// tenant never reads another's rows. It runs after JWTAuth, which sets
+// the claims it checks.
+func TenantResolver(tenants tenant.Store, opts ...TenantOption) func(http.Handler) http.Handler {
+	tr := &tenantResolver{tenants: tenants}
+	for _, opt := range opts {
+		opt(tr)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, err := tr.resolve(r.Context(), r)
+			if errors.Is(err, tenant.ErrUnknown) {
+				respond.Error(w, http.StatusBadRequest, "UNKNOWN_TENANT", "no tenant is served at this host")
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Error("resolve tenant", "error", err)
+				respond.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "could not resolve the tenant")
+				return
+			}
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Tenant() != t.ID {
+				respond.Error(w, http.StatusForbidden, "TENANT_MISMATCH", "the token was issued for another tenant")
+				return
+			}
+			ctx := tenant.NewContext(r.Context(), t)
+			ctx = logging.NewContext(ctx, logging.FromContext(ctx).With("tenant_id", t.ID))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// trustsHeader reports whether r comes from a TrustTenantHeader network.
+// Unlike AllowNetworks it does not trust loopback on its own, since a
+// proxy on the same host forwards outside callers' headers.
+func (tr *tenantResolver) trustsHeader(r *http.Request) bool {
+	addr, ok := remoteAddr(r.RemoteAddr)
+	return ok && inNetworks(addr, tr.trusted)
+}
+
+func (tr *tenantResolver) resolve(ctx context.Context, r *http.Request) (*tenant.Tenant, error) {
+	if id := r.Header.Get(TenantHeader); id != "" && tr.trustsHeader(r) {
+		return tr.tenants.Get(ctx, id)
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	t, err := tr.tenants.ByDomain(ctx, host)
+	if errors.Is(err, tenant.ErrUnknown) && tr.fallback != "" {
+		return tr.tenants.Get(ctx, tr.fallback)
+	}
+	return t, err
+}
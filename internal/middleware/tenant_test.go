@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestTenantResolver(t *testing.T) {
+	tenants := tenant.NewMemoryStore(
+		tenant.Default,
+		tenant.Tenant{ID: "north", Name: "North University", Domain: "apply.north.edu"},
+		tenant.Tenant{ID: "south", Name: "South College"},
+	)
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = tenant.ID(r.Context())
+	})
+	// httptest requests come from 192.0.2.1.
+	gateway := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+	for _, tc := range []struct {
+		name, host, header string
+		fallback, trusted  bool
+		want               string // "" expects 400
+	}{
+		{"domain", "apply.north.edu", "", false, false, "north"},
+		{"domain with port and case", "Apply.North.edu:8443", "", false, false, "north"},
+		{"header", "api.internal", "south", false, true, "south"},
+		{"header wins over domain", "apply.north.edu", "south", false, true, "south"},
+		{"untrusted header ignored", "apply.north.edu", "south", false, false, "north"},
+		{"untrusted header, unknown host", "api.internal", "south", false, false, ""},
+		{"unknown host", "apply.west.edu", "", false, false, ""},
+		{"unknown host, fallback", "apply.west.edu", "", true, false, tenant.DefaultID},
+		{"unknown header, fallback", "apply.north.edu", "west", true, true, ""},
+	} {
+		var opts []TenantOption
+		if tc.fallback {
+			opts = append(opts, FallbackTenant(tenant.DefaultID))
+		}
+		if tc.trusted {
+			opts = append(opts, TrustTenantHeader(gateway))
+		}
+		got = ""
+		req := httptest.NewRequest("GET", "/v1/applications", nil)
+		req.Host = tc.host
+		if tc.header != "" {
+			req.Header.Set(TenantHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		TenantResolver(tenants, opts...)(next).ServeHTTP(rec, req)
+		switch {
+		case tc.want == "" && (rec.Code != http.StatusBadRequest || got != ""):
+			t.Errorf("%s: %d, tenant %q, want 400", tc.name, rec.Code, got)
+		case tc.want != "" && got != tc.want:
+			t.Errorf("%s: %d, tenant %q, want %q", tc.name, rec.Code, got, tc.want)
+		}
+	}
+}
+
+func TestTenantResolverChecksTokenTenant(t *testing.T) {
+	tenants := tenant.NewMemoryStore(
+		tenant.Default,
+		tenant.Tenant{ID: "north", Name: "North University", Domain: "apply.north.edu"},
+		tenant.Tenant{ID: "south", Name: "South College", Domain: "apply.south.edu"},
+	)
+	h := TenantResolver(tenants, TrustTenantHeader([]netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}))(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, tc := range []struct {
+		name, claim, host, header string
+		want                      int
+	}{
+		{"own tenant", "north", "apply.north.edu", "", http.StatusOK},
+		{"other tenant's host", "north", "apply.south.edu", "", http.StatusForbidden},
+		{"other tenant by header", "north", "apply.north.edu", "south", http.StatusForbidden},
+		{"no claim is the default tenant", "", "apply.north.edu", "", http.StatusForbidden},
+		{"no claim at the default tenant", "", "api.internal", tenant.DefaultID, http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/v1/applications", nil)
+		req.Host = tc.host
+		if tc.header != "" {
+			req.Header.Set(TenantHeader, tc.header)
+		}
+		claims := &auth.Claims{Role: "student", TenantID: tc.claim}
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyClaims, claims))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+// Trace starts a server span per request with tracer, continuing the
+// caller's trace when the request carries a traceparent header, and puts
+// it in the request context for the store, search, and upload spans
+// below it. Like Instrument it wraps the whole Router and names each span
+// after the matched route pattern, e.g. "GET /v1/applications/{id}".
+// Responses of 500 and above mark the span as failed.
+func Trace(tracer trace.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, pattern := router.CapturePattern(ctx)
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+			)
+			defer span.End()
+
+			sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			route := patternPath(pattern())
+			span.SetName(r.Method + " " + route)
+			if route != unmatchedPath {
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/router"
+)
+
+func TestTrace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	var logs bytes.Buffer
+	rt := router.New(JWTAuth("s3cret"))
+	rt.Use(RequestLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+	var handlerSpan trace.SpanContext
+	rt.HandleFunc("GET /version/{part}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		if r.PathValue("part") == "boom" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}, router.SkipAuth())
+	rec := metrics.NewRecorder(nil)
+	h := Trace(tracer)(Instrument(rec)(rt))
+
+	req := httptest.NewRequest("GET", "/version/app-1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/version/boom", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+	spans := sr.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	ok, boom, nope := spans[0], spans[1], spans[2]
+	if ok.Name() != "GET /version/{part}" || ok.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q %v", ok.Name(), ok.SpanKind())
+	}
+	if got := ok.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the caller's", got)
+	}
+	if ok.Parent().SpanID().String() != "00f067aa0ba902b7" || !ok.Parent().IsRemote() {
+		t.Errorf("parent = %v, want the remote caller span", ok.Parent())
+	}
+	if handlerSpan.SpanID() != boom.SpanContext().SpanID() {
+		t.Error("handler context does not carry the request span")
+	}
+	if boom.Status().Code != codes.Error || ok.Status().Code == codes.Error {
+		t.Errorf("statuses: ok %v, boom %v", ok.Status(), boom.Status())
+	}
+	if nope.Name() != "GET "+unmatchedPath {
+		t.Errorf("unmatched span name = %q", nope.Name())
+	}
+	attrs := map[string]string{}
+	for _, kv := range ok.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/version/{part}" || attrs["http.response.status_code"] != "200" || attrs["url.path"] != "/version/app-1" {
+		t.Errorf("attributes = %v", attrs)
+	}
+
+	// Instrument shares the route pattern Trace captured.
+	if got := testutil.ToFloat64(rec.Requests.WithLabelValues("GET", "/version/{part}", "500")); got != 1 {
+		t.Errorf("metrics under Trace: %v", got)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(bytes.SplitN(logs.Bytes(), []byte("\n"), 2)[0], &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request log trace_id = %v", line["trace_id"])
+	}
+}
+
+func TestTraceNoop(t *testing.T) {
+	h := Trace(noop.NewTracerProvider().Tracer(""))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,334 @@
+// Package migrations versions the admissions-api schema in Postgres. The
+// SQL files under migrations/ are embedded in the binary and applied in
+// name order by Runner, which records each one in schema_migrations.
+//
+// A file is named YYYYMMDDHHMMSS_description.sql and holds its up and
+// down steps under marker comments:
+//
+//	-- +migrate Up
+//	CREATE TABLE ...;
+//
+//	-- +migrate Down
+//	DROP TABLE ...;
+//
+// The tables admissions-api reads were first created by the Prisma
+// migrations under prisma/migrations; the baseline here repeats them with
+// IF NOT EXISTS, so it is a no-op on a database Prisma has already
+// migrated and creates them on a fresh one.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// Markers separating a file's up and down steps.
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+// lockKey is the pg_advisory_lock key held while migrating, an arbitrary
+// constant shared by every admissions-api binary. Replicas starting
+// together then apply each migration once: the first takes the lock and
+// the rest wait, then find nothing pending.
+const lockKey int64 = 7_310_446_811_702_130_001
+
+var fileNameRE = regexp.MustCompile(`^([0-9]{14})_([a-z0-9_]+)\.sql$`)
+
+// Migration is one parsed migration file.
+type Migration struct {
+	// Version is the file's YYYYMMDDHHMMSS prefix.
+	Version string
+	// Name is the description after it.
+	Name string
+	Up   string
+	Down string
+}
+
+// Parse reads the *.sql files at the root of fsys, in version order. Names
+// must match YYYYMMDDHHMMSS_description.sql with a valid timestamp, every
+// file needs an up step, and versions must be unique.
+func Parse(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out  []Migration
+		errs []error
+		seen = map[string]string{}
+	)
+	for _, name := range names {
+		m, err := parseFile(fsys, name)
+		if err == nil && seen[m.Version] != "" {
+			err = fmt.Errorf("version %s is also used by %s", m.Version, seen[m.Version])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("migrations: %s: %w", name, err))
+			continue
+		}
+		seen[m.Version] = name
+		out = append(out, m)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Version < out[b].Version })
+	return out, nil
+}
+
+func parseFile(fsys fs.FS, name string) (Migration, error) {
+	m := fileNameRE.FindStringSubmatch(name)
+	if m == nil {
+		return Migration{}, errors.New("want a name such as 20260102150405_add_table.sql")
+	}
+	if _, err := time.Parse("20060102150405", m[1]); err != nil {
+		return Migration{}, fmt.Errorf("version %s is not a timestamp", m[1])
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Migration{}, err
+	}
+	text := string(data)
+	upAt := strings.Index(text, upMarker)
+	if upAt < 0 {
+		return Migration{}, fmt.Errorf("no %q section", upMarker)
+	}
+	up, down := text[upAt+len(upMarker):], ""
+	if downAt := strings.Index(up, downMarker); downAt >= 0 {
+		up, down = up[:downAt], up[downAt+len(downMarker):]
+	}
+	if strings.TrimSpace(up) == "" {
+		return Migration{}, errors.New("empty up step")
+	}
+	return Migration{Version: m[1], Name: m[2], Up: strings.TrimSpace(up), Down: strings.TrimSpace(down)}, nil
+}
+
+// Runner applies and rolls back migrations.
+type Runner struct {
+	Migrations []Migration
+	// Logger receives one line per applied or rolled-back migration; nil
+	// means slog.Default().
+	Logger *slog.Logger
+	// DryRun makes Up log and count the pending migrations without
+	// running them. It still creates an empty schema_migrations table
+	// when there is none.
+	DryRun bool
+}
+
+// NewRunner returns a Runner for the migrations in fsys, or for the
+// embedded ones when fsys is nil.
+func NewRunner(fsys fs.FS) (*Runner, error) {
+	if fsys == nil {
+		sub, err := fs.Sub(embedded, "migrations")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+	ms, err := Parse(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{Migrations: ms}, nil
+}
+
+// Status reports one migration and whether it has been applied.
+type Status struct {
+	Migration
+	AppliedAt time.Time // zero if pending
+}
+
+// Up applies the pending migrations in fsys, or the embedded ones when
+// fsys is nil, and returns how many it applied. See Runner.Up.
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS) (applied int, err error) {
+	r, err := NewRunner(fsys)
+	if err != nil {
+		return 0, err
+	}
+	return r.Up(ctx, db)
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction with its schema_migrations row, and returns how many it
+// applied. It stops at the first failure, leaving the earlier ones
+// applied and counted. With DryRun, the count is of the migrations it
+// would apply.
+func (r *Runner) Up(ctx context.Context, db *sql.DB) (applied int, err error) {
+	err = r.locked(ctx, db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if r.DryRun {
+				r.logger().Info("migration pending", "version", m.Version, "name", m.Name)
+				applied++
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, insertVersion, m.Version, m.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: apply %s_%s: %w", m.Version, m.Name, err)
+			}
+			r.logger().Info("migration applied", "version", m.Version, "name", m.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migration, one step. It is a
+// no-op when nothing is applied and fails for a migration without a down
+// step or one this binary does not know.
+func (r *Runner) Down(ctx context.Context, db *sql.DB) error {
+	return r.locked(ctx, db, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return nil
+		}
+		latest := ""
+		for v := range applied {
+			latest = max(latest, v)
+		}
+		i := sort.Search(len(r.Migrations), func(i int) bool { return r.Migrations[i].Version >= latest })
+		if i == len(r.Migrations) || r.Migrations[i].Version != latest {
+			return fmt.Errorf("migrations: applied version %s is not in this binary", latest)
+		}
+		m := r.Migrations[i]
+		if m.Down == "" {
+			return fmt.Errorf("migrations: %s_%s has no down step", m.Version, m.Name)
+		}
+		err = inTx(ctx, conn, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, deleteVersion, m.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migrations: roll back %s_%s: %w", m.Version, m.Name, err)
+		}
+		r.logger().Info("migration rolled back", "version", m.Version, "name", m.Name)
+		return nil
+	})
+}
+
+// Status lists every known migration with its applied time.
+func (r *Runner) Status(ctx context.Context, db *sql.DB) ([]Status, error) {
+	var out []Status
+	err := r.locked(ctx, db, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Migrations {
+			out = append(out, Status{Migration: m, AppliedAt: applied[m.Version]})
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *Runner) logger() *slog.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return slog.Default()
+}
+
+const (
+	createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+	selectVersions = `SELECT version, applied_at FROM schema_migrations`
+	insertVersion  = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	deleteVersion  = `DELETE FROM schema_migrations WHERE version = $1`
+	lock           = `SELECT pg_advisory_lock($1)`
+	unlock         = `SELECT pg_advisory_unlock($1)`
+)
+
+// locked runs fn on one connection holding the migration lock, after
+// making sure schema_migrations exists. Session-level advisory locks
+// belong to a connection, so everything runs on conn rather than the
+// pool.
+func (r *Runner) locked(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, lock, lockKey); err != nil {
+		return fmt.Errorf("migrations: lock: %w", err)
+	}
+	defer func() {
+		// A fresh context: the lock must be released even when ctx
+		// is what ended the run.
+		if _, err := conn.ExecContext(context.Background(), unlock, lockKey); err != nil {
+			r.logger().Warn("migration unlock failed", "error", err)
+		}
+	}()
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("migrations: create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[string]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, selectVersions)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := map[string]time.Time{}
+	for rows.Next() {
+		var (
+			v  string
+			at time.Time
+		)
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("migrations: read schema_migrations: %w", err)
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(*sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+-- The tables admissions-api reads, as created by the Prisma migrations
+-- 20260312173000_repo_prisma_baseline, 20261014090000_application_deadlines,
+-- and 20261014120000_applicant_search. IF NOT EXISTS makes this a no-op on a
+-- database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS student_applications (
+    id TEXT NOT NULL,
+    applicant_id TEXT NOT NULL,
+    program_code TEXT NOT NULL,
+    round TEXT,
+    status TEXT NOT NULL,
+    submitted_at TIMESTAMP(3) NOT NULL,
+    updated_at TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT student_applications_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_student_applications_applicant_id ON student_applications (applicant_id);
+CREATE INDEX IF NOT EXISTS idx_student_applications_program_status ON student_applications (program_code, status);
+
+CREATE TABLE IF NOT EXISTS application_deadlines (
+    program_code TEXT NOT NULL,
+    round TEXT NOT NULL,
+    closes_at TIMESTAMP(3) NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT application_deadlines_pkey PRIMARY KEY (program_code, round)
+);
+
+CREATE TABLE IF NOT EXISTS applicant_profiles (
+    id TEXT NOT NULL,
+    full_name TEXT NOT NULL,
+    email TEXT,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT applicant_profiles_pkey PRIMARY KEY (id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS applicant_profiles;
+DROP TABLE IF EXISTS application_deadlines;
+DROP TABLE IF EXISTS student_applications;
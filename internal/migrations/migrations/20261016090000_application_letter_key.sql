@@ -0,0 +1,9 @@
+-- The archived decision letter of an application, as added by the Prisma
+-- migration 20261016090000_application_letter_key. IF NOT EXISTS makes this
+-- a no-op on a database Prisma has already migrated.
+
+-- +migrate Up
+ALTER TABLE student_applications ADD COLUMN IF NOT EXISTS letter_key TEXT;
+
+-- +migrate Down
+ALTER TABLE student_applications DROP COLUMN IF EXISTS letter_key;
@@ -0,0 +1,9 @@
+-- When an application was soft-deleted, as added by the Prisma migration
+-- 20261017090000_application_soft_delete. IF NOT EXISTS makes this a no-op
+-- on a database Prisma has already migrated.
+
+-- +migrate Up
+ALTER TABLE student_applications ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(3);
+
+-- +migrate Down
+ALTER TABLE student_applications DROP COLUMN IF EXISTS deleted_at;
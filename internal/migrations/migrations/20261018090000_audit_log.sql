@@ -0,0 +1,21 @@
+-- The audit log of application and document mutations, as created by the
+-- Prisma migration 20261018090000_audit_log. IF NOT EXISTS makes this a
+-- no-op on a database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL NOT NULL,
+    occurred_at TIMESTAMP(3) NOT NULL,
+    actor_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    diff JSONB NOT NULL,
+
+    CONSTRAINT audit_log_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity_id_occurred_at ON audit_log (entity_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log (occurred_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,86 @@
+-- Tenants, and the tenant_id that scopes every admissions table to one,
+-- as added by the Prisma migration 20261019090000_tenants. Existing rows
+-- belong to the default tenant; new ones to the tenant their transaction
+-- is scoped to (see store.Scoped), which row level security also limits
+-- every read to. IF NOT EXISTS and DROP POLICY IF EXISTS make this a no-op
+-- on a database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    domain TEXT,
+    config JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT tenants_pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS tenants_domain_key ON tenants (domain);
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE student_applications ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE student_applications ALTER COLUMN tenant_id SET DEFAULT current_setting('app.current_tenant');
+CREATE INDEX IF NOT EXISTS idx_student_applications_tenant_id ON student_applications (tenant_id);
+ALTER TABLE student_applications DROP CONSTRAINT IF EXISTS student_applications_tenant_id_fkey;
+ALTER TABLE student_applications ADD CONSTRAINT student_applications_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE student_applications ENABLE ROW LEVEL SECURITY;
+ALTER TABLE student_applications FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON student_applications;
+CREATE POLICY tenant_isolation ON student_applications
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+ALTER TABLE application_deadlines ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE application_deadlines ALTER COLUMN tenant_id SET DEFAULT current_setting('app.current_tenant');
+CREATE INDEX IF NOT EXISTS idx_application_deadlines_tenant_id ON application_deadlines (tenant_id);
+ALTER TABLE application_deadlines DROP CONSTRAINT IF EXISTS application_deadlines_pkey;
+ALTER TABLE application_deadlines ADD CONSTRAINT application_deadlines_pkey PRIMARY KEY (tenant_id, program_code, round);
+ALTER TABLE application_deadlines DROP CONSTRAINT IF EXISTS application_deadlines_tenant_id_fkey;
+ALTER TABLE application_deadlines ADD CONSTRAINT application_deadlines_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE application_deadlines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE application_deadlines FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON application_deadlines;
+CREATE POLICY tenant_isolation ON application_deadlines
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+ALTER TABLE applicant_profiles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE applicant_profiles ALTER COLUMN tenant_id SET DEFAULT current_setting('app.current_tenant');
+CREATE INDEX IF NOT EXISTS idx_applicant_profiles_tenant_id ON applicant_profiles (tenant_id);
+ALTER TABLE applicant_profiles DROP CONSTRAINT IF EXISTS applicant_profiles_tenant_id_fkey;
+ALTER TABLE applicant_profiles ADD CONSTRAINT applicant_profiles_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE applicant_profiles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE applicant_profiles FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON applicant_profiles;
+CREATE POLICY tenant_isolation ON applicant_profiles
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE audit_log ALTER COLUMN tenant_id SET DEFAULT current_setting('app.current_tenant');
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log (tenant_id);
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_tenant_id_fkey;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_log;
+CREATE POLICY tenant_isolation ON audit_log
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP POLICY IF EXISTS tenant_isolation ON audit_log;
+ALTER TABLE audit_log NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_log DISABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS tenant_id;
+DROP POLICY IF EXISTS tenant_isolation ON applicant_profiles;
+ALTER TABLE applicant_profiles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE applicant_profiles DISABLE ROW LEVEL SECURITY;
+ALTER TABLE applicant_profiles DROP COLUMN IF EXISTS tenant_id;
+DROP POLICY IF EXISTS tenant_isolation ON application_deadlines;
+ALTER TABLE application_deadlines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE application_deadlines DISABLE ROW LEVEL SECURITY;
+ALTER TABLE application_deadlines DROP CONSTRAINT IF EXISTS application_deadlines_pkey;
+ALTER TABLE application_deadlines DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE application_deadlines ADD CONSTRAINT application_deadlines_pkey PRIMARY KEY (program_code, round);
+DROP POLICY IF EXISTS tenant_isolation ON student_applications;
+ALTER TABLE student_applications NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE student_applications DISABLE ROW LEVEL SECURITY;
+ALTER TABLE student_applications DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,30 @@
+-- The versioned program catalog, one row per version of a program, as
+-- created by the Prisma migration 20261020090000_program_versions. IF NOT
+-- EXISTS and DROP POLICY IF EXISTS make this a no-op on a database Prisma
+-- has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS program_versions (
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+    code TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    faculty TEXT NOT NULL,
+    required_documents JSONB NOT NULL DEFAULT '[]',
+    capacity INTEGER NOT NULL,
+    deadlines JSONB NOT NULL DEFAULT '{}',
+    valid_from TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT program_versions_pkey PRIMARY KEY (tenant_id, code, version)
+);
+CREATE INDEX IF NOT EXISTS idx_program_versions_code_valid_from ON program_versions (code, valid_from);
+ALTER TABLE program_versions DROP CONSTRAINT IF EXISTS program_versions_tenant_id_fkey;
+ALTER TABLE program_versions ADD CONSTRAINT program_versions_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE program_versions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE program_versions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON program_versions;
+CREATE POLICY tenant_isolation ON program_versions
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP TABLE IF EXISTS program_versions;
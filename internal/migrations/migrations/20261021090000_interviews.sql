@@ -0,0 +1,50 @@
+-- Interview slots and their bookings, as created by the Prisma migration
+-- 20261021090000_interviews. IF NOT EXISTS and DROP POLICY IF EXISTS make
+-- this a no-op on a database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS interview_slots (
+    id TEXT NOT NULL,
+    program_code TEXT NOT NULL,
+    start_at TIMESTAMP(3) NOT NULL,
+    end_at TIMESTAMP(3) NOT NULL,
+    advisor_id TEXT NOT NULL,
+    max_candidates INTEGER NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT interview_slots_pkey PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS interview_bookings (
+    id TEXT NOT NULL,
+    slot_id TEXT NOT NULL,
+    application_id TEXT NOT NULL,
+    program_code TEXT NOT NULL,
+    booked_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT interview_bookings_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_interview_slots_tenant_id_program_code_start_at ON interview_slots (tenant_id, program_code, start_at);
+CREATE UNIQUE INDEX IF NOT EXISTS interview_bookings_tenant_id_application_id_program_code_key ON interview_bookings (tenant_id, application_id, program_code);
+CREATE INDEX IF NOT EXISTS idx_interview_bookings_slot_id ON interview_bookings (slot_id);
+ALTER TABLE interview_slots DROP CONSTRAINT IF EXISTS interview_slots_tenant_id_fkey;
+ALTER TABLE interview_slots ADD CONSTRAINT interview_slots_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE interview_bookings DROP CONSTRAINT IF EXISTS interview_bookings_slot_id_fkey;
+ALTER TABLE interview_bookings ADD CONSTRAINT interview_bookings_slot_id_fkey FOREIGN KEY (slot_id) REFERENCES interview_slots (id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE interview_bookings DROP CONSTRAINT IF EXISTS interview_bookings_tenant_id_fkey;
+ALTER TABLE interview_bookings ADD CONSTRAINT interview_bookings_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE interview_slots ENABLE ROW LEVEL SECURITY;
+ALTER TABLE interview_slots FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON interview_slots;
+CREATE POLICY tenant_isolation ON interview_slots
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+ALTER TABLE interview_bookings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE interview_bookings FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON interview_bookings;
+CREATE POLICY tenant_isolation ON interview_bookings
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP TABLE IF EXISTS interview_bookings;
+DROP TABLE IF EXISTS interview_slots;
@@ -0,0 +1,29 @@
+-- The metadata of documents attached to applications, as created by the
+-- Prisma migration 20261022090000_documents. IF NOT EXISTS and DROP POLICY
+-- IF EXISTS make this a no-op on a database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS documents (
+    id TEXT NOT NULL,
+    application_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    uploaded_at TIMESTAMP(3) NOT NULL,
+    deleted_at TIMESTAMP(3),
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT documents_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_documents_application_id_uploaded_at ON documents (application_id, uploaded_at);
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_tenant_id_fkey;
+ALTER TABLE documents ADD CONSTRAINT documents_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE documents ENABLE ROW LEVEL SECURITY;
+ALTER TABLE documents FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON documents;
+CREATE POLICY tenant_isolation ON documents
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP TABLE IF EXISTS documents;
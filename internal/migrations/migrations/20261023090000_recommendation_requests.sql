@@ -0,0 +1,34 @@
+-- The letters applicants ask referees for, as created by the Prisma
+-- migration 20261023090000_recommendation_requests. IF NOT EXISTS and DROP
+-- POLICY IF EXISTS make this a no-op on a database Prisma has already
+-- migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS recommendation_requests (
+    id TEXT NOT NULL,
+    application_id TEXT NOT NULL,
+    referee_email TEXT NOT NULL,
+    token TEXT NOT NULL,
+    status TEXT NOT NULL,
+    expires_at TIMESTAMP(3) NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL,
+    sent_at TIMESTAMP(3) NOT NULL,
+    submitted_at TIMESTAMP(3),
+    document_id TEXT,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT recommendation_requests_pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS recommendation_requests_token_key ON recommendation_requests (token);
+CREATE INDEX IF NOT EXISTS idx_recommendation_requests_application_id_created_at ON recommendation_requests (application_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_recommendation_requests_status_expires_at ON recommendation_requests (status, expires_at);
+ALTER TABLE recommendation_requests DROP CONSTRAINT IF EXISTS recommendation_requests_tenant_id_fkey;
+ALTER TABLE recommendation_requests ADD CONSTRAINT recommendation_requests_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE recommendation_requests ENABLE ROW LEVEL SECURITY;
+ALTER TABLE recommendation_requests FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON recommendation_requests;
+CREATE POLICY tenant_isolation ON recommendation_requests
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP TABLE IF EXISTS recommendation_requests;
@@ -0,0 +1,29 @@
+-- The users who sign in through the identity provider, as created by the
+-- Prisma migration 20261024090000_users. IF NOT EXISTS and DROP POLICY IF
+-- EXISTS make this a no-op on a database Prisma has already migrated.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    name TEXT,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL,
+    last_login_at TIMESTAMP(3) NOT NULL,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT users_pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_id_issuer_subject_key ON users (tenant_id, issuer, subject);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_fkey;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON users;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+
+-- +migrate Down
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,320 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakePostgres is just enough of Postgres for Runner: an advisory lock, a
+// schema_migrations table with transactional writes, and a log of the
+// migration statements executed.
+type fakePostgres struct {
+	lockMu sync.Mutex // held between pg_advisory_lock and _unlock
+
+	mu       sync.Mutex
+	versions map[string]time.Time
+	executed []string
+	failOn   string // a statement containing this fails
+}
+
+func newFakePostgres() *fakePostgres {
+	return &fakePostgres{versions: map[string]time.Time{}}
+}
+
+func (p *fakePostgres) Connect(context.Context) (driver.Conn, error) { return &fakeConn{p: p}, nil }
+func (p *fakePostgres) Driver() driver.Driver                        { return nil }
+
+func (p *fakePostgres) applied() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []string
+	for v := range p.versions {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type fakeConn struct {
+	p  *fakePostgres
+	tx []func() // writes pending until Commit
+	in bool
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { c.in, c.tx = true, nil; return c, nil }
+
+func (c *fakeConn) Commit() error {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	for _, f := range c.tx {
+		f()
+	}
+	c.in, c.tx = false, nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.in, c.tx = false, nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch query {
+	case lock:
+		c.p.lockMu.Lock()
+		return driver.RowsAffected(0), nil
+	case unlock:
+		c.p.lockMu.Unlock()
+		return driver.RowsAffected(0), nil
+	case createTable:
+		return driver.RowsAffected(0), nil
+	}
+	if !c.in {
+		return nil, errors.New("migration statement outside a transaction")
+	}
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	if c.p.failOn != "" && strings.Contains(query, c.p.failOn) {
+		return nil, errors.New("syntax error")
+	}
+	switch query {
+	case insertVersion:
+		v := args[0].Value.(string)
+		c.tx = append(c.tx, func() { c.p.versions[v] = time.Now() })
+	case deleteVersion:
+		v := args[0].Value.(string)
+		c.tx = append(c.tx, func() { delete(c.p.versions, v) })
+	default:
+		c.tx = append(c.tx, func() { c.p.executed = append(c.p.executed, query) })
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query != selectVersions {
+		return nil, errors.New("unexpected query " + query)
+	}
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	rows := &fakeRows{}
+	for v, at := range c.p.versions {
+		rows.rows = append(rows.rows, []driver.Value{v, at})
+	}
+	return rows, nil
+}
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"version", "applied_at"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"20260102000000_second.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE b ();\n-- +migrate Down\nDROP TABLE b;\n")},
+		"20260101000000_first.sql":  {Data: []byte("-- notes\n-- +migrate Up\nCREATE TABLE a ();\n\n-- +migrate Down\nDROP TABLE a;\n")},
+		"20260103000000_third.sql":  {Data: []byte("-- +migrate Up\nCREATE TABLE c ();\n")},
+		"README.md":                 {Data: []byte("not a migration")},
+	}
+}
+
+func newTestRunner(t *testing.T, fsys fs.FS) *Runner {
+	t.Helper()
+	r, err := NewRunner(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Logger = discardLogger
+	return r
+}
+
+func TestParse(t *testing.T) {
+	ms, err := Parse(testFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range ms {
+		got = append(got, m.Version+"_"+m.Name)
+	}
+	if strings.Join(got, ",") != "20260101000000_first,20260102000000_second,20260103000000_third" {
+		t.Errorf("order = %v", got)
+	}
+	if ms[0].Up != "CREATE TABLE a ();" || ms[0].Down != "DROP TABLE a;" || ms[2].Down != "" {
+		t.Errorf("sections = %+v", ms)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, files := range map[string]fstest.MapFS{
+		"missing timestamp": {"add_table.sql": {Data: []byte("-- +migrate Up\nSELECT 1;")}},
+		"short timestamp":   {"202601010000_x.sql": {Data: []byte("-- +migrate Up\nSELECT 1;")}},
+		"bad timestamp":     {"20261301000000_x.sql": {Data: []byte("-- +migrate Up\nSELECT 1;")}},
+		"uppercase":         {"20260101000000_AddTable.sql": {Data: []byte("-- +migrate Up\nSELECT 1;")}},
+		"no up":             {"20260101000000_x.sql": {Data: []byte("SELECT 1;")}},
+		"empty up":          {"20260101000000_x.sql": {Data: []byte("-- +migrate Up\n-- +migrate Down\nSELECT 1;")}},
+		"duplicate": {
+			"20260101000000_x.sql": {Data: []byte("-- +migrate Up\nSELECT 1;")},
+			"20260101000000_y.sql": {Data: []byte("-- +migrate Up\nSELECT 2;")},
+		},
+	} {
+		if _, err := Parse(files); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	r, err := NewRunner(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for _, m := range r.Migrations {
+		if m.Down == "" {
+			t.Errorf("%s_%s has no down step", m.Version, m.Name)
+		}
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	pg := newFakePostgres()
+	db := sql.OpenDB(pg)
+	defer db.Close()
+	r := newTestRunner(t, testFS())
+
+	if n, err := r.Up(ctx, db); err != nil || n != 3 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	if got := strings.Join(pg.applied(), ","); got != "20260101000000,20260102000000,20260103000000" {
+		t.Fatalf("applied = %s", got)
+	}
+	if n, err := r.Up(ctx, db); err != nil || n != 0 {
+		t.Fatalf("second Up = %d, %v", n, err)
+	}
+	if len(pg.executed) != 3 {
+		t.Errorf("second Up re-ran migrations: %v", pg.executed)
+	}
+
+	// The third migration has no down step.
+	if err := r.Down(ctx, db); err == nil || !strings.Contains(err.Error(), "no down step") {
+		t.Fatalf("Down without a down step: %v", err)
+	}
+	delete(pg.versions, "20260103000000")
+	if err := r.Down(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pg.applied(), ","); got != "20260101000000" {
+		t.Errorf("after one Down, applied = %s", got)
+	}
+	if last := pg.executed[len(pg.executed)-1]; last != "DROP TABLE b;" {
+		t.Errorf("Down ran %q", last)
+	}
+
+	status, err := r.Status(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0].AppliedAt.IsZero() || !status[1].AppliedAt.IsZero() {
+		t.Errorf("status = %+v", status)
+	}
+
+	pg.versions["20250101000000"] = time.Now()
+	delete(pg.versions, "20260101000000")
+	if err := r.Down(ctx, db); err == nil || !strings.Contains(err.Error(), "not in this binary") {
+		t.Errorf("Down of an unknown version: %v", err)
+	}
+}
+
+func TestUpPartiallyMigrated(t *testing.T) {
+	pg := newFakePostgres()
+	pg.versions["20260101000000"] = time.Now()
+	db := sql.OpenDB(pg)
+	defer db.Close()
+
+	n, err := Up(context.Background(), db, testFS())
+	if err != nil || n != 2 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	if got := strings.Join(pg.executed, " "); got != "CREATE TABLE b (); CREATE TABLE c ();" {
+		t.Errorf("executed %q, want only the pending migrations in order", got)
+	}
+}
+
+func TestUpDryRun(t *testing.T) {
+	ctx := context.Background()
+	pg := newFakePostgres()
+	pg.versions["20260101000000"] = time.Now()
+	db := sql.OpenDB(pg)
+	defer db.Close()
+	r := newTestRunner(t, testFS())
+	r.DryRun = true
+
+	for range 2 {
+		if n, err := r.Up(ctx, db); err != nil || n != 2 {
+			t.Fatalf("dry run = %d, %v, want the 2 pending", n, err)
+		}
+	}
+	if len(pg.executed) != 0 || len(pg.applied()) != 1 {
+		t.Errorf("dry run executed %v, applied %v", pg.executed, pg.applied())
+	}
+}
+
+func TestUpStopsAtFailure(t *testing.T) {
+	pg := newFakePostgres()
+	pg.failOn = "TABLE b"
+	db := sql.OpenDB(pg)
+	defer db.Close()
+	n, err := newTestRunner(t, testFS()).Up(context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "apply 20260102000000_second") || n != 1 {
+		t.Fatalf("Up = %d, %v", n, err)
+	}
+	if got := strings.Join(pg.applied(), ","); got != "20260101000000" {
+		t.Errorf("applied = %s, want only the migration before the failure", got)
+	}
+}
+
+func TestConcurrentUpAppliesOnce(t *testing.T) {
+	pg := newFakePostgres()
+	db := sql.OpenDB(pg)
+	defer db.Close()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := newTestRunner(t, testFS()).Up(context.Background(), db); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(pg.executed) != 3 {
+		t.Errorf("executed %d migrations across replicas, want 3: %v", len(pg.executed), pg.executed)
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+// ApplicantProfile is the person behind one or more applications; ID is the
+// applicant_id they carry and the subject of the applicant's tokens.
+type ApplicantProfile struct {
+	ID       string `json:"id"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email,omitempty"`
+}
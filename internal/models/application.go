@@ -0,0 +1,31 @@
+// Package models defines the admissions domain types shared by the store
+// and HTTP layers.
+package models
+
+import (
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+)
+
+// StudentApplication is one applicant's application to one program.
+type StudentApplication struct {
+	ID string `json:"id"`
+	// TenantID is the university the application was made to; stores set
+	// it from the context of Create.
+	TenantID    string `json:"tenant_id,omitempty"`
+	ApplicantID string `json:"applicant_id"`
+	ProgramCode string `json:"program_code"`
+	// Round is the admission round, e.g. "early"; deadlines are set per
+	// program and round.
+	Round       string       `json:"round,omitempty"`
+	Status      status.State `json:"status"`
+	SubmittedAt time.Time    `json:"submitted_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	// LetterKey is where the decision letter for the current status is
+	// archived, once one has been generated; a status change clears it.
+	LetterKey string `json:"letter_key,omitempty"`
+	// DeletedAt is when the application was soft-deleted; stores hide it
+	// from reads unless asked otherwise.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// InterviewSlot is a time an advisor interviews up to MaxCandidates
+// applicants to one program.
+type InterviewSlot struct {
+	ID            string    `json:"id"`
+	ProgramCode   string    `json:"program_code"`
+	StartAt       time.Time `json:"start_at"`
+	EndAt         time.Time `json:"end_at"`
+	AdvisorID     string    `json:"advisor_id"`
+	MaxCandidates int       `json:"max_candidates"`
+	// Booked is how many bookings the slot holds when it is read.
+	Booked   int    `json:"booked"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// InterviewBooking holds an application's place in a slot. An
+// application has at most one booking per program.
+type InterviewBooking struct {
+	ID            string    `json:"id"`
+	SlotID        string    `json:"slot_id"`
+	ApplicationID string    `json:"application_id"`
+	BookedAt      time.Time `json:"booked_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Program is one entry of the program catalog, as configured in one of
+// its versions. Every change to a program stores a new version rather
+// than overwriting the last, so its configuration on any past date can
+// still be read.
+type Program struct {
+	Code              string   `json:"code"`
+	Name              string   `json:"name"`
+	Faculty           string   `json:"faculty"`
+	RequiredDocuments []string `json:"required_documents"`
+	Capacity          int      `json:"capacity"`
+	// DeadlinesByRound is when each admission round, such as "early" or
+	// "regular", stops accepting submissions.
+	DeadlinesByRound map[string]time.Time `json:"deadlines_by_round"`
+	// Version counts the program's versions, from 1 at creation.
+	Version int `json:"version"`
+	// ValidFrom is when this version replaced the previous one.
+	ValidFrom time.Time `json:"valid_from"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+}
+
+// IsOpen reports whether any round of the program still accepts
+// submissions at now.
+func (p *Program) IsOpen(now time.Time) bool {
+	for _, closes := range p.DeadlinesByRound {
+		if now.Before(closes) {
+			return true
+		}
+	}
+	return false
+}
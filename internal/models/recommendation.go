@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RecommendationStatus is where a recommendation request stands.
+type RecommendationStatus string
+
+// Recommendation request statuses. A request is pending until its referee
+// submits a letter or ExpiresAt passes; re-sending the invite makes an
+// expired request pending again.
+const (
+	RecommendationPending   RecommendationStatus = "pending"
+	RecommendationSubmitted RecommendationStatus = "submitted"
+	RecommendationExpired   RecommendationStatus = "expired"
+)
+
+// RecommendationRequest asks a referee to submit a letter for an
+// application through a link carrying Token.
+type RecommendationRequest struct {
+	ID            string `json:"id"`
+	ApplicationID string `json:"application_id"`
+	RefereeEmail  string `json:"referee_email"`
+	// Token is the referee's single-use credential. It only ever leaves
+	// the service in the invite link, so the applicant cannot submit a
+	// letter themselves.
+	Token     string               `json:"-"`
+	Status    RecommendationStatus `json:"status"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	CreatedAt time.Time            `json:"created_at"`
+	// SentAt is when the current invite was emailed.
+	SentAt      time.Time  `json:"sent_at"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	// DocumentID is the documents.Meta of the submitted letter.
+	DocumentID string `json:"document_id,omitempty"`
+}
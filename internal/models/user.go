@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// User is someone who signs in through the university identity provider.
+// ID is the subject of their tokens and, for students, the applicant_id
+// of their applications; Issuer and Subject identify the provider
+// account behind it.
+type User struct {
+	ID          string    `json:"id"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// SentMessage is one call recorded by MockSender.
+type SentMessage struct {
+	To, Subject, Template string
+	Data                  any
+	// Body is the rendered message when the MockSender has Templates.
+	Body string
+	// RequestID is the request ID of Send's context.
+	RequestID string
+}
+
+// MockSender records messages instead of delivering them.
+type MockSender struct {
+	// Templates, if set, renders every message so template errors fail
+	// Send as they would with SMTPSender.
+	Templates *TemplateRegistry
+	// Err, if set, is returned by every Send and nothing is recorded.
+	Err error
+
+	mu   sync.Mutex
+	sent []SentMessage
+}
+
+// Send implements EmailSender.
+func (m *MockSender) Send(ctx context.Context, to, subject string, tmpl string, data any) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	msg := SentMessage{To: to, Subject: subject, Template: tmpl, Data: data, RequestID: requestid.FromContext(ctx)}
+	if m.Templates != nil {
+		body, err := m.Templates.Render(tmpl, data)
+		if err != nil {
+			return err
+		}
+		msg.Body = body
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the recorded messages in order.
+func (m *MockSender) Sent() []SentMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SentMessage(nil), m.sent...)
+}
@@ -0,0 +1,111 @@
+// Package notify emails applicants when their application changes status
+// or they book an interview, and referees when an applicant asks them for
+// a recommendation.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+)
+
+// EmailSender renders the named template with data and delivers it. A
+// template error is returned before anything is sent.
+type EmailSender interface {
+	Send(ctx context.Context, to, subject string, tmpl string, data any) error
+}
+
+// ErrNoAddress is returned by a Directory with no address for an
+// applicant.
+var ErrNoAddress = errors.New("notify: no email address for applicant")
+
+// Directory resolves an applicant ID to an email address.
+type Directory interface {
+	Email(ctx context.Context, applicantID string) (string, error)
+}
+
+// StaticDirectory is a fixed applicant ID to address map.
+type StaticDirectory map[string]string
+
+// Email implements Directory.
+func (d StaticDirectory) Email(_ context.Context, applicantID string) (string, error) {
+	if addr, ok := d[applicantID]; ok {
+		return addr, nil
+	}
+	return "", ErrNoAddress
+}
+
+// Message is the email sent when an application enters a status.
+type Message struct {
+	Subject  string
+	Template string
+}
+
+// DefaultMessages covers receipt, review, and decisions; other statuses
+// send nothing.
+var DefaultMessages = map[status.State]Message{
+	"pending":      {Subject: "We received your application", Template: "application_received"},
+	"under_review": {Subject: "Your application is under review", Template: "under_review"},
+	"accepted":     {Subject: "A decision on your application", Template: "decision"},
+	"rejected":     {Subject: "A decision on your application", Template: "decision"},
+}
+
+// StatusData is the data passed to status templates.
+type StatusData struct {
+	Application models.StudentApplication
+}
+
+// StatusMailer emails an application's applicant about its status.
+type StatusMailer struct {
+	Sender    EmailSender
+	Directory Directory
+	// Messages defaults to DefaultMessages.
+	Messages map[status.State]Message
+}
+
+// StatusChanged sends the message for app's current status, if there is
+// one.
+func (m *StatusMailer) StatusChanged(ctx context.Context, app *models.StudentApplication) error {
+	messages := m.Messages
+	if messages == nil {
+		messages = DefaultMessages
+	}
+	msg, ok := messages[app.Status]
+	if !ok {
+		return nil
+	}
+	to, err := m.Directory.Email(ctx, app.ApplicantID)
+	if err != nil {
+		return fmt.Errorf("notify: address for %s: %w", app.ApplicantID, err)
+	}
+	return m.Sender.Send(ctx, to, msg.Subject, msg.Template, StatusData{Application: *app})
+}
+
+// RecommendationMessage is the invite emailed to a referee, rendered with
+// RecommendationData.
+var RecommendationMessage = Message{Subject: "A request for a recommendation letter", Template: "recommendation_request"}
+
+// RecommendationData is the data passed to the recommendation template.
+// Link is the referee's single-use submission link.
+type RecommendationData struct {
+	Link          string
+	ApplicationID string
+	ProgramCode   string
+	ExpiresAt     time.Time
+}
+
+// InterviewMessage confirms a booked interview to the applicant, rendered
+// with InterviewData.
+var InterviewMessage = Message{Subject: "Your interview is booked", Template: "interview_booked"}
+
+// InterviewData is the data passed to the interview template.
+type InterviewData struct {
+	ApplicationID string
+	ProgramCode   string
+	StartAt       time.Time
+	EndAt         time.Time
+}
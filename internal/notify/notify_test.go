@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) *TemplateRegistry {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestRepositoryTemplates(t *testing.T) {
+	reg, err := LoadTemplates("../../config/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := models.StudentApplication{ID: "app-1", ProgramCode: "CS", Round: "early"}
+	for st, msg := range DefaultMessages {
+		app.Status = st
+		body, err := reg.Render(msg.Template, StatusData{Application: app})
+		if err != nil {
+			t.Errorf("%s: %v", st, err)
+		}
+		if !strings.Contains(body, "app-1") {
+			t.Errorf("%s body does not mention the application:\n%s", st, body)
+		}
+	}
+}
+
+func TestTemplateRegistry(t *testing.T) {
+	reg := writeTemplates(t, map[string]string{
+		"hello.html":  `<p>Hi {{.Name}}</p>`,
+		"strict.html": `{{.missing}}`,
+		"notes.txt":   `ignored`,
+	})
+	if got := strings.Join(reg.Names(), ","); got != "hello,strict" {
+		t.Errorf("Names() = %s", got)
+	}
+	body, err := reg.Render("hello", map[string]string{"Name": "<Ada>"})
+	if err != nil || body != "<p>Hi &lt;Ada&gt;</p>" {
+		t.Errorf("Render = %q, %v", body, err)
+	}
+	if _, err := reg.Render("strict", map[string]string{}); err == nil {
+		t.Error("missing key rendered without error")
+	}
+	if _, err := reg.Render("goodbye", nil); err == nil {
+		t.Error("unknown template rendered without error")
+	}
+	if _, err := LoadTemplates(t.TempDir()); err == nil {
+		t.Error("empty template directory loaded")
+	}
+}
+
+func TestStatusMailer(t *testing.T) {
+	sender := &MockSender{Templates: writeTemplates(t, map[string]string{
+		"under_review.html": `{{.Application.ID}} is under review`,
+	})}
+	m := &StatusMailer{Sender: sender, Directory: StaticDirectory{"stu-1": "ada@example.edu"}}
+	ctx := context.Background()
+
+	if err := m.StatusChanged(ctx, &models.StudentApplication{ID: "app-1", ApplicantID: "stu-1", Status: "under_review"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.StatusChanged(ctx, &models.StudentApplication{ID: "app-1", ApplicantID: "stu-1", Status: "withdrawn"}); err != nil {
+		t.Fatal(err)
+	}
+	sent := sender.Sent()
+	if len(sent) != 1 || sent[0].To != "ada@example.edu" || sent[0].Body != "app-1 is under review" {
+		t.Errorf("sent = %+v", sent)
+	}
+
+	// decision.html is missing, so the render error must surface.
+	if err := m.StatusChanged(ctx, &models.StudentApplication{ApplicantID: "stu-1", Status: "accepted"}); err == nil {
+		t.Error("render error swallowed")
+	}
+	if err := m.StatusChanged(ctx, &models.StudentApplication{ApplicantID: "stu-2", Status: "under_review"}); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("unknown applicant: %v", err)
+	}
+}
+
+// fakeSMTP accepts one message and returns its DATA section.
+func fakeSMTP(t *testing.T) (addr string, data <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				out <- b.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, data := fakeSMTP(t)
+	s := &SMTPSender{
+		Addr:      addr,
+		From:      "Admissions <admissions@example.edu>",
+		Templates: writeTemplates(t, map[string]string{"hello.html": "<p>Hi {{.}}</p>\n"}),
+		Now:       func() time.Time { return time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC) },
+	}
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	if err := s.Send(ctx, "ada@example.edu", "Über review", "hello", "Ada"); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-data
+	for _, want := range []string{
+		"From: \"Admissions\" <admissions@example.edu>\r\n",
+		"To: <ada@example.edu>\r\n",
+		"Subject: =?utf-8?q?=C3=9Cber_review?=\r\n",
+		"Date: Wed, 14 Oct 2026 09:00:00 +0000\r\n",
+		"Content-Type: text/html; charset=\"utf-8\"\r\n",
+		"X-Request-ID: req-42\r\n",
+		"\r\n<p>Hi Ada</p>\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSMTPSenderRejectsBeforeSending(t *testing.T) {
+	s := &SMTPSender{
+		Addr:      "127.0.0.1:1",
+		From:      "admissions@example.edu",
+		Templates: writeTemplates(t, map[string]string{"hello.html": "{{.Name}}"}),
+	}
+	ctx := context.Background()
+	if err := s.Send(ctx, "ada@example.edu", "Hi", "hello", 42); err == nil || !strings.Contains(err.Error(), "render hello") {
+		t.Errorf("render error: %v", err)
+	}
+	if err := s.Send(ctx, "ada@example.edu\r\nBcc: x@example.com", "Hi", "hello", map[string]string{"Name": "x"}); err == nil || !strings.Contains(err.Error(), "recipient") {
+		t.Errorf("header injection: %v", err)
+	}
+}
+
+func TestSMTPSenderBreaker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	s := &SMTPSender{
+		Addr:      addr,
+		From:      "admissions@example.edu",
+		Templates: writeTemplates(t, map[string]string{"hello.html": "<p>Hi</p>"}),
+		Breaker:   &circuit.Breaker{Name: "smtp", Policy: circuit.Policy{FailureThreshold: 2}},
+	}
+	ctx := context.Background()
+	for range 2 {
+		if err := s.Send(ctx, "ada@example.edu", "Hi", "hello", nil); err == nil || errors.Is(err, circuit.ErrOpen) {
+			t.Fatalf("relay down: %v, want a dial error", err)
+		}
+	}
+	if err := s.Send(ctx, "ada@example.edu", "Hi", "hello", nil); !errors.Is(err, circuit.ErrOpen) {
+		t.Errorf("after 2 failures: %v, want circuit.ErrOpen", err)
+	}
+	// Mail refused before it reaches the relay does not count.
+	s.Breaker = &circuit.Breaker{Name: "smtp", Policy: circuit.Policy{FailureThreshold: 1}}
+	s.Send(ctx, "not an address", "Hi", "hello", nil)
+	if s.Breaker.State() != circuit.Closed {
+		t.Errorf("bad recipient opened the breaker")
+	}
+}
+
+// stallSender blocks every Send until ctx is done.
+type stallSender struct{ started chan string }
+
+func (s stallSender) Send(ctx context.Context, to, _, _ string, _ any) error {
+	s.started <- to
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestQueue(t *testing.T) {
+	mock := &MockSender{}
+	q := NewQueue(mock, 2)
+	q.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	if err := q.Send(requestid.NewContext(ctx, "req-42"), "a@example.edu", "Hi", "hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	q.Send(ctx, "b@example.edu", "Hi", "hello", nil)
+	if err := q.Send(ctx, "c@example.edu", "Hi", "hello", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("third Send into a queue of two = %v", err)
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		q.Run(runCtx)
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); len(mock.Sent()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	<-done
+	if n := len(mock.Sent()); n != 2 {
+		t.Fatalf("Run delivered %d messages, want 2", n)
+	}
+	if id := mock.Sent()[0].RequestID; id != "req-42" {
+		t.Errorf("delivery request ID = %q, want the sending request's", id)
+	}
+
+	// Queued after Run stopped, as by a request finishing during shutdown.
+	q.Send(ctx, "d@example.edu", "Hi", "hello", nil)
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mock.Sent(); len(sent) != 3 || sent[2].To != "d@example.edu" {
+		t.Errorf("after Flush, sent = %+v", sent)
+	}
+	mock.Err = errors.New("relay down")
+	q.Send(ctx, "e@example.edu", "Hi", "hello", nil)
+	if err := q.Flush(ctx); err == nil || !strings.Contains(err.Error(), "1 of 1 queued messages failed") {
+		t.Errorf("Flush with a failing relay = %v", err)
+	}
+}
+
+func TestQueueFlushRetriesInterrupted(t *testing.T) {
+	stall := stallSender{started: make(chan string, 1)}
+	q := NewQueue(stall, 4)
+	ctx := context.Background()
+	q.Send(ctx, "a@example.edu", "Hi", "hello", nil)
+	q.Send(ctx, "b@example.edu", "Hi", "hello", nil)
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		q.Run(runCtx)
+		close(done)
+	}()
+	<-stall.started
+	stop()
+	<-done
+
+	// Both messages, the interrupted one first, are still owed.
+	mock := &MockSender{}
+	q.Sender = mock
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mock.Sent(); len(sent) != 2 || sent[0].To != "a@example.edu" {
+		t.Errorf("Flush sent %+v", sent)
+	}
+
+	q.Send(ctx, "c@example.edu", "Hi", "hello", nil)
+	expired, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := q.Flush(expired); !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "1 queued messages left unsent") {
+		t.Errorf("Flush after the deadline = %v", err)
+	}
+}
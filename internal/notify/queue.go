@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// DefaultSendTimeout bounds each delivery from a Queue.
+const DefaultSendTimeout = 30 * time.Second
+
+// ErrQueueFull is returned by Queue.Send when size messages are already
+// waiting.
+var ErrQueueFull = errors.New("notify: send queue full")
+
+// Queue is an EmailSender that delivers through Sender in the background,
+// so a slow relay does not hold up the request that sent the mail. Send
+// only queues the message: template and delivery failures are logged, not
+// returned. It implements server.Worker and server.Flusher, so passing it
+// to server.WithWorker delivers what is still queued at shutdown.
+type Queue struct {
+	Sender EmailSender
+	// Timeout bounds each delivery; zero means DefaultSendTimeout.
+	Timeout time.Duration
+	// Logger receives delivery failures; nil means slog.Default().
+	Logger *slog.Logger
+
+	jobs chan queued
+
+	mu          sync.Mutex
+	interrupted []queued // cut off by shutdown; Flush retries them
+}
+
+type queued struct {
+	to, subject, tmpl string
+	data              any
+	requestID         string // of the request that sent it, for the relay and logs
+}
+
+// NewQueue returns a Queue holding up to size undelivered messages.
+func NewQueue(sender EmailSender, size int) *Queue {
+	return &Queue{Sender: sender, jobs: make(chan queued, size)}
+}
+
+// Send implements EmailSender. ctx is not used for the delivery, which
+// outlives the request, beyond carrying its request ID on to Sender.
+func (q *Queue) Send(ctx context.Context, to, subject string, tmpl string, data any) error {
+	select {
+	case q.jobs <- queued{to, subject, tmpl, data, requestid.FromContext(ctx)}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run delivers queued messages until ctx is done. A delivery cut off by
+// ctx is kept for Flush.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-q.jobs:
+			err := q.deliver(ctx, j)
+			if err != nil && ctx.Err() != nil {
+				q.mu.Lock()
+				q.interrupted = append(q.interrupted, j)
+				q.mu.Unlock()
+				return
+			}
+			if err != nil {
+				q.logger().Error("notification failed", "to", j.to, "template", j.tmpl, "request_id", j.requestID, "error", err)
+			}
+		}
+	}
+}
+
+// Flush delivers every message still queued, including any Run was cut
+// off sending, until the queue is empty or ctx is done. It must not run
+// alongside Run. The error counts the messages that failed or were left
+// unsent.
+func (q *Queue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	pending := q.interrupted
+	q.interrupted = nil
+	q.mu.Unlock()
+	var failed, total int
+	for {
+		var j queued
+		if len(pending) > 0 {
+			j, pending = pending[0], pending[1:]
+		} else {
+			select {
+			case j = <-q.jobs:
+			default:
+				if failed > 0 {
+					return fmt.Errorf("notify: %d of %d queued messages failed", failed, total)
+				}
+				return nil
+			}
+		}
+		total++
+		if ctx.Err() != nil {
+			left := 1 + len(pending) + len(q.jobs)
+			return fmt.Errorf("notify: %d queued messages left unsent: %w", left, ctx.Err())
+		}
+		if err := q.deliver(ctx, j); err != nil {
+			failed++
+			q.logger().Error("notification failed", "to", j.to, "template", j.tmpl, "request_id", j.requestID, "error", err)
+		}
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, j queued) error {
+	timeout := q.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSendTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if j.requestID != "" {
+		ctx = requestid.NewContext(ctx, j.requestID)
+	}
+	return q.Sender.Send(ctx, j.to, j.subject, j.tmpl, j.data)
+}
+
+func (q *Queue) logger() *slog.Logger {
+	if q.Logger != nil {
+		return q.Logger
+	}
+	return slog.Default()
+}
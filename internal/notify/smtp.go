@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// SMTPSender delivers HTML mail through an SMTP relay with net/smtp,
+// upgrading with STARTTLS when the server offers it.
+type SMTPSender struct {
+	// Addr is the relay's host:port.
+	Addr string
+	// From is the envelope and header sender, e.g.
+	// "Admissions <admissions@example.edu>".
+	From string
+	// Auth, if set, is used after STARTTLS; net/smtp refuses PLAIN auth
+	// over an unencrypted connection to anything but localhost.
+	Auth      smtp.Auth
+	Templates *TemplateRegistry
+	// Breaker, if set, guards the connections to the relay, so while it
+	// is down Send fails at once with circuit.ErrOpen.
+	Breaker *circuit.Breaker
+
+	// Now overrides the Date header clock in tests.
+	Now func() time.Time
+}
+
+// Send implements EmailSender. ctx bounds the whole SMTP exchange, and its
+// request ID, if any, goes out as an X-Request-ID header so the relay's
+// logs can be matched with the request that sent the mail.
+func (s *SMTPSender) Send(ctx context.Context, to, subject string, tmpl string, data any) error {
+	body, err := s.Templates.Render(tmpl, data)
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("notify: sender %q: %w", s.From, err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("notify: recipient %q: %w", to, err)
+	}
+	msg := s.message(from, rcpt, subject, body, requestid.FromContext(ctx))
+
+	return s.Breaker.Do(ctx, func() error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", s.Addr)
+		if err != nil {
+			return fmt.Errorf("notify: dial %s: %w", s.Addr, err)
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		// Unblock the exchange if ctx is cancelled without a deadline.
+		stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+		defer stop()
+
+		if err := s.deliver(conn, from.Address, rcpt.Address, msg); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fmt.Errorf("notify: send to %s: %w", rcpt.Address, ctxErr)
+			}
+			err = fmt.Errorf("notify: send to %s: %w", rcpt.Address, err)
+			// A relay refusing the message with a 5xx reply is up.
+			var reply *textproto.Error
+			if errors.As(err, &reply) && reply.Code >= 500 {
+				return circuit.Healthy(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *SMTPSender) deliver(conn net.Conn, from, to string, msg []byte) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if err := c.Auth(s.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats an RFC 5322 HTML message. The subject is Q-encoded,
+// both addresses come from mail.ParseAddress, and a request ID is kept
+// only if requestid.Valid, so no header can carry a line break.
+func (s *SMTPSender) message(from, to *mail.Address, subject, body, requestID string) []byte {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	var b strings.Builder
+	for _, h := range [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/html; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "8bit"},
+	} {
+		b.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	if requestid.Valid(requestID) {
+		b.WriteString(requestid.Header + ": " + requestID + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLDirectory reads addresses from the applicant_profiles table (see
+// prisma/schema.prisma), among the profiles of the context's tenant.
+type SQLDirectory struct {
+	DB *sql.DB
+}
+
+// Email implements Directory.
+func (d SQLDirectory) Email(ctx context.Context, applicantID string) (string, error) {
+	const stmt = `SELECT email FROM applicant_profiles WHERE id = $1`
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	var email sql.NullString
+	err := store.Scoped(ctx, d.DB, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, stmt, applicantID).Scan(&email)
+	})
+	if errors.Is(err, sql.ErrNoRows) || err == nil && email.String == "" {
+		return "", ErrNoAddress
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", fmt.Errorf("notify: query: %w", err)
+	}
+	return email.String, nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TemplateExt is the extension of the files a TemplateRegistry loads.
+const TemplateExt = ".html"
+
+// TemplateRegistry holds email bodies parsed from a directory of
+// html/template files, keyed by file name without TemplateExt.
+type TemplateRegistry struct {
+	templates map[string]*template.Template
+}
+
+// LoadTemplates parses every *.html file in dir. Templates fail on missing
+// map keys so a typo in a field name surfaces as an error instead of an
+// empty string in the email.
+func LoadTemplates(dir string) (*TemplateRegistry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TemplateExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("notify: no %s templates in %s", TemplateExt, dir)
+	}
+	reg := &TemplateRegistry{templates: map[string]*template.Template{}}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), TemplateExt)
+		t, err := template.New(name).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("notify: parse %s: %w", path, err)
+		}
+		reg.templates[name] = t
+	}
+	return reg, nil
+}
+
+// Names lists the loaded templates in order.
+func (r *TemplateRegistry) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the named template. Nothing is returned on failure, so
+// a half-rendered body can never be sent.
+func (r *TemplateRegistry) Render(name string, data any) (string, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return "", fmt.Errorf("notify: unknown template %q (have %v)", name, r.Names())
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("notify: render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,130 @@
+package programs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// MemoryStore is an in-process Store for tests and local development.
+type MemoryStore struct {
+	mu sync.Mutex
+	// versions holds each program's versions, oldest first, keyed by
+	// tenant and code.
+	versions map[programKey][]models.Program
+	now      func() time.Time
+}
+
+type programKey struct{ tenant, code string }
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{versions: map[programKey][]models.Program{}, now: time.Now}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(ctx context.Context, p *models.Program) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := programKey{tenant.ID(ctx), p.Code}
+	if len(s.versions[k]) > 0 {
+		return ErrExists
+	}
+	p.Version, p.ValidFrom, p.TenantID = 1, s.now().UTC(), k.tenant
+	s.versions[k] = []models.Program{clone(*p)}
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, code string) (*models.Program, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vs := s.lookup(ctx, code)
+	if len(vs) == 0 {
+		return nil, store.ErrNotFound
+	}
+	p := clone(vs[len(vs)-1])
+	return &p, nil
+}
+
+// At implements Store.
+func (s *MemoryStore) At(ctx context.Context, code string, t time.Time) (*models.Program, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vs := s.lookup(ctx, code)
+	for i := len(vs) - 1; i >= 0; i-- {
+		if !vs[i].ValidFrom.After(t) {
+			p := clone(vs[i])
+			return &p, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context) ([]models.Program, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Program{}
+	for k, vs := range s.versions {
+		if tenant.Allows(ctx, k.tenant) {
+			out = append(out, clone(vs[len(vs)-1]))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Code != out[j].Code {
+			return out[i].Code < out[j].Code
+		}
+		return out[i].TenantID < out[j].TenantID
+	})
+	return out, nil
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, p *models.Program) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vs := s.lookup(ctx, p.Code)
+	if len(vs) == 0 {
+		return store.ErrNotFound
+	}
+	last := vs[len(vs)-1]
+	p.Version, p.ValidFrom, p.TenantID = last.Version+1, s.now().UTC(), last.TenantID
+	k := programKey{last.TenantID, p.Code}
+	s.versions[k] = append(vs, clone(*p))
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vs := s.lookup(ctx, code)
+	if len(vs) == 0 {
+		return store.ErrNotFound
+	}
+	delete(s.versions, programKey{vs[0].TenantID, code})
+	return nil
+}
+
+// lookup returns the versions of code in the catalog of ctx's tenant.
+func (s *MemoryStore) lookup(ctx context.Context, code string) []models.Program {
+	return s.versions[programKey{tenant.ID(ctx), code}]
+}
+
+// clone copies p's slice and map, so a stored version does not change
+// with the caller's copy.
+func clone(p models.Program) models.Program {
+	p.RequiredDocuments = append([]string(nil), p.RequiredDocuments...)
+	deadlines := make(map[string]time.Time, len(p.DeadlinesByRound))
+	for round, t := range p.DeadlinesByRound {
+		deadlines[round] = t
+	}
+	p.DeadlinesByRound = deadlines
+	return p
+}
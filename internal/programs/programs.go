@@ -0,0 +1,124 @@
+// Package programs is the program catalog: the programs a tenant accepts
+// applications to, with their required documents, capacity, and round
+// deadlines. Programs are versioned. Create stores version 1 and every
+// Update a new version valid from the time of the update, so At can
+// answer what a program looked like on a past date, such as the deadline
+// an applicant was shown.
+package programs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/cache"
+)
+
+// DefaultCacheTTL is how long Cached keeps a catalog read.
+const DefaultCacheTTL = 5 * time.Minute
+
+// ErrExists is returned when creating a program whose code is taken.
+var ErrExists = errors.New("programs: program code already exists")
+
+// Store persists the catalog. Lookups of an unknown code return
+// store.ErrNotFound. Programs belong to the tenant of the context they
+// are created in, and only that tenant's reads see them.
+type Store interface {
+	// Create stores p as version 1 of a new program, setting its
+	// Version, ValidFrom, and TenantID.
+	Create(ctx context.Context, p *models.Program) error
+	// Get returns the current version of a program.
+	Get(ctx context.Context, code string) (*models.Program, error)
+	// At returns the version of a program in effect at t: the last one
+	// valid from t or earlier.
+	At(ctx context.Context, code string, t time.Time) (*models.Program, error)
+	// List returns the current version of every program, ordered by
+	// code.
+	List(ctx context.Context) ([]models.Program, error)
+	// Update stores p as the next version of the program with its code,
+	// setting p's Version, ValidFrom, and TenantID.
+	Update(ctx context.Context, p *models.Program) error
+	// Delete removes a program with all its versions.
+	Delete(ctx context.Context, code string) error
+}
+
+// Cached puts the reads of the current catalog, Get and List, behind c
+// for ttl, or DefaultCacheTTL if ttl is not positive. Writes through it
+// invalidate what they change; a write through another replica shows
+// once the ttl passes, unless c is shared, as a cache.RedisCache is. At
+// reads the store directly.
+func Cached(st Store, c cache.Cache, ttl time.Duration) Store {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &cached{Store: st, cache: c, ttl: ttl}
+}
+
+type cached struct {
+	Store
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// key is the cache key of name in the catalog of ctx's tenant.
+func key(ctx context.Context, name string) string {
+	return "programs:" + tenant.ID(ctx) + ":" + name
+}
+
+func (c *cached) Get(ctx context.Context, code string) (*models.Program, error) {
+	var p models.Program
+	err := c.load(ctx, key(ctx, "code:"+code), &p, func(ctx context.Context) (any, error) {
+		return c.Store.Get(ctx, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *cached) List(ctx context.Context) ([]models.Program, error) {
+	var ps []models.Program
+	err := c.load(ctx, key(ctx, "list"), &ps, func(ctx context.Context) (any, error) {
+		return c.Store.List(ctx)
+	})
+	return ps, err
+}
+
+// load decodes into v the JSON cached under k, filling the cache from
+// read on a miss.
+func (c *cached) load(ctx context.Context, k string, v any, read func(context.Context) (any, error)) error {
+	b, err := c.cache.GetOrLoad(ctx, k, c.ttl, func(ctx context.Context) ([]byte, error) {
+		v, err := read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (c *cached) Create(ctx context.Context, p *models.Program) error {
+	defer c.invalidate(ctx, p.Code)
+	return c.Store.Create(ctx, p)
+}
+
+func (c *cached) Update(ctx context.Context, p *models.Program) error {
+	defer c.invalidate(ctx, p.Code)
+	return c.Store.Update(ctx, p)
+}
+
+func (c *cached) Delete(ctx context.Context, code string) error {
+	defer c.invalidate(ctx, code)
+	return c.Store.Delete(ctx, code)
+}
+
+func (c *cached) invalidate(ctx context.Context, code string) {
+	c.cache.Delete(ctx, key(ctx, "code:"+code))
+	c.cache.Delete(ctx, key(ctx, "list"))
+}
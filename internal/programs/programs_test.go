@@ -0,0 +1,143 @@
+package programs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestMemoryStoreVersions(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+	regular := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	p := &models.Program{Code: "CS", Name: "Computer Science", Faculty: "Engineering", Capacity: 40, DeadlinesByRound: map[string]time.Time{"regular": regular}}
+	if err := s.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 1 || p.TenantID != "north" {
+		t.Fatalf("created %+v", p)
+	}
+	if err := s.Create(ctx, &models.Program{Code: "CS"}); !errors.Is(err, ErrExists) {
+		t.Fatalf("second create: %v, want ErrExists", err)
+	}
+
+	now = now.AddDate(0, 1, 0)
+	extended := *p
+	extended.DeadlinesByRound = map[string]time.Time{"regular": regular.AddDate(0, 0, 14)}
+	if err := s.Update(ctx, &extended); err != nil {
+		t.Fatal(err)
+	}
+	if extended.Version != 2 {
+		t.Fatalf("updated version %d", extended.Version)
+	}
+	got, err := s.Get(ctx, "CS")
+	if err != nil || !got.DeadlinesByRound["regular"].Equal(regular.AddDate(0, 0, 14)) {
+		t.Fatalf("current: %+v, %v", got, err)
+	}
+	old, err := s.At(ctx, "CS", now.Add(-time.Hour))
+	if err != nil || old.Version != 1 || !old.DeadlinesByRound["regular"].Equal(regular) {
+		t.Fatalf("before the update: %+v, %v", old, err)
+	}
+	if _, err := s.At(ctx, "CS", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("before creation: %v", err)
+	}
+
+	south := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "south"})
+	if _, err := s.Get(south, "CS"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("another tenant's program: %v", err)
+	}
+	if list, _ := s.List(south); len(list) != 0 {
+		t.Errorf("another tenant lists %+v", list)
+	}
+	if list, _ := s.List(ctx); len(list) != 1 || list[0].Version != 2 {
+		t.Errorf("list = %+v, want the current version", list)
+	}
+
+	if err := s.Delete(ctx, "CS"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.At(ctx, "CS", now); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("after delete: %v", err)
+	}
+}
+
+// mapCache is a cache.Cache in a map.
+type mapCache map[string][]byte
+
+func (c mapCache) Get(_ context.Context, key string) ([]byte, bool) {
+	b, ok := c[key]
+	return b, ok
+}
+func (c mapCache) Set(_ context.Context, key string, value []byte, _ time.Duration) { c[key] = value }
+func (c mapCache) Delete(_ context.Context, key string)                             { delete(c, key) }
+func (c mapCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	if b, ok := c[key]; ok {
+		return b, nil
+	}
+	b, err := load(ctx)
+	if err == nil {
+		c[key] = b
+	}
+	return b, err
+}
+
+// countingStore counts the reads reaching a Store.
+type countingStore struct {
+	Store
+	reads int
+}
+
+func (s *countingStore) Get(ctx context.Context, code string) (*models.Program, error) {
+	s.reads++
+	return s.Store.Get(ctx, code)
+}
+
+func (s *countingStore) List(ctx context.Context) ([]models.Program, error) {
+	s.reads++
+	return s.Store.List(ctx)
+}
+
+func TestCached(t *testing.T) {
+	backing := &countingStore{Store: NewMemoryStore()}
+	c := mapCache{}
+	st := Cached(backing, c, time.Minute)
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+	if err := st.Create(ctx, &models.Program{Code: "CS", Name: "Computer Science", Faculty: "Engineering"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if p, err := st.Get(ctx, "CS"); err != nil || p.Name != "Computer Science" {
+			t.Fatalf("get: %+v, %v", p, err)
+		}
+		if list, err := st.List(ctx); err != nil || len(list) != 1 {
+			t.Fatalf("list: %+v, %v", list, err)
+		}
+	}
+	if backing.reads != 2 {
+		t.Errorf("%d reads reached the store, want one Get and one List", backing.reads)
+	}
+	if _, ok := c["programs:north:code:CS"]; !ok {
+		t.Errorf("cache keys = %v, want them scoped to the tenant", c)
+	}
+
+	if err := st.Update(ctx, &models.Program{Code: "CS", Name: "Computing", Faculty: "Engineering"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := st.Get(ctx, "CS"); p.Name != "Computing" || p.Version != 2 {
+		t.Errorf("after update: %+v", p)
+	}
+	if list, _ := st.List(ctx); list[0].Name != "Computing" {
+		t.Errorf("list after update: %+v", list)
+	}
+	if _, err := st.Get(ctx, "EE"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("unknown code through the cache: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package programs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLStore keeps the catalog in the program_versions table (see
+// prisma/schema.prisma), one row per version, in transactions scoped to
+// the context's tenant (see store.Scoped).
+type SQLStore struct {
+	DB *sql.DB
+}
+
+const programColumns = `code, version, name, faculty, required_documents, capacity, deadlines, valid_from, tenant_id`
+
+// Version 1 conflicts with the existing program's, so a taken code
+// inserts nothing.
+const insertProgram = `INSERT INTO program_versions (code, version, name, faculty, required_documents, capacity, deadlines)
+VALUES ($1, 1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING
+RETURNING version, valid_from, tenant_id`
+
+// An unknown code has no rows to take the next version from, so it
+// inserts nothing.
+const insertVersion = `INSERT INTO program_versions (code, version, name, faculty, required_documents, capacity, deadlines)
+SELECT $1, max(version) + 1, $2, $3, $4, $5, $6 FROM program_versions WHERE code = $1 HAVING count(*) > 0
+RETURNING version, valid_from, tenant_id`
+
+// Create implements Store.
+func (s SQLStore) Create(ctx context.Context, p *models.Program) error {
+	err := s.insert(ctx, insertProgram, p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrExists
+	}
+	return err
+}
+
+// Update implements Store.
+func (s SQLStore) Update(ctx context.Context, p *models.Program) error {
+	err := s.insert(ctx, insertVersion, p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s SQLStore) insert(ctx context.Context, stmt string, p *models.Program) error {
+	docs, err := json.Marshal(p.RequiredDocuments)
+	if err != nil {
+		return fmt.Errorf("programs: encode required documents: %w", err)
+	}
+	deadlines, err := json.Marshal(p.DeadlinesByRound)
+	if err != nil {
+		return fmt.Errorf("programs: encode deadlines: %w", err)
+	}
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		err := tx.QueryRowContext(ctx, stmt, p.Code, p.Name, p.Faculty, docs, p.Capacity, deadlines).Scan(&p.Version, &p.ValidFrom, &p.TenantID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("programs: insert: %w", err)
+		}
+		return err
+	})
+}
+
+// Get implements Store.
+func (s SQLStore) Get(ctx context.Context, code string) (*models.Program, error) {
+	return s.one(ctx, `SELECT `+programColumns+` FROM program_versions WHERE code = $1 ORDER BY version DESC LIMIT 1`, code)
+}
+
+// At implements Store.
+func (s SQLStore) At(ctx context.Context, code string, t time.Time) (*models.Program, error) {
+	return s.one(ctx, `SELECT `+programColumns+` FROM program_versions WHERE code = $1 AND valid_from <= $2 ORDER BY version DESC LIMIT 1`, code, t.UTC())
+}
+
+func (s SQLStore) one(ctx context.Context, stmt string, args ...any) (*models.Program, error) {
+	var p *models.Program
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		var err error
+		p, err = scanProgram(tx.QueryRowContext(ctx, stmt, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			tracing.RecordError(span, err)
+		}
+		return err
+	})
+	return p, err
+}
+
+// List implements Store.
+func (s SQLStore) List(ctx context.Context) ([]models.Program, error) {
+	const stmt = `SELECT ` + programColumns + ` FROM (
+    SELECT DISTINCT ON (tenant_id, code) ` + programColumns + ` FROM program_versions ORDER BY tenant_id, code, version DESC
+) current ORDER BY code, tenant_id`
+	out := []models.Program{}
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		rows, err := tx.QueryContext(ctx, stmt)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("programs: query: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			p, err := scanProgram(rows)
+			if err != nil {
+				return err
+			}
+			out = append(out, *p)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("programs: query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete implements Store.
+func (s SQLStore) Delete(ctx context.Context, code string) error {
+	const stmt = `DELETE FROM program_versions WHERE code = $1`
+	return store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		res, err := tx.ExecContext(ctx, stmt, code)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("programs: delete: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return store.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// scanProgram reads one row of programColumns. sql.ErrNoRows is returned
+// as is.
+func scanProgram(row interface{ Scan(...any) error }) (*models.Program, error) {
+	var (
+		p               models.Program
+		docs, deadlines []byte
+	)
+	err := row.Scan(&p.Code, &p.Version, &p.Name, &p.Faculty, &docs, &p.Capacity, &deadlines, &p.ValidFrom, &p.TenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("programs: scan: %w", err)
+	}
+	if err := json.Unmarshal(docs, &p.RequiredDocuments); err != nil {
+		return nil, fmt.Errorf("programs: decode required documents: %w", err)
+	}
+	if err := json.Unmarshal(deadlines, &p.DeadlinesByRound); err != nil {
+		return nil, fmt.Errorf("programs: decode deadlines: %w", err)
+	}
+	return &p, nil
+}
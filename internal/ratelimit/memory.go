@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+)
+
+// sweepEvery is how many Takes pass between sweeps of full buckets.
+const sweepEvery = 1024
+
+// MemStore is a Store for a single instance; every replica of a scaled-out
+// service has its own budget with it.
+type MemStore struct {
+	clock   clock.Clock
+	mu      sync.Mutex
+	buckets map[string]*memBucket
+	takes   int
+}
+
+type memBucket struct {
+	bucket
+	limit  int
+	window time.Duration
+}
+
+// NewMemStore returns an empty MemStore reading the time from clk, or the
+// system clock if clk is nil.
+func NewMemStore(clk clock.Clock) *MemStore {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &MemStore{clock: clk, buckets: map[string]*memBucket{}}
+}
+
+// Take implements Store.
+func (s *MemStore) Take(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.takes++; s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memBucket{bucket: bucket{tokens: float64(limit), at: now}}
+		s.buckets[key] = b
+	}
+	b.limit, b.window = limit, window
+	return b.take(now, limit, window), nil
+}
+
+// sweep drops buckets that have refilled completely; recreating them full
+// is equivalent.
+func (s *MemStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.at.Add(b.window)) {
+			delete(s.buckets, key)
+		}
+	}
+}
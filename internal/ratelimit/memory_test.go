@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+)
+
+func TestMemStoreRefillsContinuously(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	s := NewMemStore(clock.Func(func() time.Time { return now }))
+	ctx := context.Background()
+	take := func() Result {
+		t.Helper()
+		res, err := s.Take(ctx, "user:stu-1", 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	for i := 2; i >= 0; i-- {
+		if res := take(); !res.Allowed || res.Remaining != i {
+			t.Fatalf("take with %d left: %+v", i, res)
+		}
+	}
+	res := take()
+	if res.Allowed || res.RetryAfter != 20*time.Second {
+		t.Fatalf("empty bucket: %+v, want a 20s retry (one token per 20s)", res)
+	}
+	if want := now.Add(time.Minute); !res.Reset.Equal(want) {
+		t.Errorf("Reset = %s, want %s", res.Reset, want)
+	}
+
+	// Half a token back: still denied, with the rest of the wait.
+	now = now.Add(10 * time.Second)
+	if res := take(); res.Allowed || res.RetryAfter != 10*time.Second {
+		t.Fatalf("after 10s: %+v", res)
+	}
+	now = now.Add(10 * time.Second)
+	if res := take(); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after 20s: %+v", res)
+	}
+
+	// A bucket never holds more than limit, however long it idles.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		take()
+	}
+	if res := take(); res.Allowed {
+		t.Errorf("bucket overfilled while idle: %+v", res)
+	}
+
+	// Keys are independent.
+	if res, _ := s.Take(ctx, "user:stu-2", 3, time.Minute); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("other key: %+v", res)
+	}
+}
+
+func TestMemStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	s := NewMemStore(clock.Func(func() time.Time { return now }))
+	s.Take(context.Background(), "idle", 5, time.Second)
+	now = now.Add(time.Second)
+	s.sweep(now)
+	if len(s.buckets) != 0 {
+		t.Errorf("full bucket kept: %v", s.buckets)
+	}
+}
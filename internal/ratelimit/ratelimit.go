@@ -0,0 +1,76 @@
+// Package ratelimit budgets requests per client with token buckets that
+// refill continuously, so the limit holds over any sliding window rather
+// than resetting at fixed boundaries.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Store takes one token from the bucket for key, creating it full if it
+// does not exist. A bucket holds limit tokens and refills at limit per
+// window.
+type Store interface {
+	Take(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// Result reports the outcome of one Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when
+	// Allowed.
+	RetryAfter time.Duration
+	// Reset is when the bucket will be full again.
+	Reset time.Time
+}
+
+// Config is one route's budget. Scope names the bucket family, so a route
+// with its own Config never shares tokens with the default budget.
+type Config struct {
+	Scope  string
+	Limit  int
+	Window time.Duration
+}
+
+type configKey struct{}
+
+// WithConfig returns a context carrying cfg for the rate-limit middleware.
+func WithConfig(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// ConfigFrom returns the Config stored by WithConfig, if any.
+func ConfigFrom(ctx context.Context) (Config, bool) {
+	cfg, ok := ctx.Value(configKey{}).(Config)
+	return cfg, ok
+}
+
+// bucket is MemStore's refill arithmetic; the RedisStore script mirrors
+// it.
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// take refills b up to now and removes one token if it can.
+func (b *bucket) take(now time.Time, limit int, window time.Duration) Result {
+	rate := float64(limit) / float64(window)
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(limit), b.tokens+float64(elapsed)*rate)
+	}
+	b.at = now
+	res := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rate))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = now.Add(time.Duration(math.Ceil((float64(limit) - b.tokens) / rate)))
+	return res
+}
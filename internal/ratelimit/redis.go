@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/ratelimit"
+)
+
+// RedisStore is a Store shared by every replica through Redis. Its
+// buckets are pkg/middleware/ratelimit.Redis's, refilling limit tokens
+// per window, so each Take is the same single Lua script call and
+// concurrent requests cannot overspend a bucket.
+type RedisStore struct {
+	Client redis.Scripter
+	// Prefix namespaces the bucket keys; NewRedisStore sets "ratelimit:",
+	// and empty means ratelimit.DefaultRedisPrefix.
+	Prefix string
+}
+
+// NewRedisStore returns a RedisStore using client.
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{Client: client, Prefix: "ratelimit:"}
+}
+
+// Check pings Redis, so a RedisStore can back a readiness check. A
+// Client that cannot ping fails it.
+func (s *RedisStore) Check(ctx context.Context) error {
+	p, ok := s.Client.(interface {
+		Ping(ctx context.Context) *redis.StatusCmd
+	})
+	if !ok {
+		return errors.New("ratelimit: redis client cannot ping")
+	}
+	return p.Ping(ctx).Err()
+}
+
+// Take implements Store.
+func (s *RedisStore) Take(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	rule := ratelimit.Rule{Rate: float64(limit) / window.Seconds(), Burst: limit}
+	d, err := ratelimit.NewRedis(s.Client, s.Prefix).Allow(ctx, key, rule)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    d.Allowed,
+		Limit:      limit,
+		Remaining:  d.Remaining,
+		RetryAfter: d.RetryAfter,
+		Reset:      time.Now().Add(d.Reset),
+	}, nil
+}
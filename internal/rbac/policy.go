@@ -0,0 +1,130 @@
+// Package rbac maps caller roles to the HTTP endpoints they may call.
+package rbac
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in roles carried in the access token's role claim.
+const (
+	RoleStudent = "student"
+	RoleAdvisor = "advisor"
+	RoleAdmin   = "admin"
+)
+
+// Rule allows one method on one path pattern. Method "*" matches any
+// method. Path segments written as {name} match any single segment and a
+// trailing "*" segment matches the rest of the path.
+type Rule struct {
+	Method string
+	Path   string
+}
+
+func (r Rule) matches(method string, segments []string) bool {
+	if r.Method != "*" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	pattern := splitPath(r.Path)
+	for i, p := range pattern {
+		if p == "*" && i == len(pattern)-1 {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			continue
+		}
+		if p != segments[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
+
+// Policy is the role → allowed-rules table. The zero value denies
+// everything.
+type Policy struct {
+	roles map[string][]Rule
+}
+
+// policyFile is the YAML layout:
+//
+//	roles:
+//	  student:
+//	    - GET /v1/applications/{id}
+//	  admin:
+//	    - "* /v1/*"
+type policyFile struct {
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// ParsePolicy decodes a YAML policy. Unknown keys and malformed rules are
+// errors so that a typo cannot silently widen or narrow access.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var f policyFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("rbac: parse policy: %w", err)
+	}
+	p := &Policy{roles: make(map[string][]Rule, len(f.Roles))}
+	for role, rules := range f.Roles {
+		for _, raw := range rules {
+			rule, err := parseRule(raw)
+			if err != nil {
+				return nil, fmt.Errorf("rbac: role %s: %w", role, err)
+			}
+			p.roles[role] = append(p.roles[role], rule)
+		}
+	}
+	return p, nil
+}
+
+// LoadPolicy reads a YAML policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rbac: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// Allowed reports whether role may call method on path.
+func (p *Policy) Allowed(role, method, path string) bool {
+	if p == nil {
+		return false
+	}
+	segments := splitPath(path)
+	for _, rule := range p.roles[role] {
+		if rule.matches(method, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRule(raw string) (Rule, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(raw), " ")
+	path = strings.TrimSpace(path)
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return Rule{}, fmt.Errorf("invalid rule %q: want \"METHOD /path\"", raw)
+	}
+	if strings.Contains(path, "..") {
+		return Rule{}, fmt.Errorf("invalid rule %q: path must not contain ..", raw)
+	}
+	return Rule{Method: strings.ToUpper(method), Path: path}, nil
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,59 @@
+package rbac
+
+import "testing"
+
+const testPolicy = `
+roles:
+  student:
+    - GET /v1/applications/{id}
+    - post /v1/applications
+  admin:
+    - "* /v1/*"
+`
+
+func TestPolicyAllowed(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		role, method, path string
+		want               bool
+	}{
+		{RoleStudent, "GET", "/v1/applications/42", true},
+		{RoleStudent, "POST", "/v1/applications", true},
+		{RoleStudent, "DELETE", "/v1/applications/42", false},
+		{RoleStudent, "GET", "/v1/applications/42/documents", false},
+		{RoleStudent, "GET", "/v1/admin/users", false},
+		{RoleAdmin, "DELETE", "/v1/applications/42", true},
+		{RoleAdmin, "GET", "/v1/admin/users", true},
+		{RoleAdmin, "GET", "/internal/debug", false},
+		{RoleAdvisor, "GET", "/v1/applications/42", false},
+		{"", "GET", "/v1/applications/42", false},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(tt.role, tt.method, tt.path); got != tt.want {
+			t.Errorf("Allowed(%q, %s, %s) = %v, want %v", tt.role, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePolicyRejectsMalformed(t *testing.T) {
+	for _, src := range []string{
+		"rolez:\n  admin: []\n",
+		"roles:\n  admin:\n    - /v1/applications\n",
+		"roles:\n  admin:\n    - GET v1/applications\n",
+		"roles:\n  admin:\n    - GET /v1/../admin\n",
+	} {
+		if _, err := ParsePolicy([]byte(src)); err == nil {
+			t.Errorf("ParsePolicy(%q) succeeded, want error", src)
+		}
+	}
+}
+
+func TestNilPolicyDeniesAll(t *testing.T) {
+	var p *Policy
+	if p.Allowed(RoleAdmin, "GET", "/v1/applications") {
+		t.Fatal("nil policy allowed a request")
+	}
+}
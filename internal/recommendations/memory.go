@@ -0,0 +1,124 @@
+package recommendations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// MemoryStore is an in-process Store for tests and local development.
+type MemoryStore struct {
+	mu       sync.Mutex
+	requests map[string]models.RecommendationRequest
+	// tokens maps each request's current token to its ID.
+	tokens map[string]string
+	now    func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{requests: map[string]models.RecommendationRequest{}, tokens: map[string]string{}, now: time.Now}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, req *models.RecommendationRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req.ID = store.NewID()
+	req.CreatedAt = s.now().UTC()
+	s.requests[req.ID] = *req
+	s.tokens[req.Token] = req.ID
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (*models.RecommendationRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &req, nil
+}
+
+// ByToken implements Store.
+func (s *MemoryStore) ByToken(_ context.Context, token string) (*models.RecommendationRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[s.tokens[token]]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &req, nil
+}
+
+// ListByApplication implements Store.
+func (s *MemoryStore) ListByApplication(_ context.Context, appID string) ([]models.RecommendationRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.RecommendationRequest{}
+	for _, req := range s.requests {
+		if req.ApplicationID == appID {
+			out = append(out, req)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Reissue implements Store.
+func (s *MemoryStore) Reissue(_ context.Context, id, token string, expiresAt, sentAt time.Time) (*models.RecommendationRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if req.Status == models.RecommendationSubmitted {
+		return nil, ErrSubmitted
+	}
+	delete(s.tokens, req.Token)
+	req.Token, req.ExpiresAt, req.SentAt, req.Status = token, expiresAt, sentAt, models.RecommendationPending
+	s.requests[id] = req
+	s.tokens[token] = id
+	return &req, nil
+}
+
+// Submit implements Store.
+func (s *MemoryStore) Submit(_ context.Context, token, documentID string, at time.Time) (*models.RecommendationRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[s.tokens[token]]
+	switch {
+	case !ok:
+		return nil, store.ErrNotFound
+	case req.Status == models.RecommendationSubmitted:
+		return nil, ErrSubmitted
+	case req.Status == models.RecommendationExpired || !at.Before(req.ExpiresAt):
+		return nil, ErrExpired
+	}
+	at = at.UTC()
+	req.Status, req.SubmittedAt, req.DocumentID = models.RecommendationSubmitted, &at, documentID
+	s.requests[req.ID] = req
+	return &req, nil
+}
+
+// ExpireDue implements Store.
+func (s *MemoryStore) ExpireDue(_ context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, req := range s.requests {
+		if req.Status == models.RecommendationPending && !now.Before(req.ExpiresAt) {
+			req.Status = models.RecommendationExpired
+			s.requests[id] = req
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,103 @@
+// Package recommendations tracks the letters applicants ask referees for.
+// Each request carries a random token, sent to the referee in an invite
+// link, that admits exactly one submission before the request expires;
+// re-sending the invite replaces the token, so an older link stops
+// working.
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+// DefaultTTL is how long an invite link stays valid.
+const DefaultTTL = 14 * 24 * time.Hour
+
+// DefaultExpiryInterval is how often an Expirer sweeps for lapsed
+// requests.
+const DefaultExpiryInterval = time.Minute
+
+var (
+	// ErrSubmitted is returned for a request whose letter is already in.
+	ErrSubmitted = errors.New("recommendations: letter already submitted")
+	// ErrExpired is returned when submitting to a request past its
+	// ExpiresAt.
+	ErrExpired = errors.New("recommendations: request expired")
+)
+
+// Store persists recommendation requests. Lookups of an unknown ID or
+// token return store.ErrNotFound.
+type Store interface {
+	// Create assigns req an ID and stores it.
+	Create(ctx context.Context, req *models.RecommendationRequest) error
+	Get(ctx context.Context, id string) (*models.RecommendationRequest, error)
+	// ByToken returns the request whose current token is token.
+	ByToken(ctx context.Context, token string) (*models.RecommendationRequest, error)
+	// ListByApplication returns an application's requests, oldest first.
+	ListByApplication(ctx context.Context, appID string) ([]models.RecommendationRequest, error)
+	// Reissue gives a pending or expired request a new token, expiry,
+	// and send time, making it pending; the old token no longer matches.
+	// It returns ErrSubmitted once the letter is in.
+	Reissue(ctx context.Context, id, token string, expiresAt, sentAt time.Time) (*models.RecommendationRequest, error)
+	// Submit records documentID as the letter for the request holding
+	// token, at time at. It succeeds once per token: afterwards it
+	// returns ErrSubmitted, and for a request expired by at, ErrExpired.
+	Submit(ctx context.Context, token, documentID string, at time.Time) (*models.RecommendationRequest, error)
+	// ExpireDue marks pending requests whose ExpiresAt is not after now as
+	// expired and reports how many it marked.
+	ExpireDue(ctx context.Context, now time.Time) (int, error)
+}
+
+// NewToken returns a random UUID for an invite link.
+func NewToken() string { return store.NewID() }
+
+// Expirer marks lapsed requests as expired every Interval. It is a
+// server.Worker. Submit refuses a lapsed token on its own, so the sweep
+// only keeps the stored status, which applicants see, current.
+type Expirer struct {
+	Store Store
+	// Clock defaults to clock.System and Interval to
+	// DefaultExpiryInterval.
+	Clock    clock.Clock
+	Interval time.Duration
+	// Logger, nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Run sweeps until ctx is done.
+func (e *Expirer) Run(ctx context.Context) {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultExpiryInterval
+	}
+	now := e.Clock
+	if now == nil {
+		now = clock.System
+	}
+	logger := e.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := e.Store.ExpireDue(ctx, now.Now())
+			switch {
+			case err != nil && ctx.Err() == nil:
+				logger.Error("expire recommendation requests", "error", err)
+			case n > 0:
+				logger.Info("recommendation requests expired", "count", n)
+			}
+		}
+	}
+}
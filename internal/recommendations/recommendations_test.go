@@ -0,0 +1,126 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/clock"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+var t0 = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func newRequest(t *testing.T, s Store, token string, expiresAt time.Time) *models.RecommendationRequest {
+	t.Helper()
+	req := &models.RecommendationRequest{ApplicationID: "app-1", RefereeEmail: "prof@example.edu", Token: token, Status: models.RecommendationPending, ExpiresAt: expiresAt, SentAt: t0}
+	if err := s.Create(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	req := newRequest(t, s, "tok-1", t0.Add(time.Hour))
+
+	got, err := s.Submit(ctx, "tok-1", "doc-1", t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.RecommendationSubmitted || got.DocumentID != "doc-1" || got.SubmittedAt == nil || !got.SubmittedAt.Equal(t0) {
+		t.Errorf("submitted = %+v", got)
+	}
+	if _, err := s.Submit(ctx, "tok-1", "doc-2", t0); !errors.Is(err, ErrSubmitted) {
+		t.Errorf("second submit: %v, want ErrSubmitted", err)
+	}
+	if _, err := s.Reissue(ctx, req.ID, "tok-2", t0.Add(time.Hour), t0); !errors.Is(err, ErrSubmitted) {
+		t.Errorf("reissue after submit: %v, want ErrSubmitted", err)
+	}
+	if stored, _ := s.Get(ctx, req.ID); stored.DocumentID != "doc-1" {
+		t.Errorf("letter replaced by %s", stored.DocumentID)
+	}
+	if _, err := s.Submit(ctx, "unknown", "doc-3", t0); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("unknown token: %v", err)
+	}
+}
+
+func TestReissueInvalidatesOldToken(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	req := newRequest(t, s, "old", t0.Add(time.Hour))
+
+	later := t0.Add(2 * time.Hour)
+	got, err := s.Reissue(ctx, req.ID, "new", later.Add(time.Hour), later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != "new" || !got.SentAt.Equal(later) || got.Status != models.RecommendationPending {
+		t.Errorf("reissued = %+v", got)
+	}
+	if _, err := s.ByToken(ctx, "old"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("old token still resolves: %v", err)
+	}
+	if _, err := s.Submit(ctx, "old", "doc-1", later); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("submit with the old token: %v", err)
+	}
+	if _, err := s.Submit(ctx, "new", "doc-1", later); err != nil {
+		t.Errorf("submit with the new token: %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	lapsed := newRequest(t, s, "lapsed", t0)
+	live := newRequest(t, s, "live", t0.Add(time.Hour))
+
+	// Submit refuses a lapsed token before any sweep.
+	if _, err := s.Submit(ctx, "lapsed", "doc-1", t0); !errors.Is(err, ErrExpired) {
+		t.Errorf("submit at ExpiresAt: %v, want ErrExpired", err)
+	}
+	if n, err := s.ExpireDue(ctx, t0); err != nil || n != 1 {
+		t.Fatalf("ExpireDue = %d, %v; want 1", n, err)
+	}
+	for id, want := range map[string]models.RecommendationStatus{lapsed.ID: models.RecommendationExpired, live.ID: models.RecommendationPending} {
+		if got, _ := s.Get(ctx, id); got.Status != want {
+			t.Errorf("%s: %s, want %s", got.Token, got.Status, want)
+		}
+	}
+
+	// Re-sending revives an expired request under a new token.
+	if _, err := s.Reissue(ctx, lapsed.ID, "revived", t0.Add(time.Hour), t0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Submit(ctx, "revived", "doc-1", t0); err != nil {
+		t.Errorf("submit after re-sending: %v", err)
+	}
+}
+
+func TestExpirerSweeps(t *testing.T) {
+	s := NewMemoryStore()
+	req := newRequest(t, s, "tok", t0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		(&Expirer{Store: s, Clock: clock.Fixed(t0), Interval: time.Millisecond}).Run(ctx)
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		if got, _ := s.Get(context.Background(), req.ID); got.Status == models.RecommendationExpired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("request not expired by the sweep")
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
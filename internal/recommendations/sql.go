@@ -0,0 +1,200 @@
+package recommendations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLStore keeps requests in the recommendation_requests table (see
+// prisma/schema.prisma), in transactions scoped to the context's tenant
+// (see store.Scoped). Tokens are unique, so a link resolves to the same
+// request on every replica.
+type SQLStore struct {
+	DB *sql.DB
+}
+
+const requestColumns = `id, application_id, referee_email, token, status, expires_at, created_at, sent_at, submitted_at, document_id`
+
+// Create implements Store.
+func (s SQLStore) Create(ctx context.Context, req *models.RecommendationRequest) error {
+	const stmt = `INSERT INTO recommendation_requests (id, application_id, referee_email, token, status, expires_at, created_at, sent_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	created := *req
+	created.ID = store.NewID()
+	created.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		_, err := tx.ExecContext(ctx, stmt, created.ID, created.ApplicationID, created.RefereeEmail, created.Token,
+			string(created.Status), created.ExpiresAt.UTC(), created.CreatedAt, created.SentAt.UTC())
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("recommendations: insert: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*req = created
+	return nil
+}
+
+// Get implements Store.
+func (s SQLStore) Get(ctx context.Context, id string) (*models.RecommendationRequest, error) {
+	return s.one(ctx, `SELECT `+requestColumns+` FROM recommendation_requests WHERE id = $1`, id)
+}
+
+// ByToken implements Store.
+func (s SQLStore) ByToken(ctx context.Context, token string) (*models.RecommendationRequest, error) {
+	return s.one(ctx, `SELECT `+requestColumns+` FROM recommendation_requests WHERE token = $1`, token)
+}
+
+// ListByApplication implements Store.
+func (s SQLStore) ListByApplication(ctx context.Context, appID string) ([]models.RecommendationRequest, error) {
+	const stmt = `SELECT ` + requestColumns + ` FROM recommendation_requests WHERE application_id = $1 ORDER BY created_at, id`
+	out := []models.RecommendationRequest{}
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		rows, err := tx.QueryContext(ctx, stmt, appID)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("recommendations: query: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			req, err := scanRequest(rows)
+			if err != nil {
+				return fmt.Errorf("recommendations: scan: %w", err)
+			}
+			out = append(out, *req)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("recommendations: query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reissue implements Store.
+func (s SQLStore) Reissue(ctx context.Context, id, token string, expiresAt, sentAt time.Time) (*models.RecommendationRequest, error) {
+	const stmt = `UPDATE recommendation_requests SET token = $2, expires_at = $3, sent_at = $4, status = 'pending'
+WHERE id = $1 AND status <> 'submitted'
+RETURNING ` + requestColumns
+	return s.change(ctx, stmt, []any{id, token, expiresAt.UTC(), sentAt.UTC()}, `id`, id, time.Time{})
+}
+
+// Submit implements Store. The conditional UPDATE takes the row's lock, so
+// of concurrent submissions with one token exactly one matches it.
+func (s SQLStore) Submit(ctx context.Context, token, documentID string, at time.Time) (*models.RecommendationRequest, error) {
+	const stmt = `UPDATE recommendation_requests SET status = 'submitted', submitted_at = $2, document_id = $3
+WHERE token = $1 AND status = 'pending' AND expires_at > $2
+RETURNING ` + requestColumns
+	at = at.UTC()
+	return s.change(ctx, stmt, []any{token, at, documentID}, `token`, token, at)
+}
+
+// ExpireDue implements Store.
+func (s SQLStore) ExpireDue(ctx context.Context, now time.Time) (int, error) {
+	const stmt = `UPDATE recommendation_requests SET status = 'expired' WHERE status = 'pending' AND expires_at <= $1`
+	var n int64
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		res, err := tx.ExecContext(ctx, stmt, now.UTC())
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("recommendations: expire: %w", err)
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+// change runs an UPDATE ... RETURNING of one request. When it matches no
+// row, the request is read again by column = key to tell why: missing,
+// already submitted, or, for a Submit at time at, expired.
+func (s SQLStore) change(ctx context.Context, stmt string, args []any, column, key string, at time.Time) (*models.RecommendationRequest, error) {
+	var req *models.RecommendationRequest
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		req, err = queryRequest(ctx, tx, stmt, args...)
+		if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		cur, err := queryRequest(ctx, tx, `SELECT `+requestColumns+` FROM recommendation_requests WHERE `+column+` = $1`, key)
+		switch {
+		case err != nil:
+			return err
+		case cur.Status == models.RecommendationSubmitted:
+			return ErrSubmitted
+		case !at.IsZero():
+			return ErrExpired
+		}
+		// The row changed between the two statements.
+		return store.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s SQLStore) one(ctx context.Context, stmt string, arg any) (*models.RecommendationRequest, error) {
+	var req *models.RecommendationRequest
+	err := store.Scoped(ctx, s.DB, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		req, err = queryRequest(ctx, tx, stmt, arg)
+		return err
+	})
+	return req, err
+}
+
+// queryRequest reads the request a statement returns, or store.ErrNotFound.
+func queryRequest(ctx context.Context, tx *sql.Tx, stmt string, args ...any) (*models.RecommendationRequest, error) {
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	req, err := scanRequest(tx.QueryRowContext(ctx, stmt, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("recommendations: query: %w", err)
+	}
+	return req, nil
+}
+
+// scanRequest reads one row of requestColumns.
+func scanRequest(row interface{ Scan(...any) error }) (*models.RecommendationRequest, error) {
+	var (
+		req         models.RecommendationRequest
+		status      string
+		submittedAt sql.NullTime
+		documentID  sql.NullString
+	)
+	err := row.Scan(&req.ID, &req.ApplicationID, &req.RefereeEmail, &req.Token, &status, &req.ExpiresAt, &req.CreatedAt, &req.SentAt, &submittedAt, &documentID)
+	if err != nil {
+		return nil, err
+	}
+	req.Status = models.RecommendationStatus(status)
+	req.DocumentID = documentID.String
+	if submittedAt.Valid {
+		t := submittedAt.Time
+		req.SubmittedAt = &t
+	}
+	return &req, nil
+}
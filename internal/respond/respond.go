@@ -0,0 +1,33 @@
+// Package respond writes JSON responses in the shape shared by the UniAssist
+// HTTP services: `{"error": "...", "code": "..."}` for failures.
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorBody is the JSON body returned for every non-2xx response.
+type ErrorBody struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Details any    `json:"details,omitempty"`
+}
+
+// JSON writes v as a JSON document with the given status code.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Error writes a structured error body.
+func Error(w http.ResponseWriter, status int, code, message string) {
+	JSON(w, status, ErrorBody{Error: message, Code: code})
+}
+
+// ErrorWithDetails writes a structured error body carrying extra details,
+// such as per-field validation failures.
+func ErrorWithDetails(w http.ResponseWriter, status int, code, message string, details any) {
+	JSON(w, status, ErrorBody{Error: message, Code: code, Details: details})
+}
@@ -0,0 +1,122 @@
+// Package router wires admissions API routes onto an http.ServeMux and
+// applies the authentication middleware per route.
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/ratelimit"
+)
+
+// Middleware wraps a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Router registers routes with an optional authentication middleware that
+// is applied to every route unless it opts out with SkipAuth.
+type Router struct {
+	mux  *http.ServeMux
+	auth Middleware
+	use  []Middleware
+}
+
+type routeOptions struct {
+	skipAuth bool
+	mws      []Middleware
+	limit    *ratelimit.Config
+}
+
+// Option customizes a single route.
+type Option func(*routeOptions)
+
+// SkipAuth marks a route as public, e.g. /health or /auth/refresh.
+func SkipAuth() Option {
+	return func(o *routeOptions) { o.skipAuth = true }
+}
+
+// With wraps a single route in mws, inside the authentication middleware
+// so they can rely on the caller's claims. The first is the outermost.
+func With(mws ...Middleware) Option {
+	return func(o *routeOptions) { o.mws = append(o.mws, mws...) }
+}
+
+// Limit gives a route its own rate-limit budget, read by
+// middleware.RateLimit in place of the default one.
+func Limit(cfg ratelimit.Config) Option {
+	return func(o *routeOptions) { o.limit = &cfg }
+}
+
+// New returns a Router. A nil auth middleware leaves all routes public.
+func New(auth Middleware) *Router {
+	return &Router{mux: http.NewServeMux(), auth: auth}
+}
+
+// Use adds middleware to every route registered after it, public or not.
+// It runs inside authentication, so on protected routes it sees the
+// caller's claims, and outside per-route With middleware.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.use = append(rt.use, mws...)
+}
+
+// Handle registers h for an http.ServeMux pattern such as
+// "GET /v1/applications/{id}".
+func (rt *Router) Handle(pattern string, h http.Handler, opts ...Option) {
+	var o routeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	mws := append(append([]Middleware{}, rt.use...), o.mws...)
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			h = mws[i](h)
+		}
+	}
+	if rt.auth != nil && !o.skipAuth {
+		h = rt.auth(h)
+	}
+	if o.limit != nil {
+		h = withLimit(h, *o.limit)
+	}
+	rt.mux.Handle(pattern, recordPattern(h, pattern))
+}
+
+type patternKey struct{}
+
+// CapturePattern returns a context in which the Router notes the pattern
+// of the route that serves the request, and a func returning it, or ""
+// if no route matched. Middleware wrapping the whole Router uses it for
+// low-cardinality labels. Nested calls share the outermost slot, so
+// several such middlewares can stack.
+func CapturePattern(ctx context.Context) (context.Context, func() string) {
+	if slot, ok := ctx.Value(patternKey{}).(*string); ok {
+		return ctx, func() string { return *slot }
+	}
+	slot := new(string)
+	return context.WithValue(ctx, patternKey{}, slot), func() string { return *slot }
+}
+
+func recordPattern(next http.Handler, pattern string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slot, ok := r.Context().Value(patternKey{}).(*string); ok {
+			*slot = pattern
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withLimit attaches cfg to requests before anything else runs.
+func withLimit(next http.Handler, cfg ratelimit.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ratelimit.WithConfig(r.Context(), cfg)))
+	})
+}
+
+// HandleFunc is the http.HandlerFunc form of Handle.
+func (rt *Router) HandleFunc(pattern string, h http.HandlerFunc, opts ...Option) {
+	rt.Handle(pattern, h, opts...)
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+)
+
+// Records lists every application a MemoryEngine scans;
+// *store.MemoryStore implements it.
+type Records interface {
+	All(ctx context.Context) ([]models.StudentApplication, error)
+}
+
+// MemoryEngine scans in-process records for tests and local development
+// without DATABASE_URL. Like PostgresEngine it requires every query token
+// to appear in the record; the score is the share of the record's tokens
+// that matched.
+type MemoryEngine struct {
+	Applications Records
+	// Profiles maps applicant IDs to profiles; it may be nil.
+	Profiles map[string]models.ApplicantProfile
+}
+
+// Search implements Engine.
+func (e MemoryEngine) Search(ctx context.Context, query string, filters Filters) ([]Result, error) {
+	apps, err := e.Applications.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms := tokens(query)
+	substr := strings.ToLower(strings.TrimSpace(query))
+	out := []Result{}
+	for _, app := range apps {
+		if !filters.allow(app) {
+			continue
+		}
+		r := Result{Application: app}
+		if p, ok := e.Profiles[app.ApplicantID]; ok {
+			r.Applicant = &p
+		}
+		fields := r.fields()
+		if len(terms) > 0 {
+			score, ok := rank(tokens(strings.Join(fields, " ")), terms)
+			if !ok {
+				continue
+			}
+			r.Score = score
+		} else if !slices.ContainsFunc(fields, func(f string) bool { return strings.Contains(strings.ToLower(f), substr) }) {
+			continue
+		}
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Application.SubmittedAt.Equal(b.Application.SubmittedAt) {
+			return a.Application.SubmittedAt.Before(b.Application.SubmittedAt)
+		}
+		return a.Application.ID < b.Application.ID
+	})
+	if n := filters.limit(); len(out) > n {
+		out = out[:n]
+	}
+	return out, nil
+}
+
+func (f Filters) allow(app models.StudentApplication) bool {
+	return (f.ApplicantID == "" || app.ApplicantID == f.ApplicantID) &&
+		(f.ProgramCode == "" || app.ProgramCode == f.ProgramCode) &&
+		(f.Status == "" || app.Status == f.Status)
+}
+
+// fields is the searchable text of r, mirroring searchDocument.
+func (r Result) fields() []string {
+	fields := []string{r.Application.ProgramCode, string(r.Application.Status)}
+	if r.Applicant != nil {
+		fields = append(fields, r.Applicant.FullName, r.Applicant.Email)
+	}
+	return fields
+}
+
+// rank reports whether every term occurs in doc and, if so, the share of
+// doc's tokens that are terms.
+func rank(doc, terms []string) (float64, bool) {
+	for _, t := range terms {
+		if !slices.Contains(doc, t) {
+			return 0, false
+		}
+	}
+	hits := 0
+	for _, d := range doc {
+		if slices.Contains(terms, d) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(doc)), true
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// PostgresEngine searches the student_applications and applicant_profiles
+// tables (see prisma/schema.prisma) with the "simple" text search
+// configuration, so names and program codes are matched without stemming.
+// It finds the applications store.SQLStore writes; pair a
+// store.MemoryStore with MemoryEngine instead.
+type PostgresEngine struct {
+	DB *sql.DB
+}
+
+// searchDocument is the text a row is matched against. It spans both
+// tables, so it is computed per row rather than indexed; the filters use
+// the btree indexes on student_applications.
+const searchDocument = `to_tsvector('simple', coalesce(p.full_name, '') || ' ' || coalesce(p.email, '')) ||
+	to_tsvector('simple', a.program_code || ' ' || a.status)`
+
+// Search implements Engine, over the rows of the context's tenant (see
+// store.Scoped).
+func (e PostgresEngine) Search(ctx context.Context, query string, filters Filters) ([]Result, error) {
+	var out []Result
+	err := store.Scoped(ctx, e.DB, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		out, err = search(ctx, tx, query, filters)
+		return err
+	})
+	return out, err
+}
+
+func search(ctx context.Context, tx *sql.Tx, query string, filters Filters) ([]Result, error) {
+	stmt, args := buildQuery(query, filters)
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("search: query: %w", err)
+	}
+	defer rows.Close()
+	out := []Result{}
+	for rows.Next() {
+		var (
+			r                      Result
+			round                  sql.NullString
+			profileID, name, email sql.NullString
+		)
+		a := &r.Application
+		if err := rows.Scan(&a.ID, &a.ApplicantID, &a.ProgramCode, &round, &a.Status, &a.SubmittedAt, &a.UpdatedAt,
+			&profileID, &name, &email, &r.Score); err != nil {
+			return nil, fmt.Errorf("search: scan: %w", err)
+		}
+		a.Round = round.String
+		if profileID.Valid {
+			r.Applicant = &models.ApplicantProfile{ID: profileID.String, FullName: name.String, Email: email.String}
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search: query: %w", err)
+	}
+	return out, nil
+}
+
+// buildQuery returns the statement and arguments for a search. Queries
+// with searchable tokens rank rows with ts_rank over plainto_tsquery;
+// anything else becomes an ILIKE scan with a score of 0.
+func buildQuery(query string, f Filters) (string, []any) {
+	var (
+		args  []any
+		where = []string{"a.deleted_at IS NULL"}
+		score = "0::float8"
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if len(tokens(query)) > 0 {
+		q := arg(query)
+		score = fmt.Sprintf("ts_rank(%s, plainto_tsquery('simple', %s))::float8", searchDocument, q)
+		where = append(where, fmt.Sprintf("(%s) @@ plainto_tsquery('simple', %s)", searchDocument, q))
+	} else if q := strings.TrimSpace(query); q != "" {
+		p := arg("%" + escapeLike(q) + "%")
+		where = append(where, fmt.Sprintf("(p.full_name ILIKE %[1]s OR p.email ILIKE %[1]s OR a.program_code ILIKE %[1]s OR a.status ILIKE %[1]s)", p))
+	}
+	if f.ApplicantID != "" {
+		where = append(where, "a.applicant_id = "+arg(f.ApplicantID))
+	}
+	if f.ProgramCode != "" {
+		where = append(where, "a.program_code = "+arg(f.ProgramCode))
+	}
+	if f.Status != "" {
+		where = append(where, "a.status = "+arg(string(f.Status)))
+	}
+
+	var b strings.Builder
+	b.WriteString(`SELECT a.id, a.applicant_id, a.program_code, a.round, a.status, a.submitted_at, a.updated_at,
+	p.id, p.full_name, p.email, ` + score + ` AS score
+FROM student_applications a
+LEFT JOIN applicant_profiles p ON p.id = a.applicant_id`)
+	b.WriteString("\nWHERE " + strings.Join(where, "\n  AND "))
+	b.WriteString("\nORDER BY score DESC, a.submitted_at, a.id\nLIMIT " + arg(f.limit()))
+	return b.String(), args
+}
+
+// escapeLike escapes the ILIKE wildcards in s so they match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,57 @@
+// Package search finds applications by applicant name, program, and status
+// for the /v1/search endpoint.
+package search
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+)
+
+// DefaultLimit caps the results when Filters.Limit is unset.
+const DefaultLimit = 50
+
+// Engine runs a free-text query over applications and their applicants.
+// Results are sorted by descending Score. A query with no searchable
+// tokens, such as "" or "  ", is not an error: it falls back to a plain
+// substring scan and so matches every record the filters allow.
+type Engine interface {
+	Search(ctx context.Context, query string, filters Filters) ([]Result, error)
+}
+
+// Filters narrow a search. Empty fields match everything.
+type Filters struct {
+	// ApplicantID restricts results to one applicant; the handler sets it
+	// to the caller for students.
+	ApplicantID string
+	ProgramCode string
+	Status      status.State
+	// Limit caps the number of results; 0 or less means DefaultLimit.
+	Limit int
+}
+
+func (f Filters) limit() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	return f.Limit
+}
+
+// Result is one matching application. Applicant is nil when no profile
+// exists for the application's applicant_id.
+type Result struct {
+	Application models.StudentApplication `json:"application"`
+	Applicant   *models.ApplicantProfile  `json:"applicant,omitempty"`
+	Score       float64                   `json:"score"`
+}
+
+// tokens splits q into lower-cased words the way the "simple" text search
+// configuration does, dropping punctuation.
+func tokens(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
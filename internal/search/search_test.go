@@ -0,0 +1,111 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/store"
+)
+
+func newEngine(t *testing.T) MemoryEngine {
+	t.Helper()
+	apps := store.NewMemoryStore()
+	for _, app := range []models.StudentApplication{
+		{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"},
+		{ApplicantID: "stu-1", ProgramCode: "EE", Status: "under_review"},
+		{ApplicantID: "stu-2", ProgramCode: "CS", Status: "accepted"},
+	} {
+		if err := apps.Create(context.Background(), &app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return MemoryEngine{Applications: apps, Profiles: map[string]models.ApplicantProfile{
+		"stu-1": {ID: "stu-1", FullName: "Ada Lovelace", Email: "ada@example.edu"},
+		"stu-2": {ID: "stu-2", FullName: "Alan Turing Lovelace"},
+	}}
+}
+
+func TestMemoryEngineRanksMatches(t *testing.T) {
+	res, err := newEngine(t).Search(context.Background(), "Lovelace", Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("got %d results, want 3", len(res))
+	}
+	for i := 1; i < len(res); i++ {
+		if res[i].Score > res[i-1].Score {
+			t.Errorf("results not sorted by score: %v then %v", res[i-1].Score, res[i].Score)
+		}
+	}
+	if res[0].Applicant.ID != "stu-2" {
+		t.Errorf("top result is %s, want stu-2, whose record has fewer tokens", res[0].Applicant.ID)
+	}
+
+	res, _ = newEngine(t).Search(context.Background(), "ada cs", Filters{})
+	if len(res) != 1 || res[0].Application.ProgramCode != "CS" || res[0].Applicant.ID != "stu-1" {
+		t.Errorf("every token must match, got %+v", res)
+	}
+}
+
+func TestMemoryEngineFilters(t *testing.T) {
+	e := newEngine(t)
+	res, _ := e.Search(context.Background(), "lovelace", Filters{ApplicantID: "stu-2"})
+	if len(res) != 1 || res[0].Application.ApplicantID != "stu-2" {
+		t.Errorf("applicant filter: %+v", res)
+	}
+	res, _ = e.Search(context.Background(), "", Filters{ProgramCode: "CS", Status: "accepted"})
+	if len(res) != 1 || res[0].Application.ApplicantID != "stu-2" {
+		t.Errorf("program/status filter: %+v", res)
+	}
+	res, _ = e.Search(context.Background(), "", Filters{Limit: 2})
+	if len(res) != 2 {
+		t.Errorf("limit: got %d results, want 2", len(res))
+	}
+}
+
+func TestMemoryEngineWithoutTokens(t *testing.T) {
+	for _, q := range []string{"", "   ", "\t\n"} {
+		res, err := newEngine(t).Search(context.Background(), q, Filters{})
+		if err != nil {
+			t.Fatalf("Search(%q): %v", q, err)
+		}
+		if len(res) != 3 {
+			t.Errorf("Search(%q) = %d results, want all 3", q, len(res))
+		}
+	}
+	res, err := newEngine(t).Search(context.Background(), "@", Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Errorf("punctuation-only query should substring-match stu-1's email, got %d results", len(res))
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	stmt, args := buildQuery("ada lovelace", Filters{ApplicantID: "stu-1", Status: "pending"})
+	for _, want := range []string{"plainto_tsquery('simple', $1)", "a.applicant_id = $2", "a.status = $3", "ORDER BY score DESC", "LIMIT $4"} {
+		if !strings.Contains(stmt, want) {
+			t.Errorf("statement missing %q:\n%s", want, stmt)
+		}
+	}
+	if len(args) != 4 || args[0] != "ada lovelace" || args[3] != DefaultLimit {
+		t.Errorf("args = %v", args)
+	}
+
+	stmt, args = buildQuery("  ", Filters{})
+	if strings.Contains(stmt, "tsquery") || !strings.Contains(stmt, "WHERE a.deleted_at IS NULL\nORDER BY") {
+		t.Errorf("whitespace query should scan live rows without another condition:\n%s", stmt)
+	}
+	if len(args) != 1 {
+		t.Errorf("args = %v", args)
+	}
+
+	stmt, args = buildQuery("%_", Filters{})
+	if !strings.Contains(stmt, "ILIKE $1") || args[0] != `%\%\_%` {
+		t.Errorf("got %q with %v", stmt, args)
+	}
+}
@@ -0,0 +1,94 @@
+// Package status encodes the legal lifecycle of a student application.
+package status
+
+import (
+	"fmt"
+	"slices"
+)
+
+// State is an application status.
+type State string
+
+// Application states.
+const (
+	Pending     State = "pending"
+	UnderReview State = "under_review"
+	Accepted    State = "accepted"
+	Rejected    State = "rejected"
+	Enrolled    State = "enrolled"
+	Appealed    State = "appealed"
+	Withdrawn   State = "withdrawn"
+)
+
+// ErrInvalidTransition reports an illegal status change.
+type ErrInvalidTransition struct {
+	From, To State
+}
+
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("status: invalid transition %s -> %s", e.From, e.To)
+}
+
+// Machine is a transition table. Self-transitions on known states are
+// always accepted as no-ops so repeated updates stay idempotent.
+type Machine struct {
+	edges map[State][]State
+}
+
+// NewMachine builds a machine from an adjacency list. Every state that
+// appears as a target must also be a key (possibly with no edges).
+func NewMachine(edges map[State][]State) *Machine {
+	for _, targets := range edges {
+		for _, to := range targets {
+			if _, ok := edges[to]; !ok {
+				panic(fmt.Sprintf("status: target state %q has no entry", to))
+			}
+		}
+	}
+	return &Machine{edges: edges}
+}
+
+var defaultMachine = NewMachine(map[State][]State{
+	Pending:     {UnderReview, Withdrawn},
+	UnderReview: {Accepted, Rejected, Withdrawn},
+	Accepted:    {Enrolled, Withdrawn},
+	Rejected:    {Appealed},
+	Appealed:    {UnderReview, Rejected},
+	Enrolled:    {},
+	Withdrawn:   {},
+})
+
+// Default returns the admissions lifecycle:
+//
+//	pending -> under_review -> accepted -> enrolled
+//	                        -> rejected -> appealed -> under_review | rejected
+//	pending, under_review, accepted -> withdrawn
+func Default() *Machine { return defaultMachine }
+
+// Known reports whether s is a state of the machine.
+func (m *Machine) Known(s State) bool {
+	_, ok := m.edges[s]
+	return ok
+}
+
+// States lists the machine's states in a stable order.
+func (m *Machine) States() []State {
+	out := make([]State, 0, len(m.edges))
+	for s := range m.edges {
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Transition returns nil if moving from → to is legal and an
+// *ErrInvalidTransition otherwise.
+func (m *Machine) Transition(from, to State) error {
+	if !m.Known(from) || !m.Known(to) {
+		return &ErrInvalidTransition{From: from, To: to}
+	}
+	if from == to || slices.Contains(m.edges[from], to) {
+		return nil
+	}
+	return &ErrInvalidTransition{From: from, To: to}
+}
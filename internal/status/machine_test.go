@@ -0,0 +1,60 @@
+package status
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultMachineEveryEdge(t *testing.T) {
+	legal := map[[2]State]bool{
+		{Pending, UnderReview}:   true,
+		{Pending, Withdrawn}:     true,
+		{UnderReview, Accepted}:  true,
+		{UnderReview, Rejected}:  true,
+		{UnderReview, Withdrawn}: true,
+		{Accepted, Enrolled}:     true,
+		{Accepted, Withdrawn}:    true,
+		{Rejected, Appealed}:     true,
+		{Appealed, UnderReview}:  true,
+		{Appealed, Rejected}:     true,
+	}
+	m := Default()
+	states := m.States()
+	if len(states) != 7 {
+		t.Fatalf("states = %v", states)
+	}
+	for _, from := range states {
+		for _, to := range states {
+			err := m.Transition(from, to)
+			want := from == to || legal[[2]State{from, to}]
+			if want && err != nil {
+				t.Errorf("%s -> %s rejected: %v", from, to, err)
+			}
+			if !want {
+				var invalid *ErrInvalidTransition
+				if !errors.As(err, &invalid) || invalid.From != from || invalid.To != to {
+					t.Errorf("%s -> %s = %v, want *ErrInvalidTransition", from, to, err)
+				}
+			}
+		}
+	}
+}
+
+func TestTransitionUnknownStates(t *testing.T) {
+	m := Default()
+	var invalid *ErrInvalidTransition
+	for _, pair := range [][2]State{{"reviewed", "reviewed"}, {Pending, "archived"}, {"", Pending}} {
+		if err := m.Transition(pair[0], pair[1]); !errors.As(err, &invalid) {
+			t.Errorf("Transition(%q, %q) = %v, want *ErrInvalidTransition", pair[0], pair[1], err)
+		}
+	}
+}
+
+func TestNewMachinePanicsOnDanglingTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewMachine accepted a target without an entry")
+		}
+	}()
+	NewMachine(map[State][]State{Pending: {Accepted}})
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+)
+
+// ErrInvalidCursor is returned for a ListOptions.Cursor that was not
+// produced by a previous List call.
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+// ListOptions selects one page of a listing ordered by submission time,
+// then ID. A non-empty Cursor continues after the record it names (keyset
+// pagination) and takes precedence over Offset.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// Filter selects applications across applicants for Query. Empty fields
+// match everything.
+type Filter struct {
+	ApplicantID string
+	ProgramCode string
+	Status      status.State
+}
+
+// Match reports whether app satisfies f.
+func (f Filter) Match(app models.StudentApplication) bool {
+	return (f.ApplicantID == "" || app.ApplicantID == f.ApplicantID) &&
+		(f.ProgramCode == "" || app.ProgramCode == f.ProgramCode) &&
+		(f.Status == "" || app.Status == f.Status)
+}
+
+// ListResult is one page of a listing. Total counts every matching record,
+// not just this page. NextCursor names the last record of the page when
+// HasMore is set.
+type ListResult struct {
+	Items      []models.StudentApplication
+	Total      int64
+	NextCursor string
+	HasMore    bool
+}
+
+// PaginationMode selects how clients page through listings. Keyset is
+// stable under concurrent inserts and cheap with an index on
+// (submitted_at, id); offset allows jumping to arbitrary pages.
+type PaginationMode string
+
+const (
+	PaginationKeyset PaginationMode = "keyset"
+	PaginationOffset PaginationMode = "offset"
+)
+
+// ParsePaginationMode parses a PAGINATION_MODE value; empty means keyset.
+func ParsePaginationMode(s string) (PaginationMode, error) {
+	switch m := PaginationMode(s); m {
+	case "":
+		return PaginationKeyset, nil
+	case PaginationKeyset, PaginationOffset:
+		return m, nil
+	}
+	return "", errors.New("store: pagination mode must be keyset or offset, got " + s)
+}
+
+// cursor is the keyset position of a record.
+type cursor struct {
+	submittedAt time.Time
+	id          string
+}
+
+func encodeCursor(app models.StudentApplication) string {
+	raw := app.SubmittedAt.UTC().Format(time.RFC3339Nano) + "|" + app.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return cursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{t, id}, nil
+}
+
+// after reports whether app sorts after c.
+func (c cursor) after(app models.StudentApplication) bool {
+	if !app.SubmittedAt.Equal(c.submittedAt) {
+		return app.SubmittedAt.After(c.submittedAt)
+	}
+	return app.ID > c.id
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// MemoryStore is an in-process ApplicationStore for tests and local
+// development without DATABASE_URL. It keeps tenants apart as SQLStore
+// does: a context scoped to a tenant sees only that tenant's
+// applications, as if the others did not exist.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	apps    map[string]models.StudentApplication
+	now     func() time.Time
+	machine *status.Machine
+}
+
+// NewMemoryStore returns an empty MemoryStore enforcing the default status
+// machine.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{apps: map[string]models.StudentApplication{}, now: time.Now, machine: status.Default()}
+}
+
+// Create assigns an ID, timestamps, and the context's tenant, and stores
+// app.
+func (s *MemoryStore) Create(ctx context.Context, app *models.StudentApplication) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	app.ID = NewID()
+	app.TenantID = tenant.ID(ctx)
+	app.SubmittedAt = now
+	app.UpdatedAt = now
+	s.apps[app.ID] = *app
+	return nil
+}
+
+// GetByID returns the application or ErrNotFound.
+func (s *MemoryStore) GetByID(ctx context.Context, id string, qopts ...QueryOption) (*models.StudentApplication, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app, ok := s.get(ctx, id)
+	if !ok || !ApplyQueryOptions(qopts...).Visible(app.DeletedAt) {
+		return nil, ErrNotFound
+	}
+	return &app, nil
+}
+
+// List returns a page of an applicant's applications, oldest first. A
+// Limit of 0 or less returns every remaining record.
+func (s *MemoryStore) List(ctx context.Context, applicantID string, opts ListOptions, qopts ...QueryOption) (*ListResult, error) {
+	return s.list(ctx, func(app models.StudentApplication) bool { return app.ApplicantID == applicantID }, opts, ApplyQueryOptions(qopts...))
+}
+
+// Query is List across applicants: a page of the live applications f
+// matches, oldest first.
+func (s *MemoryStore) Query(ctx context.Context, f Filter, opts ListOptions) (*ListResult, error) {
+	return s.list(ctx, f.Match, opts, QueryOptions{})
+}
+
+func (s *MemoryStore) list(ctx context.Context, match func(models.StudentApplication) bool, opts ListOptions, q QueryOptions) (*ListResult, error) {
+	var after *cursor
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+
+	s.mu.RLock()
+	all := []models.StudentApplication{}
+	for _, app := range s.apps {
+		if tenant.Allows(ctx, app.TenantID) && match(app) && q.Visible(app.DeletedAt) {
+			all = append(all, app)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].SubmittedAt.Equal(all[j].SubmittedAt) {
+			return all[i].SubmittedAt.Before(all[j].SubmittedAt)
+		}
+		return all[i].ID < all[j].ID
+	})
+
+	rest := all
+	switch {
+	case after != nil:
+		i := sort.Search(len(all), func(i int) bool { return after.after(all[i]) })
+		rest = all[i:]
+	case opts.Offset > 0:
+		rest = all[min(opts.Offset, len(all)):]
+	}
+	res := &ListResult{Items: rest, Total: int64(len(all))}
+	if opts.Limit > 0 && len(rest) > opts.Limit {
+		res.Items = rest[:opts.Limit]
+		res.HasMore = true
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1])
+	}
+	return res, nil
+}
+
+// Update replaces the mutable fields of an existing application.
+func (s *MemoryStore) Update(ctx context.Context, app *models.StudentApplication) error {
+	return s.UpdateFunc(ctx, app, nil)
+}
+
+// UpdateFunc is Update with a commit hook. fn runs under the store's write
+// lock, blocking other callers while it runs, and must not call back into s.
+func (s *MemoryStore) UpdateFunc(ctx context.Context, app *models.StudentApplication, fn func(*models.StudentApplication) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.get(ctx, app.ID)
+	if !ok || cur.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if err := s.machine.Transition(cur.Status, app.Status); err != nil {
+		return err
+	}
+	cur.ProgramCode = app.ProgramCode
+	cur.LetterKey = app.LetterKey
+	if cur.Status != app.Status {
+		cur.LetterKey = ""
+	}
+	cur.Status = app.Status
+	cur.UpdatedAt = s.now().UTC()
+	if fn != nil {
+		if err := fn(&cur); err != nil {
+			return err
+		}
+	}
+	s.apps[app.ID] = cur
+	*app = cur
+	return nil
+}
+
+// Delete soft-deletes an application.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.get(ctx, id)
+	if !ok || app.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := s.now().UTC()
+	app.DeletedAt = &now
+	s.apps[id] = app
+	return nil
+}
+
+// HardDelete removes an application, soft-deleted or not.
+func (s *MemoryStore) HardDelete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(ctx, id); !ok {
+		return ErrNotFound
+	}
+	delete(s.apps, id)
+	return nil
+}
+
+// All returns every live application of the context's tenant in no
+// particular order.
+func (s *MemoryStore) All(ctx context.Context) ([]models.StudentApplication, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]models.StudentApplication, 0, len(s.apps))
+	for _, app := range s.apps {
+		if app.DeletedAt == nil && tenant.Allows(ctx, app.TenantID) {
+			out = append(out, app)
+		}
+	}
+	return out, nil
+}
+
+// get returns the application with id if the context's tenant may see
+// it. s.mu must be held.
+func (s *MemoryStore) get(ctx context.Context, id string) (models.StudentApplication, bool) {
+	app, ok := s.apps[id]
+	return app, ok && tenant.Allows(ctx, app.TenantID)
+}
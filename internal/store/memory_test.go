@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// seed creates n applications for applicant, two per timestamp so paging
+// has to break ties on ID.
+func seed(t *testing.T, s *MemoryStore, applicant string, n int) {
+	t.Helper()
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		at := base.Add(time.Duration(i/2) * time.Minute)
+		s.now = func() time.Time { return at }
+		if err := s.Create(context.Background(), &models.StudentApplication{ApplicantID: applicant, ProgramCode: "CS"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(items []models.StudentApplication) []string {
+	out := make([]string, len(items))
+	for i, app := range items {
+		out[i] = app.ID
+	}
+	return out
+}
+
+func TestMemoryStoreListKeyset(t *testing.T) {
+	s := NewMemoryStore()
+	seed(t, s, "stu-1", 7)
+	seed(t, s, "stu-2", 3)
+	ctx := context.Background()
+
+	all, err := s.List(ctx, "stu-1", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Items) != 7 || all.Total != 7 || all.HasMore {
+		t.Fatalf("unpaged list = %d items, total %d, has_more %v", len(all.Items), all.Total, all.HasMore)
+	}
+
+	var got []string
+	opts := ListOptions{Limit: 3}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging did not terminate")
+		}
+		res, err := s.List(ctx, "stu-1", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Total != 7 {
+			t.Errorf("page total = %d, want 7", res.Total)
+		}
+		got = append(got, ids(res.Items)...)
+		if !res.HasMore {
+			if res.NextCursor != "" {
+				t.Error("last page has a next cursor")
+			}
+			break
+		}
+		opts.Cursor = res.NextCursor
+	}
+	if want := ids(all.Items); len(got) != len(want) {
+		t.Fatalf("paged ids = %v, want %v", got, want)
+	} else {
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("paged ids = %v, want %v", got, want)
+			}
+		}
+	}
+
+	// A new record sorting before the cursor does not shift later pages.
+	first, _ := s.List(ctx, "stu-1", ListOptions{Limit: 3})
+	s.now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	s.Create(ctx, &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS"})
+	second, _ := s.List(ctx, "stu-1", ListOptions{Limit: 3, Cursor: first.NextCursor})
+	if second.Items[0].ID != all.Items[3].ID {
+		t.Errorf("keyset page shifted after an earlier insert")
+	}
+}
+
+func TestMemoryStoreListOffset(t *testing.T) {
+	s := NewMemoryStore()
+	seed(t, s, "stu-1", 5)
+	all, _ := s.List(context.Background(), "stu-1", ListOptions{})
+	res, err := s.List(context.Background(), "stu-1", ListOptions{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 2 || res.Items[0].ID != all.Items[2].ID || !res.HasMore || res.Total != 5 {
+		t.Errorf("offset page = %+v", res)
+	}
+	res, _ = s.List(context.Background(), "stu-1", ListOptions{Limit: 2, Offset: 10})
+	if len(res.Items) != 0 || res.HasMore {
+		t.Errorf("offset past the end = %+v", res)
+	}
+}
+
+func TestMemoryStoreQuery(t *testing.T) {
+	s := NewMemoryStore()
+	seed(t, s, "stu-1", 3)
+	seed(t, s, "stu-2", 2)
+	ctx := context.Background()
+	s.Create(ctx, &models.StudentApplication{ApplicantID: "stu-2", ProgramCode: "EE", Status: status.Accepted})
+
+	for _, tc := range []struct {
+		f    Filter
+		want int
+	}{
+		{Filter{}, 6},
+		{Filter{ProgramCode: "CS"}, 5},
+		{Filter{ApplicantID: "stu-2"}, 3},
+		{Filter{ApplicantID: "stu-2", Status: status.Accepted}, 1},
+		{Filter{ProgramCode: "LAW"}, 0},
+	} {
+		res, err := s.Query(ctx, tc.f, ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Items) != tc.want || res.Total != int64(tc.want) {
+			t.Errorf("Query(%+v) = %d items, total %d, want %d", tc.f, len(res.Items), res.Total, tc.want)
+		}
+	}
+
+	first, _ := s.Query(ctx, Filter{ProgramCode: "CS"}, ListOptions{Limit: 4})
+	rest, err := s.Query(ctx, Filter{ProgramCode: "CS"}, ListOptions{Limit: 4, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.HasMore || len(rest.Items) != 1 || rest.HasMore {
+		t.Errorf("paged query = %d then %d items", len(first.Items), len(rest.Items))
+	}
+}
+
+func TestMemoryStoreListInvalidCursor(t *testing.T) {
+	s := NewMemoryStore()
+	for _, c := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "eHx5"} {
+		if _, err := s.List(context.Background(), "stu-1", ListOptions{Cursor: c}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+}
+
+func TestMemoryStoreUpdateFuncRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending}
+	if err := s.Create(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	next := *app
+	next.Status = status.UnderReview
+	if err := s.UpdateFunc(ctx, &next, func(a *models.StudentApplication) error {
+		if a.Status != status.UnderReview {
+			t.Errorf("hook saw status %s, want under_review", a.Status)
+		}
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("UpdateFunc = %v, want the hook's error", err)
+	}
+	got, _ := s.GetByID(ctx, app.ID)
+	if got.Status != status.Pending {
+		t.Errorf("status = %s, want the update rolled back", got.Status)
+	}
+}
+
+func TestMemoryStoreStatusChangeClearsLetter(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending}
+	if err := s.Create(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	app.LetterKey = "applications/x/letter.pdf"
+	if err := s.Update(ctx, app); err != nil || app.LetterKey == "" {
+		t.Fatalf("Update keeping the status: %v, letter key %q", err, app.LetterKey)
+	}
+	app.Status = status.UnderReview
+	if err := s.Update(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetByID(ctx, app.ID); got.LetterKey != "" {
+		t.Errorf("letter key = %q after a status change, want it cleared", got.LetterKey)
+	}
+}
+
+func TestMemoryStoreSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	seed(t, s, "stu-1", 3)
+	all, _ := s.List(ctx, "stu-1", ListOptions{})
+	gone := all.Items[1].ID
+	if err := s.Delete(ctx, gone); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetByID(ctx, gone); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID = %v, want ErrNotFound", err)
+	}
+	if got, err := s.GetByID(ctx, gone, WithDeleted()); err != nil || got.DeletedAt == nil {
+		t.Errorf("GetByID WithDeleted = %+v, %v; want the record with DeletedAt set", got, err)
+	}
+	if res, _ := s.List(ctx, "stu-1", ListOptions{}); res.Total != 2 || len(res.Items) != 2 {
+		t.Errorf("List = %d items of %d, want the deleted one left out", len(res.Items), res.Total)
+	}
+	if res, _ := s.List(ctx, "stu-1", ListOptions{}, WithDeleted()); res.Total != 3 {
+		t.Errorf("List WithDeleted total = %d, want 3", res.Total)
+	}
+	if res, _ := s.Query(ctx, Filter{}, ListOptions{}); res.Total != 2 {
+		t.Errorf("Query total = %d, want 2", res.Total)
+	}
+	if apps, _ := s.All(ctx); len(apps) != 2 {
+		t.Errorf("All = %d records, want 2", len(apps))
+	}
+	deleted, _ := s.GetByID(ctx, gone, WithDeleted())
+	if err := s.Update(ctx, deleted); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a deleted record = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, gone); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+
+	if err := s.HardDelete(ctx, gone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByID(ctx, gone, WithDeleted()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID WithDeleted after HardDelete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryUserStoreUpsertExternal(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryUserStore()
+	first := &models.User{Issuer: "https://sso.example.edu", Subject: "u123", Email: "ada@example.edu"}
+	if err := s.UpsertExternal(ctx, first, "student"); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.Role != "student" || first.CreatedAt.IsZero() {
+		t.Fatalf("new user = %+v", first)
+	}
+
+	again := &models.User{Issuer: first.Issuer, Subject: first.Subject, Email: "ada@cs.example.edu"}
+	if err := s.UpsertExternal(ctx, again, "advisor"); err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Role != "student" || again.Email != "ada@cs.example.edu" {
+		t.Errorf("second login = %+v, want the same student with the new email", again)
+	}
+
+	promoted := &models.User{Issuer: first.Issuer, Subject: first.Subject, Role: "advisor"}
+	s.UpsertExternal(ctx, promoted, "student")
+	other := &models.User{Issuer: "https://other.example.edu", Subject: "u123"}
+	s.UpsertExternal(ctx, other, "student")
+	if got, _ := s.GetByID(ctx, first.ID); got.Role != "advisor" || other.ID == first.ID {
+		t.Errorf("role from the provider: %+v; other issuer's user %s", got, other.ID)
+	}
+	if _, err := s.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID(missing) = %v", err)
+	}
+}
+
+func TestMemoryStoreScopesTenants(t *testing.T) {
+	s := NewMemoryStore()
+	north := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+	south := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "south"})
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: status.Pending}
+	if err := s.Create(north, app); err != nil {
+		t.Fatal(err)
+	}
+	if app.TenantID != "north" {
+		t.Errorf("TenantID = %q, want the context's", app.TenantID)
+	}
+	if _, err := s.GetByID(north, app.ID); err != nil {
+		t.Errorf("own tenant: %v", err)
+	}
+
+	// To another tenant the application does not exist.
+	if _, err := s.GetByID(south, app.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID from another tenant: %v", err)
+	}
+	if res, _ := s.List(south, "stu-1", ListOptions{}); len(res.Items) != 0 {
+		t.Errorf("List from another tenant = %v", ids(res.Items))
+	}
+	if err := s.Update(south, &models.StudentApplication{ID: app.ID, ProgramCode: "EE", Status: status.Pending}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update from another tenant: %v", err)
+	}
+	if err := s.Delete(south, app.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete from another tenant: %v", err)
+	}
+
+	// An unscoped context, as workers use, sees every tenant.
+	if res, _ := s.Query(context.Background(), Filter{}, ListOptions{}); len(res.Items) != 1 {
+		t.Errorf("unscoped Query = %v", ids(res.Items))
+	}
+}
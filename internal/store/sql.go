@@ -0,0 +1,329 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/status"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLStore keeps applications in the student_applications table (see
+// prisma/schema.prisma), running every call in a transaction scoped to
+// the context's tenant (see Scoped). Given a context that already carries
+// one, as audit.SQLRecorder.Atomic passes down, it joins it, so the audit
+// entry commits or rolls back with the write.
+type SQLStore struct {
+	db      *sql.DB
+	now     func() time.Time
+	machine *status.Machine
+}
+
+// NewSQLStore returns a SQLStore on db enforcing the default status
+// machine.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, now: time.Now, machine: status.Default()}
+}
+
+const applicationColumns = `id, applicant_id, program_code, round, status, submitted_at, updated_at, letter_key, deleted_at, tenant_id`
+
+const insertApplication = `INSERT INTO student_applications (id, applicant_id, program_code, round, status, submitted_at, updated_at, letter_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING tenant_id`
+
+// Create assigns an ID and timestamps and stores app, which takes the
+// context's tenant from the tenant_id default.
+func (s *SQLStore) Create(ctx context.Context, app *models.StudentApplication) error {
+	// The columns keep milliseconds, and cursors must match what is read
+	// back.
+	now := s.now().UTC().Truncate(time.Millisecond)
+	created := *app
+	created.ID = NewID()
+	created.SubmittedAt = now
+	created.UpdatedAt = now
+	err := Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, insertApplication)
+		defer span.End()
+		err := tx.QueryRowContext(ctx, insertApplication, created.ID, created.ApplicantID, created.ProgramCode,
+			nullString(created.Round), string(created.Status), created.SubmittedAt, created.UpdatedAt, nullString(created.LetterKey)).Scan(&created.TenantID)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("store: insert: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*app = created
+	return nil
+}
+
+// GetByID returns the application or ErrNotFound.
+func (s *SQLStore) GetByID(ctx context.Context, id string, qopts ...QueryOption) (*models.StudentApplication, error) {
+	var app *models.StudentApplication
+	err := Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		app, err = getApplication(ctx, tx, id, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ApplyQueryOptions(qopts...).Visible(app.DeletedAt) {
+		return nil, ErrNotFound
+	}
+	return app, nil
+}
+
+// List returns a page of an applicant's applications, oldest first. A
+// Limit of 0 or less returns every remaining record.
+func (s *SQLStore) List(ctx context.Context, applicantID string, opts ListOptions, qopts ...QueryOption) (*ListResult, error) {
+	return s.list(ctx, Filter{ApplicantID: applicantID}, opts, ApplyQueryOptions(qopts...))
+}
+
+// Query is List across applicants: a page of the live applications f
+// matches, oldest first.
+func (s *SQLStore) Query(ctx context.Context, f Filter, opts ListOptions) (*ListResult, error) {
+	return s.list(ctx, f, opts, QueryOptions{})
+}
+
+func (s *SQLStore) list(ctx context.Context, f Filter, opts ListOptions, q QueryOptions) (*ListResult, error) {
+	var after *cursor
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+	count, page := buildList(f, q, opts, after)
+	res := &ListResult{Items: []models.StudentApplication{}}
+	err := Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := countApplications(ctx, tx, count, &res.Total); err != nil {
+			return err
+		}
+		ctx, span := tracing.StartQuery(ctx, page.stmt)
+		defer span.End()
+		rows, err := tx.QueryContext(ctx, page.stmt, page.args...)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("store: query: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			app, err := scanApplication(rows)
+			if err != nil {
+				return err
+			}
+			res.Items = append(res.Items, *app)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("store: query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The page asks for one record more than Limit to learn whether
+	// there are more.
+	if opts.Limit > 0 && len(res.Items) > opts.Limit {
+		res.Items = res.Items[:opts.Limit]
+		res.HasMore = true
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1])
+	}
+	return res, nil
+}
+
+func countApplications(ctx context.Context, tx *sql.Tx, count statement, total *int64) error {
+	ctx, span := tracing.StartQuery(ctx, count.stmt)
+	defer span.End()
+	if err := tx.QueryRowContext(ctx, count.stmt, count.args...).Scan(total); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("store: count: %w", err)
+	}
+	return nil
+}
+
+// statement is a query and its arguments.
+type statement struct {
+	stmt string
+	args []any
+}
+
+// buildList returns the statement counting the applications f and q
+// select and the one reading the page of them that opts and after pick,
+// ordered for the (submitted_at, id) keyset.
+func buildList(f Filter, q QueryOptions, opts ListOptions, after *cursor) (count, page statement) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ApplicantID != "" {
+		add("applicant_id = $%d", f.ApplicantID)
+	}
+	if f.ProgramCode != "" {
+		add("program_code = $%d", f.ProgramCode)
+	}
+	if f.Status != "" {
+		add("status = $%d", string(f.Status))
+	}
+	if !q.WithDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	count = statement{"SELECT count(*) FROM student_applications" + whereClause(where), args[:len(args):len(args)]}
+
+	if after != nil {
+		args = append(args, after.submittedAt, after.id)
+		where = append(where, fmt.Sprintf("(submitted_at, id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+	stmt := "SELECT " + applicationColumns + " FROM student_applications" + whereClause(where) + " ORDER BY submitted_at, id"
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit+1)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if after == nil && opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		stmt += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return count, statement{stmt, args}
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// Update replaces the mutable fields of an existing application.
+func (s *SQLStore) Update(ctx context.Context, app *models.StudentApplication) error {
+	return s.UpdateFunc(ctx, app, nil)
+}
+
+const updateApplication = `UPDATE student_applications SET program_code = $2, status = $3, letter_key = $4, updated_at = $5 WHERE id = $1`
+
+// UpdateFunc is Update with a commit hook. fn runs inside the transaction,
+// with the row locked until it commits.
+func (s *SQLStore) UpdateFunc(ctx context.Context, app *models.StudentApplication, fn func(*models.StudentApplication) error) error {
+	var cur *models.StudentApplication
+	err := Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if cur, err = getApplication(ctx, tx, app.ID, " FOR UPDATE"); err != nil {
+			return err
+		}
+		if cur.DeletedAt != nil {
+			return ErrNotFound
+		}
+		if err := s.machine.Transition(cur.Status, app.Status); err != nil {
+			return err
+		}
+		cur.ProgramCode = app.ProgramCode
+		cur.LetterKey = app.LetterKey
+		if cur.Status != app.Status {
+			cur.LetterKey = ""
+		}
+		cur.Status = app.Status
+		cur.UpdatedAt = s.now().UTC().Truncate(time.Millisecond)
+		if fn != nil {
+			if err := fn(cur); err != nil {
+				return err
+			}
+		}
+		return execOne(ctx, tx, updateApplication, cur.ID, cur.ProgramCode, string(cur.Status), nullString(cur.LetterKey), cur.UpdatedAt)
+	})
+	if err != nil {
+		return err
+	}
+	*app = *cur
+	return nil
+}
+
+// Delete soft-deletes an application.
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	const stmt = `UPDATE student_applications SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	return Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return execOne(ctx, tx, stmt, id, s.now().UTC().Truncate(time.Millisecond))
+	})
+}
+
+// HardDelete removes an application, soft-deleted or not.
+func (s *SQLStore) HardDelete(ctx context.Context, id string) error {
+	const stmt = `DELETE FROM student_applications WHERE id = $1`
+	return Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return execOne(ctx, tx, stmt, id)
+	})
+}
+
+// getApplication reads one application, soft-deleted or not, appending suffix to the
+// statement.
+func getApplication(ctx context.Context, tx *sql.Tx, id, suffix string) (*models.StudentApplication, error) {
+	stmt := `SELECT ` + applicationColumns + ` FROM student_applications WHERE id = $1` + suffix
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	app, err := scanApplication(tx.QueryRowContext(ctx, stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return app, err
+}
+
+// execOne runs a statement changing one row, returning ErrNotFound when it
+// changed none.
+func execOne(ctx context.Context, tx *sql.Tx, stmt string, args ...any) error {
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	res, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("store: exec: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanApplication reads one row of applicationColumns. sql.ErrNoRows is
+// returned as is.
+func scanApplication(row interface{ Scan(...any) error }) (*models.StudentApplication, error) {
+	var (
+		app              models.StudentApplication
+		state            string
+		round, letterKey sql.NullString
+		deletedAt        sql.NullTime
+	)
+	err := row.Scan(&app.ID, &app.ApplicantID, &app.ProgramCode, &round, &state, &app.SubmittedAt, &app.UpdatedAt, &letterKey, &deletedAt, &app.TenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: scan: %w", err)
+	}
+	app.Status = status.State(state)
+	app.Round = round.String
+	app.LetterKey = letterKey.String
+	if deletedAt.Valid {
+		t := deletedAt.Time
+		app.DeletedAt = &t
+	}
+	return &app, nil
+}
+
+// nullString stores an empty s as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+func TestBuildList(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	count, page := buildList(Filter{ApplicantID: "stu-1", Status: "pending"}, QueryOptions{}, ListOptions{Limit: 10}, &cursor{at, "app-1"})
+	if count.stmt != "SELECT count(*) FROM student_applications WHERE applicant_id = $1 AND status = $2 AND deleted_at IS NULL" || len(count.args) != 2 {
+		t.Errorf("count = %s, args = %v", count.stmt, count.args)
+	}
+	if !strings.HasSuffix(page.stmt, " WHERE applicant_id = $1 AND status = $2 AND deleted_at IS NULL AND (submitted_at, id) > ($3, $4) ORDER BY submitted_at, id LIMIT $5") ||
+		len(page.args) != 5 || page.args[4] != 11 {
+		t.Errorf("page = %s, args = %v", page.stmt, page.args)
+	}
+
+	// Offsets page without a cursor; WithDeleted drops the only filter.
+	count, page = buildList(Filter{}, QueryOptions{WithDeleted: true}, ListOptions{Offset: 20}, nil)
+	if count.stmt != "SELECT count(*) FROM student_applications" || len(count.args) != 0 {
+		t.Errorf("unfiltered count = %s, args = %v", count.stmt, count.args)
+	}
+	if !strings.HasSuffix(page.stmt, " FROM student_applications ORDER BY submitted_at, id OFFSET $1") || len(page.args) != 1 {
+		t.Errorf("offset page = %s, args = %v", page.stmt, page.args)
+	}
+}
+
+func TestSQLStoreDeleteScoped(t *testing.T) {
+	d := &logDB{}
+	db := sql.OpenDB(d)
+	defer db.Close()
+	s := NewSQLStore(db)
+	at := time.Date(2026, 3, 1, 9, 0, 0, 123456789, time.UTC)
+	s.now = func() time.Time { return at }
+	north := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+
+	// logDB changes no rows, so the delete finds nothing and rolls back.
+	if err := s.Delete(north, "app-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete = %v, want ErrNotFound", err)
+	}
+	want := "BEGIN; " + setTenant + " [north]; UPDATE student_applications SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL [app-1] [" +
+		at.Truncate(time.Millisecond).String() + "]; ROLLBACK"
+	if got := strings.Join(d.log, "; "); got != want {
+		t.Errorf("log = %s", got)
+	}
+
+	// A caller's transaction is joined, not committed.
+	d.log = nil
+	err := Scoped(north, db, func(ctx context.Context, _ *sql.Tx) error {
+		s.HardDelete(ctx, "app-1")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "BEGIN; " + setTenant + " [north]; " + setTenant + " [north]; DELETE FROM student_applications WHERE id = $1 [app-1]; COMMIT"
+	if got := strings.Join(d.log, "; "); got != want {
+		t.Errorf("joined log = %s", got)
+	}
+}
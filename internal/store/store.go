@@ -0,0 +1,80 @@
+// Package store persists admissions domain records.
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errors.New("store: not found")
+
+// ApplicationStore persists student applications. Update must check the
+// status change against the status machine and return its
+// *status.ErrInvalidTransition without persisting anything. A status
+// change clears LetterKey, since the letter no longer matches it.
+//
+// UpdateFunc is Update with a commit hook: fn sees the record as it will be
+// stored and the write only becomes visible if fn returns nil; otherwise
+// nothing is persisted and fn's error is returned. A nil fn makes it
+// Update.
+//
+// Delete is a soft delete: it sets DeletedAt, after which GetByID and List
+// skip the record unless given WithDeleted, and Update, UpdateFunc, and
+// Delete return ErrNotFound for it. HardDelete removes a record, deleted
+// or not, for good.
+type ApplicationStore interface {
+	Create(ctx context.Context, app *models.StudentApplication) error
+	GetByID(ctx context.Context, id string, qopts ...QueryOption) (*models.StudentApplication, error)
+	List(ctx context.Context, applicantID string, opts ListOptions, qopts ...QueryOption) (*ListResult, error)
+	Update(ctx context.Context, app *models.StudentApplication) error
+	UpdateFunc(ctx context.Context, app *models.StudentApplication, fn func(*models.StudentApplication) error) error
+	Delete(ctx context.Context, id string) error
+	HardDelete(ctx context.Context, id string) error
+}
+
+// QueryOption adjusts which records a read returns.
+type QueryOption func(*QueryOptions)
+
+// QueryOptions is a set of QueryOption applied; see ApplyQueryOptions.
+type QueryOptions struct {
+	// WithDeleted includes soft-deleted records.
+	WithDeleted bool
+}
+
+// WithDeleted makes a read include soft-deleted records.
+func WithDeleted() QueryOption {
+	return func(o *QueryOptions) { o.WithDeleted = true }
+}
+
+// ApplyQueryOptions collects opts for a store implementation to read.
+func ApplyQueryOptions(opts ...QueryOption) QueryOptions {
+	var o QueryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Visible reports whether a record deleted at deletedAt, nil if it is
+// live, is returned under o.
+func (o QueryOptions) Visible(deletedAt *time.Time) bool {
+	return deletedAt == nil || o.WithDeleted
+}
+
+// NewID returns a random RFC 4122 version 4 UUID.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("store: crypto/rand failed: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
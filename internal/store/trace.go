@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// Traced wraps s so every call runs in its own span, a child of the
+// request's, named store.<Method> and carrying the application ID when
+// there is one. A SQL-backed s adds query spans below these.
+func Traced(s ApplicationStore, tracer trace.Tracer) ApplicationStore {
+	return tracedStore{s: s, tracer: tracer}
+}
+
+type tracedStore struct {
+	s      ApplicationStore
+	tracer trace.Tracer
+}
+
+func (t tracedStore) start(ctx context.Context, op, id string) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, "store."+op)
+	if id != "" {
+		span.SetAttributes(attribute.String("application.id", id))
+	}
+	return ctx, span
+}
+
+// endSpan records err, if any, and ends span. ErrNotFound is an answer,
+// not a failure.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+}
+
+func (t tracedStore) Create(ctx context.Context, app *models.StudentApplication) error {
+	ctx, span := t.start(ctx, "Create", "")
+	err := t.s.Create(ctx, app)
+	if err == nil {
+		span.SetAttributes(attribute.String("application.id", app.ID))
+	}
+	endSpan(span, err)
+	return err
+}
+
+func (t tracedStore) GetByID(ctx context.Context, id string, qopts ...QueryOption) (*models.StudentApplication, error) {
+	ctx, span := t.start(ctx, "GetByID", id)
+	app, err := t.s.GetByID(ctx, id, qopts...)
+	endSpan(span, err)
+	return app, err
+}
+
+func (t tracedStore) List(ctx context.Context, applicantID string, opts ListOptions, qopts ...QueryOption) (*ListResult, error) {
+	ctx, span := t.start(ctx, "List", "")
+	res, err := t.s.List(ctx, applicantID, opts, qopts...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t tracedStore) Update(ctx context.Context, app *models.StudentApplication) error {
+	ctx, span := t.start(ctx, "Update", app.ID)
+	err := t.s.Update(ctx, app)
+	endSpan(span, err)
+	return err
+}
+
+func (t tracedStore) UpdateFunc(ctx context.Context, app *models.StudentApplication, fn func(*models.StudentApplication) error) error {
+	ctx, span := t.start(ctx, "UpdateFunc", app.ID)
+	err := t.s.UpdateFunc(ctx, app, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (t tracedStore) Delete(ctx context.Context, id string) error {
+	ctx, span := t.start(ctx, "Delete", id)
+	err := t.s.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t tracedStore) HardDelete(ctx context.Context, id string) error {
+	ctx, span := t.start(ctx, "HardDelete", id)
+	err := t.s.HardDelete(ctx, id)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+)
+
+func TestTraced(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	s := Traced(NewMemoryStore(), tp.Tracer("test"))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}
+	if err := s.Create(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetByID: %v", err)
+	}
+	app.Status = "enrolled"
+	if err := s.Update(ctx, app); err == nil {
+		t.Fatal("invalid transition accepted")
+	}
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+	for i, want := range []string{"store.Create", "store.GetByID", "store.Update"} {
+		sp := spans[i]
+		if sp.Name() != want || sp.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d = %q (parent %v), want %s under the request", i, sp.Name(), sp.Parent().SpanID(), want)
+		}
+	}
+	if id := spans[0].Attributes(); len(id) != 1 || id[0].Value.AsString() != app.ID {
+		t.Errorf("Create attributes = %v", id)
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Error("not found marked as a failure")
+	}
+	if spans[2].Status().Code != codes.Error {
+		t.Error("rejected update not marked as a failure")
+	}
+}
+
+func TestTracedNoop(t *testing.T) {
+	s := Traced(NewMemoryStore(), noop.NewTracerProvider().Tracer(""))
+	app := &models.StudentApplication{ApplicantID: "stu-1", ProgramCode: "CS", Status: "pending"}
+	if err := s.Create(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetByID(context.Background(), app.ID); err != nil || got.ID != app.ID {
+		t.Fatalf("GetByID = %+v, %v", got, err)
+	}
+}
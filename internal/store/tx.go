@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+type txKey struct{}
+
+// WithTx returns a context carrying tx. A SQL-backed store given such a
+// context runs its statements on tx instead of its own connection, so the
+// caller can commit other writes, such as audit rows, with them or roll
+// everything back together.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction stored by WithTx, if any.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Settings read by the row level security policies on every tenant-owned
+// table (see the tenants migration): a row is visible when its tenant_id
+// is app.current_tenant, or to any session with app.cross_tenant on.
+const (
+	setTenant      = `SELECT set_config('app.current_tenant', $1, true)`
+	setCrossTenant = `SELECT set_config('app.cross_tenant', 'on', true)`
+)
+
+// Scoped runs fn on a transaction scoped to the tenant of ctx (see
+// internal/tenant), which the context fn is given also carries, as WithTx
+// does.
+// Row level security then limits every statement on it to that tenant's
+// rows, and inserts take the tenant as their tenant_id default. An
+// unscoped ctx, as background workers and the admin API use, sees every
+// tenant. The settings are transaction-local, so a pooled connection
+// never carries one tenant's scope into another's queries; a ctx already
+// carrying a transaction has the scope set on it, with fn's error
+// returned and committing left to its owner.
+func Scoped(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		if err := scope(ctx, tx); err != nil {
+			return err
+		}
+		return fn(ctx, tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin: %w", err)
+	}
+	if err := scope(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := fn(WithTx(ctx, tx), tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit: %w", err)
+	}
+	return nil
+}
+
+func scope(ctx context.Context, tx *sql.Tx) error {
+	var err error
+	if id := tenant.ID(ctx); id != "" {
+		_, err = tx.ExecContext(ctx, setTenant, id)
+	} else {
+		_, err = tx.ExecContext(ctx, setCrossTenant)
+	}
+	if err != nil {
+		return fmt.Errorf("store: set tenant: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/tenant"
+)
+
+// logDB is a database that only logs what it is asked to do.
+type logDB struct{ log []string }
+
+func (d *logDB) Connect(context.Context) (driver.Conn, error) { return logConn{d}, nil }
+func (d *logDB) Driver() driver.Driver                        { return nil }
+
+type logConn struct{ d *logDB }
+
+func (c logConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c logConn) Close() error                        { return nil }
+func (c logConn) Begin() (driver.Tx, error)           { c.d.log = append(c.d.log, "BEGIN"); return c, nil }
+func (c logConn) Commit() error                       { c.d.log = append(c.d.log, "COMMIT"); return nil }
+func (c logConn) Rollback() error                     { c.d.log = append(c.d.log, "ROLLBACK"); return nil }
+
+func (c logConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	for _, a := range args {
+		query += fmt.Sprintf(" [%v]", a.Value)
+	}
+	c.d.log = append(c.d.log, query)
+	return driver.RowsAffected(0), nil
+}
+
+func TestScoped(t *testing.T) {
+	d := &logDB{}
+	db := sql.OpenDB(d)
+	defer db.Close()
+	north := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "north"})
+
+	err := Scoped(north, db, func(ctx context.Context, tx *sql.Tx) error {
+		if got, _ := TxFromContext(ctx); got != tx {
+			t.Error("fn's context does not carry its transaction")
+		}
+		_, err := tx.ExecContext(ctx, "INSERT")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(d.log, "; "); got != "BEGIN; "+setTenant+" [north]; INSERT; COMMIT" {
+		t.Errorf("scoped: %s", got)
+	}
+
+	// Unscoped, as workers run, and failing.
+	d.log = nil
+	boom := errors.New("boom")
+	if err := Scoped(context.Background(), db, func(context.Context, *sql.Tx) error { return boom }); err != boom {
+		t.Errorf("err = %v, want fn's", err)
+	}
+	if got := strings.Join(d.log, "; "); got != "BEGIN; "+setCrossTenant+"; ROLLBACK" {
+		t.Errorf("unscoped: %s", got)
+	}
+}
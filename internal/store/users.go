@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/models"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// UserStore persists the users who sign in through an identity provider.
+//
+// UpsertExternal records a login by the provider account u.Issuer and
+// u.Subject, creating its user with a new ID on the first one. u.Email
+// and u.Name replace the stored ones; u.Role does too if set, and
+// otherwise an existing user keeps their role and a new one gets
+// defaultRole. u is filled in with the stored user.
+type UserStore interface {
+	UpsertExternal(ctx context.Context, u *models.User, defaultRole string) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+}
+
+// MemoryUserStore is an in-process UserStore for tests and local
+// development without DATABASE_URL.
+type MemoryUserStore struct {
+	mu       sync.RWMutex
+	users    map[string]models.User
+	external map[[2]string]string // issuer, subject -> ID
+	now      func() time.Time
+}
+
+// NewMemoryUserStore returns an empty MemoryUserStore.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[string]models.User{}, external: map[[2]string]string{}, now: time.Now}
+}
+
+// UpsertExternal implements UserStore.
+func (s *MemoryUserStore) UpsertExternal(_ context.Context, u *models.User, defaultRole string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	key := [2]string{u.Issuer, u.Subject}
+	cur, ok := s.users[s.external[key]]
+	if !ok {
+		cur = models.User{ID: NewID(), Issuer: u.Issuer, Subject: u.Subject, Role: defaultRole, CreatedAt: now}
+		s.external[key] = cur.ID
+	}
+	cur.Email, cur.Name, cur.LastLoginAt = u.Email, u.Name, now
+	if u.Role != "" {
+		cur.Role = u.Role
+	}
+	s.users[cur.ID] = cur
+	*u = cur
+	return nil
+}
+
+// GetByID returns the user or ErrNotFound.
+func (s *MemoryUserStore) GetByID(_ context.Context, id string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// SQLUserStore keeps users in the users table (see prisma/schema.prisma),
+// in transactions scoped to the context's tenant (see Scoped), so a
+// provider account is one user per tenant on every replica.
+type SQLUserStore struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLUserStore returns a SQLUserStore on db.
+func NewSQLUserStore(db *sql.DB) *SQLUserStore {
+	return &SQLUserStore{db: db, now: time.Now}
+}
+
+const userColumns = `id, issuer, subject, email, name, role, created_at, last_login_at`
+
+// UpsertExternal implements UserStore in one statement, so concurrent
+// first logins by an account create a single user.
+func (s *SQLUserStore) UpsertExternal(ctx context.Context, u *models.User, defaultRole string) error {
+	const stmt = `INSERT INTO users (id, issuer, subject, email, name, role, created_at, last_login_at)
+VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), $7), $8, $8)
+ON CONFLICT (tenant_id, issuer, subject) DO UPDATE
+SET email = EXCLUDED.email, name = EXCLUDED.name, last_login_at = EXCLUDED.last_login_at,
+    role = COALESCE(NULLIF($6, ''), users.role)
+RETURNING ` + userColumns
+	now := s.now().UTC().Truncate(time.Millisecond)
+	return Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		got, err := scanUser(tx.QueryRowContext(ctx, stmt, NewID(), u.Issuer, u.Subject, nullString(u.Email), nullString(u.Name), u.Role, defaultRole, now))
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("store: upsert user: %w", err)
+		}
+		*u = *got
+		return nil
+	})
+}
+
+// GetByID returns the user or ErrNotFound.
+func (s *SQLUserStore) GetByID(ctx context.Context, id string) (*models.User, error) {
+	const stmt = `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	var u *models.User
+	err := Scoped(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		ctx, span := tracing.StartQuery(ctx, stmt)
+		defer span.End()
+		var err error
+		u, err = scanUser(tx.QueryRowContext(ctx, stmt, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("store: get user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	var (
+		u           models.User
+		email, name sql.NullString
+	)
+	if err := row.Scan(&u.ID, &u.Issuer, &u.Subject, &email, &name, &u.Role, &u.CreatedAt, &u.LastLoginAt); err != nil {
+		return nil, err
+	}
+	u.Email, u.Name = email.String, name.String
+	return &u, nil
+}
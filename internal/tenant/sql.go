@@ -0,0 +1,77 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/tracing"
+)
+
+// SQLStore reads the tenants table (see prisma/schema.prisma), which row
+// level security does not cover: resolving a tenant comes before scoping
+// to it.
+type SQLStore struct {
+	DB *sql.DB
+}
+
+const selectTenant = `SELECT id, name, coalesce(domain, ''), config FROM tenants`
+
+// Get implements Store.
+func (s SQLStore) Get(ctx context.Context, id string) (*Tenant, error) {
+	return s.one(ctx, selectTenant+` WHERE id = $1`, id)
+}
+
+// ByDomain implements Store.
+func (s SQLStore) ByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	return s.one(ctx, selectTenant+` WHERE domain = lower($1)`, domain)
+}
+
+func (s SQLStore) one(ctx context.Context, stmt string, arg string) (*Tenant, error) {
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	var (
+		t   Tenant
+		cfg []byte
+	)
+	err := s.DB.QueryRowContext(ctx, stmt, arg).Scan(&t.ID, &t.Name, &t.Domain, &cfg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknown
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("tenant: query: %w", err)
+	}
+	t.Config = cfg
+	return &t, nil
+}
+
+// List implements Store, ordered by ID.
+func (s SQLStore) List(ctx context.Context) ([]Tenant, error) {
+	stmt := selectTenant + ` ORDER BY id`
+	ctx, span := tracing.StartQuery(ctx, stmt)
+	defer span.End()
+	rows, err := s.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("tenant: query: %w", err)
+	}
+	defer rows.Close()
+	out := []Tenant{}
+	for rows.Next() {
+		var (
+			t   Tenant
+			cfg []byte
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Domain, &cfg); err != nil {
+			return nil, fmt.Errorf("tenant: scan: %w", err)
+		}
+		t.Config = cfg
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tenant: query: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,138 @@
+// Package tenant identifies the university a request is served for. One
+// deployment serves several, each a Tenant found by the host it is
+// reached at; middleware.TenantResolver puts the request's Tenant in its
+// context, where the stores read it to keep each tenant's records apart.
+//
+// A context without a tenant is not scoped to one: background workers and
+// the admin API's cross-tenant queries run that way. Everything on the
+// public API runs with one, since TenantResolver rejects a request it
+// cannot place.
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknown is returned for a tenant ID or domain that is not
+// configured.
+var ErrUnknown = errors.New("tenant: unknown tenant")
+
+// DefaultID is the tenant that single-tenant deployments serve, and that
+// owns every record written before tenants were introduced.
+const DefaultID = "default"
+
+// Default is the tenant with DefaultID, as the tenants migration creates
+// it.
+var Default = Tenant{ID: DefaultID, Name: "Default"}
+
+// Tenant is one university served by the deployment.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Domain is the host its applicants reach the API at, in lowercase,
+	// such as apply.example.edu; empty for a tenant only reachable by
+	// X-Tenant-ID.
+	Domain string `json:"domain,omitempty"`
+	// Config holds per-tenant settings as a JSON object, stored as jsonb.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Store looks tenants up. Get and ByDomain return ErrUnknown for one that
+// does not exist; domains compare case-insensitively.
+type Store interface {
+	Get(ctx context.Context, id string) (*Tenant, error)
+	ByDomain(ctx context.Context, domain string) (*Tenant, error)
+	List(ctx context.Context) ([]Tenant, error)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context scoped to t.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant ctx is scoped to, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// ID returns the ID of the tenant ctx is scoped to, or "" for an unscoped
+// context.
+func ID(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+	return ""
+}
+
+// Allows reports whether a context scoped to ID(ctx) may see a record
+// owned by tenantID: an unscoped context sees every record.
+func Allows(ctx context.Context, tenantID string) bool {
+	id := ID(ctx)
+	return id == "" || id == tenantID
+}
+
+// MemoryStore is an in-process Store for tests and local development
+// without DATABASE_URL.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	tenants map[string]Tenant
+}
+
+// NewMemoryStore returns a MemoryStore holding ts.
+func NewMemoryStore(ts ...Tenant) *MemoryStore {
+	s := &MemoryStore{tenants: map[string]Tenant{}}
+	for _, t := range ts {
+		s.Put(t)
+	}
+	return s
+}
+
+// Put adds t, replacing the tenant with its ID.
+func (s *MemoryStore) Put(t Tenant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[t.ID] = t
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return nil, ErrUnknown
+	}
+	return &t, nil
+}
+
+// ByDomain implements Store.
+func (s *MemoryStore) ByDomain(_ context.Context, domain string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.tenants {
+		if t.Domain != "" && strings.EqualFold(t.Domain, domain) {
+			return &t, nil
+		}
+	}
+	return nil, ErrUnknown
+}
+
+// List implements Store, ordered by ID.
+func (s *MemoryStore) List(_ context.Context) ([]Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client limits.
+const (
+	// writeWait bounds writing one event or ping to a client.
+	writeWait = 10 * time.Second
+	// sendBuffer is how many events a client may fall behind by before
+	// the Hub disconnects it.
+	sendBuffer = 16
+)
+
+// Client is one WebSocket connection of a user. Its read loop answers the
+// browser's pings and notices it going away; its write loop sends events
+// and pings of its own. Clients have nothing to say, so data frames they
+// send are read and dropped.
+type Client struct {
+	userID string
+	// staffOf is the tenant whose staff channel the client is on, if it
+	// is an advisor's or admin's.
+	staffOf string
+	conn    *websocket.Conn
+	send    chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(userID string, conn *websocket.Conn) *Client {
+	return &Client{userID: userID, conn: conn, send: make(chan []byte, sendBuffer), done: make(chan struct{})}
+}
+
+// close tells the write loop to close the connection, which ends the read
+// loop too. Only the write loop closes it, so a close never waits behind
+// a write to a stalled client for longer than writeWait.
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// readLoop reads messages until the connection fails, closes, or goes
+// timeout without a frame. Pings and pongs are handled while it reads and
+// count as signs of life too; the connection still answers pings itself.
+func (c *Client) readLoop(timeout time.Duration) {
+	alive := func() { c.conn.SetReadDeadline(time.Now().Add(timeout)) }
+	alive()
+	c.conn.SetPongHandler(func(string) error {
+		alive()
+		return nil
+	})
+	pong := c.conn.PingHandler()
+	c.conn.SetPingHandler(func(data string) error {
+		alive()
+		return pong(data)
+	})
+	for {
+		_, msg, err := c.conn.NextReader()
+		if err != nil {
+			return
+		}
+		alive()
+		if _, err := io.Copy(io.Discard, msg); err != nil {
+			return
+		}
+	}
+}
+
+// writeLoop sends queued events and a ping every interval until close is
+// called or a write fails, then closes the connection, saying goodbye
+// first if it was asked to.
+func (c *Client) writeLoop(interval time.Duration) {
+	defer c.conn.Close()
+	ping := time.NewTicker(interval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
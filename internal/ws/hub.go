@@ -0,0 +1,278 @@
+// Package ws pushes events to users' browsers over WebSocket connections,
+// for instance application status changes to the applicants they concern.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/rbac"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+)
+
+// DefaultPingInterval is how often a Hub pings its clients. A client not
+// heard from, a pong included, for two intervals is disconnected.
+const DefaultPingInterval = 30 * time.Second
+
+// Event is one message pushed to a user, sent as a JSON text frame.
+type Event struct {
+	Type    string    `json:"type"`
+	Payload any       `json:"payload"`
+	At      time.Time `json:"at"`
+}
+
+// Publisher sends events to a user's open connections, or to those of a
+// tenant's staff; Hub implements it.
+type Publisher interface {
+	Publish(userID string, event Event) error
+	PublishStaff(tenantID string, event Event) error
+}
+
+// Hub holds the open connections of every user, any number per user for
+// the tabs they have open, and fans each event out to all of a user's.
+// Mount it at GET /v1/ws behind JWTAuth: the connection belongs to the
+// token's subject, and an advisor's or admin's also joins their tenant's
+// staff channel. Run it as a server worker so shutdown closes them.
+type Hub struct {
+	log          *slog.Logger
+	pingInterval time.Duration
+	origins      *cors.Rules
+	upgrader     websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[string]map[*Client]struct{}
+	staff   map[string]map[*Client]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// Option configures a Hub.
+type Option func(*Hub)
+
+// WithLogger sets the logger for dropped connections; nil means
+// slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(h *Hub) { h.log = l }
+}
+
+// WithPingInterval sets how often clients are pinged; zero means
+// DefaultPingInterval.
+func WithPingInterval(d time.Duration) Option {
+	return func(h *Hub) { h.pingInterval = d }
+}
+
+// WithAllowedOrigins admits connections from the origins rules allows,
+// the CORS allow-list, besides the API's own; nil admits no others.
+func WithAllowedOrigins(rules *cors.Rules) Option {
+	return func(h *Hub) { h.origins = rules }
+}
+
+// NewHub returns a Hub with no connections.
+func NewHub(opts ...Option) *Hub {
+	h := &Hub{clients: map[string]map[*Client]struct{}{}, staff: map[string]map[*Client]struct{}{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.log == nil {
+		h.log = slog.Default()
+	}
+	if h.pingInterval <= 0 {
+		h.pingInterval = DefaultPingInterval
+	}
+	h.upgrader = websocket.Upgrader{
+		// Browsers insist on the server selecting one of the offered
+		// subprotocols; only WebSocketTokenProtocol is, so the token
+		// offered after it is never echoed.
+		Subprotocols: []string{middleware.WebSocketTokenProtocol},
+		CheckOrigin:  h.checkOrigin,
+		Error:        handshakeError,
+	}
+	return h
+}
+
+// Publish implements Publisher. It does not wait for the event to be
+// written: a connection too far behind to take it is closed instead, and
+// the browser is left to reconnect and reload. An event without At is
+// stamped with the current time. Publishing to a user with no open
+// connections is not an error.
+func (h *Hub) Publish(userID string, event Event) error {
+	return h.publish(h.clients, userID, event)
+}
+
+// PublishStaff implements Publisher like Publish, sending to the
+// connections of every advisor and admin of tenantID.
+func (h *Hub) PublishStaff(tenantID string, event Event) error {
+	return h.publish(h.staff, tenantID, event)
+}
+
+func (h *Hub) publish(channels map[string]map[*Client]struct{}, key string, event Event) error {
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ws: encode %s event: %w", event.Type, err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range channels[key] {
+		select {
+		case c.send <- msg:
+		default:
+			h.log.Warn("websocket client too slow, disconnecting", "user_id", c.userID, "event", event.Type)
+			c.close()
+		}
+	}
+	return nil
+}
+
+// Connections reports how many connections userID has open.
+func (h *Hub) Connections(userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients[userID])
+}
+
+// StaffConnections reports how many connections the staff of tenantID
+// have open.
+func (h *Hub) StaffConnections(tenantID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.staff[tenantID])
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection for the
+// caller. Browsers authenticate through the handshake's subprotocols, as
+// middleware.WebSocketTokenProtocol describes. A page may connect only
+// from the API's own origin or one the CORS allow-list admits (see
+// WithAllowedOrigins), so another site cannot use a token it got hold of
+// from its visitors' browsers; clients that send no Origin, which are not
+// browsers, are not checked.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || claims.Subject == "" {
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing bearer token")
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		respond.Error(w, http.StatusUpgradeRequired, "UPGRADE_REQUIRED", "connect with a WebSocket client")
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has answered the request already.
+		h.log.Debug("websocket handshake failed", "user_id", claims.Subject, "error", err)
+		return
+	}
+	c := newClient(claims.Subject, conn)
+	if claims.Role == rbac.RoleAdvisor || claims.Role == rbac.RoleAdmin {
+		c.staffOf = claims.Tenant()
+	}
+	h.serve(c)
+}
+
+// handshakeError answers a handshake the upgrader refused: a forbidden
+// Origin, or one missing what the protocol requires.
+func handshakeError(w http.ResponseWriter, _ *http.Request, status int, reason error) {
+	code := "INVALID_REQUEST"
+	if status == http.StatusForbidden {
+		code = "FORBIDDEN"
+	}
+	respond.Error(w, status, code, reason.Error())
+}
+
+// checkOrigin admits a handshake without an Origin header, or from the
+// API's own host, or from an origin h.origins allows.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.origins.Allows(origin)
+}
+
+// Run waits for ctx to end, then closes every connection and returns once
+// their goroutines have; later upgrades are closed at once. It implements
+// server.Worker.
+func (h *Hub) Run(ctx context.Context) {
+	<-ctx.Done()
+	h.mu.Lock()
+	h.closed = true
+	for _, conns := range h.clients {
+		for c := range conns {
+			c.close()
+		}
+	}
+	h.mu.Unlock()
+	h.wg.Wait()
+}
+
+// serve runs c until either side closes it.
+func (h *Hub) serve(c *Client) {
+	if !h.register(c) {
+		c.conn.Close()
+		return
+	}
+	defer h.wg.Done()
+	defer h.unregister(c)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.writeLoop(h.pingInterval)
+	}()
+	c.readLoop(2 * h.pingInterval)
+	c.close()
+	wg.Wait()
+}
+
+func (h *Hub) register(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.wg.Add(1)
+	join(h.clients, c.userID, c)
+	if c.staffOf != "" {
+		join(h.staff, c.staffOf, c)
+	}
+	return true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	leave(h.clients, c.userID, c)
+	if c.staffOf != "" {
+		leave(h.staff, c.staffOf, c)
+	}
+}
+
+func join(channels map[string]map[*Client]struct{}, key string, c *Client) {
+	if channels[key] == nil {
+		channels[key] = map[*Client]struct{}{}
+	}
+	channels[key][c] = struct{}{}
+}
+
+func leave(channels map[string]map[*Client]struct{}, key string, c *Client) {
+	delete(channels[key], c)
+	if len(channels[key]) == 0 {
+		delete(channels, key)
+	}
+}
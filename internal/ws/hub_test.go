@@ -0,0 +1,215 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/middleware"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+)
+
+const testSecret = "test-secret"
+
+func newTestHub(t *testing.T, opts ...Option) (*Hub, *httptest.Server) {
+	t.Helper()
+	hub := NewHub(opts...)
+	srv := httptest.NewServer(middleware.JWTAuth(testSecret)(hub))
+	t.Cleanup(srv.Close)
+	return hub, srv
+}
+
+// dial connects as userID the way a page served with the API does.
+func dial(t *testing.T, srv *httptest.Server, userID string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := dialFrom(t, srv, userID, srv.URL)
+	if err != nil {
+		t.Fatalf("dial as %s: %v", userID, err)
+	}
+	return conn
+}
+
+// dialFrom connects as userID the way a browser on a page from origin
+// does, with the access token offered as a subprotocol.
+func dialFrom(t *testing.T, srv *httptest.Server, userID, origin string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	pair, err := auth.NewIssuer(testSecret).Issue(userID, "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := websocket.Dialer{Subprotocols: []string{middleware.WebSocketTokenProtocol, pair.AccessToken}}
+	conn, resp, err := d.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", http.Header{"Origin": {origin}})
+	if err != nil {
+		return nil, resp, err
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, resp, nil
+}
+
+// waitFor polls cond for up to 2s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) (Event, error) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		return Event{}, err
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(msg), &e); err != nil {
+		t.Fatalf("event %q: %v", msg, err)
+	}
+	return e, nil
+}
+
+func TestHubFansOutToEveryTab(t *testing.T) {
+	hub, srv := newTestHub(t)
+	tab1, tab2 := dial(t, srv, "stu-1"), dial(t, srv, "stu-1")
+	other := dial(t, srv, "stu-2")
+	if got := tab1.Subprotocol(); got != middleware.WebSocketTokenProtocol {
+		t.Errorf("selected protocol %q, want %q", got, middleware.WebSocketTokenProtocol)
+	}
+	waitFor(t, "three connections", func() bool { return hub.Connections("stu-1") == 2 && hub.Connections("stu-2") == 1 })
+
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	if err := hub.Publish("stu-1", Event{Type: "application.status_changed", Payload: map[string]string{"to": "accepted"}, At: at}); err != nil {
+		t.Fatal(err)
+	}
+	for i, tab := range []*websocket.Conn{tab1, tab2} {
+		e, err := receive(t, tab)
+		if err != nil {
+			t.Fatalf("tab %d: %v", i+1, err)
+		}
+		if e.Type != "application.status_changed" || !e.At.Equal(at) || e.Payload.(map[string]any)["to"] != "accepted" {
+			t.Errorf("tab %d got %+v", i+1, e)
+		}
+	}
+	if e, err := receive(t, other); err == nil {
+		t.Errorf("another user got %+v", e)
+	}
+
+	if err := hub.Publish("stu-3", Event{Type: "ignored"}); err != nil {
+		t.Errorf("publish to a user with no connections: %v", err)
+	}
+	if err := hub.Publish("stu-1", Event{Type: "bad", Payload: func() {}}); err == nil {
+		t.Error("an unencodable payload published")
+	}
+}
+
+func TestHubDropsClosedAndSilentClients(t *testing.T) {
+	hub, srv := newTestHub(t, WithPingInterval(20*time.Millisecond))
+	conn := dial(t, srv, "stu-1")
+	waitFor(t, "the connection", func() bool { return hub.Connections("stu-1") == 1 })
+	conn.Close()
+	waitFor(t, "the closed tab to go", func() bool { return hub.Connections("stu-1") == 0 })
+
+	// A client that reads answers pings and outlives many intervals; one
+	// that has stopped is dropped for not answering them.
+	live := dial(t, srv, "stu-2")
+	dial(t, srv, "stu-3")
+	go func() {
+		for {
+			if _, _, err := live.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	waitFor(t, "the silent client to go", func() bool { return hub.Connections("stu-3") == 0 })
+	time.Sleep(100 * time.Millisecond)
+	if hub.Connections("stu-2") != 1 {
+		t.Error("a client answering pings was dropped")
+	}
+}
+
+func TestHubRunClosesConnections(t *testing.T) {
+	hub, srv := newTestHub(t)
+	conn := dial(t, srv, "stu-1")
+	waitFor(t, "the connection", func() bool { return hub.Connections("stu-1") == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+	if hub.Connections("stu-1") != 0 {
+		t.Error("connection still registered after Run returned")
+	}
+	if _, err := receive(t, conn); err == nil {
+		t.Error("connection still open after shutdown")
+	}
+
+	// Connections opened after shutdown are closed at once.
+	late := dial(t, srv, "stu-1")
+	if _, err := receive(t, late); err == nil {
+		t.Error("connection accepted after shutdown")
+	}
+}
+
+func TestHubRequiresUpgradeAndToken(t *testing.T) {
+	_, srv := newTestHub(t)
+	resp, err := http.Get(srv.URL + "/v1/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without a token: %d", resp.StatusCode)
+	}
+
+	pair, _ := auth.NewIssuer(testSecret).Issue("stu-1", "student")
+	req, _ := http.NewRequest("GET", srv.URL+"/v1/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("plain GET: %d, want 426", resp.StatusCode)
+	}
+}
+
+func TestHubChecksOrigin(t *testing.T) {
+	rules, err := cors.Compile(cors.Policy{AllowedOrigins: []string{"https://apply.example.edu"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub, srv := newTestHub(t, WithAllowedOrigins(rules))
+	for _, origin := range []string{srv.URL, "https://apply.example.edu", ""} {
+		if _, _, err := dialFrom(t, srv, "stu-1", origin); err != nil {
+			t.Errorf("from %q: %v", origin, err)
+		}
+	}
+	waitFor(t, "three connections", func() bool { return hub.Connections("stu-1") == 3 })
+
+	_, resp, err := dialFrom(t, srv, "stu-1", "https://evil.example.com")
+	if err == nil {
+		t.Fatal("connected from an origin off the allow-list")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("from another site: %v, want 403", resp)
+	}
+}
@@ -0,0 +1,150 @@
+package packaging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Runner executes external commands. Tests substitute a fake.
+type Runner interface {
+	Run(ctx context.Context, name string, args ...string) error
+}
+
+// ExecRunner runs commands with os/exec in Dir, streaming their output
+// and, when Stdin is set, their input.
+type ExecRunner struct {
+	Dir    string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run implements Runner.
+func (r ExecRunner) Run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = r.Dir
+	cmd.Stdin = r.Stdin
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// BuildSpec describes one image build.
+type BuildSpec struct {
+	Dockerfile string
+	Context    string
+	Image      string
+	// Platforms are buildx platforms such as linux/amd64. Empty builds for
+	// the host platform.
+	Platforms []string
+	Push      bool
+	// BuildArgs are passed as --build-arg, e.g. the BuildArgs stamp.
+	BuildArgs map[string]string
+	// Secrets and SSH are passed as --secret and --ssh, e.g.
+	// "id=netrc,src=/home/me/.netrc" and "default", for templates that
+	// fetch PrivateModules.
+	Secrets []string
+	SSH     []string
+}
+
+var platformRE = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+(/v[0-9]+)?$`)
+
+// Validate checks the spec before any command runs.
+func (s BuildSpec) Validate() error {
+	if s.Dockerfile == "" || s.Image == "" {
+		return fmt.Errorf("build: Dockerfile and image are required")
+	}
+	for _, p := range s.Platforms {
+		if !platformRE.MatchString(p) {
+			return fmt.Errorf("build: invalid platform %q (want os/arch, e.g. linux/arm64)", p)
+		}
+	}
+	if len(s.Platforms) > 1 && !s.Push {
+		return fmt.Errorf("build: a multi-platform image can only be exported by pushing it; add --push")
+	}
+	return nil
+}
+
+// BuildPlan returns the docker invocations for spec and any warnings about
+// degraded behavior. withBuildx reports whether `docker buildx` works on
+// this host; without it multi-platform builds fall back to the host
+// platform.
+func BuildPlan(spec BuildSpec, withBuildx bool) (cmds [][]string, warnings []string, err error) {
+	if err := spec.Validate(); err != nil {
+		return nil, nil, err
+	}
+	ctxDir := spec.Context
+	if ctxDir == "" {
+		ctxDir = "."
+	}
+	var buildArgs []string
+	keys := make([]string, 0, len(spec.BuildArgs))
+	for k := range spec.BuildArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buildArgs = append(buildArgs, "--build-arg", k+"="+spec.BuildArgs[k])
+	}
+	for _, secret := range spec.Secrets {
+		buildArgs = append(buildArgs, "--secret", secret)
+	}
+	for _, ssh := range spec.SSH {
+		buildArgs = append(buildArgs, "--ssh", ssh)
+	}
+	if len(spec.Platforms) > 0 && withBuildx {
+		args := []string{"docker", "buildx", "build", "--platform", strings.Join(spec.Platforms, ","), "-f", spec.Dockerfile, "-t", spec.Image}
+		args = append(args, buildArgs...)
+		if spec.Push {
+			args = append(args, "--push")
+		} else {
+			args = append(args, "--load")
+		}
+		return [][]string{append(args, ctxDir)}, nil, nil
+	}
+	if len(spec.Platforms) > 0 {
+		host := runtime.GOOS + "/" + runtime.GOARCH
+		if len(spec.Platforms) > 1 || spec.Platforms[0] != host {
+			warnings = append(warnings, fmt.Sprintf(
+				"docker buildx is not available; building %s for the host platform %s only instead of %s",
+				spec.Image, host, strings.Join(spec.Platforms, ",")))
+		}
+	}
+	build := append([]string{"docker", "build", "-f", spec.Dockerfile, "-t", spec.Image}, buildArgs...)
+	cmds = append(cmds, append(build, ctxDir))
+	if spec.Push {
+		cmds = append(cmds, []string{"docker", "push", spec.Image})
+	}
+	return cmds, warnings, nil
+}
+
+// Build runs the plan for spec, printing warnings to warn.
+func Build(ctx context.Context, r Runner, spec BuildSpec, warn io.Writer) error {
+	withBuildx := len(spec.Platforms) > 0 && r.Run(ctx, "docker", "buildx", "version") == nil
+	cmds, warnings, err := BuildPlan(spec, withBuildx)
+	if err != nil {
+		return err
+	}
+	if warn == nil {
+		warn = os.Stderr
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(warn, "warning: %s\n", w)
+	}
+	for _, c := range cmds {
+		if err := r.Run(ctx, c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeRunner struct {
+	calls   [][]string
+	noBuilx bool
+}
+
+func (f *fakeRunner) Run(_ context.Context, name string, args ...string) error {
+	call := append([]string{name}, args...)
+	f.calls = append(f.calls, call)
+	if f.noBuilx && len(args) > 0 && args[0] == "buildx" {
+		return errors.New("docker: 'buildx' is not a docker command")
+	}
+	return nil
+}
+
+func TestBuildMultiPlatformWithBuildx(t *testing.T) {
+	r := &fakeRunner{}
+	spec := BuildSpec{Dockerfile: "ops/packaging/services/billing.Dockerfile", Image: "reg/billing:v1", Platforms: []string{"linux/amd64", "linux/arm64"}, Push: true}
+	if err := Build(context.Background(), r, spec, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"docker", "buildx", "version"},
+		{"docker", "buildx", "build", "--platform", "linux/amd64,linux/arm64", "-f", spec.Dockerfile, "-t", "reg/billing:v1", "--push", "."},
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("calls = %v\nwant %v", r.calls, want)
+	}
+}
+
+func TestBuildFallsBackWithoutBuildx(t *testing.T) {
+	r := &fakeRunner{noBuilx: true}
+	var warn bytes.Buffer
+	spec := BuildSpec{Dockerfile: "billing.Dockerfile", Image: "reg/billing:v1", Platforms: []string{"linux/amd64", "linux/arm64"}, Push: true}
+	if err := Build(context.Background(), r, spec, &warn); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(warn.String(), "buildx is not available") {
+		t.Fatalf("missing fallback warning: %q", warn.String())
+	}
+	want := [][]string{
+		{"docker", "buildx", "version"},
+		{"docker", "build", "-f", "billing.Dockerfile", "-t", "reg/billing:v1", "."},
+		{"docker", "push", "reg/billing:v1"},
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("calls = %v\nwant %v", r.calls, want)
+	}
+}
+
+func TestBuildSpecValidate(t *testing.T) {
+	for _, spec := range []BuildSpec{
+		{Image: "reg/billing"},
+		{Dockerfile: "d", Image: "reg/billing", Platforms: []string{"arm64"}},
+		{Dockerfile: "d", Image: "reg/billing", Platforms: []string{"linux/amd64", "linux/arm64"}},
+	} {
+		if err := spec.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestBuildPlanBuildArgs(t *testing.T) {
+	spec := BuildSpec{Dockerfile: "d", Image: "billing:abc", BuildArgs: map[string]string{ArgVersion: "v1", ArgCommit: "abc"}}
+	cmds, _, err := BuildPlan(spec, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"docker", "build", "-f", "d", "-t", "billing:abc", "--build-arg", "COMMIT=abc", "--build-arg", "VERSION=v1", "."}
+	if !reflect.DeepEqual(cmds[0], want) {
+		t.Fatalf("cmd = %v\nwant %v", cmds[0], want)
+	}
+
+	spec = BuildSpec{Dockerfile: "d", Image: "billing:abc", Secrets: []string{"id=netrc,src=/home/me/.netrc"}, SSH: []string{"default"}}
+	cmds, _, err = BuildPlan(spec, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"docker", "build", "-f", "d", "-t", "billing:abc", "--secret", "id=netrc,src=/home/me/.netrc", "--ssh", "default", "."}
+	if !reflect.DeepEqual(cmds[0], want) {
+		t.Fatalf("cmd = %v\nwant %v", cmds[0], want)
+	}
+}
@@ -0,0 +1,129 @@
+package packaging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The shared builder image: a golang image with the repository's modules
+// already downloaded, built once per go.mod/go.sum and tagged by their
+// hash, so every service build on the host starts from it instead of
+// downloading the same modules again.
+const (
+	SharedBuilderRepo = "uniassist-go-builder"
+	// BuilderArg is the build arg naming the image the builder stage of a
+	// Dockerfile rendered with Vars.SharedBuilder starts from.
+	BuilderArg = "BUILDER"
+	// BuilderDir is where EnsureBuilder writes the builder's build
+	// context, relative to the repository root.
+	BuilderDir = "dist/go-builder"
+)
+
+// BuilderOptions configure RenderBuilder.
+type BuilderOptions struct {
+	// Base is the golang image to start from.
+	Base string
+	// BuildKit downloads through a cache mount kept across rebuilds.
+	BuildKit bool
+}
+
+// RenderBuilder renders the shared builder's Dockerfile from
+// templates/builder.Dockerfile.tmpl.
+func RenderBuilder(o BuilderOptions) ([]byte, error) {
+	if o.Base == "" {
+		return nil, errors.New("builder: a base image is required")
+	}
+	return execute("builder.Dockerfile.tmpl", o, pinner(nil))
+}
+
+// SharedBuilderBase returns the default of the BUILDER arg a Dockerfile
+// declares before its first FROM, and whether it declares one, i.e.
+// whether it was rendered with Vars.SharedBuilder.
+func SharedBuilderBase(dockerfile []byte) (string, bool) {
+	base := globalArgs(dockerfile)[BuilderArg]
+	return base, base != ""
+}
+
+// BuilderResult reports what EnsureBuilder did.
+type BuilderResult struct {
+	// Image is the builder image to pass as the BUILDER build arg.
+	Image string
+	// Built is set when the image was built rather than reused.
+	Built bool
+	// Took is how long building it took, or took when it was last built
+	// on this host, which a reuse saves; zero if that is not known.
+	Took time.Duration
+}
+
+// builderRecord is what EnsureBuilder keeps in BuilderDir/build.json.
+type builderRecord struct {
+	Image   string  `json:"image"`
+	Seconds float64 `json:"seconds"`
+}
+
+// EnsureBuilder makes sure the shared builder image for the repository at
+// root, starting from base, is in the local daemon, building it if not.
+// BuildKit, when `docker buildx` works, keeps the module cache across
+// rebuilds; without it the builder is built with plain `docker build` and
+// downloads every module again when go.sum changes.
+func EnsureBuilder(ctx context.Context, r Runner, root, base string) (BuilderResult, error) {
+	buildKit := r.Run(ctx, "docker", "buildx", "version") == nil
+	dockerfile, err := RenderBuilder(BuilderOptions{Base: base, BuildKit: buildKit})
+	if err != nil {
+		return BuilderResult{}, err
+	}
+	h := sha256.New()
+	h.Write(dockerfile)
+	mods := map[string][]byte{}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return BuilderResult{}, fmt.Errorf("builder: %w", err)
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(b))
+		h.Write(b)
+		mods[name] = b
+	}
+	res := BuilderResult{Image: SharedBuilderRepo + ":" + hex.EncodeToString(h.Sum(nil))[:12]}
+
+	dir := filepath.Join(root, BuilderDir)
+	record := filepath.Join(dir, "build.json")
+	if r.Run(ctx, "docker", "image", "inspect", "--format", "{{.Id}}", res.Image) == nil {
+		var rec builderRecord
+		if b, err := os.ReadFile(record); err == nil && json.Unmarshal(b, &rec) == nil && rec.Image == res.Image {
+			res.Took = time.Duration(rec.Seconds * float64(time.Second))
+		}
+		return res, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return BuilderResult{}, err
+	}
+	mods["Dockerfile"] = dockerfile
+	for name, b := range mods {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			return BuilderResult{}, err
+		}
+	}
+	args := []string{"build", "-f", filepath.Join(BuilderDir, "Dockerfile"), "-t", res.Image, BuilderDir}
+	if buildKit {
+		args = append([]string{"buildx", "build", "--load"}, args[1:]...)
+	}
+	start := time.Now()
+	if err := r.Run(ctx, "docker", args...); err != nil {
+		return BuilderResult{}, fmt.Errorf("builder: %w", err)
+	}
+	res.Built, res.Took = true, time.Since(start)
+	b, _ := json.Marshal(builderRecord{Image: res.Image, Seconds: res.Took.Seconds()})
+	if err := os.WriteFile(record, b, 0o644); err != nil {
+		return BuilderResult{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package packaging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// daemonRunner is a Runner whose daemon keeps the images built through it.
+type daemonRunner struct {
+	calls   [][]string
+	images  map[string]bool
+	noBuilx bool
+}
+
+func (d *daemonRunner) Run(_ context.Context, name string, args ...string) error {
+	d.calls = append(d.calls, append([]string{name}, args...))
+	switch {
+	case d.noBuilx && args[0] == "buildx":
+		return errors.New("docker: 'buildx' is not a docker command")
+	case args[0] == "image" && !d.images[args[len(args)-1]]:
+		return errors.New("Error: No such image")
+	case slices.Contains(args, "build"):
+		d.images[args[slices.Index(args, "-t")+1]] = true
+	}
+	return nil
+}
+
+func TestRenderBuilder(t *testing.T) {
+	out, err := RenderBuilder(BuilderOptions{Base: "golang:1.22-alpine", BuildKit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	for _, want := range []string{"# syntax=docker/dockerfile:1\n", "\nFROM golang:1.22-alpine\n", "--mount=type=cache,id=uniassist-gomod,target=/cache", "GOPROXY=file:///cache/cache/download"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("BuildKit builder missing %q:\n%s", want, out)
+		}
+	}
+	plain, err := RenderBuilder(BuilderOptions{Base: "golang:1.22-alpine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "--mount") || strings.Contains(string(plain), "syntax=") || !strings.Contains(string(plain), "\nRUN go mod download") {
+		t.Errorf("builder without BuildKit:\n%s", plain)
+	}
+	if _, err := RenderBuilder(BuilderOptions{}); err == nil {
+		t.Error("builder without a base rendered")
+	}
+}
+
+func TestEnsureBuilder(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("go.mod", "module example.com/app\n\ngo 1.22\n")
+	writeFile("go.sum", "")
+	r := &daemonRunner{images: map[string]bool{}}
+	ctx := context.Background()
+
+	first, err := EnsureBuilder(ctx, r, root, "golang:1.22-alpine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Built || !strings.HasPrefix(first.Image, SharedBuilderRepo+":") {
+		t.Fatalf("first call: %+v", first)
+	}
+	build := []string{"docker", "buildx", "build", "--load", "-f", filepath.Join(BuilderDir, "Dockerfile"), "-t", first.Image, BuilderDir}
+	if !reflect.DeepEqual(r.calls[len(r.calls)-1], build) {
+		t.Errorf("build = %v\nwant %v", r.calls[len(r.calls)-1], build)
+	}
+	for _, name := range []string{"Dockerfile", "go.mod", "go.sum", "build.json"} {
+		if _, err := os.Stat(filepath.Join(root, BuilderDir, name)); err != nil {
+			t.Errorf("builder context: %v", err)
+		}
+	}
+
+	// Unchanged modules reuse the image, reporting the time it took.
+	r.calls = nil
+	again, err := EnsureBuilder(ctx, r, root, "golang:1.22-alpine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Built || again.Image != first.Image || again.Took != first.Took {
+		t.Errorf("second call: %+v, want a reuse of %+v", again, first)
+	}
+	if slices.ContainsFunc(r.calls, func(c []string) bool { return slices.Contains(c, "build") }) {
+		t.Errorf("second call built: %v", r.calls)
+	}
+
+	// A go.sum change is a new builder, built without BuildKit here.
+	writeFile("go.sum", "example.com/dep v1.0.0 h1:abc=\n")
+	r.noBuilx = true
+	changed, err := EnsureBuilder(ctx, r, root, "golang:1.22-alpine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed.Built || changed.Image == first.Image {
+		t.Errorf("after a go.sum change: %+v", changed)
+	}
+	if last := r.calls[len(r.calls)-1]; last[1] != "build" {
+		t.Errorf("build without BuildKit = %v", last)
+	}
+	df, err := os.ReadFile(filepath.Join(root, BuilderDir, "Dockerfile"))
+	if err != nil || strings.Contains(string(df), "--mount") {
+		t.Errorf("Dockerfile without BuildKit: %v\n%s", err, df)
+	}
+}
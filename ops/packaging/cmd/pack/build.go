@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdBuild(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		repoName  = fs.String("repo", "", "image repository, e.g. registry.example.com/billing (default: service name)")
+		tag       = fs.String("tag", "", "image tag (default: derived from the commit and, for dirty trees, the context hash)")
+		platforms = fs.String("platforms", "", "comma-separated buildx platforms, e.g. linux/amd64,linux/arm64")
+		push      = fs.Bool("push", false, "push the image after building")
+		tagOnly   = fs.Bool("print-tag-only", false, "print the tag that would be built and exit without building")
+		root      = fs.String("root", ".", "repository root, or any directory below it")
+		buildCtx  = fs.String("context", ".", "build context, relative to the repository root")
+		netrc     = fs.String("netrc", "", "netrc file exposed as the netrc build secret for private Go modules")
+		ssh       = fs.Bool("ssh", false, "forward the SSH agent for private Go modules")
+		verify    = fs.Bool("verify", false, "check the image against the service's budget (see pack verify) before pushing it")
+		reqScan   = fs.Bool("require-scanner", false, "with --verify, fail when neither trivy nor grype is installed")
+		sbom      = fs.Bool("sbom", false, "write the image's SBOM to dist/<service>-sbom.json (see pack sbom), attaching it to the image when pushed")
+		shared    = fs.Bool("shared-builder", false, "start from the shared builder image, building it first if go.mod or go.sum changed (the service must set shared_builder: true)")
+	)
+	service, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if service == "" && fs.NArg() == 1 {
+		service = fs.Arg(0)
+	}
+	if service == "" {
+		fmt.Fprintln(stderr, "usage: pack build <service> [--repo name] [--tag tag] [--platforms list] [--push] [--print-tag-only] [--netrc file] [--ssh] [--verify [--require-scanner]] [--sbom] [--shared-builder]")
+		return 2
+	}
+	// Verifying the image and listing its packages both read it from the
+	// local daemon, so it is built there and pushed after.
+	local := *verify || *sbom
+	if local && len(splitList(*platforms)) > 1 {
+		fmt.Fprintln(stderr, "pack build: --verify and --sbom need the image in the local daemon, which a multi-platform build is not; build each platform separately")
+		return 2
+	}
+	// The builder image is only in the local daemon, which a
+	// multi-platform buildx builder cannot read from.
+	if *shared && len(splitList(*platforms)) > 1 {
+		fmt.Fprintln(stderr, "pack build: --shared-builder needs the builder in the local daemon, which a multi-platform build cannot use; build each platform separately")
+		return 2
+	}
+	if *repoName == "" {
+		*repoName = service
+	}
+
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack build: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dockerfile := packaging.DockerfilePath(service)
+	if *tag == "" {
+		*tag, err = packaging.ImageTag(ctx, filepath.Join(repo, dockerfile), filepath.Join(repo, *buildCtx))
+		if err != nil {
+			fmt.Fprintf(stderr, "pack build: %v (pass --tag to set one explicitly)\n", err)
+			return 1
+		}
+	}
+	if *tagOnly {
+		fmt.Fprintln(stdout, *tag)
+		return 0
+	}
+
+	// Source tarballs have no git metadata; their binaries report
+	// "unknown" rather than failing the build.
+	meta, err := packaging.ReadGitMetadata(ctx, repo)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: no version metadata (%v); stamping unknown\n", err)
+		meta = packaging.GitMetadata{}
+	}
+
+	image := *repoName + ":" + *tag
+	spec := packaging.BuildSpec{
+		Dockerfile: dockerfile,
+		Context:    *buildCtx,
+		Image:      image,
+		Platforms:  splitList(*platforms),
+		Push:       *push && !local,
+		BuildArgs:  packaging.BuildArgs(meta),
+	}
+	if *netrc != "" {
+		spec.Secrets = append(spec.Secrets, "id=netrc,src="+*netrc)
+	}
+	if *ssh {
+		spec.SSH = append(spec.SSH, "default")
+	}
+	// Docker's own output goes to stderr so stdout carries only the image
+	// reference for scripts to capture.
+	runner := packaging.ExecRunner{Dir: repo, Stdout: stderr, Stderr: stderr}
+	if *shared {
+		if err := useSharedBuilder(ctx, runner, repo, service, &spec, stderr); err != nil {
+			fmt.Fprintf(stderr, "pack build: %v\n", err)
+			return 1
+		}
+	}
+	if err := packaging.Build(ctx, runner, spec, stderr); err != nil {
+		fmt.Fprintf(stderr, "pack build: %v\n", err)
+		return 1
+	}
+	// A verified image is built without pushing, so one over budget never
+	// reaches the registry.
+	if *verify {
+		report, err := verifyImage(ctx, repo, service, image, *reqScan, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack build: %v\n", err)
+			return 1
+		}
+		writeVerifyReport(stderr, report)
+		if !report.Passed {
+			return 1
+		}
+	}
+	var sbomFile string
+	if *sbom {
+		if sbomFile, err = writeSBOM(ctx, repo, service, image, "", stderr); err != nil {
+			fmt.Fprintf(stderr, "pack build: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stderr, "wrote %s\n", sbomFile)
+	}
+	if local && *push {
+		if err := runner.Run(ctx, "docker", "push", image); err != nil {
+			fmt.Fprintf(stderr, "pack build: %v\n", err)
+			return 1
+		}
+		if *sbom {
+			if err := attachSBOM(ctx, image, sbomFile, stderr); err != nil {
+				fmt.Fprintf(stderr, "pack build: %v\n", err)
+				return 1
+			}
+		}
+	}
+	fmt.Fprintln(stdout, image)
+	return 0
+}
+
+// useSharedBuilder points spec's BUILDER build arg at the shared builder
+// image, building it first if need be, and reports the time it took or,
+// reused, the time it saves this build.
+func useSharedBuilder(ctx context.Context, r packaging.Runner, repo, service string, spec *packaging.BuildSpec, stderr io.Writer) error {
+	df, err := os.ReadFile(filepath.Join(repo, spec.Dockerfile))
+	if err != nil {
+		return err
+	}
+	base, ok := packaging.SharedBuilderBase(df)
+	if !ok {
+		return fmt.Errorf("%s does not start from the %s build arg; set shared_builder: true for %s in %s and re-render it", spec.Dockerfile, packaging.BuilderArg, service, packaging.RegistryPath)
+	}
+	res, err := packaging.EnsureBuilder(ctx, r, repo, base)
+	if err != nil {
+		return err
+	}
+	switch {
+	case res.Built:
+		fmt.Fprintf(stderr, "built shared builder %s in %s; builds reuse it until go.mod or go.sum change\n", res.Image, res.Took.Round(100*time.Millisecond))
+	case res.Took > 0:
+		fmt.Fprintf(stderr, "reusing shared builder %s, saving its %s module download\n", res.Image, res.Took.Round(100*time.Millisecond))
+	default:
+		fmt.Fprintf(stderr, "reusing shared builder %s\n", res.Image)
+	}
+	spec.BuildArgs[packaging.BuilderArg] = res.Image
+	return nil
+}
+
+// leadingArg splits off a positional argument given before the flags, so
+// both `pack build billing --push` and `pack build --push billing` work.
+func leadingArg(args []string) (string, []string) {
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		return args[0], args[1:]
+	}
+	return "", args
+}
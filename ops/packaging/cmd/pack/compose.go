@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdCompose(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compose", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		root   = fs.String("root", ".", "repository root, or any directory below it")
+		force  = fs.Bool("force", false, "overwrite an existing "+packaging.ComposePath)
+		check  = fs.Bool("check", false, "fail with a diff if "+packaging.ComposePath+" differs from the registry")
+		only   = fs.String("only", "", "comma-separated services to include, with those they depend on (default: all)")
+		local  = fs.String("local", "", "service to leave out and run on the host against the stack")
+		routes = fs.String("routes", "", "gateway route table to rewrite for the stack into "+packaging.ComposeRoutesPath)
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *force && *check {
+		fmt.Fprintln(stderr, "pack compose: --force and --check are mutually exclusive")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack compose: %v\n", err)
+		return 1
+	}
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack compose: %v\n", err)
+		return 1
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(stderr, "pack compose: no services in %s\n", packaging.RegistryPath)
+		return 1
+	}
+	var opts []packaging.ComposeOption
+	if names := splitList(*only); len(names) > 0 {
+		opts = append(opts, packaging.ComposeOnly(names...))
+	}
+	if *local != "" {
+		opts = append(opts, packaging.ComposeLocal(*local))
+	}
+	data, err := packaging.GenerateCompose(specs, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack compose: %v\n", err)
+		return 1
+	}
+	outputs := []output{{packaging.ComposePath, data}}
+	if *routes != "" {
+		table, err := os.ReadFile(*routes)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack compose: %v\n", err)
+			return 1
+		}
+		rewritten, err := packaging.RewriteComposeRoutes(table, specs)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack compose: %s: %v\n", *routes, err)
+			return 1
+		}
+		outputs = append(outputs, output{packaging.ComposeRoutesPath, rewritten})
+	}
+	if err := emit(repo, outputs, *force, *check, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "pack compose: %v\n", err)
+		return 1
+	}
+	return 0
+}
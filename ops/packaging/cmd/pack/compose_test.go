@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComposeWriteAndCheck(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090, needs: [postgres]}
+  - {name: portal, language: node, port: 3000, depends_on: [billing]}
+`)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"compose", "--root", root}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("compose exit %d: %s", code, stderr.String())
+	}
+	path := filepath.Join(root, "docker-compose.yml")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"  billing:\n", "  portal:\n", "  postgres:\n", "dockerfile: ops/packaging/services/portal.Dockerfile"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("compose file missing %q:\n%s", want, got)
+		}
+	}
+
+	if code := run(args, &stdout, &stderr); code == 0 {
+		t.Fatal("second compose overwrote the file without --force")
+	}
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 0 {
+		t.Fatalf("check on fresh file exit %d: %s", code, stderr.String())
+	}
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090, needs: [postgres]}
+  - {name: portal, language: node, port: 3000}
+`)
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 1 {
+		t.Fatalf("check after a registry change exit %d, want 1", code)
+	}
+	writeRegistry(t, root, `services:
+  - {name: portal, language: node, port: 3000, depends_on: [ledger]}
+`)
+	stderr.Reset()
+	if code := run(append(args, "--force"), &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `unknown dependency "ledger"`) {
+		t.Fatalf("unknown dependency: exit %d: %s", code, stderr.String())
+	}
+
+	// A port conflict writes nothing; a port outside the range only warns.
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090}
+  - {name: portal, language: node, port: 9090}
+`)
+	before, _ := os.ReadFile(path)
+	stderr.Reset()
+	if code := run(append(args, "--force"), &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `port 9090 is already used by "billing"`) {
+		t.Fatalf("port conflict: exit %d: %s", code, stderr.String())
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("port conflict rewrote the compose file")
+	}
+	writeRegistry(t, root, `allowed_ports: {min: 3000, max: 8999}
+services:
+  - {name: billing, language: go, port: 9090}
+`)
+	stderr.Reset()
+	if code := run(append(args, "--force"), &stdout, &stderr); code != 0 || !strings.Contains(stderr.String(), `warning: service "billing": port 9090 is outside the allowed range 3000-8999`) {
+		t.Fatalf("out-of-range port: exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestComposeRoutes(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090}\n  - {name: portal, language: node, port: 3000}\n")
+	table := filepath.Join(t.TempDir(), "routes.yml")
+	if err := os.WriteFile(table, []byte("routes:\n  - {path_prefix: /billing, upstream: 'http://localhost:9090'}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"compose", "--root", root, "--local", "portal", "--routes", table}, &stdout, &stderr); code != 0 {
+		t.Fatalf("compose exit %d: %s", code, stderr.String())
+	}
+	compose, _ := os.ReadFile(filepath.Join(root, "docker-compose.yml"))
+	if strings.Contains(string(compose), "  portal:\n") || !strings.Contains(string(compose), "portal:host-gateway") {
+		t.Errorf("--local portal:\n%s", compose)
+	}
+	routes, err := os.ReadFile(filepath.Join(root, "docker-compose.routes.yml"))
+	if err != nil || !strings.Contains(string(routes), "http://billing:9090") {
+		t.Errorf("rewritten routes (%v):\n%s", err, routes)
+	}
+}
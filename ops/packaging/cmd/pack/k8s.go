@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdK8s(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("k8s", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		root     = fs.String("root", ".", "repository root, or any directory below it")
+		service  = fs.String("service", "", "service name in "+packaging.RegistryPath)
+		all      = fs.Bool("all", false, "generate manifests for every service in "+packaging.RegistryPath)
+		force    = fs.Bool("force", false, "overwrite existing manifests")
+		check    = fs.Bool("check", false, "fail with a diff if a manifest differs from the registry")
+		out      = fs.String("out", "", "write the manifests to this directory instead of "+packaging.K8sDir)
+		toStdout = fs.Bool("stdout", false, "print the manifests as one multi-document YAML stream instead of writing them")
+		diff     = fs.Bool("diff", false, "compare the manifests with the cluster's objects through kubectl diff instead of writing them")
+		repoName = fs.String("repo", "", "image repository (default: service name); with --tag, runs the pack build image")
+		tag      = fs.String("tag", "", "image tag, or auto for the one pack build derives; default is the kind overlay's :local image")
+		sets     []string
+	)
+	fs.Func("set", "override a registry value, key=value (repeatable): replicas, image, resources.limits.cpu, autoscale.max_replicas, env.NAME, ...", func(s string) error {
+		sets = append(sets, s)
+		return nil
+	})
+	name, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if name == "" && fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	if name != "" {
+		if *service != "" && *service != name {
+			fmt.Fprintln(stderr, "pack k8s: give the service once, as an argument or with --service")
+			return 2
+		}
+		*service = name
+	}
+	modes := 0
+	for _, on := range []bool{*force, *check, *toStdout, *diff} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		fmt.Fprintln(stderr, "pack k8s: --force, --check, --stdout, and --diff are mutually exclusive")
+		return 2
+	}
+	if *out != "" && (*toStdout || *diff) {
+		fmt.Fprintln(stderr, "pack k8s: --out writes files; it does not combine with --stdout or --diff")
+		return 2
+	}
+	if (*service == "") == !*all {
+		fmt.Fprintln(stderr, "pack k8s: exactly one of <service>, --service, and --all is required")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack k8s: %v\n", err)
+		return 1
+	}
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack k8s: %v\n", err)
+		return 1
+	}
+	if !*all {
+		spec, ok := packaging.FindService(specs, *service)
+		if !ok {
+			fmt.Fprintf(stderr, "pack k8s: %s is not in %s\n", *service, packaging.RegistryPath)
+			return 1
+		}
+		specs = []packaging.ServiceSpec{spec}
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(stderr, "pack k8s: no services in %s\n", packaging.RegistryPath)
+		return 1
+	}
+	// --out takes a path from the caller's directory, not the repository's;
+	// emit joins the outputs' paths onto base.
+	base, dir := repo, packaging.K8sDir
+	if *out != "" {
+		if dir, err = filepath.Abs(*out); err != nil {
+			fmt.Fprintf(stderr, "pack k8s: %v\n", err)
+			return 1
+		}
+		base = ""
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := false
+	for _, spec := range specs {
+		outputs, err := k8sOutputs(ctx, repo, spec, k8sImage{repo: *repoName, tag: *tag}, sets, dir)
+		if err == nil {
+			switch {
+			case *toStdout:
+				_, err = stdout.Write(k8sStream(outputs))
+			case *diff:
+				err = kubectlDiff(ctx, repo, k8sStream(outputs), stdout, stderr)
+			default:
+				err = emit(base, outputs, *force, *check, stdout, stderr)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", spec.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// k8sImage is the image the --repo and --tag flags ask for. Without
+// either, the manifests run uniassist/<name>:local, the image the kind
+// overlay loads.
+type k8sImage struct {
+	repo, tag string
+}
+
+// k8sOutputs generates the manifests for spec, with the --set overrides
+// applied, under dir in a stable order.
+func k8sOutputs(ctx context.Context, repo string, spec packaging.ServiceSpec, img k8sImage, sets []string, dir string) ([]output, error) {
+	var image string
+	if img.repo != "" || img.tag != "" {
+		if img.repo == "" {
+			img.repo = spec.Name
+		}
+		if img.tag == "" || img.tag == "auto" {
+			// The same tag pack build gives the image, from the same
+			// Dockerfile and the default context.
+			tag, err := packaging.ImageTag(ctx, filepath.Join(repo, packaging.DockerfilePath(spec.Name)), repo)
+			if err != nil {
+				return nil, fmt.Errorf("%w (pass --tag to set one explicitly)", err)
+			}
+			img.tag = tag
+		}
+		image = img.repo + ":" + img.tag
+	}
+	for _, kv := range sets {
+		if err := packaging.SetK8sValue(&spec, &image, kv); err != nil {
+			return nil, err
+		}
+	}
+	var opts []packaging.K8sOption
+	if image != "" {
+		opts = append(opts, packaging.WithImage(image))
+	}
+	files, err := packaging.GenerateK8sManifests(spec, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []output
+	for name, data := range files {
+		outputs = append(outputs, output{filepath.Join(dir, name), data})
+	}
+	slices.SortFunc(outputs, func(a, b output) int { return strings.Compare(a.rel, b.rel) })
+	return outputs, nil
+}
+
+// k8sStream joins outputs into one multi-document YAML stream, as kubectl
+// apply -f - reads it.
+func k8sStream(outputs []output) []byte {
+	docs := make([][]byte, len(outputs))
+	for i, o := range outputs {
+		docs[i] = o.data
+	}
+	return bytes.Join(docs, []byte("---\n"))
+}
+
+// kubectlDiff runs kubectl diff on stream against the current context's
+// cluster. kubectl exits 1 when the objects differ, which is a finding
+// rather than a failure to run.
+func kubectlDiff(ctx context.Context, repo string, stream []byte, stdout, stderr io.Writer) error {
+	runner := packaging.ExecRunner{Dir: repo, Stdin: bytes.NewReader(stream), Stdout: stdout, Stderr: stderr}
+	err := runner.Run(ctx, "kubectl", "diff", "-f", "-")
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return errors.New("differs from the cluster")
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestK8sWriteAndCheck(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090}\n")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"k8s", "--root", root, "--all"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("k8s exit %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"billing-deployment.yaml", "billing-service.yaml"} {
+		got, err := os.ReadFile(filepath.Join(root, "ops", "deploy", "k8s", "base", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), "port: 9090") {
+			t.Errorf("%s missing port 9090:\n%s", name, got)
+		}
+	}
+
+	if code := run(args, &stdout, &stderr); code == 0 {
+		t.Fatal("second k8s overwrote the manifests without --force")
+	}
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 0 {
+		t.Fatalf("check on fresh manifests exit %d: %s", code, stderr.String())
+	}
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090, replicas: 2}\n")
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 1 {
+		t.Fatalf("check after a registry change exit %d, want 1", code)
+	}
+	if code := run([]string{"k8s", "--root", root, "--service", "ledger"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("k8s for an unregistered service exit %d, want 1", code)
+	}
+	if code := run([]string{"k8s", "--root", root}, &stdout, &stderr); code != 2 {
+		t.Fatalf("k8s without --service or --all exit %d, want 2", code)
+	}
+}
+
+func TestK8sStdoutAndOut(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090}\n")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"k8s", "billing", "--root", root, "--stdout", "--repo", "registry.example.com/billing", "--tag", "v1", "--set", "autoscale.min_replicas=2", "--set", "autoscale.max_replicas=5", "--set", "env.LOG_LEVEL=debug"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("k8s --stdout exit %d: %s", code, stderr.String())
+	}
+	got := stdout.String()
+	for _, want := range []string{"kind: Deployment", "kind: Service", "kind: HorizontalPodAutoscaler", "image: registry.example.com/billing:v1", "name: LOG_LEVEL", "\n---\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stream missing %q:\n%s", want, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "ops", "deploy", "k8s", "base", "billing-deployment.yaml")); !os.IsNotExist(err) {
+		t.Errorf("--stdout wrote the manifests: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "manifests")
+	if code := run([]string{"k8s", "billing", "--root", root, "--out", out}, &stdout, &stderr); code != 0 {
+		t.Fatalf("k8s --out exit %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(out, "billing-service.yaml")); err != nil {
+		t.Error(err)
+	}
+	if code := run([]string{"k8s", "billing", "--root", root, "--stdout", "--set", "replicas=lots"}, &stdout, &stderr); code != 1 {
+		t.Errorf("k8s with a bad --set exit %d, want 1", code)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	root := fs.String("root", ".", "repository root, or any directory below it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack lint: %v\n", err)
+		return 1
+	}
+	reg, err := openRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack lint: %v\n", err)
+		return 1
+	}
+	var opts []packaging.LintOption
+	if reg.AllowedBaseImages != nil {
+		opts = append(opts, packaging.AllowBaseImages(reg.AllowedBaseImages...))
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		if files, err = filepath.Glob(filepath.Join(repo, packaging.ServicesDir, "*.Dockerfile")); err != nil {
+			fmt.Fprintf(stderr, "pack lint: %v\n", err)
+			return 1
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repo, path)
+		}
+		rel, err := filepath.Rel(repo, path)
+		if err != nil {
+			rel = path
+		}
+		var spec *packaging.ServiceSpec
+		if s, ok := packaging.FindService(reg.Services, strings.TrimSuffix(filepath.Base(path), ".Dockerfile")); ok {
+			spec = &s
+		}
+		issues, err := packaging.Lint(path, spec, opts...)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", rel, err)
+			failed = true
+			continue
+		}
+		for _, issue := range issues {
+			fmt.Fprintf(stdout, "%s:%s\n", rel, issue)
+			if issue.Severity == packaging.SeverityError {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintExitCodes(t *testing.T) {
+	root := newRepo(t)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"render", "--root", root, "--service", "billing", "--port", "9090"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	rel := filepath.Join("ops", "packaging", "services", "billing.Dockerfile")
+
+	stdout.Reset()
+	if code := run([]string{"lint", "--root", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("lint exit %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), rel+":0: warning:") {
+		t.Errorf("missing manifest warning:\n%s", stdout.String())
+	}
+
+	path := filepath.Join(root, rel)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("USER nobody"), []byte("USER root"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"lint", "--root", root, rel}, &stdout, &stderr); code != 1 {
+		t.Fatalf("lint on root Dockerfile exit %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "error: stage at line") || !strings.Contains(stdout.String(), "[non-root-user]") {
+		t.Errorf("root user not reported:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,71 @@
+// Command pack renders and checks the packaging artifacts under
+// ops/packaging.
+//
+// Usage:
+//
+//	pack render --service <name> [--lang go] [--port 8080] [--force | --check]
+//	pack render --all [--force | --check]
+//	pack build <service> [--repo name] [--tag tag] [--platforms linux/amd64,linux/arm64] [--push] [--print-tag-only] [--netrc file] [--ssh] [--verify [--require-scanner]] [--sbom]
+//	pack verify <image> [--service name] [--json] [--require-scanner]
+//	pack sbom <service> [--image ref [--attach]] [--out file]
+//	pack scaffold <name> [--dir services/<name>] [--force]
+//	pack lint [--root dir] [Dockerfile...]
+//	pack compose [--root dir] [--only a,b] [--local name] [--routes file] [--force | --check]
+//	pack k8s <service> | --all [--root dir] [--force | --check | --stdout | --diff] [--out dir] [--repo name] [--tag tag|auto] [--set key=value]...
+//	pack systemd --service <name> | --all [--root dir] [--force | --check]
+//	pack routes verify [--timeout 5s] <config.yaml>
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"render", "render a service Dockerfile from its template", cmdRender},
+	{"build", "build (and optionally push) a service image", cmdBuild},
+	{"verify", "check an image's size, layers, base, and vulnerabilities against its budget", cmdVerify},
+	{"sbom", "write a CycloneDX SBOM of a service's Go modules and image packages", cmdSBOM},
+	{"scaffold", "generate a runnable Go service skeleton", cmdScaffold},
+	{"lint", "check service Dockerfiles for placeholders, root users, cgo, ports, pins, and remote ADDs", cmdLint},
+	{"compose", "generate docker-compose.yml for local development from the registry", cmdCompose},
+	{"k8s", "generate Kubernetes Deployment, Service, and autoscaler manifests from the registry", cmdK8s},
+	{"systemd", "generate systemd units for running Go services on a host from the registry", cmdSystemd},
+	{"makefile", "generate Makefile build and push targets from the registry", cmdMakefile},
+	{"routes", "dial every gateway upstream in a config file once to check TLS and reachability", cmdRoutes},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "pack: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: pack <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
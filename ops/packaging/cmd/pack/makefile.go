@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdMakefile(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("makefile", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		root     = fs.String("root", ".", "repository root, or any directory below it")
+		registry = fs.String("registry", "", "image registry prefix, such as ghcr.io/acme (default: bare service names)")
+		force    = fs.Bool("force", false, "overwrite an existing "+packaging.MakefilePath)
+		check    = fs.Bool("check", false, "fail with a diff if "+packaging.MakefilePath+" differs from the registry")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *force && *check {
+		fmt.Fprintln(stderr, "pack makefile: --force and --check are mutually exclusive")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack makefile: %v\n", err)
+		return 1
+	}
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack makefile: %v\n", err)
+		return 1
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(stderr, "pack makefile: no services in %s\n", packaging.RegistryPath)
+		return 1
+	}
+	data, err := packaging.GenerateMakefile(specs, *registry)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack makefile: %v\n", err)
+		return 1
+	}
+	if err := emit(repo, []output{{packaging.MakefilePath, data}}, *force, *check, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "pack makefile: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakefileWriteAndCheck(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090}
+`)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"makefile", "--root", root, "--registry", "ghcr.io/acme"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("makefile exit %d: %s", code, stderr.String())
+	}
+	got, err := os.ReadFile(filepath.Join(root, "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"REGISTRY ?= ghcr.io/acme\n", "\nbuild-billing:\n", "-f ops/packaging/services/billing.Dockerfile"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Makefile missing %q:\n%s", want, got)
+		}
+	}
+
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 0 {
+		t.Fatalf("check on fresh file exit %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"makefile", "--root", root, "--check"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("check with another registry exit %d, want 1", code)
+	}
+	stderr.Reset()
+	if code := run([]string{"makefile", "--root", root, "--registry", "ghcr.io/Acme", "--force"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "invalid registry") {
+		t.Fatalf("invalid registry: exit %d: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdRender(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		vars  packaging.Vars
+		lang  = fs.String("lang", "go", "template language")
+		root  = fs.String("root", ".", "repository root, or any directory below it")
+		src   = fs.String("src", "", "service source directory, relative to the repository root; checked against --lang")
+		arch  = fs.String("arch", "", "comma-separated GOARCH list to cross-compile for (default: native)")
+		force = fs.Bool("force", false, "overwrite an existing Dockerfile")
+		check = fs.Bool("check", false, "fail with a diff if the Dockerfile on disk differs from the template output")
+		all   = fs.Bool("all", false, "render every service in "+packaging.RegistryPath)
+
+		private = fs.String("private-modules", "", "comma-separated GOPRIVATE patterns to fetch with a netrc secret or SSH agent")
+		tags    = fs.String("build-tags", "", "comma-separated Go build tags passed to go build -tags, e.g. enterprise")
+	)
+	fs.StringVar(&vars.ServiceName, "service", "", "service name (required)")
+	fs.IntVar(&vars.ExposePort, "port", 8080, "port exposed by the service")
+	fs.IntVar(&vars.MetricsPort, "metrics-port", 0, "separate port serving /metrics, exposed too (default: none)")
+	fs.StringVar(&vars.HealthPath, "health-path", packaging.DefaultHealthPath, "HTTP path probed by the image HEALTHCHECK")
+	fs.StringVar(&vars.HealthInterval, "health-interval", packaging.DefaultHealthInterval, "HEALTHCHECK interval")
+	fs.StringVar(&vars.HealthTimeout, "health-timeout", packaging.DefaultHealthTimeout, "HEALTHCHECK timeout")
+	fs.IntVar(&vars.HealthRetries, "health-retries", packaging.DefaultHealthRetries, "HEALTHCHECK retries before the container is unhealthy")
+	fs.StringVar(&vars.BinaryName, "binary", "", "binary name inside the image (default: service name)")
+	fs.StringVar(&vars.Package, "package", "", "Go package to build, relative to the build context (default: .)")
+	fs.StringVar(&vars.Base, "base", packaging.BaseAlpine, "runtime base image: alpine or distroless")
+	fs.BoolVar(&vars.UseCacheMounts, "cache-mounts", false, "keep the Go module and build caches in BuildKit cache mounts")
+	fs.BoolVar(&vars.SharedBuilder, "shared-builder", false, "start the builder stage from the BUILDER build arg, for pack build --shared-builder")
+	fs.BoolVar(&vars.PinDigests, "pin-digests", false, "pin base images to the digests their tags point at now (needs registry access)")
+	fs.BoolVar(&vars.WithTzdata, "with-tzdata", false, "include zoneinfo in the runtime image")
+	fs.StringVar(&vars.GoVersion, "go-version", packaging.DefaultGoVersion, "Go toolchain version of the builder image")
+	fs.StringVar(&vars.NodeVersion, "node-version", packaging.DefaultNodeVersion, "Node.js version of the base images (--lang node)")
+	fs.StringVar(&vars.Entrypoint, "entrypoint", packaging.DefaultEntrypoint, "script under dist/ to run (--lang node)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *force && *check {
+		fmt.Fprintln(stderr, "pack render: --force and --check are mutually exclusive")
+		return 2
+	}
+	if *all {
+		if vars.ServiceName != "" {
+			fmt.Fprintln(stderr, "pack render: --all and --service are mutually exclusive")
+			return 2
+		}
+	} else if vars.ServiceName == "" {
+		fmt.Fprintln(stderr, "pack render: --service or --all is required")
+		return 2
+	}
+
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack render: %v\n", err)
+		return 1
+	}
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack render: %v\n", err)
+		return 1
+	}
+	if *all {
+		return renderAll(repo, specs, *force, *check, stdout, stderr)
+	}
+	if spec, ok := packaging.FindService(specs, vars.ServiceName); ok {
+		// A registered service renders from its entry alone, exactly as
+		// --all does, so the two never disagree.
+		var override string
+		fs.Visit(func(f *flag.Flag) {
+			if override == "" && !registeredFlags[f.Name] {
+				override = f.Name
+			}
+		})
+		if override != "" {
+			fmt.Fprintf(stderr, "pack render: %s is registered in %s, which decides its Dockerfile; drop --%s\n", spec.Name, packaging.RegistryPath, override)
+			return 2
+		}
+		if err := emitService(repo, spec, *force, *check, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "pack render: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *src != "" {
+		if err := packaging.CheckLanguage(filepath.Join(repo, *src), *lang); err != nil {
+			fmt.Fprintf(stderr, "pack render: %v\n", err)
+			return 1
+		}
+	}
+	vars.PrivateModules = splitList(*private)
+	vars.BuildTags = splitList(*tags)
+	outputs, err := renderService(*lang, vars, splitList(*arch))
+	if err != nil {
+		fmt.Fprintf(stderr, "pack render: %v\n", err)
+		return 1
+	}
+	if err := emit(repo, outputs, *force, *check, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "pack render: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// registeredFlags are the render flags that still apply to a service in
+// the registry.
+var registeredFlags = map[string]bool{"service": true, "root": true, "force": true, "check": true}
+
+// renderAll renders every service in the registry. It keeps going past
+// failures and reports all of them at the end.
+func renderAll(repo string, specs []packaging.ServiceSpec, force, check bool, stdout, stderr io.Writer) int {
+	if len(specs) == 0 {
+		fmt.Fprintf(stderr, "pack render: no services in %s\n", packaging.RegistryPath)
+		return 1
+	}
+	var failed []string
+	for _, spec := range specs {
+		if err := emitService(repo, spec, force, check, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", spec.Name, err)
+			failed = append(failed, spec.Name)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(stderr, "pack render: %d of %d services failed: %s\n", len(failed), len(specs), strings.Join(failed, ", "))
+		return 1
+	}
+	return 0
+}
+
+func emitService(repo string, spec packaging.ServiceSpec, force, check bool, stdout, stderr io.Writer) error {
+	outputs, err := renderService(spec.Language, spec.Vars(), nil)
+	if err != nil {
+		return err
+	}
+	return emit(repo, outputs, force, check, stdout, stderr)
+}
+
+// loadRegistry reads the service registry under repo and prints its
+// warnings to stderr. A repository without one has no registered
+// services, which only the commands that generate for every service
+// treat as an error.
+func loadRegistry(repo string, stderr io.Writer) ([]packaging.ServiceSpec, error) {
+	reg, err := openRegistry(repo, stderr)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Services, nil
+}
+
+// openRegistry is loadRegistry returning the whole registry, empty if
+// the repository has none.
+func openRegistry(repo string, stderr io.Writer) (*packaging.Registry, error) {
+	reg, err := packaging.LoadRegistry(filepath.Join(repo, packaging.RegistryPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return &packaging.Registry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range reg.Warnings() {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	return reg, nil
+}
+
+// renderService renders a service's Dockerfile and, for Go, its
+// .dockerignore.
+func renderService(lang string, vars packaging.Vars, arches []string) ([]output, error) {
+	var (
+		rendered []byte
+		err      error
+	)
+	switch {
+	case len(arches) > 1 && lang == "go":
+		rendered, err = packaging.RenderMultiArch(vars, arches)
+	case len(arches) > 1:
+		err = fmt.Errorf("multi-arch rendering is only supported for --lang go")
+	default:
+		if len(arches) == 1 {
+			vars.TargetArch = arches[0]
+		}
+		rendered, err = packaging.Render(lang, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+	outputs := []output{{packaging.DockerfilePath(vars.ServiceName), rendered}}
+	if lang == "go" {
+		ignore, err := packaging.GenerateDockerignore(vars.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output{packaging.DockerignorePath(vars.ServiceName), ignore})
+	}
+	return outputs, nil
+}
+
+// emit writes outputs under repo or, with check, compares them against the
+// files on disk and prints a diff for each one that drifted.
+func emit(repo string, outputs []output, force, check bool, stdout, stderr io.Writer) error {
+	if check {
+		var drifted []string
+		for _, o := range outputs {
+			onDisk, err := os.ReadFile(filepath.Join(repo, o.rel))
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(onDisk, o.data) {
+				fmt.Fprintf(stderr, "%s has drifted from its template:\n", o.rel)
+				fmt.Fprint(stderr, packaging.Diff(o.rel, "rendered", onDisk, o.data))
+				drifted = append(drifted, o.rel)
+				continue
+			}
+			fmt.Fprintf(stdout, "%s is up to date\n", o.rel)
+		}
+		if len(drifted) > 0 {
+			return fmt.Errorf("drifted: %s", strings.Join(drifted, ", "))
+		}
+		return nil
+	}
+	for _, o := range outputs {
+		if err := packaging.WriteFile(filepath.Join(repo, o.rel), o.data, force); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "wrote %s\n", o.rel)
+	}
+	return nil
+}
+
+// output is a rendered file and its path relative to the repository root.
+type output struct {
+	rel  string
+	data []byte
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
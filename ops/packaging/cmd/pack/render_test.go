@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "ops", "packaging", "services"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// writeRegistry writes the service registry of the repo at root.
+func writeRegistry(t *testing.T, root, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, "ops", "packaging", "services.yaml"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderForceAndCheck(t *testing.T) {
+	root := newRepo(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"render", "--root", root, "--service", "billing", "--port", "9090"}
+
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	path := filepath.Join(root, "ops", "packaging", "services", "billing.Dockerfile")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "EXPOSE 9090") {
+		t.Fatalf("rendered Dockerfile missing port:\n%s", got)
+	}
+	ignore, err := os.ReadFile(path + ".dockerignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ignore), "!cmd/billing\n") {
+		t.Fatalf("dockerignore does not keep the service:\n%s", ignore)
+	}
+
+	if code := run(args, &stdout, &stderr); code == 0 {
+		t.Fatal("second render overwrote the Dockerfile without --force")
+	}
+	if code := run(append(args, "--force"), &stdout, &stderr); code != 0 {
+		t.Fatalf("render --force exit %d: %s", code, stderr.String())
+	}
+
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 0 {
+		t.Fatalf("check on fresh render exit %d: %s", code, stderr.String())
+	}
+	if err := os.WriteFile(path, bytes.Replace(got, []byte("USER nobody"), []byte("USER root"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 1 {
+		t.Fatalf("check on drifted file exit %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "-USER root") || !strings.Contains(stderr.String(), "+USER nobody") {
+		t.Fatalf("drift diff missing changed lines:\n%s", stderr.String())
+	}
+}
+
+func TestRenderAll(t *testing.T) {
+	root := newRepo(t)
+	services := filepath.Join(root, "ops", "packaging", "services")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"render", "--all", "--root", root}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "no services in") {
+		t.Fatalf("render --all without a registry: exit %d: %s", code, stderr.String())
+	}
+
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090}
+  - {name: portal, language: node, port: 3000}
+  - {name: broken, language: go, port: 0}
+  - {name: clash, language: go, port: 9090}
+`)
+	stderr.Reset()
+	if code := run([]string{"render", "--all", "--root", root}, &stdout, &stderr); code != 1 {
+		t.Fatalf("render --all with an invalid registry exit %d, want 1", code)
+	}
+	for _, want := range []string{"(broken): invalid port 0", `(clash): port 9090 is already used by "billing"`} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr missing %q:\n%s", want, stderr.String())
+		}
+	}
+	if entries, _ := os.ReadDir(services); len(entries) != 0 {
+		t.Errorf("invalid registry rendered %d files", len(entries))
+	}
+
+	writeRegistry(t, root, `services:
+  - {name: billing, language: go, port: 9090}
+  - {name: portal, language: node, port: 3000}
+`)
+	if err := os.WriteFile(filepath.Join(services, "portal.Dockerfile"), []byte("FROM scratch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := run([]string{"render", "--all", "--root", root}, &stdout, &stderr); code != 1 {
+		t.Fatalf("render --all over an existing file exit %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "1 of 2 services failed: portal") {
+		t.Errorf("stderr missing the failure summary:\n%s", stderr.String())
+	}
+	for _, name := range []string{"billing.Dockerfile", "billing.Dockerfile.dockerignore"} {
+		if _, err := os.Stat(filepath.Join(services, name)); err != nil {
+			t.Errorf("valid service not rendered past failures: %v", err)
+		}
+	}
+}
+
+func TestRenderRegisteredService(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090, health: /ready}\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"render", "--root", root, "--service", "billing", "--port", "8080"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "drop --port") {
+		t.Fatalf("override of a registered service: exit %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"render", "--root", root, "--service", "billing"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	got, err := os.ReadFile(filepath.Join(root, "ops", "packaging", "services", "billing.Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "http://localhost:9090/ready") {
+		t.Errorf("Dockerfile not rendered from the registry:\n%s", got)
+	}
+	if code := run([]string{"render", "--root", root, "--all", "--check"}, &stdout, &stderr); code != 0 {
+		t.Errorf("--service and --all disagree: %s", stderr.String())
+	}
+}
+
+func TestRenderBuildTagsFlag(t *testing.T) {
+	root := newRepo(t)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"render", "--root", root, "--service", "billing", "--build-tags", "enterprise;id"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `invalid build tag "enterprise;id"`) {
+		t.Fatalf("malicious tag: exit %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"render", "--root", root, "--service", "billing", "--build-tags", "enterprise, netgo"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	got, err := os.ReadFile(filepath.Join(root, "ops", "packaging", "services", "billing.Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `go build -tags "enterprise,netgo" `) {
+		t.Errorf("Dockerfile missing the build tags:\n%s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/gateway"
+)
+
+func cmdRoutes(args []string, stdout, stderr io.Writer) int {
+	sub, args := leadingArg(args)
+	if sub != "verify" {
+		fmt.Fprintln(stderr, "usage: pack routes verify [--timeout 5s] <config.yaml>")
+		return 2
+	}
+	fs := flag.NewFlagSet("routes verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	timeout := fs.Duration("timeout", 5*time.Second, "how long each upstream may take to connect and handshake")
+	file, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if file == "" && fs.NArg() == 1 {
+		file = fs.Arg(0)
+	}
+	if file == "" {
+		fmt.Fprintln(stderr, "usage: pack routes verify [--timeout 5s] <config.yaml>")
+		return 2
+	}
+	routes, err := gateway.LoadRoutesFile(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack routes verify: %v\n", err)
+		return 1
+	}
+	if err := gateway.VerifyUpstreams(context.Background(), routes, *timeout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d routes: every upstream reachable\n", len(routes))
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoutesVerify(t *testing.T) {
+	up := httptest.NewServer(http.NotFoundHandler())
+	defer up.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := "http://" + ln.Addr().String()
+	ln.Close()
+
+	write := func(body string) string {
+		path := filepath.Join(t.TempDir(), "gateway.yaml")
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var stdout, stderr bytes.Buffer
+	good := write("routes:\n  - {path_prefix: /a, upstream: " + up.URL + "}\n")
+	if code := run([]string{"routes", "verify", good, "--timeout", "1s"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("verify exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "1 routes: every upstream reachable") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	stderr.Reset()
+	bad := write("routes:\n  - {path_prefix: /a, upstream: " + up.URL + "}\n  - {path_prefix: /b, upstream: " + down + "}\n")
+	if code := run([]string{"routes", "verify", bad}, &stdout, &stderr); code != 1 {
+		t.Fatalf("verify of an unreachable upstream exit %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "route 1 (/b)") || strings.Contains(stderr.String(), "route 0") {
+		t.Errorf("stderr = %q, want only route 1 reported", stderr.String())
+	}
+
+	if code := run([]string{"routes"}, &stdout, &stderr); code != 2 {
+		t.Errorf("routes without verify exit %d, want 2", code)
+	}
+}
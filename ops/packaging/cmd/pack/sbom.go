@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdSBOM(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sbom", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		root   = fs.String("root", ".", "repository root, or any directory below it")
+		image  = fs.String("image", "", "built image in the local Docker daemon whose OS packages are listed")
+		out    = fs.String("out", "", "file to write, relative to the repository root (default: dist/<service>-sbom.json)")
+		attach = fs.Bool("attach", false, "push the SBOM to the registry as an artifact of --image, with oras or cosign")
+	)
+	service, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if service == "" && fs.NArg() == 1 {
+		service = fs.Arg(0)
+	}
+	if service == "" {
+		fmt.Fprintln(stderr, "usage: pack sbom <service> [--image ref [--attach]] [--out file]")
+		return 2
+	}
+	if *attach && *image == "" {
+		fmt.Fprintln(stderr, "pack sbom: --attach needs --image, the pushed image to attach the SBOM to")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack sbom: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	file, err := writeSBOM(ctx, repo, service, *image, *out, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack sbom: %v\n", err)
+		return 1
+	}
+	if *attach {
+		if err := attachSBOM(ctx, *image, file, stderr); err != nil {
+			fmt.Fprintf(stderr, "pack sbom: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintln(stdout, file)
+	return 0
+}
+
+// writeSBOM generates the SBOM of service's registry entry, with the OS
+// packages of image if it is set, writes it to out (or SBOMPath), and
+// returns the file written.
+func writeSBOM(ctx context.Context, repo, service, image, out string, stderr io.Writer) (string, error) {
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		return "", err
+	}
+	spec, ok := packaging.FindService(specs, service)
+	if !ok {
+		return "", fmt.Errorf("service %q is not in %s", service, packaging.RegistryPath)
+	}
+	if out == "" {
+		out = packaging.SBOMPath(service)
+	}
+	bom, warnings, err := packaging.GenerateSBOM(ctx, packaging.SBOMOptions{
+		Spec:       spec,
+		Root:       repo,
+		Dockerfile: filepath.Join(repo, packaging.DockerfilePath(service)),
+		Image:      image,
+		Version:    imageTag(image),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	file := filepath.Join(repo, out)
+	if err := packaging.WriteSBOM(file, bom); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// attachSBOM pushes file to the registry as an artifact of image.
+func attachSBOM(ctx context.Context, image, file string, stderr io.Writer) error {
+	tool, err := packaging.AttachSBOM(ctx, image, file)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "attached %s to %s with %s\n", filepath.Base(file), image, tool)
+	return nil
+}
+
+// imageTag returns the tag of an image reference, or "" if it has none.
+func imageTag(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSBOMWrite(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: web, language: node, port: 3000}\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"sbom", "web", "--root", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	want := filepath.Join(root, "dist", "web-sbom.json")
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("stdout %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(want)
+	if err != nil || !strings.Contains(string(data), `"bomFormat": "CycloneDX"`) {
+		t.Errorf("SBOM: %v\n%s", err, data)
+	}
+	if !strings.Contains(stderr.String(), "warning: web is a node service") {
+		t.Errorf("no warning for the missing Go graph: %s", stderr.String())
+	}
+	if code := run([]string{"sbom", "billing", "--root", root}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `"billing" is not in`) {
+		t.Errorf("unknown service exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestSBOMUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"sbom"}, &stdout, &stderr); code != 2 {
+		t.Errorf("sbom without a service exit %d, want 2", code)
+	}
+	if code := run([]string{"sbom", "web", "--attach"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "--attach needs --image") {
+		t.Errorf("--attach without --image exit %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"build", "web", "--sbom", "--platforms", "linux/amd64,linux/arm64"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "multi-platform") {
+		t.Errorf("build --sbom of a multi-platform image exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"web:abc123":                           "abc123",
+		"localhost:5000/web":                   "",
+		"registry.example.com/web:v1@sha256:0": "v1",
+		"":                                     "",
+	} {
+		if got := imageTag(image); got != want {
+			t.Errorf("imageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
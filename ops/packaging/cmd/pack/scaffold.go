@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdScaffold(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		dir   = fs.String("dir", "", "directory to create, relative to the repository root (default: services/<name>)")
+		root  = fs.String("root", ".", "repository root, or any directory below it")
+		force = fs.Bool("force", false, "write into an existing directory, replacing the generated files")
+	)
+	name, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if name == "" && fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	if name == "" {
+		fmt.Fprintln(stderr, "usage: pack scaffold <name> [--dir path] [--force]")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack scaffold: %v\n", err)
+		return 1
+	}
+	if *dir == "" {
+		*dir = filepath.Join("services", name)
+	}
+	var opts []packaging.ScaffoldOption
+	if *force {
+		opts = append(opts, packaging.Overwrite())
+	}
+	if err := packaging.ScaffoldService(name, filepath.Join(repo, *dir), opts...); err != nil {
+		fmt.Fprintf(stderr, "pack scaffold: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "scaffolded %s in %s\n", name, *dir)
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdSystemd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("systemd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		root    = fs.String("root", ".", "repository root, or any directory below it")
+		service = fs.String("service", "", "service name in "+packaging.RegistryPath)
+		all     = fs.Bool("all", false, "generate units for every Go service in "+packaging.RegistryPath)
+		force   = fs.Bool("force", false, "overwrite existing units")
+		check   = fs.Bool("check", false, "fail with a diff if a unit differs from the registry")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *force && *check {
+		fmt.Fprintln(stderr, "pack systemd: --force and --check are mutually exclusive")
+		return 2
+	}
+	if (*service == "") == !*all {
+		fmt.Fprintln(stderr, "pack systemd: exactly one of --service and --all is required")
+		return 2
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack systemd: %v\n", err)
+		return 1
+	}
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack systemd: %v\n", err)
+		return 1
+	}
+	if *all {
+		// Only Go services have a unit template; the rest run in
+		// containers alone.
+		var goSpecs []packaging.ServiceSpec
+		for _, spec := range specs {
+			if spec.Language == "go" {
+				goSpecs = append(goSpecs, spec)
+			}
+		}
+		specs = goSpecs
+	} else {
+		spec, ok := packaging.FindService(specs, *service)
+		if !ok {
+			fmt.Fprintf(stderr, "pack systemd: %s is not in %s\n", *service, packaging.RegistryPath)
+			return 1
+		}
+		specs = []packaging.ServiceSpec{spec}
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(stderr, "pack systemd: no Go services in %s\n", packaging.RegistryPath)
+		return 1
+	}
+	failed := false
+	for _, spec := range specs {
+		unit, err := packaging.GenerateSystemdUnit(spec)
+		if err == nil {
+			rel := filepath.Join(packaging.SystemdDir, spec.Name+".service")
+			err = emit(repo, []output{{rel, unit}}, *force, *check, stdout, stderr)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", spec.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSystemdWriteAndCheck(t *testing.T) {
+	root := newRepo(t)
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9090}\n  - {name: portal, language: node, port: 3000}\n")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"systemd", "--root", root, "--all"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("systemd exit %d: %s", code, stderr.String())
+	}
+	got, err := os.ReadFile(filepath.Join(root, "ops", "deploy", "systemd", "billing.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "\nExecStart=/opt/uniassist/billing/billing\n") || !strings.Contains(string(got), "\nEnvironment=PORT=9090\n") {
+		t.Errorf("billing.service:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ops", "deploy", "systemd", "portal.service")); !os.IsNotExist(err) {
+		t.Errorf("--all wrote a unit for a node service: %v", err)
+	}
+
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 0 {
+		t.Fatalf("check on a fresh unit exit %d: %s", code, stderr.String())
+	}
+	writeRegistry(t, root, "services:\n  - {name: billing, language: go, port: 9091}\n  - {name: portal, language: node, port: 3000}\n")
+	if code := run(append(args, "--check"), &stdout, &stderr); code != 1 {
+		t.Fatalf("check after a port change exit %d, want 1", code)
+	}
+	if code := run([]string{"systemd", "--root", root, "--service", "portal"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("systemd for a node service exit %d, want 1", code)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func cmdVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		service        = fs.String("service", "", "registry entry whose budget applies (default: the image's repository name)")
+		root           = fs.String("root", ".", "repository root, or any directory below it")
+		asJSON         = fs.Bool("json", false, "print the report as JSON")
+		requireScanner = fs.Bool("require-scanner", false, "fail, instead of warning, when neither trivy nor grype is installed")
+	)
+	image, args := leadingArg(args)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if image == "" && fs.NArg() == 1 {
+		image = fs.Arg(0)
+	}
+	if image == "" {
+		fmt.Fprintln(stderr, "usage: pack verify <image> [--service name] [--json] [--require-scanner]")
+		return 2
+	}
+	if *service == "" {
+		*service = imageService(image)
+	}
+	repo, err := packaging.FindRoot(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack verify: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := verifyImage(ctx, repo, *service, image, *requireScanner, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack verify: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "pack verify: %v\n", err)
+			return 1
+		}
+	} else {
+		writeVerifyReport(stdout, report)
+	}
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// verifyImage checks image against the budget of service's registry
+// entry.
+func verifyImage(ctx context.Context, repo, service, image string, requireScanner bool, stderr io.Writer) (*packaging.VerifyReport, error) {
+	specs, err := loadRegistry(repo, stderr)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := packaging.FindService(specs, service)
+	if !ok {
+		return nil, fmt.Errorf("service %q is not in %s; pass --service", service, packaging.RegistryPath)
+	}
+	return packaging.VerifyImage(ctx, packaging.VerifyOptions{
+		Image:          image,
+		Service:        service,
+		Budget:         spec.Budget,
+		Dockerfile:     filepath.Join(repo, packaging.DockerfilePath(service)),
+		RequireScanner: requireScanner,
+	})
+}
+
+// imageService returns the repository name of an image reference, the
+// service name `pack build` tags it with by default:
+// registry.example.com/team/billing:abc123 is billing.
+func imageService(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	name = path.Base(name)
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
+
+// writeVerifyReport prints a report as a table, one row per check, and
+// a closing verdict.
+func writeVerifyReport(w io.Writer, r *packaging.VerifyReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tACTUAL\tBUDGET\tDETAIL")
+	for _, c := range r.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Status, c.Actual, c.Budget, c.Detail)
+	}
+	tw.Flush()
+	verdict := "passed"
+	if !r.Passed {
+		verdict = "failed"
+	}
+	fmt.Fprintf(w, "%s (%s): budget %s\n", r.Image, r.Service, verdict)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/ops/packaging"
+)
+
+func TestImageService(t *testing.T) {
+	for image, want := range map[string]string{
+		"billing:abc123":                       "billing",
+		"registry.example.com/team/billing:v1": "billing",
+		"localhost:5000/billing":               "billing",
+		"billing@sha256:0123456789abcdef":      "billing",
+	} {
+		if got := imageService(image); got != want {
+			t.Errorf("imageService(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestWriteVerifyReport(t *testing.T) {
+	var out bytes.Buffer
+	writeVerifyReport(&out, &packaging.VerifyReport{
+		Image:   "billing:v1",
+		Service: "billing",
+		Checks: []packaging.Check{
+			{Name: "size", Status: packaging.CheckFail, Actual: "312.4Mi", Budget: "150Mi"},
+			{Name: "scan", Status: packaging.CheckWarn, Actual: "-", Budget: "none HIGH or above", Detail: "no vulnerability scanner"},
+		},
+	})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "CHECK") || !strings.Contains(lines[1], "fail") || !strings.Contains(lines[1], "312.4Mi") {
+		t.Fatalf("report:\n%s", out.String())
+	}
+	if lines[3] != "billing:v1 (billing): budget failed" {
+		t.Errorf("verdict = %q", lines[3])
+	}
+}
+
+func TestVerifyUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"verify"}, &stdout, &stderr); code != 2 {
+		t.Errorf("verify without an image exit %d, want 2", code)
+	}
+	if code := run([]string{"build", "billing", "--verify", "--platforms", "linux/amd64,linux/arm64"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "multi-platform") {
+		t.Errorf("build --verify of a multi-platform image exit %d: %s", code, stderr.String())
+	}
+}
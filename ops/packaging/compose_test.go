@@ -0,0 +1,250 @@
+package packaging
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateCompose(t *testing.T) {
+	out, err := GenerateCompose([]ServiceSpec{
+		{Name: "workflow-runtime", Language: "go", Port: 8080, DependsOn: []string{"workflow-platform-api"}, Needs: []string{BackingRedis}},
+		{Name: "workflow-platform-api", Language: "go", Port: 8080, Needs: []string{BackingPostgres, BackingRedis}},
+		{Name: "admissions-api", Language: "go", Port: 8080},
+		{Name: "cache-proxy", Language: "node", Port: 6379},
+		{Name: "trigger-scheduler", Language: "go", Port: 8792, DependsOn: []string{"workflow-platform-api", "workflow-runtime"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file composeFile
+	if err := yaml.Unmarshal(out, &file); err != nil {
+		t.Fatalf("generated compose file is not YAML: %v\n%s", err, out)
+	}
+	if len(file.Services) != 7 {
+		t.Fatalf("got %d services, want 5 plus postgres and redis:\n%s", len(file.Services), out)
+	}
+
+	hostPorts := map[string]string{}
+	for name, svc := range file.Services {
+		if len(svc.Networks) != 1 || svc.Networks[0] != ComposeNetwork {
+			t.Errorf("%s: networks = %v", name, svc.Networks)
+		}
+		for _, p := range svc.Ports {
+			host, _, _ := strings.Cut(p, ":")
+			if other, dup := hostPorts[host]; dup {
+				t.Errorf("host port %s used by %s and %s", host, other, name)
+			}
+			hostPorts[host] = name
+		}
+	}
+	for name, want := range map[string]string{
+		"admissions-api":        "8080:8080",
+		"cache-proxy":           "6380:6379",
+		"workflow-platform-api": "8081:8080",
+		"workflow-runtime":      "8082:8080",
+		"trigger-scheduler":     "8792:8792",
+		BackingRedis:            "6379:6379",
+		BackingPostgres:         "5432:5432",
+	} {
+		if got := file.Services[name].Ports; len(got) != 1 || got[0] != want {
+			t.Errorf("%s: ports = %v, want [%s]", name, got, want)
+		}
+	}
+
+	for name, want := range map[string][]string{
+		"workflow-runtime":      {"workflow-platform-api", BackingRedis},
+		"workflow-platform-api": {BackingPostgres, BackingRedis},
+		"trigger-scheduler":     {"workflow-platform-api", "workflow-runtime"},
+		"admissions-api":        nil,
+	} {
+		svc := file.Services[name]
+		if len(svc.DependsOn) != len(want) {
+			t.Errorf("%s: depends_on = %v, want %v", name, svc.DependsOn, want)
+		}
+		for _, dep := range want {
+			if svc.DependsOn[dep].Condition != "service_healthy" {
+				t.Errorf("%s: depends_on[%s] = %+v", name, dep, svc.DependsOn[dep])
+			}
+		}
+	}
+
+	api := file.Services["workflow-platform-api"]
+	if api.Build == nil || api.Build.Context != "." || api.Build.Dockerfile != "ops/packaging/services/workflow-platform-api.Dockerfile" {
+		t.Errorf("build = %+v", api.Build)
+	}
+	if api.Environment["PORT"] != "8080" || !strings.HasPrefix(api.Environment["DATABASE_URL"], "postgres://") || api.Environment["REDIS_URL"] == "" {
+		t.Errorf("environment = %v", api.Environment)
+	}
+	if _, ok := file.Services["admissions-api"].Environment["REDIS_URL"]; ok {
+		t.Error("service without needs got REDIS_URL")
+	}
+	if _, ok := file.Volumes["postgres-data"]; !ok {
+		t.Errorf("volumes = %v", file.Volumes)
+	}
+
+	again, err := GenerateCompose([]ServiceSpec{
+		{Name: "admissions-api", Language: "go", Port: 8080},
+		{Name: "trigger-scheduler", Language: "go", Port: 8792, DependsOn: []string{"workflow-platform-api", "workflow-runtime"}},
+		{Name: "cache-proxy", Language: "node", Port: 6379},
+		{Name: "workflow-platform-api", Language: "go", Port: 8080, Needs: []string{BackingPostgres, BackingRedis}},
+		{Name: "workflow-runtime", Language: "go", Port: 8080, DependsOn: []string{"workflow-platform-api"}, Needs: []string{BackingRedis}},
+	})
+	if err != nil || string(again) != string(out) {
+		t.Errorf("output depends on input order (err %v)", err)
+	}
+}
+
+func TestGenerateComposeMetricsPort(t *testing.T) {
+	out, err := GenerateCompose([]ServiceSpec{{Name: "billing", Language: "go", Port: 9090, MetricsPort: 9464}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "METRICS_PORT: \"9464\"") {
+		t.Errorf("METRICS_PORT not set:\n%s", out)
+	}
+	if strings.Contains(string(out), "9464:") {
+		t.Errorf("metrics port published on the host:\n%s", out)
+	}
+}
+
+func TestGenerateComposeWithoutBacking(t *testing.T) {
+	out, err := GenerateCompose([]ServiceSpec{{Name: "billing", Language: "go", Port: 9090}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"postgres", "redis", "volumes:"} {
+		if strings.Contains(string(out), unwanted) {
+			t.Errorf("output mentions %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestGenerateComposeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []ServiceSpec
+		want     []string
+	}{
+		{"unknown dependency", []ServiceSpec{
+			{Name: "a", Language: "go", Port: 80, DependsOn: []string{"b"}},
+			{Name: "c", Language: "go", Port: 81, DependsOn: []string{"d"}},
+		}, []string{`"a": unknown dependency "b"`, `"c": unknown dependency "d"`}},
+		{"cycle", []ServiceSpec{
+			{Name: "a", Language: "go", Port: 80, DependsOn: []string{"b"}},
+			{Name: "b", Language: "go", Port: 81, DependsOn: []string{"c"}},
+			{Name: "c", Language: "go", Port: 82, DependsOn: []string{"a"}},
+		}, []string{"dependency cycle: a -> b -> c -> a"}},
+		{"self", []ServiceSpec{{Name: "a", Language: "go", Port: 80, DependsOn: []string{"a"}}}, []string{"cycle: a -> a"}},
+		{"clashes", []ServiceSpec{
+			{Name: "redis", Language: "go", Port: 80},
+			{Name: "a", Language: "go", Port: 80},
+			{Name: "a", Language: "go", Port: 81},
+			{Name: "b", Language: "go", Port: 80, Needs: []string{"mysql"}},
+		}, []string{`"redis": name clashes`, `"a": duplicate service`, `unsupported backing service "mysql"`}},
+	}
+	for _, tt := range tests {
+		_, err := GenerateCompose(tt.services)
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error does not mention %q:\n%v", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestGenerateComposeOnlyAndLocal(t *testing.T) {
+	services := []ServiceSpec{
+		{Name: "gateway", Language: "go", Port: 8080, DependsOn: []string{"billing"}, Env: map[string]string{"ROUTES_FILE": "/etc/routes.yml"}},
+		{Name: "billing", Language: "go", Port: 9090, DependsOn: []string{"ledger"}, Needs: []string{BackingPostgres}},
+		{Name: "ledger", Language: "go", Port: 9091},
+		{Name: "portal", Language: "node", Port: 3000},
+	}
+	decode := func(opts ...ComposeOption) composeFile {
+		t.Helper()
+		out, err := GenerateCompose(services, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file composeFile
+		if err := yaml.Unmarshal(out, &file); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	keys := func(file composeFile) string {
+		var names []string
+		for name := range file.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+
+	file := decode(ComposeOnly("gateway"))
+	if got := keys(file); got != "billing,gateway,ledger,postgres" {
+		t.Errorf("--only gateway: services %s, want it with its dependencies", got)
+	}
+	if env := file.Services["gateway"].Environment; env["ROUTES_FILE"] != "/etc/routes.yml" {
+		t.Errorf("registry env missing: %v", env)
+	}
+
+	file = decode(ComposeLocal("billing"))
+	if got := keys(file); got != "gateway,ledger,portal,postgres" {
+		t.Errorf("--local billing: services %s, want billing left out but its postgres kept", got)
+	}
+	gw := file.Services["gateway"]
+	if _, ok := gw.DependsOn["billing"]; ok {
+		t.Errorf("gateway still waits on the local billing: %v", gw.DependsOn)
+	}
+	if len(gw.ExtraHosts) != 1 || gw.ExtraHosts[0] != "billing:host-gateway" {
+		t.Errorf("extra_hosts = %v", gw.ExtraHosts)
+	}
+	if _, err := GenerateCompose(services, ComposeOnly("search")); err == nil {
+		t.Error("--only an unregistered service: no error")
+	}
+
+	a, _ := GenerateCompose(services, ComposeOnly("gateway", "portal"))
+	b, _ := GenerateCompose(services, ComposeOnly("portal", "gateway"))
+	if !bytes.Equal(a, b) {
+		t.Error("output depends on the order of --only")
+	}
+}
+
+func TestRewriteComposeRoutes(t *testing.T) {
+	services := []ServiceSpec{
+		{Name: "billing", Language: "go", Port: 9090},
+		{Name: "ledger", Language: "go", Port: 8080},
+		{Name: "portal", Language: "node", Port: 8080},
+	}
+	table := `# local routes
+routes:
+  - path_prefix: /billing
+    upstream: http://localhost:9090/v1
+    timeout: 5s
+  - path_prefix: /ledger
+    upstream: https://ledger.internal:8443
+  - path_prefix: /docs
+    upstream: https://docs.example.com
+`
+	out, err := RewriteComposeRoutes([]byte(table), services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"upstream: http://billing:9090/v1", "upstream: https://ledger:8080", "upstream: https://docs.example.com", "timeout: 5s"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+	ambiguous := "routes:\n  - {path_prefix: /, upstream: 'http://127.0.0.1:8080'}\n"
+	if _, err := RewriteComposeRoutes([]byte(ambiguous), services); err == nil || !strings.Contains(err.Error(), "ledger, portal") {
+		t.Errorf("shared loopback port: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package packaging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContext = 2
+
+// Diff returns a line-oriented diff from a to b, or "" when they are
+// identical. Unchanged runs are trimmed to a few lines of context, which is
+// enough for the small files the pack tool renders.
+func Diff(aName, bName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	al, bl := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the LCS length of al[i:] and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		line string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			ops = append(ops, op{' ', al[i]})
+			i++
+			j++
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', al[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', bl[j]})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for k, o := range ops {
+		if o.kind == ' ' && !nearChange(ops, k, func(x op) bool { return x.kind != ' ' }) {
+			continue
+		}
+		fmt.Fprintf(&out, "%c%s\n", o.kind, o.line)
+	}
+	return out.String()
+}
+
+func nearChange[T any](ops []T, k int, changed func(T) bool) bool {
+	for d := -diffContext; d <= diffContext; d++ {
+		if n := k + d; n >= 0 && n < len(ops) && changed(ops[n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
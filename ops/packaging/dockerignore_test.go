@@ -0,0 +1,43 @@
+package packaging
+
+import "testing"
+
+func TestGenerateDockerignore(t *testing.T) {
+	out, err := GenerateDockerignore("admissions-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore := ParseDockerignore(out)
+	for _, name := range []string{
+		".git/HEAD",
+		"cmd/billing/main.go",
+		"cmd/admissions-api-v2/main.go",
+		"internal/store/memory_test.go",
+		"cmd/admissions-api/main_test.go",
+		"cmd/admissions-api/README.md",
+		"README.md",
+		"internal/auth/README.md",
+		"ops/packaging/render.go",
+		"apps/worker/node_modules/x/index.js",
+	} {
+		if !ignore.Ignored(name) {
+			t.Errorf("%s is in the build context, want it ignored", name)
+		}
+	}
+	for _, name := range []string{
+		"cmd/admissions-api/main.go",
+		"cmd/healthprobe/main.go",
+		"internal/store/memory.go",
+		"config/rbac.yaml",
+		"go.mod",
+		"go.sum",
+	} {
+		if ignore.Ignored(name) {
+			t.Errorf("%s is ignored, want it in the build context", name)
+		}
+	}
+
+	if _, err := GenerateDockerignore("../billing"); err == nil {
+		t.Error("GenerateDockerignore accepted a path as the service name")
+	}
+}
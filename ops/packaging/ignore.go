@@ -0,0 +1,113 @@
+package packaging
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Dockerignore matches paths against .dockerignore patterns with Docker's
+// rules: the last matching pattern wins, "!" re-includes, "**" spans
+// directories, and a pattern that matches a directory also matches
+// everything below it.
+type Dockerignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+}
+
+// ParseDockerignore parses .dockerignore content. Comment and blank lines
+// are skipped.
+func ParseDockerignore(data []byte) *Dockerignore {
+	d := &Dockerignore{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		pattern := path.Clean(strings.TrimPrefix(strings.TrimPrefix(line, "!"), "/"))
+		d.rules = append(d.rules, ignoreRule{pattern: pattern, re: globRE(pattern), negate: negate})
+	}
+	return d
+}
+
+// LoadContextIgnore returns the ignore rules BuildKit applies when building
+// dockerfile with contextDir: dockerfile's own <Dockerfile>.dockerignore if
+// it exists, else contextDir/.dockerignore, else none.
+func LoadContextIgnore(dockerfile, contextDir string) (*Dockerignore, error) {
+	for _, p := range []string{dockerfile + ".dockerignore", filepath.Join(contextDir, ".dockerignore")} {
+		data, err := os.ReadFile(p)
+		if err == nil {
+			return ParseDockerignore(data), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return &Dockerignore{}, nil
+}
+
+// Ignored reports whether name, a slash-separated path relative to the
+// build context, is excluded.
+func (d *Dockerignore) Ignored(name string) bool {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	excluded := false
+	for _, r := range d.rules {
+		for p := name; p != "." && p != "/"; p = path.Dir(p) {
+			if r.re.MatchString(p) {
+				excluded = !r.negate
+				break
+			}
+		}
+	}
+	return excluded
+}
+
+// mayReinclude reports whether a "!" pattern could match something below
+// the ignored directory dir, in which case a walk must still descend.
+func (d *Dockerignore) mayReinclude(dir string) bool {
+	for _, r := range d.rules {
+		if !r.negate {
+			continue
+		}
+		literal := r.pattern
+		if i := strings.IndexAny(literal, "*?["); i >= 0 {
+			literal = literal[:i]
+		}
+		if strings.HasPrefix(literal, dir+"/") || strings.HasPrefix(dir+"/", literal) {
+			return true
+		}
+	}
+	return false
+}
+
+func globRE(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
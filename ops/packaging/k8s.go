@@ -0,0 +1,542 @@
+package packaging
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// K8sDir is where pack k8s writes manifests, relative to the repository
+// root: the kustomize base the kind and staging overlays build on.
+const K8sDir = "ops/deploy/k8s/base"
+
+// K8sNamespace is the namespace of the hand-written manifests in K8sDir,
+// which the overlays patch by name and namespace.
+const K8sNamespace = "uniassist-staging"
+
+// Deployment defaults used when a registry entry leaves replicas or a
+// resource quantity unset.
+const (
+	DefaultReplicas      = 1
+	DefaultCPURequest    = "100m"
+	DefaultMemoryRequest = "128Mi"
+	DefaultCPULimit      = "500m"
+	DefaultMemoryLimit   = "256Mi"
+)
+
+// Resources are a container's CPU and memory requests and limits, in
+// Kubernetes quantity syntax.
+type Resources struct {
+	Requests ResourceList `yaml:"requests"`
+	Limits   ResourceList `yaml:"limits"`
+}
+
+// ResourceList is one side of Resources.
+type ResourceList struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+// Autoscale is the HorizontalPodAutoscaler of a service, scaling its
+// Deployment between MinReplicas and MaxReplicas to hold the pods' average
+// CPU use at CPUUtilization percent of their request.
+type Autoscale struct {
+	MinReplicas    int `yaml:"min_replicas"`
+	MaxReplicas    int `yaml:"max_replicas"`
+	CPUUtilization int `yaml:"cpu_utilization"`
+}
+
+// DefaultCPUUtilization is the target of an Autoscale that sets none.
+const DefaultCPUUtilization = 80
+
+// Validate rejects bounds the API server would, and a target outside
+// 1-100 percent.
+func (a Autoscale) Validate() error {
+	if a.MinReplicas < 1 || a.MaxReplicas < a.MinReplicas {
+		return fmt.Errorf("invalid autoscale replicas %d-%d: want 1 <= min_replicas <= max_replicas", a.MinReplicas, a.MaxReplicas)
+	}
+	if a.CPUUtilization < 0 || a.CPUUtilization > 100 {
+		return fmt.Errorf("invalid autoscale cpu_utilization %d: want a percentage", a.CPUUtilization)
+	}
+	return nil
+}
+
+var envNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnv are the variables every generated container gets from its
+// registry entry.
+var reservedEnv = []string{"PORT", "UNIASSIST_SERVICE_ID", "METRICS_PORT"}
+
+var (
+	cpuQuantityRE    = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?)(m?)$`)
+	memoryQuantityRE = regexp.MustCompile(`^([0-9]+)(Ki|Mi|Gi|Ti|k|M|G|T)?$`)
+	memoryUnits      = map[string]float64{"": 1, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40}
+)
+
+// withDefaults fills unset quantities.
+func (r Resources) withDefaults() Resources {
+	for _, q := range []struct {
+		v   *string
+		def string
+	}{
+		{&r.Requests.CPU, DefaultCPURequest},
+		{&r.Requests.Memory, DefaultMemoryRequest},
+		{&r.Limits.CPU, DefaultCPULimit},
+		{&r.Limits.Memory, DefaultMemoryLimit},
+	} {
+		if *q.v == "" {
+			*q.v = q.def
+		}
+	}
+	return r
+}
+
+// Validate checks the quantities parse and no request exceeds its limit,
+// which the API server would reject at apply time.
+func (r Resources) Validate() error {
+	r = r.withDefaults()
+	reqCPU, err := parseCPU(r.Requests.CPU)
+	if err != nil {
+		return err
+	}
+	limCPU, err := parseCPU(r.Limits.CPU)
+	if err != nil {
+		return err
+	}
+	reqMem, err := parseMemory(r.Requests.Memory)
+	if err != nil {
+		return err
+	}
+	limMem, err := parseMemory(r.Limits.Memory)
+	if err != nil {
+		return err
+	}
+	if reqCPU > limCPU {
+		return fmt.Errorf("cpu request %s exceeds limit %s", r.Requests.CPU, r.Limits.CPU)
+	}
+	if reqMem > limMem {
+		return fmt.Errorf("memory request %s exceeds limit %s", r.Requests.Memory, r.Limits.Memory)
+	}
+	return nil
+}
+
+// parseCPU returns a CPU quantity in millicores.
+func parseCPU(q string) (float64, error) {
+	m := cpuQuantityRE.FindStringSubmatch(q)
+	if m == nil {
+		return 0, fmt.Errorf("invalid cpu quantity %q: want cores such as 0.5 or millicores such as 500m", q)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	if m[3] == "" {
+		n *= 1000
+	}
+	return n, nil
+}
+
+// parseMemory returns a memory quantity in bytes.
+func parseMemory(q string) (float64, error) {
+	m := memoryQuantityRE.FindStringSubmatch(q)
+	if m == nil {
+		return 0, fmt.Errorf("invalid memory quantity %q: want a size such as 256Mi", q)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	return n * memoryUnits[m[2]], nil
+}
+
+// Runtime users of the rendered images, by the numeric ID Kubernetes
+// needs to verify runAsNonRoot: alpine's nobody, distroless's nonroot,
+// and the node image's node.
+const (
+	uidNobody  = 65534
+	uidNonroot = 65532
+	uidNode    = 1000
+)
+
+func runtimeUID(lang, base string) int64 {
+	switch {
+	case lang == "node":
+		return uidNode
+	case base == BaseDistroless:
+		return uidNonroot
+	}
+	return uidNobody
+}
+
+type k8sMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type k8sDeployment struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMeta           `yaml:"metadata"`
+	Spec       k8sDeploymentSpec `yaml:"spec"`
+}
+
+type k8sDeploymentSpec struct {
+	Replicas int            `yaml:"replicas"`
+	Selector k8sSelector    `yaml:"selector"`
+	Template k8sPodTemplate `yaml:"template"`
+}
+
+type k8sSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type k8sPodTemplate struct {
+	Metadata k8sMeta    `yaml:"metadata"`
+	Spec     k8sPodSpec `yaml:"spec"`
+}
+
+type k8sPodSpec struct {
+	SecurityContext k8sPodSecurity `yaml:"securityContext"`
+	Containers      []k8sContainer `yaml:"containers"`
+}
+
+type k8sPodSecurity struct {
+	RunAsNonRoot bool  `yaml:"runAsNonRoot"`
+	RunAsUser    int64 `yaml:"runAsUser"`
+}
+
+type k8sContainer struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	ImagePullPolicy string               `yaml:"imagePullPolicy"`
+	Ports           []k8sContainerPort   `yaml:"ports"`
+	EnvFrom         []k8sEnvFrom         `yaml:"envFrom"`
+	Env             []k8sEnv             `yaml:"env"`
+	ReadinessProbe  k8sProbe             `yaml:"readinessProbe"`
+	LivenessProbe   *k8sProbe            `yaml:"livenessProbe,omitempty"`
+	Resources       Resources            `yaml:"resources"`
+	SecurityContext k8sContainerSecurity `yaml:"securityContext"`
+}
+
+type k8sContainerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Name          string `yaml:"name"`
+}
+
+type k8sEnvFrom struct {
+	ConfigMapRef *k8sRef `yaml:"configMapRef,omitempty"`
+	SecretRef    *k8sRef `yaml:"secretRef,omitempty"`
+}
+
+type k8sRef struct {
+	Name string `yaml:"name"`
+}
+
+type k8sEnv struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type k8sProbe struct {
+	HTTPGet          k8sHTTPGet `yaml:"httpGet"`
+	PeriodSeconds    int        `yaml:"periodSeconds"`
+	TimeoutSeconds   int        `yaml:"timeoutSeconds"`
+	FailureThreshold int        `yaml:"failureThreshold"`
+}
+
+type k8sHTTPGet struct {
+	Path string `yaml:"path"`
+	Port int    `yaml:"port"`
+}
+
+type k8sContainerSecurity struct {
+	AllowPrivilegeEscalation bool            `yaml:"allowPrivilegeEscalation"`
+	Capabilities             k8sCapabilities `yaml:"capabilities"`
+}
+
+type k8sCapabilities struct {
+	Drop []string `yaml:"drop"`
+}
+
+type k8sService struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   k8sMeta        `yaml:"metadata"`
+	Spec       k8sServiceSpec `yaml:"spec"`
+}
+
+type k8sServiceSpec struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []k8sServicePort  `yaml:"ports"`
+}
+
+type k8sServicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+}
+
+type k8sHPA struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   k8sMeta    `yaml:"metadata"`
+	Spec       k8sHPASpec `yaml:"spec"`
+}
+
+type k8sHPASpec struct {
+	ScaleTargetRef k8sTargetRef `yaml:"scaleTargetRef"`
+	MinReplicas    int          `yaml:"minReplicas"`
+	MaxReplicas    int          `yaml:"maxReplicas"`
+	Metrics        []k8sMetric  `yaml:"metrics"`
+}
+
+type k8sTargetRef struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+type k8sMetric struct {
+	Type     string            `yaml:"type"`
+	Resource k8sResourceMetric `yaml:"resource"`
+}
+
+type k8sResourceMetric struct {
+	Name   string          `yaml:"name"`
+	Target k8sMetricTarget `yaml:"target"`
+}
+
+type k8sMetricTarget struct {
+	Type               string `yaml:"type"`
+	AverageUtilization int    `yaml:"averageUtilization"`
+}
+
+// K8sOption customizes GenerateK8sManifests.
+type K8sOption func(*k8sOptions)
+
+type k8sOptions struct {
+	image string
+}
+
+// WithImage runs image, such as the one pack build tagged, instead of
+// uniassist/<name>:local, the one the kind overlay loads.
+func WithImage(image string) K8sOption {
+	return func(o *k8sOptions) { o.image = image }
+}
+
+// probePeriod is how often Kubernetes probes readiness and liveness. It
+// is shorter than the image's HEALTHCHECK interval so a new pod takes
+// traffic, and a draining one sheds it, within seconds.
+const probePeriod = 10
+
+// GenerateK8sManifests returns a Deployment and a Service for spec, keyed
+// by file name (<name>-deployment.yaml and <name>-service.yaml), in the
+// shape of the hand-written manifests in K8sDir, and with Autoscale set a
+// HorizontalPodAutoscaler (<name>-hpa.yaml). The container port and,
+// unless the spec sets Readiness, the readiness probe are the ones the
+// rendered Dockerfile EXPOSEs and HEALTHCHECKs; the pod runs as the
+// image's non-root user by UID, as runAsNonRoot requires.
+func GenerateK8sManifests(spec ServiceSpec, opts ...K8sOption) (map[string][]byte, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	o := k8sOptions{image: "uniassist/" + spec.Name + ":local"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	v := spec.Vars().withDefaults()
+	replicas := spec.Replicas
+	if replicas == 0 {
+		replicas = DefaultReplicas
+	}
+	if spec.Autoscale != nil {
+		replicas = spec.Autoscale.MinReplicas
+	}
+	timeout, _ := time.ParseDuration(v.HealthTimeout)
+	probe := func(path string) k8sProbe {
+		return k8sProbe{
+			HTTPGet:          k8sHTTPGet{Path: path, Port: v.ExposePort},
+			PeriodSeconds:    probePeriod,
+			TimeoutSeconds:   max(1, int(math.Ceil(timeout.Seconds()))),
+			FailureThreshold: v.HealthRetries,
+		}
+	}
+	readiness := spec.Readiness
+	if readiness == "" {
+		readiness = v.HealthPath
+	}
+	var liveness *k8sProbe
+	if spec.Liveness != "" {
+		p := probe(spec.Liveness)
+		liveness = &p
+	}
+	labels := map[string]string{"app": spec.Name}
+	meta := k8sMeta{Name: spec.Name, Namespace: K8sNamespace, Labels: labels}
+
+	pod := k8sMeta{Labels: labels}
+	container := k8sContainer{
+		Name:            spec.Name,
+		Image:           o.image,
+		ImagePullPolicy: "IfNotPresent",
+		Ports:           []k8sContainerPort{{ContainerPort: v.ExposePort, Name: "http"}},
+		EnvFrom: []k8sEnvFrom{
+			{ConfigMapRef: &k8sRef{Name: "uniassist-common-config"}},
+			{SecretRef: &k8sRef{Name: "uniassist-secrets"}},
+		},
+		Env: []k8sEnv{
+			{Name: "PORT", Value: strconv.Itoa(v.ExposePort)},
+			{Name: "UNIASSIST_SERVICE_ID", Value: spec.Name},
+		},
+		ReadinessProbe: probe(readiness),
+		LivenessProbe:  liveness,
+		Resources:      spec.Resources.withDefaults(),
+		SecurityContext: k8sContainerSecurity{
+			Capabilities: k8sCapabilities{Drop: []string{"ALL"}},
+		},
+	}
+	if v.MetricsPort != 0 {
+		// Scraped on the pod directly; the Service only carries http.
+		port := strconv.Itoa(v.MetricsPort)
+		container.Ports = append(container.Ports, k8sContainerPort{ContainerPort: v.MetricsPort, Name: "metrics"})
+		container.Env = append(container.Env, k8sEnv{Name: "METRICS_PORT", Value: port})
+		pod.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": port, "prometheus.io/path": "/metrics"}
+	}
+	names := make([]string, 0, len(spec.Env))
+	for name := range spec.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		container.Env = append(container.Env, k8sEnv{Name: name, Value: spec.Env[name]})
+	}
+
+	deployment := k8sDeployment{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   meta,
+		Spec: k8sDeploymentSpec{
+			Replicas: replicas,
+			Selector: k8sSelector{MatchLabels: labels},
+			Template: k8sPodTemplate{
+				Metadata: pod,
+				Spec: k8sPodSpec{
+					SecurityContext: k8sPodSecurity{RunAsNonRoot: true, RunAsUser: runtimeUID(spec.Language, v.Base)},
+					Containers:      []k8sContainer{container},
+				},
+			},
+		},
+	}
+	service := k8sService{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Metadata:   meta,
+		Spec: k8sServiceSpec{
+			Selector: labels,
+			Ports:    []k8sServicePort{{Name: "http", Port: v.ExposePort, TargetPort: v.ExposePort}},
+		},
+	}
+
+	docs := map[string]any{
+		spec.Name + "-deployment.yaml": deployment,
+		spec.Name + "-service.yaml":    service,
+	}
+	if a := spec.Autoscale; a != nil {
+		target := a.CPUUtilization
+		if target == 0 {
+			target = DefaultCPUUtilization
+		}
+		docs[spec.Name+"-hpa.yaml"] = k8sHPA{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+			Metadata:   meta,
+			Spec: k8sHPASpec{
+				ScaleTargetRef: k8sTargetRef{APIVersion: "apps/v1", Kind: "Deployment", Name: spec.Name},
+				MinReplicas:    a.MinReplicas,
+				MaxReplicas:    a.MaxReplicas,
+				Metrics: []k8sMetric{{
+					Type:     "Resource",
+					Resource: k8sResourceMetric{Name: "cpu", Target: k8sMetricTarget{Type: "Utilization", AverageUtilization: target}},
+				}},
+			},
+		}
+	}
+	out := map[string][]byte{}
+	for name, doc := range docs {
+		data, err := marshalK8s(spec.Name, doc)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = data
+	}
+	return out, nil
+}
+
+func marshalK8s(service string, doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated with `go run ./ops/packaging/cmd/pack k8s`; edit the %s\n# entry in %s, not this file.\n", service, RegistryPath)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SetK8sValue applies one pack k8s --set override, key=value, to spec, or
+// to the image WithImage would take. The keys are the registry's own
+// (replicas, resources.limits.cpu, autoscale.max_replicas, ...), plus
+// env.<NAME> for one variable and image for the container image; the
+// result is validated by GenerateK8sManifests like any registry entry.
+func SetK8sValue(spec *ServiceSpec, image *string, kv string) error {
+	key, value, ok := strings.Cut(kv, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("--set %q: want key=value", kv)
+	}
+	if name, ok := strings.CutPrefix(key, "env."); ok {
+		env := make(map[string]string, len(spec.Env)+1)
+		for k, v := range spec.Env {
+			env[k] = v
+		}
+		env[name] = value
+		spec.Env = env
+		return nil
+	}
+	if strings.HasPrefix(key, "autoscale.") && spec.Autoscale == nil {
+		spec.Autoscale = &Autoscale{}
+	} else if spec.Autoscale != nil {
+		a := *spec.Autoscale
+		spec.Autoscale = &a
+	}
+	strs := map[string]*string{
+		"image":                     image,
+		"resources.requests.cpu":    &spec.Resources.Requests.CPU,
+		"resources.requests.memory": &spec.Resources.Requests.Memory,
+		"resources.limits.cpu":      &spec.Resources.Limits.CPU,
+		"resources.limits.memory":   &spec.Resources.Limits.Memory,
+	}
+	ints := map[string]*int{"replicas": &spec.Replicas}
+	if a := spec.Autoscale; a != nil {
+		ints["autoscale.min_replicas"] = &a.MinReplicas
+		ints["autoscale.max_replicas"] = &a.MaxReplicas
+		ints["autoscale.cpu_utilization"] = &a.CPUUtilization
+	}
+	if p, ok := strs[key]; ok {
+		*p = value
+		return nil
+	}
+	if p, ok := ints[key]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("--set %s: %q is not an integer", key, value)
+		}
+		*p = n
+		return nil
+	}
+	return fmt.Errorf("--set %s: unknown key (want replicas, image, resources.{requests,limits}.{cpu,memory}, autoscale.{min_replicas,max_replicas,cpu_utilization}, or env.<NAME>)", key)
+}
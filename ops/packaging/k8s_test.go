@@ -0,0 +1,232 @@
+package packaging
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateK8sManifests(t *testing.T) {
+	for _, spec := range []ServiceSpec{
+		{Name: "admissions-api", Language: "go", Port: 8080, Health: "/health"},
+		{Name: "billing", Language: "go", Port: 9090, Base: BaseDistroless, Health: "/ready"},
+		{Name: "portal", Language: "node", Port: 3000},
+	} {
+		t.Run(spec.Name, func(t *testing.T) {
+			files, err := GenerateK8sManifests(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("got %d files, want a deployment and a service", len(files))
+			}
+			var deployment k8sDeployment
+			if err := yaml.Unmarshal(files[spec.Name+"-deployment.yaml"], &deployment); err != nil {
+				t.Fatalf("deployment is not YAML: %v", err)
+			}
+			var service k8sService
+			if err := yaml.Unmarshal(files[spec.Name+"-service.yaml"], &service); err != nil {
+				t.Fatalf("service is not YAML: %v", err)
+			}
+			if deployment.Kind != "Deployment" || service.Kind != "Service" {
+				t.Fatalf("kinds = %q, %q", deployment.Kind, service.Kind)
+			}
+
+			// The probe and port must be the ones the image itself
+			// EXPOSEs and HEALTHCHECKs.
+			dockerfile, err := Render(spec.Language, spec.Vars())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pod := deployment.Spec.Template.Spec
+			c := pod.Containers[0]
+			port := c.Ports[0].ContainerPort
+			if !strings.Contains(string(dockerfile), fmt.Sprintf("\nEXPOSE %d\n", port)) {
+				t.Errorf("containerPort %d is not the Dockerfile's EXPOSE", port)
+			}
+			probe := c.ReadinessProbe.HTTPGet
+			if url := fmt.Sprintf("http://localhost:%d%s", probe.Port, probe.Path); !strings.Contains(string(dockerfile), url) {
+				t.Errorf("readiness probe %s is not the Dockerfile's HEALTHCHECK", url)
+			}
+			if sp := service.Spec.Ports[0]; sp.TargetPort != port {
+				t.Errorf("service targetPort %d, want %d", sp.TargetPort, port)
+			}
+			if deployment.Spec.Selector.MatchLabels["app"] != spec.Name || service.Spec.Selector["app"] != spec.Name {
+				t.Errorf("selectors do not match app=%s", spec.Name)
+			}
+
+			if !pod.SecurityContext.RunAsNonRoot || pod.SecurityContext.RunAsUser == 0 {
+				t.Errorf("pod security context = %+v, want a non-root UID", pod.SecurityContext)
+			}
+			if c.SecurityContext.AllowPrivilegeEscalation || len(c.SecurityContext.Capabilities.Drop) != 1 {
+				t.Errorf("container security context = %+v", c.SecurityContext)
+			}
+		})
+	}
+}
+
+func TestGenerateK8sManifestsSizing(t *testing.T) {
+	files, err := GenerateK8sManifests(ServiceSpec{
+		Name: "billing", Language: "go", Port: 9090, Base: BaseDistroless, HealthTimeout: "1500ms", HealthRetries: 5, Replicas: 3,
+		Resources: Resources{Requests: ResourceList{CPU: "250m"}, Limits: ResourceList{Memory: "1Gi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d k8sDeployment
+	if err := yaml.Unmarshal(files["billing-deployment.yaml"], &d); err != nil {
+		t.Fatal(err)
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	want := Resources{
+		Requests: ResourceList{CPU: "250m", Memory: DefaultMemoryRequest},
+		Limits:   ResourceList{CPU: DefaultCPULimit, Memory: "1Gi"},
+	}
+	if d.Spec.Replicas != 3 || c.Resources != want {
+		t.Errorf("replicas %d, resources %+v; want 3, %+v", d.Spec.Replicas, c.Resources, want)
+	}
+	if p := c.ReadinessProbe; p.TimeoutSeconds != 2 || p.FailureThreshold != 5 {
+		t.Errorf("probe timeout %ds, threshold %d; want 2s, 5", p.TimeoutSeconds, p.FailureThreshold)
+	}
+	if uid := d.Spec.Template.Spec.SecurityContext.RunAsUser; uid != uidNonroot {
+		t.Errorf("distroless runAsUser = %d, want %d", uid, uidNonroot)
+	}
+}
+
+func TestGenerateK8sManifestsProbes(t *testing.T) {
+	spec := ServiceSpec{Name: "billing", Language: "go", Port: 9090, Health: "/health"}
+	probes := func() (*k8sProbe, *k8sProbe) {
+		t.Helper()
+		files, err := GenerateK8sManifests(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d k8sDeployment
+		if err := yaml.Unmarshal(files["billing-deployment.yaml"], &d); err != nil {
+			t.Fatal(err)
+		}
+		c := d.Spec.Template.Spec.Containers[0]
+		return &c.ReadinessProbe, c.LivenessProbe
+	}
+	if ready, live := probes(); ready.HTTPGet.Path != "/health" || live != nil {
+		t.Errorf("defaults: readiness %s, liveness %+v; want /health and none", ready.HTTPGet.Path, live)
+	}
+	spec.Readiness, spec.Liveness = "/healthz/ready", "/healthz/live"
+	ready, live := probes()
+	if ready.HTTPGet.Path != "/healthz/ready" || live == nil || live.HTTPGet.Path != "/healthz/live" || live.HTTPGet.Port != 9090 {
+		t.Errorf("readiness %+v, liveness %+v", ready, live)
+	}
+	spec.Liveness = "healthz"
+	if _, err := GenerateK8sManifests(spec); err == nil {
+		t.Error("relative liveness path accepted")
+	}
+}
+
+func TestGenerateK8sManifestsMetricsPort(t *testing.T) {
+	files, err := GenerateK8sManifests(ServiceSpec{Name: "billing", Language: "go", Port: 9090, MetricsPort: 9464})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d k8sDeployment
+	if err := yaml.Unmarshal(files["billing-deployment.yaml"], &d); err != nil {
+		t.Fatal(err)
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	if len(c.Ports) != 2 || c.Ports[1] != (k8sContainerPort{ContainerPort: 9464, Name: "metrics"}) {
+		t.Errorf("container ports = %+v", c.Ports)
+	}
+	if a := d.Spec.Template.Metadata.Annotations; a["prometheus.io/scrape"] != "true" || a["prometheus.io/port"] != "9464" {
+		t.Errorf("pod annotations = %v", a)
+	}
+	var s k8sService
+	if err := yaml.Unmarshal(files["billing-service.yaml"], &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Spec.Ports) != 1 {
+		t.Errorf("service exposes the metrics port: %+v", s.Spec.Ports)
+	}
+}
+
+func TestGenerateK8sManifestsAutoscaleAndEnv(t *testing.T) {
+	spec := ServiceSpec{
+		Name: "billing", Language: "go", Port: 9090, Replicas: 1,
+		Autoscale: &Autoscale{MinReplicas: 2, MaxReplicas: 6},
+		Env:       map[string]string{"LOG_LEVEL": "debug", "FEATURE_X": "on"},
+	}
+	files, err := GenerateK8sManifests(spec, WithImage("registry.example.com/billing:abc123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d k8sDeployment
+	if err := yaml.Unmarshal(files["billing-deployment.yaml"], &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Spec.Replicas != 2 {
+		t.Errorf("replicas = %d, want the autoscaler's minimum", d.Spec.Replicas)
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	if c.Image != "registry.example.com/billing:abc123" {
+		t.Errorf("image = %q", c.Image)
+	}
+	var names []string
+	for _, e := range c.Env {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "PORT,UNIASSIST_SERVICE_ID,FEATURE_X,LOG_LEVEL" {
+		t.Errorf("env = %s", got)
+	}
+	var hpa k8sHPA
+	if err := yaml.Unmarshal(files["billing-hpa.yaml"], &hpa); err != nil {
+		t.Fatalf("hpa is not YAML: %v", err)
+	}
+	if hpa.Spec.ScaleTargetRef.Name != "billing" || hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 6 {
+		t.Errorf("hpa spec = %+v", hpa.Spec)
+	}
+	if m := hpa.Spec.Metrics; len(m) != 1 || m[0].Resource.Target.AverageUtilization != DefaultCPUUtilization {
+		t.Errorf("hpa metrics = %+v", m)
+	}
+}
+
+func TestSetK8sValue(t *testing.T) {
+	spec := ServiceSpec{Name: "billing", Language: "go", Port: 9090, Env: map[string]string{"A": "1"}}
+	image := ""
+	for _, kv := range []string{"replicas=3", "resources.limits.memory=512Mi", "autoscale.max_replicas=4", "autoscale.min_replicas=2", "env.B=2", "image=billing:v1"} {
+		if err := SetK8sValue(&spec, &image, kv); err != nil {
+			t.Fatalf("%s: %v", kv, err)
+		}
+	}
+	if spec.Replicas != 3 || spec.Resources.Limits.Memory != "512Mi" || image != "billing:v1" {
+		t.Errorf("spec = %+v, image = %q", spec, image)
+	}
+	if spec.Autoscale == nil || *spec.Autoscale != (Autoscale{MinReplicas: 2, MaxReplicas: 4}) {
+		t.Errorf("autoscale = %+v", spec.Autoscale)
+	}
+	if spec.Env["A"] != "1" || spec.Env["B"] != "2" {
+		t.Errorf("env = %v", spec.Env)
+	}
+	for _, kv := range []string{"replicas", "replicas=many", "ports=80"} {
+		if err := SetK8sValue(&spec, &image, kv); err == nil {
+			t.Errorf("%s: no error", kv)
+		}
+	}
+}
+
+func TestResourcesValidate(t *testing.T) {
+	for _, tc := range []struct {
+		r    Resources
+		want string
+	}{
+		{Resources{}, ""},
+		{Resources{Requests: ResourceList{CPU: "1.5"}, Limits: ResourceList{CPU: "2000m", Memory: "2G"}}, ""},
+		{Resources{Requests: ResourceList{CPU: "600m"}}, "cpu request 600m exceeds limit 500m"},
+		{Resources{Limits: ResourceList{Memory: "100Mi"}}, "memory request 128Mi exceeds limit 100Mi"},
+		{Resources{Requests: ResourceList{Memory: "lots"}}, `invalid memory quantity "lots"`},
+	} {
+		err := tc.r.Validate()
+		if (tc.want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%+v: err = %v, want %q", tc.r, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package packaging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CheckLanguage catches a --lang that contradicts the service's source
+// directory, such as rendering the Node template for a Go module.
+func CheckLanguage(srcDir, lang string) error {
+	exists := func(name string) (bool, error) {
+		_, err := os.Stat(filepath.Join(srcDir, name))
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	hasGoMod, err := exists("go.mod")
+	if err != nil {
+		return err
+	}
+	if lang == "node" && hasGoMod {
+		return fmt.Errorf("%s contains go.mod; use --lang go for Go services", srcDir)
+	}
+	return nil
+}
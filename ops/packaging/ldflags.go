@@ -0,0 +1,79 @@
+package packaging
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Build arguments the Go Dockerfile template turns into -ldflags, stamping
+// main.version, main.commit, and main.buildTime into the binary. The
+// Dockerfile defaults each to "unknown".
+const (
+	ArgVersion   = "VERSION"
+	ArgCommit    = "COMMIT"
+	ArgBuildTime = "BUILD_TIME"
+)
+
+// BuildArgs returns the --build-arg values for meta. Characters that could
+// break out of the Dockerfile's -X quoting are replaced with '_', and empty
+// values become "unknown".
+func BuildArgs(meta GitMetadata) map[string]string {
+	return map[string]string{
+		ArgVersion:   sanitizeLDValue(meta.Version),
+		ArgCommit:    sanitizeLDValue(meta.Commit),
+		ArgBuildTime: sanitizeLDValue(meta.BuildTime),
+	}
+}
+
+func sanitizeLDValue(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(" .,_+-:/@", r):
+			return r
+		}
+		return '_'
+	}, v)
+}
+
+// GitMetadata is the version information derived from a git checkout.
+type GitMetadata struct {
+	Version   string
+	Commit    string
+	BuildTime string
+}
+
+// ReadGitMetadata describes HEAD of the repository at dir: the nearest tag
+// (or abbreviated commit), the full commit SHA, and the commit date. The
+// commit date rather than the wall clock keeps rebuilds of one commit
+// identical.
+func ReadGitMetadata(ctx context.Context, dir string) (GitMetadata, error) {
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	var m GitMetadata
+	var err error
+	if m.Version, err = git("describe", "--tags", "--always", "--dirty"); err != nil {
+		return GitMetadata{}, err
+	}
+	if m.Commit, err = git("rev-parse", "HEAD"); err != nil {
+		return GitMetadata{}, err
+	}
+	if m.BuildTime, err = git("log", "-1", "--format=%cI"); err != nil {
+		return GitMetadata{}, err
+	}
+	return m, nil
+}
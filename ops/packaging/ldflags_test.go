@@ -0,0 +1,91 @@
+package packaging
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// splitGoFlags mimics how the go tool splits an -ldflags value: on spaces,
+// keeping single- or double-quoted runs together.
+func splitGoFlags(t *testing.T, s string) []string {
+	t.Helper()
+	var out []string
+	var cur strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inField = r, true
+		case r == ' ':
+			if inField {
+				out = append(out, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		t.Fatalf("unterminated quote in %q", s)
+	}
+	if inField {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// ldflagsFor returns the -ldflags value the rendered Dockerfile passes to
+// go build once Docker substitutes the build args.
+func ldflagsFor(t *testing.T, dockerfile []byte, args map[string]string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(string(dockerfile), `-ldflags "`)
+	if !ok {
+		t.Fatalf("no -ldflags in:\n%s", dockerfile)
+	}
+	value, _, _ := strings.Cut(rest, `"`)
+	return os.Expand(value, func(k string) string { return args[k] })
+}
+
+func TestRenderStampsBuildArgs(t *testing.T) {
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ARG VERSION=unknown\n", "ARG COMMIT=unknown\n", "ARG BUILD_TIME=unknown\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+	args := BuildArgs(GitMetadata{Version: "v1.4.0", Commit: "0a1b2c3", BuildTime: "2024-05-01 10:00:00 +0000"})
+	got := splitGoFlags(t, ldflagsFor(t, out, args))
+	want := []string{"-X", "main.version=v1.4.0", "-X", "main.commit=0a1b2c3", "-X", "main.buildTime=2024-05-01 10:00:00 +0000"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("ldflags split = %q, want %q", got, want)
+	}
+}
+
+func TestBuildArgsSanitizes(t *testing.T) {
+	args := BuildArgs(GitMetadata{Version: `v1'"; rm -rf / #`, Commit: "$(whoami)"})
+	for k, v := range args {
+		for _, bad := range []string{`"`, "'", "$", "(", ";", "#"} {
+			if strings.Contains(v, bad) {
+				t.Errorf("%s: unsafe %q survived in %q", k, bad, v)
+			}
+		}
+	}
+	if args[ArgBuildTime] != "unknown" {
+		t.Errorf("empty build time = %q, want unknown", args[ArgBuildTime])
+	}
+	out, _ := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if got := splitGoFlags(t, ldflagsFor(t, out, args)); len(got) != 6 {
+		t.Fatalf("sanitized ldflags split into %q", got)
+	}
+}
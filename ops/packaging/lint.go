@@ -0,0 +1,425 @@
+package packaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity grades a LintIssue. Errors should block a commit; warnings
+// flag checks that could not run.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rule names, reported in LintIssue.Rule.
+const (
+	RulePlaceholder = "placeholder"
+	RuleUser        = "non-root-user"
+	RuleCGO         = "static-cgo"
+	RuleExpose      = "expose-port"
+	RulePinned      = "pinned-base"
+	RuleRemoteAdd   = "no-remote-add"
+	RuleMultiStage  = "multi-stage"
+	// RuleDisable reports malformed pack-lint:disable comments; it cannot
+	// itself be disabled.
+	RuleDisable = "lint-disable"
+)
+
+// LintRules are the rules a pack-lint:disable comment can name.
+var LintRules = []string{RulePlaceholder, RuleUser, RuleCGO, RuleExpose, RulePinned, RuleRemoteAdd, RuleMultiStage}
+
+// disablePrefix starts a comment that suppresses rules for the instruction
+// below it:
+//
+//	# pack-lint:disable=non-root-user the entrypoint drops privileges itself
+//	FROM alpine:3.19
+//
+// Several rules are separated by commas, and the justification after them
+// is required.
+const disablePrefix = "pack-lint:disable="
+
+// DefaultBaseImages are the tags base images may be pinned to, other than
+// by digest, when the registry sets no allowed_base_images: the ones the
+// templates render.
+var DefaultBaseImages = []string{
+	"golang:" + DefaultGoVersion + "-alpine",
+	"alpine:3.19",
+	"gcr.io/distroless/static-debian12:nonroot",
+	"node:" + DefaultNodeVersion + "-alpine",
+	"python:3.12-slim",
+}
+
+// LintOption adjusts the rules Lint applies.
+type LintOption func(*lintOptions)
+
+type lintOptions struct {
+	baseImages []string
+}
+
+// AllowBaseImages replaces DefaultBaseImages as the references base
+// images may use without a digest. Patterns use path.Match syntax, so
+// "node:22*" allows every Node 22 tag.
+func AllowBaseImages(patterns ...string) LintOption {
+	return func(o *lintOptions) { o.baseImages = patterns }
+}
+
+// LintIssue is one problem found by Lint. Line is 1-based; zero means the
+// issue concerns the file as a whole.
+type LintIssue struct {
+	Line     int
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d: %s: %s [%s]", i.Line, i.Severity, i.Message, i.Rule)
+}
+
+// placeholderRE matches leftover scaffolding such as <name> or an
+// unexpanded template action.
+var placeholderRE = regexp.MustCompile(`<[A-Za-z][A-Za-z0-9_.-]*>|\{\{`)
+
+// Lint checks a service Dockerfile under ServicesDir:
+//
+//   - no <name> placeholder or {{ template action is left unresolved;
+//   - every runtime stage runs as a non-root USER, or from a :nonroot
+//     base image;
+//   - every `go build` sets CGO_ENABLED=0 unless spec enables cgo;
+//   - every runtime stage EXPOSEs spec's port and no other but its
+//     metrics port;
+//   - every base image is pinned by digest or to an allowed tag (see
+//     AllowBaseImages), never to latest;
+//   - no ADD fetches a remote URL;
+//   - a Go service builds in a stage of its own, so the toolchain stays
+//     out of the runtime image.
+//
+// spec is the service's registry entry; for a nil spec, a service not in
+// the registry, the port check is skipped with a warning, and the service
+// counts as Go if it runs go build. Runtime stages are the last stage and
+// any stage with EXPOSE, CMD, or ENTRYPOINT, so multi-arch files are
+// checked per architecture. An issue on an instruction is suppressed by a
+// pack-lint:disable comment above it that names its rule with a
+// justification (see disablePrefix). Issues are sorted by line. The error
+// is reserved for files that cannot be read or parsed.
+func Lint(file string, spec *ServiceSpec, opts ...LintOption) ([]LintIssue, error) {
+	o := lintOptions{baseImages: DefaultBaseImages}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	hasSpec := spec != nil
+	if !hasSpec {
+		spec = &ServiceSpec{}
+	}
+
+	var issues []LintIssue
+	add := func(line int, sev Severity, rule, format string, args ...any) {
+		issues = append(issues, LintIssue{Line: line, Severity: sev, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, line := range splitLines(data) {
+		if m := placeholderRE.FindString(line); m != "" {
+			add(i+1, SeverityError, RulePlaceholder, "unresolved placeholder %q", m)
+		}
+	}
+
+	stages := parseStages(data)
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("%s: no FROM instruction", file)
+	}
+	if !hasSpec {
+		add(0, SeverityWarning, RuleExpose, "service not in %s; EXPOSE not checked against a declared port", RegistryPath)
+	}
+	args := globalArgs(data)
+	goBuild := false
+	for i, st := range stages {
+		// A FROM naming a global ARG is checked against its default.
+		image := os.Expand(st.image(), func(name string) string {
+			if v, ok := args[name]; ok {
+				return v
+			}
+			return "${" + name + "}"
+		})
+		switch {
+		case slices.ContainsFunc(stages[:i], func(prev stage) bool { return strings.EqualFold(prev.name(), image) }):
+			// An earlier stage, checked there.
+		case strings.Contains(image, "$"):
+			add(st.from.line, SeverityWarning, RulePinned, "base image %s is set by a build argument; pinning not checked", image)
+		case !pinnedImage(image, o.baseImages):
+			add(st.from.line, SeverityError, RulePinned, "base image %s is not pinned by digest or to a tag in allowed_base_images of %s", image, RegistryPath)
+		}
+		for _, in := range st.instructions {
+			if in.keyword == "RUN" && strings.Contains(in.args, "go build") {
+				goBuild = true
+				if !spec.CGO && !strings.Contains(in.args, "CGO_ENABLED=0") {
+					add(in.line, SeverityError, RuleCGO, "go build without CGO_ENABLED=0; set cgo: true in %s if the service needs cgo", RegistryPath)
+				}
+			}
+			if in.keyword == "ADD" {
+				for _, src := range addSources(in.args) {
+					if isRemoteSource(src) {
+						add(in.line, SeverityError, RuleRemoteAdd, "ADD fetches %s; download and verify it in a RUN step instead", src)
+					}
+				}
+			}
+		}
+		if i != len(stages)-1 && !st.isRuntime() {
+			continue
+		}
+
+		user := st.last("USER")
+		switch {
+		case user != nil && isRootUser(user.args):
+			add(user.line, SeverityError, RuleUser, "stage %s runs as root", st.name())
+		case user == nil && !strings.Contains(st.from.args, ":nonroot"):
+			add(st.from.line, SeverityError, RuleUser, "stage %s sets no USER and so runs as root", st.name())
+		}
+
+		if !hasSpec {
+			continue
+		}
+		exposed := st.all("EXPOSE")
+		if len(exposed) == 0 {
+			add(st.from.line, SeverityError, RuleExpose, "stage %s does not EXPOSE port %d", st.name(), spec.Port)
+		}
+		for _, in := range exposed {
+			for _, port := range strings.Fields(in.args) {
+				if p, _, _ := strings.Cut(port, "/"); p != fmt.Sprint(spec.Port) && (spec.MetricsPort == 0 || p != fmt.Sprint(spec.MetricsPort)) {
+					add(in.line, SeverityError, RuleExpose, "EXPOSE %s does not match port %d in %s", port, spec.Port, RegistryPath)
+				}
+			}
+		}
+	}
+	if len(stages) == 1 && (spec.Language == "go" || !hasSpec && goBuild) {
+		add(stages[0].from.line, SeverityError, RuleMultiStage, "Go service built in a single stage; build in a builder stage and COPY the binary into the runtime stage")
+	}
+	issues = suppress(issues, stages)
+	sort.SliceStable(issues, func(a, b int) bool { return issues[a].Line < issues[b].Line })
+	return issues, nil
+}
+
+// suppress drops the issues that pack-lint:disable comments above their
+// instructions name, and reports the comments that are malformed.
+func suppress(issues []LintIssue, stages []stage) []LintIssue {
+	type lineRule struct {
+		line int
+		rule string
+	}
+	disabled := map[lineRule]bool{}
+	var bad []LintIssue
+	for _, st := range stages {
+		for _, in := range append([]instruction{st.from}, st.instructions...) {
+			for _, d := range in.disables {
+				rules, reason, _ := strings.Cut(d.text, " ")
+				if strings.TrimSpace(reason) == "" {
+					bad = append(bad, LintIssue{Line: d.line, Severity: SeverityError, Rule: RuleDisable, Message: "pack-lint:disable needs a justification after the rules"})
+					continue
+				}
+				for _, rule := range strings.Split(rules, ",") {
+					if !slices.Contains(LintRules, rule) {
+						bad = append(bad, LintIssue{Line: d.line, Severity: SeverityError, Rule: RuleDisable, Message: fmt.Sprintf("pack-lint:disable names unknown rule %q", rule)})
+						continue
+					}
+					for line := in.line; line <= in.end; line++ {
+						disabled[lineRule{line, rule}] = true
+					}
+				}
+			}
+		}
+	}
+	kept := issues[:0]
+	for _, i := range issues {
+		if !disabled[lineRule{i.Line, i.Rule}] {
+			kept = append(kept, i)
+		}
+	}
+	return append(kept, bad...)
+}
+
+// pinnedImage reports whether a base image reference is pinned: by
+// digest, to a tag matching one of allowed, or to no image at all.
+func pinnedImage(image string, allowed []string) bool {
+	if image == "scratch" || strings.Contains(image, "@sha256:") {
+		return true
+	}
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, image); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addSources returns the sources of an ADD instruction's arguments, in
+// either the shell or the JSON form.
+func addSources(args string) []string {
+	var fields []string
+	if strings.HasPrefix(args, "[") {
+		if err := json.Unmarshal([]byte(args), &fields); err != nil {
+			return nil
+		}
+	} else {
+		for _, f := range strings.Fields(args) {
+			if !strings.HasPrefix(f, "--") {
+				fields = append(fields, f)
+			}
+		}
+	}
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields[:len(fields)-1]
+}
+
+func isRemoteSource(src string) bool {
+	return strings.Contains(src, "://") || strings.HasPrefix(src, "git@")
+}
+
+func isRootUser(args string) bool {
+	user, _, _ := strings.Cut(strings.TrimSpace(args), ":")
+	return user == "root" || user == "0"
+}
+
+// instruction is one Dockerfile instruction with its continuation lines
+// joined; it spans lines line to end.
+type instruction struct {
+	line     int
+	end      int
+	keyword  string
+	args     string
+	disables []directive
+}
+
+// directive is a pack-lint:disable comment; text is what follows
+// disablePrefix.
+type directive struct {
+	line int
+	text string
+}
+
+type stage struct {
+	from         instruction
+	instructions []instruction
+}
+
+func (s stage) name() string {
+	if f := strings.Fields(s.from.args); len(f) >= 3 && strings.EqualFold(f[len(f)-2], "AS") {
+		return f[len(f)-1]
+	}
+	return fmt.Sprintf("at line %d", s.from.line)
+}
+
+// image is the image or earlier stage the stage starts from.
+func (s stage) image() string {
+	for _, f := range strings.Fields(s.from.args) {
+		if !strings.HasPrefix(f, "--") {
+			return f
+		}
+	}
+	return ""
+}
+
+func (s stage) isRuntime() bool {
+	return s.last("EXPOSE") != nil || s.last("CMD") != nil || s.last("ENTRYPOINT") != nil
+}
+
+func (s stage) all(keyword string) []instruction {
+	var out []instruction
+	for _, in := range s.instructions {
+		if in.keyword == keyword {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+func (s stage) last(keyword string) *instruction {
+	for i := len(s.instructions) - 1; i >= 0; i-- {
+		if s.instructions[i].keyword == keyword {
+			return &s.instructions[i]
+		}
+	}
+	return nil
+}
+
+// globalArgs returns the defaults of the ARGs declared before the first
+// FROM, which FROM lines may reference.
+func globalArgs(data []byte) map[string]string {
+	args := map[string]string{}
+	for _, line := range splitLines(data) {
+		keyword, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch strings.ToUpper(keyword) {
+		case "ARG":
+			if name, def, ok := strings.Cut(strings.TrimSpace(rest), "="); ok {
+				args[name] = strings.Trim(def, `"`)
+			}
+		case "FROM":
+			return args
+		}
+	}
+	return args
+}
+
+// parseStages splits a Dockerfile into stages at each FROM. Comments,
+// blank lines, and instructions before the first FROM are dropped, except
+// that pack-lint:disable comments are kept with the instruction below
+// them.
+func parseStages(data []byte) []stage {
+	var (
+		stages   []stage
+		cur      *instruction
+		disables []directive
+	)
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		in := *cur
+		cur = nil
+		if in.keyword == "FROM" {
+			stages = append(stages, stage{from: in})
+		} else if len(stages) > 0 {
+			st := &stages[len(stages)-1]
+			st.instructions = append(st.instructions, in)
+		}
+	}
+	for i, line := range splitLines(data) {
+		trimmed := strings.TrimSpace(line)
+		if comment, ok := strings.CutPrefix(trimmed, "#"); ok {
+			if d, ok := strings.CutPrefix(strings.TrimSpace(comment), disablePrefix); ok && cur == nil {
+				disables = append(disables, directive{line: i + 1, text: strings.TrimSpace(d)})
+			}
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		text, cont := strings.CutSuffix(trimmed, `\`)
+		if cur == nil {
+			keyword, args, _ := strings.Cut(text, " ")
+			cur = &instruction{line: i + 1, keyword: strings.ToUpper(keyword), args: strings.TrimSpace(args), disables: disables}
+			disables = nil
+		} else {
+			cur.args += " " + strings.TrimSpace(text)
+		}
+		cur.end = i + 1
+		if !cont {
+			flush()
+		}
+	}
+	flush()
+	return stages
+}
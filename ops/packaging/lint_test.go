@@ -0,0 +1,316 @@
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDockerfile writes name.Dockerfile into dir.
+func writeDockerfile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".Dockerfile")
+	if err := os.WriteFile(path, []byte(strings.TrimPrefix(body, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLintRenderedDockerfilesAreClean(t *testing.T) {
+	root := t.TempDir()
+	spec := &ServiceSpec{Name: "billing", Language: "go", Port: 9090}
+	for _, v := range []Vars{
+		{ServiceName: "billing", ExposePort: 9090},
+		{ServiceName: "billing", ExposePort: 9090, Base: BaseDistroless},
+		{ServiceName: "billing", ExposePort: 9090, PrivateModules: []string{"github.com/acme/*"}, UseCacheMounts: true},
+		{ServiceName: "billing", ExposePort: 9090, SharedBuilder: true, UseCacheMounts: true},
+	} {
+		out, err := Render("go", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := writeDockerfile(t, root, "billing", string(out))
+		issues, err := Lint(path, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) > 0 {
+			t.Errorf("clean %+v render has issues %v:\n%s", v, issues, out)
+		}
+	}
+
+	out, err := RenderMultiArch(Vars{ServiceName: "billing", ExposePort: 9090}, []string{"amd64", "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Lint(writeDockerfile(t, root, "billing", string(out)), spec)
+	if err != nil || len(issues) > 0 {
+		t.Errorf("multi-arch render: %v %v", issues, err)
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	root := t.TempDir()
+	specs := map[string]*ServiceSpec{
+		"billing": {Name: "billing", Language: "go", Port: 8080},
+		"native":  {Name: "native", Language: "go", Port: 8080, CGO: true},
+		"console": {Name: "console", Language: "node", Port: 8080},
+	}
+
+	tests := []struct {
+		name string
+		file string
+		body string
+		want []LintIssue // Message is matched as a substring
+	}{
+		{
+			name: "placeholder", file: "console",
+			body: `
+FROM alpine:3.19
+COPY <name> ./
+USER nobody
+EXPOSE 8080
+CMD ["./{{.BinaryName}}"]
+`,
+			want: []LintIssue{
+				{Line: 2, Severity: SeverityError, Rule: RulePlaceholder, Message: `"<name>"`},
+				{Line: 5, Severity: SeverityError, Rule: RulePlaceholder, Message: `"{{"`},
+			},
+		},
+		{
+			name: "no user", file: "console",
+			body: `
+FROM alpine:3.19 AS runtime
+EXPOSE 8080
+CMD ["./billing"]
+`,
+			want: []LintIssue{{Line: 1, Severity: SeverityError, Rule: RuleUser, Message: "stage runtime sets no USER"}},
+		},
+		{
+			name: "root user", file: "console",
+			body: `
+FROM alpine:3.19
+USER nobody
+USER 0:0
+EXPOSE 8080
+CMD ["./billing"]
+`,
+			want: []LintIssue{{Line: 3, Severity: SeverityError, Rule: RuleUser, Message: "runs as root"}},
+		},
+		{
+			name: "cgo", file: "billing",
+			body: `
+FROM golang:1.22-alpine AS builder
+RUN GOOS=linux \
+    go build -o /app/billing .
+FROM alpine:3.19
+USER nobody
+EXPOSE 8080
+CMD ["./billing"]
+`,
+			want: []LintIssue{{Line: 2, Severity: SeverityError, Rule: RuleCGO, Message: "without CGO_ENABLED=0"}},
+		},
+		{
+			name: "cgo manifest", file: "native",
+			body: `
+FROM golang:1.22-alpine AS builder
+RUN CGO_ENABLED=1 go build -o /app/native .
+FROM alpine:3.19
+USER nobody
+EXPOSE 8080
+CMD ["./native"]
+`,
+		},
+		{
+			name: "wrong port", file: "console",
+			body: `
+FROM alpine:3.19
+USER nobody
+EXPOSE 8080/tcp 9090
+CMD ["./billing"]
+`,
+			want: []LintIssue{{Line: 3, Severity: SeverityError, Rule: RuleExpose, Message: "EXPOSE 9090 does not match port 8080"}},
+		},
+		{
+			name: "missing expose", file: "console",
+			body: `
+FROM alpine:3.19
+USER nobody
+CMD ["./billing"]
+`,
+			want: []LintIssue{{Line: 1, Severity: SeverityError, Rule: RuleExpose, Message: "does not EXPOSE port 8080"}},
+		},
+		{
+			name: "unpinned base", file: "console",
+			body: `
+FROM node AS builder
+FROM builder AS assets
+FROM node:latest
+FROM node:22-alpine@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+ARG BASE=alpine:3.19
+FROM ${BASE}
+USER node
+EXPOSE 8080
+`,
+			want: []LintIssue{
+				{Line: 1, Severity: SeverityError, Rule: RulePinned, Message: "base image node is not pinned"},
+				{Line: 3, Severity: SeverityError, Rule: RulePinned, Message: "base image node:latest is not pinned"},
+				{Line: 6, Severity: SeverityWarning, Rule: RulePinned, Message: "set by a build argument"},
+			},
+		},
+		{
+			name: "global arg base", file: "console",
+			body: `
+ARG RUNTIME=node:latest
+FROM $RUNTIME
+USER node
+EXPOSE 8080
+`,
+			want: []LintIssue{{Line: 2, Severity: SeverityError, Rule: RulePinned, Message: "base image node:latest is not pinned"}},
+		},
+		{
+			name: "remote add", file: "console",
+			body: `
+FROM node:22-alpine
+ADD --chown=node https://example.com/tool.tgz ./vendor/ /opt/
+ADD ["git@github.com:acme/assets.git", "/assets"]
+ADD dist.tar.gz /app/
+USER node
+EXPOSE 8080
+`,
+			want: []LintIssue{
+				{Line: 2, Severity: SeverityError, Rule: RuleRemoteAdd, Message: "ADD fetches https://example.com/tool.tgz"},
+				{Line: 3, Severity: SeverityError, Rule: RuleRemoteAdd, Message: "ADD fetches git@github.com:acme/assets.git"},
+			},
+		},
+		{
+			name: "single stage go", file: "billing",
+			body: `
+FROM alpine:3.19
+COPY billing /app/billing
+USER nobody
+EXPOSE 8080
+CMD ["/app/billing"]
+`,
+			want: []LintIssue{{Line: 1, Severity: SeverityError, Rule: RuleMultiStage, Message: "built in a single stage"}},
+		},
+		{
+			name: "disabled", file: "console",
+			body: `
+# pack-lint:disable=non-root-user,pinned-base the entrypoint drops to uid 1000 after chown
+FROM node:latest
+# pack-lint:disable=no-remote-add
+ADD https://example.com/tool.tgz /opt/
+# pack-lint:disable=remote-add the vendor publishes no checksum
+ADD https://example.com/other.tgz /opt/
+# pack-lint:disable=expose-port the admin port is firewalled
+EXPOSE 8080 \
+       9090
+`,
+			want: []LintIssue{
+				{Line: 3, Severity: SeverityError, Rule: RuleDisable, Message: "needs a justification"},
+				{Line: 4, Severity: SeverityError, Rule: RuleRemoteAdd, Message: "ADD fetches"},
+				{Line: 5, Severity: SeverityError, Rule: RuleDisable, Message: `unknown rule "remote-add"`},
+				{Line: 6, Severity: SeverityError, Rule: RuleRemoteAdd, Message: "ADD fetches"},
+			},
+		},
+		{
+			name: "no manifest", file: "worker",
+			body: `
+FROM node:22-alpine
+USER node
+EXPOSE 3000
+CMD ["node", "dist/index.js"]
+`,
+			want: []LintIssue{{Line: 0, Severity: SeverityWarning, Rule: RuleExpose, Message: "service not in ops/packaging/services.yaml"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := Lint(writeDockerfile(t, root, tt.file, tt.body), specs[tt.file])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != len(tt.want) {
+				t.Fatalf("got %d issues, want %d: %v", len(issues), len(tt.want), issues)
+			}
+			for i, want := range tt.want {
+				got := issues[i]
+				if got.Line != want.Line || got.Severity != want.Severity || got.Rule != want.Rule || !strings.Contains(got.Message, want.Message) {
+					t.Errorf("issue %d = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLintAllowBaseImages(t *testing.T) {
+	path := writeDockerfile(t, t.TempDir(), "console", `
+FROM node:20-bookworm
+USER node
+EXPOSE 8080
+`)
+	spec := &ServiceSpec{Name: "console", Language: "node", Port: 8080}
+	issues, err := Lint(path, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != RulePinned {
+		t.Fatalf("default allowed tags: %v", issues)
+	}
+	if issues, err := Lint(path, spec, AllowBaseImages("node:20-*")); err != nil || len(issues) > 0 {
+		t.Errorf("allowed pattern: %v %v", issues, err)
+	}
+}
+
+func TestLintMetricsPort(t *testing.T) {
+	root := t.TempDir()
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, MetricsPort: 9464})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeDockerfile(t, root, "billing", string(out))
+	if issues, err := Lint(path, &ServiceSpec{Name: "billing", Language: "go", Port: 9090, MetricsPort: 9464}); err != nil || len(issues) > 0 {
+		t.Errorf("registered metrics port: %v %v", issues, err)
+	}
+	issues, err := Lint(path, &ServiceSpec{Name: "billing", Language: "go", Port: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != RuleExpose || !strings.Contains(issues[0].Message, "EXPOSE 9464") {
+		t.Errorf("unregistered metrics port: %v", issues)
+	}
+}
+
+func TestLintErrors(t *testing.T) {
+	root := t.TempDir()
+	if _, err := Lint(filepath.Join(root, "missing.Dockerfile"), nil); err == nil {
+		t.Error("missing file accepted")
+	}
+	if _, err := Lint(writeDockerfile(t, root, "empty", "# nothing here\n"), nil); err == nil {
+		t.Error("file without FROM accepted")
+	}
+}
+
+func TestLintAdmissionsAPI(t *testing.T) {
+	repo, err := FindRoot(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := LoadServices(filepath.Join(repo, RegistryPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, ok := FindService(specs, "admissions-api")
+	if !ok {
+		t.Fatal("admissions-api is not registered")
+	}
+	issues, err := Lint(filepath.Join(repo, DockerfilePath("admissions-api")), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) > 0 {
+		t.Errorf("checked-in admissions-api Dockerfile has issues: %v", issues)
+	}
+}
@@ -0,0 +1,96 @@
+package packaging
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MakefilePath is where pack makefile writes the image targets, relative
+// to the repository root.
+const MakefilePath = "Makefile"
+
+// imageRegistryRE accepts an image name prefix: a registry host with an
+// optional port, then optional lowercase path components, such as
+// ghcr.io/willyu1007 or localhost:5000.
+var imageRegistryRE = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]+)?(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+
+const makefileHeader = "# Generated with `go run ./ops/packaging/cmd/pack makefile`; edit\n" +
+	"# " + RegistryPath + ", not this file.\n" +
+	"#\n" +
+	"#   make build-<service>   build one image, tagged $(REGISTRY)/<service>:$(VERSION)\n" +
+	"#   make push-<service>    build and push it\n" +
+	"#   make build-all push-all\n" +
+	"#\n" +
+	"# VERSION, COMMIT, and BUILD_TIME come from git when make runs, as for\n" +
+	"# `pack build`; set any of them, or REGISTRY, on the command line to\n" +
+	"# override it.\n"
+
+// makeGitVars resolves the build metadata once per make run, with the
+// same git commands as ReadGitMetadata. VERSION keeps only characters a
+// Docker tag allows; outside a checkout everything is "unknown".
+const makeGitVars = `ifeq ($(origin VERSION),undefined)
+VERSION := $(or $(shell git describe --tags --always --dirty 2>/dev/null | tr -c 'A-Za-z0-9_.\n-' '_'),unknown)
+endif
+ifeq ($(origin COMMIT),undefined)
+COMMIT := $(or $(shell git rev-parse HEAD 2>/dev/null),unknown)
+endif
+ifeq ($(origin BUILD_TIME),undefined)
+BUILD_TIME := $(or $(shell git log -1 --format=%cI 2>/dev/null),unknown)
+endif
+`
+
+// GenerateMakefile returns a Makefile with build-<name> and push-<name>
+// targets for every service, plus build-all and push-all. Each build runs
+// docker build from the repository root with the service's rendered
+// Dockerfile, stamps the VERSION, COMMIT, and BUILD_TIME build args, and
+// tags the image registry/<name>:$(VERSION); an empty registry leaves
+// bare <name>:$(VERSION) tags. Services with private modules get the
+// netrc secret their Dockerfile mounts, from $(NETRC).
+func GenerateMakefile(specs []ServiceSpec, registry string) ([]byte, error) {
+	if registry != "" && !imageRegistryRE.MatchString(registry) {
+		return nil, fmt.Errorf("makefile: invalid registry %q: want a host and optional path, such as ghcr.io/acme", registry)
+	}
+	if err := ValidateServices(specs); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(makefileHeader)
+	b.WriteString("\n")
+	b.WriteString(strings.TrimSpace("REGISTRY ?= "+registry) + "\n")
+	b.WriteString("NETRC ?= $(HOME)/.netrc\n")
+	b.WriteString(makeGitVars)
+	b.WriteString("IMAGE_PREFIX = $(if $(REGISTRY),$(REGISTRY)/)\n")
+
+	var builds, pushes []string
+	for _, s := range specs {
+		builds = append(builds, "build-"+s.Name)
+		pushes = append(pushes, "push-"+s.Name)
+	}
+	fmt.Fprintf(&b, "\n.PHONY: build-all push-all %s %s\n", strings.Join(builds, " "), strings.Join(pushes, " "))
+	fmt.Fprintf(&b, "\nbuild-all: %s\n", strings.Join(builds, " "))
+	fmt.Fprintf(&b, "\npush-all: %s\n", strings.Join(pushes, " "))
+
+	for _, s := range specs {
+		image := "$(IMAGE_PREFIX)" + s.Name + ":$(VERSION)"
+		docker := "docker build"
+		if s.CacheMounts || len(s.PrivateModules) > 0 {
+			docker = "DOCKER_BUILDKIT=1 docker build"
+		}
+		args := []string{
+			"-f", filepath.ToSlash(DockerfilePath(s.Name)),
+			"-t", image,
+			"--build-arg", ArgVersion + "=$(VERSION)",
+			"--build-arg", ArgCommit + "=$(COMMIT)",
+			"--build-arg", ArgBuildTime + "=$(BUILD_TIME)",
+		}
+		if len(s.PrivateModules) > 0 {
+			args = append(args, "--secret", "id=netrc,src=$(NETRC)")
+		}
+		fmt.Fprintf(&b, "\nbuild-%s:\n\t%s %s .\n", s.Name, docker, strings.Join(args, " "))
+		fmt.Fprintf(&b, "\npush-%s: build-%s\n\tdocker push %s\n", s.Name, s.Name, image)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package packaging
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMakefile(t *testing.T) {
+	specs := []ServiceSpec{
+		{Name: "admissions-api", Language: "go", Port: 8080, CacheMounts: true},
+		{Name: "billing", Language: "go", Port: 9090, PrivateModules: []string{"github.com/acme/*"}},
+		{Name: "portal", Language: "node", Port: 3000},
+	}
+	out, err := GenerateMakefile(specs, "ghcr.io/acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(out)
+
+	// Recipe-bearing build targets; build-all only lists prerequisites.
+	targets := regexp.MustCompile(`(?m)^build-([a-z0-9-]+):\n\t`).FindAllStringSubmatch(text, -1)
+	if len(targets) != len(specs) {
+		t.Fatalf("got %d build targets, want one per service:\n%s", len(targets), out)
+	}
+	for i, s := range specs {
+		if targets[i][1] != s.Name {
+			t.Errorf("build target %d is for %s, want %s", i, targets[i][1], s.Name)
+		}
+		for _, want := range []string{
+			"-f " + filepath.ToSlash(DockerfilePath(s.Name)) + " ",
+			"-t $(IMAGE_PREFIX)" + s.Name + ":$(VERSION) ",
+			"\npush-" + s.Name + ": build-" + s.Name + "\n\tdocker push $(IMAGE_PREFIX)" + s.Name + ":$(VERSION)\n",
+		} {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s: Makefile missing %q:\n%s", s.Name, want, out)
+			}
+		}
+	}
+	for _, want := range []string{
+		"REGISTRY ?= ghcr.io/acme\n",
+		"$(shell git describe --tags --always --dirty",
+		"--build-arg VERSION=$(VERSION) --build-arg COMMIT=$(COMMIT) --build-arg BUILD_TIME=$(BUILD_TIME)",
+		"\nbuild-all: build-admissions-api build-billing build-portal\n",
+		"\npush-all: push-admissions-api push-billing push-portal\n",
+		"\tDOCKER_BUILDKIT=1 docker build -f ops/packaging/services/admissions-api.Dockerfile",
+		"--secret id=netrc,src=$(NETRC) .\n",
+		"\tdocker build -f ops/packaging/services/portal.Dockerfile",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Makefile missing %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := GenerateMakefile(specs, "https://ghcr.io"); err == nil {
+		t.Error("accepted a registry URL")
+	}
+	if _, err := GenerateMakefile(append(specs, specs[0]), ""); err == nil {
+		t.Error("accepted a duplicate service")
+	}
+}
+
+// TestGenerateMakefileDryRun has make itself expand the generated file,
+// so a syntax slip or a wrong variable shows up as a bad command line.
+func TestGenerateMakefileDryRun(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not installed")
+	}
+	out, err := GenerateMakefile([]ServiceSpec{{Name: "billing", Language: "go", Port: 9090}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "Makefile")
+	if err := WriteFile(path, out, false); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("make", "-n", "-f", path, "VERSION=v1.2.3", "push-all")
+	cmd.Dir = t.TempDir() // outside a checkout: COMMIT falls back to unknown
+	got, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("make -n: %v\n%s", err, got)
+	}
+	want := "docker build -f ops/packaging/services/billing.Dockerfile -t billing:v1.2.3 --build-arg VERSION=v1.2.3 --build-arg COMMIT=unknown --build-arg BUILD_TIME=unknown .\n" +
+		"docker push billing:v1.2.3\n"
+	if string(got) != want {
+		t.Errorf("make -n printed:\n%s\nwant:\n%s", got, want)
+	}
+}
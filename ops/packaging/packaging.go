@@ -0,0 +1,63 @@
+// Package packaging renders the container packaging artifacts under
+// ops/packaging from the templates in ops/packaging/templates.
+//
+// Templates are embedded so the pack tool works from any checkout without
+// locating the template directory at runtime; rendered output is written
+// relative to the repository root.
+package packaging
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//go:embed templates/*.tmpl templates/scaffold/*.tmpl
+var templatesFS embed.FS
+
+// ServicesDir is where rendered service Dockerfiles live, relative to the
+// repository root.
+const ServicesDir = "ops/packaging/services"
+
+// DockerfilePath returns the path of a service's rendered Dockerfile
+// relative to the repository root.
+func DockerfilePath(service string) string {
+	return filepath.Join(ServicesDir, service+".Dockerfile")
+}
+
+// FindRoot walks up from start until it finds the directory that contains
+// ops/packaging, which is treated as the repository root.
+func FindRoot(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "ops", "packaging")); err == nil && fi.IsDir() {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("packaging: ops/packaging not found in any parent directory")
+		}
+		dir = parent
+	}
+}
+
+// WriteFile writes data to path, creating parent directories. Existing
+// files are only replaced when force is set.
+func WriteFile(path string, data []byte, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
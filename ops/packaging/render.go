@@ -0,0 +1,510 @@
+package packaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Vars are the variables available to the Dockerfile templates. Fields
+// shared by every language come first; the header of each template
+// documents which of the rest it uses.
+type Vars struct {
+	ServiceName string
+	ExposePort  int
+	// MetricsPort, when set, is EXPOSEd too: the separate listener that
+	// serves /metrics, so the public one does not.
+	MetricsPort int
+
+	// HealthPath is probed over HTTP on ExposePort by the image's
+	// HEALTHCHECK. HealthInterval and HealthTimeout are Docker durations
+	// such as "30s"; HealthRetries is the failures tolerated before the
+	// container is marked unhealthy.
+	HealthPath     string
+	HealthInterval string
+	HealthTimeout  string
+	HealthRetries  int
+
+	// Packages are extra apk packages installed in the runtime image.
+	Packages []string
+
+	// Go templates.
+	BinaryName string
+	Package    string
+	GoVersion  string
+	BuildTags  []string
+	// CGO builds with CGO_ENABLED=1 in a builder with a C toolchain. The
+	// binary then links against musl, so it needs the alpine runtime.
+	CGO bool
+	// PrivateModules are GOPRIVATE patterns such as
+	// "github.com/willyu1007/*". When set, `go mod download` skips the
+	// proxy and checksum database for them and authenticates with a
+	// BuildKit netrc secret or forwarded SSH agent.
+	PrivateModules []string
+	// UseCacheMounts keeps the module cache and build cache in BuildKit
+	// cache mounts so rebuilds skip downloads and unchanged packages.
+	// Plain `docker build` without BuildKit rejects the mounts.
+	UseCacheMounts bool
+	// SharedBuilder starts the builder stage from the BUILDER build arg,
+	// which `pack build --shared-builder` points at an image with the
+	// module cache already downloaded (see RenderBuilder). Unset, it is
+	// the stock golang image, so plain builds still work.
+	SharedBuilder bool
+
+	// TargetArch cross-compiles for one GOARCH (e.g. arm64). Empty keeps
+	// a native build for the builder's own platform.
+	TargetArch string
+
+	// Base selects the runtime stage: BaseAlpine (default) or
+	// BaseDistroless.
+	Base string
+	// WithTzdata ships /usr/share/zoneinfo for services that load time
+	// zones by name.
+	WithTzdata bool
+
+	// Node templates.
+	NodeVersion string
+	Entrypoint  string
+
+	// PinDigests renders every base image as name:tag@sha256:<digest>,
+	// looked up with Resolve, or ResolveDigest if Resolve is nil, so a
+	// re-pushed tag cannot change what the Dockerfile builds. Off, images
+	// keep their floating tags.
+	PinDigests bool
+	Resolve    DigestResolver
+}
+
+// Runtime base images selectable through Vars.Base.
+const (
+	BaseAlpine     = "alpine"
+	BaseDistroless = "distroless"
+)
+
+// Bases lists the accepted Vars.Base values.
+var Bases = []string{BaseAlpine, BaseDistroless}
+
+// Toolchain defaults used when the corresponding Vars field is empty. Keep
+// DefaultGoVersion in step with the go directive in go.mod and
+// DefaultNodeVersion with the hand-written service Dockerfiles.
+const (
+	DefaultGoVersion   = "1.22"
+	DefaultNodeVersion = "22"
+	DefaultEntrypoint  = "index.mjs"
+)
+
+// HEALTHCHECK defaults used when the corresponding Vars field is empty.
+const (
+	DefaultHealthPath     = "/healthz"
+	DefaultHealthInterval = "30s"
+	DefaultHealthTimeout  = "3s"
+	DefaultHealthRetries  = 3
+)
+
+var (
+	serviceNameRE = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	goVersionRE   = regexp.MustCompile(`^1\.[0-9]+(\.[0-9]+)?$`)
+	nodeVersionRE = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}$`)
+	entrypointRE  = regexp.MustCompile(`^[A-Za-z0-9_./-]+\.(m?js|cjs)$`)
+	healthPathRE  = regexp.MustCompile(`^/[A-Za-z0-9_./~-]*$`)
+	durationRE    = regexp.MustCompile(`^[0-9]+(ms|s|m|h)$`)
+	buildTagRE    = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	apkPackageRE  = regexp.MustCompile(`^[a-z0-9][a-z0-9+._-]*$`)
+	// modulePatternRE accepts GOPRIVATE path globs: a host-like first
+	// element, then optional path elements, with * wildcards.
+	modulePatternRE = regexp.MustCompile(`^[a-z0-9*][a-z0-9.*-]*(/[A-Za-z0-9._~*-]+)*$`)
+)
+
+// withDefaults fills the optional variables.
+func (v Vars) withDefaults() Vars {
+	if v.BinaryName == "" {
+		v.BinaryName = v.ServiceName
+	}
+	if v.Package == "" {
+		v.Package = "."
+	}
+	if v.GoVersion == "" {
+		v.GoVersion = DefaultGoVersion
+	}
+	if v.Base == "" {
+		v.Base = BaseAlpine
+	}
+	if v.HealthPath == "" {
+		v.HealthPath = DefaultHealthPath
+	}
+	if v.HealthInterval == "" {
+		v.HealthInterval = DefaultHealthInterval
+	}
+	if v.HealthTimeout == "" {
+		v.HealthTimeout = DefaultHealthTimeout
+	}
+	if v.HealthRetries == 0 {
+		v.HealthRetries = DefaultHealthRetries
+	}
+	if v.NodeVersion == "" {
+		v.NodeVersion = DefaultNodeVersion
+	}
+	if v.Entrypoint == "" {
+		v.Entrypoint = DefaultEntrypoint
+	}
+	return v
+}
+
+// Validate reports the first variable that cannot be rendered safely.
+func (v Vars) Validate() error {
+	if !serviceNameRE.MatchString(v.ServiceName) {
+		return fmt.Errorf("invalid service name %q: use lowercase letters, digits, and dashes", v.ServiceName)
+	}
+	if v.ExposePort < 1 || v.ExposePort > 65535 {
+		return fmt.Errorf("invalid port %d: must be 1-65535", v.ExposePort)
+	}
+	if v.MetricsPort < 0 || v.MetricsPort > 65535 {
+		return fmt.Errorf("invalid metrics port %d: must be 1-65535", v.MetricsPort)
+	}
+	if v.MetricsPort == v.ExposePort {
+		return fmt.Errorf("metrics port %d is the service port; it needs a listener of its own", v.MetricsPort)
+	}
+	if v.HealthPath != "" && !healthPathRE.MatchString(v.HealthPath) {
+		return fmt.Errorf("invalid health path %q: want an absolute URL path", v.HealthPath)
+	}
+	for name, d := range map[string]string{"interval": v.HealthInterval, "timeout": v.HealthTimeout} {
+		if d != "" && !durationRE.MatchString(d) {
+			return fmt.Errorf("invalid health %s %q: want a duration such as 30s", name, d)
+		}
+	}
+	if v.HealthRetries < 0 {
+		return fmt.Errorf("invalid health retries %d", v.HealthRetries)
+	}
+	if v.BinaryName != "" && !serviceNameRE.MatchString(v.BinaryName) {
+		return fmt.Errorf("invalid binary name %q", v.BinaryName)
+	}
+	if v.GoVersion != "" && !goVersionRE.MatchString(v.GoVersion) {
+		return fmt.Errorf("invalid Go version %q", v.GoVersion)
+	}
+	if v.Base != "" && !slices.Contains(Bases, v.Base) {
+		return fmt.Errorf("unsupported base image %q (supported: %v)", v.Base, Bases)
+	}
+	if v.TargetArch != "" && !slices.Contains(Arches, v.TargetArch) {
+		return fmt.Errorf("unsupported architecture %q (supported: %v)", v.TargetArch, Arches)
+	}
+	for _, tag := range v.BuildTags {
+		if !buildTagRE.MatchString(tag) {
+			return fmt.Errorf("invalid build tag %q: want letters, digits, underscores, and dots", tag)
+		}
+	}
+	for _, pkg := range v.Packages {
+		if !apkPackageRE.MatchString(pkg) {
+			return fmt.Errorf("invalid package name %q", pkg)
+		}
+	}
+	for _, mod := range v.PrivateModules {
+		if !modulePatternRE.MatchString(mod) {
+			return fmt.Errorf("invalid private module pattern %q: want a module path glob such as example.com/org/*", mod)
+		}
+	}
+	if v.Base == BaseDistroless && (v.CGO || len(v.Packages) > 0) {
+		return errors.New("the distroless base supports neither cgo nor extra packages; use the alpine base")
+	}
+	if v.CGO && v.TargetArch != "" {
+		return errors.New("cgo builds cannot cross-compile; drop the pinned architecture")
+	}
+	if v.SharedBuilder && (v.CGO || len(v.PrivateModules) > 0) {
+		return errors.New("the shared builder supports neither cgo nor private modules, which build on images and credentials of their own")
+	}
+	if v.NodeVersion != "" && !nodeVersionRE.MatchString(v.NodeVersion) {
+		return fmt.Errorf("invalid Node.js version %q", v.NodeVersion)
+	}
+	if v.Entrypoint != "" && (!entrypointRE.MatchString(v.Entrypoint) || strings.Contains(v.Entrypoint, "..")) {
+		return fmt.Errorf("invalid entrypoint %q: want a .js/.mjs/.cjs path under dist/", v.Entrypoint)
+	}
+	return nil
+}
+
+// validateFor rejects options the language's template does not implement,
+// so they are not silently dropped.
+func (v Vars) validateFor(lang string) error {
+	if lang == "go" {
+		return nil
+	}
+	if v.TargetArch != "" {
+		return fmt.Errorf("--lang %s does not support a target architecture", lang)
+	}
+	if v.CGO || len(v.BuildTags) > 0 {
+		return fmt.Errorf("--lang %s does not support cgo or build tags", lang)
+	}
+	if len(v.PrivateModules) > 0 {
+		return fmt.Errorf("--lang %s does not support private Go modules", lang)
+	}
+	if v.UseCacheMounts {
+		return fmt.Errorf("--lang %s does not support cache mounts", lang)
+	}
+	if v.SharedBuilder {
+		return fmt.Errorf("--lang %s does not support the shared builder", lang)
+	}
+	if v.Base != "" && v.Base != BaseAlpine {
+		return fmt.Errorf("--lang %s does not support base %q", lang, v.Base)
+	}
+	return nil
+}
+
+// Arches lists the GOARCH values accepted for TargetArch.
+var Arches = []string{"amd64", "arm64", "arm", "386", "ppc64le", "riscv64", "s390x"}
+
+// Languages lists the values accepted by Render's lang argument.
+var Languages = []string{"go", "node"}
+
+// TemplateName returns the embedded template file for a language.
+func TemplateName(lang string) (string, error) {
+	for _, l := range Languages {
+		if l == lang {
+			return "Dockerfile." + lang + ".tmpl", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language %q (supported: %v)", lang, Languages)
+}
+
+// Render renders the Dockerfile template for lang.
+func Render(lang string, v Vars) ([]byte, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	if err := v.validateFor(lang); err != nil {
+		return nil, err
+	}
+	name, err := TemplateName(lang)
+	if err != nil {
+		return nil, err
+	}
+	return execute(name, v.withDefaults(), pinner(v.resolver()))
+}
+
+// resolver is the digest lookup Render pins images with, or nil when
+// pinning is off.
+func (v Vars) resolver() DigestResolver {
+	switch {
+	case !v.PinDigests:
+		return nil
+	case v.Resolve != nil:
+		return memoize(v.Resolve)
+	}
+	return memoize(ResolveDigest)
+}
+
+// RenderMultiArch renders one build and runtime stanza per architecture
+// into a single Dockerfile. Stages are named builder-<arch> and
+// runtime-<arch>; pick one with `docker build --target runtime-<arch>`.
+func RenderMultiArch(v Vars, arches []string) ([]byte, error) {
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("no architectures given")
+	}
+	if r := v.resolver(); r != nil {
+		// Every stanza uses the same images; look them up once.
+		v.Resolve = r
+	}
+	var out bytes.Buffer
+	seen := map[string]bool{}
+	for i, arch := range arches {
+		if seen[arch] {
+			return nil, fmt.Errorf("architecture %q listed twice", arch)
+		}
+		seen[arch] = true
+		v.TargetArch = arch
+		stanza, err := Render("go", v)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteString("\n")
+			// The BUILDER arg is declared once, before the first FROM.
+			stanza = trimHeader(stanza)
+			if _, rest, ok := bytes.Cut(stanza, []byte("\n")); ok && bytes.HasPrefix(stanza, []byte("ARG "+BuilderArg+"=")) {
+				stanza = rest
+			}
+		}
+		out.Write(stanza)
+	}
+	return out.Bytes(), nil
+}
+
+// trimHeader drops the leading comment block of a rendered stanza so the
+// file header is only emitted once.
+func trimHeader(b []byte) []byte {
+	for len(b) > 0 && (b[0] == '#' || b[0] == '\n') {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			return nil
+		}
+		b = b[i+1:]
+	}
+	return b
+}
+
+// RenderTemplate renders an embedded template from ops/packaging/templates
+// with string variables, e.g. RenderTemplate("Dockerfile.go.tmpl",
+// map[string]string{"ServiceName": "billing", ...}). Every variable the
+// template references must be present and non-empty; all missing names are
+// reported together instead of rendering an empty string. The HEALTHCHECK
+// tuning variables fall back to their package defaults.
+func RenderTemplate(templateName string, vars map[string]string) ([]byte, error) {
+	if !strings.HasSuffix(templateName, ".tmpl") {
+		templateName += ".tmpl"
+	}
+	tmpl, err := parseTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+	data := maps.Clone(templateDefaults)
+	maps.Copy(data, vars)
+	required, optional := referencedFields(tmpl)
+	var missing []string
+	for _, field := range required {
+		if data[field] == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("render template %s: missing variables: %s", templateName, strings.Join(missing, ", "))
+	}
+	for _, field := range optional {
+		if _, ok := data[field]; !ok {
+			data[field] = ""
+		}
+	}
+	return executeTemplate(tmpl, data)
+}
+
+// templateDefaults are the RenderTemplate variables that may be omitted.
+var templateDefaults = map[string]string{
+	"HealthPath":     DefaultHealthPath,
+	"HealthInterval": DefaultHealthInterval,
+	"HealthTimeout":  DefaultHealthTimeout,
+	"HealthRetries":  strconv.Itoa(DefaultHealthRetries),
+}
+
+func parseTemplate(name string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).ParseFS(templatesFS, "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func execute(name string, data any, pin func(string) (string, error)) ([]byte, error) {
+	tmpl, err := parseTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(template.FuncMap{"pin": pin})
+	return executeTemplate(tmpl, data)
+}
+
+func executeTemplate(tmpl *template.Template, data any) ([]byte, error) {
+	name := tmpl.Name()
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// funcs are the helpers available to every template.
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	// pin renders a base image reference; Render replaces it to pin
+	// digests when Vars.PinDigests is set.
+	"pin": pinner(nil),
+	// stage names a build stage, suffixed with the target architecture
+	// when cross-compiling so multi-arch output has unique stage names.
+	"stage": func(name, arch string) string {
+		if arch == "" {
+			return name
+		}
+		return name + "-" + arch
+	},
+	// gitHosts returns the hosts of module patterns that git can be
+	// pointed at over SSH; wildcard hosts cannot be rewritten.
+	"gitHosts": func(patterns []string) []string {
+		var hosts []string
+		for _, p := range patterns {
+			host, _, _ := strings.Cut(p, "/")
+			if !strings.Contains(host, "*") && !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+		sort.Strings(hosts)
+		return hosts
+	},
+}
+
+// referencedFields lists the top-level variables (".Name") a template uses,
+// sorted and de-duplicated. Variables tested by if/with/range are optional:
+// the template has a branch for their absence.
+func referencedFields(tmpl *template.Template) (required, optional []string) {
+	seen := map[string]bool{}
+	guarded := map[string]bool{}
+	var walk func(n parse.Node, cond bool)
+	control := func(pipe *parse.PipeNode, list, elseList *parse.ListNode) {
+		walk(pipe, true)
+		walk(list, false)
+		walk(elseList, false)
+	}
+	walk = func(n parse.Node, cond bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, cond)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, cond)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c, cond)
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a, cond)
+			}
+		case *parse.FieldNode:
+			seen[n.Ident[0]] = true
+			if cond {
+				guarded[n.Ident[0]] = true
+			}
+		case *parse.IfNode:
+			control(n.Pipe, n.List, n.ElseList)
+		case *parse.RangeNode:
+			control(n.Pipe, n.List, n.ElseList)
+		case *parse.WithNode:
+			control(n.Pipe, n.List, n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe, cond)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root, false)
+		}
+	}
+	for f := range seen {
+		if guarded[f] {
+			optional = append(optional, f)
+		} else {
+			required = append(required, f)
+		}
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+	return required, optional
+}
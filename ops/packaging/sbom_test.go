@@ -0,0 +1,151 @@
+package packaging
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// goListFixture is go list -deps -json output for a main package using
+// one required module, one replaced by another version, and net/http.
+const goListFixture = `{"ImportPath": "net/http", "Standard": true, "Imports": ["fmt"]}
+{"ImportPath": "fmt", "Standard": true}
+{"ImportPath": "github.com/redis/go-redis/v9", "Module": {"Path": "github.com/redis/go-redis/v9", "Version": "v9.5.1"}, "Imports": ["net/http", "github.com/cespare/xxhash/v2"]}
+{"ImportPath": "github.com/cespare/xxhash/v2", "Module": {"Path": "github.com/cespare/xxhash/v2", "Version": "v2.2.0", "Replace": {"Path": "github.com/cespare/xxhash/v2", "Version": "v2.3.0"}}}
+{"ImportPath": "example.com/svc/internal/api", "Module": {"Path": "example.com/svc", "Main": true}, "Imports": ["net/http", "github.com/redis/go-redis/v9"]}
+{"ImportPath": "example.com/svc", "Module": {"Path": "example.com/svc", "Main": true}, "Imports": ["example.com/svc/internal/api", "fmt"]}
+`
+
+func TestGoComponents(t *testing.T) {
+	pkgs, err := parseGoList([]byte(goListFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps, deps := goComponents(pkgs, "go1.22.5", "service:svc")
+	var purls []string
+	for _, c := range comps {
+		purls = append(purls, c.PURL)
+	}
+	want := []string{"pkg:golang/github.com/cespare/xxhash/v2@v2.3.0", "pkg:golang/github.com/redis/go-redis/v9@v9.5.1", "pkg:golang/stdlib@go1.22.5"}
+	if !slices.Equal(purls, want) {
+		t.Errorf("components %v, want %v", purls, want)
+	}
+	if p := comps[0].Properties; len(p) != 1 || p[0].Value != "github.com/cespare/xxhash/v2@v2.2.0" {
+		t.Errorf("replaced module properties = %+v", p)
+	}
+	graph := map[string][]string{}
+	for _, d := range deps {
+		graph[d.Ref] = d.DependsOn
+	}
+	if got := graph["service:svc"]; !slices.Equal(got, []string{"pkg:golang/github.com/redis/go-redis/v9@v9.5.1", "pkg:golang/stdlib"}) {
+		t.Errorf("main module depends on %v", got)
+	}
+	if got := graph["pkg:golang/github.com/redis/go-redis/v9@v9.5.1"]; !slices.Equal(got, []string{"pkg:golang/github.com/cespare/xxhash/v2@v2.3.0", "pkg:golang/stdlib"}) {
+		t.Errorf("go-redis depends on %v", got)
+	}
+	if got, ok := graph["pkg:golang/stdlib"]; !ok || len(got) != 0 {
+		t.Errorf("stdlib depends on %v (listed %v)", got, ok)
+	}
+}
+
+func TestBaseComponent(t *testing.T) {
+	for ref, want := range map[string]Component{
+		"alpine:3.19": {BOMRef: "image:alpine:3.19", Type: "container", Name: "alpine", Version: "3.19"},
+		"gcr.io/distroless/static-debian12:nonroot@sha256:abc": {
+			BOMRef: "image:gcr.io/distroless/static-debian12:nonroot@sha256:abc", Type: "container",
+			Name: "gcr.io/distroless/static-debian12", Version: "sha256:abc",
+			PURL: "pkg:oci/static-debian12@sha256%3Aabc?repository_url=gcr.io/distroless/static-debian12",
+		},
+		"localhost:5000/base": {BOMRef: "image:localhost:5000/base", Type: "container", Name: "localhost:5000/base"},
+	} {
+		if got := baseComponent(ref); got.BOMRef != want.BOMRef || got.Name != want.Name || got.Version != want.Version || got.PURL != want.PURL {
+			t.Errorf("baseComponent(%q) = %+v, want %+v", ref, got, want)
+		}
+	}
+}
+
+func TestParsePackageDatabases(t *testing.T) {
+	apk := "C:Q1abc=\nP:musl\nV:1.2.4-r2\nA:x86_64\nL:MIT\n\nP:ca-certificates-bundle\nV:20240226-r0\nA:x86_64\n\n"
+	got := parseAPKInstalled([]byte(apk))
+	if len(got) != 2 || got[0].PURL != "pkg:apk/alpine/musl@1.2.4-r2?arch=x86_64" || got[0].Properties[0].Value != "MIT" || got[1].Name != "ca-certificates-bundle" {
+		t.Errorf("apk packages = %+v", got)
+	}
+
+	dpkg := "Package: libc6\nStatus: install ok installed\nArchitecture: amd64\nVersion: 2.36-9+deb12u4\nDescription: GNU C Library\n continued line\n\nPackage: gone\nStatus: deinstall ok config-files\nVersion: 1.0\n\nPackage: tzdata\nVersion: 2024a-0+deb12u1\nArchitecture: all\n"
+	got = parseDpkgStatus([]byte(dpkg))
+	if len(got) != 2 || got[0].PURL != "pkg:deb/debian/libc6@2.36-9+deb12u4?arch=amd64" || got[1].Name != "tzdata" {
+		t.Errorf("dpkg packages = %+v", got)
+	}
+}
+
+// TestGenerateSBOMOffline lists a real module, with a dependency replaced
+// by a local directory, with the network off.
+func TestGenerateSBOMOffline(t *testing.T) {
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"go.mod":         "module example.com/svc\n\ngo 1.22\n\nrequire example.com/lib v1.0.0\n\nreplace example.com/lib => ./lib\n",
+		"main.go":        "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/lib\"\n)\n\nfunc main() { fmt.Println(lib.Name) }\n",
+		"lib/go.mod":     "module example.com/lib\n\ngo 1.22\n",
+		"lib/lib.go":     "package lib\n\nconst Name = \"lib\"\n",
+		"svc.Dockerfile": "FROM golang:1.22-alpine AS builder\nRUN go build -o /app .\nFROM alpine:3.19\nCOPY --from=builder /app /app\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bom, warnings, err := GenerateSBOM(context.Background(), SBOMOptions{
+		Spec:       ServiceSpec{Name: "svc", Language: "go"},
+		Root:       root,
+		Dockerfile: filepath.Join(root, "svc.Dockerfile"),
+		Version:    "v1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "no image given") {
+		t.Errorf("warnings %v", warnings)
+	}
+	var names []string
+	for _, c := range bom.Components {
+		names = append(names, c.Type+" "+c.Name)
+	}
+	if !slices.Equal(names, []string{"library example.com/lib", "library stdlib", "container alpine"}) {
+		t.Errorf("components %v", names)
+	}
+	if p := bom.Components[0].Properties; len(p) != 1 || p[0] != (Property{"pack:go:replace-dir", "./lib"}) {
+		t.Errorf("local replace properties = %+v", p)
+	}
+	if bom.Metadata.Component.Name != "svc" || bom.Metadata.Component.Version != "v1" || bom.Metadata.Timestamp != "" {
+		t.Errorf("metadata = %+v", bom.Metadata)
+	}
+
+	file := filepath.Join(root, SBOMPath("svc"))
+	if err := WriteSBOM(file, bom); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil || doc["bomFormat"] != "CycloneDX" || doc["specVersion"] != "1.5" {
+		t.Errorf("written SBOM: %v\n%s", err, data)
+	}
+}
+
+func TestGenerateSBOMOtherLanguage(t *testing.T) {
+	bom, warnings, err := GenerateSBOM(context.Background(), SBOMOptions{Spec: ServiceSpec{Name: "web", Language: "node"}, Dockerfile: "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bom.Components) != 0 || len(warnings) != 2 || !strings.Contains(warnings[0], "node service") {
+		t.Errorf("components %v, warnings %v", bom.Components, warnings)
+	}
+}
@@ -0,0 +1,98 @@
+package packaging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// scaffoldFiles maps generated file names to their templates under
+// templates/scaffold.
+var scaffoldFiles = []struct{ name, tmpl string }{
+	{"go.mod", "scaffold/go.mod.tmpl"},
+	{"go.sum", "scaffold/go.sum.tmpl"},
+	{"main.go", "scaffold/main.go.tmpl"},
+	{"logging.go", "scaffold/logging.go.tmpl"},
+	{"metrics.go", "scaffold/metrics.go.tmpl"},
+	{"tracing.go", "scaffold/tracing.go.tmpl"},
+	{"main_test.go", "scaffold/main_test.go.tmpl"},
+	{"config/config.go", "scaffold/config.go.tmpl"},
+	{"config/config_test.go", "scaffold/config_test.go.tmpl"},
+	{"httpx/httpx.go", "scaffold/httpx.go.tmpl"},
+	{"httpx/httpx_test.go", "scaffold/httpx_test.go.tmpl"},
+	{"httpx/cors.go", "scaffold/cors.go.tmpl"},
+	{"httpx/cors_test.go", "scaffold/cors_test.go.tmpl"},
+}
+
+// scaffoldVars are the variables of the scaffold templates.
+type scaffoldVars struct {
+	Name      string
+	Module    string
+	Port      int
+	GoVersion string
+}
+
+// ScaffoldOption customizes ScaffoldService.
+type ScaffoldOption func(*scaffoldOptions)
+
+type scaffoldOptions struct {
+	force bool
+}
+
+// Overwrite lets ScaffoldService write into an existing directory,
+// replacing the files it generates and leaving any others alone.
+func Overwrite() ScaffoldOption {
+	return func(o *scaffoldOptions) { o.force = true }
+}
+
+// ScaffoldService writes a minimal runnable Go service named name into
+// dir: a go.mod pinning client_golang, the OpenTelemetry SDK, and yaml.v3
+// at the repository's versions, and a main.go serving GET /healthz on the port the Dockerfile
+// template exposes. Settings are read by the generated config package
+// from the YAML file named by CONFIG_FILE, if any, overlaid by the
+// environment (PORT, LOG_LEVEL, SHUTDOWN_TIMEOUT, METRICS_PORT,
+// OTEL_EXPORTER_OTLP_ENDPOINT, CORS_*). It logs
+// JSON lines to stdout at LOG_LEVEL (default info), one per request with
+// its method, path, status, latency, and X-Request-ID, answers a
+// panicking handler with a 500 instead of crashing (the generated httpx
+// package holds that middleware), and serves
+// Prometheus request counts and latencies on /metrics, or on METRICS_PORT
+// when that is set. With OTEL_EXPORTER_OTLP_ENDPOINT it exports a server
+// span per request, tagged with its route, over OTLP/HTTP. With
+// CORS_ALLOWED_ORIGINS it answers preflights and admits browser scripts
+// from those origins (httpx.CORS). On SIGINT or SIGTERM the service drains in-flight
+// requests for SHUTDOWN_TIMEOUT (default 15s) before force-closing; the
+// generated main_test.go covers that path. It refuses to touch an
+// existing dir unless Overwrite is given.
+func ScaffoldService(name, dir string, opts ...ScaffoldOption) error {
+	var o scaffoldOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !serviceNameRE.MatchString(name) {
+		return fmt.Errorf("invalid service name %q: use lowercase letters, digits, and dashes", name)
+	}
+	if _, err := os.Stat(dir); err == nil && !o.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	vars := scaffoldVars{Name: name, Module: name, Port: 8080, GoVersion: DefaultGoVersion}
+	for _, f := range scaffoldFiles {
+		tmpl, err := template.New(filepath.Base(f.tmpl)).Option("missingkey=error").ParseFS(templatesFS, "templates/"+f.tmpl)
+		if err != nil {
+			return fmt.Errorf("parse template %s: %w", f.tmpl, err)
+		}
+		data, err := executeTemplate(tmpl, vars)
+		if err != nil {
+			return err
+		}
+		if err := WriteFile(filepath.Join(dir, f.name), data, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package packaging
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldServiceBuilds(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "billing")
+	if err := ScaffoldService("billing", dir); err != nil {
+		t.Fatal(err)
+	}
+	main, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`default:"8080"`, `"GET /healthz"`, "syscall.SIGTERM", "srv.Shutdown", `config:"shutdown_timeout"`, `config:"log_level"`, `config:"metrics_port"`, `config:"otel_exporter_otlp_endpoint"`, `config.Load[Config](os.Getenv("CONFIG_FILE"))`, `config:"cors"`, "httpx.Chain(append(mws, httpx.Recover)...)(mux)"} {
+		if !strings.Contains(string(main), want) {
+			t.Errorf("main.go missing %q", want)
+		}
+	}
+
+	// The generated main_test.go signals the process and asserts an
+	// in-flight request still completes.
+	for _, args := range [][]string{{"vet", "./..."}, {"build", "-o", os.DevNull, "."}, {"test", "-count=1", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	if out, _ := exec.Command("gofmt", "-l", dir).Output(); len(out) > 0 {
+		t.Errorf("scaffold is not gofmt-clean: %s", out)
+	}
+}
+
+// TestScaffoldPinsRepositoryVersions keeps the scaffold's dependencies on
+// the versions the repository builds with, so a new service compiles
+// from the same module cache.
+func TestScaffoldPinsRepositoryVersions(t *testing.T) {
+	root, err := FindRoot(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := templatesFS.ReadFile("templates/scaffold/go.mod.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := 0
+	for _, line := range strings.Split(string(tmpl), "\n") {
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "require "))
+		if len(fields) < 2 || !strings.Contains(fields[0], ".") || !strings.HasPrefix(fields[1], "v") {
+			continue
+		}
+		pinned++
+		if !strings.Contains(string(repo), fields[0]+" "+fields[1]) {
+			t.Errorf("scaffold requires %s %s, which is not the version in go.mod", fields[0], fields[1])
+		}
+	}
+	if pinned == 0 {
+		t.Fatal("found no requirements in the scaffold go.mod")
+	}
+}
+
+func TestScaffoldServiceRefusesExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(keep, []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ScaffoldService("billing", dir); err == nil {
+		t.Fatal("scaffolded into an existing directory without Overwrite")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err == nil {
+		t.Fatal("refused scaffold still wrote main.go")
+	}
+	if err := ScaffoldService("billing", dir, Overwrite()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Error("Overwrite removed an unrelated file")
+	}
+	if err := ScaffoldService("Billing!", filepath.Join(dir, "x")); err == nil {
+		t.Error("invalid service name accepted")
+	}
+}
@@ -0,0 +1,441 @@
+package packaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RegistryPath is the service registry, relative to the repository root:
+// one file describing every service the packaging tooling generates
+// Dockerfiles, docker-compose.yml, and Kubernetes manifests for.
+const RegistryPath = "ops/packaging/services.yaml"
+
+// ServiceSpec is one service in the registry:
+//
+//	name: admissions-api
+//	language: go
+//	port: 8080
+//	metrics_port: 9464
+//	health: /health
+//	health_interval: 30s
+//	readiness: /healthz/ready
+//	liveness: /healthz/live
+//	package: ./cmd/admissions-api
+//	build_tags: [netgo]
+//	packages: [ca-certificates]
+//	cgo: false
+//	base: alpine
+//	private_modules: [github.com/acme/*]
+//	cache_mounts: true
+//	pin_digests: true
+//	depends_on: [workflow-platform-api]
+//	needs: [postgres, redis]
+//	replicas: 2
+//	resources:
+//	  requests: {cpu: 100m, memory: 128Mi}
+//	  limits: {cpu: 500m, memory: 256Mi}
+//	autoscale: {min_replicas: 2, max_replicas: 6, cpu_utilization: 75}
+//	env: {LOG_LEVEL: info}
+//	budget: {max_size: 150Mi, max_layers: 20, scan: {fail_on: HIGH}}
+type ServiceSpec struct {
+	Name     string `yaml:"name"`
+	Language string `yaml:"language"`
+	Port     int    `yaml:"port"`
+	// MetricsPort is an optional second port serving /metrics, EXPOSEd by
+	// the image and passed to the service as METRICS_PORT but never
+	// published.
+	MetricsPort int `yaml:"metrics_port"`
+	// Health is the HTTP path probed by the image HEALTHCHECK, every
+	// HealthInterval with HealthTimeout per probe; HealthRetries failures
+	// in a row mark the container unhealthy. Unset fields take the Vars
+	// defaults.
+	Health         string `yaml:"health"`
+	HealthInterval string `yaml:"health_interval"`
+	HealthTimeout  string `yaml:"health_timeout"`
+	HealthRetries  int    `yaml:"health_retries"`
+	// Readiness and Liveness are the HTTP paths of the Kubernetes
+	// readiness and liveness probes, with Health's timeout and retries.
+	// Readiness defaults to Health; without Liveness the pod has no
+	// liveness probe.
+	Readiness string `yaml:"readiness"`
+	Liveness  string `yaml:"liveness"`
+	// Package is the Go main package, relative to the repository root.
+	Package string `yaml:"package"`
+	// BuildTags are passed to go build -tags.
+	BuildTags []string `yaml:"build_tags"`
+	// Packages are extra apk packages installed in the runtime image.
+	Packages []string `yaml:"packages"`
+	// CGO builds with CGO_ENABLED=1 against musl in the builder image.
+	CGO bool `yaml:"cgo"`
+	// Base is the runtime image, "alpine" (default) or "distroless".
+	Base string `yaml:"base"`
+	// PrivateModules are GOPRIVATE patterns fetched with build-time
+	// credentials.
+	PrivateModules []string `yaml:"private_modules"`
+	// CacheMounts keeps Go's module and build caches in BuildKit cache
+	// mounts between builds.
+	CacheMounts bool `yaml:"cache_mounts"`
+	// SharedBuilder builds on the shared builder image of `pack build
+	// --shared-builder`; see Vars.SharedBuilder.
+	SharedBuilder bool `yaml:"shared_builder"`
+	// PinDigests renders the base images by digest, looked up in their
+	// registries when the Dockerfile is rendered or checked.
+	PinDigests bool `yaml:"pin_digests"`
+	// DependsOn names the services that must be started before this one
+	// in the generated docker-compose.yml.
+	DependsOn []string `yaml:"depends_on"`
+	// Needs lists the backing services (postgres, redis) the service is
+	// wired to in docker-compose.yml.
+	Needs []string `yaml:"needs"`
+	// Replicas and Resources size the generated Kubernetes Deployment;
+	// unset values take DefaultReplicas and the Default*Request and
+	// Default*Limit quantities.
+	Replicas  int       `yaml:"replicas"`
+	Resources Resources `yaml:"resources"`
+	// Autoscale adds a HorizontalPodAutoscaler to the generated
+	// manifests, starting the Deployment at its MinReplicas instead of
+	// Replicas.
+	Autoscale *Autoscale `yaml:"autoscale"`
+	// Env is set in the generated Deployment's container, after the
+	// PORT, UNIASSIST_SERVICE_ID, and METRICS_PORT it always gets.
+	Env map[string]string `yaml:"env"`
+	// Budget bounds the built image, checked by pack verify and
+	// pack build --verify.
+	Budget ImageBudget `yaml:"budget"`
+}
+
+// Registry is the layout of RegistryPath:
+//
+//	allowed_ports: {min: 3000, max: 9999}
+//	allowed_base_images: [alpine:3.19, "node:22*"]
+//	services:
+//	  - name: admissions-api
+//	    ...
+type Registry struct {
+	// AllowedPorts is the range services' ports should fall in; nil
+	// means DefaultPortRange. See Warnings.
+	AllowedPorts *PortRange `yaml:"allowed_ports"`
+	// AllowedBaseImages are the tags pack lint lets base images be
+	// pinned to without a digest; nil means DefaultBaseImages. See
+	// AllowBaseImages.
+	AllowedBaseImages []string      `yaml:"allowed_base_images"`
+	Services          []ServiceSpec `yaml:"services"`
+}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// DefaultPortRange is the allowed range of a registry that sets none: the
+// unprivileged ports below the ephemeral range hosts assign to outgoing
+// connections.
+var DefaultPortRange = PortRange{Min: 1024, Max: 49151}
+
+// Contains reports whether port is in r.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Min && port <= r.Max
+}
+
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// Validate rejects a range that is empty or not within 1-65535.
+func (r PortRange) Validate() error {
+	if r.Min < 1 || r.Max > 65535 || r.Min > r.Max {
+		return fmt.Errorf("invalid port range %s: want 1 <= min <= max <= 65535", r)
+	}
+	return nil
+}
+
+// Warnings lists what is allowed but probably a mistake: services whose
+// port is outside AllowedPorts.
+func (r *Registry) Warnings() []string {
+	allowed := DefaultPortRange
+	if r.AllowedPorts != nil {
+		allowed = *r.AllowedPorts
+	}
+	return PortWarnings(r.Services, allowed)
+}
+
+// ParseServices decodes and validates a registry and returns its
+// services; see ParseRegistry.
+func ParseServices(data []byte) ([]ServiceSpec, error) {
+	reg, err := ParseRegistry(data)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Services, nil
+}
+
+// ParseRegistry decodes and validates a registry (see ValidateServices).
+// Unknown keys are errors so that a typo does not silently fall back to
+// a default.
+func ParseRegistry(data []byte) (*Registry, error) {
+	var reg Registry
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&reg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(RegistryPath), err)
+	}
+	if len(reg.Services) == 0 {
+		return nil, fmt.Errorf("%s lists no services", filepath.Base(RegistryPath))
+	}
+	if reg.AllowedPorts != nil {
+		if err := reg.AllowedPorts.Validate(); err != nil {
+			return nil, fmt.Errorf("allowed_ports: %w", err)
+		}
+	}
+	for _, pattern := range reg.AllowedBaseImages {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("allowed_base_images: %q: %w", pattern, err)
+		}
+	}
+	if err := ValidateServices(reg.Services); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// LoadServices reads and validates the registry at path and returns its
+// services; see LoadRegistry.
+func LoadServices(path string) ([]ServiceSpec, error) {
+	reg, err := LoadRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Services, nil
+}
+
+// LoadRegistry reads and validates the registry at path, normally
+// RegistryPath under the repository root. The tooling loads it once per
+// run and hands the ServiceSpecs to every generator, so a registry that
+// fails validation generates nothing.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := ParseRegistry(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// ValidateServices checks every spec and the registry as a whole: names
+// and ports are unique (see ValidatePorts), and dependencies name
+// registered services without forming a cycle. Every problem is reported
+// together.
+func ValidateServices(specs []ServiceSpec) error {
+	var errs []error
+	byName := map[string]ServiceSpec{}
+	for i, s := range specs {
+		err := s.Validate()
+		if err == nil && byName[s.Name].Name != "" {
+			err = errors.New("duplicate service")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %d (%s): %w", i, s.Name, err))
+			continue
+		}
+		byName[s.Name] = s
+	}
+	if err := ValidatePorts(specs); err != nil {
+		errs = append(errs, err)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := checkDependencies(byName, names); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ValidatePorts rejects services sharing a port, naming both: each
+// EXPOSEs it and listens on it, so wherever two of them share a network
+// namespace or a host mapping one silently takes the other's traffic.
+// Metrics ports count too.
+func ValidatePorts(specs []ServiceSpec) error {
+	var errs []error
+	byPort := map[int]string{}
+	for i, s := range specs {
+		for _, p := range []struct {
+			kind string
+			port int
+		}{{"port", s.Port}, {"metrics port", s.MetricsPort}} {
+			if p.port == 0 {
+				continue
+			}
+			if prev, ok := byPort[p.port]; ok {
+				errs = append(errs, fmt.Errorf("service %d (%s): %s %d is already used by %q", i, s.Name, p.kind, p.port, prev))
+				continue
+			}
+			byPort[p.port] = s.Name
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PortWarnings lists the services whose port is outside allowed. Such a
+// port still works, so it is not an error, but it may need root inside
+// the container or collide with the host's ephemeral ports.
+func PortWarnings(specs []ServiceSpec, allowed PortRange) []string {
+	var out []string
+	for _, s := range specs {
+		if !allowed.Contains(s.Port) {
+			out = append(out, fmt.Sprintf("service %q: port %d is outside the allowed range %s", s.Name, s.Port, allowed))
+		}
+		if s.MetricsPort != 0 && !allowed.Contains(s.MetricsPort) {
+			out = append(out, fmt.Sprintf("service %q: metrics port %d is outside the allowed range %s", s.Name, s.MetricsPort, allowed))
+		}
+	}
+	return out
+}
+
+// FindService returns the spec named name.
+func FindService(specs []ServiceSpec, name string) (ServiceSpec, bool) {
+	i := slices.IndexFunc(specs, func(s ServiceSpec) bool { return s.Name == name })
+	if i < 0 {
+		return ServiceSpec{}, false
+	}
+	return specs[i], true
+}
+
+// Validate checks one service's fields, including the template variables
+// they map to, which Render checks again.
+func (s ServiceSpec) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if !slices.Contains(Languages, s.Language) {
+		return fmt.Errorf("unsupported language %q (supported: %v)", s.Language, Languages)
+	}
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf("invalid port %d: must be 1-65535", s.Port)
+	}
+	for _, n := range s.Needs {
+		if !slices.Contains(BackingServices, n) {
+			return fmt.Errorf("unsupported backing service %q (supported: %v)", n, BackingServices)
+		}
+	}
+	for _, p := range []string{s.Readiness, s.Liveness} {
+		if p != "" && !healthPathRE.MatchString(p) {
+			return fmt.Errorf("invalid probe path %q: want an absolute URL path", p)
+		}
+	}
+	if s.Replicas < 0 {
+		return fmt.Errorf("invalid replicas %d", s.Replicas)
+	}
+	if err := s.Resources.Validate(); err != nil {
+		return err
+	}
+	if s.Autoscale != nil {
+		if err := s.Autoscale.Validate(); err != nil {
+			return err
+		}
+	}
+	for name := range s.Env {
+		if !envNameRE.MatchString(name) {
+			return fmt.Errorf("invalid env name %q: want letters, digits, and underscores", name)
+		}
+		if slices.Contains(reservedEnv, name) {
+			return fmt.Errorf("env %s is set from the registry entry; do not set it in env", name)
+		}
+	}
+	if err := s.Budget.Validate(); err != nil {
+		return err
+	}
+	v := s.Vars()
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	return v.validateFor(s.Language)
+}
+
+// Vars maps the spec onto template variables.
+func (s ServiceSpec) Vars() Vars {
+	return Vars{
+		ServiceName:    s.Name,
+		ExposePort:     s.Port,
+		MetricsPort:    s.MetricsPort,
+		HealthPath:     s.Health,
+		HealthInterval: s.HealthInterval,
+		HealthTimeout:  s.HealthTimeout,
+		HealthRetries:  s.HealthRetries,
+		Package:        s.Package,
+		BuildTags:      s.BuildTags,
+		Packages:       s.Packages,
+		CGO:            s.CGO,
+		Base:           s.Base,
+		PrivateModules: s.PrivateModules,
+		UseCacheMounts: s.CacheMounts,
+		SharedBuilder:  s.SharedBuilder,
+		PinDigests:     s.PinDigests,
+	}
+}
+
+// checkDependencies rejects dependencies on services missing from
+// byName, then dependency loops, which cannot be started in any order,
+// naming the first one found.
+func checkDependencies(byName map[string]ServiceSpec, names []string) error {
+	var errs []error
+	for _, name := range names {
+		for _, dep := range byName[name].DependsOn {
+			if _, ok := byName[dep]; !ok {
+				errs = append(errs, fmt.Errorf("service %q: unknown dependency %q", name, dep))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			i := slices.Index(stack, name)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(stack[i:], name), " -> "))
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRegistry(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// registryOf makes a registry from single-service YAML bodies.
+func registryOf(services ...string) string {
+	var b strings.Builder
+	b.WriteString("services:\n")
+	for _, s := range services {
+		for i, line := range strings.Split(strings.TrimSpace(s), "\n") {
+			if i == 0 {
+				b.WriteString("  - " + line + "\n")
+			} else {
+				b.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestLoadServices(t *testing.T) {
+	path := writeRegistry(t, registryOf(`
+name: billing
+language: go
+port: 9090
+health: /ready
+health_interval: 10s
+build_tags: [netgo, osusergo]
+packages: [ca-certificates]
+cgo: true
+private_modules: [github.com/acme/*]
+cache_mounts: true
+depends_on: [ledger]
+`, "name: ledger\nlanguage: node\nport: 3000\n"))
+	specs, err := LoadServices(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d services, want 2", len(specs))
+	}
+	spec, ok := FindService(specs, "billing")
+	if !ok {
+		t.Fatal("billing not found")
+	}
+	out, err := Render(spec.Language, spec.Vars())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidDockerfile(t, out)
+	for _, want := range []string{
+		"FROM golang:1.22-alpine AS builder",
+		"RUN apk add --no-cache build-base",
+		"CGO_ENABLED=1 GOOS=$TARGETOS",
+		`go build -tags "netgo,osusergo" `,
+		"RUN apk add --no-cache ca-certificates",
+		"HEALTHCHECK --interval=10s --timeout=3s --retries=3",
+		"http://localhost:9090/ready",
+		"ARG GOPRIVATE=github.com/acme/*",
+		"RUN --mount=type=cache,target=/go/pkg/mod --mount=type=cache,target=/root/.cache/go-build CGO_ENABLED=1",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseServicesErrors(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{"empty", "", []string{"lists no services"}},
+		{"top-level typo", "service:\n  - name: a\n", []string{"field service not found"}},
+		{"typo", registryOf("name: typo\nlanguage: go\nport: 80\nhealth_path: /x\n"), []string{"field health_path not found"}},
+		{"port", registryOf("name: port\nlanguage: go\nport: 70000\n"), []string{"invalid port 70000"}},
+		{"lang", registryOf("name: lang\nlanguage: rust\nport: 80\n"), []string{"unsupported language"}},
+		{"cgo-node", registryOf("name: cgo-node\nlanguage: node\nport: 80\ncgo: true\n"), []string{"does not support cgo"}},
+		{"base", registryOf("name: base\nlanguage: go\nport: 80\nbase: ubuntu\n"), []string{`unsupported base image "ubuntu"`}},
+		{"needs", registryOf("name: needs\nlanguage: go\nport: 80\nneeds: [mongo]\n"), []string{`unsupported backing service "mongo"`}},
+		{"replicas", registryOf("name: replicas\nlanguage: go\nport: 80\nreplicas: -1\n"), []string{"invalid replicas -1"}},
+		{"cpu", registryOf("name: cpu\nlanguage: go\nport: 80\nresources: {limits: {cpu: 1 core}}\n"), []string{`invalid cpu quantity "1 core"`}},
+		{"memory", registryOf("name: memory\nlanguage: go\nport: 80\nresources: {requests: {memory: 1Gi}}\n"), []string{"memory request 1Gi exceeds limit 256Mi"}},
+		{"autoscale", registryOf("name: autoscale\nlanguage: go\nport: 80\nautoscale: {min_replicas: 3, max_replicas: 2}\n"), []string{"invalid autoscale replicas 3-2"}},
+		{"budget size", registryOf("name: budget\nlanguage: go\nport: 80\nbudget: {max_size: 150MB}\n"), []string{`budget max_size: invalid memory quantity "150MB"`}},
+		{"budget severity", registryOf("name: budget\nlanguage: go\nport: 80\nbudget: {scan: {fail_on: severe}}\n"), []string{`invalid budget scan fail_on "severe"`}},
+		{"env name", registryOf("name: env\nlanguage: go\nport: 80\nenv: {LOG-LEVEL: info}\n"), []string{"invalid env name"}},
+		{"env reserved", registryOf("name: env\nlanguage: go\nport: 80\nenv: {PORT: \"81\"}\n"), []string{"env PORT is set from the registry entry"}},
+		{"cgo-distroless", registryOf("name: cgo-distroless\nlanguage: go\nport: 80\nbase: distroless\ncgo: true\n"), []string{"supports neither cgo"}},
+		{"duplicate name", registryOf("name: a\nlanguage: go\nport: 80\n", "name: a\nlanguage: go\nport: 81\n"), []string{"service 1 (a): duplicate service"}},
+		{"duplicate port", registryOf(
+			"name: a\nlanguage: go\nport: 8080\n",
+			"name: b\nlanguage: node\nport: 3000\n",
+			"name: c\nlanguage: go\nport: 8080\n",
+		), []string{`service 2 (c): port 8080 is already used by "a"`}},
+		{"unknown dependency", registryOf("name: a\nlanguage: go\nport: 80\ndepends_on: [b]\n"), []string{`service "a": unknown dependency "b"`}},
+		{"cycle", registryOf(
+			"name: a\nlanguage: go\nport: 80\ndepends_on: [b]\n",
+			"name: b\nlanguage: go\nport: 81\ndepends_on: [c]\n",
+			"name: c\nlanguage: go\nport: 82\ndepends_on: [a]\n",
+		), []string{"dependency cycle: a -> b -> c -> a"}},
+		{"self", registryOf("name: a\nlanguage: go\nport: 80\ndepends_on: [a]\n"), []string{"dependency cycle: a -> a"}},
+		{"several", registryOf(
+			"name: a\nlanguage: go\nport: 80\n",
+			"name: b\nlanguage: go\nport: 80\n",
+			"name: c\nlanguage: rust\nport: 81\n",
+		), []string{"port 80 is already used", "unsupported language"}},
+	}
+	for _, tc := range tests {
+		_, err := ParseServices([]byte(tc.body))
+		for _, want := range tc.want {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: err = %v, want %q", tc.name, err, want)
+			}
+		}
+	}
+
+	if _, err := LoadServices(filepath.Join(t.TempDir(), "services.yaml")); err == nil {
+		t.Error("missing registry accepted")
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	specs := []ServiceSpec{
+		{Name: "workflow-runtime", Language: "go", Port: 8080},
+		{Name: "portal", Language: "node", Port: 3000},
+		{Name: "admissions-api", Language: "go", Port: 8080},
+	}
+	err := ValidatePorts(specs)
+	if err == nil || !strings.Contains(err.Error(), `service 2 (admissions-api): port 8080 is already used by "workflow-runtime"`) {
+		t.Errorf("err = %v, want both services named", err)
+	}
+	specs[2].Port = 8081
+	if err := ValidatePorts(specs); err != nil {
+		t.Errorf("distinct ports: %v", err)
+	}
+	specs[1].MetricsPort = 8081
+	if err := ValidatePorts(specs); err == nil || !strings.Contains(err.Error(), `service 2 (admissions-api): port 8081 is already used by "portal"`) {
+		t.Errorf("err = %v, want the metrics port to count", err)
+	}
+}
+
+func TestPortWarnings(t *testing.T) {
+	specs := []ServiceSpec{
+		{Name: "web", Language: "node", Port: 80},
+		{Name: "api", Language: "go", Port: 8080},
+		{Name: "probe", Language: "go", Port: 50000},
+	}
+	got := PortWarnings(specs, DefaultPortRange)
+	want := []string{
+		`service "web": port 80 is outside the allowed range 1024-49151`,
+		`service "probe": port 50000 is outside the allowed range 1024-49151`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	reg, err := ParseRegistry([]byte("allowed_ports: {min: 3000, max: 3999}\n" + registryOf("name: api\nlanguage: go\nport: 8080\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := reg.Warnings(); len(w) != 1 || !strings.Contains(w[0], "allowed range 3000-3999") {
+		t.Errorf("configured range: warnings = %q", w)
+	}
+	for _, bad := range []string{"{min: 4000, max: 3000}", "{min: 0, max: 80}", "{min: 80, max: 70000}"} {
+		if _, err := ParseRegistry([]byte("allowed_ports: " + bad + "\n" + registryOf("name: api\nlanguage: go\nport: 8080\n"))); err == nil {
+			t.Errorf("allowed_ports %s accepted", bad)
+		}
+	}
+
+	reg, err = ParseRegistry([]byte("allowed_base_images: [alpine:3.19, \"node:22*\"]\n" + registryOf("name: api\nlanguage: go\nport: 8080\n")))
+	if err != nil || len(reg.AllowedBaseImages) != 2 {
+		t.Errorf("allowed_base_images: %v %v", reg, err)
+	}
+	if _, err := ParseRegistry([]byte("allowed_base_images: [\"node:[22\"]\n" + registryOf("name: api\nlanguage: go\nport: 8080\n"))); err == nil {
+		t.Error("malformed allowed_base_images pattern accepted")
+	}
+}
+
+func TestRegisteredServicesRender(t *testing.T) {
+	repo, err := FindRoot(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := LoadServices(filepath.Join(repo, RegistryPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range specs {
+		if _, err := Render(spec.Language, spec.Vars()); err != nil {
+			t.Errorf("%s: %v", spec.Name, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package packaging
+
+import (
+	"fmt"
+	"path"
+)
+
+// SystemdDir is where pack systemd writes units, relative to the
+// repository root.
+const SystemdDir = "ops/deploy/systemd"
+
+// Host layout of a systemd-managed service: its binary is installed as
+// SystemdInstallRoot/<name>/<binary> and its configuration read from
+// SystemdEnvDir/<name>.env.
+const (
+	SystemdInstallRoot = "/opt/uniassist"
+	SystemdEnvDir      = "/etc/uniassist"
+)
+
+// systemdVars are the variables of the systemd unit template.
+type systemdVars struct {
+	Vars
+	InstallDir      string
+	EnvironmentFile string
+	UID             int64
+	UserName        string
+}
+
+// GenerateSystemdUnit returns a systemd unit, <name>.service, running
+// spec's binary on a host the way its image runs it in a container: as
+// the same non-root user, with PORT and the other variables the
+// container gets, plus whatever the service's EnvironmentFile holds.
+// Only Go services have a unit template; their image is a single static
+// binary, so installing it on a host is copying one file.
+func GenerateSystemdUnit(spec ServiceSpec) ([]byte, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if spec.Language != "go" {
+		return nil, fmt.Errorf("systemd: no unit template for %s services", spec.Language)
+	}
+	v := spec.Vars().withDefaults()
+	uid := runtimeUID(spec.Language, v.Base)
+	user := "nobody"
+	if uid == uidNonroot {
+		user = "nonroot"
+	}
+	tmpl, err := parseTemplate("systemd.service.go.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return executeTemplate(tmpl, systemdVars{
+		Vars:            v,
+		InstallDir:      path.Join(SystemdInstallRoot, spec.Name),
+		EnvironmentFile: path.Join(SystemdEnvDir, spec.Name+".env"),
+		UID:             uid,
+		UserName:        user,
+	})
+}
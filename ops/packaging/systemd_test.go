@@ -0,0 +1,126 @@
+package packaging
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parseUnit reads a systemd unit into section -> key -> values, failing
+// on any line systemd would not accept.
+func parseUnit(t *testing.T, data []byte) map[string]map[string][]string {
+	t.Helper()
+	unit := map[string]map[string][]string{}
+	var section string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+			if _, dup := unit[section]; dup {
+				t.Fatalf("line %d: section %s repeated", n, section)
+			}
+			unit[section] = map[string][]string{}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || section == "" || key == "" || strings.ContainsAny(key, " \t") {
+				t.Fatalf("line %d is not a key=value in a section: %q", n, line)
+			}
+			unit[section][key] = append(unit[section][key], value)
+		}
+	}
+	return unit
+}
+
+func TestGenerateSystemdUnit(t *testing.T) {
+	for _, tt := range []struct {
+		spec ServiceSpec
+		uid  int64
+	}{
+		{ServiceSpec{Name: "admissions-api", Language: "go", Port: 8080, MetricsPort: 9464}, uidNobody},
+		{ServiceSpec{Name: "billing", Language: "go", Port: 9090, Base: BaseDistroless}, uidNonroot},
+	} {
+		t.Run(tt.spec.Name, func(t *testing.T) {
+			data, err := GenerateSystemdUnit(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unit := parseUnit(t, data)
+			for _, s := range []string{"Unit", "Service", "Install"} {
+				if unit[s] == nil {
+					t.Fatalf("no [%s] section:\n%s", s, data)
+				}
+			}
+			svc := unit["Service"]
+			one := func(key string) string {
+				t.Helper()
+				if len(svc[key]) != 1 {
+					t.Fatalf("%s = %q, want one value", key, svc[key])
+				}
+				return svc[key][0]
+			}
+
+			// The binary must be the one the image runs.
+			exec := one("ExecStart")
+			dockerfile, err := Render("go", tt.spec.Vars())
+			if err != nil {
+				t.Fatal(err)
+			}
+			binary := regexp.MustCompile(`(?m)^(?:CMD \["\./|ENTRYPOINT \["/app/)([^"]+)"\]$`).FindSubmatch(dockerfile)
+			if binary == nil {
+				t.Fatalf("no binary in the Dockerfile:\n%s", dockerfile)
+			}
+			if want := path.Join(SystemdInstallRoot, tt.spec.Name, string(binary[1])); exec != want {
+				t.Errorf("ExecStart = %q, want %q", exec, want)
+			}
+			if one("WorkingDirectory") != path.Dir(exec) {
+				t.Errorf("WorkingDirectory = %q, want %q", one("WorkingDirectory"), path.Dir(exec))
+			}
+
+			if u := one("User"); u != strconv.FormatInt(tt.uid, 10) {
+				t.Errorf("User = %s, want %d", u, tt.uid)
+			}
+			if one("Restart") != "on-failure" {
+				t.Errorf("Restart = %q", one("Restart"))
+			}
+			if f := one("EnvironmentFile"); f != path.Join(SystemdEnvDir, tt.spec.Name+".env") {
+				t.Errorf("EnvironmentFile = %q", f)
+			}
+			env := map[string]string{}
+			for _, kv := range svc["Environment"] {
+				k, v, _ := strings.Cut(kv, "=")
+				env[k] = v
+			}
+			want := map[string]string{"PORT": strconv.Itoa(tt.spec.Port), "UNIASSIST_SERVICE_ID": tt.spec.Name}
+			if tt.spec.MetricsPort != 0 {
+				want["METRICS_PORT"] = strconv.Itoa(tt.spec.MetricsPort)
+			}
+			if len(env) != len(want) {
+				t.Errorf("Environment = %v, want %v", env, want)
+			}
+			for k, v := range want {
+				if env[k] != v {
+					t.Errorf("Environment %s = %q, want %q", k, env[k], v)
+				}
+			}
+			if got := unit["Install"]["WantedBy"]; len(got) != 1 || got[0] != "multi-user.target" {
+				t.Errorf("WantedBy = %q", got)
+			}
+		})
+	}
+}
+
+func TestGenerateSystemdUnitRejects(t *testing.T) {
+	if _, err := GenerateSystemdUnit(ServiceSpec{Name: "portal", Language: "node", Port: 3000}); err == nil || !strings.Contains(err.Error(), "node") {
+		t.Errorf("node service: %v", err)
+	}
+	if _, err := GenerateSystemdUnit(ServiceSpec{Name: "Bad Name", Language: "go", Port: 8080}); err == nil {
+		t.Error("invalid spec generated a unit")
+	}
+}
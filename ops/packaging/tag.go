@@ -0,0 +1,183 @@
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HashContext returns a hex SHA-256 over the files BuildKit would send for
+// contextDir (paths, executable bits, and contents, skipping everything
+// ignore excludes) plus the Dockerfile itself, which usually lives outside
+// the context. The result does not depend on walk order or timestamps.
+func HashContext(contextDir string, ignore *Dockerignore, dockerfile string) (string, error) {
+	type entry struct{ path, sum string }
+	var entries []entry
+	err := filepath.WalkDir(contextDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(contextDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.Ignored(rel) {
+			if d.IsDir() && !ignore.mayReinclude(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := hashFile(p, d)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{rel, sum})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+
+	h := sha256.New()
+	df, err := os.ReadFile(dockerfile)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "Dockerfile\x00%x\n", sha256.Sum256(df))
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s\x00%s\n", e.path, e.sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile hashes one context entry: a symlink by its target, a regular
+// file by its executable bit and contents.
+func hashFile(p string, d fs.DirEntry) (string, error) {
+	if d.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(p)
+		if err != nil {
+			return "", err
+		}
+		return "link:" + target, nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%o:%x", info.Mode().Perm()&0o111, h.Sum(nil)), nil
+}
+
+// ImageTag returns a deterministic tag for building dockerfile with
+// contextDir: the 12-character HEAD commit when the inputs are committed,
+// or <commit>-dirty-<hash> when the Dockerfile, its .dockerignore, or a
+// file in the context that the ignore rules do not exclude has uncommitted
+// changes, where hash
+// is the first 12 characters of HashContext.
+func ImageTag(ctx context.Context, dockerfile, contextDir string) (string, error) {
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = contextDir
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+		}
+		return out, nil
+	}
+	out, err := git("rev-parse", "--short=12", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(out))
+	if out, err = git("rev-parse", "--show-toplevel"); err != nil {
+		return "", err
+	}
+	top := strings.TrimSpace(string(out))
+
+	absContext, err := filepath.Abs(contextDir)
+	if err != nil {
+		return "", err
+	}
+	absDockerfile, err := filepath.Abs(dockerfile)
+	if err != nil {
+		return "", err
+	}
+	ignore, err := LoadContextIgnore(absDockerfile, absContext)
+	if err != nil {
+		return "", err
+	}
+	// The Dockerfile and its ignore file shape the build but usually live
+	// outside the context or are excluded from it.
+	absIgnore := absDockerfile + ".dockerignore"
+	status, err := git("status", "--porcelain", "-z", "--untracked-files=all", "--", absContext, absDockerfile, absIgnore)
+	if err != nil {
+		return "", err
+	}
+	dirty := false
+	for _, changed := range porcelainPaths(status) {
+		abs := filepath.Join(top, filepath.FromSlash(changed))
+		if abs == absDockerfile || abs == absIgnore {
+			dirty = true
+			break
+		}
+		rel, err := filepath.Rel(absContext, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if !ignore.Ignored(filepath.ToSlash(rel)) {
+			dirty = true
+			break
+		}
+	}
+	if !dirty {
+		return commit, nil
+	}
+	sum, err := HashContext(absContext, ignore, absDockerfile)
+	if err != nil {
+		return "", err
+	}
+	return commit + "-dirty-" + sum[:12], nil
+}
+
+// porcelainPaths extracts the paths from `git status --porcelain -z`
+// output. Renames and copies list both the new and the original path.
+func porcelainPaths(out []byte) []string {
+	var paths []string
+	fields := bytes.Split(out, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if len(f) < 4 {
+			continue
+		}
+		paths = append(paths, string(f[3:]))
+		if f[0] == 'R' || f[0] == 'C' {
+			if i+1 < len(fields) {
+				paths = append(paths, string(fields[i+1]))
+			}
+			i++
+		}
+	}
+	return paths
+}
@@ -0,0 +1,123 @@
+package packaging
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"Dockerfile":           "FROM scratch\n",
+		"go.mod":               "module x\n",
+		"cmd/billing/main.go":  "package main\n",
+		"cmd/payroll/main.go":  "package main\n",
+		"cmd/billing/notes.md": "draft\n",
+	})
+	ignore := ParseDockerignore([]byte("cmd/*\n!cmd/billing\n**/*.md\nDockerfile\n"))
+	hash := func() string {
+		t.Helper()
+		h, err := HashContext(root, ignore, filepath.Join(root, "Dockerfile"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	base := hash()
+	if base != hash() {
+		t.Fatal("HashContext is not deterministic")
+	}
+
+	writeFiles(t, root, map[string]string{"cmd/billing/notes.md": "edited\n", "cmd/payroll/main.go": "package main // edited\n"})
+	if hash() != base {
+		t.Error("edits to ignored files changed the hash")
+	}
+	writeFiles(t, root, map[string]string{"Dockerfile": "FROM alpine\n"})
+	if hash() == base {
+		t.Error("Dockerfile edit did not change the hash")
+	}
+	writeFiles(t, root, map[string]string{"Dockerfile": "FROM scratch\n", "cmd/billing/main.go": "package main // edited\n"})
+	if hash() == base {
+		t.Error("service source edit did not change the hash")
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                      "module x\n",
+		"cmd/billing/main.go":         "package main\n",
+		"README.md":                   "docs\n",
+		"svc.Dockerfile":              "FROM scratch\n",
+		"svc.Dockerfile.dockerignore": "*.md\nsvc.Dockerfile*\n",
+	})
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-qm", "init")
+	commit := git("rev-parse", "--short=12", "HEAD")[:12]
+
+	tag := func() string {
+		t.Helper()
+		tag, err := ImageTag(context.Background(), filepath.Join(root, "svc.Dockerfile"), root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag
+	}
+	if got := tag(); got != commit {
+		t.Fatalf("clean tree tag = %q, want %q", got, commit)
+	}
+	writeFiles(t, root, map[string]string{"README.md": "edited\n", "notes.md": "untracked\n"})
+	if got := tag(); got != commit {
+		t.Errorf("ignored edits made the tag %q, want %q", got, commit)
+	}
+
+	writeFiles(t, root, map[string]string{"svc.Dockerfile.dockerignore": "*.md\nsvc.Dockerfile*\nnotes\n"})
+	if got := tag(); got == commit {
+		t.Error("edited .dockerignore left the tag clean")
+	}
+	git("checkout", "svc.Dockerfile.dockerignore")
+
+	writeFiles(t, root, map[string]string{"cmd/billing/main.go": "package main // edited\n"})
+	dirty := tag()
+	if !regexp.MustCompile("^" + commit + "-dirty-[0-9a-f]{12}$").MatchString(dirty) {
+		t.Fatalf("dirty tag = %q", dirty)
+	}
+	if again := tag(); again != dirty {
+		t.Errorf("dirty tag changed between runs: %q then %q", dirty, again)
+	}
+	writeFiles(t, root, map[string]string{"svc.Dockerfile": "FROM alpine\n"})
+	if got := tag(); got == dirty {
+		t.Error("Dockerfile edit did not change the dirty tag")
+	}
+}
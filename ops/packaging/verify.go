@@ -0,0 +1,402 @@
+package packaging
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ImageBudget bounds what a service's image may carry, checked by
+// VerifyImage after it is built:
+//
+//	budget:
+//	  max_size: 150Mi
+//	  max_layers: 20
+//	  disallowed_bases: [ubuntu, node:*-bullseye]
+//	  scan: {fail_on: HIGH}
+type ImageBudget struct {
+	// MaxSize is the largest compressed size, what a registry stores and
+	// a node pulls, in the units of a memory quantity such as 150Mi or
+	// 300M.
+	MaxSize string `yaml:"max_size"`
+	// MaxLayers is the most filesystem layers the image may have.
+	MaxLayers int `yaml:"max_layers"`
+	// DisallowedBases are base images the runtime stage may not start
+	// from: a repository such as ubuntu, matching every tag, or a
+	// name:tag pattern such as node:*-bullseye.
+	DisallowedBases []string `yaml:"disallowed_bases"`
+	// Scan runs trivy or grype, whichever is installed, over the image.
+	Scan *ScanBudget `yaml:"scan"`
+}
+
+// ScanBudget is the vulnerability scan of an ImageBudget.
+type ScanBudget struct {
+	// FailOn is the lowest severity that fails the scan: LOW, MEDIUM,
+	// HIGH, or CRITICAL. Empty means HIGH.
+	FailOn string `yaml:"fail_on"`
+}
+
+// DefaultFailOn is the FailOn of a ScanBudget that sets none.
+const DefaultFailOn = "HIGH"
+
+// severityRank orders scanner severities. Grype's Negligible and both
+// scanners' Unknown rank below every FailOn.
+var severityRank = map[string]int{"UNKNOWN": 0, "NEGLIGIBLE": 0, "LOW": 1, "MEDIUM": 2, "HIGH": 3, "CRITICAL": 4}
+
+func (s ScanBudget) failOn() string {
+	if s.FailOn == "" {
+		return DefaultFailOn
+	}
+	return strings.ToUpper(s.FailOn)
+}
+
+// Validate checks the budget's sizes, patterns, and severity.
+func (b ImageBudget) Validate() error {
+	if b.MaxSize != "" {
+		if _, err := parseMemory(b.MaxSize); err != nil {
+			return fmt.Errorf("budget max_size: %w", err)
+		}
+	}
+	if b.MaxLayers < 0 {
+		return fmt.Errorf("invalid budget max_layers %d", b.MaxLayers)
+	}
+	for _, p := range b.DisallowedBases {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			return fmt.Errorf("invalid budget disallowed base %q: want an image name or name:tag pattern", p)
+		}
+	}
+	if b.Scan != nil {
+		if rank, ok := severityRank[b.Scan.failOn()]; !ok || rank == 0 {
+			return fmt.Errorf("invalid budget scan fail_on %q: want LOW, MEDIUM, HIGH, or CRITICAL", b.Scan.FailOn)
+		}
+	}
+	return nil
+}
+
+// CheckStatus is the outcome of one Check. Only CheckFail fails a
+// VerifyReport; CheckWarn marks a check that could not run, and
+// CheckSkip one the budget does not declare.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckFail CheckStatus = "fail"
+	CheckWarn CheckStatus = "warn"
+	CheckSkip CheckStatus = "skip"
+)
+
+// Check names, reported in Check.Name.
+const (
+	CheckSize   = "size"
+	CheckLayers = "layers"
+	CheckBase   = "base"
+	CheckScan   = "scan"
+)
+
+// Check is one budget check of a VerifyReport: the image's Actual value
+// against the Budget declared for it.
+type Check struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Actual string      `json:"actual"`
+	Budget string      `json:"budget"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+// VerifyReport is the result of VerifyImage.
+type VerifyReport struct {
+	Image   string  `json:"image"`
+	Service string  `json:"service"`
+	Passed  bool    `json:"passed"`
+	Checks  []Check `json:"checks"`
+}
+
+// ImageInfo is what the budget checks measure of an image.
+type ImageInfo struct {
+	// Size is the compressed size in bytes.
+	Size   int64
+	Layers int
+	// Base is the image the Dockerfile's runtime stage starts from, or
+	// "" when it is not known.
+	Base string
+}
+
+// CheckBudget checks info against every limit of b but the scan, in the
+// order size, layers, base.
+func CheckBudget(info ImageInfo, b ImageBudget) []Check {
+	size := Check{Name: CheckSize, Status: CheckSkip, Actual: formatSize(info.Size), Budget: "-"}
+	if b.MaxSize != "" {
+		max, _ := parseMemory(b.MaxSize)
+		size.Budget, size.Status = b.MaxSize, CheckPass
+		if float64(info.Size) > max {
+			size.Status = CheckFail
+		}
+	}
+	layers := Check{Name: CheckLayers, Status: CheckSkip, Actual: strconv.Itoa(info.Layers), Budget: "-"}
+	if b.MaxLayers > 0 {
+		layers.Budget, layers.Status = strconv.Itoa(b.MaxLayers), CheckPass
+		if info.Layers > b.MaxLayers {
+			layers.Status = CheckFail
+		}
+	}
+	base := Check{Name: CheckBase, Status: CheckSkip, Actual: info.Base, Budget: "-"}
+	if len(b.DisallowedBases) > 0 {
+		base.Budget, base.Status = "not "+strings.Join(b.DisallowedBases, ", "), CheckPass
+		switch p, ok := disallowedBase(info.Base, b.DisallowedBases); {
+		case info.Base == "":
+			base.Status, base.Actual, base.Detail = CheckWarn, "-", "base image unknown: no Dockerfile for the service"
+		case ok:
+			base.Status, base.Detail = CheckFail, "matches "+p
+		}
+	}
+	return []Check{size, layers, base}
+}
+
+// disallowedBase returns the first of patterns that base matches.
+// The digest of a pinned base is ignored.
+func disallowedBase(base string, patterns []string) (string, bool) {
+	base, _, _ = strings.Cut(base, "@")
+	name := base
+	if i := strings.LastIndex(base, ":"); i > strings.LastIndex(base, "/") {
+		name = base[:i]
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, base); ok {
+			return p, true
+		}
+		if ok, _ := path.Match(p, name); ok && !strings.Contains(p, ":") {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func formatSize(n int64) string {
+	return fmt.Sprintf("%.1fMi", float64(n)/(1<<20))
+}
+
+// BaseImage returns the image the last stage of a Dockerfile starts
+// from, following stages that start from an earlier one, or "" for a
+// file with no FROM.
+func BaseImage(dockerfile []byte) string {
+	stages := parseStages(dockerfile)
+	if len(stages) == 0 {
+		return ""
+	}
+	image := stages[len(stages)-1].image()
+	for range stages {
+		i := slices.IndexFunc(stages, func(st stage) bool { return strings.EqualFold(st.name(), image) })
+		if i < 0 {
+			break
+		}
+		image = stages[i].image()
+	}
+	return image
+}
+
+// Vulnerability is one scanner finding.
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Package  string `json:"package"`
+}
+
+// Scanner is a vulnerability scanner VerifyImage can shell out to.
+type Scanner struct {
+	Name string
+	// Args are the arguments that scan image and print JSON for Parse.
+	Args  func(image string) []string
+	Parse func(data []byte) ([]Vulnerability, error)
+}
+
+// Scanners are the scanners VerifyImage looks for on PATH, in order.
+var Scanners = []Scanner{
+	{Name: "trivy", Args: func(image string) []string {
+		return []string{"image", "--quiet", "--scanners", "vuln", "--format", "json", image}
+	}, Parse: parseTrivy},
+	{Name: "grype", Args: func(image string) []string {
+		return []string{image, "--quiet", "--output", "json"}
+	}, Parse: parseGrype},
+}
+
+func parseTrivy(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID string
+				PkgName         string
+				Severity        string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("trivy: parse report: %w", err)
+	}
+	var out []Vulnerability
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			out = append(out, Vulnerability{ID: v.VulnerabilityID, Severity: strings.ToUpper(v.Severity), Package: v.PkgName})
+		}
+	}
+	return out, nil
+}
+
+func parseGrype(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name string `json:"name"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("grype: parse report: %w", err)
+	}
+	var out []Vulnerability
+	for _, m := range report.Matches {
+		out = append(out, Vulnerability{ID: m.Vulnerability.ID, Severity: strings.ToUpper(m.Vulnerability.Severity), Package: m.Artifact.Name})
+	}
+	return out, nil
+}
+
+// CheckVulnerabilities fails when any of vulns, found by scanner, is of
+// s's FailOn severity or above, naming the first few.
+func CheckVulnerabilities(scanner string, vulns []Vulnerability, s ScanBudget) Check {
+	failOn := s.failOn()
+	c := Check{Name: CheckScan, Status: CheckPass, Budget: "none " + failOn + " or above"}
+	var over []string
+	for _, v := range vulns {
+		if severityRank[v.Severity] >= severityRank[failOn] {
+			over = append(over, v.ID+" ("+v.Package+", "+v.Severity+")")
+		}
+	}
+	c.Actual = fmt.Sprintf("%d %s or above (%d total)", len(over), failOn, len(vulns))
+	c.Detail = scanner
+	if len(over) > 0 {
+		c.Status = CheckFail
+		if len(over) > 5 {
+			over = append(over[:5], fmt.Sprintf("and %d more", len(over)-5))
+		}
+		c.Detail = scanner + ": " + strings.Join(over, ", ")
+	}
+	return c
+}
+
+// VerifyOptions is what VerifyImage checks.
+type VerifyOptions struct {
+	Image   string
+	Service string
+	Budget  ImageBudget
+	// Dockerfile is the path of the service's Dockerfile, read for the
+	// base image; the base check warns when it cannot be read.
+	Dockerfile string
+	// RequireScanner fails the scan check, instead of warning, when
+	// neither trivy nor grype is on PATH, and runs the scan even when the
+	// budget declares none.
+	RequireScanner bool
+}
+
+// ErrNoScanner is reported when no Scanners binary is on PATH.
+var ErrNoScanner = errors.New("no vulnerability scanner (trivy or grype) on PATH")
+
+// VerifyImage checks opts.Image, which must be in the local Docker
+// daemon, against opts.Budget. The size is that of the image saved and
+// gzipped, as a registry would store it. The error is reserved for an
+// image that cannot be inspected or a scanner that fails to run; budget
+// failures are in the report.
+func VerifyImage(ctx context.Context, opts VerifyOptions) (*VerifyReport, error) {
+	info, err := InspectImage(ctx, opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := os.ReadFile(opts.Dockerfile); err == nil {
+		info.Base = BaseImage(data)
+	}
+	r := &VerifyReport{Image: opts.Image, Service: opts.Service, Checks: CheckBudget(info, opts.Budget)}
+	if opts.Budget.Scan != nil || opts.RequireScanner {
+		scan := ScanBudget{}
+		if opts.Budget.Scan != nil {
+			scan = *opts.Budget.Scan
+		}
+		c, err := scanImage(ctx, opts.Image, scan)
+		switch {
+		case errors.Is(err, ErrNoScanner):
+			c = Check{Name: CheckScan, Status: CheckWarn, Actual: "-", Budget: "none " + scan.failOn() + " or above", Detail: err.Error()}
+			if opts.RequireScanner {
+				c.Status = CheckFail
+			}
+		case err != nil:
+			return nil, err
+		}
+		r.Checks = append(r.Checks, c)
+	}
+	r.Passed = !slices.ContainsFunc(r.Checks, func(c Check) bool { return c.Status == CheckFail })
+	return r, nil
+}
+
+func scanImage(ctx context.Context, image string, s ScanBudget) (Check, error) {
+	for _, sc := range Scanners {
+		bin, err := exec.LookPath(sc.Name)
+		if err != nil {
+			continue
+		}
+		var stderr strings.Builder
+		cmd := exec.CommandContext(ctx, bin, sc.Args(image)...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return Check{}, fmt.Errorf("%s %s: %w: %s", sc.Name, image, err, strings.TrimSpace(stderr.String()))
+		}
+		vulns, err := sc.Parse(out)
+		if err != nil {
+			return Check{}, err
+		}
+		return CheckVulnerabilities(sc.Name, vulns, s), nil
+	}
+	return Check{}, ErrNoScanner
+}
+
+// InspectImage measures an image in the local Docker daemon: its layer
+// count from docker image inspect, and its compressed size by gzipping
+// docker save's output, as close to what a registry stores as a local
+// image gets. Base is left to the caller.
+func InspectImage(ctx context.Context, image string) (ImageInfo, error) {
+	out, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{len .RootFS.Layers}}", image).Output()
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("docker image inspect %s: %w", image, err)
+	}
+	layers, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("docker image inspect %s: unexpected output %q", image, out)
+	}
+	var size countingWriter
+	zw := gzip.NewWriter(&size)
+	cmd := exec.CommandContext(ctx, "docker", "save", image)
+	cmd.Stdout = zw
+	if err := cmd.Run(); err != nil {
+		return ImageInfo{}, fmt.Errorf("docker save %s: %w", image, err)
+	}
+	if err := zw.Close(); err != nil {
+		return ImageInfo{}, err
+	}
+	return ImageInfo{Size: int64(size), Layers: layers}, nil
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,86 @@
+package packaging
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckBudget(t *testing.T) {
+	b := ImageBudget{MaxSize: "100Mi", MaxLayers: 10, DisallowedBases: []string{"ubuntu", "node:*-bullseye"}}
+	for _, tc := range []struct {
+		name string
+		info ImageInfo
+		want []CheckStatus
+	}{
+		{"within", ImageInfo{Size: 40 << 20, Layers: 8, Base: "alpine:3.19"}, []CheckStatus{CheckPass, CheckPass, CheckPass}},
+		{"over", ImageInfo{Size: 300 << 20, Layers: 11, Base: "ubuntu:22.04"}, []CheckStatus{CheckFail, CheckFail, CheckFail}},
+		{"pinned tag pattern", ImageInfo{Size: 1, Layers: 1, Base: "node:22-bullseye@sha256:abc"}, []CheckStatus{CheckPass, CheckPass, CheckFail}},
+		{"other tag", ImageInfo{Size: 1, Layers: 1, Base: "node:22-alpine"}, []CheckStatus{CheckPass, CheckPass, CheckPass}},
+		{"unknown base", ImageInfo{Size: 1, Layers: 1}, []CheckStatus{CheckPass, CheckPass, CheckWarn}},
+	} {
+		checks := CheckBudget(tc.info, b)
+		var got []CheckStatus
+		for _, c := range checks {
+			got = append(got, c.Status)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: statuses = %v, want %v (%+v)", tc.name, got, tc.want, checks)
+		}
+	}
+
+	checks := CheckBudget(ImageInfo{Size: 300 << 20, Layers: 11, Base: "ubuntu:22.04"}, b)
+	if c := checks[0]; c.Actual != "300.0Mi" || c.Budget != "100Mi" {
+		t.Errorf("size check = %+v", c)
+	}
+	if c := checks[2]; c.Detail != "matches ubuntu" {
+		t.Errorf("base check = %+v", c)
+	}
+	for _, c := range CheckBudget(ImageInfo{Size: 1 << 30, Layers: 99, Base: "ubuntu"}, ImageBudget{}) {
+		if c.Status != CheckSkip {
+			t.Errorf("check %s without a budget: %s, want skip", c.Name, c.Status)
+		}
+	}
+}
+
+func TestBaseImage(t *testing.T) {
+	for _, tc := range []struct{ dockerfile, want string }{
+		{"FROM --platform=$BUILDPLATFORM golang:1.22-alpine AS builder\nRUN go build\nFROM alpine:3.19\nCOPY --from=builder /app /app\n", "alpine:3.19"},
+		{"FROM node:22-alpine AS base\nFROM base AS deps\nRUN npm ci\nFROM deps\nCMD [\"node\"]\n", "node:22-alpine"},
+		{"# no stages\n", ""},
+	} {
+		if got := BaseImage([]byte(tc.dockerfile)); got != tc.want {
+			t.Errorf("BaseImage(%q) = %q, want %q", tc.dockerfile, got, tc.want)
+		}
+	}
+}
+
+func TestParseScannerReports(t *testing.T) {
+	trivy := `{"Results":[{"Target":"alpine","Vulnerabilities":[{"VulnerabilityID":"CVE-1","PkgName":"openssl","Severity":"CRITICAL"}]},{"Target":"app"}]}`
+	grype := `{"matches":[{"vulnerability":{"id":"CVE-2","severity":"Medium"},"artifact":{"name":"busybox"}}]}`
+	got, err := parseTrivy([]byte(trivy))
+	if err != nil || !reflect.DeepEqual(got, []Vulnerability{{"CVE-1", "CRITICAL", "openssl"}}) {
+		t.Errorf("parseTrivy = %v, %v", got, err)
+	}
+	got, err = parseGrype([]byte(grype))
+	if err != nil || !reflect.DeepEqual(got, []Vulnerability{{"CVE-2", "MEDIUM", "busybox"}}) {
+		t.Errorf("parseGrype = %v, %v", got, err)
+	}
+	if _, err := parseTrivy([]byte("not json")); err == nil {
+		t.Error("parseTrivy accepted a malformed report")
+	}
+}
+
+func TestCheckVulnerabilities(t *testing.T) {
+	vulns := []Vulnerability{{"CVE-1", "CRITICAL", "openssl"}, {"CVE-2", "MEDIUM", "busybox"}, {"CVE-3", "NEGLIGIBLE", "zlib"}}
+	c := CheckVulnerabilities("trivy", vulns, ScanBudget{})
+	if c.Status != CheckFail || c.Actual != "1 HIGH or above (3 total)" || !strings.Contains(c.Detail, "CVE-1 (openssl, CRITICAL)") {
+		t.Errorf("default fail_on: %+v", c)
+	}
+	if c := CheckVulnerabilities("grype", vulns[1:], ScanBudget{FailOn: "high"}); c.Status != CheckPass {
+		t.Errorf("fail_on high with a medium finding: %+v", c)
+	}
+	if c := CheckVulnerabilities("grype", vulns, ScanBudget{FailOn: "MEDIUM"}); c.Status != CheckFail || !strings.HasPrefix(c.Actual, "2 MEDIUM") {
+		t.Errorf("fail_on medium: %+v", c)
+	}
+}
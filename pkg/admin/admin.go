@@ -0,0 +1,345 @@
+// Package admin serves runtime introspection of a running service on a
+// listener of its own, never the public one:
+//
+//	GET       /admin/config      effective configuration, secrets redacted
+//	GET       /admin/routes      gateway routes, breakers, and last reload
+//	POST      /admin/routes      ?reset_breaker=<upstream host>
+//	GET       /admin/breakers    gateway circuit breaker states
+//	GET       /admin/cache       gateway response cache size
+//	DELETE    /admin/cache       ?prefix=/catalog drops cached responses
+//	GET       /admin/runtime     goroutine, connection, and memory counts
+//	GET, PUT  /admin/loglevel    {"level": "debug|info|warn|error"}
+//	GET       /debug/pprof/...   net/http/pprof
+//
+// plus whatever routes the service adds WithHandler.
+//
+// Every request needs the configured static token as a Bearer token, and
+// a request carrying X-Forwarded-For or Forwarded, as everything the
+// gateway proxies does, is refused: the admin port is for kubectl
+// port-forward and the like, not for traffic relayed from the public
+// listener. Build the gateway WithReservedPorts(s.Port()) too, so no
+// route can point at it.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/buildinfo"
+	pkgconfig "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/gateway"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// DefaultAddr is where the admin listener binds when Config.Addr is
+// empty.
+const DefaultAddr = ":9901"
+
+// Config is the admin listener's settings, loadable through pkg/config.
+// The listener is off unless Enabled, which callers check before Listen.
+type Config struct {
+	Enabled bool             `config:"enabled"`
+	Addr    string           `config:"addr" default:":9901"`
+	Token   pkgconfig.Secret `config:"token"`
+}
+
+// Option configures Listen.
+type Option func(*Server)
+
+// WithConfig serves values on /admin/config. They must be redacted
+// already, as pkgconfig.Values and Environ return them.
+func WithConfig(values map[string]string) Option {
+	return func(s *Server) { s.config = values }
+}
+
+// WithLevel lets PUT /admin/loglevel change level, which the service's
+// logger must have been built with.
+func WithLevel(level *slog.LevelVar) Option {
+	return func(s *Server) { s.level = level }
+}
+
+// WithGateway serves rt's routes and breakers.
+func WithGateway(rt *gateway.Router) Option {
+	return func(s *Server) { s.gateway = rt }
+}
+
+// WithHandler serves h for pattern, a net/http.ServeMux pattern such as
+// "GET /admin/tenants", behind the same token as the built-in routes. It
+// is for a service's own operator endpoints.
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(s *Server) { s.extra = append(s.extra, route{pattern, h}) }
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// WithConns reports c's counts on /admin/runtime.
+func WithConns(c *Conns) Option {
+	return func(s *Server) { s.conns = c }
+}
+
+// WithLogger sets where level changes and listener failures are logged;
+// nil means slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// Server is the admin listener. It is a server.Worker: Run serves until
+// shutdown starts.
+type Server struct {
+	token   string
+	config  map[string]string
+	level   *slog.LevelVar
+	gateway *gateway.Router
+	conns   *Conns
+	logger  *slog.Logger
+	extra   []route
+	started time.Time
+
+	ln      net.Listener
+	handler http.Handler
+}
+
+// Listen opens the admin listener on cfg.Addr, so a taken port fails
+// startup, without serving it yet. cfg.Token is required.
+func Listen(cfg Config, opts ...Option) (*Server, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("admin: a token is required")
+	}
+	s := &Server{token: string(cfg.Token), started: time.Now()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	addr := cfg.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	var err error
+	if s.ln, err = net.Listen("tcp", addr); err != nil {
+		return nil, fmt.Errorf("admin: %w", err)
+	}
+	s.handler = s.routes()
+	return s, nil
+}
+
+// Addr is the address the listener is bound to.
+func (s *Server) Addr() net.Addr { return s.ln.Addr() }
+
+// Port is the listener's port, for gateway.WithReservedPorts.
+func (s *Server) Port() int { return s.ln.Addr().(*net.TCPAddr).Port }
+
+// Run implements server.Worker.
+func (s *Server) Run(ctx context.Context) {
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.log().Info("admin listening", "addr", s.Addr().String())
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(s.ln); !errors.Is(err, http.ErrServerClosed) {
+		s.log().Error("admin listener stopped", "error", err)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("Forwarded") != "" {
+		respond.Error(w, http.StatusForbidden, "FORBIDDEN", "admin endpoints are not served through a proxy")
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		respond.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing or invalid admin token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, _ *http.Request) {
+		config := s.config
+		if config == nil {
+			config = map[string]string{}
+		}
+		respond.JSON(w, http.StatusOK, config)
+	})
+	if s.gateway != nil {
+		mux.Handle("GET /admin/routes", s.gateway.AdminHandler())
+		mux.Handle("POST /admin/routes", s.gateway.AdminHandler())
+		mux.HandleFunc("GET /admin/breakers", func(w http.ResponseWriter, _ *http.Request) {
+			respond.JSON(w, http.StatusOK, s.gateway.Breakers())
+		})
+		mux.Handle("GET /admin/cache", s.gateway.CacheHandler())
+		mux.Handle("DELETE /admin/cache", s.gateway.CacheHandler())
+	}
+	mux.HandleFunc("GET /admin/runtime", s.runtime)
+	if s.level != nil {
+		mux.HandleFunc("GET /admin/loglevel", s.getLevel)
+		mux.HandleFunc("PUT /admin/loglevel", s.putLevel)
+	}
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	for _, r := range s.extra {
+		mux.Handle(r.pattern, r.handler)
+	}
+	return mux
+}
+
+func (s *Server) runtime(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	out := map[string]any{
+		"goroutines":       runtime.NumGoroutine(),
+		"gomaxprocs":       runtime.GOMAXPROCS(0),
+		"heap_alloc_bytes": mem.HeapAlloc,
+		"sys_bytes":        mem.Sys,
+		"gc_cycles":        mem.NumGC,
+		"uptime":           time.Since(s.started).Round(time.Second).String(),
+		"build":            buildinfo.Fields(),
+	}
+	if s.conns != nil {
+		out["connections"] = s.conns.Counts()
+	}
+	respond.JSON(w, http.StatusOK, out)
+}
+
+// levels are the names PUT /admin/loglevel accepts.
+var levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+func (s *Server) getLevel(w http.ResponseWriter, _ *http.Request) {
+	respond.JSON(w, http.StatusOK, levelBody{Level: strings.ToLower(s.level.Level().String())})
+}
+
+func (s *Server) putLevel(w http.ResponseWriter, r *http.Request) {
+	var body levelBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&body); err != nil {
+		respond.Error(w, http.StatusBadRequest, "BAD_REQUEST", "want a JSON body such as {\"level\": \"debug\"}")
+		return
+	}
+	level, ok := levels[strings.ToLower(strings.TrimSpace(body.Level))]
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "BAD_REQUEST", "level must be debug, info, warn, or error")
+		return
+	}
+	from := s.level.Level()
+	s.level.Set(level)
+	s.log().Warn("admin: log level changed", "from", from.String(), "to", level.String())
+	s.getLevel(w, r)
+}
+
+func (s *Server) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	return slog.Default()
+}
+
+// Environ returns the process environment for WithConfig, for services
+// configured by variables rather than a config struct. Variables whose
+// name marks them sensitive, as logging.Sensitive judges, are redacted,
+// and passwords in URL values are masked.
+func Environ() map[string]string {
+	out := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		switch {
+		case logging.Sensitive(k) && v != "":
+			v = pkgconfig.Redacted
+		case strings.Contains(v, "://"):
+			if u, err := url.Parse(v); err == nil {
+				v = u.Redacted()
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// Conns counts a server's connections by state. Set its ConnState as the
+// server's ConnState.
+type Conns struct {
+	mu       sync.Mutex
+	states   map[net.Conn]http.ConnState
+	accepted uint64
+	hijacked uint64
+}
+
+// ConnCounts is a snapshot of Conns.
+type ConnCounts struct {
+	Open     int    `json:"open"`
+	Active   int    `json:"active"`
+	Idle     int    `json:"idle"`
+	Hijacked uint64 `json:"hijacked"`
+	Accepted uint64 `json:"accepted"`
+}
+
+// ConnState records c entering state.
+func (c *Conns) ConnState(conn net.Conn, state http.ConnState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.states == nil {
+		c.states = map[net.Conn]http.ConnState{}
+	}
+	switch state {
+	case http.StateNew:
+		c.accepted++
+		c.states[conn] = state
+	case http.StateActive, http.StateIdle:
+		c.states[conn] = state
+	case http.StateHijacked:
+		c.hijacked++
+		delete(c.states, conn)
+	case http.StateClosed:
+		delete(c.states, conn)
+	}
+}
+
+// Counts returns the current counts. Hijacked connections, WebSockets
+// among them, leave the server's tracking: they are counted as they are
+// taken over but not as open.
+func (c *Conns) Counts() ConnCounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := ConnCounts{Open: len(c.states), Hijacked: c.hijacked, Accepted: c.accepted}
+	for _, s := range c.states {
+		switch s {
+		case http.StateActive:
+			n.Active++
+		case http.StateIdle:
+			n.Idle++
+		}
+	}
+	return n
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/gateway"
+)
+
+const token = "admin-token-for-tests"
+
+func quiet() Option { return WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))) }
+
+func listen(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	s, err := Listen(Config{Addr: "127.0.0.1:0", Token: token}, append([]Option{quiet()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.ln.Close() })
+	return s
+}
+
+func do(t *testing.T, s *Server, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth(t *testing.T) {
+	s := listen(t)
+	for _, tc := range []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong token", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized},
+		{"not bearer", map[string]string{"Authorization": token}, http.StatusUnauthorized},
+		{"token", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+		{"through a proxy", map[string]string{"Authorization": "Bearer " + token, "X-Forwarded-For": "10.0.0.1"}, http.StatusForbidden},
+		{"through a proxy, RFC 7239", map[string]string{"Authorization": "Bearer " + token, "Forwarded": "for=10.0.0.1"}, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest("GET", "/admin/runtime", nil)
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+	if _, err := Listen(Config{Addr: "127.0.0.1:0"}); err == nil {
+		t.Error("Listen without a token succeeded")
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	var level slog.LevelVar
+	var logs bytes.Buffer
+	s := listen(t, WithLevel(&level), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	if rec := do(t, s, "PUT", "/admin/loglevel", `{"level": "debug"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"debug"`) {
+		t.Fatalf("PUT debug: %d %s", rec.Code, rec.Body)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level = %v, want debug", level.Level())
+	}
+	if !strings.Contains(logs.String(), "log level changed") {
+		t.Errorf("change not logged: %s", logs.String())
+	}
+	if rec := do(t, s, "GET", "/admin/loglevel", ""); !strings.Contains(rec.Body.String(), `"level":"debug"`) {
+		t.Errorf("GET = %s", rec.Body)
+	}
+	for _, body := range []string{`{"level": "verbose"}`, `{"level": "warn+2"}`, `debug`} {
+		if rec := do(t, s, "PUT", "/admin/loglevel", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status %d, want 400", body, rec.Code)
+		}
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("rejected PUT changed the level to %v", level.Level())
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	t.Setenv("ADMIN_TEST_PASSWORD", "hunter2")
+	t.Setenv("ADMIN_TEST_URL", "postgres://app:s3cret@db/admissions")
+	rt, err := gateway.New([]gateway.Route{{PathPrefix: "/v1", Upstream: "http://api:8080"}}, gateway.WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns Conns
+	s := listen(t, WithConfig(Environ()), WithGateway(rt), WithConns(&conns))
+
+	rec := do(t, s, "GET", "/admin/config", "")
+	if body := rec.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "s3cret") || !strings.Contains(body, "ADMIN_TEST_PASSWORD") {
+		t.Errorf("config not redacted: %s", body)
+	}
+	if rec := do(t, s, "GET", "/admin/routes", ""); !strings.Contains(rec.Body.String(), `"path_prefix":"/v1"`) {
+		t.Errorf("routes = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, s, "GET", "/admin/breakers", ""); !strings.Contains(rec.Body.String(), `"state":"closed"`) {
+		t.Errorf("breakers = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, s, "DELETE", "/admin/cache?prefix=/v1", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"removed":0`) {
+		t.Errorf("cache invalidation = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, s, "GET", "/debug/pprof/", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("pprof index = %d", rec.Code)
+	}
+
+	// A server whose connections are counted.
+	public := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := do(t, s, "GET", "/admin/runtime", "")
+		w.Write(rec.Body.Bytes())
+	}))
+	public.Config.ConnState = conns.ConnState
+	public.Start()
+	defer public.Close()
+	resp, err := http.Get(public.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var runtime struct {
+		Goroutines  int        `json:"goroutines"`
+		Connections ConnCounts `json:"connections"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&runtime); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.Goroutines == 0 || runtime.Connections.Active != 1 || runtime.Connections.Accepted != 1 {
+		t.Errorf("runtime = %+v, want the request's own connection active", runtime)
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	req, _ := http.NewRequest("GET", "http://"+s.Addr().String()+"/admin/runtime", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d", resp.StatusCode)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
+
+func TestWithHandler(t *testing.T) {
+	s := listen(t, WithHandler("GET /admin/tenants", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "tenants")
+	})))
+	if rec := do(t, s, "GET", "/admin/tenants", ""); rec.Code != http.StatusOK || rec.Body.String() != "tenants" {
+		t.Errorf("added route: %d %s", rec.Code, rec.Body)
+	}
+	req := httptest.NewRequest("GET", "/admin/tenants", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("added route without the token: %d", rec.Code)
+	}
+}
@@ -0,0 +1,306 @@
+// Package auth is Bearer JWT authentication middleware for the entrance
+// gateway and services, so upstreams need not each validate tokens.
+// Tokens are verified against an issuer's JWKS (see KeySet) or a
+// configured signing key (see StaticKey): signature, iss, aud, exp, and
+// nbf, with clock skew allowed on the time claims. The
+// accepted claims go into the request context and, for upstreams, into
+// the X-User-ID and X-User-Scopes headers, which are always stripped from
+// the client's request first so they cannot be forged.
+//
+//	keys := auth.NewKeySet("https://id.example.edu/.well-known/jwks.json", nil)
+//	go keys.Run(ctx)
+//	mw, err := auth.New(auth.Options{Keys: keys, Issuer: iss, Audience: "entrance"},
+//		auth.PublicPaths("/healthz", "/readyz"))
+package auth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+// Headers set for upstreams from an accepted token.
+const (
+	HeaderUserID     = "X-User-ID"
+	HeaderUserScopes = "X-User-Scopes" // space-separated
+)
+
+// DefaultClockSkew is how far exp and nbf may be off when Options leaves
+// ClockSkew unset.
+const DefaultClockSkew = 30 * time.Second
+
+// DefaultAlgorithms are the asymmetric signing algorithms accepted when
+// Options leaves Algorithms unset. Symmetric ones are never a default: a
+// JWKS publishes public keys.
+var DefaultAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Mode is how a route treats credentials.
+type Mode string
+
+const (
+	// Required rejects requests without a valid token with 401.
+	Required Mode = "required"
+	// Optional lets requests without a token through anonymously but
+	// still rejects an invalid one.
+	Optional Mode = "optional"
+	// None ignores the Authorization header, for health and public
+	// endpoints.
+	None Mode = "none"
+)
+
+// Validate reports a Mode other than the three above or empty.
+func (m Mode) Validate() error {
+	switch m {
+	case "", Required, Optional, None:
+		return nil
+	}
+	return fmt.Errorf("auth %q: want required, optional, or none", string(m))
+}
+
+// KeySource resolves a token's kid header to its verification key.
+// *KeySet is the JWKS implementation.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Options configures New.
+type Options struct {
+	Keys KeySource
+	// Issuer and Audience must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// ClockSkew is allowed on exp and nbf; zero means DefaultClockSkew.
+	ClockSkew time.Duration
+	// Algorithms are the accepted alg headers; nil means
+	// DefaultAlgorithms.
+	Algorithms []string
+
+	now func() time.Time
+}
+
+// Claims is an accepted token's payload.
+type Claims struct {
+	Subject string
+	// Scopes come from the space-separated scope claim, or from scp as a
+	// list or string.
+	Scopes []string
+	// Raw holds every claim in the token.
+	Raw jwt.MapClaims
+}
+
+type claimsKey struct{}
+
+// FromContext returns the claims New stored for an authenticated
+// request.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
+
+// ClaimsFromContext is FromContext.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) { return FromContext(ctx) }
+
+// StaticKey is a KeySource returning key for every kid, for services
+// given one signing key instead of a JWKS: an *rsa.PublicKey,
+// *ecdsa.PublicKey, or ed25519.PublicKey, or a []byte secret with
+// Options.Algorithms set to HS256, HS384, or HS512.
+func StaticKey(key crypto.PublicKey) KeySource { return staticKey{key} }
+
+type staticKey struct{ key crypto.PublicKey }
+
+func (k staticKey) Key(context.Context, string) (crypto.PublicKey, error) { return k.key, nil }
+
+// PublicPaths returns a modeFor for New requiring a token everywhere but
+// the given paths, which skip authentication. A path ending in "/"
+// matches everything below it.
+func PublicPaths(paths ...string) func(*http.Request) Mode {
+	return func(r *http.Request) Mode {
+		for _, p := range paths {
+			if r.URL.Path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p) {
+				return None
+			}
+		}
+		return Required
+	}
+}
+
+// New returns middleware authenticating each request per the Mode modeFor
+// returns; an empty Mode, or a nil modeFor, means Required. It fails if Keys, Issuer, or
+// Audience is missing, since a token checked without them proves
+// nothing.
+//
+// Missing, malformed, and expired tokens get 401 with a WWW-Authenticate
+// challenge; an authentic token issued for another audience gets 403,
+// since signing in again will not help. When the
+// key set cannot be fetched at all, requests needing it get 503, so an
+// issuer outage is not reported as the client's fault.
+func New(opts Options, modeFor func(*http.Request) Mode) (func(http.Handler) http.Handler, error) {
+	var errs []error
+	if opts.Keys == nil {
+		errs = append(errs, errors.New("auth: no key source"))
+	}
+	if opts.Issuer == "" {
+		errs = append(errs, errors.New("auth: no issuer"))
+	}
+	if opts.Audience == "" {
+		errs = append(errs, errors.New("auth: no audience"))
+	}
+	if opts.ClockSkew < 0 {
+		errs = append(errs, errors.New("auth: clock skew must not be negative"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if modeFor == nil {
+		modeFor = func(*http.Request) Mode { return Required }
+	}
+	v := &verifier{keys: opts.Keys, parser: jwt.NewParser(
+		jwt.WithValidMethods(orAlgorithms(opts.Algorithms)),
+		jwt.WithIssuer(opts.Issuer),
+		jwt.WithAudience(opts.Audience),
+		jwt.WithLeeway(orDefault(opts.ClockSkew, DefaultClockSkew)),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(orNow(opts.now)),
+	)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del(HeaderUserID)
+			r.Header.Del(HeaderUserScopes)
+			mode := modeFor(r)
+			if mode == None {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, ok := bearerToken(r)
+			if !ok {
+				if mode == Optional && r.Header.Get("Authorization") == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+				unauthorized(w, "", "missing bearer token")
+				return
+			}
+			claims, err := v.verify(r.Context(), token)
+			var unavailable *keysUnavailableError
+			switch {
+			case errors.As(err, &unavailable):
+				logging.FromContext(r.Context()).Warn("token keys unavailable", "error", err)
+				writeError(w, http.StatusServiceUnavailable, "AUTH_UNAVAILABLE", "authentication is temporarily unavailable")
+				return
+			case errors.Is(err, jwt.ErrTokenInvalidAudience):
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(w, http.StatusForbidden, "FORBIDDEN", "token is not valid for this audience")
+				return
+			case err != nil:
+				unauthorized(w, "invalid_token", "invalid or expired token")
+				return
+			}
+			r.Header.Set(HeaderUserID, claims.Subject)
+			if len(claims.Scopes) > 0 {
+				r.Header.Set(HeaderUserScopes, strings.Join(claims.Scopes, " "))
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}, nil
+}
+
+type verifier struct {
+	keys   KeySource
+	parser *jwt.Parser
+}
+
+// keysUnavailableError is a key lookup failure other than an unknown
+// key ID: the issuer's key set could not be fetched.
+type keysUnavailableError struct{ err error }
+
+func (e *keysUnavailableError) Error() string { return e.err.Error() }
+func (e *keysUnavailableError) Unwrap() error { return e.err }
+
+func (v *verifier) verify(ctx context.Context, raw string) (*Claims, error) {
+	var mc jwt.MapClaims
+	_, err := v.parser.ParseWithClaims(raw, &mc, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.keys.Key(ctx, kid)
+		if err != nil && !errors.Is(err, ErrUnknownKey) {
+			return nil, &keysUnavailableError{err}
+		}
+		return key, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sub, err := mc.GetSubject()
+	if err != nil || sub == "" {
+		return nil, errors.New("auth: token has no subject")
+	}
+	return &Claims{Subject: sub, Scopes: scopes(mc), Raw: mc}, nil
+}
+
+// scopes reads the OAuth scope claim, or the scp variant some issuers
+// use.
+func scopes(mc jwt.MapClaims) []string {
+	switch s := mc["scope"].(type) {
+	case string:
+		return strings.Fields(s)
+	}
+	switch s := mc["scp"].(type) {
+	case string:
+		return strings.Fields(s)
+	case []any:
+		var out []string
+		for _, item := range s {
+			if str, ok := item.(string); ok && str != "" && !strings.ContainsAny(str, " \t") {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, challenge, msg string) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+challenge+`"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", msg)
+}
+
+// writeError answers in the services' JSON error shape.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "{\"error\":%q,\"code\":%q}\n", msg, code)
+}
+
+func orAlgorithms(algs []string) []string {
+	if len(algs) > 0 {
+		return algs
+	}
+	return DefaultAlgorithms
+}
+
+func orNow(now func() time.Time) func() time.Time {
+	if now != nil {
+		return now
+	}
+	return time.Now
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testNow = time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+// staticKeys is a KeySource over a fixed map, failing with err if set.
+type staticKeys struct {
+	keys map[string]crypto.PublicKey
+	err  error
+}
+
+func (s staticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   "https://id.example.edu",
+		"aud":   "entrance",
+		"sub":   "applicant-42",
+		"scope": "applications:read applications:write",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"nbf":   testNow.Add(-time.Minute).Unix(),
+	}
+}
+
+// authServer runs the middleware in front of a handler echoing the
+// upstream headers, in mode.
+func authServer(t *testing.T, keys KeySource, mode Mode) http.Handler {
+	t.Helper()
+	mw, err := New(Options{Keys: keys, Issuer: "https://id.example.edu", Audience: "entrance", now: func() time.Time { return testNow }},
+		func(*http.Request) Mode { return mode })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Seen-User", r.Header.Get(HeaderUserID))
+		w.Header().Set("Seen-Scopes", r.Header.Get(HeaderUserScopes))
+		if c, ok := ClaimsFromContext(r.Context()); ok {
+			w.Header().Set("Seen-Claims", c.Subject)
+		}
+	}))
+}
+
+func do(h http.Handler, token string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/applications", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAcceptsValidToken(t *testing.T) {
+	key := newECKey(t)
+	h := authServer(t, staticKeys{keys: map[string]crypto.PublicKey{"k1": &key.PublicKey}}, Required)
+	rec := do(h, sign(t, key, "k1", validClaims()), HeaderUserID, "admin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Seen-User"); got != "applicant-42" {
+		t.Errorf("upstream %s = %q", HeaderUserID, got)
+	}
+	if got := rec.Header().Get("Seen-Scopes"); got != "applications:read applications:write" {
+		t.Errorf("upstream %s = %q", HeaderUserScopes, got)
+	}
+	if got := rec.Header().Get("Seen-Claims"); got != "applicant-42" {
+		t.Errorf("context claims subject = %q", got)
+	}
+}
+
+func TestRejectsInvalidTokens(t *testing.T) {
+	key, other := newECKey(t), newECKey(t)
+	keys := staticKeys{keys: map[string]crypto.PublicKey{"k1": &key.PublicKey}}
+	h := authServer(t, keys, Required)
+	with := func(k string, v any) jwt.MapClaims {
+		c := validClaims()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	hs.Header["kid"] = "k1"
+	symmetric, _ := hs.SignedString([]byte("secret"))
+
+	for name, token := range map[string]string{
+		"garbage":       "not.a.jwt",
+		"wrong key":     sign(t, other, "k1", validClaims()),
+		"unknown kid":   sign(t, other, "k9", validClaims()),
+		"wrong issuer":  sign(t, key, "k1", with("iss", "https://evil.example")),
+		"expired":       sign(t, key, "k1", with("exp", testNow.Add(-DefaultClockSkew-time.Second).Unix())),
+		"no exp":        sign(t, key, "k1", with("exp", nil)),
+		"not yet valid": sign(t, key, "k1", with("nbf", testNow.Add(DefaultClockSkew+time.Second).Unix())),
+		"no subject":    sign(t, key, "k1", with("sub", nil)),
+		"symmetric":     symmetric,
+	} {
+		rec := do(h, token)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", name, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "Bearer") {
+			t.Errorf("%s: WWW-Authenticate %q", name, got)
+		}
+	}
+
+	// Within the skew, exp and nbf still pass.
+	for name, c := range map[string]jwt.MapClaims{
+		"just expired":  with("exp", testNow.Add(-DefaultClockSkew/2).Unix()),
+		"almost valid":  with("nbf", testNow.Add(DefaultClockSkew/2).Unix()),
+		"no scope":      with("scope", nil),
+		"audience list": with("aud", []string{"other", "entrance"}),
+	} {
+		if rec := do(h, sign(t, key, "k1", c)); rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200", name, rec.Code)
+		}
+	}
+}
+
+func TestWrongAudienceIsForbidden(t *testing.T) {
+	key, other := newECKey(t), newECKey(t)
+	h := authServer(t, staticKeys{keys: map[string]crypto.PublicKey{"k1": &key.PublicKey}}, Required)
+	c := validClaims()
+	c["aud"] = "billing"
+	rec := do(h, sign(t, key, "k1", c))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "FORBIDDEN") {
+		t.Errorf("status %d %s, want 403 FORBIDDEN", rec.Code, rec.Body)
+	}
+	// A forged token for another audience is still just invalid.
+	if rec := do(h, sign(t, other, "k1", c)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong key and audience: status %d, want 401", rec.Code)
+	}
+}
+
+func TestStaticKey(t *testing.T) {
+	secret := []byte("entrance-signing-secret")
+	mw, err := New(Options{Keys: StaticKey(secret), Issuer: "https://id.example.edu", Audience: "entrance",
+		Algorithms: []string{"HS256"}, now: func() time.Time { return testNow }}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := FromContext(r.Context()); ok {
+			w.Header().Set("Seen-Claims", c.Subject)
+		}
+	}))
+	hs := func(secret []byte) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if rec := do(h, hs(secret)); rec.Code != http.StatusOK || rec.Header().Get("Seen-Claims") != "applicant-42" {
+		t.Errorf("status %d, claims %q", rec.Code, rec.Header().Get("Seen-Claims"))
+	}
+	if rec := do(h, hs([]byte("other"))); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status %d, want 401", rec.Code)
+	}
+}
+
+func TestPublicPaths(t *testing.T) {
+	modeFor := PublicPaths("/healthz", "/docs/")
+	for path, want := range map[string]Mode{
+		"/healthz":           None,
+		"/healthz/deep":      Required,
+		"/docs/":             None,
+		"/docs/openapi.json": None,
+		"/docs":              Required,
+		"/api/applications":  Required,
+	} {
+		if got := modeFor(httptest.NewRequest(http.MethodGet, path, nil)); got != want {
+			t.Errorf("%s: mode %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	key := newECKey(t)
+	keys := staticKeys{keys: map[string]crypto.PublicKey{"k1": &key.PublicKey}}
+	for _, tc := range []struct {
+		mode   Mode
+		token  string
+		status int
+	}{
+		{Required, "", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+		{Optional, "", http.StatusOK},
+		{Optional, "garbage", http.StatusUnauthorized},
+		{None, "", http.StatusOK},
+		{None, "garbage", http.StatusOK},
+	} {
+		rec := do(authServer(t, keys, tc.mode), tc.token, HeaderUserID, "admin", HeaderUserScopes, "root")
+		if rec.Code != tc.status {
+			t.Errorf("mode %q token %q: status %d, want %d", tc.mode, tc.token, rec.Code, tc.status)
+		}
+		if rec.Header().Get("Seen-User") != "" || rec.Header().Get("Seen-Scopes") != "" {
+			t.Errorf("mode %q: forged user headers reached the upstream", tc.mode)
+		}
+	}
+}
+
+func TestKeysUnavailable(t *testing.T) {
+	key := newECKey(t)
+	h := authServer(t, staticKeys{err: errors.New("jwks: connection refused")}, Required)
+	rec := do(h, sign(t, key, "k1", validClaims()))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "AUTH_UNAVAILABLE") {
+		t.Errorf("status %d %s, want 503 AUTH_UNAVAILABLE", rec.Code, rec.Body)
+	}
+}
+
+func TestRotationThroughKeySet(t *testing.T) {
+	k1, k2 := newECKey(t), newECKey(t)
+	srv := newJWKSServer(t, ecJWK("k1", &k1.PublicKey))
+	now := testNow
+	ks := testKeySet(srv, &now)
+	h := authServer(t, ks, Required)
+	if rec := do(h, sign(t, k1, "k1", validClaims())); rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	srv.publish(ecJWK("k2", &k2.PublicKey))
+	now = now.Add(DefaultRefetchInterval)
+	if rec := do(h, sign(t, k2, "k2", validClaims())); rec.Code != http.StatusOK {
+		t.Errorf("token signed with the rotated key: status %d", rec.Code)
+	}
+}
+
+func TestNewRequiresIssuerAndAudience(t *testing.T) {
+	_, err := New(Options{}, nil)
+	if err == nil {
+		t.Fatal("no error")
+	}
+	for _, want := range []string{"key source", "issuer", "audience"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
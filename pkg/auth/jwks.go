@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache lifetimes of a fetched key set.
+const (
+	// DefaultTTL applies when the JWKS response sets no max-age.
+	DefaultTTL = 15 * time.Minute
+	// DefaultMinTTL floors max-age, including no-cache and no-store, so a
+	// misconfigured issuer cannot make every request a fetch.
+	DefaultMinTTL = time.Minute
+	// DefaultMaxTTL caps max-age, so a retired key does not outlive its
+	// removal from the set by more than a day.
+	DefaultMaxTTL = 24 * time.Hour
+	// DefaultRefetchInterval is the least time between two fetches, the
+	// bound on how often tokens with unknown key IDs can trigger one.
+	DefaultRefetchInterval = 10 * time.Second
+)
+
+// fetchTimeout bounds one JWKS request. A fetch is shared by every
+// request waiting on it, so it does not end with any one of them.
+const fetchTimeout = 10 * time.Second
+
+// maxJWKSSize bounds the JWKS response body.
+const maxJWKSSize = 1 << 20
+
+// ErrUnknownKey is returned by KeySet.Key for a key ID the issuer's set
+// does not contain, even after refetching it.
+var ErrUnknownKey = errors.New("auth: unknown signing key")
+
+// KeySet is the public signing keys published at a JWKS URL, cached for
+// the response's Cache-Control max-age. A token signed with a key ID the
+// cache lacks triggers one refetch, shared by every request waiting on
+// it, so a key rotation does not fail requests; if the key is still
+// missing the token is rejected. Run keeps the cache fresh in the
+// background so requests rarely wait on a fetch at all. When a refetch
+// fails, the cached keys keep serving.
+type KeySet struct {
+	url    string
+	client *http.Client
+
+	// MinTTL, MaxTTL, and RefetchInterval override the package defaults
+	// when positive.
+	MinTTL          time.Duration
+	MaxTTL          time.Duration
+	RefetchInterval time.Duration
+	// Logger receives fetch failures; nil means slog.Default().
+	Logger *slog.Logger
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	expires   time.Time // end of the cache lifetime
+	attempted time.Time // last fetch, successful or not
+	lastErr   error     // its failure
+	inflight  *fetch
+}
+
+// fetch is one JWKS request in progress.
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewKeySet returns a KeySet for the JWKS at url, fetched with client,
+// or http.DefaultClient if client is nil. Nothing is fetched until the
+// first Key or Run.
+func NewKeySet(url string, client *http.Client) *KeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &KeySet{url: url, client: client, now: time.Now}
+}
+
+// Key returns the public key with ID kid, fetching the set first if the
+// cache has expired or lacks kid.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	key, ok := ks.keys[kid]
+	fresh := ks.now().Before(ks.expires)
+	ks.mu.Unlock()
+	if ok && fresh {
+		return key, nil
+	}
+	err := ks.refresh(ctx)
+	ks.mu.Lock()
+	key, ok = ks.keys[kid]
+	ks.mu.Unlock()
+	switch {
+	case ok:
+		return key, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// Run refreshes the set shortly before each cache lifetime ends, and
+// retries every RefetchInterval after a failure, until ctx is done. It
+// implements pkg/server.Worker.
+func (ks *KeySet) Run(ctx context.Context) {
+	for {
+		ks.refresh(ctx)
+		ks.mu.Lock()
+		wait := ks.expires.Sub(ks.now())
+		ks.mu.Unlock()
+		// Refresh at nine tenths of the lifetime, but no sooner than a
+		// refetch is allowed.
+		wait = max(wait-wait/10, ks.refetchInterval())
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// refresh fetches the set, or joins a fetch already in progress. When the
+// last fetch was less than RefetchInterval ago it only returns that
+// fetch's error.
+func (ks *KeySet) refresh(ctx context.Context) error {
+	ks.mu.Lock()
+	if f := ks.inflight; f != nil {
+		ks.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	now := ks.now()
+	if !ks.attempted.IsZero() && now.Sub(ks.attempted) < ks.refetchInterval() {
+		err := ks.lastErr
+		ks.mu.Unlock()
+		return err
+	}
+	f := &fetch{done: make(chan struct{})}
+	ks.inflight, ks.attempted = f, now
+	ks.mu.Unlock()
+
+	fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	keys, ttl, err := ks.fetch(fetchCtx)
+	cancel()
+	if err != nil {
+		err = fmt.Errorf("auth: fetch %s: %w", ks.url, err)
+		ks.logger().Warn("JWKS fetch failed; keeping cached keys", "error", err)
+	}
+	ks.mu.Lock()
+	if err == nil {
+		ks.keys, ks.expires = keys, ks.now().Add(ttl)
+	}
+	ks.inflight, ks.lastErr = nil, err
+	ks.mu.Unlock()
+	f.err = err
+	close(f.done)
+	return err
+}
+
+func (ks *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("status %s", resp.Status)
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&doc); err != nil {
+		return nil, 0, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	var errs []error
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key %d (%s): %w", i, k.Kid, err))
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, 0, errors.Join(append([]error{errors.New("no usable signing keys")}, errs...)...)
+	}
+	if len(errs) > 0 {
+		ks.logger().Warn("JWKS keys skipped", "url", ks.url, "error", errors.Join(errs...))
+	}
+	return keys, ks.ttl(resp.Header.Get("Cache-Control")), nil
+}
+
+// ttl is the cache lifetime a Cache-Control header asks for, within
+// [MinTTL, MaxTTL].
+func (ks *KeySet) ttl(cacheControl string) time.Duration {
+	floor, ceiling := orDefault(ks.MinTTL, DefaultMinTTL), orDefault(ks.MaxTTL, DefaultMaxTTL)
+	ttl := DefaultTTL
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return floor
+		case "max-age":
+			if secs, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && secs >= 0 {
+				ttl = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return min(max(ttl, floor), ceiling)
+}
+
+func (ks *KeySet) refetchInterval() time.Duration {
+	return orDefault(ks.RefetchInterval, DefaultRefetchInterval)
+}
+
+func (ks *KeySet) logger() *slog.Logger {
+	if ks.Logger != nil {
+		return ks.Logger
+	}
+	return slog.Default()
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// jwk is one JSON Web Key (RFC 7517) of type RSA, EC, or OKP (Ed25519).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("e out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("x: want a 32-byte Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
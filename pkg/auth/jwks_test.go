@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes the keys in set, counting fetches.
+type jwksServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	set          []map[string]string
+	cacheControl string
+	fail         bool
+	fetches      atomic.Int32
+	delay        time.Duration
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	t.Helper()
+	s := &jwksServer{set: keys, cacheControl: "max-age=300"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.fetches.Add(1)
+		time.Sleep(s.delay)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", s.cacheControl)
+		json.NewEncoder(w).Encode(map[string]any{"keys": s.set})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set = keys
+}
+
+func (s *jwksServer) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func ecJWK(kid string, pub *ecdsa.PublicKey) map[string]string {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return map[string]string{"kty": "EC", "kid": kid, "crv": pub.Curve.Params().Name, "use": "sig",
+		"x": b64(pub.X.FillBytes(make([]byte, size))), "y": b64(pub.Y.FillBytes(make([]byte, size)))}
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// testKeySet returns a KeySet for srv on a clock the test moves.
+func testKeySet(srv *jwksServer, now *time.Time) *KeySet {
+	ks := NewKeySet(srv.URL, srv.Client())
+	ks.now = func() time.Time { return *now }
+	return ks
+}
+
+func TestKeySetCachesForMaxAge(t *testing.T) {
+	k1 := newECKey(t)
+	srv := newJWKSServer(t, ecJWK("k1", &k1.PublicKey))
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	ks := testKeySet(srv, &now)
+	ctx := context.Background()
+
+	for range 3 {
+		key, err := ks.Key(ctx, "k1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k1.PublicKey.Equal(key) {
+			t.Fatal("wrong key")
+		}
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches within max-age, want 1", n)
+	}
+	now = now.Add(301 * time.Second)
+	ks.Key(ctx, "k1")
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("%d fetches after max-age, want 2", n)
+	}
+
+	// A failed refetch keeps serving the cached key.
+	srv.setFail(true)
+	now = now.Add(301 * time.Second)
+	if _, err := ks.Key(ctx, "k1"); err != nil {
+		t.Errorf("stale key not served while the JWKS is down: %v", err)
+	}
+}
+
+func TestKeySetRotationRefetchesOnce(t *testing.T) {
+	k1, k2 := newECKey(t), newECKey(t)
+	srv := newJWKSServer(t, ecJWK("k1", &k1.PublicKey))
+	srv.delay = 20 * time.Millisecond
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	ks := testKeySet(srv, &now)
+	ctx := context.Background()
+	if _, err := ks.Key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuer rotates to k2 and tokens signed with it arrive at once.
+	srv.publish(ecJWK("k1", &k1.PublicKey), ecJWK("k2", &k2.PublicKey))
+	now = now.Add(DefaultRefetchInterval)
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ks.Key(ctx, "k2"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("request during rotation failed: %v", err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("%d fetches, want one for the new key", n)
+	}
+
+	// A kid the issuer does not publish is refetched once, then rejected
+	// without another fetch until RefetchInterval passes.
+	now = now.Add(DefaultRefetchInterval)
+	for range 3 {
+		if _, err := ks.Key(ctx, "forged"); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("unknown kid: %v", err)
+		}
+	}
+	if n := srv.fetches.Load(); n != 3 {
+		t.Errorf("%d fetches after unknown kids, want 3", n)
+	}
+}
+
+func TestKeySetUnavailable(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.setFail(true)
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	ks := testKeySet(srv, &now)
+	for range 2 {
+		if _, err := ks.Key(context.Background(), "k1"); err == nil || errors.Is(err, ErrUnknownKey) {
+			t.Errorf("JWKS down: %v, want a fetch error", err)
+		}
+	}
+}
+
+func TestKeySetTTL(t *testing.T) {
+	ks := NewKeySet("", nil)
+	for header, want := range map[string]time.Duration{
+		"":                          DefaultTTL,
+		"public, max-age=3600":      time.Hour,
+		"max-age=1":                 DefaultMinTTL,
+		"max-age=31536000":          DefaultMaxTTL,
+		"no-cache":                  DefaultMinTTL,
+		"max-age=600, no-store":     DefaultMinTTL,
+		`max-age="120", must-reval`: 2 * time.Minute,
+		"max-age=soon":              DefaultTTL,
+	} {
+		if got := ks.ttl(header); got != want {
+			t.Errorf("ttl(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := newECKey(t)
+	for name, k := range map[string]jwk{
+		"rsa": {Kty: "RSA", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		"ec":  {Kty: "EC", Crv: "P-256", X: ecJWK("", &ec.PublicKey)["x"], Y: ecJWK("", &ec.PublicKey)["y"]},
+		"ed":  {Kty: "OKP", Crv: "Ed25519", X: b64(edPub)},
+	} {
+		pub, err := k.publicKey()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		want := map[string]interface {
+			Equal(crypto.PublicKey) bool
+		}{"rsa": &rsaKey.PublicKey, "ec": &ec.PublicKey, "ed": edPub}[name]
+		if !want.Equal(pub) {
+			t.Errorf("%s: decoded a different key", name)
+		}
+	}
+	for name, k := range map[string]jwk{
+		"off curve": {Kty: "EC", Crv: "P-256", X: b64([]byte{1}), Y: b64([]byte{2})},
+		"curve":     {Kty: "EC", Crv: "secp256k1", X: b64([]byte{1}), Y: b64([]byte{2})},
+		"oct":       {Kty: "oct"},
+		"short ed":  {Kty: "OKP", Crv: "Ed25519", X: b64([]byte{1, 2})},
+		"rsa e":     {Kty: "RSA", N: b64(rsaKey.N.Bytes()), E: b64([]byte{1})},
+	} {
+		if _, err := k.publicKey(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package buildinfo holds the version metadata stamped into a binary at
+// build time, for --version flags and health payloads.
+package buildinfo
+
+import "fmt"
+
+// Unknown is reported for metadata that was not stamped, e.g. in builds
+// from a source tarball or plain `go build`.
+const Unknown = "unknown"
+
+// Build metadata. Commands set these from their own -ldflags targets with
+// Set.
+var (
+	Version   = Unknown
+	Commit    = Unknown
+	BuildTime = Unknown
+)
+
+// Set records the stamped values, keeping Unknown for empty ones.
+func Set(version, commit, buildTime string) {
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{{&Version, version}, {&Commit, commit}, {&BuildTime, buildTime}} {
+		*v.dst = Unknown
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+}
+
+// String formats the metadata for --version output.
+func String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", Version, Commit, BuildTime)
+}
+
+// Fields returns the metadata for JSON payloads.
+func Fields() map[string]string {
+	return map[string]string{"version": Version, "commit": Commit, "build_time": BuildTime}
+}
@@ -0,0 +1,14 @@
+package buildinfo
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	defer Set("", "", "")
+	Set("v1.2.0", "0a1b2c3", "")
+	if got, want := String(), "v1.2.0 (commit 0a1b2c3, built unknown)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if f := Fields(); f["version"] != "v1.2.0" || f["build_time"] != Unknown {
+		t.Errorf("Fields() = %v", f)
+	}
+}
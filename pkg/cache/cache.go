@@ -0,0 +1,236 @@
+// Package cache is a read-through cache shared by the entrance app's
+// services, backed by Redis:
+//
+//	c, err := cache.New(os.Getenv("REDIS_URL"))
+//	...
+//	b, err := c.GetOrLoad(ctx, "program:"+code, 5*time.Minute, func(ctx context.Context) ([]byte, error) {
+//		return loadProgram(ctx, code)
+//	})
+//
+// Concurrent GetOrLoad misses on one key in a process share a single
+// loader call, so an expired hot key does not stampede the database.
+//
+// The cache never fails a request. While Redis is unreachable, every call
+// behaves as a miss or a no-op, GetOrLoad goes straight to its loader, and
+// a warning is logged once; Redis is tried again every RetryInterval and
+// its return is logged too.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultRetryInterval is how long a cache that lost Redis skips it
+// before trying again.
+const DefaultRetryInterval = 5 * time.Second
+
+// Cache stores byte values under string keys for a time.
+type Cache interface {
+	// Get returns the value under key, and whether there was one.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores value under key for ttl; zero means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	// Delete removes key.
+	Delete(ctx context.Context, key string)
+	// GetOrLoad returns the value under key, calling load and storing
+	// its result for ttl on a miss. An error from load is returned as is
+	// and nothing is stored.
+	GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(context.Context) ([]byte, error)) ([]byte, error)
+}
+
+// Client is the part of a go-redis client a RedisCache uses;
+// *redis.Client and *redis.ClusterClient implement it.
+type Client interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Option configures New and NewRedis.
+type Option func(*RedisCache)
+
+// WithLogger sets where availability warnings go; nil means
+// slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(c *RedisCache) { c.logger = l }
+}
+
+// WithPrefix namespaces every key; the default is "cache:".
+func WithPrefix(p string) Option {
+	return func(c *RedisCache) { c.prefix = p }
+}
+
+// WithRetryInterval sets how long Redis is skipped after a failure; zero
+// means DefaultRetryInterval.
+func WithRetryInterval(d time.Duration) Option {
+	return func(c *RedisCache) { c.retry = d }
+}
+
+// New connects to the Redis at addr, a redis:// or rediss:// URL or a
+// bare host:port. An empty addr returns Nop, for services running without
+// Redis. A Redis that does not answer at startup is not an error: it is
+// logged, and the cache works as a no-op until Redis answers. Only a
+// malformed addr is.
+func New(addr string, opts ...Option) (Cache, error) {
+	if addr == "" {
+		return Nop{}, nil
+	}
+	ropts := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		if ropts, err = redis.ParseURL(addr); err != nil {
+			return nil, fmt.Errorf("cache: %w", err)
+		}
+	}
+	// A cache answer is only worth having quickly.
+	ropts.DialTimeout = 500 * time.Millisecond
+	ropts.ReadTimeout = 250 * time.Millisecond
+	ropts.WriteTimeout = 250 * time.Millisecond
+	client := redis.NewClient(ropts)
+	c := NewRedis(client, opts...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.observe(client.Ping(ctx).Err())
+	return c, nil
+}
+
+// RedisCache is a Cache in Redis. Values are stored as Redis strings
+// under the cache's prefix.
+type RedisCache struct {
+	client Client
+	prefix string
+	logger *slog.Logger
+	retry  time.Duration
+	now    func() time.Time
+
+	loads   singleflight.Group
+	mu      sync.Mutex // serializes the down/up log lines
+	down    atomic.Bool
+	retryAt atomic.Int64 // unix nanoseconds before which Redis is skipped
+}
+
+// NewRedis returns a RedisCache using client.
+func NewRedis(client Client, opts ...Option) *RedisCache {
+	c := &RedisCache{client: client, prefix: "cache:", now: time.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry <= 0 {
+		c.retry = DefaultRetryInterval
+	}
+	return c
+}
+
+// Get implements Cache.
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	if c.skipping() {
+		return nil, false
+	}
+	b, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.observe(nil)
+		return nil, false
+	}
+	c.observe(err)
+	return b, err == nil
+}
+
+// Set implements Cache.
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if c.skipping() {
+		return
+	}
+	c.observe(c.client.Set(ctx, c.prefix+key, value, ttl).Err())
+}
+
+// Delete implements Cache. A delete that cannot reach Redis is lost, so
+// callers invalidating a key rely on its ttl as the backstop.
+func (c *RedisCache) Delete(ctx context.Context, key string) {
+	if c.skipping() {
+		return
+	}
+	c.observe(c.client.Del(ctx, c.prefix+key).Err())
+}
+
+// GetOrLoad implements Cache. The shared loader call runs with the
+// context of the caller that started it, minus its cancellation, so one
+// caller giving up does not fail the others; each caller still stops
+// waiting when its own ctx is done.
+func (c *RedisCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	if b, ok := c.Get(ctx, key); ok {
+		return b, nil
+	}
+	ch := c.loads.DoChan(key, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		b, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.Set(ctx, key, b, ttl)
+		return b, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// skipping reports whether Redis failed less than the retry interval ago.
+func (c *RedisCache) skipping() bool {
+	return c.down.Load() && c.now().UnixNano() < c.retryAt.Load()
+}
+
+// observe records the outcome of a Redis call, logging when Redis is
+// lost and when it is back.
+func (c *RedisCache) observe(err error) {
+	if err == nil {
+		if c.down.Load() {
+			c.mu.Lock()
+			if c.down.Swap(false) {
+				c.log().Info("cache: redis is back; caching resumed")
+			}
+			c.mu.Unlock()
+		}
+		return
+	}
+	c.retryAt.Store(c.now().Add(c.retry).UnixNano())
+	c.mu.Lock()
+	if !c.down.Swap(true) {
+		c.log().Warn("cache: redis unavailable; serving without cache", "error", err, "retry_in", c.retry.String())
+	}
+	c.mu.Unlock()
+}
+
+func (c *RedisCache) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
+// Nop is a Cache that stores nothing: every Get misses and GetOrLoad
+// always calls its loader.
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) ([]byte, bool)         { return nil, false }
+func (Nop) Set(context.Context, string, []byte, time.Duration) {}
+func (Nop) Delete(context.Context, string)                     {}
+func (Nop) GetOrLoad(ctx context.Context, _ string, _ time.Duration, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	return load(ctx)
+}
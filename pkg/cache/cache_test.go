@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis is an in-memory Client. While down is set every call fails
+// as if Redis were unreachable.
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+	gets atomic.Int32
+	down atomic.Bool
+}
+
+var errUnreachable = errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.gets.Add(1)
+	if f.down.Load() {
+		return redis.NewStringResult("", errUnreachable)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(string(v), nil)
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value any, ttl time.Duration) *redis.StatusCmd {
+	if f.down.Load() {
+		return redis.NewStatusResult("", errUnreachable)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = value.([]byte)
+	f.ttls[key] = ttl
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if f.down.Load() {
+		return redis.NewIntResult(0, errUnreachable)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range keys {
+		delete(f.data, k)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func quietLogger(buf *bytes.Buffer) Option {
+	return WithLogger(slog.New(slog.NewTextHandler(buf, nil)))
+}
+
+func TestGetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedis(f, WithPrefix("t:"))
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	c.Set(ctx, "k", []byte("v"), time.Minute)
+	if f.ttls["t:k"] != time.Minute {
+		t.Errorf("stored under %v, want t:k with a 1m ttl", f.ttls)
+	}
+	if b, ok := c.Get(ctx, "k"); !ok || string(b) != "v" {
+		t.Errorf("Get = %q, %v", b, ok)
+	}
+	c.Delete(ctx, "k")
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Error("hit after Delete")
+	}
+}
+
+func TestGetOrLoadSingleFlight(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedis(f)
+	const callers = 20
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("loaded"), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := c.GetOrLoad(ctx, "hot", time.Minute, load)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = string(b)
+		}()
+	}
+	// Let every caller miss and join the load before it finishes.
+	for f.gets.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times for %d concurrent misses, want 1", n, callers)
+	}
+	for i, r := range results {
+		if r != "loaded" {
+			t.Errorf("caller %d got %q", i, r)
+		}
+	}
+	if b, err := c.GetOrLoad(ctx, "hot", time.Minute, load); err != nil || string(b) != "loaded" || calls.Load() != 1 {
+		t.Errorf("GetOrLoad after the load = %q, %v with %d loader calls; want a cache hit", b, err, calls.Load())
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedis(f)
+	boom := errors.New("boom")
+	if _, err := c.GetOrLoad(ctx, "k", time.Minute, func(context.Context) ([]byte, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want the loader's", err)
+	}
+	if len(f.data) != 0 {
+		t.Errorf("failed load cached: %v", f.data)
+	}
+}
+
+func TestGetOrLoadCallerCancel(t *testing.T) {
+	c := NewRedis(newFakeRedis())
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		<-release
+		return []byte("v"), ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "k", time.Minute, load)
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	second := make(chan []byte, 1)
+	go func() {
+		b, _ := c.GetOrLoad(context.Background(), "k", time.Minute, load)
+		second <- b
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled caller got %v", err)
+	}
+	close(release)
+	if b := <-second; string(b) != "v" {
+		t.Errorf("other caller got %q, want the load to survive the first caller leaving", b)
+	}
+}
+
+func TestDegradesWhileRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	var logs bytes.Buffer
+	f := newFakeRedis()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	c := NewRedis(f, quietLogger(&logs), WithRetryInterval(time.Minute))
+	c.now = func() time.Time { return now }
+	f.down.Store(true)
+
+	calls := 0
+	load := func(context.Context) ([]byte, error) {
+		calls++
+		return []byte("fresh"), nil
+	}
+	for range 3 {
+		if b, err := c.GetOrLoad(ctx, "k", time.Minute, load); err != nil || string(b) != "fresh" {
+			t.Fatalf("GetOrLoad with Redis down = %q, %v; want the loader's value", b, err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("loader called %d times, want every call to reach it", calls)
+	}
+	if n := strings.Count(logs.String(), "redis unavailable"); n != 1 {
+		t.Errorf("warned %d times, want once:\n%s", n, logs.String())
+	}
+	if n := f.gets.Load(); n != 1 {
+		t.Errorf("Redis tried %d times within the retry interval, want 1", n)
+	}
+
+	f.down.Store(false)
+	now = now.Add(time.Minute)
+	c.Set(ctx, "k", []byte("cached"), time.Minute)
+	if b, ok := c.Get(ctx, "k"); !ok || string(b) != "cached" {
+		t.Errorf("after recovery Get = %q, %v", b, ok)
+	}
+	if !strings.Contains(logs.String(), "redis is back") {
+		t.Errorf("recovery not logged:\n%s", logs.String())
+	}
+}
+
+func TestNewUnreachable(t *testing.T) {
+	var logs bytes.Buffer
+	c, err := New("127.0.0.1:1", quietLogger(&logs))
+	if err != nil {
+		t.Fatalf("New with Redis down: %v", err)
+	}
+	if !strings.Contains(logs.String(), "redis unavailable") {
+		t.Errorf("no warning logged:\n%s", logs.String())
+	}
+	b, err := c.GetOrLoad(context.Background(), "k", time.Minute, func(context.Context) ([]byte, error) { return []byte("v"), nil })
+	if err != nil || string(b) != "v" {
+		t.Errorf("GetOrLoad = %q, %v", b, err)
+	}
+
+	if _, err := New("redis://:bad port"); err == nil {
+		t.Error("malformed URL accepted")
+	}
+	if c, err := New(""); err != nil || c != (Nop{}) {
+		t.Errorf("New(\"\") = %v, %v; want Nop", c, err)
+	}
+}
@@ -0,0 +1,349 @@
+// Package circuit is the circuit breaker every outbound integration
+// shares, so a dependency that is down costs each caller one fast error
+// instead of a full timeout:
+//
+//	b := &circuit.Breaker{Name: "s3", Policy: circuit.Policy{FailureThreshold: 5, Timeout: 30 * time.Second}}
+//	err := b.Do(ctx, func() error { return upload(ctx) })
+//	if errors.Is(err, circuit.ErrOpen) {
+//		// answer 503
+//	}
+//
+// After FailureThreshold calls in a row fail, the breaker opens and calls
+// fail at once with ErrOpen. After Timeout it is half-open:
+// SuccessThreshold calls go through as probes, closing the breaker if
+// they all succeed and opening it again on any failure, while the calls
+// made meanwhile are refused.
+//
+// Do judges a call by its error. Callers that judge by more, such as an
+// HTTP client counting 5xx answers, use Allow and report each call's
+// Outcome to Call.Done instead.
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults for the Policy fields left zero.
+const (
+	DefaultFailureThreshold = 5
+	DefaultSuccessThreshold = 1
+	DefaultTimeout          = 30 * time.Second
+)
+
+// ErrOpen is matched, with errors.Is, by the *OpenError returned for
+// calls a breaker refuses without making them.
+var ErrOpen = errors.New("circuit breaker open")
+
+// OpenError is a call a breaker refused.
+type OpenError struct {
+	// Name is the breaker's.
+	Name string
+	// RetryAfter is how long until the breaker lets probes through, or
+	// zero when it is half-open with every probe already out.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	if e.Name == "" {
+		return ErrOpen.Error()
+	}
+	return e.Name + ": " + ErrOpen.Error()
+}
+
+// Is makes an *OpenError match ErrOpen.
+func (e *OpenError) Is(target error) bool { return target == ErrOpen }
+
+// State is the state of a breaker.
+type State string
+
+const (
+	Closed   State = "closed"
+	HalfOpen State = "half_open"
+	Open     State = "open"
+)
+
+// Policy is when a breaker opens and closes. Zero fields take the
+// defaults.
+type Policy struct {
+	// FailureThreshold is how many failures in a row open the breaker.
+	FailureThreshold int
+	// Window, if set, is how long a streak of failures counts: a failure
+	// more than Window after the first of the streak starts a new one.
+	Window time.Duration
+	// SuccessThreshold is how many probes a half-open breaker lets
+	// through, all of which must succeed to close it.
+	SuccessThreshold int
+	// Timeout is how long the breaker stays open.
+	Timeout time.Duration
+}
+
+func (p Policy) withDefaults() Policy {
+	p.FailureThreshold = orDefault(p.FailureThreshold, DefaultFailureThreshold)
+	p.SuccessThreshold = orDefault(p.SuccessThreshold, DefaultSuccessThreshold)
+	p.Timeout = orDefault(p.Timeout, DefaultTimeout)
+	return p
+}
+
+// Breaker guards the calls to one dependency. The zero value is a closed
+// breaker with the default policy, and a nil *Breaker makes every call,
+// so integrations can take one optionally. It is safe for concurrent use
+// once in use; its fields must not change after the first call, except
+// through SetPolicy.
+type Breaker struct {
+	// Name identifies the dependency in OpenError.
+	Name string
+	Policy
+	// OnStateChange, if set, is called on every transition, such as to
+	// set a gauge. It is called with the breaker locked, so it must be
+	// quick and must not use the Breaker.
+	OnStateChange func(from, to State)
+	// Now is the breaker's clock; nil means time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	gen       uint64
+	failures  int       // consecutive, while closed
+	since     time.Time // the first of those failures
+	admitted  int       // probes let through while half-open
+	successes int       // probes that succeeded
+	openedAt  time.Time
+	trips     int
+}
+
+// Outcome is what a call tells the breaker about its dependency.
+type Outcome int
+
+const (
+	Success Outcome = iota
+	Failure
+	// Abandoned calls were canceled by the caller and say nothing.
+	Abandoned
+)
+
+// Call is a call Allow admitted.
+type Call struct {
+	b   *Breaker
+	gen uint64
+}
+
+// Done reports the call's outcome. Outcomes of calls admitted before the
+// breaker last changed state are ignored, so a slow call admitted while
+// closed cannot close a breaker that has since opened, and a probe only
+// counts for the half-open period it was let through in.
+func (c Call) Done(o Outcome) {
+	if c.b != nil {
+		c.b.record(c.gen, o)
+	}
+}
+
+// Allow admits a call, which must then report its outcome to Done, or
+// refuses it with an *OpenError. A nil breaker admits every call.
+func (b *Breaker) Allow() (Call, error) {
+	if b == nil {
+		return Call{}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.Policy.withDefaults()
+	switch b.current() {
+	case Open:
+		if wait := b.openedAt.Add(p.Timeout).Sub(b.clock()); wait > 0 {
+			return Call{}, &OpenError{Name: b.Name, RetryAfter: wait}
+		}
+		b.transition(HalfOpen)
+		fallthrough
+	case HalfOpen:
+		if b.admitted >= p.SuccessThreshold {
+			return Call{}, &OpenError{Name: b.Name}
+		}
+		b.admitted++
+	}
+	return Call{b, b.gen}, nil
+}
+
+// Do runs fn unless the breaker is open, in which case it returns an
+// *OpenError at once. fn's error is returned as is, and counts as a
+// failure unless it is wrapped with Healthy or ctx was canceled, meaning
+// the caller gave up rather than the dependency. A deadline running out
+// is a failure, as the dependency was too slow.
+func (b *Breaker) Do(ctx context.Context, fn func() error) error {
+	call, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	var h *healthyError
+	o := Success
+	switch {
+	case err == nil:
+	case errors.As(err, &h):
+		err = h.err
+	case errors.Is(ctx.Err(), context.Canceled):
+		o = Abandoned
+	default:
+		o = Failure
+	}
+	call.Done(o)
+	return err
+}
+
+// SetPolicy replaces the breaker's policy from the next call on, keeping
+// its state, as a reloaded configuration does. On a nil breaker it does
+// nothing.
+func (b *Breaker) SetPolicy(p Policy) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Policy = p
+}
+
+// State returns the breaker's state. An open breaker reports Open until
+// the first call after Timeout makes it half-open.
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+// Trips returns how many times the breaker has opened.
+func (b *Breaker) Trips() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trips
+}
+
+// Status is a breaker's state as shown to operators.
+type Status struct {
+	State State
+	// Failures counts the current streak while closed.
+	Failures int
+	// OpenedAt is when the breaker last opened; it is zero for a breaker
+	// that never has.
+	OpenedAt time.Time
+}
+
+// Status returns the breaker's state, as State does, with its streak.
+func (b *Breaker) Status() Status {
+	if b == nil {
+		return Status{State: Closed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Status{State: b.current(), Failures: b.failures, OpenedAt: b.openedAt}
+}
+
+// Reset closes the breaker, forgetting any failures. A nil breaker is
+// always closed.
+func (b *Breaker) Reset() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.current() != Closed {
+		b.transition(Closed)
+	}
+	b.failures = 0
+}
+
+// healthyError marks an error that shows the dependency working.
+type healthyError struct{ err error }
+
+func (e *healthyError) Error() string { return e.err.Error() }
+func (e *healthyError) Unwrap() error { return e.err }
+
+// Healthy wraps an error fn returns to Do that shows the dependency is up
+// and answering, such as a recipient the relay refused or an object that
+// does not exist, so the call counts as a success. Do returns err itself.
+func Healthy(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &healthyError{err}
+}
+
+// record applies the outcome of a call Allow admitted in generation gen.
+func (b *Breaker) record(gen uint64, o Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	p := b.Policy.withDefaults()
+	switch b.current() {
+	case Closed:
+		switch o {
+		case Success:
+			b.failures = 0
+		case Failure:
+			now := b.clock()
+			if b.failures == 0 || p.Window > 0 && now.Sub(b.since) > p.Window {
+				b.failures, b.since = 0, now
+			}
+			if b.failures++; b.failures >= p.FailureThreshold {
+				b.transition(Open)
+			}
+		}
+	case HalfOpen:
+		switch o {
+		case Success:
+			if b.successes++; b.successes >= p.SuccessThreshold {
+				b.transition(Closed)
+			}
+		case Failure:
+			b.transition(Open)
+		case Abandoned:
+			b.admitted--
+		}
+	}
+}
+
+// transition moves to state to and starts a new generation. b.mu must be
+// held.
+func (b *Breaker) transition(to State) {
+	from := b.current()
+	b.state = to
+	b.gen++
+	b.failures, b.admitted, b.successes = 0, 0, 0
+	if to == Open {
+		b.openedAt = b.clock()
+		b.trips++
+	}
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+// current is the state, with the zero value closed. b.mu must be held.
+func (b *Breaker) current() State {
+	if b.state == "" {
+		return Closed
+	}
+	return b.state
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.Now == nil {
+		return time.Now()
+	}
+	return b.Now()
+}
+
+func orDefault[T int | time.Duration](v, def T) T {
+	if v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,379 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+// newBreaker returns a breaker whose clock runs from *now, recording its
+// transitions.
+func newBreaker(now *time.Time, changes *[]State) *Breaker {
+	return &Breaker{
+		Name:          "smtp",
+		Policy:        Policy{FailureThreshold: 3, SuccessThreshold: 2, Timeout: time.Minute},
+		OnStateChange: func(_, to State) { *changes = append(*changes, to) },
+		Now:           func() time.Time { return *now },
+	}
+}
+
+func fail() error { return errDown }
+func pass() error { return nil }
+
+func TestOpensAfterFailureThreshold(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	var changes []State
+	b := newBreaker(&now, &changes)
+	ctx := context.Background()
+
+	b.Do(ctx, fail)
+	b.Do(ctx, fail)
+	b.Do(ctx, pass) // a success resets the streak
+	for range 2 {
+		if err := b.Do(ctx, fail); !errors.Is(err, errDown) {
+			t.Fatalf("error %v, want fn's own", err)
+		}
+	}
+	if b.State() != Closed {
+		t.Fatalf("state %s after 2 failures in a row, want closed", b.State())
+	}
+	b.Do(ctx, fail)
+	if b.State() != Open || b.Trips() != 1 {
+		t.Fatalf("state %s, trips %d; want open, 1", b.State(), b.Trips())
+	}
+	called := false
+	err := b.Do(ctx, func() error { called = true; return nil })
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker: error %v, fn called %v", err, called)
+	}
+	if err.Error() != "smtp: circuit breaker open" {
+		t.Errorf("error %q does not name the dependency", err)
+	}
+	if len(changes) != 1 || changes[0] != Open {
+		t.Errorf("transitions %v", changes)
+	}
+}
+
+func TestHalfOpenProbes(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	var changes []State
+	b := newBreaker(&now, &changes)
+	ctx := context.Background()
+	for range 3 {
+		b.Do(ctx, fail)
+	}
+
+	now = now.Add(time.Minute - time.Second)
+	if err := b.Do(ctx, pass); !errors.Is(err, ErrOpen) {
+		t.Fatalf("before Timeout: %v, want ErrOpen", err)
+	}
+	now = now.Add(time.Second)
+	// Two probes are let through at once; a third call waits on them.
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	started := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			done <- b.Do(ctx, func() error { started <- struct{}{}; <-release; return nil })
+		}()
+	}
+	<-started
+	<-started
+	if err := b.Do(ctx, pass); !errors.Is(err, ErrOpen) {
+		t.Errorf("third call while probing: %v, want ErrOpen", err)
+	}
+	close(release)
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.State() != Closed {
+		t.Fatalf("state %s after the probes passed, want closed", b.State())
+	}
+
+	for range 3 {
+		b.Do(ctx, fail)
+	}
+	now = now.Add(time.Minute)
+	b.Do(ctx, fail)
+	if b.State() != Open || b.Trips() != 3 {
+		t.Errorf("failed probe: state %s, trips %d; want open, 3", b.State(), b.Trips())
+	}
+	want := []State{Open, HalfOpen, Closed, Open, HalfOpen, Open}
+	if len(changes) != len(want) {
+		t.Fatalf("transitions %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("transitions %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestHealthyAndAbandonedCallsDoNotCount(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	var changes []State
+	b := newBreaker(&now, &changes)
+	rejected := errors.New("550 no such mailbox")
+	for range 5 {
+		if err := b.Do(context.Background(), func() error { return Healthy(rejected) }); err != rejected {
+			t.Fatalf("error %v, want the unwrapped rejection", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 5 {
+		b.Do(ctx, func() error { return ctx.Err() })
+	}
+	if b.State() != Closed {
+		t.Errorf("state %s, want closed", b.State())
+	}
+
+	// Running out of time is the dependency's fault.
+	ctx, cancel = context.WithDeadline(context.Background(), now)
+	defer cancel()
+	for range 3 {
+		b.Do(ctx, func() error { return ctx.Err() })
+	}
+	if b.State() != Open {
+		t.Errorf("state %s after 3 timeouts, want open", b.State())
+	}
+}
+
+func TestStaleOutcomesAreIgnored(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	var changes []State
+	b := newBreaker(&now, &changes)
+	ctx := context.Background()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		b.Do(ctx, func() error { close(started); <-release; return nil })
+		close(done)
+	}()
+	<-started
+	for range 3 {
+		b.Do(ctx, fail)
+	}
+	close(release)
+	<-done
+	if b.State() != Open {
+		t.Errorf("a call admitted before the breaker opened closed it: state %s", b.State())
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	for range 10 {
+		if err := b.Do(context.Background(), fail); !errors.Is(err, errDown) {
+			t.Fatalf("error %v", err)
+		}
+	}
+	if err := b.Do(context.Background(), func() error { return Healthy(errDown) }); err != errDown {
+		t.Errorf("Healthy error came back as %v", err)
+	}
+	if b.State() != Closed || b.Trips() != 0 {
+		t.Errorf("state %s, trips %d", b.State(), b.Trips())
+	}
+	b.SetPolicy(Policy{FailureThreshold: 1})
+	b.Reset()
+	if st := b.Status(); st != (Status{State: Closed}) {
+		t.Errorf("status %+v after SetPolicy and Reset", st)
+	}
+}
+
+// transitions records a breaker's state changes.
+type transitions struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (tr *transitions) record(from, to State) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.got = append(tr.got, string(from)+">"+string(to))
+}
+
+func (tr *transitions) count(change string) int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	n := 0
+	for _, c := range tr.got {
+		if c == change {
+			n++
+		}
+	}
+	return n
+}
+
+// testBreaker is a breaker with policy p on a clock the test moves.
+func testBreaker(p Policy) (*Breaker, *transitions, *time.Time) {
+	tr := &transitions{}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := &Breaker{Name: "upstream api:8080", Policy: p, OnStateChange: tr.record, Now: func() time.Time { return now }}
+	return b, tr, &now
+}
+
+func failCall(t *testing.T, b *Breaker) {
+	t.Helper()
+	call, err := b.Allow()
+	if err != nil {
+		t.Fatalf("call refused: %v", err)
+	}
+	call.Done(Failure)
+}
+
+func TestWindowAndRetryAfter(t *testing.T) {
+	b, tr, now := testBreaker(Policy{FailureThreshold: 3, Window: time.Minute, Timeout: 10 * time.Second})
+
+	failCall(t, b)
+	failCall(t, b)
+	*now = now.Add(2 * time.Minute)
+	failCall(t, b)
+	if b.State() != Closed || b.Status().Failures != 1 {
+		t.Fatalf("a streak older than the window still counted: %d failures", b.Status().Failures)
+	}
+	failCall(t, b)
+	failCall(t, b)
+	if b.State() != Open || tr.count("closed>open") != 1 {
+		t.Fatalf("state %s after 3 failures, transitions %v", b.State(), tr.got)
+	}
+	if st := b.Status(); !st.OpenedAt.Equal(*now) {
+		t.Errorf("opened at %s, want %s", st.OpenedAt, *now)
+	}
+
+	_, err := b.Allow()
+	var open *OpenError
+	if !errors.As(err, &open) || open.RetryAfter != 10*time.Second || open.Name != b.Name {
+		t.Fatalf("open breaker: err %v", err)
+	}
+	*now = now.Add(9 * time.Second)
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatal("breaker let a call through before its timeout")
+	}
+}
+
+func TestAbandonedProbeAndReset(t *testing.T) {
+	b, tr, now := testBreaker(Policy{FailureThreshold: 1, Timeout: 10 * time.Second, SuccessThreshold: 2})
+	failCall(t, b)
+
+	*now = now.Add(10 * time.Second)
+	first, err1 := b.Allow()
+	second, err2 := b.Allow()
+	if err1 != nil || err2 != nil || b.State() != HalfOpen {
+		t.Fatalf("probes refused: %v, %v (state %s)", err1, err2, b.State())
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Fatal("half-open breaker let a third call through")
+	}
+	// A probe the caller abandoned frees its slot.
+	second.Done(Abandoned)
+	second, err := b.Allow()
+	if err != nil {
+		t.Fatalf("abandoned probe not replaced: %v", err)
+	}
+	first.Done(Success)
+	if b.State() != HalfOpen {
+		t.Fatal("closed after one of two probes")
+	}
+	second.Done(Success)
+	if b.State() != Closed {
+		t.Fatalf("state %s after both probes succeeded", b.State())
+	}
+
+	failCall(t, b)
+	b.Reset()
+	if _, err := b.Allow(); err != nil || b.State() != Closed {
+		t.Fatalf("reset breaker: %s, %v", b.State(), err)
+	}
+	want := "closed>open open>half_open half_open>closed closed>open open>closed"
+	if got := strings.Join(tr.got, " "); got != want {
+		t.Errorf("transitions:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestSetPolicyKeepsState(t *testing.T) {
+	b, _, _ := testBreaker(Policy{FailureThreshold: 3})
+	failCall(t, b)
+	failCall(t, b)
+	b.SetPolicy(Policy{FailureThreshold: 5})
+	failCall(t, b)
+	if b.State() != Closed || b.Status().Failures != 3 {
+		t.Fatalf("state %s with %d failures, want closed with 3", b.State(), b.Status().Failures)
+	}
+	failCall(t, b)
+	failCall(t, b)
+	if b.State() != Open {
+		t.Errorf("state %s after 5 failures, want open", b.State())
+	}
+}
+
+// TestBoundaryRace makes many calls at the instant the timeout ends:
+// exactly one of them moves the breaker to half-open, exactly
+// SuccessThreshold get through, and their failures reopen it once.
+func TestBoundaryRace(t *testing.T) {
+	p := Policy{FailureThreshold: 1, Timeout: 10 * time.Second, SuccessThreshold: 3}
+	b, tr, now := testBreaker(p)
+	failCall(t, b)
+	*now = b.Status().OpenedAt.Add(p.Timeout)
+
+	const callers = 200
+	var (
+		start   = make(chan struct{})
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		calls   []Call
+		refused atomic.Int32
+	)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			call, err := b.Allow()
+			if err != nil {
+				refused.Add(1)
+				return
+			}
+			mu.Lock()
+			calls = append(calls, call)
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if len(calls) != p.SuccessThreshold || refused.Load() != callers-int32(p.SuccessThreshold) {
+		t.Errorf("%d admitted, %d refused; want %d and %d", len(calls), refused.Load(), p.SuccessThreshold, callers-p.SuccessThreshold)
+	}
+	if n := tr.count("open>half_open"); n != 1 {
+		t.Errorf("moved to half-open %d times, want once", n)
+	}
+
+	// The probes fail together; the first reopens the breaker and the
+	// rest, from the half-open period that ended, change nothing.
+	start = make(chan struct{})
+	for _, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			call.Done(Failure)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if n := tr.count("half_open>open"); n != 1 {
+		t.Errorf("reopened %d times, want once: %v", n, tr.got)
+	}
+	if b.State() != Open || len(tr.got) != 3 {
+		t.Errorf("state %s after transitions %v, want open", b.State(), tr.got)
+	}
+}
@@ -0,0 +1,214 @@
+// Package config fills a service's configuration struct from, in
+// increasing order of precedence, field defaults, an optional YAML file
+// named by CONFIG_FILE, environment variables, and command-line flags.
+//
+// Fields opt in with a config tag naming their key:
+//
+//	type Config struct {
+//		Port     int           `config:"port" default:"8080" usage:"listen port"`
+//		Grace    time.Duration `config:"shutdown_grace" default:"20s"`
+//		Database *url.URL      `config:"database_url,required,secret"`
+//		Origins  []string      `config:"allowed_origins"`
+//	}
+//
+// The key is used as is in the file, upper-cased (behind the optional
+// prefix) for the environment, and with dashes for the flag, so
+// shutdown_grace is read from SHUTDOWN_GRACE and -shutdown-grace.
+// Struct-typed fields with a config tag nest their fields under the key:
+// db.url in the file, DB_URL (or DB__URL WithEnvSeparator("__")), and
+// -db-url. Untagged fields are left alone.
+//
+// Supported types are strings, bools, integers, floats, time.Duration,
+// url.URL and *url.URL, Secret, []string (comma-separated outside the file), and
+// anything implementing encoding.TextUnmarshaler. Other slices and maps,
+// such as a list of route structs, are decoded as YAML: from the file
+// as nested values, and from the environment or a flag as inline YAML or
+// JSON.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// FileEnv names the environment variable holding the optional YAML file
+// path.
+const FileEnv = "CONFIG_FILE"
+
+// Option customizes Load.
+type Option func(*options)
+
+type options struct {
+	args         []string
+	lookupEnv    func(string) (string, bool)
+	prefix       string
+	separator    string
+	files        *[]string
+	allowUnknown bool
+}
+
+// WithArgs parses args as flags instead of os.Args[1:].
+func WithArgs(args []string) Option {
+	return func(o *options) { o.args = args }
+}
+
+// WithLookupEnv reads the environment through lookup instead of
+// os.LookupEnv, e.g. to load from a fixed map in tests.
+func WithLookupEnv(lookup func(string) (string, bool)) Option {
+	return func(o *options) { o.lookupEnv = lookup }
+}
+
+// WithEnvPrefix prepends prefix to every derived variable name, so with
+// "BILLING_" the port key is read from BILLING_PORT. CONFIG_FILE itself
+// is not prefixed.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// WithEnvSeparator joins the keys of nested fields with sep instead of
+// "_" in variable names, so with "__" db.max_conns is read from
+// DB__MAX_CONNS and cannot be mistaken for a db_max.conns.
+func WithEnvSeparator(sep string) Option {
+	return func(o *options) { o.separator = sep }
+}
+
+// WithFile reads path instead of the file named by CONFIG_FILE. An empty
+// path skips the file.
+func WithFile(path string) Option {
+	if path == "" {
+		return WithFiles()
+	}
+	return WithFiles(path)
+}
+
+// WithFiles reads each of paths in order instead of the file named by
+// CONFIG_FILE, a key in a later file overriding the same key in an
+// earlier one. No paths skips the file.
+func WithFiles(paths ...string) Option {
+	return func(o *options) { o.files = &paths }
+}
+
+// AllowUnknownKeys ignores file keys that match no field instead of
+// failing, for files shared between services.
+func AllowUnknownKeys() Option {
+	return func(o *options) { o.allowUnknown = true }
+}
+
+// Load returns a T filled from every source. T must be a struct. Parse
+// errors from all sources, and every required field left without a
+// value, are reported together. With -h or -help among the arguments the
+// error wraps flag.ErrHelp.
+func Load[T any](opts ...Option) (T, error) {
+	var cfg T
+	o := options{args: os.Args[1:], lookupEnv: os.LookupEnv, separator: "_"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	rv := reflect.ValueOf(&cfg).Elem()
+	if rv.Kind() != reflect.Struct {
+		return cfg, fmt.Errorf("config: Load needs a struct type, got %s", rv.Type())
+	}
+	fields, err := collect(rv, nil, o.prefix, o.separator)
+	if err != nil {
+		return cfg, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.hasDefault {
+			if err := f.set(f.def); err != nil {
+				errs = append(errs, fmt.Errorf("config: default for %s: %w", f.key, err))
+			}
+		}
+	}
+
+	var paths []string
+	if path, _ := o.lookupEnv(FileEnv); path != "" {
+		paths = []string{path}
+	}
+	if o.files != nil {
+		paths = *o.files
+	}
+	for _, path := range paths {
+		errs = append(errs, loadFile(path, fields, o.allowUnknown)...)
+	}
+
+	for _, f := range fields {
+		if v, ok := o.lookupEnv(f.env); ok {
+			if err := f.set(v); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", f.env, err))
+			}
+		}
+	}
+
+	if err := parseFlags(o.args, fields); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cfg, err
+		}
+		errs = append(errs, err)
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if f.required && !f.isSet {
+			missing = append(missing, fmt.Sprintf("%s (%s or -%s)", f.key, f.env, f.flag))
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("config: missing required values: %s", strings.Join(missing, ", ")))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// parseFlags registers one flag per field and applies the ones present in
+// args, in field order.
+func parseFlags(args []string, fields []*field) error {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	values := map[string]string{}
+	for _, f := range fields {
+		fs.Var(&flagValue{f: f, values: values}, f.flag, f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var errs []error
+	for _, f := range fields {
+		v, ok := values[f.flag]
+		if !ok {
+			continue
+		}
+		if err := f.set(v); err != nil {
+			errs = append(errs, fmt.Errorf("config: -%s: %w", f.flag, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flagValue defers parsing to Load so flags apply after the environment
+// regardless of where the flag package calls Set.
+type flagValue struct {
+	f      *field
+	values map[string]string
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.f == nil {
+		return ""
+	}
+	return v.f.def
+}
+
+func (v *flagValue) Set(s string) error {
+	v.values[v.f.flag] = s
+	return nil
+}
+
+// IsBoolFlag lets bool fields be set with a bare -name.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.f.v.Kind() == reflect.Bool
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Port     int           `config:"port" default:"8080" usage:"listen port"`
+	Grace    time.Duration `config:"shutdown_grace" default:"20s"`
+	Database *url.URL      `config:"database_url,required"`
+	Origins  []string      `config:"allowed_origins"`
+	Debug    bool          `config:"debug"`
+	Token    string        `config:"token,secret"`
+	DB       struct {
+		Pool int `config:"pool" default:"4"`
+	} `config:"db"`
+	Ignored string
+}
+
+func env(vars map[string]string) Option {
+	return WithLookupEnv(func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+port: 9000
+shutdown_grace: 5s
+database_url: postgres://file/db
+allowed_origins: [https://a.example, https://b.example]
+db:
+  pool: 8
+`)
+	cfg, err := Load[testConfig](
+		env(map[string]string{
+			FileEnv:           path,
+			"SHUTDOWN_GRACE":  "7s",
+			"DATABASE_URL":    "postgres://env/db",
+			"ALLOWED_ORIGINS": "https://c.example",
+		}),
+		WithArgs([]string{"-database-url", "postgres://flag/db", "-debug"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 || cfg.DB.Pool != 8 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Grace != 7*time.Second {
+		t.Errorf("env did not override the file: grace %s", cfg.Grace)
+	}
+	if !reflect.DeepEqual(cfg.Origins, []string{"https://c.example"}) {
+		t.Errorf("origins %q", cfg.Origins)
+	}
+	if cfg.Database.String() != "postgres://flag/db" || !cfg.Debug {
+		t.Errorf("flags did not override the env: %+v", cfg)
+	}
+	if cfg.Ignored != "" {
+		t.Errorf("untagged field set to %q", cfg.Ignored)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load[testConfig](env(map[string]string{"DATABASE_URL": "postgres://h/db"}), WithArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.Grace != 20*time.Second || cfg.DB.Pool != 4 || cfg.Origins != nil {
+		t.Errorf("defaults: %+v", cfg)
+	}
+}
+
+func TestLoadEnvPrefix(t *testing.T) {
+	cfg, err := Load[testConfig](
+		env(map[string]string{"BILLING_DATABASE_URL": "postgres://h/db", "BILLING_DB_POOL": "2", "PORT": "1"}),
+		WithEnvPrefix("BILLING_"), WithArgs(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Pool != 2 || cfg.Port != 8080 {
+		t.Errorf("prefixed env: %+v", cfg)
+	}
+}
+
+func TestLoadEnvSeparator(t *testing.T) {
+	cfg, err := Load[testConfig](
+		env(map[string]string{"APP_DATABASE_URL": "postgres://h/db", "APP_DB__POOL": "3", "APP_DB_POOL": "9"}),
+		WithEnvPrefix("APP_"), WithEnvSeparator("__"), WithArgs(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Pool != 3 {
+		t.Errorf("db.pool = %d, want it from APP_DB__POOL", cfg.DB.Pool)
+	}
+}
+
+func TestLoadFilesInOrder(t *testing.T) {
+	base := writeFile(t, "port: 9000\ndatabase_url: postgres://base/db\ndb:\n  pool: 8\n")
+	local := writeFile(t, "port: 9001\n")
+	cfg, err := Load[testConfig](WithFiles(base, local), env(map[string]string{FileEnv: "/nonexistent.yaml"}), WithArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9001 || cfg.DB.Pool != 8 || cfg.Database.Host != "base" {
+		t.Errorf("merged files: %+v", cfg)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	s := struct{ Password Secret }{"hunter2"}
+	for _, got := range []string{fmt.Sprint(s), fmt.Sprintf("%+v", s), fmt.Sprintf("%#v", s)} {
+		if strings.Contains(got, "hunter2") {
+			t.Errorf("printed %s", got)
+		}
+	}
+	if b, _ := json.Marshal(s); string(b) != `{"Password":"[redacted]"}` {
+		t.Errorf("JSON %s", b)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("x", "password", s.Password)
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("logged %s", buf.String())
+	}
+	if string(s.Password) != "hunter2" || Secret("").String() != "" {
+		t.Error("conversion or empty Secret")
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	path := writeFile(t, "database_url: postgres://h/db\nprot: 1\ndb:\n  pol: 2\n")
+	_, err := Load[testConfig](WithFile(path), env(nil), WithArgs(nil))
+	if err == nil || !strings.Contains(err.Error(), "unknown keys: db.pol, prot") {
+		t.Fatalf("got %v, want both unknown keys", err)
+	}
+	if _, err := Load[testConfig](WithFile(path), AllowUnknownKeys(), env(nil), WithArgs(nil)); err != nil {
+		t.Errorf("AllowUnknownKeys: %v", err)
+	}
+}
+
+func TestLoadRequiredAggregated(t *testing.T) {
+	type twoRequired struct {
+		A string `config:"a,required"`
+		B string `config:"b,required"`
+		C string `config:"c,required" default:"set"`
+	}
+	_, err := Load[twoRequired](env(nil), WithArgs(nil))
+	if err == nil {
+		t.Fatal("missing required values accepted")
+	}
+	want := "config: missing required values: a (A or -a), b (B or -b)"
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestLoadParseErrors(t *testing.T) {
+	_, err := Load[testConfig](
+		env(map[string]string{"PORT": "eighty", "SHUTDOWN_GRACE": "soon"}),
+		WithArgs([]string{"-database-url", "postgres://h/db"}),
+	)
+	if err == nil {
+		t.Fatal("bad values accepted")
+	}
+	for _, want := range []string{"PORT", "SHUTDOWN_GRACE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name %s", err, want)
+		}
+	}
+
+	if _, err := Load[testConfig](env(nil), WithArgs([]string{"-h"})); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: got %v, want flag.ErrHelp", err)
+	}
+	if _, err := Load[struct {
+		C chan int `config:"c"`
+	}](env(nil), WithArgs(nil)); err == nil || !strings.Contains(err.Error(), "unsupported type") {
+		t.Errorf("chan field: %v", err)
+	}
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	in, err := Load[testConfig](
+		env(map[string]string{
+			"DATABASE_URL":    "postgres://app@db.internal:5432/admissions?sslmode=require",
+			"SHUTDOWN_GRACE":  "1m30s",
+			"ALLOWED_ORIGINS": "https://a.example, https://b.example",
+			"TOKEN":           "hunter2",
+		}),
+		WithArgs(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := Dump(in)
+	if strings.Contains(dump, "hunter2") || !strings.Contains(dump, "token="+Redacted) {
+		t.Errorf("secret not redacted: %s", dump)
+	}
+
+	// Feed every non-secret pair back in as flags.
+	var args []string
+	for _, pair := range strings.Fields(dump) {
+		k, v, _ := strings.Cut(pair, "=")
+		if k == "token" {
+			continue
+		}
+		if uq, err := strconv.Unquote(v); err == nil {
+			v = uq
+		}
+		args = append(args, "-"+strings.NewReplacer(".", "-", "_", "-").Replace(k)+"="+v)
+	}
+	out, err := Load[testConfig](env(nil), WithArgs(args))
+	if err != nil {
+		t.Fatalf("reload %q: %v", args, err)
+	}
+	out.Token = in.Token
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip changed the config:\n in %+v\nout %+v", in, out)
+	}
+}
+
+func TestDumpMasksURLPasswords(t *testing.T) {
+	u, _ := url.Parse("postgres://app:s3cret@db/admissions")
+	dump := Dump(&testConfig{Database: u})
+	if strings.Contains(dump, "s3cret") || !strings.Contains(dump, "database_url=postgres://app:xxxxx@db/admissions") {
+		t.Errorf("password not masked: %s", dump)
+	}
+	if !strings.Contains(dump, `token=""`) {
+		t.Errorf("empty secret should dump empty: %s", dump)
+	}
+}
+
+func TestLoadStructuredField(t *testing.T) {
+	type upstream struct {
+		Name    string        `yaml:"name"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	type withList struct {
+		Upstreams []upstream        `config:"upstreams"`
+		Labels    map[string]string `config:"labels"`
+	}
+	path := writeFile(t, "upstreams:\n  - name: api\n    timeout: 5s\n  - name: web\nlabels:\n  team: admissions\n")
+	cfg, err := Load[withList](WithFile(path), env(nil), WithArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []upstream{{"api", 5 * time.Second}, {"web", 0}}
+	if !reflect.DeepEqual(cfg.Upstreams, want) || cfg.Labels["team"] != "admissions" {
+		t.Errorf("file: %+v", cfg)
+	}
+
+	dump := Dump(cfg)
+	quoted, err := strconv.QuotedPrefix(strings.TrimPrefix(dump, "upstreams="))
+	if err != nil {
+		t.Fatalf("dump %s: %v", dump, err)
+	}
+	v, _ := strconv.Unquote(quoted)
+	again, err := Load[withList](env(map[string]string{"UPSTREAMS": v}), WithArgs(nil))
+	if err != nil {
+		t.Fatalf("reload %q from %s: %v", v, dump, err)
+	}
+	if !reflect.DeepEqual(again.Upstreams, want) {
+		t.Errorf("round trip through %q: %+v", v, again.Upstreams)
+	}
+
+	bad := writeFile(t, "upstreams:\n  - name: api\n    timeuot: 5s\n")
+	if _, err := Load[withList](WithFile(bad), env(nil), WithArgs(nil)); err == nil || !strings.Contains(err.Error(), "timeuot") {
+		t.Errorf("unknown nested key: %v", err)
+	}
+}
+
+func TestValues(t *testing.T) {
+	u, _ := url.Parse("postgres://app:s3cret@db/admissions")
+	got := Values(&testConfig{Database: u, Token: "hunter2"})
+	if got["database_url"] != "postgres://app:xxxxx@db/admissions" || got["token"] != Redacted {
+		t.Errorf("Values = %v, want the password masked and the token redacted", got)
+	}
+	if _, ok := got["shutdown_grace"]; !ok {
+		t.Errorf("Values = %v, want every field", got)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces secret values in Dump output.
+const Redacted = "[redacted]"
+
+// Dump renders cfg, a struct or pointer to one as returned by Load, as
+// space-separated key=value pairs in field order, suitable for logging at
+// startup. Fields tagged secret show Redacted when set, and URL passwords
+// are masked wherever they appear.
+func Dump(cfg any) string {
+	pairs := dump(cfg)
+	var b strings.Builder
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		val := p[1]
+		if val == "" || strings.ContainsAny(val, " \t\n\"=") {
+			val = strconv.Quote(val)
+		}
+		b.WriteString(p[0])
+		b.WriteByte('=')
+		b.WriteString(val)
+	}
+	return b.String()
+}
+
+// Values is Dump as a map from key to unquoted value, for serving as
+// JSON.
+func Values(cfg any) map[string]string {
+	pairs := dump(cfg)
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		out[p[0]] = p[1]
+	}
+	return out
+}
+
+// dump returns the key and redacted value of every field of cfg, in
+// field order.
+func dump(cfg any) [][2]string {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	fields, err := collect(v, nil, "", "_")
+	if err != nil {
+		return nil
+	}
+	pairs := make([][2]string, len(fields))
+	for i, f := range fields {
+		val := format(f.v)
+		if f.secret && !f.v.IsZero() {
+			val = Redacted
+		}
+		pairs[i] = [2]string{f.key, val}
+	}
+	return pairs
+}
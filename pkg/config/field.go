@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field is one tagged leaf of the configuration struct.
+type field struct {
+	key  string   // dotted path, e.g. db.url
+	path []string // file path, e.g. [db url]
+	env  string
+	flag string
+
+	def        string
+	hasDefault bool
+	usage      string
+	required   bool
+	secret     bool
+
+	v     reflect.Value
+	isSet bool
+}
+
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	urlType        = reflect.TypeOf(url.URL{})
+	urlPtrType     = reflect.TypeOf((*url.URL)(nil))
+	stringsType    = reflect.TypeOf([]string(nil))
+	unmarshalerTyp = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// collect walks the tagged fields of struct v, recursing into nested
+// structs, and checks every leaf has a supported type. sep joins the
+// nesting levels of environment variable names.
+func collect(v reflect.Value, path []string, prefix, sep string) ([]*field, error) {
+	var fields []*field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("config")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if name == "" {
+			return nil, fmt.Errorf("config: field %s.%s has no key", t, sf.Name)
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("config: field %s.%s is unexported", t, sf.Name)
+		}
+		p := append(append([]string{}, path...), name)
+		fv := v.Field(i)
+		if isNested(sf.Type) {
+			nested, err := collect(fv, p, prefix, sep)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+		if !supported(sf.Type) {
+			return nil, fmt.Errorf("config: field %s.%s has unsupported type %s", t, sf.Name, sf.Type)
+		}
+		joined := strings.Join(p, "_")
+		f := &field{
+			key:   strings.Join(p, "."),
+			path:  p,
+			env:   prefix + strings.ToUpper(strings.Join(p, sep)),
+			flag:  strings.ReplaceAll(joined, "_", "-"),
+			usage: sf.Tag.Get("usage"),
+			v:     fv,
+		}
+		f.def, f.hasDefault = sf.Tag.Lookup("default")
+		for _, flag := range strings.Split(flags, ",") {
+			switch flag {
+			case "":
+			case "required":
+				f.required = true
+			case "secret":
+				f.secret = true
+			default:
+				return nil, fmt.Errorf("config: field %s.%s: unknown tag option %q", t, sf.Name, flag)
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// isNested reports whether t is a struct holding its own tagged fields
+// rather than a value parsed from one string.
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != urlType && !reflect.PointerTo(t).Implements(unmarshalerTyp)
+}
+
+func supported(t reflect.Type) bool {
+	switch {
+	case t == durationType, t == urlType, t == urlPtrType, t == stringsType:
+		return true
+	case reflect.PointerTo(t).Implements(unmarshalerTyp), structured(t):
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// structured reports whether t is decoded as YAML rather than parsed from
+// a scalar: slices other than []string, and maps.
+func structured(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice && t != stringsType) || t.Kind() == reflect.Map
+}
+
+// set parses s into the field and marks it set.
+func (f *field) set(s string) error {
+	if err := parseInto(f.v, s); err != nil {
+		return err
+	}
+	f.isSet = true
+	return nil
+}
+
+// setList assigns file sequence items to a []string field.
+func (f *field) setList(items []string) {
+	f.v.Set(reflect.ValueOf(items))
+	f.isSet = true
+}
+
+func parseInto(v reflect.Value, s string) error {
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType, urlPtrType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if v.Type() == urlPtrType {
+			v.Set(reflect.ValueOf(u))
+		} else {
+			v.Set(reflect.ValueOf(*u))
+		}
+		return nil
+	case stringsType:
+		v.Set(reflect.ValueOf(splitList(s)))
+		return nil
+	}
+	if structured(v.Type()) {
+		return decodeYAML([]byte(s), v, true)
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// format renders v the way parseInto reads it back.
+func format(v reflect.Value) string {
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case urlType:
+		u := v.Interface().(url.URL)
+		return u.Redacted()
+	case urlPtrType:
+		if v.IsNil() {
+			return ""
+		}
+		return v.Interface().(*url.URL).Redacted()
+	case stringsType:
+		return strings.Join(v.Interface().([]string), ",")
+	}
+	if structured(v.Type()) {
+		if v.IsNil() {
+			return ""
+		}
+		return flowYAML(v.Interface())
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// flowYAML renders v as single-line flow YAML, e.g.
+// [{path_prefix: /api, timeout: 5s}].
+func flowYAML(v any) string {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	var flow func(*yaml.Node)
+	flow = func(n *yaml.Node) {
+		n.Style |= yaml.FlowStyle
+		for _, c := range n.Content {
+			flow(c)
+		}
+	}
+	flow(&n)
+	b, err := yaml.Marshal(&n)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile applies the YAML mapping in path to fields. Keys matching no
+// field are errors unless allowUnknown is set; they are reported in one
+// error rather than one at a time.
+func loadFile(path string, fields []*field, allowUnknown bool) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config: %w", err)}
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return []error{fmt.Errorf("config: parse %s: %w", path, err)}
+	}
+	byKey := map[string]*field{}
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+	var (
+		errs    []error
+		unknown []string
+	)
+	var walk func(n *yaml.Node, under []string)
+	walk = func(n *yaml.Node, under []string) {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := append(append([]string{}, under...), k.Value)
+			key := strings.Join(p, ".")
+			if f, ok := byKey[key]; ok {
+				if err := applyNode(f, v, !allowUnknown); err != nil {
+					errs = append(errs, fmt.Errorf("config: %s: line %d: %s: %w", path, v.Line, key, err))
+				}
+				continue
+			}
+			if v.Kind == yaml.MappingNode && hasPrefix(fields, p) {
+				walk(v, p)
+				continue
+			}
+			unknown = append(unknown, key)
+		}
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return []error{fmt.Errorf("config: %s: want a mapping at the top level", path)}
+	}
+	walk(root, nil)
+	if len(unknown) > 0 && !allowUnknown {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Errorf("config: %s: unknown keys: %s", path, strings.Join(unknown, ", ")))
+	}
+	return errs
+}
+
+// applyNode sets f from a scalar, from a sequence for []string fields, or
+// from any node for structured fields, whose unknown keys are errors when
+// strict. Null values leave the field as it was.
+func applyNode(f *field, n *yaml.Node, strict bool) error {
+	if structured(f.v.Type()) {
+		if n.Tag == "!!null" {
+			return nil
+		}
+		data, err := yaml.Marshal(n)
+		if err != nil {
+			return err
+		}
+		if err := decodeYAML(data, f.v, strict); err != nil {
+			return err
+		}
+		f.isSet = true
+		return nil
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return nil
+		}
+		return f.set(n.Value)
+	case yaml.SequenceNode:
+		if f.v.Type() != stringsType {
+			return fmt.Errorf("got a list for a %s", f.v.Type())
+		}
+		items := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return errors.New("list items must be scalars")
+			}
+			items = append(items, item.Value)
+		}
+		f.setList(items)
+		return nil
+	}
+	return fmt.Errorf("got a mapping for a %s", f.v.Type())
+}
+
+// decodeYAML replaces v with the YAML document in data.
+func decodeYAML(data []byte, v reflect.Value, strict bool) error {
+	fresh := reflect.New(v.Type())
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(strict)
+	if err := dec.Decode(fresh.Interface()); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	v.Set(fresh.Elem())
+	return nil
+}
+
+// hasPrefix reports whether some field lives under path.
+func hasPrefix(fields []*field, path []string) bool {
+	for _, f := range fields {
+		if len(f.path) > len(path) && strings.Join(f.path[:len(path)], ".") == strings.Join(path, ".") {
+			return true
+		}
+	}
+	return false
+}
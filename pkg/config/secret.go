@@ -0,0 +1,27 @@
+package config
+
+import "log/slog"
+
+// Secret is a string that does not print. fmt, slog, encoding/json, and
+// YAML all render a set Secret as Redacted, so a config struct holding
+// one can be logged whole; an empty one renders empty, so a missing
+// secret still shows. Convert with string(s) where the value is used.
+type Secret string
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer, for %#v.
+func (s Secret) GoString() string { return `"` + s.String() + `"` }
+
+// LogValue implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value { return slog.StringValue(s.String()) }
+
+// MarshalText implements encoding.TextMarshaler, which encoding/json and
+// YAML encoders use.
+func (s Secret) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
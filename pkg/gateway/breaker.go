@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+)
+
+// Breaker defaults for the fields a Breaker leaves zero.
+const (
+	DefaultBreakerFailures = 5
+	DefaultBreakerWindow   = 10 * time.Second
+	DefaultBreakerCooldown = 30 * time.Second
+	DefaultBreakerProbes   = 1
+)
+
+// Breaker is the circuit breaker policy of a route's upstream host. The
+// breaker opens after Failures consecutive failed requests (a 5xx answer
+// or no answer at all) within Window, and while open the gateway answers
+// 503 at once instead of waiting on the upstream. After Cooldown it is
+// half-open: Probes requests go through, and if they all succeed it
+// closes, while any failure opens it again.
+//
+// State is kept per upstream host and survives Reload, so routes sharing
+// a host share one breaker and must agree on its policy.
+type Breaker struct {
+	// Disabled turns the breaker off for the route's host.
+	Disabled bool `yaml:"disabled" json:"disabled,omitempty"`
+	// Failures opens the breaker; zero means DefaultBreakerFailures.
+	Failures int `yaml:"failures" json:"failures,omitempty"`
+	// Window is how long a streak of failures counts; one older than it
+	// starts over. Zero means DefaultBreakerWindow.
+	Window time.Duration `yaml:"window" json:"window,omitempty"`
+	// Cooldown is how long the breaker stays open; zero means
+	// DefaultBreakerCooldown.
+	Cooldown time.Duration `yaml:"cooldown" json:"cooldown,omitempty"`
+	// Probes is how many requests a half-open breaker lets through;
+	// zero means DefaultBreakerProbes.
+	Probes int `yaml:"probes" json:"probes,omitempty"`
+}
+
+// IsZero reports whether b is unset.
+func (b Breaker) IsZero() bool {
+	return b == Breaker{}
+}
+
+// Validate reports a negative field.
+func (b Breaker) Validate() error {
+	var errs []error
+	if b.Failures < 0 {
+		errs = append(errs, fmt.Errorf("failures %d: must not be negative", b.Failures))
+	}
+	if b.Window < 0 {
+		errs = append(errs, fmt.Errorf("window %s: must not be negative", b.Window))
+	}
+	if b.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("cooldown %s: must not be negative", b.Cooldown))
+	}
+	if b.Probes < 0 {
+		errs = append(errs, fmt.Errorf("probes %d: must not be negative", b.Probes))
+	}
+	return errors.Join(errs...)
+}
+
+// withDefaults fills the zero fields of b.
+func (b Breaker) withDefaults() Breaker {
+	if b.Failures == 0 {
+		b.Failures = DefaultBreakerFailures
+	}
+	if b.Window == 0 {
+		b.Window = DefaultBreakerWindow
+	}
+	if b.Cooldown == 0 {
+		b.Cooldown = DefaultBreakerCooldown
+	}
+	if b.Probes == 0 {
+		b.Probes = DefaultBreakerProbes
+	}
+	return b
+}
+
+// policy is b, with the zero fields filled, as a circuit.Policy.
+func (b Breaker) policy() circuit.Policy {
+	b = b.withDefaults()
+	return circuit.Policy{FailureThreshold: b.Failures, Window: b.Window, SuccessThreshold: b.Probes, Timeout: b.Cooldown}
+}
+
+// BreakerState is the state of an upstream's circuit breaker.
+type BreakerState = circuit.State
+
+const (
+	BreakerClosed   = circuit.Closed
+	BreakerHalfOpen = circuit.HalfOpen
+	BreakerOpen     = circuit.Open
+)
+
+// newCircuit returns the breaker of upstream host, reporting its
+// transitions to notify. Its policy is set when a table using it starts
+// serving (see Router.swap), so a reload changing the policy applies from
+// the next request without resetting the state, and a reload that fails
+// changes nothing.
+func newCircuit(host string, notify func(host string, from, to BreakerState)) *circuit.Breaker {
+	return &circuit.Breaker{
+		Name:          "upstream " + host,
+		OnStateChange: func(from, to BreakerState) { notify(host, from, to) },
+	}
+}
+
+// BreakerStatus is an upstream breaker as shown on the admin endpoint.
+type BreakerStatus struct {
+	Upstream string       `json:"upstream"`
+	State    BreakerState `json:"state"`
+	// Failures counts the current streak while closed.
+	Failures int `json:"failures"`
+	// OpenedAt is when the breaker last opened, if it is not closed.
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+func breakerStatus(host string, b *circuit.Breaker) BreakerStatus {
+	st := b.Status()
+	s := BreakerStatus{Upstream: host, State: st.State, Failures: st.Failures}
+	if st.State != BreakerClosed {
+		s.OpenedAt = &st.OpenedAt
+	}
+	return s
+}
+
+// breakerTransport runs a route's upstream requests through its host's
+// circuit. It sits outside the retry transport, so a request counts once
+// however many attempts it took.
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *circuit.Breaker
+}
+
+func newBreakerTransport(base http.RoundTripper, b *circuit.Breaker, p Breaker) http.RoundTripper {
+	if b == nil || p.Disabled {
+		return base
+	}
+	return &breakerTransport{base: base, breaker: b}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	call, err := t.breaker.Allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	o := circuit.Success
+	switch {
+	case err != nil && errors.Is(err, context.Canceled) && req.Context().Err() != nil && !deadlineExceeded(req.Context()):
+		o = circuit.Abandoned
+	case errors.As(err, new(*http.MaxBytesError)):
+		// The client's body was over the route's limit.
+		o = circuit.Abandoned
+	case err != nil, resp.StatusCode >= 500:
+		o = circuit.Failure
+	}
+	call.Done(o)
+	return resp, err
+}
+
+// breakerChanged logs and counts a breaker transition.
+func (rt *Router) breakerChanged(host string, from, to BreakerState) {
+	level := slog.LevelInfo
+	if to == BreakerOpen {
+		level = slog.LevelWarn
+	}
+	rt.logger().Log(context.Background(), level, "gateway circuit breaker "+string(to), "upstream", host, "from", from)
+	rt.stats.breakerState(host, to, true)
+}
+
+// Breakers returns the state of every upstream host's breaker, by host.
+func (rt *Router) Breakers() []BreakerStatus {
+	circuits := rt.table.Load().circuits
+	out := make([]BreakerStatus, 0, len(circuits))
+	for host, b := range circuits {
+		out = append(out, breakerStatus(host, b))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Upstream < out[j].Upstream })
+	return out
+}
+
+// ResetBreaker closes the breaker of upstream host, as named in
+// Breakers, and reports whether there is one.
+func (rt *Router) ResetBreaker(host string) bool {
+	b, ok := rt.table.Load().circuits[host]
+	if ok {
+		b.Reset()
+	}
+	return ok
+}
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+)
+
+// gate is an upstream answering status, blocking requests while held.
+type gate struct {
+	*httptest.Server
+	status  atomic.Int32
+	hits    atomic.Int32
+	mu      sync.Mutex
+	release chan struct{}
+}
+
+func newGate(t *testing.T, status int) *gate {
+	t.Helper()
+	g := &gate{}
+	g.status.Store(int32(status))
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.hits.Add(1)
+		g.mu.Lock()
+		release := g.release
+		g.mu.Unlock()
+		if release != nil {
+			<-release
+		}
+		w.WriteHeader(int(g.status.Load()))
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// send serves a GET of path without decoding the answer.
+func send(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func (g *gate) hold() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.release = make(chan struct{})
+}
+
+func (g *gate) open() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	close(g.release)
+	g.release = nil
+}
+
+func TestRouterBreaker(t *testing.T) {
+	up := newGate(t, http.StatusInternalServerError)
+	host := strings.TrimPrefix(up.URL, "http://")
+	m := metrics.New(metrics.Options{})
+	policy := Breaker{Failures: 3, Cooldown: 50 * time.Millisecond}
+	rt, err := New([]Route{
+		{Name: "a", PathPrefix: "/a", Upstream: up.URL, Breaker: policy},
+		{Name: "b", PathPrefix: "/b", Upstream: up.URL + "/b", Breaker: policy},
+	}, WithoutAccessLog(), WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	rt.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+
+	for range 3 {
+		if rec := send(rt, "/a/x"); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("closed breaker: got %d, want the upstream's 500", rec.Code)
+		}
+	}
+	for _, path := range []string{"/a/x", "/b/x"} {
+		rec := send(rt, path)
+		var body struct{ Code string }
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusServiceUnavailable || body.Code != "CIRCUIT_OPEN" || rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("%s with the breaker open: %d %s, Retry-After %q", path, rec.Code, rec.Body, rec.Header().Get("Retry-After"))
+		}
+	}
+	if n := up.hits.Load(); n != 3 {
+		t.Errorf("upstream saw %d requests, want 3", n)
+	}
+	if !strings.Contains(logs.String(), `"msg":"gateway circuit breaker open"`) {
+		t.Errorf("opening not logged:\n%s", logs.String())
+	}
+
+	// A reload keeps the state of a host it still routes to.
+	if err := rt.Reload([]Route{{Name: "a", PathPrefix: "/a", Upstream: up.URL, Breaker: policy}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.Breakers(); len(got) != 1 || got[0].Upstream != host || got[0].State != BreakerOpen || got[0].OpenedAt == nil {
+		t.Fatalf("breakers after reload = %+v", got)
+	}
+
+	up.status.Store(http.StatusOK)
+	time.Sleep(policy.Cooldown)
+	if rec := send(rt, "/a/x"); rec.Code != http.StatusOK {
+		t.Fatalf("probe after the cooldown: got %d", rec.Code)
+	}
+	if got := rt.Breakers(); got[0].State != BreakerClosed {
+		t.Errorf("breaker %s after a successful probe", got[0].State)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`gateway_circuit_state{upstream="` + host + `"} 0`,
+		`gateway_circuit_transitions_total{state="open",upstream="` + host + `"} 1`,
+		`gateway_circuit_transitions_total{state="half_open",upstream="` + host + `"} 1`,
+		`gateway_circuit_transitions_total{state="closed",upstream="` + host + `"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
+
+// TestRouterBreakerProbeRace fires a burst of requests the moment the
+// cooldown ends; only the configured probes may reach the upstream.
+func TestRouterBreakerProbeRace(t *testing.T) {
+	up := newGate(t, http.StatusBadGateway)
+	policy := Breaker{Failures: 1, Cooldown: 30 * time.Millisecond, Probes: 2}
+	rt, err := New([]Route{{PathPrefix: "/", Upstream: up.URL, Breaker: policy}}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	send(rt, "/x")
+	up.hits.Store(0)
+	up.status.Store(http.StatusOK)
+	up.hold()
+	time.Sleep(policy.Cooldown)
+
+	const burst = 50
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses = map[int]int{}
+	)
+	for range burst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := send(rt, "/x")
+			mu.Lock()
+			statuses[rec.Code]++
+			mu.Unlock()
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		refused := statuses[http.StatusServiceUnavailable]
+		mu.Unlock()
+		if refused == burst-policy.Probes || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	up.open()
+	wg.Wait()
+	if n := up.hits.Load(); n != int32(policy.Probes) {
+		t.Errorf("upstream saw %d requests during half-open, want %d", n, policy.Probes)
+	}
+	if statuses[http.StatusOK] != policy.Probes || statuses[http.StatusServiceUnavailable] != burst-policy.Probes {
+		t.Errorf("statuses = %v", statuses)
+	}
+	if got := rt.Breakers(); got[0].State != BreakerClosed {
+		t.Errorf("breaker %s after the probes succeeded", got[0].State)
+	}
+}
+
+func TestRouterBreakerOptions(t *testing.T) {
+	up := newGate(t, http.StatusServiceUnavailable)
+	rt, err := New([]Route{{PathPrefix: "/", Upstream: up.URL}},
+		WithoutAccessLog(), WithDefaults(Defaults{Breaker: Breaker{Disabled: true}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range DefaultBreakerFailures + 1 {
+		send(rt, "/x")
+	}
+	if n := up.hits.Load(); n != DefaultBreakerFailures+1 {
+		t.Errorf("disabled breaker: upstream saw %d of %d requests", n, DefaultBreakerFailures+1)
+	}
+
+	_, err = New([]Route{
+		{PathPrefix: "/a", Upstream: "http://api:8080"},
+		{PathPrefix: "/b", Upstream: "http://api:8080/b", Breaker: Breaker{Failures: 2}},
+		{PathPrefix: "/c", Upstream: "http://other:8080", Breaker: Breaker{Cooldown: -time.Second}},
+	})
+	for _, want := range []string{"route 1 (/b): breaker differs from route 0 (/a), which shares upstream api:8080", "route 2 (/c): breaker: cooldown -1s"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q: %v", want, err)
+		}
+	}
+}
+
+func TestAdminResetBreaker(t *testing.T) {
+	up := newGate(t, http.StatusBadGateway)
+	host := strings.TrimPrefix(up.URL, "http://")
+	rt, err := New([]Route{{PathPrefix: "/", Upstream: up.URL, Breaker: Breaker{Failures: 1, Cooldown: time.Hour}}}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	send(rt, "/x")
+
+	admin := func(method, query string) (*httptest.ResponseRecorder, []BreakerStatus) {
+		rec := httptest.NewRecorder()
+		rt.AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, "/admin/routes"+query, nil))
+		var body struct {
+			Breakers []BreakerStatus `json:"breakers"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body.Breakers
+	}
+	if _, got := admin("GET", ""); len(got) != 1 || got[0].Upstream != host || got[0].State != BreakerOpen {
+		t.Fatalf("breakers = %+v", got)
+	}
+	if rec, got := admin("POST", "?reset_breaker="+url.QueryEscape(host)); rec.Code != http.StatusOK || got[0].State != BreakerClosed {
+		t.Errorf("reset: %d, breakers %+v", rec.Code, got)
+	}
+	if rec := send(rt, "/x"); rec.Code != http.StatusBadGateway {
+		t.Errorf("request after reset: got %d, want the upstream's 502", rec.Code)
+	}
+	if rec, _ := admin("POST", "?reset_breaker=nowhere:80"); rec.Code != http.StatusNotFound {
+		t.Errorf("reset of an unknown host: got %d, want 404", rec.Code)
+	}
+	if rec, _ := admin("POST", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST without a host: got %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,524 @@
+package gateway
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+)
+
+// Cache defaults for the sizes a Cache or the Router leaves zero.
+const (
+	DefaultCacheMaxBytes = 64 << 20
+	DefaultCacheMaxBody  = 1 << 20
+)
+
+// CacheHeader is set to HIT or MISS on every GET a cached route answers.
+const CacheHeader = "X-Cache"
+
+// cacheKeyHeaders are always part of the cache key, so responses that
+// vary by content negotiation or compression are kept apart.
+var cacheKeyHeaders = []string{"Accept", "Accept-Encoding"}
+
+// Cache is a route's response cache policy. GET responses are kept in
+// the Router's in-memory LRU (see WithCacheSize) for TTL, keyed by path,
+// query, the Accept and Accept-Encoding headers, and Headers. A response
+// is not kept if the upstream says Cache-Control no-store, private, or
+// no-cache, sets a cookie, or varies by a header outside the key; a
+// shorter max-age or s-maxage shortens TTL, and a response to a request
+// with an Authorization header is kept only if marked public or with
+// s-maxage. Client Cache-Control is not honoured, so clients cannot
+// bypass the cache onto the upstream. Concurrent misses on one key share
+// a single upstream request.
+type Cache struct {
+	// TTL is how long a response is served from the cache; zero means
+	// the route is not cached.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+	// Headers are further request headers the response depends on, such
+	// as Accept-Language.
+	Headers []string `yaml:"headers" json:"headers,omitempty"`
+	// MaxBody is the largest response body kept; zero means
+	// DefaultCacheMaxBody. Larger responses are passed through.
+	MaxBody int64 `yaml:"max_body" json:"max_body,omitempty"`
+}
+
+// IsZero reports whether c is unset.
+func (c Cache) IsZero() bool {
+	return c.TTL == 0 && c.Headers == nil && c.MaxBody == 0
+}
+
+// Validate reports a negative field, a malformed header name, or
+// settings without a TTL to apply them to.
+func (c Cache) Validate() error {
+	var errs []error
+	if c.TTL < 0 {
+		errs = append(errs, fmt.Errorf("ttl %s: must not be negative", c.TTL))
+	}
+	if c.MaxBody < 0 {
+		errs = append(errs, fmt.Errorf("max_body %d: must not be negative", c.MaxBody))
+	}
+	for _, h := range c.Headers {
+		if !httpguts.ValidHeaderFieldName(h) {
+			errs = append(errs, fmt.Errorf("headers: %q is not a header name", h))
+		}
+	}
+	if c.TTL == 0 && !c.IsZero() {
+		errs = append(errs, errors.New("ttl is required to cache the route"))
+	}
+	return errors.Join(errs...)
+}
+
+// keyHeaders returns the canonical names of the request headers in c's
+// cache key, sorted.
+func (c Cache) keyHeaders() []string {
+	names := []string{}
+	for _, h := range append(slices.Clone(cacheKeyHeaders), c.Headers...) {
+		names = append(names, http.CanonicalHeaderKey(h))
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func (c Cache) maxBody() int64 {
+	if c.MaxBody > 0 {
+		return c.MaxBody
+	}
+	return DefaultCacheMaxBody
+}
+
+// cacheEntry is one stored response.
+type cacheEntry struct {
+	key     string
+	path    string // request path, for InvalidateCache
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	n := len(e.key) + len(e.path) + len(e.body)
+	for k, vs := range e.header {
+		for _, v := range vs {
+			n += len(k) + len(v)
+		}
+	}
+	return int64(n)
+}
+
+// write answers a request from e.
+func (e *cacheEntry) write(w http.ResponseWriter, now time.Time) {
+	h := w.Header()
+	for k, vs := range e.header {
+		h[k] = slices.Clone(vs)
+	}
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.stored).Seconds())))
+	h.Set(CacheHeader, "HIT")
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// flight is a miss being fetched. entry is set before done closes, or
+// left nil if the response could not be cached and each waiter must
+// fetch its own.
+type flight struct {
+	done  chan struct{}
+	entry *cacheEntry
+}
+
+// responseCache is an LRU of responses bounded by their total size. It
+// belongs to the Router, not a table, so entries survive Reload; the key
+// includes the route's prefix and upstream, so none outlives its route.
+type responseCache struct {
+	maxBytes int64
+	now      func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+	flights map[string]*flight
+}
+
+func newResponseCache(maxBytes int64) *responseCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	return &responseCache{
+		maxBytes: maxBytes,
+		now:      time.Now,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		flights:  map[string]*flight{},
+	}
+}
+
+// lookup returns the fresh entry under key or, on a miss, the flight
+// fetching it: a new one, which the caller must land, if leader.
+func (c *responseCache) lookup(key string) (e *cacheEntry, f *flight, leader bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if c.now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			return e, nil, false
+		}
+		c.remove(el)
+	}
+	if f, ok := c.flights[key]; ok {
+		return nil, f, false
+	}
+	f = &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	return nil, f, true
+}
+
+// land ends the flight for key, storing e if it is not nil.
+func (c *responseCache) land(key string, f *flight, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.flights, key)
+	f.entry = e
+	close(f.done)
+	if e == nil || e.size() > c.maxBytes {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += e.size()
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops el. c.mu must be held.
+func (c *responseCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+}
+
+// invalidate drops the entries for paths under prefix, matched at a
+// segment boundary as routes are, and returns how many there were.
+func (c *responseCache) invalidate(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if underPrefix(el.Value.(*cacheEntry).path, prefix) {
+			c.remove(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// CacheStats describes the Router's response cache.
+type CacheStats struct {
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+func (c *responseCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Entries: len(c.entries), Bytes: c.size, MaxBytes: c.maxBytes}
+}
+
+// WithCacheSize bounds the response cache shared by every cached route;
+// zero means DefaultCacheMaxBytes. The least recently used responses are
+// dropped to make room.
+func WithCacheSize(maxBytes int64) Option {
+	return func(c *options) { c.cacheBytes = maxBytes }
+}
+
+// InvalidateCache drops the cached responses for paths under prefix,
+// e.g. /catalog for /catalog and /catalog/programs but not /catalogs,
+// and returns how many it dropped. A prefix of / drops them all.
+func (rt *Router) InvalidateCache(prefix string) int {
+	return rt.cache.invalidate(prefix)
+}
+
+// CacheStats reports the response cache's size.
+func (rt *Router) CacheStats() CacheStats {
+	return rt.cache.stats()
+}
+
+// CacheHandler serves the response cache's size on GET and, on DELETE
+// with ?prefix=/path, drops the responses under that prefix, answering
+// {"removed": n}. Mount it where AdminHandler is.
+func (rt *Router) CacheHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			respond.JSON(w, http.StatusOK, rt.CacheStats())
+		case http.MethodDelete:
+			prefix := r.URL.Query().Get("prefix")
+			if !strings.HasPrefix(prefix, "/") {
+				respond.Error(w, http.StatusBadRequest, "BAD_REQUEST", "DELETE needs ?prefix=/path; / drops everything")
+				return
+			}
+			n := rt.InvalidateCache(prefix)
+			rt.logger().Info("gateway cache invalidated", "prefix", prefix, "removed", n)
+			respond.JSON(w, http.StatusOK, map[string]int{"removed": n})
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			respond.Error(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "use GET or DELETE")
+		}
+	})
+}
+
+// serveCached answers req on a cached route: from the cache, from a
+// concurrent request's response, or from the upstream, which the
+// response is then kept from.
+func (rt *Router) serveCached(w http.ResponseWriter, req *http.Request, r route) {
+	if !cacheableRequest(req) {
+		r.proxy.ServeHTTP(w, req)
+		return
+	}
+	key := r.cacheKey(req)
+	e, f, leader := rt.cache.lookup(key)
+	if leader {
+		rt.fill(w, req, r, key, f)
+		return
+	}
+	if e == nil {
+		select {
+		case <-f.done:
+			e = f.entry
+		case <-req.Context().Done():
+			return
+		}
+	}
+	rt.stats.cached(r.label(), e != nil)
+	if e != nil {
+		e.write(w, rt.cache.now())
+		return
+	}
+	w.Header().Set(CacheHeader, "MISS")
+	r.proxy.ServeHTTP(w, req)
+}
+
+// fill fetches a miss from the upstream, keeping the response if it may
+// be cached and releasing the requests waiting on f either way.
+func (rt *Router) fill(w http.ResponseWriter, req *http.Request, r route, key string, f *flight) {
+	rt.stats.cached(r.label(), false)
+	cw := &cacheWriter{w: w, req: req, policy: r.Cache, now: rt.cache.now}
+	cw.release = func() { rt.cache.land(key, f, nil) }
+	// An upstream failing mid-body makes the proxy panic; the waiters
+	// still need letting go.
+	defer func() {
+		if cw.release != nil {
+			cw.release()
+		}
+	}()
+	r.proxy.ServeHTTP(cw, req)
+	if !cw.buffering || req.Context().Err() != nil {
+		return
+	}
+	now := rt.cache.now()
+	rt.cache.land(key, f, &cacheEntry{key: key, path: req.URL.Path, status: cw.status, header: cw.header, body: cw.body, stored: now, expires: now.Add(cw.ttl)})
+	cw.release = nil
+	cw.flush()
+}
+
+// cacheKey identifies the response to req on r.
+func (r route) cacheKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.PathPrefix + "\x00" + r.Upstream + "\x00" + req.URL.RequestURI())
+	if r.PreserveHost {
+		b.WriteString("\x00" + req.Host)
+	}
+	for _, h := range r.Cache.keyHeaders() {
+		b.WriteString("\x00" + h + ":" + strings.Join(req.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// cacheableRequest reports whether req may be answered from the cache:
+// a plain GET, not a WebSocket upgrade or a range request.
+func cacheableRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.Header.Get("Upgrade") == "" && req.Header.Get("Range") == ""
+}
+
+// cacheableStatuses are the statuses RFC 9111 lets a cache keep without
+// explicit freshness.
+var cacheableStatuses = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// storable reports whether the response to req may be kept under
+// policy, and for how long from now.
+func storable(req *http.Request, status int, h http.Header, policy Cache, now time.Time) (time.Duration, bool) {
+	if !cacheableStatuses[status] || len(h.Values("Set-Cookie")) > 0 {
+		return 0, false
+	}
+	if strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		return 0, false
+	}
+	ttl := policy.TTL
+	cc := cacheControl(h)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["private"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return 0, false
+	}
+	_, public := cc["public"]
+	sMaxAge, shared := cc["s-maxage"]
+	if req.Header.Get("Authorization") != "" && !public && !shared {
+		return 0, false
+	}
+	age, hasAge := cc["max-age"]
+	if shared {
+		age, hasAge = sMaxAge, true
+	}
+	switch {
+	case hasAge:
+		secs, err := strconv.Atoi(age)
+		if err != nil {
+			return 0, false
+		}
+		ttl = min(ttl, time.Duration(secs)*time.Second)
+	case h.Get("Expires") != "":
+		t, err := http.ParseTime(h.Get("Expires"))
+		if err != nil {
+			return 0, false
+		}
+		ttl = min(ttl, t.Sub(now))
+	}
+	if ttl <= 0 {
+		return 0, false
+	}
+	keyed := policy.keyHeaders()
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" || name != "" && !slices.Contains(keyed, name) {
+				return 0, false
+			}
+		}
+	}
+	return ttl, true
+}
+
+// cacheControl parses a response's Cache-Control directives, lower-cased,
+// with any values unquoted.
+func cacheControl(h http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+// cacheWriter holds back the upstream's response so it can be kept,
+// passing it straight through from the moment it proves uncacheable: by
+// status or headers, or by outgrowing max.
+type cacheWriter struct {
+	w       http.ResponseWriter
+	req     *http.Request
+	policy  Cache
+	now     func() time.Time
+	release func() // lets the waiters go upstream themselves; nil once called
+
+	wroteHeader bool
+	buffering   bool
+	status      int
+	ttl         time.Duration
+	header      http.Header
+	body        []byte
+}
+
+func (cw *cacheWriter) Header() http.Header {
+	return cw.w.Header()
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		cw.w.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader, cw.status = true, status
+	h := cw.w.Header()
+	n, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	tooBig := err == nil && n > cw.policy.maxBody()
+	if ttl, ok := storable(cw.req, status, h, cw.policy, cw.now()); ok && !tooBig {
+		cw.buffering, cw.ttl, cw.header = true, ttl, h.Clone()
+		return
+	}
+	cw.passThrough()
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.buffering {
+		return cw.w.Write(b)
+	}
+	if int64(len(cw.body)+len(b)) > cw.policy.maxBody() {
+		cw.passThrough()
+		return cw.w.Write(b)
+	}
+	cw.body = append(cw.body, b...)
+	return len(b), nil
+}
+
+// Flush implements http.Flusher for streamed responses, which are never
+// buffered.
+func (cw *cacheWriter) Flush() {
+	if !cw.buffering {
+		http.NewResponseController(cw.w).Flush()
+	}
+}
+
+// passThrough gives up on keeping the response: the waiters are let go
+// and what is held back is written out.
+func (cw *cacheWriter) passThrough() {
+	if cw.release != nil {
+		cw.release()
+		cw.release = nil
+	}
+	cw.flush()
+}
+
+// flush writes out the status and body held back so far and stops
+// buffering.
+func (cw *cacheWriter) flush() {
+	cw.buffering = false
+	cw.w.Header().Set(CacheHeader, "MISS")
+	cw.w.WriteHeader(cw.status)
+	if len(cw.body) > 0 {
+		cw.w.Write(cw.body)
+	}
+	cw.body = nil
+}
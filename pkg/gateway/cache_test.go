@@ -0,0 +1,263 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+)
+
+// catalog is an upstream answering each path with the Cache-Control
+// (and other headers) given for it, and a body counting its calls.
+func catalog(t *testing.T, headers map[string]http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		for k, v := range headers[r.URL.Path] {
+			w.Header()[k] = v
+		}
+		fmt.Fprintf(w, "%s call %d accept=%s", r.URL.Path, n, r.Header.Get("Accept"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func get(h http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouterCache(t *testing.T) {
+	srv, calls := catalog(t, map[string]http.Header{
+		"/catalog/no-store": {"Cache-Control": {"no-store"}},
+		"/catalog/private":  {"Cache-Control": {"private, max-age=60"}},
+		"/catalog/stale":    {"Cache-Control": {"max-age=0"}},
+		"/catalog/short":    {"Cache-Control": {"public, max-age=10"}},
+		"/catalog/cookie":   {"Set-Cookie": {"session=1"}},
+		"/catalog/vary":     {"Vary": {"Cookie"}},
+		"/catalog/public":   {"Cache-Control": {"public"}},
+	})
+	m := metrics.New(metrics.Options{})
+	rt, err := New([]Route{
+		{Name: "catalog", PathPrefix: "/catalog", Upstream: srv.URL, Cache: Cache{TTL: time.Minute}},
+		{PathPrefix: "/live", Upstream: srv.URL},
+	}, WithoutAccessLog(), WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	rt.cache.now = func() time.Time { return now }
+
+	first := get(rt, "/catalog/programs?level=msc")
+	if first.Header().Get(CacheHeader) != "MISS" || first.Code != http.StatusOK {
+		t.Fatalf("first GET: %d %s %q", first.Code, first.Header().Get(CacheHeader), first.Body)
+	}
+	now = now.Add(3 * time.Second)
+	second := get(rt, "/catalog/programs?level=msc")
+	if second.Header().Get(CacheHeader) != "HIT" || second.Body.String() != first.Body.String() || second.Header().Get("Age") != "3" {
+		t.Errorf("second GET: %s age %s %q, want a HIT of %q", second.Header().Get(CacheHeader), second.Header().Get("Age"), second.Body, first.Body)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times for one cached response", n)
+	}
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"another query":  get(rt, "/catalog/programs?level=bsc"),
+		"another Accept": get(rt, "/catalog/programs?level=msc", "Accept", "text/csv"),
+	} {
+		if rec.Header().Get(CacheHeader) != "MISS" {
+			t.Errorf("%s: %s, want a MISS", name, rec.Header().Get(CacheHeader))
+		}
+	}
+
+	// Responses the upstream marks, or that depend on more than the
+	// key, are fetched every time.
+	for _, path := range []string{"/catalog/no-store", "/catalog/private", "/catalog/stale", "/catalog/cookie", "/catalog/vary"} {
+		before := calls.Load()
+		get(rt, path)
+		if rec := get(rt, path); rec.Header().Get(CacheHeader) != "MISS" || calls.Load() != before+2 {
+			t.Errorf("%s: second GET was a %s after %d upstream calls", path, rec.Header().Get(CacheHeader), calls.Load()-before)
+		}
+	}
+	// A request with credentials is only shared when the upstream says
+	// it may be.
+	for path, cached := range map[string]bool{"/catalog/credentials": false, "/catalog/public": true} {
+		get(rt, path, "Authorization", "Bearer t")
+		if hit := get(rt, path, "Authorization", "Bearer t").Header().Get(CacheHeader) == "HIT"; hit != cached {
+			t.Errorf("%s with Authorization: hit %v, want %v", path, hit, cached)
+		}
+	}
+
+	// A shorter max-age wins over the route's TTL, and expiry refetches.
+	get(rt, "/catalog/short")
+	now = now.Add(11 * time.Second)
+	if rec := get(rt, "/catalog/short"); rec.Header().Get(CacheHeader) != "MISS" {
+		t.Errorf("after max-age: %s", rec.Header().Get(CacheHeader))
+	}
+	now = now.Add(time.Minute)
+	if rec := get(rt, "/catalog/programs?level=msc"); rec.Header().Get(CacheHeader) != "MISS" {
+		t.Errorf("after the TTL: %s", rec.Header().Get(CacheHeader))
+	}
+
+	// Other methods and uncached routes pass straight through.
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("POST", "/catalog/programs", nil))
+	if rec.Header().Get(CacheHeader) != "" || get(rt, "/live/x").Header().Get(CacheHeader) != "" {
+		t.Error("X-Cache set outside a cached GET")
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`gateway_cache_lookups_total{result="hit",route="catalog"} 2`,
+		`gateway_cache_hit_ratio{route="catalog"}`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics lack %q", want)
+		}
+	}
+}
+
+func TestRouterCacheCoalescesMisses(t *testing.T) {
+	var calls atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(arrived)
+		}
+		<-release
+		if r.URL.Path == "/catalog/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		fmt.Fprint(w, "programs")
+	}))
+	defer srv.Close()
+	rt, err := New([]Route{{PathPrefix: "/catalog", Upstream: srv.URL, Cache: Cache{TTL: time.Minute}}}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path      string
+		wantCalls int32
+	}{
+		{"/catalog/programs", 1},
+		// What cannot be kept is not shared either: each waiter fetches
+		// its own once the first response proves uncacheable.
+		{"/catalog/no-store", 8},
+	} {
+		calls.Store(0)
+		arrived, release = make(chan struct{}), make(chan struct{})
+		var wg sync.WaitGroup
+		results := make(chan *httptest.ResponseRecorder, 8)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- get(rt, tc.path)
+			}()
+		}
+		<-arrived
+		time.Sleep(50 * time.Millisecond) // let the others queue behind the first
+		close(release)
+		wg.Wait()
+		close(results)
+		hits := 0
+		for rec := range results {
+			if rec.Code != http.StatusOK || rec.Body.String() != "programs" {
+				t.Errorf("%s: %d %q", tc.path, rec.Code, rec.Body)
+			}
+			if rec.Header().Get(CacheHeader) == "HIT" {
+				hits++
+			}
+		}
+		if n := calls.Load(); n != tc.wantCalls {
+			t.Errorf("%s: 8 concurrent GETs made %d upstream calls, want %d", tc.path, n, tc.wantCalls)
+		}
+		if tc.wantCalls == 1 && hits != 7 {
+			t.Errorf("%s: %d hits, want 7", tc.path, hits)
+		}
+	}
+}
+
+func TestRouterCacheLimits(t *testing.T) {
+	big := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/catalog/big" {
+			w.(http.Flusher).Flush() // no Content-Length: found too big while buffering
+			fmt.Fprint(w, big)
+			return
+		}
+		fmt.Fprint(w, strings.Repeat("y", 300))
+	}))
+	defer srv.Close()
+	rt, err := New([]Route{{PathPrefix: "/catalog", Upstream: srv.URL, Cache: Cache{TTL: time.Minute, MaxBody: 1024}}}, WithoutAccessLog(), WithCacheSize(1200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if rec := get(rt, "/catalog/big"); rec.Body.String() != big || rec.Header().Get(CacheHeader) != "MISS" {
+			t.Fatalf("oversized body: %s, %d bytes", rec.Header().Get(CacheHeader), rec.Body.Len())
+		}
+	}
+	for _, p := range []string{"/catalog/a", "/catalog/b", "/catalog/c"} {
+		get(rt, p)
+	}
+	st := rt.CacheStats()
+	if st.Entries != 2 || st.Bytes > st.MaxBytes {
+		t.Errorf("stats = %+v, want the oldest of three entries evicted", st)
+	}
+	if get(rt, "/catalog/a").Header().Get(CacheHeader) != "MISS" || get(rt, "/catalog/c").Header().Get(CacheHeader) != "HIT" {
+		t.Error("eviction did not drop the least recently used entry")
+	}
+}
+
+func TestCacheHandler(t *testing.T) {
+	srv, _ := catalog(t, nil)
+	rt, err := New([]Route{
+		{PathPrefix: "/catalog", Upstream: srv.URL, Cache: Cache{TTL: time.Minute}},
+		{PathPrefix: "/catalogs", Upstream: srv.URL, Cache: Cache{TTL: time.Minute}},
+	}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/catalog", "/catalog/programs", "/catalogs/x"} {
+		get(rt, p)
+	}
+	h := rt.CacheHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/admin/cache?prefix=/catalog", nil))
+	var body struct{ Removed int }
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.Removed != 2 {
+		t.Errorf("DELETE: %d %s, want 2 removed", rec.Code, rec.Body)
+	}
+	if get(rt, "/catalog/programs").Header().Get(CacheHeader) != "MISS" || get(rt, "/catalogs/x").Header().Get(CacheHeader) != "HIT" {
+		t.Error("invalidation did not stop at the segment boundary")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/admin/cache", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("DELETE without a prefix: %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/cache", nil))
+	var st CacheStats
+	json.Unmarshal(rec.Body.Bytes(), &st)
+	if st.Entries != 2 || st.MaxBytes != DefaultCacheMaxBytes {
+		t.Errorf("GET = %s", rec.Body)
+	}
+}
@@ -0,0 +1,717 @@
+// Package gateway is the entrance app's reverse-proxy core: it forwards
+// each request to the upstream of the longest matching path prefix.
+//
+// Routes load through pkg/config like any other setting:
+//
+//	type Config struct {
+//		Routes []gateway.Route `config:"routes,required"`
+//	}
+//
+// with a CONFIG_FILE such as
+//
+//	routes:
+//	  - path_prefix: /v1/applications
+//	    upstream: http://admissions-api:8080
+//	    timeout: 10s
+//	  - path_prefix: /v1/health
+//	    upstream: http://admissions-api:8080
+//	    auth: none
+//	  - path_prefix: /workflows
+//	    upstream: http://workflow-platform-api:8791
+//	    strip_prefix: true
+//	    rate_limit: {rate: 5, burst: 20}
+//	    retry: {attempts: 3, on_statuses: [502, 503], backoff: 50ms}
+//	    breaker: {failures: 10, cooldown: 1m}
+//	  - path_prefix: /catalog
+//	    upstream: http://catalog:8080
+//	    cache: {ttl: 5m, headers: [Accept-Language]}
+//	  - path_prefix: /documents
+//	    upstream: https://documents.internal:8443
+//	    tls: {ca_file: /etc/uniassist/ca.pem, cert_file: /etc/uniassist/tls/tls.crt, key_file: /etc/uniassist/tls/tls.key}
+//	  - path_prefix: /uniassist.matching.v1.Matcher
+//	    upstream: http://matching:9090
+//	    protocol: grpc-web
+//
+// Every request is access-logged through pkg/middleware/logging unless
+// the Router is built WithoutAccessLog. Routes with a rate_limit are
+// limited per client by pkg/middleware/ratelimit; see WithRateLimit. A
+// Router built WithAuth checks Bearer tokens on every route whose auth is
+// not none; see pkg/auth.
+//
+// Each upstream host has a circuit breaker: after consecutive failures it
+// answers 503 CIRCUIT_OPEN without trying the upstream until a cooldown
+// has passed and probe requests succeed. See Breaker.
+//
+// A route's timeout covers every retry its retry policy allows; when it
+// runs out the client gets a JSON 504, counted per route in
+// gateway_upstream_timeouts_total if the Router is built WithMetrics.
+// WithDefaults sets the timeout, idle connection timeout, retry policy,
+// breaker policy, and body limit of routes that leave them unset.
+//
+// Each upstream has a connection pool of its own, built from its route's
+// tls settings, which can present a client certificate for mutual TLS;
+// VerifyUpstreams dials every upstream once to catch a bad certificate
+// or CA before traffic does.
+//
+// A route's max_request_body_bytes answers longer bodies 413
+// PAYLOAD_TOO_LARGE, without reading past the limit.
+//
+// A route with a cors policy has its OPTIONS preflights answered by the
+// gateway, ahead of auth and rate limiting, and the CORS headers of its
+// responses set by it; see pkg/middleware/cors. Routes without one pass
+// both through to the upstream.
+//
+// Routes with a cache keep their GET responses in memory, shared by
+// every cached route up to WithCacheSize, and answer with X-Cache: HIT or
+// MISS; see Cache. CacheHandler drops them by path prefix.
+//
+// WebSocket upgrades are spliced through to the upstream after its 101,
+// and text/event-stream responses are flushed to the client write by
+// write; the access log and metrics middleware pass Hijack and Flush on.
+//
+// Routes with protocol grpc or grpc-web proxy gRPC calls to an HTTP/2
+// upstream, trailers included and streams unbuffered, and grpc-web ones
+// also translate browser gRPC-Web calls; see Protocol. Their failures are
+// answered with a grpc-status rather than JSON.
+//
+// Router.WatchFile reloads them from that file on every edit or SIGHUP
+// without a restart; an invalid edit is rejected and the old table keeps
+// serving.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+	accesslog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// Route sends requests under PathPrefix to Upstream. A prefix matches at
+// a path segment boundary: /api matches /api and /api/x but not /apix.
+type Route struct {
+	// Name labels the route's metrics; empty means PathPrefix.
+	Name       string `yaml:"name"`
+	PathPrefix string `yaml:"path_prefix"`
+	// Upstream is an absolute http or https URL. A path on it is
+	// prepended to the forwarded request path.
+	Upstream string `yaml:"upstream"`
+	// StripPrefix removes PathPrefix before forwarding, so /api/x reaches
+	// the upstream as /x.
+	StripPrefix bool `yaml:"strip_prefix"`
+	// Timeout bounds each proxied request, body included; zero means no
+	// limit beyond the server's own. A WebSocket upgrade or an EventSource
+	// request is bounded only until the upstream answers it with a 101 or
+	// an event stream, which then lasts until either end closes it.
+	Timeout time.Duration `yaml:"timeout"`
+	// IdleConnTimeout closes the route's idle upstream connections after
+	// it; zero means the Router's default.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+	// Retry retries failed upstream requests; the zero Retry means the
+	// Router's default, which is none.
+	Retry Retry `yaml:"retry"`
+	// Breaker is the circuit breaker policy of the upstream host; the
+	// zero Breaker means the Router's default, which is on with the
+	// Default* thresholds.
+	Breaker Breaker `yaml:"breaker"`
+	// PreserveHost forwards the client's Host header instead of the
+	// upstream's.
+	PreserveHost bool `yaml:"preserve_host"`
+	// RateLimit budgets each client's requests to the route; the zero
+	// Rule means no limit.
+	RateLimit ratelimit.Rule `yaml:"rate_limit"`
+	// Auth is required, optional, or none. Empty means required on a
+	// Router built WithAuth and none otherwise; naming required or
+	// optional without WithAuth is an error.
+	Auth auth.Mode `yaml:"auth"`
+	// TLS configures the connection to an https upstream; the zero value
+	// verifies it against the system roots.
+	TLS UpstreamTLS `yaml:"tls"`
+	// Cache keeps the route's GET responses for a time; the zero Cache
+	// means none are kept.
+	Cache Cache `yaml:"cache"`
+	// MaxRequestBodyBytes answers requests with a longer body 413 instead
+	// of forwarding them; zero means the Router's default, which is no
+	// limit. A body without a Content-Length is cut off as it streams,
+	// so it is never held whole.
+	MaxRequestBodyBytes int64 `yaml:"max_request_body_bytes"`
+	// CORS answers the route's preflights at the gateway and sets the
+	// CORS headers of its responses, replacing the upstream's; the zero
+	// Policy leaves both to the upstream.
+	CORS cors.Policy `yaml:"cors"`
+	// Protocol is http, grpc, or grpc-web; empty means http. A grpc or
+	// grpc-web upstream is reached over HTTP/2 and must not carry a path,
+	// since gRPC addresses methods by it.
+	Protocol Protocol `yaml:"protocol"`
+}
+
+// Router is an http.Handler dispatching to one reverse proxy per route.
+// Its route table can be replaced while it serves; see Reload.
+type Router struct {
+	// Logger receives reload outcomes; nil means slog.Default().
+	Logger *slog.Logger
+
+	table      atomic.Pointer[table]
+	transport  *http.Transport                // the base each upstream's pool is cloned from
+	transports map[transportKey]*upstreamPool // by upstream and its settings; guarded by mu
+	defaults   Defaults
+	stats      *proxyMetrics // nil without WithMetrics
+	handler    http.Handler  // serve, behind the request ID, metrics, access log, rate limit, and auth
+	auth       bool          // built WithAuth
+	reserved   map[int]bool  // WithReservedPorts
+	cache      *responseCache
+
+	mu   sync.Mutex // serializes Reload
+	last atomic.Pointer[ReloadResult]
+}
+
+// table is an immutable route set. Reload builds a new one and swaps the
+// pointer, so a request matches against exactly one table.
+type table struct {
+	routes     []route                     // longest prefix first
+	source     []Route                     // as configured, for diffs and the admin view
+	circuits   map[string]*circuit.Breaker // by upstream host, carried over by Reload
+	policies   map[string]circuit.Policy   // of circuits, applied by swap
+	transports map[transportKey]bool
+}
+
+type route struct {
+	Route
+	proxy *httputil.ReverseProxy
+	cors  *cors.Rules
+}
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	accessLog   accesslog.Options
+	noAccessLog bool
+	requestID   requestid.Options
+	metrics     *metrics.HTTP
+	rateLimit   ratelimit.Options
+	auth        *auth.Options
+	defaults    Defaults
+	reserved    []int
+	cacheBytes  int64
+}
+
+// Defaults apply to every route that leaves the field unset.
+type Defaults struct {
+	Timeout             time.Duration
+	IdleConnTimeout     time.Duration
+	Retry               Retry
+	Breaker             Breaker
+	MaxRequestBodyBytes int64
+}
+
+// WithAccessLog configures the access log, e.g. with the trusted proxies
+// in front of the gateway or sampling for probe paths.
+func WithAccessLog(o accesslog.Options) Option {
+	return func(c *options) { c.accessLog = o }
+}
+
+// WithoutAccessLog turns the access log off, for a Router mounted behind
+// middleware that logs requests already.
+func WithoutAccessLog() Option {
+	return func(c *options) { c.noAccessLog = true }
+}
+
+// WithRequestID configures how requests are given the X-Request-ID the
+// gateway forwards upstream, e.g. to ignore IDs sent by untrusted
+// clients. Every request gets one, with or without the access log.
+func WithRequestID(o requestid.Options) Option {
+	return func(c *options) { c.requestID = o }
+}
+
+// WithMetrics records each request in m under its route's name, or
+// metrics.Unmatched when no route claims it. Serve m.Handler on a
+// listener of its own rather than through the gateway.
+func WithMetrics(m *metrics.HTTP) Option {
+	return func(c *options) { c.metrics = m }
+}
+
+// WithRateLimit configures how routes with a rate_limit are enforced:
+// the Limiter holding the buckets, the API-key header, and the trusted
+// proxies in front of the gateway whose X-Forwarded-For names the
+// client. Without it the buckets are kept in memory and clients are
+// keyed by X-API-Key or connection IP.
+func WithRateLimit(o ratelimit.Options) Option {
+	return func(c *options) { c.rateLimit = o }
+}
+
+// WithDefaults sets the timeout, idle connection timeout, retry policy,
+// circuit breaker policy, and request body limit of routes that leave
+// them zero.
+func WithDefaults(d Defaults) Option {
+	return func(c *options) { c.defaults = d }
+}
+
+// WithAuth validates Bearer tokens on every route whose auth is not
+// none, against o.Keys, which is normally an auth.KeySet whose Run
+// refreshes it in the background. Upstreams receive the token's subject
+// and scopes as X-User-ID and X-User-Scopes, and forged ones are
+// stripped on every route. Without it, a route naming required or
+// optional is rejected rather than served open.
+func WithAuth(o auth.Options) Option {
+	return func(c *options) { c.auth = &o }
+}
+
+// New validates routes and builds their proxies, so a bad upstream fails
+// at startup rather than on the first request. Every problem is reported
+// together.
+func New(routes []Route, opts ...Option) (*Router, error) {
+	var c options
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if err := c.defaults.validate(); err != nil {
+		return nil, err
+	}
+	rt := &Router{
+		transport:  http.DefaultTransport.(*http.Transport).Clone(),
+		transports: map[transportKey]*upstreamPool{},
+		defaults:   c.defaults,
+		auth:       c.auth != nil,
+		cache:      newResponseCache(c.cacheBytes),
+	}
+	if c.defaults.IdleConnTimeout > 0 {
+		rt.transport.IdleConnTimeout = c.defaults.IdleConnTimeout
+	}
+	if len(c.reserved) > 0 {
+		rt.reserved = map[int]bool{}
+		for _, p := range c.reserved {
+			rt.reserved[p] = true
+		}
+		rt.guardReserved()
+	}
+	if c.metrics != nil {
+		var err error
+		if rt.stats, err = newProxyMetrics(c.metrics.Registry); err != nil {
+			return nil, err
+		}
+	}
+	t, err := rt.build(routes)
+	if err != nil {
+		return nil, err
+	}
+	rt.swap(t)
+	rt.handler = http.HandlerFunc(rt.serve)
+	if c.auth != nil {
+		authenticate, err := auth.New(*c.auth, rt.authMode)
+		if err != nil {
+			return nil, fmt.Errorf("gateway: %w", err)
+		}
+		rt.handler = authenticate(rt.handler)
+	}
+	rt.handler = ratelimit.New(c.rateLimit, rt.rateRule)(rt.handler)
+	// Outside auth and the rate limit, so preflights, which carry no
+	// credentials, are answered, and browsers can read 401s and 429s.
+	rt.handler = cors.New(rt.corsRules)(rt.handler)
+	if !c.noAccessLog {
+		rt.handler = accesslog.New(c.accessLog)(rt.handler)
+	}
+	if c.metrics != nil {
+		rt.handler = c.metrics.Middleware(rt.routeName)(rt.handler)
+	}
+	rt.handler = requestid.New(c.requestID)(rt.handler)
+	return rt, nil
+}
+
+func (rt *Router) build(routes []Route) (*table, error) {
+	var (
+		t        = &table{source: slices.Clone(routes), circuits: map[string]*circuit.Breaker{}, policies: map[string]circuit.Policy{}, transports: map[transportKey]bool{}}
+		errs     []error
+		seen     = map[string]bool{}
+		breakers = map[string]int{} // upstream host -> first route sending to it
+		prev     = rt.table.Load()
+	)
+	for i, r := range routes {
+		target, err := r.validate()
+		switch {
+		case err != nil:
+		case seen[r.PathPrefix]:
+			err = errors.New("duplicate path prefix")
+		case !rt.auth && (r.Auth == auth.Required || r.Auth == auth.Optional):
+			err = fmt.Errorf("auth %s: the gateway has no token validation configured (WithAuth)", r.Auth)
+		default:
+			err = rt.checkReserved(target)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gateway: route %d (%s): %w", i, r.PathPrefix, err))
+			continue
+		}
+		seen[r.PathPrefix] = true
+		r = rt.defaults.apply(r)
+		pool, key, err := rt.transportFor(r, target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gateway: route %d (%s): %w", i, r.PathPrefix, err))
+			continue
+		}
+		t.transports[key] = true
+		host := target.Host
+		if j, ok := breakers[host]; !ok {
+			breakers[host] = i
+		} else if other := rt.defaults.apply(routes[j]); other.Breaker != r.Breaker {
+			errs = append(errs, fmt.Errorf("gateway: route %d (%s): breaker differs from route %d (%s), which shares upstream %s", i, r.PathPrefix, j, other.PathPrefix, host))
+			continue
+		}
+		c, ok := t.circuits[host]
+		if !ok && prev != nil {
+			c, ok = prev.circuits[host]
+		}
+		if !ok {
+			c = newCircuit(host, rt.breakerChanged)
+		}
+		t.circuits[host], t.policies[host] = c, r.Breaker.policy()
+		transport := newRetryTransport(pool, r.Retry, rt.stats.retried(r.label()))
+		transport = newBreakerTransport(transport, c, r.Breaker)
+		rules, _ := cors.Compile(r.CORS) // validated above
+		t.routes = append(t.routes, route{Route: r, proxy: newProxy(r, target, transport, rt.stats.timedOut(r.label())), cors: rules})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(t.routes, func(a, b int) bool {
+		return len(t.routes[a].PathPrefix) > len(t.routes[b].PathPrefix)
+	})
+	return t, nil
+}
+
+func (r Route) validate() (*url.URL, error) {
+	if !strings.HasPrefix(r.PathPrefix, "/") {
+		return nil, errors.New("path prefix must start with /")
+	}
+	if r.Timeout < 0 {
+		return nil, errors.New("timeout must not be negative")
+	}
+	if r.IdleConnTimeout < 0 {
+		return nil, errors.New("idle_conn_timeout must not be negative")
+	}
+	if r.MaxRequestBodyBytes < 0 {
+		return nil, errors.New("max_request_body_bytes must not be negative")
+	}
+	if err := r.Retry.Validate(); err != nil {
+		return nil, fmt.Errorf("retry: %w", err)
+	}
+	if err := r.Breaker.Validate(); err != nil {
+		return nil, fmt.Errorf("breaker: %w", err)
+	}
+	if err := r.RateLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("rate_limit: %w", err)
+	}
+	if err := r.Auth.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.TLS.Validate(); err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	if err := r.Cache.Validate(); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	if err := r.CORS.Validate(); err != nil {
+		return nil, fmt.Errorf("cors: %w", err)
+	}
+	if err := r.Protocol.Validate(); err != nil {
+		return nil, err
+	}
+	target, err := url.Parse(r.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("upstream: %w", err)
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("upstream %q: want an absolute http or https URL", r.Upstream)
+	}
+	if target.RawQuery != "" || target.Fragment != "" {
+		return nil, fmt.Errorf("upstream %q: must not carry a query or fragment", r.Upstream)
+	}
+	if !r.TLS.IsZero() && target.Scheme != "https" {
+		return nil, fmt.Errorf("tls: upstream %q is not https", r.Upstream)
+	}
+	if r.Protocol.grpc() {
+		if target.Path != "" && target.Path != "/" {
+			return nil, fmt.Errorf("protocol %s: upstream %q must not carry a path", r.Protocol, r.Upstream)
+		}
+		if r.Cache.TTL > 0 {
+			return nil, fmt.Errorf("protocol %s: gRPC calls cannot be cached", r.Protocol)
+		}
+	}
+	return target, nil
+}
+
+// label names r in metrics: its Name, or its PathPrefix without one.
+func (r Route) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.PathPrefix
+}
+
+func (d Defaults) validate() error {
+	var errs []error
+	if d.Timeout < 0 {
+		errs = append(errs, errors.New("gateway: default timeout must not be negative"))
+	}
+	if d.IdleConnTimeout < 0 {
+		errs = append(errs, errors.New("gateway: default idle_conn_timeout must not be negative"))
+	}
+	if d.MaxRequestBodyBytes < 0 {
+		errs = append(errs, errors.New("gateway: default max_request_body_bytes must not be negative"))
+	}
+	if err := d.Retry.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("gateway: default retry: %w", err))
+	}
+	if err := d.Breaker.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("gateway: default breaker: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// apply fills the fields r leaves zero from d.
+func (d Defaults) apply(r Route) Route {
+	if r.Timeout == 0 {
+		r.Timeout = d.Timeout
+	}
+	if r.IdleConnTimeout == 0 {
+		r.IdleConnTimeout = d.IdleConnTimeout
+	}
+	if r.Retry.IsZero() {
+		r.Retry = d.Retry
+	}
+	if r.Breaker.IsZero() {
+		r.Breaker = d.Breaker
+	}
+	if r.MaxRequestBodyBytes == 0 {
+		r.MaxRequestBodyBytes = d.MaxRequestBodyBytes
+	}
+	return r
+}
+
+func newProxy(r Route, target *url.URL, transport http.RoundTripper, timedOut func()) *httputil.ReverseProxy {
+	modify := liftTimeout
+	if r.Protocol == ProtocolGRPCWeb {
+		modify = func(resp *http.Response) error {
+			if err := toGRPCWeb(resp); err != nil {
+				return err
+			}
+			return liftTimeout(resp)
+		}
+	}
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if r.StripPrefix {
+				stripPrefix(pr.Out.URL, r.PathPrefix)
+			}
+			pr.SetURL(target)
+			if r.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			setForwarded(pr)
+			if r.Protocol == ProtocolGRPCWeb {
+				pr.Out = fromGRPCWeb(pr.Out)
+			}
+		},
+		Transport:      transport,
+		ModifyResponse: modify,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			proxyError(w, req, err, timedOut, r.failure(req))
+		},
+	}
+}
+
+// setForwarded appends the client to X-Forwarded-For and keeps the
+// X-Forwarded-Proto and -Host set by a proxy in front, deriving them from
+// this hop only when absent.
+func setForwarded(pr *httputil.ProxyRequest) {
+	in := pr.In.Header
+	pr.Out.Header["X-Forwarded-For"] = in["X-Forwarded-For"]
+	pr.SetXForwarded()
+	if proto := in.Get("X-Forwarded-Proto"); proto != "" {
+		pr.Out.Header.Set("X-Forwarded-Proto", proto)
+	}
+	if host := in.Get("X-Forwarded-Host"); host != "" {
+		pr.Out.Header.Set("X-Forwarded-Host", host)
+	}
+}
+
+func stripPrefix(u *url.URL, prefix string) {
+	trim := func(p string) string {
+		p = strings.TrimPrefix(p, strings.TrimSuffix(prefix, "/"))
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		return p
+	}
+	u.Path = trim(u.Path)
+	if u.RawPath != "" {
+		u.RawPath = trim(u.RawPath)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rt.handler.ServeHTTP(w, req)
+}
+
+func (rt *Router) serve(w http.ResponseWriter, req *http.Request) {
+	r, ok := rt.table.Load().match(req.URL.Path)
+	if !ok {
+		respond.Error(w, http.StatusNotFound, "NOT_FOUND", "no route for "+req.URL.Path)
+		return
+	}
+	if max := r.MaxRequestBodyBytes; max > 0 {
+		// A declared length over the limit is refused before any of the
+		// body is read; an undeclared one fails the upstream request
+		// once it passes the limit, which proxyError answers 413.
+		if req.ContentLength > max {
+			bodyTooLarge(w, req, max, r.failure(req))
+			return
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = http.MaxBytesReader(w, req.Body, max)
+		}
+	}
+	if r.Timeout > 0 {
+		var done func()
+		req, done = withTimeout(req, r.Timeout)
+		defer done()
+	}
+	if r.Cache.TTL > 0 {
+		rt.serveCached(w, req, r)
+		return
+	}
+	r.proxy.ServeHTTP(w, req)
+}
+
+// routeName is the metrics label of the route req matches, or "" for
+// none.
+func (rt *Router) routeName(req *http.Request) string {
+	r, ok := rt.table.Load().match(req.URL.Path)
+	if !ok {
+		return ""
+	}
+	return r.label()
+}
+
+// rateRule is the rate limit of the route req matches, scoped by its
+// path prefix, which is unique in a table.
+func (rt *Router) rateRule(req *http.Request) (string, ratelimit.Rule, bool) {
+	r, ok := rt.table.Load().match(req.URL.Path)
+	if !ok {
+		return "", ratelimit.Rule{}, false
+	}
+	return r.PathPrefix, r.RateLimit, true
+}
+
+// authMode is the auth mode of the route req matches. Unmatched requests
+// need none: serve answers them 404 without reaching an upstream.
+func (rt *Router) authMode(req *http.Request) auth.Mode {
+	r, ok := rt.table.Load().match(req.URL.Path)
+	if !ok {
+		return auth.None
+	}
+	return r.Auth
+}
+
+// corsRules are the CORS rules of the route req matches, nil for a route
+// without them or no route.
+func (rt *Router) corsRules(req *http.Request) *cors.Rules {
+	r, ok := rt.table.Load().match(req.URL.Path)
+	if !ok {
+		return nil
+	}
+	return r.cors
+}
+
+// match returns the route with the longest prefix matching path.
+func (t *table) match(path string) (route, bool) {
+	for _, r := range t.routes {
+		if underPrefix(path, r.PathPrefix) {
+			return r, true
+		}
+	}
+	return route{}, false
+}
+
+// underPrefix reports whether path is p or below it, at a path segment
+// boundary.
+func underPrefix(path, p string) bool {
+	return path == p || strings.HasPrefix(path, p) && (strings.HasSuffix(p, "/") || path[len(p)] == '/')
+}
+
+// proxyError answers upstream failures through answer, in the API's JSON
+// error shape or as gRPC statuses, instead of ReverseProxy's empty 502.
+func proxyError(w http.ResponseWriter, req *http.Request, err error, timedOut func(), answer failureFunc) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		bodyTooLarge(w, req, tooLarge.Limit, answer)
+		return
+	}
+	var open *circuit.OpenError
+	if errors.As(err, &open) {
+		// Not logged: the breaker logged opening, and this is the point.
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(open.RetryAfter.Seconds())))))
+		answer(w, req, http.StatusServiceUnavailable, "upstream unavailable")
+		return
+	}
+	status, msg := http.StatusBadGateway, "upstream unavailable"
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || deadlineExceeded(req.Context()) || errors.As(err, &netErr) && netErr.Timeout() {
+		status, msg = http.StatusGatewayTimeout, "upstream timed out"
+	}
+	if status != http.StatusGatewayTimeout && errors.Is(err, context.Canceled) && req.Context().Err() != nil {
+		// The client went away; there is nobody to answer.
+		return
+	}
+	if status == http.StatusGatewayTimeout {
+		timedOut()
+	}
+	logging.FromContext(req.Context()).Warn("proxy request failed", "path", req.URL.Path, "error", err)
+	answer(w, req, status, msg)
+}
+
+// failureFunc answers a request the gateway failed to proxy with status
+// and msg, in the shape its client reads.
+type failureFunc func(w http.ResponseWriter, req *http.Request, status int, msg string)
+
+// failureCodes are the API error codes of the statuses proxyError
+// answers.
+var failureCodes = map[int]string{
+	http.StatusRequestEntityTooLarge: "PAYLOAD_TOO_LARGE",
+	http.StatusBadGateway:            "BAD_GATEWAY",
+	http.StatusServiceUnavailable:    "CIRCUIT_OPEN",
+	http.StatusGatewayTimeout:        "GATEWAY_TIMEOUT",
+}
+
+// jsonFailure answers in the API's JSON error shape.
+func jsonFailure(w http.ResponseWriter, _ *http.Request, status int, msg string) {
+	respond.Error(w, status, failureCodes[status], msg)
+}
+
+// bodyTooLarge answers a request whose body is over its route's limit.
+// The connection is closed after, rather than drained of the rest.
+func bodyTooLarge(w http.ResponseWriter, req *http.Request, max int64, answer failureFunc) {
+	w.Header().Set("Connection", "close")
+	answer(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", max))
+}
@@ -0,0 +1,543 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/auth"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/config"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+	accesslog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/ratelimit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// echo reports what the upstream received.
+type echo struct {
+	Name, Path, Host, XFF, Proto, FwdHost, RequestID, UserID string
+}
+
+func upstream(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(echo{
+			Name: name, Path: r.URL.Path, Host: r.Host,
+			XFF: r.Header.Get("X-Forwarded-For"), Proto: r.Header.Get("X-Forwarded-Proto"), FwdHost: r.Header.Get("X-Forwarded-Host"),
+			RequestID: r.Header.Get("X-Request-ID"), UserID: r.Header.Get("X-User-ID"),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, h http.Handler, req *http.Request) (*httptest.ResponseRecorder, echo) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var got echo
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode %q: %v", rec.Body, err)
+		}
+	}
+	return rec, got
+}
+
+func TestRouterMatching(t *testing.T) {
+	api, docs, root := upstream(t, "api"), upstream(t, "docs"), upstream(t, "root")
+	rt, err := New([]Route{
+		{PathPrefix: "/", Upstream: root.URL},
+		{PathPrefix: "/v1", Upstream: api.URL},
+		{PathPrefix: "/v1/documents", Upstream: docs.URL + "/store", StripPrefix: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ path, name, upstreamPath string }{
+		{"/v1/applications", "api", "/v1/applications"},
+		{"/v1", "api", "/v1"},
+		{"/v1/documents/42", "docs", "/store/42"},
+		{"/v1/documents", "docs", "/store/"},
+		{"/v1documents", "root", "/v1documents"},
+		{"/healthz", "root", "/healthz"},
+	}
+	for _, tt := range tests {
+		rec, got := do(t, rt, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK || got.Name != tt.name || got.Path != tt.upstreamPath {
+			t.Errorf("%s: %d %+v, want %s at %s", tt.path, rec.Code, got, tt.name, tt.upstreamPath)
+		}
+	}
+
+	rt, err = New([]Route{{PathPrefix: "/v1", Upstream: api.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := do(t, rt, httptest.NewRequest("GET", "/admin", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":"NOT_FOUND"`) {
+		t.Errorf("unrouted path: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRouterForwardingHeaders(t *testing.T) {
+	api := upstream(t, "api")
+	rt, err := New([]Route{
+		{PathPrefix: "/keep", Upstream: api.URL, PreserveHost: true},
+		{PathPrefix: "/swap", Upstream: api.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "http://entrance.example/keep", nil)
+	req.RemoteAddr = "203.0.113.7:4711"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	_, got := do(t, rt, req)
+	if got.Host != "entrance.example" {
+		t.Errorf("PreserveHost: upstream saw Host %q", got.Host)
+	}
+	if got.XFF != "198.51.100.1, 203.0.113.7" {
+		t.Errorf("X-Forwarded-For = %q, want the client appended", got.XFF)
+	}
+	if got.Proto != "https" {
+		t.Errorf("X-Forwarded-Proto = %q, want the edge proxy's value", got.Proto)
+	}
+
+	req = httptest.NewRequest("GET", "http://entrance.example/swap", nil)
+	req.RemoteAddr = "203.0.113.7:4711"
+	_, got = do(t, rt, req)
+	if got.Host != strings.TrimPrefix(api.URL, "http://") {
+		t.Errorf("upstream saw Host %q, want its own", got.Host)
+	}
+	if got.XFF != "203.0.113.7" || got.Proto != "http" || got.FwdHost != "entrance.example" {
+		t.Errorf("derived headers: %+v", got)
+	}
+}
+
+func TestRouterAccessLog(t *testing.T) {
+	api := upstream(t, "api")
+	var buf bytes.Buffer
+	rt, err := New([]Route{{PathPrefix: "/v1", Upstream: api.URL}},
+		WithAccessLog(accesslog.Options{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, got := do(t, rt, httptest.NewRequest("GET", "/v1/applications", nil))
+	id := rec.Header().Get("X-Request-ID")
+	if id == "" || got.RequestID != id {
+		t.Errorf("request ID %q in the response, %q at the upstream", id, got.RequestID)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != id || entry["path"] != "/v1/applications" || entry["status"] != float64(200) {
+		t.Errorf("access log entry = %v", entry)
+	}
+}
+
+func TestRouterRequestID(t *testing.T) {
+	api := upstream(t, "api")
+	rt, err := New([]Route{{PathPrefix: "/v1", Upstream: api.URL}},
+		WithoutAccessLog(), WithRequestID(requestid.Options{RejectClientIDs: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/v1/applications", nil)
+	req.Header.Set("X-Request-ID", "edge-42")
+	rec, got := do(t, rt, req)
+	id := rec.Header().Get("X-Request-ID")
+	if id == "" || id == "edge-42" || got.RequestID != id {
+		t.Errorf("request ID %q in the response, %q at the upstream, want a fresh one at both", id, got.RequestID)
+	}
+}
+
+func TestRouterMetrics(t *testing.T) {
+	api, docs := upstream(t, "api"), upstream(t, "docs")
+	m := metrics.New(metrics.Options{})
+	rt, err := New([]Route{
+		{Name: "applications", PathPrefix: "/v1", Upstream: api.URL},
+		{PathPrefix: "/docs", Upstream: docs.URL},
+	}, WithoutAccessLog(), WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/v1/applications/app-1", "/v1/applications/app-2", "/docs/x", "/nowhere/app-3"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`http_requests_total{route="applications",status="2xx"} 2`,
+		`http_requests_total{route="/docs",status="2xx"} 1`,
+		`http_requests_total{route="unmatched",status="4xx"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics lack %q", want)
+		}
+	}
+	if strings.Contains(w.Body.String(), "app-1") {
+		t.Error("a raw path became a label")
+	}
+}
+
+func TestRouterRateLimit(t *testing.T) {
+	api := upstream(t, "api")
+	rt, err := New([]Route{
+		{PathPrefix: "/v1", Upstream: api.URL, RateLimit: ratelimit.Rule{Rate: 1, Burst: 2}},
+		{PathPrefix: "/open", Upstream: api.URL},
+	}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec, _ := do(t, rt, req)
+		return rec
+	}
+	for range 2 {
+		if rec := get("/v1/applications", ""); rec.Code != http.StatusOK {
+			t.Fatalf("within the burst: %d", rec.Code)
+		}
+	}
+	rec := get("/v1/applications", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("over the burst: %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body struct{ Error, Code string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "RATE_LIMITED" {
+		t.Errorf("429 body %q: %v", rec.Body, err)
+	}
+	if rec := get("/v1/applications", "partner-1"); rec.Code != http.StatusOK {
+		t.Errorf("a client with its own API key was limited: %d", rec.Code)
+	}
+	for range 3 {
+		if rec := get("/open/x", ""); rec.Code != http.StatusOK {
+			t.Fatalf("route without a rate_limit: %d", rec.Code)
+		}
+	}
+}
+
+// oneKey is an auth.KeySource holding a single key, kid k1.
+type oneKey struct{ pub crypto.PublicKey }
+
+func (k oneKey) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if kid != "k1" {
+		return nil, auth.ErrUnknownKey
+	}
+	return k.pub, nil
+}
+
+func TestRouterAuth(t *testing.T) {
+	api := upstream(t, "api")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := New([]Route{
+		{PathPrefix: "/v1", Upstream: api.URL},
+		{PathPrefix: "/v1/catalog", Upstream: api.URL, Auth: auth.Optional},
+		{PathPrefix: "/healthz", Upstream: api.URL, Auth: auth.None},
+	}, WithoutAccessLog(), WithAuth(auth.Options{Keys: oneKey{&key.PublicKey}, Issuer: "https://id.example.edu", Audience: "entrance"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": "https://id.example.edu", "aud": "entrance", "sub": "applicant-42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tok.Header["kid"] = "k1"
+	token, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path, token string) (*httptest.ResponseRecorder, echo) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-User-ID", "admin")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return do(t, rt, req)
+	}
+	for _, tc := range []struct {
+		path, token string
+		status      int
+		user        string
+	}{
+		{"/v1/applications", token, http.StatusOK, "applicant-42"},
+		{"/v1/applications", "", http.StatusUnauthorized, ""},
+		{"/v1/applications", "forged", http.StatusUnauthorized, ""},
+		{"/v1/catalog/programs", "", http.StatusOK, ""},
+		{"/v1/catalog/programs", token, http.StatusOK, "applicant-42"},
+		{"/healthz", "", http.StatusOK, ""},
+	} {
+		rec, got := get(tc.path, tc.token)
+		if rec.Code != tc.status || got.UserID != tc.user {
+			t.Errorf("GET %s with token %t: %d, upstream X-User-ID %q; want %d, %q", tc.path, tc.token != "", rec.Code, got.UserID, tc.status, tc.user)
+		}
+	}
+}
+
+func TestRouterUpstreamFailures(t *testing.T) {
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	rt, err := New([]Route{
+		{PathPrefix: "/gone", Upstream: gone.URL},
+		{PathPrefix: "/slow", Upstream: slow.URL, Timeout: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]struct {
+		status int
+		code   string
+	}{
+		"/gone": {http.StatusBadGateway, "BAD_GATEWAY"},
+		"/slow": {http.StatusGatewayTimeout, "GATEWAY_TIMEOUT"},
+	} {
+		rec, _ := do(t, rt, httptest.NewRequest("GET", path, nil))
+		var body struct{ Error, Code string }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: body %q is not JSON: %v", path, rec.Body, err)
+		}
+		if rec.Code != want.status || body.Code != want.code || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s: %d %+v, want %d %s", path, rec.Code, body, want.status, want.code)
+		}
+	}
+}
+
+func TestNewRejectsInvalidRoutes(t *testing.T) {
+	_, err := New([]Route{
+		{PathPrefix: "/ok", Upstream: "http://api:8080"},
+		{PathPrefix: "/ok", Upstream: "http://other:8080"},
+		{PathPrefix: "/rel", Upstream: "api:8080"},
+		{PathPrefix: "/bad", Upstream: "http://api:8080/%zz"},
+		{PathPrefix: "noslash", Upstream: "http://api:8080"},
+		{PathPrefix: "/ftp", Upstream: "ftp://files"},
+		{PathPrefix: "/burst", Upstream: "http://api:8080", RateLimit: ratelimit.Rule{Rate: 10}},
+		{PathPrefix: "/mode", Upstream: "http://api:8080", Auth: "sometimes"},
+		{PathPrefix: "/private", Upstream: "http://api:8080", Auth: auth.Required},
+		{PathPrefix: "/retry", Upstream: "http://api:8080", Retry: Retry{Attempts: -1, OnStatuses: []int{700}}},
+		{PathPrefix: "/cache", Upstream: "http://api:8080", Cache: Cache{Headers: []string{"Bad Header"}}},
+		{PathPrefix: "/upload", Upstream: "http://api:8080", MaxRequestBodyBytes: -1},
+		{PathPrefix: "/web", Upstream: "http://api:8080", CORS: cors.Policy{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{PathPrefix: "/soap", Upstream: "http://api:8080", Protocol: "soap"},
+		{PathPrefix: "/rpc", Upstream: "http://api:8080/v1", Protocol: ProtocolGRPC},
+	})
+	if err == nil {
+		t.Fatal("invalid routes accepted")
+	}
+	for _, want := range []string{"route 1 (/ok): duplicate", "route 2 (/rel)", "route 3 (/bad)", "route 4 (noslash)", "route 5 (/ftp)", "route 6 (/burst): rate_limit: burst 0", `route 7 (/mode): auth "sometimes"`, "route 8 (/private): auth required: the gateway has no token validation", "route 9 (/retry): retry: attempts -1", "on_statuses: 700 is not an HTTP status", `route 10 (/cache): cache: headers: "Bad Header" is not a header name`, "ttl is required", "route 11 (/upload): max_request_body_bytes must not be negative", `route 12 (/web): cors: allowed_origins "*" cannot be combined with allow_credentials`, `route 13 (/soap): protocol "soap"`, "route 14 (/rpc): protocol grpc: upstream \"http://api:8080/v1\" must not carry a path"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRoutesFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	body := `
+port: 8000
+routes:
+  - path_prefix: /v1/applications
+    upstream: http://admissions-api:8080
+    timeout: 10s
+    auth: none
+  - path_prefix: /workflows
+    upstream: http://workflow-platform-api:8791
+    strip_prefix: true
+    preserve_host: true
+    rate_limit: {rate: 0.5, burst: 20}
+    idle_conn_timeout: 90s
+    retry: {attempts: 3, on_statuses: [502, 503], backoff: 50ms}
+    breaker: {failures: 10, window: 30s, cooldown: 1m, probes: 2}
+    cache: {ttl: 5m, headers: [Accept-Language], max_body: 4096}
+    max_request_body_bytes: 1048576
+    cors:
+      allowed_origins: [https://app.uniassist.app, "https://*.uniassist.app"]
+      allowed_methods: [GET, POST]
+      allow_credentials: true
+      max_age: 10m
+`
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	type gatewayConfig struct {
+		Port   int     `config:"port"`
+		Routes []Route `config:"routes,required"`
+	}
+	noEnv := config.WithLookupEnv(func(string) (string, bool) { return "", false })
+	cfg, err := config.Load[gatewayConfig](config.WithFile(path), noEnv, config.WithArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{PathPrefix: "/v1/applications", Upstream: "http://admissions-api:8080", Timeout: 10 * time.Second, Auth: auth.None},
+		{PathPrefix: "/workflows", Upstream: "http://workflow-platform-api:8791", StripPrefix: true, PreserveHost: true, RateLimit: ratelimit.Rule{Rate: 0.5, Burst: 20},
+			IdleConnTimeout: 90 * time.Second, Retry: Retry{Attempts: 3, OnStatuses: []int{502, 503}, Backoff: 50 * time.Millisecond},
+			Breaker:             Breaker{Failures: 10, Window: 30 * time.Second, Cooldown: time.Minute, Probes: 2},
+			Cache:               Cache{TTL: 5 * time.Minute, Headers: []string{"Accept-Language"}, MaxBody: 4096},
+			MaxRequestBodyBytes: 1 << 20,
+			CORS:                cors.Policy{AllowedOrigins: []string{"https://app.uniassist.app", "https://*.uniassist.app"}, AllowedMethods: []string{"GET", "POST"}, AllowCredentials: true, MaxAge: 10 * time.Minute}},
+	}
+	if !reflect.DeepEqual(cfg.Routes, want) {
+		t.Fatalf("routes = %+v, want %+v", cfg.Routes, want)
+	}
+	if _, err := New(cfg.Routes); err != nil {
+		t.Errorf("loaded routes rejected: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("routes:\n  - path_prefix: /v1\n    upstrem: http://api\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load[gatewayConfig](config.WithFile(path), noEnv, config.WithArgs(nil)); err == nil || !strings.Contains(err.Error(), "upstrem") {
+		t.Errorf("misspelled route key: %v", err)
+	}
+}
+
+// countingReader is an endless request body that counts the bytes read.
+type countingReader struct{ n atomic.Int64 }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func TestRouterMaxRequestBody(t *testing.T) {
+	var hits atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprintf(w, `{"name":"api","path":%q,"size":%d}`, r.URL.Path, n)
+	}))
+	t.Cleanup(up.Close)
+	rt, err := New([]Route{
+		{PathPrefix: "/small", Upstream: up.URL, MaxRequestBodyBytes: 1 << 10},
+		{PathPrefix: "/any", Upstream: up.URL},
+	}, WithDefaults(Defaults{MaxRequestBodyBytes: 1 << 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec, _ := do(t, rt, httptest.NewRequest("POST", "/small", strings.NewReader(strings.Repeat("x", 1<<10)))); rec.Code != http.StatusOK {
+		t.Fatalf("body at the limit: %d %s", rec.Code, rec.Body)
+	}
+	hits.Store(0)
+
+	for name, length := range map[string]int64{"declared": 10 << 20, "chunked": -1} {
+		body := &countingReader{}
+		req := httptest.NewRequest("POST", "/small", io.LimitReader(body, 10<<20))
+		req.ContentLength = length
+		rec, _ := do(t, rt, req)
+		var got struct{ Code string }
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if rec.Code != http.StatusRequestEntityTooLarge || got.Code != "PAYLOAD_TOO_LARGE" {
+			t.Errorf("%s: %d %s, want 413 PAYLOAD_TOO_LARGE", name, rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Connection") != "close" {
+			t.Errorf("%s: connection left open to the rest of the body", name)
+		}
+		// The proxy reads in chunks of up to 32 KiB, so a body refused
+		// for passing 1 KiB has had at most one of them read.
+		if n := body.n.Load(); length > 0 && n != 0 || n > 64<<10 {
+			t.Errorf("%s: read %d bytes of a 10 MiB body", name, n)
+		}
+	}
+	if n := hits.Load(); n > 1 {
+		t.Errorf("upstream saw %d oversized requests", n)
+	}
+
+	// The default applies to routes without a limit of their own.
+	req := httptest.NewRequest("POST", "/any", strings.NewReader(strings.Repeat("x", 2<<20)))
+	if rec, _ := do(t, rt, req); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("over the default: %d", rec.Code)
+	}
+}
+
+func TestRouterCORS(t *testing.T) {
+	api := upstream(t, "api")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := cors.Policy{AllowedOrigins: []string{"https://*.uniassist.app"}, AllowedMethods: []string{"GET", "PUT"}, AllowedHeaders: []string{"Authorization"}, AllowCredentials: true}
+	rt, err := New([]Route{
+		{PathPrefix: "/v1", Upstream: api.URL, CORS: policy},
+		{PathPrefix: "/catalog", Upstream: api.URL, Auth: auth.None, CORS: policy, Cache: Cache{TTL: time.Minute}},
+		{PathPrefix: "/legacy", Upstream: api.URL, Auth: auth.None},
+	}, WithoutAccessLog(), WithAuth(auth.Options{Keys: oneKey{&key.PublicKey}, Issuer: "https://id.example.edu", Audience: "entrance"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "PUT")
+			req.Header.Set("Access-Control-Request-Headers", "Authorization")
+		}
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A preflight carries no token, so it is answered ahead of auth.
+	rec := send(http.MethodOptions, "/v1/applications", "https://portal.uniassist.app")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://portal.uniassist.app" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("preflight: %d %v", rec.Code, rec.Header())
+	}
+	// The script can read why it was turned away.
+	rec = send(http.MethodGet, "/v1/applications", "https://portal.uniassist.app")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("Access-Control-Allow-Origin") != "https://portal.uniassist.app" {
+		t.Errorf("unauthenticated GET: %d %v", rec.Code, rec.Header())
+	}
+
+	// A cached response is answered with the origin asking for it.
+	if rec := send(http.MethodGet, "/catalog/programs", "https://a.uniassist.app"); rec.Header().Get(CacheHeader) != "MISS" || rec.Header().Get("Access-Control-Allow-Origin") != "https://a.uniassist.app" {
+		t.Errorf("first GET: %v", rec.Header())
+	}
+	if rec := send(http.MethodGet, "/catalog/programs", "https://b.uniassist.app"); rec.Header().Get(CacheHeader) != "HIT" || rec.Header().Get("Access-Control-Allow-Origin") != "https://b.uniassist.app" {
+		t.Errorf("cached GET from another origin: %v", rec.Header())
+	}
+	if rec := send(http.MethodGet, "/catalog/programs", "https://evil.example"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("cached GET from a disallowed origin: %v", rec.Header())
+	}
+
+	// Without a policy the upstream sees the preflight.
+	rec = send(http.MethodOptions, "/legacy/x", "https://portal.uniassist.app")
+	var got echo
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || got.Path != "/legacy/x" || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight to a route without cors: %d %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+}
@@ -0,0 +1,380 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Protocol is what a route speaks to its upstream:
+//
+//	protocol: grpc
+//
+// http, the default, proxies HTTP/1.1 or HTTP/2 as the upstream accepts.
+// grpc proxies gRPC calls over HTTP/2 to the upstream, with prior
+// knowledge (h2c) for an http upstream and through ALPN for an https
+// one, streaming both bodies and passing the trailers on. grpc-web does
+// the same for native gRPC calls and translates browser gRPC-Web calls,
+// binary or base64 text, into native ones and their answers back, with
+// the trailers as the final frame of the body. Clients must reach the
+// gateway over HTTP/2 for native gRPC: with TLS, or through a server
+// built with pkg/server.WithH2C.
+type Protocol string
+
+// Route protocols.
+const (
+	ProtocolHTTP    Protocol = "http"
+	ProtocolGRPC    Protocol = "grpc"
+	ProtocolGRPCWeb Protocol = "grpc-web"
+)
+
+// Validate reports an unknown protocol.
+func (p Protocol) Validate() error {
+	switch p {
+	case "", ProtocolHTTP, ProtocolGRPC, ProtocolGRPCWeb:
+		return nil
+	}
+	return fmt.Errorf("protocol %q: want http, grpc, or grpc-web", p)
+}
+
+// grpc reports whether p routes gRPC calls, natively or from gRPC-Web.
+func (p Protocol) grpc() bool { return p == ProtocolGRPC || p == ProtocolGRPCWeb }
+
+// gRPC status codes the gateway answers with itself.
+const (
+	grpcDeadlineExceeded  = 4
+	grpcResourceExhausted = 8
+	grpcUnavailable       = 14
+)
+
+// grpcPingInterval is how long an HTTP/2 connection to a gRPC upstream may
+// sit without frames before it is pinged, so a dead one under a
+// long-lived stream is noticed.
+const grpcPingInterval = 30 * time.Second
+
+// upstreamPool is an upstream's connection pool. For a gRPC upstream h2
+// carries the requests, and the Transport only holds the settings it was
+// built from.
+type upstreamPool struct {
+	*http.Transport
+	h2 *http2.Transport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *upstreamPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	if p.h2 != nil {
+		return p.h2.RoundTrip(req)
+	}
+	return p.Transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes both pools' idle connections.
+func (p *upstreamPool) CloseIdleConnections() {
+	p.Transport.CloseIdleConnections()
+	if p.h2 != nil {
+		p.h2.CloseIdleConnections()
+	}
+}
+
+// newH2Transport speaks HTTP/2 to an upstream with t's dialer, TLS
+// settings, and idle timeout: h2c for scheme http, TLS negotiating h2 for
+// https.
+func newH2Transport(t *http.Transport, scheme string) *http2.Transport {
+	h2 := &http2.Transport{
+		AllowHTTP:       scheme == "http",
+		IdleConnTimeout: t.IdleConnTimeout,
+		ReadIdleTimeout: grpcPingInterval,
+	}
+	if t.TLSClientConfig != nil {
+		h2.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	// Dialing through t keeps WithReservedPorts' guard on these
+	// connections too.
+	h2.DialTLSContext = func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+		conn, err := t.DialContext(ctx, network, addr)
+		if err != nil || scheme == "http" {
+			return conn, err
+		}
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if p := tc.ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+			conn.Close()
+			return nil, fmt.Errorf("upstream negotiated %q, not HTTP/2", p)
+		}
+		return tc, nil
+	}
+	return h2
+}
+
+// isGRPC reports whether h is a native gRPC call or answer.
+func isGRPC(h http.Header) bool {
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mt == "application/grpc" || strings.HasPrefix(mt, "application/grpc+")
+}
+
+// grpcWebKey holds, in the context of a request translated from gRPC-Web,
+// the media type the client sent, which its answer is given in.
+type grpcWebKey struct{}
+
+// grpcWebType returns the gRPC-Web media type of h, such as
+// application/grpc-web+proto or application/grpc-web-text, and whether it
+// is one.
+func grpcWebType(h http.Header) (string, bool) {
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	base, _, _ := strings.Cut(mt, "+")
+	return mt, base == "application/grpc-web" || base == "application/grpc-web-text"
+}
+
+// fromGRPCWeb turns out, a gRPC-Web call, into the native gRPC call the
+// upstream is sent, decoding a text call's body as it streams.
+func fromGRPCWeb(out *http.Request) *http.Request {
+	mt, ok := grpcWebType(out.Header)
+	if !ok {
+		return out
+	}
+	base, codec, _ := strings.Cut(mt, "+")
+	native := "application/grpc"
+	if codec != "" {
+		native += "+" + codec
+	}
+	out.Header.Set("Content-Type", native)
+	out.Header.Set("Te", "trailers")
+	out.Header.Del("X-Grpc-Web")
+	if base == "application/grpc-web-text" {
+		out.Header.Del("Content-Length")
+		out.ContentLength = -1
+		if out.Body != nil && out.Body != http.NoBody {
+			out.Body = &base64Body{src: out.Body}
+		}
+	}
+	return out.WithContext(context.WithValue(out.Context(), grpcWebKey{}, mt))
+}
+
+// toGRPCWeb is a ReverseProxy ModifyResponse that answers a call
+// fromGRPCWeb translated in the client's gRPC-Web media type. The
+// upstream's trailers follow its messages as a trailer frame, so the
+// response carries none of its own.
+func toGRPCWeb(resp *http.Response) error {
+	mt, ok := resp.Request.Context().Value(grpcWebKey{}).(string)
+	if !ok {
+		return nil
+	}
+	if isGRPC(resp.Header) {
+		resp.Header.Set("Content-Type", mt)
+	}
+	resp.Header.Del("Trailer")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Body = &grpcWebBody{upstream: resp.Body, resp: resp, text: strings.HasPrefix(mt, "application/grpc-web-text")}
+	return nil
+}
+
+// grpcWebBody is a gRPC response body as gRPC-Web: the upstream's frames,
+// then its trailers as a frame flagged 0x80, all base64 encoded in text
+// mode, each read on its own so the stream is never held back.
+type grpcWebBody struct {
+	upstream io.ReadCloser
+	// resp.Trailer is filled in by the transport at the end of the body.
+	resp    *http.Response
+	text    bool
+	buf     []byte
+	pending []byte
+	done    bool
+}
+
+func (b *grpcWebBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		if b.buf == nil {
+			b.buf = make([]byte, 32<<10)
+		}
+		n, err := b.upstream.Read(b.buf)
+		b.emit(b.buf[:n])
+		if err == io.EOF {
+			b.done = true
+			if len(b.resp.Trailer) > 0 {
+				b.emit(trailerFrame(b.resp.Trailer))
+			}
+			// Nothing is left for the ReverseProxy to send as HTTP
+			// trailers.
+			b.resp.Trailer = nil
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *grpcWebBody) emit(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if b.text {
+		b.pending = base64.StdEncoding.AppendEncode(b.pending, data)
+		return
+	}
+	b.pending = append(b.pending, data...)
+}
+
+func (b *grpcWebBody) Close() error { return b.upstream.Close() }
+
+// trailerFrame encodes trailers as a gRPC-Web trailer frame: flag 0x80,
+// a big-endian length, and the trailers as lower-case HTTP/1 header
+// lines.
+func trailerFrame(trailers http.Header) []byte {
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var lines bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&lines, "%s: %s\r\n", strings.ToLower(k), strings.Join(trailers[k], ", "))
+	}
+	frame := make([]byte, 5, 5+lines.Len())
+	frame[0] = 0x80
+	binary.BigEndian.PutUint32(frame[1:], uint32(lines.Len()))
+	return append(frame, lines.Bytes()...)
+}
+
+// base64Body decodes a gRPC-Web text request body as it streams. Clients
+// may pad each message's encoding, so every four-byte quantum is decoded
+// on its own.
+type base64Body struct {
+	src  io.ReadCloser
+	in   []byte // undecoded, fewer than four bytes between reads
+	out  []byte
+	buf  []byte
+	done bool
+}
+
+func (b *base64Body) Read(p []byte) (int, error) {
+	for len(b.out) == 0 {
+		if b.done {
+			if len(b.in) > 0 {
+				return 0, fmt.Errorf("gateway: grpc-web-text body ends inside a base64 quantum")
+			}
+			return 0, io.EOF
+		}
+		if b.buf == nil {
+			b.buf = make([]byte, 32<<10)
+		}
+		n, err := b.src.Read(b.buf)
+		b.in = append(b.in, b.buf[:n]...)
+		var q int
+		for ; q+4 <= len(b.in); q += 4 {
+			var dec [3]byte
+			m, derr := base64.StdEncoding.Decode(dec[:], b.in[q:q+4])
+			if derr != nil {
+				return 0, fmt.Errorf("gateway: grpc-web-text body: %w", derr)
+			}
+			b.out = append(b.out, dec[:m]...)
+		}
+		b.in = append(b.in[:0], b.in[q:]...)
+		if err == io.EOF {
+			b.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, b.out)
+	b.out = b.out[n:]
+	return n, nil
+}
+
+func (b *base64Body) Close() error { return b.src.Close() }
+
+// grpcFailure answers a gRPC or gRPC-Web call the gateway could not
+// complete the way gRPC clients read errors: a trailers-only response
+// whose grpc-status stands for status.
+func grpcFailure(w http.ResponseWriter, req *http.Request, status int, msg string) {
+	code := grpcUnavailable
+	switch status {
+	case http.StatusGatewayTimeout:
+		code = grpcDeadlineExceeded
+	case http.StatusRequestEntityTooLarge:
+		code = grpcResourceExhausted
+	}
+	ct := "application/grpc"
+	if mt, ok := grpcWebType(req.Header); ok {
+		ct = mt
+	}
+	h := w.Header()
+	h.Set("Content-Type", ct)
+	h.Set("Grpc-Status", strconv.Itoa(code))
+	h.Set("Grpc-Message", url.PathEscape(msg))
+	w.WriteHeader(http.StatusOK)
+}
+
+// isGRPCOrWeb reports whether h is a gRPC or gRPC-Web call or answer.
+func isGRPCOrWeb(h http.Header) bool {
+	_, web := grpcWebType(h)
+	return web || isGRPC(h)
+}
+
+// failure is how r answers req when the gateway cannot proxy it: as a
+// gRPC status for a call to a gRPC route, and in JSON otherwise.
+func (r Route) failure(req *http.Request) failureFunc {
+	if r.Protocol.grpc() && isGRPCOrWeb(req.Header) {
+		return grpcFailure
+	}
+	return jsonFailure
+}
+
+// grpcHealth asks the upstream's grpc.health.v1.Health service whether
+// it is serving, over a connection made with p's dialer and TLS settings,
+// within timeout.
+func grpcHealth(ctx context.Context, p *upstreamPool, target *url.URL, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	creds := insecure.NewCredentials()
+	if target.Scheme == "https" {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if p.TLSClientConfig != nil {
+			cfg = p.TLSClientConfig.Clone()
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	conn, err := grpc.NewClient("passthrough:///"+hostPort(target),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return p.DialContext(ctx, "tcp", addr)
+		}))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("grpc.health.v1: %w", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc.health.v1: %s", resp.GetStatus())
+	}
+	return nil
+}
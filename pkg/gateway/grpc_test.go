@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// grpcUpstream serves the standard health service over h2c.
+func grpcUpstream(t *testing.T) (*health.Server, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return hs, "http://" + ln.Addr().String()
+}
+
+// grpcGateway serves routes over HTTP/1.1 and h2c, as a server built
+// WithH2C does.
+func grpcGateway(t *testing.T, routes ...Route) *httptest.Server {
+	t.Helper()
+	for i := range routes {
+		routes[i].Timeout = streamTimeout
+	}
+	rt, err := New(routes, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h2c.NewHandler(rt, &http2.Server{}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func healthClient(t *testing.T, gw *httptest.Server) healthpb.HealthClient {
+	t.Helper()
+	conn, err := grpc.NewClient(strings.TrimPrefix(gw.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCPassThrough(t *testing.T) {
+	hs, up := grpcUpstream(t)
+	gw := grpcGateway(t, Route{PathPrefix: "/grpc.health.v1.Health", Upstream: up, Protocol: ProtocolGRPC})
+	client := healthClient(t, gw)
+	ctx := context.Background()
+
+	// The client fails a call whose trailers are lost.
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check = %v, %v", resp, err)
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown service: %v, want the upstream's NotFound", err)
+	}
+
+	// A server stream outlives the route timeout, and each update arrives
+	// while it is open.
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stream.Recv(); err != nil || msg.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("first update: %v, %v", msg, err)
+	}
+	time.Sleep(2 * streamTimeout)
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if msg, err := stream.Recv(); err != nil || msg.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("second update: %v, %v", msg, err)
+	}
+}
+
+func TestGRPCUpstreamDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	gw := grpcGateway(t, Route{PathPrefix: "/", Upstream: "http://" + addr, Protocol: ProtocolGRPC, Breaker: Breaker{Disabled: true}})
+
+	_, err = healthClient(t, gw).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Check = %v, want Unavailable from the gateway", err)
+	}
+}
+
+// grpcWebCall posts an empty HealthCheckRequest in the gRPC-Web media
+// type ct and returns the response with its body decoded from text mode.
+func grpcWebCall(t *testing.T, gw *httptest.Server, ct string) (*http.Response, []byte) {
+	t.Helper()
+	frame := []byte{0, 0, 0, 0, 0} // an uncompressed, empty message
+	body := frame
+	text := strings.HasPrefix(ct, "application/grpc-web-text")
+	if text {
+		body = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	req, err := http.NewRequest("POST", gw.URL+"/grpc.health.v1.Health/Check", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", ct)
+	req.Header.Set("X-Grpc-Web", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text {
+		// Each write is encoded, and padded, on its own.
+		if got, err = io.ReadAll(&base64Body{src: io.NopCloser(bytes.NewReader(got))}); err != nil {
+			t.Fatalf("text body: %v", err)
+		}
+	}
+	return resp, got
+}
+
+// grpcWebFrames splits a gRPC-Web body into its messages and trailers.
+func grpcWebFrames(t *testing.T, body []byte) (msgs [][]byte, trailers string) {
+	t.Helper()
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("truncated frame header %x", body)
+		}
+		n := binary.BigEndian.Uint32(body[1:5])
+		if uint32(len(body)-5) < n {
+			t.Fatalf("truncated frame %x", body)
+		}
+		if body[0]&0x80 != 0 {
+			trailers = string(body[5 : 5+n])
+		} else {
+			msgs = append(msgs, body[5:5+n])
+		}
+		body = body[5+n:]
+	}
+	return msgs, trailers
+}
+
+func TestGRPCWebTranslation(t *testing.T) {
+	_, up := grpcUpstream(t)
+	gw := grpcGateway(t, Route{PathPrefix: "/grpc.health.v1.Health", Upstream: up, Protocol: ProtocolGRPCWeb})
+
+	for _, ct := range []string{"application/grpc-web+proto", "application/grpc-web-text"} {
+		resp, body := grpcWebCall(t, gw, ct)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != ct {
+			t.Fatalf("%s: %d %s", ct, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if len(resp.Trailer) != 0 {
+			t.Errorf("%s: HTTP trailers %v, want them in the body", ct, resp.Trailer)
+		}
+		msgs, trailers := grpcWebFrames(t, body)
+		// SERVING is field 1, varint 1.
+		if len(msgs) != 1 || !bytes.Equal(msgs[0], []byte{0x08, 0x01}) {
+			t.Errorf("%s: messages %x", ct, msgs)
+		}
+		if !strings.Contains(trailers, "grpc-status: 0\r\n") {
+			t.Errorf("%s: trailers %q", ct, trailers)
+		}
+	}
+
+	// Native gRPC calls to a grpc-web route pass through untouched.
+	if resp, err := healthClient(t, gw).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("native Check = %v, %v", resp, err)
+	}
+}
+
+func TestBase64BodyPaddedChunks(t *testing.T) {
+	// Two messages encoded separately, each padded, split mid-quantum.
+	enc := base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cdef"))
+	b := &base64Body{src: io.NopCloser(io.MultiReader(strings.NewReader(enc[:3]), strings.NewReader(enc[3:])))}
+	got, err := io.ReadAll(b)
+	if err != nil || string(got) != "abcdef" {
+		t.Errorf("decoded %q, %v", got, err)
+	}
+	if _, err := io.ReadAll(&base64Body{src: io.NopCloser(strings.NewReader("YWJj!"))}); err == nil {
+		t.Error("a body ending mid-quantum decoded")
+	}
+}
+
+func TestVerifyUpstreamsChecksGRPCHealth(t *testing.T) {
+	hs, up := grpcUpstream(t)
+	routes := []Route{{PathPrefix: "/rpc", Upstream: up, Protocol: ProtocolGRPC}}
+	if err := VerifyUpstreams(context.Background(), routes, time.Second); err != nil {
+		t.Fatalf("serving upstream: %v", err)
+	}
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := VerifyUpstreams(context.Background(), routes, time.Second); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("not serving upstream: %v", err)
+	}
+}
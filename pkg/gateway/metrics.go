@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// proxyMetrics counts upstream retries, timeouts, and cache lookups by
+// route and tracks circuit breakers by upstream host. A nil
+// *proxyMetrics counts nothing.
+type proxyMetrics struct {
+	retries      *prometheus.CounterVec
+	timeouts     *prometheus.CounterVec
+	breaker      *prometheus.GaugeVec
+	transitions  *prometheus.CounterVec
+	cacheLookups *prometheus.CounterVec
+	cacheRatio   *prometheus.GaugeVec
+
+	mu          sync.Mutex
+	cacheCounts map[string]cacheCount // by route, for cacheRatio
+}
+
+type cacheCount struct{ hits, lookups int }
+
+// breakerValues are the gateway_circuit_state values.
+var breakerValues = map[BreakerState]float64{BreakerClosed: 0, BreakerHalfOpen: 1, BreakerOpen: 2}
+
+// newProxyMetrics registers the gateway collectors with reg, or reuses
+// those a Router built earlier registered there.
+func newProxyMetrics(reg prometheus.Registerer) (*proxyMetrics, error) {
+	m := &proxyMetrics{
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_upstream_retries_total",
+			Help: "Upstream requests retried by the gateway, by route.",
+		}, []string{"route"}),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_upstream_timeouts_total",
+			Help: "Requests answered 504 because the route's timeout ran out, by route.",
+		}, []string{"route"}),
+		breaker: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gateway_circuit_state",
+			Help: "Circuit breaker state by upstream host: 0 closed, 1 half-open, 2 open.",
+		}, []string{"upstream"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_circuit_transitions_total",
+			Help: "Circuit breaker state changes by upstream host and new state.",
+		}, []string{"upstream", "state"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_cache_lookups_total",
+			Help: "GET requests on cached routes, by route and result (hit or miss).",
+		}, []string{"route", "result"}),
+		cacheRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gateway_cache_hit_ratio",
+			Help: "Share of GET requests on a cached route answered from the cache since start, by route.",
+		}, []string{"route"}),
+		cacheCounts: map[string]cacheCount{},
+	}
+	var err error
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+	if m.timeouts, err = register(reg, m.timeouts); err != nil {
+		return nil, err
+	}
+	if m.breaker, err = register(reg, m.breaker); err != nil {
+		return nil, err
+	}
+	if m.transitions, err = register(reg, m.transitions); err != nil {
+		return nil, err
+	}
+	if m.cacheLookups, err = register(reg, m.cacheLookups); err != nil {
+		return nil, err
+	}
+	if m.cacheRatio, err = register(reg, m.cacheRatio); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register registers c with reg, returning the collector already there
+// if an earlier Router registered an identical one.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
+
+func (m *proxyMetrics) retried(route string) func() {
+	if m == nil {
+		return func() {}
+	}
+	return m.retries.WithLabelValues(route).Inc
+}
+
+func (m *proxyMetrics) timedOut(route string) func() {
+	if m == nil {
+		return func() {}
+	}
+	return m.timeouts.WithLabelValues(route).Inc
+}
+
+// cached counts a GET on a cached route as a hit or a miss.
+func (m *proxyMetrics) cached(route string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(route, result).Inc()
+	m.mu.Lock()
+	c := m.cacheCounts[route]
+	c.lookups++
+	if hit {
+		c.hits++
+	}
+	m.cacheCounts[route] = c
+	m.cacheRatio.WithLabelValues(route).Set(float64(c.hits) / float64(c.lookups))
+	m.mu.Unlock()
+}
+
+// breakerState records that host's breaker is now in state; changed
+// counts it as a transition.
+func (m *proxyMetrics) breakerState(host string, state BreakerState, changed bool) {
+	if m == nil {
+		return
+	}
+	m.breaker.WithLabelValues(host).Set(breakerValues[state])
+	if changed {
+		m.transitions.WithLabelValues(host, string(state)).Inc()
+	}
+}
+
+// forgetBreaker drops the gauge of a host no route sends to any more.
+func (m *proxyMetrics) forgetBreaker(host string) {
+	if m == nil {
+		return
+	}
+	m.breaker.DeleteLabelValues(host)
+}
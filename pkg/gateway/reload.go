@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/internal/respond"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/cors"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/ratelimit"
+)
+
+// ReloadResult describes the most recent Reload. The diff is by path
+// prefix against the table that was serving at the time, and is reported
+// for rejected reloads too, to show what the edit would have done.
+type ReloadResult struct {
+	Time     time.Time `json:"time"`
+	Accepted bool      `json:"accepted"`
+	Error    string    `json:"error,omitempty"`
+	Added    []string  `json:"added"`
+	Removed  []string  `json:"removed"`
+	Changed  []string  `json:"changed"`
+}
+
+// Reload validates routes and, only if they are all valid, swaps them in
+// for the current table. Requests already matched finish on the old
+// table; each new request uses the old or the new one in full, never a
+// mix. A rejected reload leaves the old table serving and returns the
+// same aggregated error as New. Every outcome is logged to Logger and
+// kept for LastReload.
+func (rt *Router) Reload(routes []Route) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	old := rt.table.Load()
+	res := diffRoutes(old.source, routes)
+	res.Time = time.Now()
+	next, err := rt.build(routes)
+	if err != nil {
+		res.Error = err.Error()
+		rt.last.Store(&res)
+		rt.logger().Error("gateway reload rejected", "error", err,
+			"added", res.Added, "removed", res.Removed, "changed", res.Changed)
+		return err
+	}
+	rt.swap(next)
+	res.Accepted = true
+	rt.last.Store(&res)
+	rt.logger().Info("gateway reload accepted", "routes", len(routes),
+		"added", res.Added, "removed", res.Removed, "changed", res.Changed)
+	return nil
+}
+
+func (rt *Router) logger() *slog.Logger {
+	if rt.Logger != nil {
+		return rt.Logger
+	}
+	return slog.Default()
+}
+
+// swap makes t the serving table, giving its breakers their policies,
+// tracking the breakers of hosts it added and dropping those of hosts it
+// removed, and the connection pools of upstreams it no longer uses. rt.mu
+// must be held, or rt not yet shared.
+func (rt *Router) swap(t *table) {
+	for host, b := range t.circuits {
+		b.SetPolicy(t.policies[host])
+	}
+	old := rt.table.Swap(t)
+	rt.dropTransports(t)
+	for host := range t.circuits {
+		if old == nil || old.circuits[host] == nil {
+			rt.stats.breakerState(host, BreakerClosed, false)
+		}
+	}
+	if old != nil {
+		for host := range old.circuits {
+			if t.circuits[host] == nil {
+				rt.stats.forgetBreaker(host)
+			}
+		}
+	}
+}
+
+// LastReload returns the outcome of the most recent Reload, or nil if the
+// table has not been reloaded since New.
+func (rt *Router) LastReload() *ReloadResult {
+	return rt.last.Load()
+}
+
+// Routes returns the routes currently serving, in configured order.
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.table.Load().source...)
+}
+
+// diffRoutes compares two route lists by path prefix. Prefixes are listed
+// in the order they appear in their list.
+func diffRoutes(from, to []Route) ReloadResult {
+	res := ReloadResult{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	before := make(map[string]Route, len(from))
+	for _, r := range from {
+		before[r.PathPrefix] = r
+	}
+	after := make(map[string]bool, len(to))
+	for _, r := range to {
+		after[r.PathPrefix] = true
+		prev, ok := before[r.PathPrefix]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, r.PathPrefix)
+		case !reflect.DeepEqual(prev, r):
+			res.Changed = append(res.Changed, r.PathPrefix)
+		}
+	}
+	for _, r := range from {
+		if !after[r.PathPrefix] {
+			res.Removed = append(res.Removed, r.PathPrefix)
+		}
+	}
+	return res
+}
+
+// routeView is a Route as shown on the admin endpoint.
+type routeView struct {
+	PathPrefix   string          `json:"path_prefix"`
+	Upstream     string          `json:"upstream"`
+	StripPrefix  bool            `json:"strip_prefix"`
+	Timeout      string          `json:"timeout,omitempty"`
+	PreserveHost bool            `json:"preserve_host"`
+	RateLimit    *ratelimit.Rule `json:"rate_limit,omitempty"`
+	IdleConn     string          `json:"idle_conn_timeout,omitempty"`
+	Retry        *Retry          `json:"retry,omitempty"`
+	Breaker      *Breaker        `json:"breaker,omitempty"`
+	Auth         string          `json:"auth,omitempty"`
+	TLS          *UpstreamTLS    `json:"tls,omitempty"`
+	Cache        *cacheView      `json:"cache,omitempty"`
+	MaxBody      int64           `json:"max_request_body_bytes,omitempty"`
+	CORS         *cors.Policy    `json:"cors,omitempty"`
+	Protocol     string          `json:"protocol,omitempty"`
+}
+
+// cacheView is a Cache as shown on the admin endpoint.
+type cacheView struct {
+	TTL     string   `json:"ttl"`
+	Headers []string `json:"headers,omitempty"`
+	MaxBody int64    `json:"max_body,omitempty"`
+}
+
+// AdminHandler serves the current routes, circuit breakers, and last
+// reload result as JSON, for an operator checking whether an edit took
+// effect. Upstream credentials are redacted. A POST with
+// ?reset_breaker=<upstream host> closes that host's breaker first. Mount
+// it on an internal listener or behind authentication; it reveals the
+// upstream topology and can reset breakers.
+func (rt *Router) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			host := r.URL.Query().Get("reset_breaker")
+			if host == "" {
+				respond.Error(w, http.StatusBadRequest, "BAD_REQUEST", "POST needs ?reset_breaker=<upstream host>")
+				return
+			}
+			if !rt.ResetBreaker(host) {
+				respond.Error(w, http.StatusNotFound, "NOT_FOUND", "no circuit breaker for upstream "+host)
+				return
+			}
+			rt.logger().Info("gateway circuit breaker reset", "upstream", host)
+		}
+		routes := rt.Routes()
+		views := make([]routeView, len(routes))
+		for i, route := range routes {
+			v := routeView{
+				PathPrefix:   route.PathPrefix,
+				Upstream:     route.Upstream,
+				StripPrefix:  route.StripPrefix,
+				PreserveHost: route.PreserveHost,
+				Auth:         string(route.Auth),
+				Protocol:     string(route.Protocol),
+			}
+			if u, err := url.Parse(route.Upstream); err == nil {
+				v.Upstream = u.Redacted()
+			}
+			if route.Timeout > 0 {
+				v.Timeout = route.Timeout.String()
+			}
+			if !route.RateLimit.IsZero() {
+				v.RateLimit = &route.RateLimit
+			}
+			if route.IdleConnTimeout > 0 {
+				v.IdleConn = route.IdleConnTimeout.String()
+			}
+			if !route.Retry.IsZero() {
+				v.Retry = &route.Retry
+			}
+			if !route.Breaker.IsZero() {
+				v.Breaker = &route.Breaker
+			}
+			if !route.TLS.IsZero() {
+				v.TLS = &route.TLS
+			}
+			if !route.Cache.IsZero() {
+				v.Cache = &cacheView{TTL: route.Cache.TTL.String(), Headers: route.Cache.Headers, MaxBody: route.Cache.MaxBody}
+			}
+			v.MaxBody = route.MaxRequestBodyBytes
+			if !route.CORS.IsZero() {
+				v.CORS = &route.CORS
+			}
+			views[i] = v
+		}
+		respond.JSON(w, http.StatusOK, map[string]any{
+			"routes":      views,
+			"breakers":    rt.Breakers(),
+			"last_reload": rt.LastReload(),
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errReservedUpstream refuses a dial to a reserved port on this host.
+var errReservedUpstream = errors.New("gateway: connection to a reserved port on this host refused")
+
+// WithReservedPorts keeps the gateway from ever proxying to ports on its
+// own host, such as the admin listener's: a route whose upstream names
+// one of them on localhost, a loopback or local address, or this host's
+// name is rejected by New and Reload, and a dial that resolves to one
+// anyway, through DNS for instance, is refused and answered 502.
+func WithReservedPorts(ports ...int) Option {
+	return func(c *options) { c.reserved = append(c.reserved, ports...) }
+}
+
+// guardReserved makes rt's dials refuse its reserved ports on local
+// addresses. rt not yet shared.
+func (rt *Router) guardReserved() {
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Control sees the resolved address about to be connected.
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if n, _ := strconv.Atoi(port); rt.reserved[n] && isLocalIP(net.ParseIP(host)) {
+				return errReservedUpstream
+			}
+			return nil
+		},
+	}
+	rt.transport.DialContext = d.DialContext
+}
+
+// checkReserved reports an upstream naming a reserved port on this host.
+func (rt *Router) checkReserved(target *url.URL) error {
+	_, p, _ := net.SplitHostPort(hostPort(target))
+	port, _ := strconv.Atoi(p)
+	if !rt.reserved[port] || !isLocalHost(target.Hostname()) {
+		return nil
+	}
+	return fmt.Errorf("upstream %q: port %d on this host is reserved and never proxied to", target.Redacted(), port)
+}
+
+// isLocalHost reports whether host names this machine.
+func isLocalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return isLocalIP(ip)
+	}
+	name, err := os.Hostname()
+	return err == nil && strings.EqualFold(host, name)
+}
+
+// isLocalIP reports whether ip is a loopback, unspecified, or interface
+// address of this machine, any of which reaches a listener on all
+// interfaces.
+func isLocalIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReservedPorts(t *testing.T) {
+	admin := upstream(t, "admin")
+	port, _ := strconv.Atoi(admin.URL[strings.LastIndex(admin.URL, ":")+1:])
+	hostname, _ := os.Hostname()
+
+	for _, up := range []string{
+		admin.URL,
+		"http://localhost:" + strconv.Itoa(port) + "/admin",
+		"http://[::1]:" + strconv.Itoa(port),
+		"http://0.0.0.0:" + strconv.Itoa(port),
+		"http://" + hostname + ":" + strconv.Itoa(port),
+	} {
+		_, err := New([]Route{{PathPrefix: "/x", Upstream: up}}, WithReservedPorts(port), WithoutAccessLog())
+		if err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("route to %s: err = %v, want it refused", up, err)
+		}
+	}
+
+	other := upstream(t, "other")
+	rt, err := New([]Route{{PathPrefix: "/x", Upstream: other.URL}}, WithReservedPorts(port), WithoutAccessLog())
+	if err != nil {
+		t.Fatalf("route to another port refused: %v", err)
+	}
+	if rec, body := do(t, rt, httptest.NewRequest("GET", "/x", nil)); rec.Code != http.StatusOK || body.Name != "other" {
+		t.Errorf("unreserved upstream: %d %+v", rec.Code, body)
+	}
+	if err := rt.Reload([]Route{{PathPrefix: "/x", Upstream: admin.URL}}); err == nil {
+		t.Error("reload to the reserved port accepted")
+	}
+
+	// A name the config check cannot place, resolving to this host, is
+	// caught when it is dialed.
+	_, err = rt.transport.DialContext(context.Background(), "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if !errors.Is(err, errReservedUpstream) {
+		t.Errorf("dial to the reserved port: err = %v, want it refused", err)
+	}
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// DefaultRetryStatuses are retried when a Retry leaves OnStatuses nil.
+var DefaultRetryStatuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Retry defaults for the fields a Retry leaves zero.
+const (
+	DefaultRetryBackoff = 100 * time.Millisecond
+	DefaultMaxRetryBody = 1 << 20
+)
+
+// Retry is a route's retry policy. A request is retried only if it is
+// idempotent (GET, HEAD, OPTIONS, TRACE, PUT, DELETE, or any method with
+// an Idempotency-Key header) and its body, if any, fit in MaxBody and so
+// could be buffered for replay. Every attempt shares the route's Timeout,
+// and no retry starts that could not finish its backoff before it.
+type Retry struct {
+	// Attempts is the total number of tries; 0 or 1 means no retry.
+	Attempts int `yaml:"attempts" json:"attempts"`
+	// OnStatuses are the upstream statuses worth another try; nil means
+	// DefaultRetryStatuses. Connection failures are always retried.
+	OnStatuses []int `yaml:"on_statuses" json:"on_statuses,omitempty"`
+	// Backoff is the wait before the first retry, doubling after each;
+	// zero means DefaultRetryBackoff.
+	Backoff time.Duration `yaml:"backoff" json:"backoff,omitempty"`
+	// MaxBody is the largest request body buffered for replay; zero
+	// means DefaultMaxRetryBody. Larger requests are sent once.
+	MaxBody int64 `yaml:"max_body" json:"max_body,omitempty"`
+}
+
+// IsZero reports whether r is unset.
+func (r Retry) IsZero() bool {
+	return r.Attempts == 0 && r.OnStatuses == nil && r.Backoff == 0 && r.MaxBody == 0
+}
+
+// Validate reports a negative field or a status that is not an HTTP
+// status code.
+func (r Retry) Validate() error {
+	var errs []error
+	if r.Attempts < 0 {
+		errs = append(errs, fmt.Errorf("attempts %d: must not be negative", r.Attempts))
+	}
+	if r.Backoff < 0 {
+		errs = append(errs, fmt.Errorf("backoff %s: must not be negative", r.Backoff))
+	}
+	if r.MaxBody < 0 {
+		errs = append(errs, fmt.Errorf("max_body %d: must not be negative", r.MaxBody))
+	}
+	for _, s := range r.OnStatuses {
+		if s < 100 || s > 599 {
+			errs = append(errs, fmt.Errorf("on_statuses: %d is not an HTTP status", s))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryTransport retries a route's upstream requests per its policy.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  Retry
+	retried func() // counts each retry
+}
+
+func newRetryTransport(base http.RoundTripper, p Retry, retried func()) http.RoundTripper {
+	if p.Attempts <= 1 {
+		return base
+	}
+	if p.OnStatuses == nil {
+		p.OnStatuses = DefaultRetryStatuses
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultRetryBackoff
+	}
+	if p.MaxBody <= 0 {
+		p.MaxBody = DefaultMaxRetryBody
+	}
+	return &retryTransport{base: base, policy: p, retried: retried}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		return t.base.RoundTrip(req)
+	}
+	body, replayable, err := bufferBody(req, t.policy.MaxBody)
+	if err != nil {
+		return nil, err
+	}
+	if !replayable {
+		return t.base.RoundTrip(body.once(req))
+	}
+	ctx := req.Context()
+	backoff := t.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(body.once(req))
+		if attempt == t.policy.Attempts || !t.worthRetrying(ctx, resp, err) || !fits(ctx, backoff) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		t.retried()
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (t *retryTransport) worthRetrying(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return slices.Contains(t.policy.OnStatuses, resp.StatusCode)
+}
+
+// fits reports whether ctx leaves time to wait d and try again.
+func fits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// requestBody is a request body read ahead for replay: all of it when
+// replayable, or the part read before it proved too large.
+type requestBody struct {
+	data []byte
+	rest io.ReadCloser // unread remainder of a body too large to replay
+}
+
+// bufferBody reads req's body, up to max bytes, so it can be sent more
+// than once.
+func bufferBody(req *http.Request, max int64) (requestBody, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return requestBody{}, true, nil
+	}
+	if req.ContentLength > max {
+		return requestBody{rest: req.Body}, false, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, max+1))
+	if err != nil {
+		req.Body.Close()
+		return requestBody{}, false, err
+	}
+	if int64(len(data)) > max {
+		return requestBody{data: data, rest: req.Body}, false, nil
+	}
+	req.Body.Close()
+	return requestBody{data: data}, true, nil
+}
+
+// once returns a copy of req carrying the body afresh.
+func (b requestBody) once(req *http.Request) *http.Request {
+	out := req.Clone(req.Context())
+	switch {
+	case b.rest != nil:
+		out.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b.data), b.rest), b.rest}
+	case req.Body == nil || req.Body == http.NoBody:
+	default:
+		out.Body = io.NopCloser(bytes.NewReader(b.data))
+		out.ContentLength = int64(len(b.data))
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+)
+
+// flaky answers the first fails requests with status, then 200, and
+// records every body it received.
+type flaky struct {
+	*httptest.Server
+	mu     sync.Mutex
+	fails  int
+	status int
+	bodies []string
+}
+
+func newFlaky(t *testing.T, fails, status int) *flaky {
+	t.Helper()
+	f := &flaky{fails: fails, status: status}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.bodies = append(f.bodies, string(body))
+		n := len(f.bodies)
+		f.mu.Unlock()
+		if n <= f.fails {
+			w.WriteHeader(f.status)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *flaky) hits() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.bodies...)
+}
+
+func TestRouterRetries(t *testing.T) {
+	policy := Retry{Attempts: 3, Backoff: time.Millisecond, MaxBody: 16}
+	for _, tc := range []struct {
+		name, method, body string
+		header             string
+		status, hits       int
+	}{
+		{"GET", "GET", "", "", http.StatusOK, 3},
+		{"PUT with a small body", "PUT", "decision=admit", "", http.StatusOK, 3},
+		{"PUT with a body over max_body", "PUT", strings.Repeat("x", 17), "", http.StatusServiceUnavailable, 1},
+		{"POST", "POST", "{}", "", http.StatusServiceUnavailable, 1},
+		{"POST with an idempotency key", "POST", "{}", "key-1", http.StatusOK, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			up := newFlaky(t, 2, http.StatusServiceUnavailable)
+			rt, err := New([]Route{{PathPrefix: "/v1", Upstream: up.URL, Retry: policy}}, WithoutAccessLog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(tc.method, "/v1/applications/a1", strings.NewReader(tc.body))
+			if tc.header != "" {
+				req.Header.Set("Idempotency-Key", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, req)
+			hits := up.hits()
+			if rec.Code != tc.status || len(hits) != tc.hits {
+				t.Fatalf("status %d after %d upstream requests, want %d after %d", rec.Code, len(hits), tc.status, tc.hits)
+			}
+			for i, got := range hits {
+				if got != tc.body {
+					t.Errorf("attempt %d sent body %q, want %q", i+1, got, tc.body)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterRetryRespectsDeadline(t *testing.T) {
+	up := newFlaky(t, 1000, http.StatusBadGateway)
+	m := metrics.New(metrics.Options{})
+	rt, err := New([]Route{{
+		Name: "applications", PathPrefix: "/v1", Upstream: up.URL, Timeout: 100 * time.Millisecond,
+		Retry: Retry{Attempts: 10, Backoff: 30 * time.Millisecond},
+	}}, WithoutAccessLog(), WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	rec, _ := do(t, rt, httptest.NewRequest("GET", "/v1/applications", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status %d, want the upstream's last 502", rec.Code)
+	}
+	// 30ms then 60ms of backoff fit in 100ms; the next 120ms does not.
+	if n := len(up.hits()); n != 3 {
+		t.Errorf("%d attempts, want 3 within the deadline", n)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("took %s, past the route timeout", elapsed)
+	}
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `gateway_upstream_retries_total{route="applications"} 2`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics lack %q", want)
+	}
+}
+
+func TestRouterTimeoutMetric(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	m := metrics.New(metrics.Options{})
+	rt, err := New([]Route{{Name: "slow", PathPrefix: "/slow", Upstream: slow.URL}},
+		WithoutAccessLog(), WithMetrics(m), WithDefaults(Defaults{Timeout: 20 * time.Millisecond, Retry: Retry{Attempts: 2}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second Router on the same registry shares the counters.
+	if _, err := New(nil, WithMetrics(m)); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := do(t, rt, httptest.NewRequest("GET", "/slow/x", nil))
+	var body struct{ Error, Code string }
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusGatewayTimeout || body.Code != "GATEWAY_TIMEOUT" {
+		t.Fatalf("status %d %s, want 504 GATEWAY_TIMEOUT", rec.Code, rec.Body)
+	}
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `gateway_upstream_timeouts_total{route="slow"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics lack %q", want)
+	}
+}
+
+func TestRouterIdleConnTransports(t *testing.T) {
+	rt, err := New([]Route{
+		{PathPrefix: "/a", Upstream: "http://a", IdleConnTimeout: 5 * time.Second},
+		{PathPrefix: "/a2", Upstream: "http://a:80/v2", IdleConnTimeout: 5 * time.Second},
+		{PathPrefix: "/b", Upstream: "http://b", IdleConnTimeout: 5 * time.Second},
+		{PathPrefix: "/c", Upstream: "http://c"},
+	}, WithDefaults(Defaults{IdleConnTimeout: 30 * time.Second}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := transportKey{host: "http://a:80", idle: 5 * time.Second}
+	c := transportKey{host: "http://c:80", idle: 30 * time.Second}
+	if len(rt.transports) != 3 || rt.transports[a] == nil || rt.transports[c].IdleConnTimeout != 30*time.Second {
+		t.Fatalf("transports = %v, want one pool per upstream", rt.transports)
+	}
+	pool := rt.transports[a]
+	if err := rt.Reload([]Route{{PathPrefix: "/a", Upstream: "http://a", IdleConnTimeout: 5 * time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.transports) != 1 || rt.transports[a] != pool || pool.IdleConnTimeout != 5*time.Second {
+		t.Errorf("transports = %v, want a's pool kept across the reload and the others dropped", rt.transports)
+	}
+}
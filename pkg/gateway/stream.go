@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebSocket upgrades and Server-Sent Events pass through the ReverseProxy
+// of each route: it splices the client and upstream connections together
+// after a 101 response, and flushes every write of a text/event-stream
+// response, or of a gRPC one, which has no length either, at once. What
+// the gateway adds is a route timeout that lets go of such a stream once
+// it starts; see withTimeout.
+
+// streamTimerKey holds, in a streaming request's context, the timer that
+// enforces its route's timeout until the upstream answers.
+type streamTimerKey struct{}
+
+// withTimeout bounds req by its route's Timeout d. A request that may turn
+// into a long-lived stream, a WebSocket upgrade, an EventSource, or a gRPC
+// call, is only bounded until the upstream answers with a 101, an event
+// stream, or the call's headers; see liftTimeout. gRPC clients bound
+// their calls themselves with grpc-timeout. Call the returned func once the request is served.
+func withTimeout(req *http.Request, d time.Duration) (*http.Request, func()) {
+	if !mayStream(req) {
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		return req.WithContext(ctx), cancel
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) })
+	ctx = context.WithValue(ctx, streamTimerKey{}, timer)
+	return req.WithContext(ctx), func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// liftTimeout is a ReverseProxy ModifyResponse: it stops the timeout of a
+// request withTimeout let stream once the upstream has switched protocols,
+// started an event stream, or answered a gRPC call, so the stream lasts as
+// long as both ends keep it open.
+func liftTimeout(resp *http.Response) error {
+	if resp.StatusCode != http.StatusSwitchingProtocols && !isEventStream(resp.Header) && !isGRPCOrWeb(resp.Header) {
+		return nil
+	}
+	if timer, ok := resp.Request.Context().Value(streamTimerKey{}).(*time.Timer); ok {
+		timer.Stop()
+	}
+	return nil
+}
+
+// deadlineExceeded reports whether ctx ended because its route timeout
+// ran out, whether it was set by context.WithTimeout or a stream timer.
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), context.DeadlineExceeded)
+}
+
+// mayStream reports whether req asks for a protocol upgrade or, as an
+// EventSource does, for an event stream, or is a gRPC call.
+func mayStream(req *http.Request) bool {
+	if req.Header.Get("Upgrade") != "" && headerHasToken(req.Header, "Connection", "upgrade") {
+		return true
+	}
+	if isGRPCOrWeb(req.Header) {
+		return true
+	}
+	return headerHasToken(req.Header, "Accept", "text/event-stream")
+}
+
+func isEventStream(h http.Header) bool {
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mt == "text/event-stream"
+}
+
+// headerHasToken reports whether a comma-separated header lists token,
+// ignoring case and parameters.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			part, _, _ = strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package gateway
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/metrics"
+	accesslog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/ratelimit"
+)
+
+// streamTimeout is the route timeout of the streaming tests; every
+// stream outlives it.
+const streamTimeout = 200 * time.Millisecond
+
+// streamGateway serves routes through a Router with every middleware
+// that wraps the response writer: access log, metrics, and rate limit.
+func streamGateway(t *testing.T, routes ...Route) (*httptest.Server, *metrics.HTTP) {
+	t.Helper()
+	m := metrics.New(metrics.Options{})
+	for i := range routes {
+		routes[i].Timeout = streamTimeout
+		routes[i].RateLimit = ratelimit.Rule{Rate: 100, Burst: 100}
+	}
+	rt, err := New(routes,
+		WithAccessLog(accesslog.Options{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}),
+		WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return srv, m
+}
+
+func TestWebSocketPassThrough(t *testing.T) {
+	closedByClient := make(chan struct{})
+	up := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				close(closedByClient)
+				return
+			}
+			if msg == "bye" {
+				return // the upstream hangs up
+			}
+			websocket.Message.Send(ws, "echo: "+msg)
+		}
+	}))
+	defer up.Close()
+	gw, m := streamGateway(t, Route{PathPrefix: "/ws", Upstream: up.URL})
+	wsURL := "ws" + strings.TrimPrefix(gw.URL, "http") + "/ws"
+
+	ws, err := websocket.Dial(wsURL, "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("dial through the gateway: %v", err)
+	}
+	echo := func(msg string) time.Duration {
+		t.Helper()
+		start := time.Now()
+		if err := websocket.Message.Send(ws, msg); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if err := websocket.Message.Receive(ws, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != "echo: "+msg {
+			t.Fatalf("got %q, want the echo of %q", got, msg)
+		}
+		return time.Since(start)
+	}
+	if d := echo("one"); d > 100*time.Millisecond {
+		t.Errorf("round trip took %s", d)
+	}
+	time.Sleep(streamTimeout + 100*time.Millisecond)
+	if d := echo("after the route timeout"); d > 100*time.Millisecond {
+		t.Errorf("round trip took %s", d)
+	}
+
+	// The client hanging up reaches the upstream.
+	ws.Close()
+	select {
+	case <-closedByClient:
+	case <-time.After(time.Second):
+		t.Fatal("upstream did not see the client close")
+	}
+
+	// The upstream hanging up reaches the client.
+	ws, err = websocket.Dial(wsURL, "", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.Message.Send(ws, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var msg string
+	if err := websocket.Message.Receive(ws, &msg); err != io.EOF {
+		t.Errorf("after the upstream closed, Receive = %q, %v; want io.EOF", msg, err)
+	}
+	ws.Close()
+
+	// Both connections are counted as upgrades once they are over.
+	want := `http_requests_total{route="/ws",status="1xx"} 2`
+	var body string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if body = rec.Body.String(); strings.Contains(body, want) {
+			return
+		}
+	}
+	t.Errorf("metrics lack %s:\n%s", want, grepLines(body, "http_requests_total"))
+}
+
+func TestEventStreamPassThrough(t *testing.T) {
+	const events = 4
+	gap := streamTimeout / 2 // the stream runs twice the route timeout
+	closedByClient := make(chan struct{})
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		n := events
+		if r.URL.Query().Has("forever") {
+			n = 1 << 30
+		}
+		for i := range n {
+			// Each event carries when it was sent, so the client can tell
+			// how long it took to arrive.
+			event := "id: " + strconv.Itoa(i) + "\ndata: " + strconv.FormatInt(time.Now().UnixNano(), 10) + "\n\n"
+			if _, err := io.WriteString(w, event); err != nil {
+				return
+			}
+			http.NewResponseController(w).Flush()
+			select {
+			case <-r.Context().Done():
+				close(closedByClient)
+				return
+			case <-time.After(gap):
+			}
+		}
+	}))
+	defer up.Close()
+	gw, _ := streamGateway(t, Route{PathPrefix: "/events", Upstream: up.URL})
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", gw.URL+path, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+		return resp
+	}
+
+	// Every event arrives as it is sent, and the upstream ending the
+	// stream ends the client's.
+	resp := get("/events")
+	lines := bufio.NewScanner(resp.Body)
+	got := 0
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		sent, _ := strconv.ParseInt(data, 10, 64)
+		if d := time.Since(time.Unix(0, sent)); d > 100*time.Millisecond {
+			t.Errorf("event %d arrived after %s", got, d)
+		}
+		got++
+	}
+	resp.Body.Close()
+	if lines.Err() != nil || got != events {
+		t.Fatalf("read %d events, err %v; want all %d and a clean end", got, lines.Err(), events)
+	}
+
+	// The client hanging up reaches the upstream.
+	resp = get("/events?forever")
+	bufio.NewReader(resp.Body).ReadString('\n')
+	resp.Body.Close()
+	select {
+	case <-closedByClient:
+	case <-time.After(time.Second):
+		t.Fatal("upstream did not see the client close")
+	}
+}
+
+func TestStreamTimeoutBeforeUpstreamAnswers(t *testing.T) {
+	release := make(chan struct{})
+	up := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer up.Close()
+	defer close(release)
+	gw, _ := streamGateway(t, Route{PathPrefix: "/events", Upstream: up.URL})
+
+	req, _ := http.NewRequest("GET", gw.URL+"/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504 while the upstream has not answered", resp.StatusCode)
+	}
+}
+
+func TestMayStream(t *testing.T) {
+	for _, tc := range []struct {
+		header http.Header
+		want   bool
+	}{
+		{http.Header{"Connection": {"keep-alive, Upgrade"}, "Upgrade": {"websocket"}}, true},
+		{http.Header{"Upgrade": {"websocket"}}, false},
+		{http.Header{"Accept": {"text/html, text/event-stream;q=0.9"}}, true},
+		{http.Header{"Accept": {"application/json"}}, false},
+	} {
+		if got := mayStream(&http.Request{Header: tc.header}); got != tc.want {
+			t.Errorf("mayStream(%v) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+// grepLines returns the lines of s containing substr.
+func grepLines(s, substr string) string {
+	var out []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.Contains(l, substr) {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/server"
+)
+
+// UpstreamTLS configures how a route connects to an https upstream:
+//
+//	tls:
+//	  ca_file: /etc/uniassist/ca.pem
+//	  cert_file: /etc/uniassist/tls/tls.crt
+//	  key_file: /etc/uniassist/tls/tls.key
+//	  server_name: admissions-api.internal
+//
+// The zero UpstreamTLS verifies the upstream against the system roots and
+// presents no certificate.
+type UpstreamTLS struct {
+	// CAFile is a PEM bundle the upstream's certificate must chain to,
+	// instead of the system roots.
+	CAFile string `yaml:"ca_file" json:"ca_file,omitempty"`
+	// CertFile and KeyFile are the client certificate presented for
+	// mutual TLS. They are reread whenever either file changes, as the
+	// server's own certificate is; see pkg/server.CertReloader.
+	CertFile string `yaml:"cert_file" json:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file" json:"key_file,omitempty"`
+	// ServerName is the name the upstream's certificate is checked
+	// against; empty means the upstream URL's host.
+	ServerName string `yaml:"server_name" json:"server_name,omitempty"`
+	// InsecureSkipVerify accepts any upstream certificate. It is for
+	// local development only.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify,omitempty"`
+}
+
+// IsZero reports whether t is unset.
+func (t UpstreamTLS) IsZero() bool { return t == UpstreamTLS{} }
+
+// Validate reports the field combinations that cannot work. The files
+// themselves are read when the route's transport is built.
+func (t UpstreamTLS) Validate() error {
+	var errs []error
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("cert_file and key_file must be set together"))
+	}
+	if t.InsecureSkipVerify && (t.CAFile != "" || t.ServerName != "") {
+		errs = append(errs, errors.New("insecure_skip_verify ignores ca_file and server_name; set one or the other"))
+	}
+	return errors.Join(errs...)
+}
+
+// transportKey identifies an upstream connection pool. Routes to the same
+// host with the same settings share one, across reloads too.
+type transportKey struct {
+	host string // scheme://host:port
+	idle time.Duration
+	tls  UpstreamTLS
+	ca   [sha256.Size]byte // of the CA bundle, so a rotated CA gets a new pool on reload
+	h2   bool              // HTTP/2 only, for gRPC
+}
+
+// transportFor returns the connection pool for r's upstream, building it
+// from the Router's base transport on first use. rt.mu must be held, or
+// rt not yet shared.
+func (rt *Router) transportFor(r Route, target *url.URL) (*upstreamPool, transportKey, error) {
+	key := transportKey{host: target.Scheme + "://" + hostPort(target), idle: r.IdleConnTimeout, tls: r.TLS, h2: r.Protocol.grpc()}
+	var ca []byte
+	if r.TLS.CAFile != "" {
+		var err error
+		if ca, err = os.ReadFile(r.TLS.CAFile); err != nil {
+			return nil, key, fmt.Errorf("tls: ca_file: %w", err)
+		}
+		key.ca = sha256.Sum256(ca)
+	}
+	if t, ok := rt.transports[key]; ok {
+		return t, key, nil
+	}
+	t := rt.transport.Clone()
+	if r.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = r.IdleConnTimeout
+	}
+	if !r.TLS.IsZero() {
+		cfg, err := rt.clientTLS(r.TLS, ca, target.Host)
+		if err != nil {
+			return nil, key, err
+		}
+		t.TLSClientConfig = cfg
+	}
+	p := &upstreamPool{Transport: t}
+	if key.h2 {
+		p.h2 = newH2Transport(t, target.Scheme)
+	}
+	rt.transports[key] = p
+	return p, key, nil
+}
+
+// clientTLS builds the tls.Config of an upstream at host from t and the CA
+// bundle it names, already read.
+func (rt *Router) clientTLS(t UpstreamTLS, ca []byte, host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if ca != nil {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("tls: ca_file %s: no PEM certificates", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		certs, err := server.NewCertReloader(t.CertFile, t.KeyFile, func(format string, args ...any) {
+			rt.logger().Error(fmt.Sprintf(format, args...), "upstream", host)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		cfg.GetClientCertificate = certs.GetClientCertificate
+	}
+	return cfg, nil
+}
+
+// hostPort is u's host with its port, defaulted from the scheme.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// dropTransports closes the idle connections of the pools t no longer
+// uses and forgets them. rt.mu must be held, or rt not yet shared.
+func (rt *Router) dropTransports(t *table) {
+	for key, tr := range rt.transports {
+		if !t.transports[key] {
+			tr.CloseIdleConnections()
+			delete(rt.transports, key)
+		}
+	}
+}
+
+// VerifyUpstreams connects once to every distinct upstream in routes, with
+// the TLS settings of the route sending to it, and reports every one that
+// cannot be reached or fails the handshake: a missing key, a CA that does
+// not verify the upstream, or an upstream that refuses the client
+// certificate on its first exchange. A grpc or grpc-web upstream must also
+// answer the standard grpc.health.v1.Health check as SERVING. It checks
+// routes the way New does, except for auth, which it leaves to the
+// Router. ctx bounds the whole check; each dial, and each health check,
+// gets at most timeout, or 5s when timeout is zero.
+func VerifyUpstreams(ctx context.Context, routes []Route, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	rt := &Router{transport: http.DefaultTransport.(*http.Transport).Clone(), transports: map[transportKey]*upstreamPool{}}
+	var errs []error
+	seen := map[transportKey]bool{}
+	for i, r := range routes {
+		r.Auth = ""
+		target, err := r.validate()
+		var p *upstreamPool
+		var key transportKey
+		if err == nil {
+			p, key, err = rt.transportFor(r, target)
+		}
+		if err == nil && !seen[key] {
+			seen[key] = true
+			err = dialUpstream(ctx, p.Transport, target, timeout)
+			if err == nil && key.h2 {
+				err = grpcHealth(ctx, p, target, timeout)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gateway: route %d (%s): upstream %s: %w", i, r.PathPrefix, r.Upstream, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dialUpstream opens one connection to target through t's settings,
+// completing the TLS handshake for https, and closes it.
+func dialUpstream(ctx context.Context, t *http.Transport, target *url.URL, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addr := hostPort(target)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if target.Scheme != "https" {
+		return nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.TLSClientConfig != nil {
+		cfg = t.TLSClientConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = target.Hostname()
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	// A TLS 1.3 server checks the client certificate after the client has
+	// finished its side of the handshake, so a rejection only shows on
+	// the first read. Give it a moment to arrive.
+	_ = tc.SetReadDeadline(time.Now().Add(min(timeout, 200*time.Millisecond)))
+	if _, err := tc.Read(make([]byte, 1)); err != nil && !isTimeout(err) {
+		return err
+	}
+	return nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the upstream and the gateway.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for cn, valid for 127.0.0.1
+// and upstream.test.
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"upstream.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to name in dir, stamped at modified so rewrites
+// are seen whatever the file system's time resolution.
+func writeFile(t *testing.T, dir, name string, data []byte, modified time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// mtlsUpstream serves, over TLS with a certificate from ca, the common
+// name of the client certificate each request presents; one from ca is
+// required.
+func mtlsUpstream(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "upstream", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // the refused handshakes are the point
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRouterMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	srv := mtlsUpstream(t, ca)
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", ca.pem, time.Now())
+	certPEM, keyPEM := ca.issue(t, "gateway-1", x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "tls.crt", certPEM, time.Now().Add(-time.Minute))
+	keyFile := writeFile(t, dir, "tls.key", keyPEM, time.Now().Add(-time.Minute))
+
+	rt, err := New([]Route{{
+		PathPrefix: "/docs",
+		Upstream:   srv.URL,
+		TLS:        UpstreamTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "upstream.test"},
+	}}, WithoutAccessLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest("GET", "/docs", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+	if got := get(); got != "gateway-1" {
+		t.Fatalf("upstream saw client certificate %q, want gateway-1", got)
+	}
+
+	// A rotated client certificate is presented on the next connection.
+	certPEM, keyPEM = ca.issue(t, "gateway-2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "tls.crt", certPEM, time.Now())
+	writeFile(t, dir, "tls.key", keyPEM, time.Now())
+	srv.CloseClientConnections()
+	if got := get(); got != "gateway-2" {
+		t.Errorf("after rotation upstream saw %q, want gateway-2", got)
+	}
+}
+
+func TestUpstreamTLSValidation(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := writeFile(t, dir, "ca.txt", []byte("not a certificate"), time.Now())
+	for name, tc := range map[string]struct {
+		upstream string
+		tls      UpstreamTLS
+		want     string
+	}{
+		"plain http":     {"http://a", UpstreamTLS{ServerName: "a"}, "is not https"},
+		"key without":    {"https://a", UpstreamTLS{KeyFile: "tls.key"}, "must be set together"},
+		"skip and CA":    {"https://a", UpstreamTLS{InsecureSkipVerify: true, CAFile: notPEM}, "insecure_skip_verify ignores"},
+		"missing CA":     {"https://a", UpstreamTLS{CAFile: filepath.Join(dir, "absent.pem")}, "ca_file"},
+		"CA not PEM":     {"https://a", UpstreamTLS{CAFile: notPEM}, "no PEM certificates"},
+		"missing client": {"https://a", UpstreamTLS{CertFile: filepath.Join(dir, "absent.crt"), KeyFile: filepath.Join(dir, "absent.key")}, "TLS certificate"},
+	} {
+		_, err := New([]Route{{PathPrefix: "/a", Upstream: tc.upstream, TLS: tc.tls}})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestVerifyUpstreams(t *testing.T) {
+	ca := newTestCA(t)
+	srv := mtlsUpstream(t, ca)
+	plain := upstream(t, "plain")
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", ca.pem, time.Now())
+	certPEM, keyPEM := ca.issue(t, "gateway", x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "tls.crt", certPEM, time.Now())
+	keyFile := writeFile(t, dir, "tls.key", keyPEM, time.Now())
+	otherCA := writeFile(t, dir, "other.pem", newTestCA(t).pem, time.Now())
+	mtls := UpstreamTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+
+	good := []Route{
+		{PathPrefix: "/docs", Upstream: srv.URL, TLS: mtls, Auth: "required"},
+		{PathPrefix: "/docs/v2", Upstream: srv.URL + "/v2", TLS: mtls},
+		{PathPrefix: "/plain", Upstream: plain.URL},
+	}
+	if err := VerifyUpstreams(context.Background(), good, time.Second); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + ln.Addr().String()
+	ln.Close()
+	err = VerifyUpstreams(context.Background(), []Route{
+		{PathPrefix: "/no-cert", Upstream: srv.URL, TLS: UpstreamTLS{CAFile: caFile}},
+		{PathPrefix: "/wrong-ca", Upstream: srv.URL, TLS: UpstreamTLS{CAFile: otherCA, CertFile: certFile, KeyFile: keyFile}},
+		{PathPrefix: "/down", Upstream: closed},
+		{PathPrefix: "/ok", Upstream: plain.URL},
+	}, time.Second)
+	for _, want := range []string{"route 0 (/no-cert)", "route 1 (/wrong-ca)", "unknown authority", "route 2 (/down)"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "/ok") {
+		t.Errorf("reachable upstream reported: %v", err)
+	}
+}
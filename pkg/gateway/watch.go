@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// watchDebounce lets an editor's burst of write, rename, and chmod events
+// settle into one reload.
+const watchDebounce = 100 * time.Millisecond
+
+// LoadRoutesFile reads the routes key of a YAML file, such as the
+// service's CONFIG_FILE. Other top-level keys are ignored; unknown keys
+// inside a route are errors, as they are for config.Load.
+func LoadRoutesFile(path string) ([]Route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Routes yaml.Node `yaml:"routes"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("gateway: %s: %w", path, err)
+	}
+	if doc.Routes.Kind == 0 {
+		return nil, fmt.Errorf("gateway: %s: no routes key", path)
+	}
+	// Node.Decode cannot reject unknown fields, so re-encode the routes
+	// and decode them strictly.
+	raw, err := yaml.Marshal(&doc.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("gateway: %s: %w", path, err)
+	}
+	var routes []Route
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&routes); err != nil {
+		return nil, fmt.Errorf("gateway: %s: routes: %w", path, err)
+	}
+	return routes, nil
+}
+
+// WatchFile reloads rt from the routes in path whenever the file changes
+// or the process receives SIGHUP, until ctx is done. It watches the
+// file's directory rather than the file, so atomic saves (write then
+// rename) and Kubernetes ConfigMap symlink swaps are seen too; changes
+// that leave the file's content as it was are skipped, while SIGHUP
+// always reloads. A file that fails to read or parse is logged and
+// rejected like an invalid table. WatchFile returns an error only if the
+// watch cannot be set up.
+func (rt *Router) WatchFile(ctx context.Context, path string) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	return rt.watch(ctx, path, hup)
+}
+
+// watch is WatchFile with the SIGHUP channel supplied, for tests.
+func (rt *Router) watch(ctx context.Context, path string, hup <-chan os.Signal) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("gateway: watch %s: %w", path, err)
+	}
+	defer w.Close()
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("gateway: watch %s: %w", path, err)
+	}
+
+	last, _ := os.ReadFile(path)
+	reload := func(force bool) {
+		data, err := os.ReadFile(path)
+		if err == nil && !force && bytes.Equal(data, last) {
+			return
+		}
+		last = data
+		routes, err := LoadRoutesFile(path)
+		if err != nil {
+			rt.logger().Error("gateway reload rejected", "file", path, "error", err)
+			res := ReloadResult{Time: time.Now(), Error: err.Error(), Added: []string{}, Removed: []string{}, Changed: []string{}}
+			rt.mu.Lock()
+			rt.last.Store(&res)
+			rt.mu.Unlock()
+			return
+		}
+		rt.Reload(routes)
+	}
+
+	debounce := time.NewTimer(0)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			debounce.Stop()
+			return nil
+		case <-hup:
+			reload(true)
+		case _, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			debounce.Reset(watchDebounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			rt.logger().Warn("gateway route watch error", "file", path, "error", err)
+		case <-debounce.C:
+			reload(false)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package health serves a /healthz endpoint that aggregates dependency
+// checks, such as a database ping or an upstream request, into one status.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check registered without its own timeout.
+const DefaultTimeout = 2 * time.Second
+
+// Checker reports whether one dependency is usable. Check should return
+// promptly once ctx is done; the handler stops waiting for it either way.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Handler runs every registered check concurrently on each request and
+// answers 200 if all pass and 503 with the failures otherwise.
+type Handler struct {
+	// Info is merged into every response body, e.g. the service name and
+	// build metadata.
+	Info map[string]any
+	// Deadline bounds a whole request on top of each check's own timeout;
+	// zero leaves only the per-check timeouts.
+	Deadline time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]check
+}
+
+type check struct {
+	checker Checker
+	timeout time.Duration
+}
+
+// CheckResult is one check's entry in the response.
+type CheckResult struct {
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// NewHandler returns a Handler with no checks; it reports healthy until
+// checks are registered.
+func NewHandler() *Handler {
+	return &Handler{checks: map[string]check{}}
+}
+
+// Register adds a check under name with DefaultTimeout, replacing any
+// check already registered under it.
+func (h *Handler) Register(name string, c Checker) {
+	h.RegisterWithTimeout(name, DefaultTimeout, c)
+}
+
+// RegisterWithTimeout adds a check that fails if it has not returned
+// within timeout.
+func (h *Handler) RegisterWithTimeout(name string, timeout time.Duration, c Checker) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{checker: c, timeout: timeout}
+}
+
+// Check runs every registered check and returns the results by name.
+func (h *Handler) Check(ctx context.Context) map[string]CheckResult {
+	h.mu.RLock()
+	checks := make(map[string]Checker, len(h.checks))
+	for name, c := range h.checks {
+		checks[name] = CheckerFunc(func(ctx context.Context) error { return run(ctx, c.checker, c.timeout) })
+	}
+	h.mu.RUnlock()
+	return Aggregate(ctx, h.Deadline, checks)
+}
+
+// Aggregate runs checks concurrently and collects their results by name
+// within deadline: a check still running then is reported as timed out.
+// A zero deadline waits as long as ctx allows.
+func Aggregate(ctx context.Context, deadline time.Duration, checks map[string]Checker) map[string]CheckResult {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		out = make(map[string]CheckResult, len(checks))
+	)
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c Checker) {
+			defer wg.Done()
+			start := time.Now()
+			err := run(ctx, c, deadline)
+			res := CheckResult{OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			mu.Lock()
+			out[name] = res
+			mu.Unlock()
+		}(name, c)
+	}
+	wg.Wait()
+	return out
+}
+
+// run calls c in its own goroutine so that a checker ignoring its context
+// cannot hold the response past timeout, when there is one.
+func run(ctx context.Context, c Checker, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) && timeout > 0 {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	results := h.Check(r.Context())
+	var unhealthy []string
+	for name, res := range results {
+		if !res.OK {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	sort.Strings(unhealthy)
+	ok := len(unhealthy) == 0
+	body := make(map[string]any, len(h.Info)+3)
+	for k, v := range h.Info {
+		body[k] = v
+	}
+	body["ok"] = ok
+	if len(results) > 0 {
+		body["checks"] = results
+	}
+	if !ok {
+		body["unhealthy"] = unhealthy
+	}
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Pinger is implemented by *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping checks a database connection.
+func Ping(db Pinger) Checker {
+	return CheckerFunc(db.PingContext)
+}
+
+// HTTPGet checks that url answers a GET with a status below 500, so an
+// upstream that is up but rejects anonymous requests still counts as
+// reachable. A nil client uses http.DefaultClient.
+func HTTPGet(client *http.Client, url string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		return nil
+	})
+}
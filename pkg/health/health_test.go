@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type healthBody struct {
+	OK        bool                   `json:"ok"`
+	Service   string                 `json:"service"`
+	Checks    map[string]CheckResult `json:"checks"`
+	Unhealthy []string               `json:"unhealthy"`
+}
+
+func get(t *testing.T, h http.Handler) (int, healthBody) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	var body healthBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	return rec.Code, body
+}
+
+func TestHandlerHealthy(t *testing.T) {
+	h := NewHandler()
+	h.Info = map[string]any{"service": "billing"}
+	if code, body := get(t, h); code != http.StatusOK || !body.OK || body.Service != "billing" {
+		t.Errorf("no checks: %d %+v", code, body)
+	}
+	h.Register("db", CheckerFunc(func(context.Context) error { return nil }))
+	if code, body := get(t, h); code != http.StatusOK || !body.Checks["db"].OK {
+		t.Errorf("passing check: %d %+v", code, body)
+	}
+}
+
+func TestHandlerReportsFailures(t *testing.T) {
+	h := NewHandler()
+	h.Register("db", CheckerFunc(func(context.Context) error { return nil }))
+	h.Register("upstream", CheckerFunc(func(context.Context) error { return errors.New("connection refused") }))
+	code, body := get(t, h)
+	if code != http.StatusServiceUnavailable || body.OK {
+		t.Fatalf("got %d ok=%v, want 503", code, body.OK)
+	}
+	if !body.Checks["db"].OK || body.Checks["upstream"].OK || body.Checks["upstream"].Error != "connection refused" {
+		t.Errorf("checks = %+v", body.Checks)
+	}
+	if len(body.Unhealthy) != 1 || body.Unhealthy[0] != "upstream" {
+		t.Errorf("unhealthy = %v, want [upstream]", body.Unhealthy)
+	}
+}
+
+func TestHandlerTimesOutSlowChecks(t *testing.T) {
+	h := NewHandler()
+	block := make(chan struct{})
+	defer close(block)
+	// The checker ignores its context; the handler must still answer.
+	h.RegisterWithTimeout("stuck", 20*time.Millisecond, CheckerFunc(func(context.Context) error { <-block; return nil }))
+	h.RegisterWithTimeout("slow", 20*time.Millisecond, CheckerFunc(func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }))
+
+	start := time.Now()
+	code, body := get(t, h)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("handler took %s", elapsed)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", code)
+	}
+	for _, name := range []string{"stuck", "slow"} {
+		if c := body.Checks[name]; c.OK || c.Error != "timed out after 20ms" {
+			t.Errorf("%s = %+v", name, c)
+		}
+	}
+}
+
+func TestAggregateDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	start := time.Now()
+	got := Aggregate(context.Background(), 30*time.Millisecond, map[string]Checker{
+		"fast":  CheckerFunc(func(context.Context) error { return nil }),
+		"stuck": CheckerFunc(func(context.Context) error { <-block; return nil }),
+		"slow":  CheckerFunc(func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }),
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Aggregate took %s", elapsed)
+	}
+	if !got["fast"].OK {
+		t.Errorf("fast = %+v", got["fast"])
+	}
+	for _, name := range []string{"stuck", "slow"} {
+		if c := got[name]; c.OK || c.Error != "timed out after 30ms" {
+			t.Errorf("%s = %+v", name, got[name])
+		}
+	}
+}
+
+func TestHandlerDeadline(t *testing.T) {
+	h := NewHandler()
+	h.Deadline = 20 * time.Millisecond
+	h.RegisterWithTimeout("slow", time.Minute, CheckerFunc(func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }))
+	code, body := get(t, h)
+	if code != http.StatusServiceUnavailable || body.Checks["slow"].Error != "timed out after 20ms" {
+		t.Errorf("got %d %+v, want the handler deadline to cut the check short", code, body)
+	}
+}
+
+func TestHTTPGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	if err := HTTPGet(srv.Client(), srv.URL+"/up").Check(context.Background()); err != nil {
+		t.Errorf("4xx upstream should count as reachable: %v", err)
+	}
+	if err := HTTPGet(srv.Client(), srv.URL+"/down").Check(context.Background()); err == nil {
+		t.Error("5xx upstream should fail")
+	}
+}
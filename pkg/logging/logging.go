@@ -0,0 +1,80 @@
+// Package logging builds the structured slog loggers used by the Go
+// services and carries request-scoped loggers through contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Output formats accepted by NewLogger.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// EnvVar names the deployment profile variable (dev, staging, or prod)
+// FormatFromEnv reads.
+const EnvVar = "APP_ENV"
+
+// NewLogger returns a logger writing format ("json" or "text") to stderr
+// at level and above, with sensitive attributes redacted. An unknown
+// format falls back to JSON so production output stays machine-readable.
+// Pass a *slog.LevelVar as level to change it while the process runs.
+func NewLogger(level slog.Leveler, format string) *slog.Logger {
+	return newLogger(os.Stderr, level, format)
+}
+
+func newLogger(w io.Writer, level slog.Leveler, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if format == FormatText {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(NewRedactHandler(h))
+}
+
+// FormatFromEnv picks text for local development (APP_ENV unset or dev)
+// and JSON everywhere else.
+func FormatFromEnv() string {
+	switch os.Getenv(EnvVar) {
+	case "", "dev":
+		return FormatText
+	}
+	return FormatJSON
+}
+
+// ParseLevel reads a level name such as "debug" or "warn"; empty means
+// info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("log level: %w", err)
+	}
+	return level, nil
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying l for FromContext.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored by NewContext, or slog.Default()
+// outside a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf, slog.LevelInfo, FormatJSON).With("api_key", "k-123")
+	l.Info("login",
+		"user", "stu-1",
+		"password", "hunter2",
+		"Access-Token", "eyJhbGciOi",
+		"header", "Bearer eyJhbGciOi",
+		slog.Group("smtp", "addr", "mail:587", "secret", "pw"),
+	)
+	out := buf.String()
+	for _, leaked := range []string{"k-123", "hunter2", "eyJhbGciOi", `"pw"`} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("log leaked %s: %s", leaked, out)
+		}
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["user"] != "stu-1" || line["password"] != Redacted || line["api_key"] != Redacted {
+		t.Errorf("unexpected attributes: %v", line)
+	}
+	if smtp, _ := line["smtp"].(map[string]any); smtp["addr"] != "mail:587" || smtp["secret"] != Redacted {
+		t.Errorf("group not redacted field by field: %v", line["smtp"])
+	}
+}
+
+func TestNewLoggerFormatAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf, slog.LevelWarn, FormatText)
+	l.Info("dropped")
+	l.Warn("kept", "n", 1)
+	if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "level=WARN msg=kept n=1") {
+		t.Errorf("text output: %q", got)
+	}
+
+	for _, tt := range []struct{ in, want string }{{"", FormatText}, {"dev", FormatText}, {"staging", FormatJSON}, {"prod", FormatJSON}} {
+		t.Setenv(EnvVar, tt.in)
+		if got := FormatFromEnv(); got != tt.want {
+			t.Errorf("APP_ENV=%q: format %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("ParseLevel(debug) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("empty context should yield slog.Default()")
+	}
+	l := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if FromContext(NewContext(context.Background(), l)) != l {
+		t.Error("stored logger not returned")
+	}
+}
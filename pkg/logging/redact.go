@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against attribute keys lower-cased with "_"
+// and "-" removed, so access_token, X-Api-Key, and Password all match.
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "authorization", "apikey", "cookie"}
+
+// redactHandler masks sensitive attributes before they reach the wrapped
+// handler.
+type redactHandler struct {
+	inner slog.Handler
+}
+
+// NewRedactHandler wraps h so that attributes whose key names a password,
+// secret, token, API key, cookie, or authorization header, and string
+// values carrying a bearer token, are logged as Redacted. Groups are
+// searched too.
+func NewRedactHandler(h slog.Handler) slog.Handler {
+	return &redactHandler{inner: h}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redact(a))
+		return true
+	})
+	return h.inner.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redact(a)
+	}
+	return &redactHandler{inner: h.inner.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{inner: h.inner.WithGroup(name)}
+}
+
+func redact(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, g := range group {
+			redacted[i] = redact(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case Sensitive(a.Key):
+		return slog.String(a.Key, Redacted)
+	case a.Value.Kind() == slog.KindString && hasBearer(a.Value.String()):
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// Sensitive reports whether key names a password, secret, token, API key,
+// cookie, or authorization header, the attributes NewRedactHandler masks.
+func Sensitive(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBearer(s string) bool {
+	return strings.Contains(strings.ToLower(s), "bearer ")
+}
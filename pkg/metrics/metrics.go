@@ -0,0 +1,194 @@
+// Package metrics exposes a Go service's standard HTTP metrics in the
+// Prometheus text format:
+//
+//	m := metrics.New(metrics.Options{})
+//	h := m.Middleware(routeName)(mux)
+//	admin.Handle("GET /metrics", m.Handler())
+//
+// Request series are labeled by route name and status class (2xx, 4xx,
+// ...), never by raw path, so path parameters cannot grow the label set
+// without bound. Serve Handler on a listener of its own, such as
+// METRICS_PORT, so the public one does not expose it.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/buildinfo"
+)
+
+// Unmatched is the route label of requests no route claims.
+const Unmatched = "unmatched"
+
+// DefaultSizeBuckets are the response size histogram's bounds: 100 bytes
+// to 10 MB in powers of ten.
+var DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+
+// Options configures New. The zero value registers with a new registry
+// and uses the default buckets.
+type Options struct {
+	// Registry receives the collectors; nil means a new registry.
+	Registry *prometheus.Registry
+	// DurationBuckets are the request duration histogram's upper bounds
+	// in seconds; nil means prometheus.DefBuckets.
+	DurationBuckets []float64
+	// SizeBuckets are the response size histogram's upper bounds in
+	// bytes; nil means DefaultSizeBuckets.
+	SizeBuckets []float64
+}
+
+// HTTP holds the request collectors and the registry they, and the
+// build_info gauge, are registered with.
+type HTTP struct {
+	Registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	size     *prometheus.HistogramVec
+}
+
+// New registers the HTTP collectors and BuildInfo. Call it after
+// buildinfo.Set, since the build labels are read once.
+func New(opts Options) *HTTP {
+	reg := opts.Registry
+	if reg == nil {
+		reg = prometheus.NewRegistry()
+	}
+	durations := opts.DurationBuckets
+	if durations == nil {
+		durations = prometheus.DefBuckets
+	}
+	sizes := opts.SizeBuckets
+	if sizes == nil {
+		sizes = DefaultSizeBuckets
+	}
+	m := &HTTP{
+		Registry: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route and status class.",
+		}, []string{"route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route and status class.",
+			Buckets: durations,
+		}, []string{"route", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served, by route.",
+		}, []string{"route"}),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body sizes by route and status class.",
+			Buckets: sizes,
+		}, []string{"route", "status"}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.inFlight, m.size, BuildInfo())
+	return m
+}
+
+// Middleware records every request under the route name routeOf returns
+// for it; an empty name is recorded as Unmatched. routeOf runs before the
+// request is served, so the in-flight gauge carries the route too.
+func (m *HTTP) Middleware(routeOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeOf(r)
+			if route == "" {
+				route = Unmatched
+			}
+			inFlight := m.inFlight.WithLabelValues(route)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			rec := &sizeRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+			class := StatusClass(rec.status)
+			m.requests.WithLabelValues(route, class).Inc()
+			m.duration.WithLabelValues(route, class).Observe(time.Since(start).Seconds())
+			m.size.WithLabelValues(route, class).Observe(float64(rec.bytes))
+		})
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *HTTP) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// StatusClass returns the class label of an HTTP status, such as "2xx".
+func StatusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// BuildInfo returns a build_info gauge, always 1, labeled with the
+// buildinfo metadata and the Go version, so dashboards can tell which
+// build each instance runs.
+func BuildInfo() prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "build_info",
+		Help: "Build metadata of the running binary; always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":    buildinfo.Version,
+			"commit":     buildinfo.Commit,
+			"build_time": buildinfo.BuildTime,
+			"go_version": runtime.Version(),
+		},
+	}, func() float64 { return 1 })
+}
+
+// sizeRecorder remembers the status and body size written through it; a
+// handler that never calls WriteHeader answers 200, and a hijacked
+// connection is recorded as 101 Switching Protocols.
+type sizeRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *sizeRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = code, true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *sizeRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer, for server-sent events
+// and other streamed responses.
+func (r *sizeRecorder) Flush() {
+	r.wroteHeader = true
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack passes through to the underlying writer, for WebSocket
+// upgrades.
+func (r *sizeRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status, r.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *sizeRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
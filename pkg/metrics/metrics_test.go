@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/buildinfo"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New(Options{DurationBuckets: []float64{0.1, 1}})
+	var inFlight float64
+	h := m.Middleware(func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/v1/") {
+			return "applications"
+		}
+		return ""
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/applications/app-1" {
+			inFlight = testutil.ToFloat64(m.inFlight.WithLabelValues("applications"))
+		}
+		switch r.URL.Path {
+		case "/v1/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			w.Write([]byte("hello"))
+		}
+	}))
+	for _, path := range []string{"/v1/applications/app-1", "/v1/applications/app-2", "/v1/missing", "/x"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if inFlight != 1 {
+		t.Errorf("in flight while serving = %v, want 1", inFlight)
+	}
+	if got := testutil.ToFloat64(m.inFlight.WithLabelValues("applications")); got != 0 {
+		t.Errorf("in flight after serving = %v, want 0", got)
+	}
+	for _, tc := range []struct {
+		route, status string
+		want          float64
+	}{
+		{"applications", "2xx", 2},
+		{"applications", "4xx", 1},
+		{Unmatched, "2xx", 1},
+	} {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tc.route, tc.status)); got != tc.want {
+			t.Errorf("requests{route=%s,status=%s} = %v, want %v", tc.route, tc.status, got, tc.want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.requests); got != 3 {
+		t.Errorf("request series = %d, want 3: raw paths must not become labels", got)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_request_duration_seconds_bucket{route="applications",status="2xx",le="0.1"} 2`,
+		`http_response_size_bytes_sum{route="applications",status="2xx"} 10`,
+		`http_requests_total{route="unmatched",status="2xx"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that can also be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestRecorderPassThrough(t *testing.T) {
+	m := New(Options{})
+	mw := m.Middleware(func(r *http.Request) string { return r.URL.Path })
+	up := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Errorf("hijack: %v", err)
+		}
+	})).ServeHTTP(up, httptest.NewRequest("GET", "/ws", nil))
+	if !up.hijacked {
+		t.Error("Hijack did not reach the underlying writer")
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("/ws", "1xx")); got != 1 {
+		t.Errorf("requests{route=/ws,status=1xx} = %v, want the upgrade counted", got)
+	}
+
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+	if !rec.Flushed {
+		t.Error("Flush did not reach the underlying writer")
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	defer buildinfo.Set("", "", "")
+	buildinfo.Set("v1.2.0", "0a1b2c3", "")
+	m := New(Options{})
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `build_time="unknown",commit="0a1b2c3",go_version="go`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("exposition lacks %q:\n%s", want, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `version="v1.2.0"} 1`) {
+		t.Errorf("build_info is not 1 with the version:\n%s", w.Body.String())
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{200: "2xx", 201: "2xx", 302: "3xx", 404: "4xx", 503: "5xx"} {
+		if got := StatusClass(status); got != want {
+			t.Errorf("StatusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+// Package cors is Cross-Origin Resource Sharing middleware for any
+// net/http service: it answers browsers' OPTIONS preflights itself and
+// marks the responses to allowed origins so their scripts may read them.
+//
+//	rules, err := cors.Compile(cors.Policy{
+//		AllowedOrigins:   []string{"https://app.uniassist.app", "https://*.uniassist.app"},
+//		AllowCredentials: true,
+//	})
+//	h := cors.New(func(*http.Request) *cors.Rules { return rules })(mux)
+//
+// Origins are matched on scheme, host, and port together, so
+// https://app.uniassist.app does not admit http://app.uniassist.app or
+// https://app.uniassist.app:8443. The middleware owns the CORS headers of
+// the responses it handles: those the wrapped handler sets are replaced.
+package cors
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMethods are the methods a Policy without AllowedMethods allows.
+var DefaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// Policy is who may call a service from a browser script, and how. The
+// zero Policy means the middleware stays out of the way entirely.
+type Policy struct {
+	// AllowedOrigins are exact origins such as https://uniassist.app,
+	// patterns with a leading wildcard label such as
+	// https://*.uniassist.app, which matches any subdomain but not the
+	// domain itself, or "*" alone for any origin. A port other than the
+	// scheme's default has to be written out.
+	AllowedOrigins []string `yaml:"allowed_origins" json:"allowed_origins"`
+	// AllowedMethods are the methods preflights may ask for; empty means
+	// DefaultMethods.
+	AllowedMethods []string `yaml:"allowed_methods" json:"allowed_methods,omitempty"`
+	// AllowedHeaders are the request headers preflights may ask for, or
+	// "*" for any; the CORS-safelisted ones need no listing.
+	AllowedHeaders []string `yaml:"allowed_headers" json:"allowed_headers,omitempty"`
+	// ExposedHeaders are the response headers beyond the safelisted ones
+	// that scripts may read.
+	ExposedHeaders []string `yaml:"exposed_headers" json:"exposed_headers,omitempty"`
+	// AllowCredentials lets requests carry cookies and Authorization. It
+	// cannot be combined with the "*" origin.
+	AllowCredentials bool `yaml:"allow_credentials" json:"allow_credentials,omitempty"`
+	// MaxAge is how long browsers may cache a preflight's answer; zero
+	// leaves it to them.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age,omitempty"`
+}
+
+// IsZero reports whether p sets nothing.
+func (p Policy) IsZero() bool {
+	return len(p.AllowedOrigins) == 0 && len(p.AllowedMethods) == 0 && len(p.AllowedHeaders) == 0 &&
+		len(p.ExposedHeaders) == 0 && !p.AllowCredentials && p.MaxAge == 0
+}
+
+// Validate reports every problem with p.
+func (p Policy) Validate() error {
+	_, err := Compile(p)
+	return err
+}
+
+// Rules is a compiled Policy.
+type Rules struct {
+	policy   Policy
+	any      bool
+	exact    map[origin]bool
+	suffixes []origin // host is the part after "*."
+	methods  []string
+	headers  map[string]bool // lowercased
+	anyHdr   bool            // allowed_headers has "*"
+	allow    string          // Access-Control-Allow-Methods
+	expose   string
+	maxAge   string
+}
+
+// origin is a parsed, normalized origin: lowercased, with the port
+// always written out.
+type origin struct {
+	scheme, host, port string
+}
+
+// Compile checks p and prepares it for New. A zero Policy compiles to
+// nil Rules, which New passes through.
+func Compile(p Policy) (*Rules, error) {
+	if p.IsZero() {
+		return nil, nil
+	}
+	r := &Rules{policy: p, exact: map[origin]bool{}, headers: map[string]bool{}}
+	var errs []error
+	if len(p.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("allowed_origins is required"))
+	}
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			r.any = true
+			continue
+		}
+		plain, wildcard := o, false
+		if scheme, rest, ok := strings.Cut(o, "://*."); ok {
+			plain, wildcard = scheme+"://"+rest, true
+		}
+		parsed, err := parseOrigin(plain)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("allowed_origins: %q: %w", o, err))
+			continue
+		}
+		if wildcard {
+			r.suffixes = append(r.suffixes, parsed)
+		} else {
+			r.exact[parsed] = true
+		}
+	}
+	if r.any && p.AllowCredentials {
+		errs = append(errs, errors.New(`allowed_origins "*" cannot be combined with allow_credentials: browsers refuse it; list the origins`))
+	}
+	r.methods = DefaultMethods
+	if len(p.AllowedMethods) > 0 {
+		r.methods = nil
+		for _, m := range p.AllowedMethods {
+			if !isToken(m) {
+				errs = append(errs, fmt.Errorf("allowed_methods: %q is not a method", m))
+			}
+			r.methods = append(r.methods, strings.ToUpper(m))
+		}
+	}
+	for _, h := range p.AllowedHeaders {
+		switch {
+		case h == "*":
+			r.anyHdr = true
+		case !isToken(h):
+			errs = append(errs, fmt.Errorf("allowed_headers: %q is not a header name", h))
+		default:
+			r.headers[strings.ToLower(h)] = true
+		}
+	}
+	for _, h := range p.ExposedHeaders {
+		if !isToken(h) {
+			errs = append(errs, fmt.Errorf("exposed_headers: %q is not a header name", h))
+		}
+	}
+	if p.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max_age %v: must not be negative", p.MaxAge))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	r.allow = strings.Join(r.methods, ", ")
+	r.expose = strings.Join(p.ExposedHeaders, ", ")
+	if p.MaxAge > 0 {
+		r.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return r, nil
+}
+
+// Policy returns the Policy r was compiled from.
+func (r *Rules) Policy() Policy { return r.policy }
+
+// parseOrigin parses scheme://host[:port] with nothing after it.
+func parseOrigin(s string) (origin, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return origin{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return origin{}, errors.New("want an http or https origin")
+	}
+	if strings.Contains(u.Host, "*") {
+		return origin{}, errors.New("a wildcard must be the whole first label, as in https://*.example.com")
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return origin{}, errors.New("want scheme://host[:port] and nothing more")
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	return origin{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: port}, nil
+}
+
+// Allows reports whether an Origin header value is admitted. Nil Rules
+// admit none.
+func (r *Rules) Allows(origin string) bool {
+	return r != nil && r.allows(origin)
+}
+
+// allows reports whether the request's Origin header is admitted.
+func (r *Rules) allows(o string) bool {
+	if r.any {
+		return true
+	}
+	parsed, err := parseOrigin(o)
+	if err != nil {
+		return false
+	}
+	if r.exact[parsed] {
+		return true
+	}
+	for _, s := range r.suffixes {
+		if parsed.scheme == s.scheme && parsed.port == s.port && strings.HasSuffix(parsed.host, "."+s.host) {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns middleware applying the Rules rulesFor returns for each
+// request; nil Rules pass the request and response through untouched.
+//
+// A preflight, an OPTIONS request with Origin and
+// Access-Control-Request-Method, is answered here and never reaches the
+// next handler: 204 with the allowances when the origin, method, and
+// headers are allowed, 403 otherwise. Other requests from an allowed
+// origin are answered by the next handler with Access-Control-Allow-Origin
+// added; those from other origins get no CORS headers, so browsers keep
+// their responses from the calling script.
+func New(rulesFor func(*http.Request) *Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rules := rulesFor(req)
+			if rules == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+			o := req.Header.Get("Origin")
+			if req.Method == http.MethodOptions && o != "" && req.Header.Get("Access-Control-Request-Method") != "" {
+				rules.preflight(w, req, o)
+				return
+			}
+			next.ServeHTTP(&corsWriter{ResponseWriter: w, rules: rules, origin: o}, req)
+		})
+	}
+}
+
+func (r *Rules) preflight(w http.ResponseWriter, req *http.Request, o string) {
+	h := w.Header()
+	h.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	method := req.Header.Get("Access-Control-Request-Method")
+	requested := requestedHeaders(req)
+	ok := r.allows(o) && slices.Contains(r.methods, method)
+	for _, name := range requested {
+		ok = ok && (r.anyHdr || r.headers[strings.ToLower(name)])
+	}
+	if !ok {
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"cross-origin request not allowed","code":"CORS_FORBIDDEN"}` + "\n"))
+		return
+	}
+	r.setOrigin(h, o)
+	h.Set("Access-Control-Allow-Methods", r.allow)
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if r.maxAge != "" {
+		h.Set("Access-Control-Max-Age", r.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin admits o. A credentialed policy never has the "*" origin, so
+// "*" is never sent with Allow-Credentials.
+func (r *Rules) setOrigin(h http.Header, o string) {
+	if r.any {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", o)
+	if r.policy.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func requestedHeaders(req *http.Request) []string {
+	var names []string
+	for _, v := range req.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// corsHeaders are the response headers the middleware owns.
+var corsHeaders = []string{
+	"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Expose-Headers",
+	"Access-Control-Allow-Methods", "Access-Control-Allow-Headers", "Access-Control-Max-Age",
+}
+
+// corsWriter sets the CORS headers as the response header is written,
+// over any the handler set, so that a handler replaying a stored
+// response, such as a cache, cannot answer with another origin's.
+type corsWriter struct {
+	http.ResponseWriter
+	rules       *Rules
+	origin      string
+	wroteHeader bool
+}
+
+func (w *corsWriter) apply() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	for _, k := range corsHeaders {
+		h.Del(k)
+	}
+	if !w.rules.any && !varies(h, "Origin") {
+		h.Add("Vary", "Origin")
+	}
+	if w.origin == "" || !w.rules.allows(w.origin) {
+		return
+	}
+	w.rules.setOrigin(h, w.origin)
+	if w.rules.expose != "" {
+		h.Set("Access-Control-Expose-Headers", w.rules.expose)
+	}
+}
+
+func (w *corsWriter) WriteHeader(code int) {
+	if code >= 200 {
+		w.apply()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *corsWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes through to the underlying writer, for server-sent events
+// and other streamed responses.
+func (w *corsWriter) Flush() {
+	w.apply()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack passes through to the underlying writer, for WebSocket
+// upgrades.
+func (w *corsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *corsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// varies reports whether h's Vary lists name already.
+func varies(h http.Header, name string) bool {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isToken reports whether s is a non-empty RFC 9110 token, the syntax of
+// methods and header names.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c >= 0x7f || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
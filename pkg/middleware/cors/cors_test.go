@@ -0,0 +1,196 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustCompile(t *testing.T, p Policy) *Rules {
+	t.Helper()
+	r, err := Compile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCompileRejectsBadPolicies(t *testing.T) {
+	_, err := Compile(Policy{
+		AllowedOrigins:   []string{"*", "ftp://files", "https://app.uniassist.app/path", "https://a.*.uniassist.app", "uniassist.app"},
+		AllowedMethods:   []string{"GET", "BAD METHOD"},
+		AllowedHeaders:   []string{"X-Ok", "Bad:Header"},
+		AllowCredentials: true,
+		MaxAge:           -time.Second,
+	})
+	if err == nil {
+		t.Fatal("bad policy compiled")
+	}
+	for _, want := range []string{`"*" cannot be combined with allow_credentials`, `"ftp://files"`, `"https://app.uniassist.app/path"`, `"https://a.*.uniassist.app": a wildcard`, `"uniassist.app"`, `"BAD METHOD" is not a method`, `"Bad:Header" is not a header name`, "max_age -1s"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+	if _, err := Compile(Policy{MaxAge: time.Minute}); err == nil || !strings.Contains(err.Error(), "allowed_origins is required") {
+		t.Errorf("policy without origins: %v", err)
+	}
+	if r, err := Compile(Policy{}); r != nil || err != nil {
+		t.Errorf("zero policy = %v, %v; want nil rules", r, err)
+	}
+}
+
+func TestOriginMatching(t *testing.T) {
+	r := mustCompile(t, Policy{AllowedOrigins: []string{"https://app.uniassist.app", "https://*.uniassist.app", "http://localhost:3000"}})
+	for o, want := range map[string]bool{
+		"https://app.uniassist.app":      true,
+		"https://APP.uniassist.app":      true,
+		"https://app.uniassist.app:443":  true,
+		"http://app.uniassist.app":       false,
+		"https://app.uniassist.app:8443": false,
+		"https://portal.uniassist.app":   true,
+		"https://a.b.uniassist.app":      true,
+		"https://uniassist.app":          false,
+		"https://eviluniassist.app":      false,
+		"https://uniassist.app.evil.com": false,
+		"http://portal.uniassist.app":    false,
+		"http://localhost:3000":          true,
+		"http://localhost":               false,
+		"null":                           false,
+		"":                               false,
+	} {
+		if got := r.allows(o); got != want {
+			t.Errorf("allows(%q) = %v, want %v", o, got, want)
+		}
+	}
+}
+
+// upstream answers with CORS headers of its own, which the middleware
+// replaces.
+func upstream(called *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called++
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("X-Upstream", "yes")
+		w.Write([]byte("ok"))
+	})
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/applications", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+func TestPreflight(t *testing.T) {
+	rules := mustCompile(t, Policy{
+		AllowedOrigins:   []string{"https://*.uniassist.app"},
+		AllowedMethods:   []string{"GET", "put"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	var called int
+	h := New(func(*http.Request) *Rules { return rules })(upstream(&called))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, preflight("https://portal.uniassist.app", "PUT", "authorization, content-type"))
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://portal.uniassist.app",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Max-Age":           "600",
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("allowed preflight: %d", rec.Code)
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if !strings.Contains(rec.Header().Get("Vary"), "Origin") {
+		t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+	}
+
+	for name, req := range map[string]*http.Request{
+		"origin": preflight("https://uniassist.evil", "PUT", ""),
+		"method": preflight("https://portal.uniassist.app", "DELETE", ""),
+		"header": preflight("https://portal.uniassist.app", "GET", "X-Secret"),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" || !strings.Contains(rec.Body.String(), "CORS_FORBIDDEN") {
+			t.Errorf("disallowed %s: %d %v %s", name, rec.Code, rec.Header(), rec.Body)
+		}
+	}
+	if called != 0 {
+		t.Errorf("%d preflights forwarded", called)
+	}
+}
+
+func TestActualRequests(t *testing.T) {
+	credentialed := mustCompile(t, Policy{AllowedOrigins: []string{"https://app.uniassist.app"}, ExposedHeaders: []string{"X-Request-ID"}, AllowCredentials: true})
+	public := mustCompile(t, Policy{AllowedOrigins: []string{"*"}})
+	var called int
+	for _, tt := range []struct {
+		name, origin string
+		rules        *Rules
+		want         http.Header
+	}{
+		{"allowed", "https://app.uniassist.app", credentialed, http.Header{
+			"Access-Control-Allow-Origin":      {"https://app.uniassist.app"},
+			"Access-Control-Allow-Credentials": {"true"},
+			"Access-Control-Expose-Headers":    {"X-Request-ID"},
+			"Vary":                             {"Origin"},
+		}},
+		{"other origin", "https://evil.example", credentialed, http.Header{"Vary": {"Origin"}}},
+		{"no origin", "", credentialed, http.Header{"Vary": {"Origin"}}},
+		{"any origin", "https://evil.example", public, http.Header{"Access-Control-Allow-Origin": {"*"}}},
+	} {
+		h := New(func(*http.Request) *Rules { return tt.rules })(upstream(&called))
+		req := httptest.NewRequest(http.MethodPost, "/v1/applications", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got := rec.Header().Clone()
+		got.Del("X-Upstream")
+		got.Del("Content-Type")
+		if rec.Body.String() != "ok" || rec.Header().Get("X-Upstream") != "yes" {
+			t.Errorf("%s: response not passed through: %v %q", tt.name, rec.Header(), rec.Body)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: headers %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got.Get(k) != v[0] || len(got.Values(k)) != 1 {
+				t.Errorf("%s: %s = %q, want %q", tt.name, k, got.Values(k), v)
+			}
+		}
+		if got.Get("Access-Control-Allow-Origin") == "*" && got.Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("%s: credentials allowed with the * origin", tt.name)
+		}
+	}
+	if called != 4 {
+		t.Errorf("upstream called %d times, want 4", called)
+	}
+}
+
+func TestNilRulesPassThrough(t *testing.T) {
+	var called int
+	h := New(func(*http.Request) *Rules { return nil })(upstream(&called))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, preflight("https://app.uniassist.app", "PUT", ""))
+	if called != 1 || rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Vary") != "" {
+		t.Errorf("route without CORS: called %d, headers %v", called, rec.Header())
+	}
+}
@@ -0,0 +1,187 @@
+// Package logging is an access-log middleware for any net/http service:
+// one structured record per request, with the method, path, status,
+// bytes written, duration, client IP, and request ID, so a request can be
+// followed from the gateway into the upstream that served it.
+//
+//	h := logging.New(logging.Options{
+//		Logger:         logger,
+//		TrustedProxies: proxies,
+//		SampleRates:    map[string]float64{"/healthz": 0.01},
+//	})(mux)
+//
+// The request ID comes from pkg/middleware/requestid. Put requestid.New in
+// front to configure it; otherwise New assigns one with its defaults.
+//
+// It depends only on the standard library, pkg/logging, and
+// pkg/middleware/requestid, so services outside this module can import it
+// as is.
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	applog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// RequestIDHeader is requestid.Header.
+const RequestIDHeader = requestid.Header
+
+// Options configures New. The zero value logs every request as JSON to
+// stderr and takes the client IP from the connection.
+type Options struct {
+	// Logger receives the records; nil means a JSON logger on stderr.
+	Logger *slog.Logger
+	// TrustedProxies are the peers whose X-Forwarded-For is believed.
+	// From any other peer the header is ignored, so a client cannot
+	// choose the IP it is logged under.
+	TrustedProxies []netip.Prefix
+	// SampleRates logs only that fraction (0 to 1) of the requests to
+	// each exact path, such as 0.01 for a probe endpoint. Paths not
+	// listed are always logged, and so is any 5xx response.
+	SampleRates map[string]float64
+}
+
+// New returns middleware logging each request once it completes. It
+// stores a logger carrying the request ID in the request context for
+// pkg/logging's FromContext, so every line a handler logs through it
+// names the request.
+func New(opts Options) func(http.Handler) http.Handler {
+	log := opts.Logger
+	if log == nil {
+		log = applog.NewLogger(slog.LevelInfo, applog.FormatJSON)
+	}
+	return func(next http.Handler) http.Handler {
+		return requestid.New(requestid.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := log.With("request_id", requestid.FromContext(r.Context()))
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r.WithContext(applog.NewContext(r.Context(), l)))
+
+			if rec.status < http.StatusInternalServerError && !sampled(opts.SampleRates, r.URL.Path) {
+				return
+			}
+			l.Info("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_ip", ClientIP(r, opts.TrustedProxies),
+			)
+		}))
+	}
+}
+
+func sampled(rates map[string]float64, path string) bool {
+	rate, ok := rates[path]
+	return !ok || rand.Float64() < rate
+}
+
+// ClientIP returns the IP of the client behind r. X-Forwarded-For is read
+// only when the connection comes from a trusted proxy, right to left,
+// skipping the trusted hops; the first untrusted address is the client.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := parseAddr(r.RemoteAddr)
+	if !peer.IsValid() {
+		return r.RemoteAddr
+	}
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr := parseAddr(strings.TrimSpace(hops[i]))
+		if !addr.IsValid() {
+			break
+		}
+		client = addr
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// parseAddr reads an IP with or without a port; the zero Addr means it
+// is not one.
+func parseAddr(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseRecorder remembers the status and body size written through
+// it. A handler that never calls WriteHeader answers 200, and a hijacked
+// connection is recorded as 101 Switching Protocols.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = code, true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer, for server-sent events
+// and other streamed responses.
+func (r *responseRecorder) Flush() {
+	r.wroteHeader = true
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes through to the underlying writer, for WebSocket
+// upgrades.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("logging: response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status, r.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
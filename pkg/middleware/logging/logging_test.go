@@ -0,0 +1,189 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	applog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+)
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestNewLogsEachRequest(t *testing.T) {
+	var buf bytes.Buffer
+	var ctxID string
+	h := New(Options{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = r.Header.Get(RequestIDHeader)
+		applog.FromContext(r.Context()).Info("inside")
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/applications", nil)
+	req.RemoteAddr = "203.0.113.7:4711"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(RequestIDHeader)
+	if id == "" || ctxID != id {
+		t.Fatalf("response request ID %q, handler saw %q", id, ctxID)
+	}
+	got := entries(t, &buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d log lines, want the handler's and the access record", len(got))
+	}
+	if got[0]["request_id"] != id {
+		t.Errorf("context logger lacks the request ID: %v", got[0])
+	}
+	want := map[string]any{
+		"msg": "request", "method": "POST", "path": "/v1/applications", "status": float64(200),
+		"bytes": float64(5), "remote_ip": "203.0.113.7", "request_id": id,
+	}
+	for k, v := range want {
+		if got[1][k] != v {
+			t.Errorf("%s = %v, want %v", k, got[1][k], v)
+		}
+	}
+	if _, ok := got[1]["duration_ms"]; !ok {
+		t.Error("record lacks duration_ms")
+	}
+
+	buf.Reset()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "edge-42")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get(RequestIDHeader) != "edge-42" || entries(t, &buf)[1]["request_id"] != "edge-42" {
+		t.Errorf("client request ID not reused: %s", buf.String())
+	}
+}
+
+func TestNewRecordsWrittenStatus(t *testing.T) {
+	var buf bytes.Buffer
+	h := New(Options{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := entries(t, &buf)[0]["status"]; got != float64(http.StatusTeapot) {
+		t.Errorf("status = %v, want the first WriteHeader", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name, remote, xff, want string
+	}{
+		{"direct", "203.0.113.7:4711", "", "203.0.113.7"},
+		{"untrusted peer", "203.0.113.7:4711", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:4711", "198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "10.0.0.2:4711", "192.0.2.9, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"all trusted", "10.0.0.2:4711", "10.0.0.3", "10.0.0.3"},
+		{"garbage", "10.0.0.2:4711", "198.51.100.1, junk", "10.0.0.2"},
+		{"mapped", "[::ffff:203.0.113.7]:4711", "", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := ClientIP(req, trusted); got != tt.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewSampling(t *testing.T) {
+	var buf bytes.Buffer
+	status := http.StatusOK
+	h := New(Options{
+		Logger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+		SampleRates: map[string]float64{"/healthz": 0},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	for range 10 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	}
+	if buf.Len() != 0 {
+		t.Errorf("rate 0 logged:\n%s", buf.String())
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz/deep", nil))
+	if n := len(entries(t, &buf)); n != 1 {
+		t.Errorf("unlisted path logged %d times, want 1", n)
+	}
+	buf.Reset()
+	status = http.StatusServiceUnavailable
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	if n := len(entries(t, &buf)); n != 1 {
+		t.Errorf("sampled-out 503 logged %d times, want 1", n)
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that can also be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestRecorderPassThrough(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	up := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	New(Options{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Errorf("hijack: %v", err)
+		}
+	})).ServeHTTP(up, httptest.NewRequest("GET", "/ws", nil))
+	if !up.hijacked {
+		t.Error("Hijack did not reach the underlying writer")
+	}
+	if got := entries(t, &buf)[0]["status"]; got != float64(http.StatusSwitchingProtocols) {
+		t.Errorf("hijacked status = %v, want 101", got)
+	}
+
+	rec := httptest.NewRecorder()
+	New(Options{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+	if !rec.Flushed {
+		t.Error("Flush did not reach the underlying writer")
+	}
+
+	_, _, err := http.NewResponseController(&responseRecorder{ResponseWriter: httptest.NewRecorder()}).Hijack()
+	if err == nil {
+		t.Error("Hijack on a writer without it succeeded")
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultIdleTTL is how long Memory keeps a bucket nobody has used.
+const DefaultIdleTTL = 10 * time.Minute
+
+// Memory is a Limiter for a single instance; every replica of a
+// scaled-out service has its own budget with it. Buckets idle for the
+// TTL are evicted, so memory follows the clients active recently rather
+// than every client ever seen. Evicting a bucket forgets what it owed; a
+// TTL of at least Burst/Rate, the time to refill completely, makes that
+// invisible.
+type Memory struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewMemory returns an empty Memory evicting buckets idle for ttl, or
+// DefaultIdleTTL if ttl is not positive.
+func NewMemory(ttl time.Duration) *Memory {
+	if ttl <= 0 {
+		ttl = DefaultIdleTTL
+	}
+	return &Memory{ttl: ttl, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// Allow implements Limiter. Once per TTL it also sweeps out the idle
+// buckets, which keeps the cost of eviction constant per request.
+func (m *Memory) Allow(_ context.Context, key string, rule Rule) (Decision, error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) >= m.ttl {
+		m.sweep(now)
+	}
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), at: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+elapsed.Seconds()*rule.Rate)
+	}
+	b.at = now
+	var d Decision
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rule.Rate * float64(time.Second)))
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = time.Duration(math.Ceil((float64(rule.Burst) - b.tokens) / rule.Rate * float64(time.Second)))
+	return d, nil
+}
+
+// sweep drops the buckets unused for the TTL. It copies the rest into a
+// new map because a Go map never gives back the space of deleted
+// entries, so after a burst of distinct clients deleting alone would
+// keep the peak allocated.
+func (m *Memory) sweep(now time.Time) {
+	live := make(map[string]*bucket, len(m.buckets)/2)
+	for key, b := range m.buckets {
+		if now.Sub(b.at) < m.ttl {
+			live[key] = b
+		}
+	}
+	m.buckets, m.lastSweep = live, now
+}
+
+// Len returns the number of buckets held.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
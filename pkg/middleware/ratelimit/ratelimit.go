@@ -0,0 +1,149 @@
+// Package ratelimit is token-bucket rate-limiting middleware for any
+// net/http service. Each client gets a bucket per scope (typically a
+// route) holding up to Burst tokens and refilling at Rate per second; a
+// request takes one token or is answered 429 with Retry-After.
+//
+//	h := ratelimit.New(ratelimit.Options{TrustedProxies: proxies},
+//		func(*http.Request) (string, ratelimit.Rule, bool) {
+//			return "api", ratelimit.Rule{Rate: 5, Burst: 10}, true
+//		})(mux)
+//
+// Clients are keyed by their API key when they send one and by IP
+// otherwise. The buckets live behind the Limiter interface; the default,
+// Memory, keeps them in process and evicts idle ones, and Redis shares
+// them between the replicas of a scaled-out service.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	applog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/logging"
+	accesslog "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/logging"
+)
+
+// DefaultAPIKeyHeader is the header clients send their API key in.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// Rule is a budget: Burst requests at once, refilled at Rate per second.
+// The zero Rule means no limit.
+type Rule struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// IsZero reports whether r sets no limit.
+func (r Rule) IsZero() bool { return r == Rule{} }
+
+// Validate reports a rule that sets only part of a limit.
+func (r Rule) Validate() error {
+	if r.IsZero() {
+		return nil
+	}
+	var errs []error
+	if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) {
+		errs = append(errs, fmt.Errorf("rate %v: want a positive number of requests per second", r.Rate))
+	}
+	if r.Burst < 1 {
+		errs = append(errs, fmt.Errorf("burst %d: want at least 1", r.Burst))
+	}
+	return errors.Join(errs...)
+}
+
+// Decision is a Limiter's answer for one request.
+type Decision struct {
+	Allowed bool
+	// Remaining is the whole tokens left after this request.
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when
+	// Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Limiter takes one token from the bucket for key under rule, creating
+// the bucket full if it does not exist. Implementations must be safe for
+// concurrent use.
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Decision, error)
+}
+
+// Options configures New. The zero value keeps buckets in a Memory with
+// DefaultIdleTTL and keys clients by X-API-Key or connection IP.
+type Options struct {
+	// Limiter holds the buckets; nil means NewMemory(0).
+	Limiter Limiter
+	// APIKeyHeader names the header that identifies a client ahead of
+	// its IP; empty means DefaultAPIKeyHeader. The key is hashed before
+	// it reaches the Limiter.
+	APIKeyHeader string
+	// TrustedProxies are the peers whose X-Forwarded-For is believed when
+	// keying by IP, as in pkg/middleware/logging.
+	TrustedProxies []netip.Prefix
+}
+
+// New returns middleware limiting each client per the Rule ruleFor
+// returns for the request. Requests for which ruleFor reports false, or
+// a zero Rule, are not limited. scope namespaces the buckets, so two
+// routes with their own rules never share tokens.
+//
+// Over the limit it answers 429 with Retry-After in whole seconds; every
+// limited response carries X-RateLimit-Limit (the burst) and
+// X-RateLimit-Remaining. If the Limiter fails the request is let through,
+// so an outage of a shared store does not take the service down with it.
+func New(opts Options, ruleFor func(*http.Request) (scope string, rule Rule, ok bool)) func(http.Handler) http.Handler {
+	limiter := opts.Limiter
+	if limiter == nil {
+		limiter = NewMemory(0)
+	}
+	header := opts.APIKeyHeader
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope, rule, ok := ruleFor(r)
+			if !ok || rule.IsZero() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := scope + "|" + clientKey(r, header, opts.TrustedProxies)
+			d, err := limiter.Allow(r.Context(), key, rule)
+			if err != nil {
+				applog.FromContext(r.Context()).Warn("rate limiter failed; allowing request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(rule.Burst))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			if !d.Allowed {
+				h.Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(d.RetryAfter.Seconds())))))
+				h.Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"too many requests; retry later","code":"RATE_LIMITED"}` + "\n"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the caller: a digest of its API key when it sends
+// one, its IP otherwise.
+func clientKey(r *http.Request, header string, trusted []netip.Prefix) string {
+	if k := r.Header.Get(header); k != "" {
+		sum := sha256.Sum256([]byte(k))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + accesslog.ClientIP(r, trusted)
+}
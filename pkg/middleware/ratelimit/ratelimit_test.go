@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a Memory whose time is *now.
+func fakeClock(ttl time.Duration, now *time.Time) *Memory {
+	m := NewMemory(ttl)
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMemoryRefills(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	m := fakeClock(time.Hour, &now)
+	rule := Rule{Rate: 2, Burst: 3}
+	allow := func() Decision {
+		t.Helper()
+		d, err := m.Allow(context.Background(), "k", rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	for i := 2; i >= 0; i-- {
+		if d := allow(); !d.Allowed || d.Remaining != i {
+			t.Fatalf("take with %d left: %+v", i, d)
+		}
+	}
+	if d := allow(); d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("empty bucket: %+v, want a 500ms retry at 2/s", d)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if d := allow(); !d.Allowed {
+		t.Fatalf("after refilling one token: %+v", d)
+	}
+	// A bucket never holds more than the burst, however long it idles.
+	now = now.Add(30 * time.Minute)
+	for range 3 {
+		allow()
+	}
+	if d := allow(); d.Allowed {
+		t.Errorf("bucket overfilled while idle: %+v", d)
+	}
+}
+
+func TestMemoryEvictsIdleBuckets(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	m := fakeClock(time.Minute, &now)
+	ctx := context.Background()
+	rule := Rule{Rate: 1, Burst: 5}
+	const churn = 100_000
+	for i := range churn {
+		m.Allow(ctx, "ip:10.0."+strconv.Itoa(i), rule)
+	}
+	if n := m.Len(); n != churn {
+		t.Fatalf("holding %d buckets, want %d", n, churn)
+	}
+
+	// One client stays active; everyone else goes idle for the TTL.
+	now = now.Add(30 * time.Second)
+	m.Allow(ctx, "ip:10.0.7", rule)
+	now = now.Add(30 * time.Second)
+	m.Allow(ctx, "ip:192.0.2.1", rule)
+	if n := m.Len(); n != 2 {
+		t.Errorf("after the idle TTL, holding %d buckets, want the active and the new one", n)
+	}
+}
+
+// stubLimiter answers every Allow with d and err.
+type stubLimiter struct {
+	d    Decision
+	err  error
+	keys []string
+}
+
+func (s *stubLimiter) Allow(_ context.Context, key string, _ Rule) (Decision, error) {
+	s.keys = append(s.keys, key)
+	return s.d, s.err
+}
+
+func TestNew(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	rule := Rule{Rate: 1, Burst: 2}
+	perRoute := func(r *http.Request) (string, Rule, bool) {
+		if r.URL.Path == "/free" {
+			return "", Rule{}, false
+		}
+		return r.URL.Path, rule, true
+	}
+	serve := func(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "203.0.113.9:4000"
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	h := New(Options{}, perRoute)(ok)
+	for i := range 2 {
+		if rec := serve(h, "/a", nil); rec.Code != http.StatusNoContent || rec.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(1-i) {
+			t.Fatalf("request %d: %d, remaining %q", i, rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	rec := serve(h, "/a", nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over the burst: %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := serve(h, "/b", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("another route shares the bucket: %d", rec.Code)
+	}
+	if rec := serve(h, "/a", http.Header{"X-Api-Key": {"k-1"}}); rec.Code != http.StatusNoContent {
+		t.Errorf("an API key shares the IP's bucket: %d", rec.Code)
+	}
+	for range 3 {
+		if rec := serve(h, "/free", nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("unlimited route: %d", rec.Code)
+		}
+	}
+
+	stub := &stubLimiter{err: errors.New("redis down")}
+	h = New(Options{Limiter: stub, APIKeyHeader: "Authorization"}, perRoute)(ok)
+	if rec := serve(h, "/a", http.Header{"Authorization": {"secret"}}); rec.Code != http.StatusNoContent {
+		t.Errorf("limiter failure: %d, want the request let through", rec.Code)
+	}
+	if len(stub.keys) != 1 || stub.keys[0][:6] != "/a|key" || len(stub.keys[0]) != len("/a|key:")+32 {
+		t.Errorf("limiter keys = %q, want the API key digested", stub.keys)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	for _, r := range []Rule{{}, {Rate: 0.5, Burst: 1}} {
+		if err := r.Validate(); err != nil {
+			t.Errorf("%+v: %v", r, err)
+		}
+	}
+	for _, r := range []Rule{{Rate: 1}, {Burst: 3}, {Rate: -1, Burst: 3}} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("%+v: accepted", r)
+		}
+	}
+}
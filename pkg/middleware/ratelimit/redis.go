@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisPrefix namespaces a Redis limiter's bucket keys.
+const DefaultRedisPrefix = "ratelimit:bucket:"
+
+// allowScript is Memory.Allow as one atomic Redis call, and the bucket
+// internal/ratelimit.RedisStore keeps too. KEYS[1] is the bucket hash;
+// ARGV is the rate per second and the burst. The clock is the server's,
+// so replicas with skewed clocks share one timeline, and a bucket expires
+// once it would have refilled. It returns {allowed, remaining,
+// retry_after_ms, reset_ms}.
+var allowScript = redis.NewScript(`
+-- Redis < 5 must be told to replicate effects because of TIME.
+redis.replicate_commands()
+local rate = tonumber(ARGV[1]) / 1000
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1])
+local at = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  at = now
+end
+if now > at then
+  tokens = math.min(burst, tokens + (now - at) * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((burst - tokens) / rate)
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// Redis is a Limiter shared by every replica through Redis, so a client
+// has one budget however many instances serve it. Each Allow is a single
+// Lua script call, so concurrent requests cannot overspend a bucket.
+type Redis struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedis returns a Redis limiter keeping its buckets through client
+// under prefix, or DefaultRedisPrefix if prefix is empty.
+func NewRedis(client redis.Scripter, prefix string) *Redis {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Allow implements Limiter.
+func (l *Redis) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	rate := strconv.FormatFloat(rule.Rate, 'g', -1, 64)
+	vals, err := allowScript.Run(ctx, l.client, []string{l.prefix + key}, rate, rule.Burst).Int64Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: redis: %w", err)
+	}
+	if len(vals) != 4 {
+		return Decision{}, fmt.Errorf("ratelimit: redis: unexpected script reply %v", vals)
+	}
+	return Decision{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		Reset:      time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scriptStub is a redis.Scripter answering every script call with reply.
+type scriptStub struct {
+	redis.Scripter
+	reply any
+	err   error
+	keys  []string
+	args  []any
+}
+
+func (s *scriptStub) EvalSha(ctx context.Context, _ string, keys []string, args ...any) *redis.Cmd {
+	s.keys, s.args = keys, args
+	return redis.NewCmdResult(s.reply, s.err)
+}
+
+func TestRedisAllow(t *testing.T) {
+	stub := &scriptStub{reply: []any{int64(0), int64(0), int64(1500), int64(8000)}}
+	l := NewRedis(stub, "")
+	d, err := l.Allow(context.Background(), "api|ip:203.0.113.9", Rule{Rate: 0.5, Burst: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allowed || d.RetryAfter != 1500*time.Millisecond || d.Reset != 8*time.Second {
+		t.Errorf("decision = %+v", d)
+	}
+	if len(stub.keys) != 1 || stub.keys[0] != DefaultRedisPrefix+"api|ip:203.0.113.9" {
+		t.Errorf("keys = %q", stub.keys)
+	}
+	if len(stub.args) != 2 || stub.args[0] != "0.5" || stub.args[1] != 4 {
+		t.Errorf("args = %v", stub.args)
+	}
+
+	// A Redis outage reaches the middleware as an error, which lets the
+	// request through.
+	stub.err = errors.New("dial tcp: connection refused")
+	h := New(Options{Limiter: l}, func(*http.Request) (string, Rule, bool) {
+		return "api", Rule{Rate: 1, Burst: 1}, true
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("with redis down: %d", rec.Code)
+	}
+}
+
+// TestRedisScript runs allowScript on the Redis at REDIS_TEST_URL, such
+// as redis://localhost:6379/15, and is skipped without one.
+func TestRedisScript(t *testing.T) {
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		t.Skip("REDIS_TEST_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+	l := NewRedis(client, "ratelimit:test:"+strconv.FormatInt(time.Now().UnixNano(), 36)+":")
+	rule := Rule{Rate: 2, Burst: 3}
+	h := New(Options{Limiter: l}, func(*http.Request) (string, Rule, bool) {
+		return "api", rule, true
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec
+	}
+
+	for i := range rule.Burst {
+		if rec := send(); rec.Code != http.StatusNoContent || rec.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(rule.Burst-1-i) {
+			t.Fatalf("request %d of the burst: %d, remaining %s", i+1, rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	if rec := send(); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("request over the burst: %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// At 2 a second, two tokens are back after 1.25s, and a third not
+	// yet.
+	time.Sleep(1250 * time.Millisecond)
+	for i := range 2 {
+		if rec := send(); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d after the refill: %d", i+1, rec.Code)
+		}
+	}
+	d, err := l.Allow(context.Background(), "other", rule)
+	if err != nil || !d.Allowed || d.Remaining != 2 || d.Reset <= 0 || d.Reset > 500*time.Millisecond {
+		t.Errorf("fresh bucket: %+v, %v", d, err)
+	}
+	if rec := send(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("request past the refill: %d", rec.Code)
+	}
+}
@@ -0,0 +1,98 @@
+// Package requestid assigns every request an ID and carries it through
+// the context, the response, and any upstream call, so one request can be
+// followed across the entrance app and the services behind it.
+//
+//	h := requestid.New(requestid.Options{})(mux)
+//	...
+//	id := requestid.FromContext(r.Context())
+//
+// It depends only on the standard library, so services outside this
+// module can import it as is.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// Header carries the request ID in from a proxy, on to the upstream, and
+// back out to the client.
+const Header = "X-Request-ID"
+
+// validRE bounds the IDs accepted from clients, so a hostile header cannot
+// inject arbitrary text into the logs.
+var validRE = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Options configures New. The zero value reuses a well-formed ID from the
+// client.
+type Options struct {
+	// RejectClientIDs ignores X-Request-ID on incoming requests and
+	// always generates a fresh ID, for deployments whose clients are not
+	// trusted to name their own requests.
+	RejectClientIDs bool
+	// Generate makes a fresh ID; nil means 16 random bytes in hex. Its
+	// IDs should pass Valid, since the services behind this one check.
+	Generate func() string
+}
+
+// New returns middleware assigning each request an ID: the client's
+// X-Request-ID if it is well formed and allowed, otherwise a fresh one from
+// opts.Generate. The ID is stored in the request context for FromContext,
+// set on the request header so a reverse proxy forwards it upstream, and
+// echoed in the response. A request that already has an ID in its context, from
+// an outer New, keeps it.
+func New(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id := r.Header.Get(Header)
+			if opts.RejectClientIDs || !Valid(id) {
+				id = opts.generate()
+			}
+			r.Header.Set(Header, id)
+			w.Header().Set(Header, id)
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		})
+	}
+}
+
+// Valid reports whether id is acceptable as a request ID: 1 to 128
+// letters, digits, dots, underscores, or hyphens.
+func Valid(id string) bool {
+	return validRE.MatchString(id)
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying id for FromContext.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the ID stored by New or NewContext, or "" outside a
+// request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+func (o Options) generate() string {
+	if o.Generate != nil {
+		return o.Generate()
+	}
+	return generate()
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
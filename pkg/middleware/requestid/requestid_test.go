@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, h func(http.Handler) http.Handler, clientID string) (ctxID, headerID, responseID string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	if clientID != "" {
+		req.Header.Set(Header, clientID)
+	}
+	rec := httptest.NewRecorder()
+	h(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID, headerID = FromContext(r.Context()), r.Header.Get(Header)
+	})).ServeHTTP(rec, req)
+	return ctxID, headerID, rec.Header().Get(Header)
+}
+
+func TestNew(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	tests := []struct {
+		name     string
+		opts     Options
+		clientID string
+		reused   bool
+	}{
+		{"absent", Options{}, "", false},
+		{"well formed", Options{}, "edge-42.a_b", true},
+		{"malformed", Options{}, "bad id\nforged=1", false},
+		{"too long", Options{}, strings.Repeat("a", 129), false},
+		{"rejected", Options{RejectClientIDs: true}, "edge-42", false},
+	}
+	for _, tt := range tests {
+		ctxID, headerID, responseID := serve(t, New(tt.opts), tt.clientID)
+		if ctxID == "" || headerID != ctxID || responseID != ctxID {
+			t.Errorf("%s: context %q, request header %q, response %q", tt.name, ctxID, headerID, responseID)
+			continue
+		}
+		if tt.reused && ctxID != tt.clientID {
+			t.Errorf("%s: ID = %q, want the client's", tt.name, ctxID)
+		}
+		if !tt.reused && !generated.MatchString(ctxID) {
+			t.Errorf("%s: ID = %q, want 32 hex digits", tt.name, ctxID)
+		}
+	}
+}
+
+func TestNewGenerate(t *testing.T) {
+	gen := New(Options{Generate: func() string { return "gen-1" }})
+	if ctxID, _, _ := serve(t, gen, ""); ctxID != "gen-1" {
+		t.Errorf("ID = %q, want the generator's", ctxID)
+	}
+	if ctxID, _, _ := serve(t, gen, "edge-42"); ctxID != "edge-42" {
+		t.Errorf("ID = %q, want the client's over the generator's", ctxID)
+	}
+}
+
+func TestNewKeepsOuterID(t *testing.T) {
+	outer := New(Options{RejectClientIDs: true})
+	inner := New(Options{})
+	ctxID, headerID, _ := serve(t, func(h http.Handler) http.Handler { return outer(inner(h)) }, "edge-42")
+	if ctxID == "edge-42" || headerID != ctxID {
+		t.Errorf("inner New replaced the outer ID: context %q, header %q", ctxID, headerID)
+	}
+}
+
+func TestFromContextOutsideRequest(t *testing.T) {
+	if id := FromContext(httptest.NewRequest("GET", "/", nil).Context()); id != "" {
+		t.Errorf("FromContext = %q, want empty", id)
+	}
+}
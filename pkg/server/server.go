@@ -0,0 +1,298 @@
+// Package server runs an *http.Server until SIGTERM or SIGINT and then
+// shuts it down without dropping in-flight requests.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// DefaultGrace is how long in-flight requests may run after shutdown
+// starts when SHUTDOWN_GRACE is unset.
+const DefaultGrace = 20 * time.Second
+
+// GraceEnv names the environment variable that overrides DefaultGrace
+// with a Go duration such as "45s".
+const GraceEnv = "SHUTDOWN_GRACE"
+
+// ErrForcedClose is returned by Run and Serve when requests were still in
+// flight at the end of the grace period and had to be cut off.
+var ErrForcedClose = errors.New("server: grace period exceeded; connections force-closed")
+
+// Readiness is a readiness probe handler: 200 until shutdown starts and
+// 503 from the moment a signal arrives, so load balancers stop routing to
+// the instance before it stops accepting connections.
+type Readiness struct {
+	draining atomic.Bool
+}
+
+// SetDraining makes the probe fail from now on.
+func (r *Readiness) SetDraining() { r.draining.Store(true) }
+
+// Draining reports whether shutdown has started.
+func (r *Readiness) Draining() bool { return r.draining.Load() }
+
+// ServeHTTP implements http.Handler.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Draining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"ready":false}` + "\n"))
+		return
+	}
+	w.Write([]byte(`{"ready":true}` + "\n"))
+}
+
+// Worker is background work that lives as long as the server, such as a
+// queue consumer or a periodic reload.
+type Worker interface {
+	// Run works until ctx is cancelled, which happens as soon as shutdown
+	// starts, and then returns.
+	Run(ctx context.Context)
+}
+
+// WorkerFunc adapts a function to Worker.
+type WorkerFunc func(ctx context.Context)
+
+// Run implements Worker.
+func (f WorkerFunc) Run(ctx context.Context) { f(ctx) }
+
+// Flusher is implemented by a Worker with work still pending when Run
+// returns. Flush is called once in-flight requests have finished, so it
+// also sees work they queued, and must return by ctx's deadline, the end
+// of the grace period.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Option configures Run and Serve.
+type Option func(*config)
+
+type config struct {
+	grace      time.Duration
+	graceSet   bool
+	readiness  *Readiness
+	drainDelay time.Duration
+	workers    []Worker
+	logf       func(format string, args ...any)
+	tls        *TLS
+	redirectLn net.Listener // opened from tls.RedirectAddr when nil
+	timeouts   Timeouts
+	h2c        bool
+}
+
+// WithGrace sets the drain period, overriding SHUTDOWN_GRACE.
+func WithGrace(d time.Duration) Option {
+	return func(c *config) { c.grace, c.graceSet = d, true }
+}
+
+// WithReadiness flips r to draining as soon as shutdown starts.
+func WithReadiness(r *Readiness) Option {
+	return func(c *config) { c.readiness = r }
+}
+
+// WithDrainDelay keeps accepting connections for d after readiness flips,
+// giving load balancers time to observe the failing probe.
+func WithDrainDelay(d time.Duration) Option {
+	return func(c *config) { c.drainDelay = d }
+}
+
+// WithWorker runs w alongside the server and stops it at shutdown inside
+// the same grace period as in-flight requests; see Serve.
+func WithWorker(w Worker) Option {
+	return func(c *config) { c.workers = append(c.workers, w) }
+}
+
+// WithLogf replaces log.Printf for shutdown progress messages.
+func WithLogf(logf func(format string, args ...any)) Option {
+	return func(c *config) { c.logf = logf }
+}
+
+// WithH2C also serves HTTP/2 without TLS, to clients that start with its
+// preface (prior knowledge) or ask to upgrade, as gRPC clients inside the
+// cluster do. A server WithTLS negotiates HTTP/2 through ALPN regardless,
+// so the option only applies to plaintext. At shutdown HTTP/2 connections
+// are sent a GOAWAY, but their in-flight streams are not waited for as
+// HTTP/1 requests are.
+func WithH2C() Option {
+	return func(c *config) { c.h2c = true }
+}
+
+// GraceFromEnv returns SHUTDOWN_GRACE, or DefaultGrace when it is unset.
+func GraceFromEnv() (time.Duration, error) {
+	v := os.Getenv(GraceEnv)
+	if v == "" {
+		return DefaultGrace, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: want a positive duration such as 30s, got %q", GraceEnv, v)
+	}
+	return d, nil
+}
+
+// Run listens on srv.Addr and serves until ctx is done or the process
+// receives SIGTERM or SIGINT; see Serve. An empty Addr means :http, or
+// :https WithTLS.
+func Run(ctx context.Context, srv *http.Server, opts ...Option) error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+		var c config
+		for _, opt := range opts {
+			opt(&c)
+		}
+		if c.tls != nil {
+			addr = ":https"
+		}
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, ln, opts...)
+}
+
+// Serve serves srv on ln until ctx is done or the process receives SIGTERM
+// or SIGINT. It then fails the readiness probe, waits the drain delay,
+// closes the listener, and gives in-flight requests the grace period to
+// finish before force-closing their connections and returning
+// ErrForcedClose. A clean shutdown returns nil.
+//
+// Workers run from the start with a context cancelled when shutdown
+// starts. Once the requests are done, Serve waits for every Run to return
+// and flushes each Flusher, all before the same grace period ends; their
+// failures are joined to the returned error.
+//
+// WithTLS, srv serves HTTPS on ln with its TLSConfig replaced, and the
+// redirect listener stops when shutdown starts.
+//
+// Timeouts srv leaves zero are set from WithTimeouts or the Default*
+// values, and unless srv has a WriteTimeout its Handler is wrapped in
+// WriteDeadline.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, opts ...Option) error {
+	c := config{logf: log.Printf}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if !c.graceSet {
+		grace, err := GraceFromEnv()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		c.grace = grace
+	}
+	c.timeouts.apply(srv)
+	if c.h2c && c.tls == nil {
+		h2s := &http2.Server{}
+		// Registers the GOAWAY sent to HTTP/2 connections at Shutdown.
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			ln.Close()
+			return err
+		}
+		srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+	}
+	serve := srv.Serve
+	var redirect *http.Server
+	if c.tls != nil {
+		var err error
+		if redirect, err = c.setupTLS(srv, ln); err != nil {
+			ln.Close()
+			return err
+		}
+		if redirect != nil {
+			defer redirect.Close()
+		}
+		serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	workCtx, stopWork := context.WithCancel(ctx)
+	defer stopWork()
+	var running sync.WaitGroup
+	for _, w := range c.workers {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			w.Run(workCtx)
+		}()
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- serve(ln) }()
+
+	select {
+	case err := <-errc:
+		stopWork()
+		running.Wait()
+		return err
+	case <-ctx.Done():
+	}
+	// Restore default signal handling so a second SIGINT kills the process.
+	stop()
+	stopWork()
+	if c.readiness != nil {
+		c.readiness.SetDraining()
+	}
+	if c.drainDelay > 0 {
+		c.logf("server: shutdown requested; draining in %s", c.drainDelay)
+		time.Sleep(c.drainDelay)
+	}
+	if redirect != nil {
+		redirect.Close()
+	}
+	c.logf("server: shutting down; waiting up to %s for in-flight requests", c.grace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.grace)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		srv.Close()
+		err = ErrForcedClose
+	}
+	if serveErr := <-errc; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return errors.Join(err, c.stopWorkers(shutdownCtx, &running))
+}
+
+// stopWorkers waits for the cancelled workers to return and then flushes
+// them, giving up when ctx is done.
+func (c *config) stopWorkers(ctx context.Context, running *sync.WaitGroup) error {
+	if len(c.workers) == 0 {
+		return nil
+	}
+	stopped := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return errors.New("server: grace period exceeded; background workers still running")
+	}
+	var errs []error
+	for _, w := range c.workers {
+		if f, ok := w.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
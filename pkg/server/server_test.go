@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func quiet(string, ...any) {}
+
+// slowServer serves /slow, which blocks until release is closed.
+func slowServer(t *testing.T) (srv *http.Server, ln net.Listener, started, release chan struct{}) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started, release = make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	return &http.Server{Handler: mux}, ln, started, release
+}
+
+// waitFor polls cond for up to two seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestServeForceClosesAfterGrace(t *testing.T) {
+	srv, ln, started, release := slowServer(t)
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, srv, ln, WithGrace(50*time.Millisecond), WithLogf(quiet)) }()
+
+	go http.Get("http://" + ln.Addr().String() + "/slow")
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrForcedClose) {
+			t.Errorf("Serve = %v, want ErrForcedClose", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not force-close after the grace period")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	var r Readiness
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("before shutdown: %d", rec.Code)
+	}
+	r.SetDraining()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("while draining: %d, want 503", rec.Code)
+	}
+}
+
+func TestGraceFromEnv(t *testing.T) {
+	t.Setenv(GraceEnv, "")
+	if d, err := GraceFromEnv(); err != nil || d != DefaultGrace {
+		t.Errorf("unset: %s, %v", d, err)
+	}
+	t.Setenv(GraceEnv, "45s")
+	if d, err := GraceFromEnv(); err != nil || d != 45*time.Second {
+		t.Errorf("45s: %s, %v", d, err)
+	}
+	for _, v := range []string{"soon", "0s", "-1s"} {
+		t.Setenv(GraceEnv, v)
+		if _, err := GraceFromEnv(); err == nil {
+			t.Errorf("%q accepted", v)
+		}
+	}
+}
+
+// queueWorker counts the jobs queued and flushed around a shutdown.
+type queueWorker struct {
+	mu        sync.Mutex
+	pending   int
+	flushed   int
+	cancelled chan struct{}
+	flushErr  error
+}
+
+func (w *queueWorker) Run(ctx context.Context) {
+	<-ctx.Done()
+	close(w.cancelled)
+}
+
+func (w *queueWorker) Flush(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushed, w.pending = w.pending, 0
+	return w.flushErr
+}
+
+func TestServeStopsWorkers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &queueWorker{cancelled: make(chan struct{}), flushErr: errors.New("one job failed")}
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+		// Queued after the worker was cancelled; Flush must still see it.
+		w.mu.Lock()
+		w.pending++
+		w.mu.Unlock()
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, srv, ln, WithGrace(2*time.Second), WithWorker(w), WithLogf(quiet)) }()
+
+	go http.Get("http://" + ln.Addr().String() + "/")
+	<-started
+	cancel()
+	select {
+	case <-w.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker context not cancelled when shutdown started")
+	}
+	close(release)
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "one job failed") {
+		t.Errorf("Serve = %v, want the flush error", err)
+	}
+	if w.flushed != 1 {
+		t.Errorf("flushed %d jobs, want the one queued during shutdown", w.flushed)
+	}
+}
+
+func TestServeGivesUpOnStuckWorker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck := make(chan struct{})
+	defer close(stuck)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Serve(ctx, &http.Server{}, ln, WithGrace(50*time.Millisecond), WithLogf(quiet),
+		WithWorker(WorkerFunc(func(context.Context) { <-stuck })))
+	if err == nil || !strings.Contains(err.Error(), "background workers still running") {
+		t.Errorf("Serve = %v", err)
+	}
+}
+
+func TestServeH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, srv, ln, WithGrace(time.Second), WithH2C(), WithLogf(quiet)) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve = %v", err)
+		}
+	}()
+
+	h2 := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	defer h2.CloseIdleConnections()
+	for name, client := range map[string]*http.Client{"prior knowledge": {Transport: h2}, "HTTP/1.1": http.DefaultClient} {
+		resp, err := client.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if want := map[string]string{"prior knowledge": "HTTP/2.0", "HTTP/1.1": "HTTP/1.1"}[name]; string(body) != want {
+			t.Errorf("%s: served as %s, want %s", name, body, want)
+		}
+	}
+}
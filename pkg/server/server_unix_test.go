@@ -0,0 +1,61 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeDrainsOnSIGTERM(t *testing.T) {
+	srv, ln, started, release := slowServer(t)
+	addr := ln.Addr().String()
+	ready := &Readiness{}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), srv, ln, WithGrace(5*time.Second), WithReadiness(ready), WithLogf(quiet))
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		slow <- result{string(b), err}
+	}()
+	<-started
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "readiness to fail", ready.Draining)
+	waitFor(t, "the listener to close", func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	})
+
+	close(release)
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request = %q, %v; want it to complete", r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v, want a clean shutdown", err)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Defaults for the Timeouts fields left zero, in place of net/http's
+// zero values, under which a client may hold a connection forever by
+// sending or reading slowly.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = time.Minute
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+)
+
+// Timeouts environment variables read by TimeoutsFromEnv, each a Go
+// duration such as "45s".
+const (
+	ReadHeaderTimeoutEnv = "SERVER_READ_HEADER_TIMEOUT"
+	ReadTimeoutEnv       = "SERVER_READ_TIMEOUT"
+	WriteTimeoutEnv      = "SERVER_WRITE_TIMEOUT"
+	IdleTimeoutEnv       = "SERVER_IDLE_TIMEOUT"
+)
+
+// Timeouts bound how long a client may take over each part of a request,
+// so slow ones cannot tie up a server's connections. Zero fields mean the
+// Default* values and negative ones no limit.
+type Timeouts struct {
+	// ReadHeader bounds reading the request line and headers.
+	ReadHeader time.Duration
+	// Read bounds reading the whole request, body included.
+	Read time.Duration
+	// Write bounds each write of the response rather than all of it: the
+	// deadline moves on whenever the handler writes or flushes, so a
+	// response streamed for an hour is fine while a client reading one
+	// byte a second is cut off.
+	Write time.Duration
+	// Idle bounds the wait for the next request on a kept-alive
+	// connection.
+	Idle time.Duration
+}
+
+// WithTimeouts sets the timeouts Serve applies, overriding the defaults.
+func WithTimeouts(t Timeouts) Option {
+	return func(c *config) { c.timeouts = t }
+}
+
+// TimeoutsFromEnv returns the timeouts in SERVER_READ_HEADER_TIMEOUT,
+// SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT, and SERVER_IDLE_TIMEOUT,
+// zero for those unset. Every malformed one is reported.
+func TimeoutsFromEnv() (Timeouts, error) {
+	var t Timeouts
+	var errs []error
+	for env, d := range map[string]*time.Duration{
+		ReadHeaderTimeoutEnv: &t.ReadHeader,
+		ReadTimeoutEnv:       &t.Read,
+		WriteTimeoutEnv:      &t.Write,
+		IdleTimeoutEnv:       &t.Idle,
+	} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		var err error
+		if *d, err = time.ParseDuration(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: want a duration such as 30s, got %q", env, v))
+		}
+	}
+	return t, errors.Join(errs...)
+}
+
+// apply sets each of srv's timeouts that is zero from t, and wraps its
+// handler to keep moving the write deadline unless srv already has a
+// WriteTimeout, which bounds the whole response instead.
+func (t Timeouts) apply(srv *http.Server) {
+	fill := func(d *time.Duration, v, def time.Duration) {
+		if *d != 0 {
+			return
+		}
+		if v == 0 {
+			v = def
+		}
+		*d = max(v, 0)
+	}
+	fill(&srv.ReadHeaderTimeout, t.ReadHeader, DefaultReadHeaderTimeout)
+	fill(&srv.ReadTimeout, t.Read, DefaultReadTimeout)
+	fill(&srv.IdleTimeout, t.Idle, DefaultIdleTimeout)
+	write := t.Write
+	if write == 0 {
+		write = DefaultWriteTimeout
+	}
+	if srv.WriteTimeout == 0 && write > 0 {
+		h := srv.Handler
+		if h == nil {
+			h = http.DefaultServeMux
+		}
+		srv.Handler = WriteDeadline(h, write)
+	}
+}
+
+// WriteDeadline gives each response write d to complete, moving the
+// connection's write deadline on before every write and flush and once
+// more when h returns, for the server's final flush. srv.WriteTimeout
+// would instead cut off a stream d after it started. Serve applies it
+// from Timeouts.Write.
+func WriteDeadline(h http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dw := &deadlineWriter{ResponseWriter: w, rc: http.NewResponseController(w), d: d}
+		dw.extend()
+		defer dw.extend()
+		h.ServeHTTP(dw, r)
+	})
+}
+
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc *http.ResponseController
+	d  time.Duration
+}
+
+// extend moves the write deadline d past now. A writer that cannot set
+// one, such as a recorder in tests, is left as it is.
+func (w *deadlineWriter) extend() {
+	w.rc.SetWriteDeadline(time.Now().Add(w.d))
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+	w.extend()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	w.extend()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *deadlineWriter) Flush() {
+	w.extend()
+	w.rc.Flush()
+}
+
+// Hijack passes the connection on with its deadline cleared: past the
+// upgrade the handler owns it.
+func (w *deadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.rc.Hijack()
+	if err == nil {
+		conn.SetWriteDeadline(time.Time{})
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsApply(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := &http.Server{ReadTimeout: 5 * time.Minute, Handler: mux}
+	Timeouts{}.apply(srv)
+	if srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || srv.ReadTimeout != 5*time.Minute || srv.IdleTimeout != DefaultIdleTimeout || srv.WriteTimeout != 0 {
+		t.Errorf("defaults: %+v", srv)
+	}
+	if srv.Handler == http.Handler(mux) {
+		t.Error("handler not wrapped in WriteDeadline")
+	}
+
+	srv = &http.Server{WriteTimeout: time.Second, Handler: mux}
+	Timeouts{ReadHeader: 2 * time.Second, Read: -1, Idle: -1}.apply(srv)
+	if srv.ReadHeaderTimeout != 2*time.Second || srv.ReadTimeout != 0 || srv.IdleTimeout != 0 {
+		t.Errorf("explicit: %+v", srv)
+	}
+	if srv.Handler != http.Handler(mux) {
+		t.Error("handler wrapped despite the server's own WriteTimeout")
+	}
+}
+
+func TestTimeoutsFromEnv(t *testing.T) {
+	t.Setenv(ReadHeaderTimeoutEnv, "5s")
+	t.Setenv(ReadTimeoutEnv, "")
+	t.Setenv(WriteTimeoutEnv, "-1s")
+	t.Setenv(IdleTimeoutEnv, "")
+	got, err := TimeoutsFromEnv()
+	if err != nil || got != (Timeouts{ReadHeader: 5 * time.Second, Write: -time.Second}) {
+		t.Errorf("TimeoutsFromEnv() = %+v, %v", got, err)
+	}
+	t.Setenv(ReadTimeoutEnv, "soon")
+	t.Setenv(IdleTimeoutEnv, "10")
+	if _, err := TimeoutsFromEnv(); err == nil || !strings.Contains(err.Error(), ReadTimeoutEnv) || !strings.Contains(err.Error(), IdleTimeoutEnv) {
+		t.Errorf("malformed values: err = %v", err)
+	}
+}
+
+func TestWriteDeadlineCutsOffSlowReaders(t *testing.T) {
+	failed := make(chan error, 1)
+	srv := httptest.NewServer(WriteDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 64<<10)
+		for range 1 << 12 {
+			if _, err := w.Write(chunk); err != nil {
+				failed <- err
+				return
+			}
+		}
+		failed <- nil
+	}), 100*time.Millisecond))
+	defer srv.Close()
+
+	// A client that sends its request and never reads the answer.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Fatal("256 MiB written to a client that read none of it")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write to a stalled client still blocked after 5s")
+	}
+}
+
+func TestWriteDeadlineKeepsStreaming(t *testing.T) {
+	srv := httptest.NewServer(WriteDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range 8 {
+			if _, err := io.WriteString(w, "event "+string(rune('0'+i))+"\n"); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	}), 100*time.Millisecond))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := 0
+	for sc := bufio.NewScanner(resp.Body); sc.Scan(); {
+		lines++
+	}
+	// 320ms of streaming outlasts the 100ms deadline several times over.
+	if lines != 8 {
+		t.Errorf("read %d of 8 events", lines)
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS configures HTTPS for Run and Serve; see WithTLS.
+type TLS struct {
+	// CertFile and KeyFile are the PEM certificate chain and private key.
+	// They are reread whenever either file's modification time changes,
+	// so a rotated certificate is picked up without a restart.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the lowest protocol version accepted, "1.0" through
+	// "1.3"; empty means 1.2.
+	MinVersion string
+	// CipherSuites restricts the TLS 1.2 and older suites to these, named
+	// as in crypto/tls (TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256); those of
+	// TLS 1.3 are fixed. Empty means Go's defaults.
+	CipherSuites []string
+	// RedirectAddr, if set, is a plaintext listener that answers every
+	// request with a 301 to the same URL over HTTPS.
+	RedirectAddr string
+	// RedirectPort is the HTTPS port named in those redirects; zero means
+	// the port Serve listens on. 443 is left out of the URL.
+	RedirectPort int
+}
+
+// TLS environment variables read by TLSFromEnv.
+const (
+	TLSCertEnv         = "TLS_CERT_FILE"
+	TLSKeyEnv          = "TLS_KEY_FILE"
+	TLSMinVersionEnv   = "TLS_MIN_VERSION"
+	TLSCipherSuitesEnv = "TLS_CIPHER_SUITES"
+	TLSRedirectEnv     = "TLS_REDIRECT_ADDR"
+)
+
+// TLSFromEnv returns the TLS settings in TLS_CERT_FILE, TLS_KEY_FILE,
+// TLS_MIN_VERSION, TLS_CIPHER_SUITES (comma-separated), and
+// TLS_REDIRECT_ADDR, or nil when neither file is set.
+func TLSFromEnv() (*TLS, error) {
+	t := &TLS{
+		CertFile:     os.Getenv(TLSCertEnv),
+		KeyFile:      os.Getenv(TLSKeyEnv),
+		MinVersion:   os.Getenv(TLSMinVersionEnv),
+		RedirectAddr: os.Getenv(TLSRedirectEnv),
+	}
+	if t.CertFile == "" && t.KeyFile == "" {
+		return nil, nil
+	}
+	if v := os.Getenv(TLSCipherSuitesEnv); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			t.CipherSuites = append(t.CipherSuites, strings.TrimSpace(name))
+		}
+	}
+	return t, t.Validate()
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Validate reports every missing file, unknown version, and unknown or
+// fixed cipher suite at once. The files themselves are read by Serve.
+func (t *TLS) Validate() error {
+	_, err := t.config()
+	return err
+}
+
+// config returns the tls.Config t describes, without a certificate.
+func (t *TLS) config() (*tls.Config, error) {
+	var errs []error
+	if t.CertFile == "" {
+		errs = append(errs, errors.New("TLS certificate file is required"))
+	}
+	if t.KeyFile == "" {
+		errs = append(errs, errors.New("TLS key file is required"))
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.MinVersion != "" {
+		v, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			errs = append(errs, fmt.Errorf("TLS minimum version %q: want 1.0, 1.1, 1.2, or 1.3", t.MinVersion))
+		}
+		cfg.MinVersion = v
+	}
+	if len(t.CipherSuites) > 0 {
+		suites := map[string]*tls.CipherSuite{}
+		for _, s := range tls.CipherSuites() {
+			suites[s.Name] = s
+		}
+		for _, name := range t.CipherSuites {
+			s, ok := suites[name]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("TLS cipher suite %q: unknown or insecure", name))
+			case len(s.SupportedVersions) == 1 && s.SupportedVersions[0] == tls.VersionTLS13:
+				errs = append(errs, fmt.Errorf("TLS cipher suite %q: TLS 1.3 suites cannot be configured", name))
+			default:
+				cfg.CipherSuites = append(cfg.CipherSuites, s.ID)
+			}
+		}
+	}
+	if t.RedirectPort < 0 || t.RedirectPort > 65535 {
+		errs = append(errs, fmt.Errorf("TLS redirect port %d: out of range", t.RedirectPort))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// WithTLS serves HTTPS with the certificate in t, and the plaintext
+// redirect listener if t asks for one. Serve fails before serving anything
+// if the settings are invalid or the certificate cannot be loaded.
+func WithTLS(t TLS) Option {
+	return func(c *config) { c.tls = &t }
+}
+
+// CertReloader serves a certificate from disk, rereading it when the
+// files change. A reload that fails keeps the previous certificate.
+type CertReloader struct {
+	certFile, keyFile string
+	logf              func(format string, args ...any)
+
+	mu     sync.Mutex
+	cert   *tls.Certificate
+	loaded fileState // of cert
+	// failed is the file state of the last reload that did not work, so a
+	// broken pair is retried, and reported, only once it changes again.
+	failed  fileState
+	failing bool
+}
+
+// fileState identifies a version of the certificate and key files.
+type fileState struct {
+	certMod, keyMod   time.Time
+	certSize, keySize int64
+}
+
+// NewCertReloader loads certFile and keyFile, failing if they do not hold
+// a valid pair. Failed reloads are reported through logf; nil means
+// log.Printf.
+func NewCertReloader(certFile, keyFile string, logf func(format string, args ...any)) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, logf: logf}
+	if r.logf == nil {
+		r.logf = log.Printf
+	}
+	st, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(st); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. It checks the
+// files on every handshake, which costs two stat calls, and reloads them
+// once per change however many handshakes race.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	st, err := r.stat()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case err != nil:
+		r.fail(fileState{}, err)
+	case st != r.loaded && !(r.failing && st == r.failed):
+		if err := r.load(st); err != nil {
+			r.fail(st, err)
+		}
+	}
+	return r.cert, nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate, for a
+// client presenting the certificate to servers that ask for one.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+// load installs the pair as of st. r.mu must be held, or r unshared.
+func (r *CertReloader) load(st fileState) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("server: load TLS certificate: %w", err)
+	}
+	r.cert, r.loaded, r.failing = &cert, st, false
+	return nil
+}
+
+// fail reports a failed reload, once per version of the files. r.mu must
+// be held.
+func (r *CertReloader) fail(st fileState, err error) {
+	if r.failing && st == r.failed {
+		return
+	}
+	r.failed, r.failing = st, true
+	r.logf("server: ERROR: TLS certificate reload failed; still serving the certificate loaded from files modified %s: %v",
+		r.loaded.certMod.Format(time.RFC3339), err)
+}
+
+func (r *CertReloader) stat() (fileState, error) {
+	cert, err := os.Stat(r.certFile)
+	if err != nil {
+		return fileState{}, fmt.Errorf("server: TLS certificate: %w", err)
+	}
+	key, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fileState{}, fmt.Errorf("server: TLS key: %w", err)
+	}
+	return fileState{cert.ModTime(), key.ModTime(), cert.Size(), key.Size()}, nil
+}
+
+// setupTLS points srv at a CertReloader for c.tls and starts the redirect
+// listener, if there is one, returning its server.
+func (c *config) setupTLS(srv *http.Server, ln net.Listener) (*http.Server, error) {
+	cfg, err := c.tls.config()
+	if err != nil {
+		return nil, err
+	}
+	certs, err := NewCertReloader(c.tls.CertFile, c.tls.KeyFile, c.logf)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GetCertificate = certs.GetCertificate
+	srv.TLSConfig = cfg
+	if c.tls.RedirectAddr == "" && c.redirectLn == nil {
+		return nil, nil
+	}
+	rln := c.redirectLn
+	if rln == nil {
+		if rln, err = net.Listen("tcp", c.tls.RedirectAddr); err != nil {
+			return nil, fmt.Errorf("server: redirect listener: %w", err)
+		}
+	}
+	port := c.tls.RedirectPort
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok && port == 0 {
+		port = addr.Port
+	}
+	if port == 0 {
+		port = 443
+	}
+	redirect := &http.Server{Handler: redirectHandler(port), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := redirect.Serve(rln); !errors.Is(err, http.ErrServerClosed) {
+			c.logf("server: redirect listener stopped: %v", err)
+		}
+	}()
+	return redirect, nil
+}
+
+// redirectHandler answers every request with a 301 to its HTTPS URL on
+// port, or the default port when port is 443.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port != 443 {
+			host += ":" + strconv.Itoa(port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
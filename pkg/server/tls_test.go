@@ -0,0 +1,330 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCert is a self-signed certificate for 127.0.0.1 in PEM.
+type testCert struct {
+	cert     *x509.Certificate
+	certPEM  []byte
+	keyPEM   []byte
+	modified time.Time
+}
+
+func newTestCert(t *testing.T, serial int64) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("test %d", serial)},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:     cert,
+		certPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		modified: time.Now().Add(time.Duration(serial) * time.Second),
+	}
+}
+
+// install writes c over the files in dir, the certificate first, as a
+// rotation that is not atomic would.
+func (c *testCert) install(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	for _, f := range []struct {
+		path string
+		data []byte
+	}{{certFile, c.certPEM}, {keyFile, c.keyPEM}} {
+		if err := os.WriteFile(f.path, f.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Distinct modification times, whatever the file system's
+		// resolution.
+		if err := os.Chtimes(f.path, c.modified, c.modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// serveTLS serves "ok" over HTTPS with certFile and keyFile until the test
+// ends, returning the address.
+func serveTLS(t *testing.T, tlsOpts TLS, opts ...Option) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, srv, ln, append([]Option{WithGrace(time.Second), WithLogf(quiet), WithTLS(tlsOpts)}, opts...)...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve = %v", err)
+		}
+	})
+	return ln.Addr().String()
+}
+
+// trusting returns a client that trusts certs and opens a new connection
+// for every request.
+func trusting(certs ...*testCert) *http.Client {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c.cert)
+	}
+	return &http.Client{
+		Timeout:       5 * time.Second,
+		Transport:     &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	cert := newTestCert(t, 1)
+	certFile, keyFile := cert.install(t, t.TempDir())
+	redirectLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serveTLS(t, TLS{CertFile: certFile, KeyFile: keyFile, RedirectAddr: "unused"}, func(c *config) { c.redirectLn = redirectLn })
+	client := trusting(cert)
+
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("HTTPS: %d, TLS %+v", resp.StatusCode, resp.TLS)
+	}
+
+	old := trusting(cert)
+	old.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS11
+	if _, err := old.Get("https://" + addr + "/"); err == nil {
+		t.Error("TLS 1.1 client connected; the default minimum is 1.2")
+	}
+
+	resp, err = client.Get("http://" + redirectLn.Addr().String() + "/v1/applications?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	_, port, _ := net.SplitHostPort(addr)
+	if want := "https://127.0.0.1:" + port + "/v1/applications?page=2"; resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Errorf("redirect: %d to %q, want 301 to %q", resp.StatusCode, resp.Header.Get("Location"), want)
+	}
+}
+
+func TestRedirectHandlerPort(t *testing.T) {
+	for _, tc := range []struct {
+		port       int
+		host, want string
+	}{
+		{443, "apply.example.edu", "https://apply.example.edu/x"},
+		{443, "apply.example.edu:80", "https://apply.example.edu/x"},
+		{8443, "apply.example.edu:8080", "https://apply.example.edu:8443/x"},
+		{8443, "[::1]:8080", "https://[::1]:8443/x"},
+	} {
+		req, _ := http.NewRequest("POST", "http://"+tc.host+"/x", nil)
+		rec := &headerRecorder{header: http.Header{}}
+		redirectHandler(tc.port).ServeHTTP(rec, req)
+		if rec.code != http.StatusMovedPermanently || rec.header.Get("Location") != tc.want {
+			t.Errorf("%s on port %d: %d to %q, want %q", tc.host, tc.port, rec.code, rec.header.Get("Location"), tc.want)
+		}
+	}
+}
+
+type headerRecorder struct {
+	header http.Header
+	code   int
+}
+
+func (r *headerRecorder) Header() http.Header         { return r.header }
+func (r *headerRecorder) Write(p []byte) (int, error) { return len(p), nil }
+func (r *headerRecorder) WriteHeader(code int)        { r.code = code }
+
+func TestServeTLSFailsWithoutCertificate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bad.crt"), []byte("not a certificate"), 0o600)
+	for name, opts := range map[string]TLS{
+		"missing files": {CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")},
+		"invalid pair":  {CertFile: filepath.Join(dir, "bad.crt"), KeyFile: filepath.Join(dir, "bad.crt")},
+		"no key":        {CertFile: filepath.Join(dir, "bad.crt")},
+	} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Serve(context.Background(), &http.Server{}, ln, WithGrace(time.Second), WithLogf(quiet), WithTLS(opts)); err == nil {
+			t.Errorf("%s: Serve started", name)
+		}
+		if _, err := ln.Accept(); err == nil {
+			t.Errorf("%s: listener left open", name)
+		}
+	}
+}
+
+func TestTLSValidate(t *testing.T) {
+	valid := TLS{CertFile: "c", KeyFile: "k", MinVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid settings: %v", err)
+	}
+	err := (&TLS{MinVersion: "1.4", CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256"}, RedirectPort: 70000}).Validate()
+	for _, want := range []string{"certificate file is required", "key file is required", `"1.4"`, `"TLS_RSA_WITH_RC4_128_SHA": unknown or insecure`, "TLS 1.3 suites cannot be configured", "70000"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestTLSFromEnv(t *testing.T) {
+	t.Setenv(TLSCertEnv, "")
+	t.Setenv(TLSKeyEnv, "")
+	if got, err := TLSFromEnv(); got != nil || err != nil {
+		t.Errorf("unset: %+v, %v", got, err)
+	}
+	t.Setenv(TLSCertEnv, "/tls/tls.crt")
+	t.Setenv(TLSKeyEnv, "/tls/tls.key")
+	t.Setenv(TLSCipherSuitesEnv, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	t.Setenv(TLSRedirectEnv, ":8080")
+	got, err := TLSFromEnv()
+	if err != nil || got.CertFile != "/tls/tls.crt" || len(got.CipherSuites) != 2 || got.CipherSuites[1] != "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384" || got.RedirectAddr != ":8080" {
+		t.Errorf("TLSFromEnv() = %+v, %v", got, err)
+	}
+	t.Setenv(TLSMinVersionEnv, "1.4")
+	if _, err := TLSFromEnv(); err == nil {
+		t.Error("invalid TLS_MIN_VERSION accepted")
+	}
+}
+
+// TestCertRotationUnderLoad swaps the certificate, then a broken one, while
+// clients keep opening connections, none of which may fail to handshake.
+func TestCertRotationUnderLoad(t *testing.T) {
+	dir := t.TempDir()
+	first, second := newTestCert(t, 1), newTestCert(t, 2)
+	certFile, keyFile := first.install(t, dir)
+	var logs logBuffer
+	addr := serveTLS(t, TLS{CertFile: certFile, KeyFile: keyFile}, WithLogf(logs.logf))
+	client := trusting(first, second)
+
+	var (
+		stop     atomic.Bool
+		requests atomic.Int64
+		seen     sync.Map // serial -> true
+		errs     = make(chan error, 100)
+		wg       sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				resp, err := client.Get("https://" + addr + "/")
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				requests.Add(1)
+				seen.Store(resp.TLS.PeerCertificates[0].SerialNumber.Int64(), true)
+			}
+		}()
+	}
+	served := func(serial int64) bool { _, ok := seen.Load(serial); return ok }
+
+	waitFor(t, "handshakes with the first certificate", func() bool { return served(1) })
+	second.install(t, dir)
+	waitFor(t, "handshakes with the rotated certificate", func() bool { return served(2) })
+
+	// A broken rotation keeps the previous certificate in service.
+	os.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----\ngarbage\n"), 0o600)
+	os.Chtimes(certFile, second.modified.Add(time.Minute), second.modified.Add(time.Minute))
+	waitFor(t, "the broken rotation to be logged", func() bool {
+		return strings.Contains(logs.String(), "TLS certificate reload failed")
+	})
+	seen.Delete(2) // from handshakes that may have begun before the reload
+	waitFor(t, "handshakes after a broken rotation", func() bool { return served(2) })
+	stop.Store(true)
+	wg.Wait()
+
+	close(errs)
+	for err := range errs {
+		t.Errorf("request failed during rotation: %v", err)
+	}
+	if n := requests.Load(); n < 10 {
+		t.Errorf("only %d requests completed", n)
+	}
+	// Each half-written state of the files is reported once, not once per
+	// handshake.
+	if got := strings.Count(logs.String(), "reload failed"); got > 10 {
+		t.Errorf("%d reload failures logged over %d requests", got, requests.Load())
+	}
+}
+
+// logBuffer collects logf output from many goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (l *logBuffer) logf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(&l.buf, format+"\n", args...)
+}
+
+func (l *logBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
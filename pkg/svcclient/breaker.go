@@ -0,0 +1,80 @@
+package svcclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+)
+
+// Breaker defaults for the fields a Breaker leaves zero.
+const (
+	DefaultBreakerFailures = circuit.DefaultFailureThreshold
+	DefaultBreakerCooldown = circuit.DefaultTimeout
+)
+
+// ErrCircuitOpen is returned, wrapped, for calls the client's circuit
+// breaker refuses without contacting the service.
+var ErrCircuitOpen = circuit.ErrOpen
+
+// BreakerState is the state of a client's circuit breaker.
+type BreakerState = circuit.State
+
+const (
+	BreakerClosed   = circuit.Closed
+	BreakerHalfOpen = circuit.HalfOpen
+	BreakerOpen     = circuit.Open
+)
+
+// Breaker is a client's circuit breaker policy. After Failures calls in a
+// row fail, every retry spent, the breaker opens and calls fail at once
+// with ErrCircuitOpen. After Cooldown it is half-open: one call goes
+// through as a probe, closing the breaker if it succeeds and opening it
+// again if it fails, while the calls made meanwhile are refused.
+//
+// A call fails when it gets no answer or a 5xx one; other statuses show
+// the service is up. Calls the caller cancels are not counted.
+type Breaker struct {
+	// Failures opens the breaker; zero means DefaultBreakerFailures.
+	Failures int
+	// Cooldown is how long the breaker stays open; zero means
+	// DefaultBreakerCooldown.
+	Cooldown time.Duration
+	// OnStateChange, if set, is called on every transition, such as to
+	// set a gauge or count trips. It is called with the breaker locked,
+	// so it must be quick and must not use the Client.
+	OnStateChange func(from, to BreakerState)
+}
+
+// WithBreaker puts calls through a circuit breaker with policy b, so a
+// service that is down costs its callers one error rather than a full
+// retry budget on every call. Without it calls are never refused.
+func WithBreaker(b Breaker) Option {
+	return func(c *Client) {
+		c.breaker = &circuit.Breaker{
+			Policy:        circuit.Policy{FailureThreshold: b.Failures, SuccessThreshold: 1, Timeout: b.Cooldown},
+			OnStateChange: b.OnStateChange,
+		}
+	}
+}
+
+// BreakerState returns the state of the client's breaker, closed if it
+// has none.
+func (c *Client) BreakerState() BreakerState {
+	return c.breaker.State()
+}
+
+// classify reads the last attempt of a call made with ctx as an outcome
+// for the breaker. Running out of time is a failure, as the service was
+// too slow; the caller giving up is not.
+func classify(ctx context.Context, resp *http.Response, err error) circuit.Outcome {
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		return circuit.Abandoned
+	case err != nil, resp.StatusCode >= 500:
+		return circuit.Failure
+	}
+	return circuit.Success
+}
@@ -0,0 +1,184 @@
+package svcclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+)
+
+// switchable answers with the status held in status, counting calls.
+func switchable(t *testing.T) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var calls, status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls, &status
+}
+
+// newBreakerClient returns a client without retries whose breaker runs
+// on the clock *now and records its transitions in *changes.
+func newBreakerClient(url string, now *time.Time, changes *[]string) *Client {
+	c := New(url, WithMaxAttempts(1), WithBreaker(Breaker{
+		Failures: 3,
+		Cooldown: time.Minute,
+		OnStateChange: func(from, to BreakerState) {
+			*changes = append(*changes, string(from)+"->"+string(to))
+		},
+	}))
+	c.breaker.Now = func() time.Time { return *now }
+	return c
+}
+
+// trip opens c's breaker without calling the service.
+func trip(t *testing.T, c *Client) {
+	t.Helper()
+	for c.BreakerState() != BreakerOpen {
+		call, err := c.breaker.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		call.Done(circuit.Failure)
+	}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	srv, calls, status := switchable(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var changes []string
+	c := newBreakerClient(srv.URL, &now, &changes)
+	ctx := context.Background()
+
+	// A 4xx shows the service is up and breaks a streak of failures.
+	status.Store(http.StatusServiceUnavailable)
+	c.Get(ctx, "/x", nil)
+	c.Get(ctx, "/x", nil)
+	status.Store(http.StatusNotFound)
+	c.Get(ctx, "/x", nil)
+	status.Store(http.StatusServiceUnavailable)
+	c.Get(ctx, "/x", nil)
+	c.Get(ctx, "/x", nil)
+	if st := c.BreakerState(); st != BreakerClosed {
+		t.Fatalf("state after two failures = %s, want closed", st)
+	}
+
+	c.Get(ctx, "/x", nil)
+	if st := c.BreakerState(); st != BreakerOpen {
+		t.Fatalf("state after three failures = %s, want open", st)
+	}
+	before := calls.Load()
+	err := c.Get(ctx, "/x", nil)
+	if !errors.Is(err, ErrCircuitOpen) || calls.Load() != before {
+		t.Fatalf("call while open: err = %v, %d requests sent", err, calls.Load()-before)
+	}
+
+	// After the cooldown one probe goes through; failing, it reopens.
+	now = now.Add(time.Minute)
+	if err := c.Get(ctx, "/x", nil); errors.Is(err, ErrCircuitOpen) || calls.Load() != before+1 {
+		t.Fatalf("probe: err = %v", err)
+	}
+	if st := c.BreakerState(); st != BreakerOpen {
+		t.Fatalf("state after a failed probe = %s, want open", st)
+	}
+
+	now = now.Add(time.Minute)
+	status.Store(http.StatusOK)
+	if err := c.Get(ctx, "/x", nil); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if st := c.BreakerState(); st != BreakerClosed {
+		t.Fatalf("state after a good probe = %s, want closed", st)
+	}
+
+	want := []string{"closed->open", "open->half_open", "half_open->open", "open->half_open", "half_open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("transitions = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", changes, want)
+			break
+		}
+	}
+}
+
+func TestBreakerHalfOpenAdmitsOneProbe(t *testing.T) {
+	arrived, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-release
+	}))
+	defer srv.Close()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var changes []string
+	c := newBreakerClient(srv.URL, &now, &changes)
+	trip(t, c)
+	now = now.Add(time.Minute)
+
+	done := make(chan error)
+	go func() { done <- c.Get(context.Background(), "/x", nil) }()
+	<-arrived
+	if err := c.Get(context.Background(), "/x", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call during the probe: err = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if st := c.BreakerState(); st != BreakerClosed {
+		t.Errorf("state = %s, want closed", st)
+	}
+}
+
+func TestBreakerIgnoresCanceledCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var changes []string
+	c := newBreakerClient(srv.URL, &now, &changes)
+	trip(t, c)
+	now = now.Add(time.Minute)
+
+	// A probe the caller gives up on frees the slot for the next.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := c.Get(ctx, "/x", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled probe: err = %v", err)
+	}
+	if st := c.BreakerState(); st != BreakerHalfOpen {
+		t.Errorf("state after a canceled probe = %s, want half_open", st)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Get(ctx, "/x", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second probe: err = %v, want it sent and timed out", err)
+	}
+	if st := c.BreakerState(); st != BreakerOpen {
+		t.Errorf("state after a timed-out probe = %s, want open", st)
+	}
+}
+
+func TestNoBreakerByDefault(t *testing.T) {
+	srv, calls, status := switchable(t)
+	status.Store(http.StatusInternalServerError)
+	c := New(srv.URL, WithMaxAttempts(1))
+	for range DefaultBreakerFailures + 1 {
+		if err := c.Get(context.Background(), "/x", nil); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("call refused without a breaker")
+		}
+	}
+	if calls.Load() != DefaultBreakerFailures+1 || c.BreakerState() != BreakerClosed {
+		t.Errorf("%d calls sent, state %s", calls.Load(), c.BreakerState())
+	}
+}
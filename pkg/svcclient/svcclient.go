@@ -0,0 +1,392 @@
+// Package svcclient is the HTTP client the entrance app's services use to
+// call one another. Every call has a deadline, failures worth another try
+// are retried with exponential backoff, and bodies are JSON both ways:
+//
+//	programs := svcclient.New("http://programs:8080")
+//	...
+//	var p Program
+//	err := programs.Get(ctx, "/v1/programs/"+code, &p)
+//
+// Only idempotent calls (GET, HEAD, OPTIONS, PUT, DELETE, or any call with
+// an Idempotency-Key header) are retried unless the call opts in with
+// Idempotent, so a POST that timed out after the server acted on it is not
+// sent twice. WithBreaker adds a circuit breaker, failing calls fast with
+// ErrCircuitOpen while the service is down.
+//
+// Each attempt is a client span, a child of the span in the call's
+// context, and carries the W3C traceparent header so the called service
+// continues the trace. Without tracing.Init both are no-ops.
+package svcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/circuit"
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// Defaults for the options New is not given.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff  = 2 * time.Second
+)
+
+// instrumentationName names the tracer of client spans.
+const instrumentationName = "github.com/willyu1007/The-UniAssist-Entrance-App/pkg/svcclient"
+
+// maxErrorBody bounds how much of an error response is kept in a
+// StatusError.
+const maxErrorBody = 4 << 10
+
+// Client calls one service. It is safe for concurrent use.
+type Client struct {
+	base        *url.URL
+	http        *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	header      http.Header
+	breaker     *circuit.Breaker
+	tracer      trace.Tracer
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures New.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through c; nil means a client of its own
+// with the default transport. c's Timeout, if any, applies per attempt.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.http = c }
+}
+
+// WithTimeout bounds each call, every attempt and backoff included, when
+// its context has no earlier deadline; zero means DefaultTimeout and a
+// negative d no bound beyond the context's.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithMaxAttempts sets the total number of tries for a retryable call;
+// 1 disables retries and zero means DefaultMaxAttempts.
+func WithMaxAttempts(n int) Option {
+	return func(c *Client) { c.maxAttempts = n }
+}
+
+// WithBackoff sets the wait before the first retry, doubling after each
+// up to max; zero values mean DefaultBackoff and DefaultMaxBackoff.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(c *Client) { c.backoff, c.maxBackoff = initial, max }
+}
+
+// WithHeader sets a header on every request, such as an internal service
+// token.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Set(key, value) }
+}
+
+// WithTracerProvider records client spans with tp; nil means the global
+// provider tracing.Init installs.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Client) {
+		if tp != nil {
+			c.tracer = tp.Tracer(instrumentationName)
+		}
+	}
+}
+
+// New returns a Client for the service at baseURL; call paths are
+// resolved against it. It panics if baseURL is not an absolute http or
+// https URL, which is a configuration mistake caught at startup.
+func New(baseURL string, opts ...Option) *Client {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		panic(fmt.Sprintf("svcclient: base URL %q must be an absolute http or https URL", baseURL))
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	c := &Client{base: u, header: http.Header{}, sleep: sleep}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.http == nil {
+		c.http = &http.Client{}
+	}
+	if c.tracer == nil {
+		c.tracer = otel.Tracer(instrumentationName)
+	}
+	if c.timeout == 0 {
+		c.timeout = DefaultTimeout
+	}
+	if c.maxAttempts <= 0 {
+		c.maxAttempts = DefaultMaxAttempts
+	}
+	if c.backoff <= 0 {
+		c.backoff = DefaultBackoff
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = DefaultMaxBackoff
+	}
+	return c
+}
+
+// CallOption configures one call.
+type CallOption func(*call)
+
+type call struct {
+	header     http.Header
+	idempotent bool
+}
+
+// Idempotent marks a call safe to retry whatever its method, for a POST
+// the server deduplicates.
+func Idempotent() CallOption {
+	return func(c *call) { c.idempotent = true }
+}
+
+// Header sets a header on this call's request.
+func Header(key, value string) CallOption {
+	return func(c *call) { c.header.Set(key, value) }
+}
+
+// StatusError is returned for a response outside 2xx, after any retries.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the start of the response body, for the error message.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("svcclient: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		msg += ": " + body
+	}
+	return msg
+}
+
+// Get sends a GET and decodes the response into out.
+func (c *Client) Get(ctx context.Context, path string, out any, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodGet, path, nil, out, opts...)
+}
+
+// Post sends body as JSON in a POST and decodes the response into out.
+func (c *Client) Post(ctx context.Context, path string, body, out any, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodPost, path, body, out, opts...)
+}
+
+// Put sends body as JSON in a PUT and decodes the response into out.
+func (c *Client) Put(ctx context.Context, path string, body, out any, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodPut, path, body, out, opts...)
+}
+
+// Delete sends a DELETE and decodes the response into out.
+func (c *Client) Delete(ctx context.Context, path string, out any, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, out, opts...)
+}
+
+// Do sends method to path, relative to the base URL and possibly with a
+// query. A non-nil body is sent as JSON; a non-nil out receives the
+// decoded response, which is otherwise discarded. Connection failures
+// and 5xx responses are retried if the call is idempotent, waiting at
+// least as long as a Retry-After header asks; a Retry-After longer than
+// the maximum backoff, or than the deadline leaves, ends the retries. A
+// response outside 2xx is returned as a *StatusError, and a call an open
+// breaker refuses as an error wrapping ErrCircuitOpen.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any, opts ...CallOption) error {
+	cl := call{header: http.Header{}}
+	for _, opt := range opts {
+		opt(&cl)
+	}
+	ref, err := url.Parse(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return fmt.Errorf("svcclient: path %q: %w", path, err)
+	}
+	target := c.base.ResolveReference(ref).String()
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("svcclient: %s %s: encode body: %w", method, target, err)
+		}
+	}
+	breaker, err := c.breaker.Allow()
+	if err != nil {
+		return fmt.Errorf("svcclient: %s %s: %w", method, target, err)
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	attempts := 1
+	if cl.idempotent || idempotent(method, cl.header) {
+		attempts = c.maxAttempts
+	}
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, attempt, method, target, payload, cl.header)
+		wait, retry := backoff, attempt < attempts && worthRetrying(ctx, resp, err)
+		if retry && resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = max(wait, after)
+				retry = after <= c.maxBackoff
+			}
+		}
+		if retry && !fits(ctx, wait) {
+			retry = false
+		}
+		if !retry {
+			breaker.Done(classify(ctx, resp, err))
+			return finish(method, target, resp, err, out)
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			breaker.Done(classify(ctx, nil, err))
+			return fmt.Errorf("svcclient: %s %s: %w", method, target, err)
+		}
+		backoff = min(backoff*2, c.maxBackoff)
+	}
+}
+
+// send makes one attempt in a client span of its own, which ends when
+// the response headers arrive.
+func (c *Client) send(ctx context.Context, attempt int, method, target string, payload []byte, header http.Header) (*http.Response, error) {
+	attrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method), semconv.URLFull(target), semconv.ServerAddress(c.base.Hostname())}
+	if attempt > 1 {
+		attrs = append(attrs, semconv.HTTPRequestResendCount(attempt-1))
+	}
+	ctx, span := c.tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+	resp, err := c.roundTrip(ctx, method, target, payload, header)
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case resp.StatusCode >= http.StatusInternalServerError:
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	default:
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	}
+	return resp, err
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, target string, payload []byte, header http.Header) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, h := range []http.Header{c.header, header} {
+		for k, v := range h {
+			req.Header[k] = v
+		}
+	}
+	if id := requestid.FromContext(ctx); id != "" && req.Header.Get(requestid.Header) == "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return c.http.Do(req)
+}
+
+// finish turns the last attempt's outcome into Do's result.
+func finish(method, target string, resp *http.Response, err error, out any) error {
+	if err != nil {
+		return fmt.Errorf("svcclient: %s %s: %w", method, target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{Method: method, URL: target, StatusCode: resp.StatusCode, Body: b}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent || method == http.MethodHead {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("svcclient: %s %s: decode response: %w", method, target, err)
+	}
+	return nil
+}
+
+func idempotent(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return header.Get("Idempotency-Key") != ""
+}
+
+// worthRetrying reports whether an attempt failed in a way another might
+// not: a connection error while ctx is live, or a 5xx other than 501.
+func worthRetrying(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// retryAfter parses a Retry-After value, in seconds or as an HTTP date,
+// into a wait from now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(s, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// fits reports whether ctx leaves time to wait d and try again.
+func fits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,263 @@
+package svcclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/middleware/requestid"
+)
+
+// flaky answers status to its first failures requests and then echoes
+// the request as JSON.
+func flaky(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			http.Error(w, "try later", status)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]any{
+			"method": r.Method, "path": r.URL.RequestURI(), "body": body,
+			"request_id": r.Header.Get(requestid.Header), "content_type": r.Header.Get("Content-Type"),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// newTestClient returns a client for srv that records its backoff waits
+// instead of sleeping.
+func newTestClient(url string, waits *[]time.Duration, opts ...Option) *Client {
+	c := New(url, opts...)
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return c
+}
+
+type echo struct {
+	Method      string         `json:"method"`
+	Path        string         `json:"path"`
+	Body        map[string]any `json:"body"`
+	RequestID   string         `json:"request_id"`
+	ContentType string         `json:"content_type"`
+}
+
+func TestRetriesUntilSuccess(t *testing.T) {
+	srv, calls := flaky(t, 2, http.StatusServiceUnavailable, nil)
+	var waits []time.Duration
+	c := newTestClient(srv.URL+"/base", &waits)
+
+	var got echo
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	if err := c.Get(ctx, "/v1/programs/CS?round=early", &got); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server saw %d attempts, want 3", n)
+	}
+	if got.Method != "GET" || got.Path != "/base/v1/programs/CS?round=early" || got.RequestID != "req-1" {
+		t.Errorf("response = %+v", got)
+	}
+	if want := []time.Duration{DefaultBackoff, 2 * DefaultBackoff}; len(waits) != 2 || waits[0] != want[0] || waits[1] != want[1] {
+		t.Errorf("waits = %v, want %v", waits, want)
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := flaky(t, 5, http.StatusBadGateway, nil)
+	var waits []time.Duration
+	err := newTestClient(srv.URL, &waits, WithMaxAttempts(2)).Get(context.Background(), "/x", nil)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusBadGateway || string(se.Body) != "try later\n" {
+		t.Fatalf("err = %v, want a 502 StatusError", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server saw %d attempts, want 2", n)
+	}
+}
+
+func TestPostIsNotRetriedUnlessIdempotent(t *testing.T) {
+	srv, calls := flaky(t, 2, http.StatusServiceUnavailable, nil)
+	var waits []time.Duration
+	c := newTestClient(srv.URL, &waits)
+
+	var se *StatusError
+	if err := c.Post(context.Background(), "/v1/letters", map[string]any{"id": "a"}, nil); !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("plain POST: err = %v, want the first 503", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("plain POST was sent %d times", n)
+	}
+
+	var got echo
+	if err := c.Post(context.Background(), "/v1/letters", map[string]any{"id": "a"}, &got, Idempotent()); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 || got.Body["id"] != "a" || got.ContentType != "application/json" {
+		t.Errorf("idempotent POST: %d calls, response %+v", n, got)
+	}
+
+	srv, calls = flaky(t, 1, http.StatusServiceUnavailable, nil)
+	c = newTestClient(srv.URL, &waits)
+	if err := c.Post(context.Background(), "/v1/letters", nil, nil, Header("Idempotency-Key", "k-1")); err != nil || calls.Load() != 2 {
+		t.Errorf("POST with Idempotency-Key: err = %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	srv, calls := flaky(t, 1, http.StatusNotFound, nil)
+	var waits []time.Duration
+	err := newTestClient(srv.URL, &waits).Get(context.Background(), "/missing", nil)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound || calls.Load() != 1 {
+		t.Errorf("err = %v after %d calls, want one 404", err, calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, _ := flaky(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+	var waits []time.Duration
+	if err := newTestClient(srv.URL, &waits).Get(context.Background(), "/x", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(waits) != 1 || waits[0] != time.Second {
+		t.Errorf("waits = %v, want the 1s Retry-After", waits)
+	}
+
+	// A server asking for longer than the client would back off gets
+	// its error back at once.
+	srv, calls := flaky(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"120"}})
+	waits = nil
+	var se *StatusError
+	if err := newTestClient(srv.URL, &waits).Get(context.Background(), "/x", nil); !errors.As(err, &se) || calls.Load() != 1 || len(waits) != 0 {
+		t.Errorf("long Retry-After: err = %v, %d calls, waits %v", err, calls.Load(), waits)
+	}
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for v, want := range map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"Sun, 01 Mar 2026 09:00:05 GMT": 5 * time.Second,
+		"Sun, 01 Mar 2026 08:00:00 GMT": 0,
+	} {
+		if got, ok := retryAfter(v, now); !ok || got != want {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v", v, got, ok, want)
+		}
+	}
+	if _, ok := retryAfter("soon", now); ok {
+		t.Error("retryAfter accepted a malformed value")
+	}
+}
+
+func TestConnectionErrorsAreRetried(t *testing.T) {
+	srv, _ := flaky(t, 0, 0, nil)
+	url := srv.URL
+	srv.Close()
+	var waits []time.Duration
+	if err := newTestClient(url, &waits).Get(context.Background(), "/x", nil); err == nil {
+		t.Fatal("call to a closed server succeeded")
+	}
+	if len(waits) != DefaultMaxAttempts-1 {
+		t.Errorf("waits = %v, want %d retries", waits, DefaultMaxAttempts-1)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	start := time.Now()
+	err := New(srv.URL, WithTimeout(50*time.Millisecond)).Get(context.Background(), "/slow", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("call took %s despite a 50ms timeout", d)
+	}
+}
+
+func TestNewRejectsRelativeBase(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New accepted a base URL without a scheme")
+		}
+	}()
+	New("programs:8080")
+}
+
+func TestAttemptsAreTracedAndPropagated(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	var parents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parents = append(parents, r.Header.Get("traceparent"))
+		if len(parents) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+	var waits []time.Duration
+	if err := newTestClient(srv.URL, &waits, WithTracerProvider(tp)).Get(ctx, "/v1/programs/cs", nil); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 3 || len(parents) != 2 {
+		t.Fatalf("%d spans for %d requests, want two attempts and the parent", len(spans), len(parents))
+	}
+	for i, span := range spans[:2] {
+		sc := span.SpanContext
+		if span.SpanKind != trace.SpanKindClient || span.Parent.SpanID() != parent.SpanContext().SpanID() || sc.TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("attempt %d: kind %v, parent %v", i+1, span.SpanKind, span.Parent.SpanID())
+		}
+		// The called service sees this attempt's span as its parent.
+		if want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"; parents[i] != want {
+			t.Errorf("attempt %d traceparent = %q, want %q", i+1, parents[i], want)
+		}
+	}
+	attrs := func(s tracetest.SpanStub) map[string]any {
+		m := map[string]any{}
+		for _, kv := range s.Attributes {
+			m[string(kv.Key)] = kv.Value.AsInterface()
+		}
+		return m
+	}
+	first, retry := attrs(spans[0]), attrs(spans[1])
+	if spans[0].Status.Code != codes.Error || first[string(semconv.HTTPResponseStatusCodeKey)] != int64(503) {
+		t.Errorf("failed attempt: status %v, attributes %v", spans[0].Status, first)
+	}
+	if retry[string(semconv.HTTPRequestResendCountKey)] != int64(1) || retry[string(semconv.URLFullKey)] != srv.URL+"/v1/programs/cs" {
+		t.Errorf("retry attributes %v", retry)
+	}
+}
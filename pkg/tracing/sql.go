@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartQuery starts a client span for one SQL statement, named by the
+// statement with its literal values masked (see SanitizeSQL). The caller
+// ends the span once the rows are read:
+//
+//	ctx, span := tracing.StartQuery(ctx, stmt)
+//	defer span.End()
+func StartQuery(ctx context.Context, stmt string) (context.Context, trace.Span) {
+	name := SanitizeSQL(stmt)
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBQueryText(name)),
+	)
+}
+
+// SanitizeSQL replaces string and numeric literals in stmt with ? and
+// collapses whitespace, so a span name never carries a value even if one
+// was interpolated instead of bound. Placeholders such as $1 and
+// identifiers containing digits are kept.
+func SanitizeSQL(stmt string) string {
+	var b strings.Builder
+	runes := []rune(stmt)
+	space := false
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			space = true
+			continue
+		case c == '\'':
+			// Skip to the closing quote; '' is an escaped quote.
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case unicode.IsDigit(c) && (i == 0 || !isIdentRune(runes[i-1])):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
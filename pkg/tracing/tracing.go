@@ -0,0 +1,122 @@
+// Package tracing sets up OpenTelemetry tracing for a service and holds
+// the span helpers shared by its data-access code.
+//
+// Init installs the global TracerProvider once at startup; everything
+// else takes a trace.Tracer or uses Tracer, which follows the global
+// provider, so tests that never call Init run against the no-op provider
+// and need no exporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willyu1007/The-UniAssist-Entrance-App/pkg/buildinfo"
+)
+
+// Exporter address schemes accepted by Init.
+const (
+	// SchemeOTLP sends OTLP over gRPC, to a collector or any backend
+	// that speaks it (default port 4317).
+	SchemeOTLP = "otlp"
+	// SchemeJaeger sends OTLP over HTTP to Jaeger's own receiver
+	// (default port 4318). Jaeger has accepted OTLP natively since 1.35,
+	// and the Jaeger-protocol exporter is no longer maintained upstream.
+	SchemeJaeger = "jaeger"
+)
+
+// instrumentationName names the tracer used by Tracer.
+const instrumentationName = "github.com/willyu1007/The-UniAssist-Entrance-App"
+
+// shutdownTimeout bounds the final flush of buffered spans.
+const shutdownTimeout = 5 * time.Second
+
+// Init exports spans for serviceName to exporterAddr and installs the
+// W3C trace-context and baggage propagators. exporterAddr is
+// otlp://host:port (or a bare host:port) for OTLP/gRPC, or
+// jaeger://host:port for Jaeger; connections are plaintext, for an agent
+// or collector inside the cluster. An empty exporterAddr disables
+// tracing: the no-op provider stays in place.
+//
+// The returned func flushes buffered spans and shuts the exporter down;
+// call it once, after the server has drained.
+func Init(serviceName, exporterAddr string) (func(), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if exporterAddr == "" {
+		return func() {}, nil
+	}
+	scheme, host, err := parseAddr(exporterAddr)
+	if err != nil {
+		return nil, err
+	}
+	var client otlptrace.Client
+	switch scheme {
+	case SchemeOTLP:
+		client = otlptracegrpc.NewClient(otlptracegrpc.WithEndpoint(host), otlptracegrpc.WithInsecure())
+	case SchemeJaeger:
+		client = otlptracehttp.NewClient(otlptracehttp.WithEndpoint(host), otlptracehttp.WithInsecure())
+	}
+	// otlptrace.New does not dial; export errors surface later through
+	// otel's error handler.
+	exp, err := otlptrace.New(context.Background(), client)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %s exporter: %w", scheme, err)
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(buildinfo.Version),
+	)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			slog.Default().Warn("tracing shutdown failed", "error", err)
+		}
+	}, nil
+}
+
+// parseAddr splits an exporter address into its scheme and host:port.
+func parseAddr(addr string) (scheme, host string, err error) {
+	if !strings.Contains(addr, "://") {
+		addr = SchemeOTLP + "://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", fmt.Errorf("tracing: exporter address %q: %w", addr, err)
+	}
+	if u.Scheme != SchemeOTLP && u.Scheme != SchemeJaeger {
+		return "", "", fmt.Errorf("tracing: exporter address %q: scheme must be %s or %s", addr, SchemeOTLP, SchemeJaeger)
+	}
+	if u.Hostname() == "" || u.Port() == "" || (u.Path != "" && u.Path != "/") {
+		return "", "", fmt.Errorf("tracing: exporter address %q: want %s://host:port", addr, u.Scheme)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// Tracer returns the service's tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError marks span as failed with err.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
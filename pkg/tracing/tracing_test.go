@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"SELECT email FROM applicant_profiles WHERE id = $1", "SELECT email FROM applicant_profiles WHERE id = $1"},
+		{"SELECT *\n\tFROM t\n  WHERE name = 'O''Brien' AND age > 42", "SELECT * FROM t WHERE name = ? AND age > ?"},
+		{"SELECT ts_rank(x, plainto_tsquery('simple', $2))::float8, 0::float8 FROM t LIMIT 10", "SELECT ts_rank(x, plainto_tsquery(?, $2))::float8, ?::float8 FROM t LIMIT ?"},
+		{"SELECT col2, t1.v FROM t1 WHERE x = 3.14", "SELECT col2, t1.v FROM t1 WHERE x = ?"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizeSQL(tt.in); got != tt.want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStartQuery(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, parent := Tracer().Start(context.Background(), "GET /v1/applications")
+	_, span := StartQuery(ctx, "SELECT email FROM applicant_profiles WHERE id = 'stu-1'")
+	span.End()
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	q := spans[0]
+	if q.Name() != "SELECT email FROM applicant_profiles WHERE id = ?" {
+		t.Errorf("span name = %q", q.Name())
+	}
+	if q.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("query span is not a child of the request span")
+	}
+	var system bool
+	for _, kv := range q.Attributes() {
+		if kv == semconv.DBSystemPostgreSQL {
+			system = true
+		}
+		if strings.Contains(kv.Value.Emit(), "stu-1") {
+			t.Errorf("attribute %s leaks a value: %s", kv.Key, kv.Value.Emit())
+		}
+	}
+	if !system {
+		t.Errorf("attributes = %v, want db.system", q.Attributes())
+	}
+}
+
+func TestInit(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	shutdown, err := Init("admissions-api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown()
+	if otel.GetTracerProvider() != prev {
+		t.Error("empty exporter address replaced the tracer provider")
+	}
+
+	for _, addr := range []string{"collector:4317", "otlp://collector:4317", "jaeger://jaeger:4318"} {
+		shutdown, err := Init("admissions-api", addr)
+		if err != nil {
+			t.Errorf("%s: %v", addr, err)
+			continue
+		}
+		if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+			t.Errorf("%s: provider not installed", addr)
+		}
+		shutdown()
+	}
+
+	for _, addr := range []string{"zipkin://z:9411", "collector", "otlp://collector:4317/v1/traces", "otlp://:4317"} {
+		if _, err := Init("admissions-api", addr); err == nil {
+			t.Errorf("%s: accepted", addr)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+-- CreateTable
+CREATE TABLE "public"."application_deadlines" (
+    "program_code" TEXT NOT NULL,
+    "round" TEXT NOT NULL,
+    "closes_at" TIMESTAMP(3) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "application_deadlines_pkey" PRIMARY KEY ("program_code","round")
+);
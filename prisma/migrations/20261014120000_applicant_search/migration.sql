@@ -0,0 +1,29 @@
+-- CreateTable
+CREATE TABLE "public"."applicant_profiles" (
+    "id" TEXT NOT NULL,
+    "full_name" TEXT NOT NULL,
+    "email" TEXT,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "applicant_profiles_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "public"."student_applications" (
+    "id" TEXT NOT NULL,
+    "applicant_id" TEXT NOT NULL,
+    "program_code" TEXT NOT NULL,
+    "round" TEXT,
+    "status" TEXT NOT NULL,
+    "submitted_at" TIMESTAMP(3) NOT NULL,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "student_applications_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_student_applications_applicant_id" ON "public"."student_applications"("applicant_id");
+
+-- CreateIndex
+CREATE INDEX "idx_student_applications_program_status" ON "public"."student_applications"("program_code", "status");
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."student_applications" ADD COLUMN "letter_key" TEXT;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."student_applications" ADD COLUMN "deleted_at" TIMESTAMP(3);
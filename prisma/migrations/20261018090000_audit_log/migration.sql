@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "public"."audit_log" (
+    "id" BIGSERIAL NOT NULL,
+    "occurred_at" TIMESTAMP(3) NOT NULL,
+    "actor_id" TEXT NOT NULL,
+    "action" TEXT NOT NULL,
+    "entity_type" TEXT NOT NULL,
+    "entity_id" TEXT NOT NULL,
+    "diff" JSONB NOT NULL,
+
+    CONSTRAINT "audit_log_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_audit_log_entity_id_occurred_at" ON "public"."audit_log"("entity_id", "occurred_at");
+
+-- CreateIndex
+CREATE INDEX "idx_audit_log_occurred_at" ON "public"."audit_log"("occurred_at");
@@ -0,0 +1,64 @@
+-- CreateTable
+CREATE TABLE "public"."tenants" (
+    "id" TEXT NOT NULL,
+    "name" TEXT NOT NULL,
+    "domain" TEXT,
+    "config" JSONB NOT NULL DEFAULT '{}',
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "tenants_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "tenants_domain_key" ON "public"."tenants"("domain");
+
+-- The tenant that owns every row written before tenants existed.
+INSERT INTO "public"."tenants" ("id", "name") VALUES ('default', 'Default');
+
+-- AlterTable
+ALTER TABLE "public"."student_applications" ADD COLUMN "tenant_id" TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE "public"."application_deadlines" ADD COLUMN "tenant_id" TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE "public"."applicant_profiles" ADD COLUMN "tenant_id" TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE "public"."audit_log" ADD COLUMN "tenant_id" TEXT NOT NULL DEFAULT 'default';
+
+-- New rows belong to the tenant the writing transaction is scoped to.
+ALTER TABLE "public"."student_applications" ALTER COLUMN "tenant_id" SET DEFAULT current_setting('app.current_tenant');
+ALTER TABLE "public"."application_deadlines" ALTER COLUMN "tenant_id" SET DEFAULT current_setting('app.current_tenant');
+ALTER TABLE "public"."applicant_profiles" ALTER COLUMN "tenant_id" SET DEFAULT current_setting('app.current_tenant');
+ALTER TABLE "public"."audit_log" ALTER COLUMN "tenant_id" SET DEFAULT current_setting('app.current_tenant');
+
+-- Each tenant sets its own deadlines for the same program codes.
+ALTER TABLE "public"."application_deadlines" DROP CONSTRAINT "application_deadlines_pkey",
+ADD CONSTRAINT "application_deadlines_pkey" PRIMARY KEY ("tenant_id", "program_code", "round");
+
+-- CreateIndex
+CREATE INDEX "idx_student_applications_tenant_id" ON "public"."student_applications"("tenant_id");
+CREATE INDEX "idx_application_deadlines_tenant_id" ON "public"."application_deadlines"("tenant_id");
+CREATE INDEX "idx_applicant_profiles_tenant_id" ON "public"."applicant_profiles"("tenant_id");
+CREATE INDEX "idx_audit_log_tenant_id" ON "public"."audit_log"("tenant_id");
+
+-- AddForeignKey
+ALTER TABLE "public"."student_applications" ADD CONSTRAINT "student_applications_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE "public"."application_deadlines" ADD CONSTRAINT "application_deadlines_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE "public"."applicant_profiles" ADD CONSTRAINT "applicant_profiles_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE "public"."audit_log" ADD CONSTRAINT "audit_log_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security: a session sees the rows of app.current_tenant, or
+-- every row with app.cross_tenant on. FORCE applies it to the table
+-- owner too, which admissions-api usually connects as.
+ALTER TABLE "public"."student_applications" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."student_applications" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."student_applications"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+ALTER TABLE "public"."application_deadlines" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."application_deadlines" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."application_deadlines"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+ALTER TABLE "public"."applicant_profiles" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."applicant_profiles" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."applicant_profiles"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+ALTER TABLE "public"."audit_log" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."audit_log" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."audit_log"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
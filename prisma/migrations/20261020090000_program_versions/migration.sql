@@ -0,0 +1,26 @@
+-- CreateTable
+CREATE TABLE "public"."program_versions" (
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+    "code" TEXT NOT NULL,
+    "version" INTEGER NOT NULL,
+    "name" TEXT NOT NULL,
+    "faculty" TEXT NOT NULL,
+    "required_documents" JSONB NOT NULL DEFAULT '[]',
+    "capacity" INTEGER NOT NULL,
+    "deadlines" JSONB NOT NULL DEFAULT '{}',
+    "valid_from" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "program_versions_pkey" PRIMARY KEY ("tenant_id", "code", "version")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_program_versions_code_valid_from" ON "public"."program_versions"("code", "valid_from");
+
+-- AddForeignKey
+ALTER TABLE "public"."program_versions" ADD CONSTRAINT "program_versions_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security, as on the tables of the tenants migration.
+ALTER TABLE "public"."program_versions" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."program_versions" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."program_versions"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
@@ -0,0 +1,53 @@
+-- CreateTable
+CREATE TABLE "public"."interview_slots" (
+    "id" TEXT NOT NULL,
+    "program_code" TEXT NOT NULL,
+    "start_at" TIMESTAMP(3) NOT NULL,
+    "end_at" TIMESTAMP(3) NOT NULL,
+    "advisor_id" TEXT NOT NULL,
+    "max_candidates" INTEGER NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT "interview_slots_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "public"."interview_bookings" (
+    "id" TEXT NOT NULL,
+    "slot_id" TEXT NOT NULL,
+    "application_id" TEXT NOT NULL,
+    "program_code" TEXT NOT NULL,
+    "booked_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT "interview_bookings_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_interview_slots_tenant_id_program_code_start_at" ON "public"."interview_slots"("tenant_id", "program_code", "start_at");
+
+-- CreateIndex
+CREATE UNIQUE INDEX "interview_bookings_tenant_id_application_id_program_code_key" ON "public"."interview_bookings"("tenant_id", "application_id", "program_code");
+
+-- CreateIndex
+CREATE INDEX "idx_interview_bookings_slot_id" ON "public"."interview_bookings"("slot_id");
+
+-- AddForeignKey
+ALTER TABLE "public"."interview_slots" ADD CONSTRAINT "interview_slots_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."interview_bookings" ADD CONSTRAINT "interview_bookings_slot_id_fkey" FOREIGN KEY ("slot_id") REFERENCES "public"."interview_slots"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."interview_bookings" ADD CONSTRAINT "interview_bookings_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security, as on the tables of the tenants migration.
+ALTER TABLE "public"."interview_slots" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."interview_slots" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."interview_slots"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
+ALTER TABLE "public"."interview_bookings" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."interview_bookings" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."interview_bookings"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
@@ -0,0 +1,26 @@
+-- CreateTable
+CREATE TABLE "public"."documents" (
+    "id" TEXT NOT NULL,
+    "application_id" TEXT NOT NULL,
+    "key" TEXT NOT NULL,
+    "filename" TEXT NOT NULL,
+    "mime_type" TEXT NOT NULL,
+    "size" BIGINT NOT NULL,
+    "uploaded_at" TIMESTAMP(3) NOT NULL,
+    "deleted_at" TIMESTAMP(3),
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT "documents_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_documents_application_id_uploaded_at" ON "public"."documents"("application_id", "uploaded_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."documents" ADD CONSTRAINT "documents_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security, as on the tables of the tenants migration.
+ALTER TABLE "public"."documents" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."documents" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."documents"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
@@ -0,0 +1,34 @@
+-- CreateTable
+CREATE TABLE "public"."recommendation_requests" (
+    "id" TEXT NOT NULL,
+    "application_id" TEXT NOT NULL,
+    "referee_email" TEXT NOT NULL,
+    "token" TEXT NOT NULL,
+    "status" TEXT NOT NULL,
+    "expires_at" TIMESTAMP(3) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL,
+    "sent_at" TIMESTAMP(3) NOT NULL,
+    "submitted_at" TIMESTAMP(3),
+    "document_id" TEXT,
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT "recommendation_requests_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "recommendation_requests_token_key" ON "public"."recommendation_requests"("token");
+
+-- CreateIndex
+CREATE INDEX "idx_recommendation_requests_application_id_created_at" ON "public"."recommendation_requests"("application_id", "created_at");
+
+-- CreateIndex
+CREATE INDEX "idx_recommendation_requests_status_expires_at" ON "public"."recommendation_requests"("status", "expires_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."recommendation_requests" ADD CONSTRAINT "recommendation_requests_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security, as on the tables of the tenants migration.
+ALTER TABLE "public"."recommendation_requests" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."recommendation_requests" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."recommendation_requests"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');
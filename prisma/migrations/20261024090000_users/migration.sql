@@ -0,0 +1,26 @@
+-- CreateTable
+CREATE TABLE "public"."users" (
+    "id" TEXT NOT NULL,
+    "issuer" TEXT NOT NULL,
+    "subject" TEXT NOT NULL,
+    "email" TEXT,
+    "name" TEXT,
+    "role" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL,
+    "last_login_at" TIMESTAMP(3) NOT NULL,
+    "tenant_id" TEXT NOT NULL DEFAULT current_setting('app.current_tenant'),
+
+    CONSTRAINT "users_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "users_tenant_id_issuer_subject_key" ON "public"."users"("tenant_id", "issuer", "subject");
+
+-- AddForeignKey
+ALTER TABLE "public"."users" ADD CONSTRAINT "users_tenant_id_fkey" FOREIGN KEY ("tenant_id") REFERENCES "public"."tenants"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Row level security, as on the tables of the tenants migration.
+ALTER TABLE "public"."users" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public"."users" FORCE ROW LEVEL SECURITY;
+CREATE POLICY "tenant_isolation" ON "public"."users"
+    USING ("tenant_id" = current_setting('app.current_tenant', true) OR current_setting('app.cross_tenant', true) = 'on');